@@ -22,6 +22,7 @@ import (
 	"go.temporal.io/sdk/client"
 
 	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/temporalclient"
 	"github.com/mfateev/temporal-agent-harness/internal/workflow"
 )
 
@@ -66,8 +67,13 @@ func printUsage() {
 }
 
 func dialTemporal() client.Client {
+	dataConverter, err := temporalclient.ConnectionConfig{}.DataConverter(nil)
+	if err != nil {
+		log.Fatalf("Failed to create data converter: %v", err)
+	}
 	c, err := client.Dial(client.Options{
-		HostPort: client.DefaultHostPort,
+		HostPort:      client.DefaultHostPort,
+		DataConverter: dataConverter,
 	})
 	if err != nil {
 		log.Fatalf("Failed to create Temporal client: %v", err)
@@ -97,6 +103,7 @@ func cmdStart(args []string) {
 	}
 
 	input := workflow.WorkflowInput{
+		Version:        workflow.ProtocolVersion,
 		ConversationID: workflowID,
 		UserMessage:    *message,
 		Config: models.SessionConfiguration{
@@ -153,7 +160,7 @@ func cmdSend(args []string) {
 	updateHandle, err := c.UpdateWorkflow(ctx, client.UpdateWorkflowOptions{
 		WorkflowID:   *workflowID,
 		UpdateName:   workflow.UpdateUserInput,
-		Args:         []interface{}{workflow.UserInput{Content: *message}},
+		Args:         []interface{}{workflow.UserInput{Version: workflow.ProtocolVersion, Content: *message}},
 		WaitForStage: client.WorkflowUpdateStageCompleted,
 	})
 	if err != nil {
@@ -187,7 +194,7 @@ func cmdHistory(args []string) {
 		log.Fatalf("Failed to query history: %v", err)
 	}
 
-	var items []models.ConversationItem
+	var items models.ConversationItemList
 	if err := resp.Get(&items); err != nil {
 		log.Fatalf("Failed to decode history: %v", err)
 	}
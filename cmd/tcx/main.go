@@ -10,8 +10,19 @@
 //	tcx -m "hello"                    Start new session with initial message
 //	tcx -m "hello" --model gpt-4o    Use a specific model
 //	tcx --inline                     Run without alt-screen (inline mode)
+//	tcx --output plain -m "hello"    Plain text output for CI logs and pipes (auto when not a TTY)
+//	tcx --local -m "hello"           Run in-process without Temporal (no durability)
+//	tcx --local -m "fix" --artifacts-dir out --verify "go test ./..."  Headless run with a CI bundle
 //	tcx crews                        List available crew templates
 //	tcx start-crew <name> [--input key=value]...  Start a crew session
+//	tcx safety explain "<command>"   Explain how a command is classified for approval
+//	tcx sessions prune [--older-than 30d] [--dry-run] [--yes]  Delete old and abandoned sessions
+//	tcx schema [--out DIR]           Print or write the JSON Schemas of the client payloads
+//	tcx admin metrics [--query Q] [--format table|prometheus]  Aggregate session metrics via visibility
+//	tcx admin reset <workflow-id> [--event-id N]  Reset a stuck session to its last good point
+//	tcx audit export --session <id> [--format csv|json]  Export the audit log of mutating tool calls
+//	tcx audit export --all [--query Q]  Export the audit logs of all sessions
+//	tcx demo [--temporal-cli PATH]    Try tcx offline: scripted session, no API keys or cluster
 package main
 
 import (
@@ -19,11 +30,17 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/mfateev/temporal-agent-harness/internal/cli"
+	"github.com/mfateev/temporal-agent-harness/internal/command_safety"
+	"github.com/mfateev/temporal-agent-harness/internal/demo"
 	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/shell"
+	"github.com/mfateev/temporal-agent-harness/internal/temporalclient"
+	"github.com/mfateev/temporal-agent-harness/internal/workflow"
 )
 
 func main() {
@@ -42,6 +59,42 @@ func main() {
 				os.Exit(1)
 			}
 			return
+		case "safety":
+			if err := runSafety(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		case "sessions":
+			if err := runSessions(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		case "schema":
+			if err := runSchema(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		case "admin":
+			if err := runAdmin(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		case "audit":
+			if err := runAudit(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		case "demo":
+			if err := runDemo(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
 		}
 	}
 
@@ -49,10 +102,11 @@ func main() {
 	message2 := flag.String("message", "", "Initial message (alias for -m)")
 	model := flag.String("model", "gpt-4o-mini", "LLM model to use")
 	provider := flag.String("provider", "", "LLM provider override (openai, anthropic, google)")
-	temporalHost := flag.String("temporal-host", "", "Temporal server address (overrides envconfig/env vars)")
 	noMarkdown := flag.Bool("no-markdown", false, "Disable markdown rendering")
 	noColor := flag.Bool("no-color", false, "Disable colored output")
 	inline := flag.Bool("inline", false, "Disable alt-screen mode (inline output)")
+	output := flag.String("output", "auto", "Output: auto (plain when stdout or stderr is not a terminal), tty (interactive TUI), or plain (text output and progress lines, no ANSI)")
+	accessible := flag.Bool("accessible", false, "Screen-reader friendly output: plain labeled text, announced state changes, no spinner or ANSI (default: accessible in config.toml)")
 	fullAuto := flag.Bool("full-auto", false, "Auto-approve all tool calls without prompting")
 	approvalMode := flag.String("approval-mode", "", "Approval mode: unless-trusted, never, on-failure (deprecated)")
 	sandboxMode := flag.String("sandbox", "", "Sandbox mode: full-access, read-only, workspace-write")
@@ -60,11 +114,34 @@ func main() {
 	sandboxNetwork := flag.Bool("sandbox-network", true, "Allow network access in sandbox")
 	codexHome := flag.String("codex-home", "", "Path to codex config directory (default: ~/.codex)")
 	noSuggestions := flag.Bool("no-suggestions", false, "Disable prompt suggestions after turn completion")
+	ask := flag.Bool("ask", false, "Start a Q&A session: no tools or approvals, lighter prompt")
 	memory := flag.Bool("memory", false, "Enable cross-session memory subsystem")
 	memoryDb := flag.String("memory-db", "", "Path to memory SQLite DB (default: ~/.codex/state.sqlite)")
+	maxCost := flag.Float64("max-cost", 0, "Pause a session when its estimated LLM spend reaches this many USD")
+	persona := flag.String("persona", "", "Persona preset tuning tone and verbosity: concise, explanatory, mentor, or none (default: [persona] in config.toml)")
+	local := flag.Bool("local", false, "Run the session in this process without Temporal: no durability or resume; -m runs one turn and exits")
+	artifactsDir := flag.String("artifacts-dir", "", "With --local -m: write a machine-readable bundle of the run (summary.json, diff.patch, final_message.md) to this directory, or to s3://bucket/prefix or gs://bucket/prefix")
+	verify := flag.String("verify", "", "With --artifacts-dir: shell command run after the turn; its result is recorded in the bundle")
+	remoteDest := flag.String("remote", "", "Run shell, exec and file tools on this machine over SSH: [user@]host[:port] (default: [remote] in the worker's config.toml)")
+	remoteKey := flag.String("remote-key", "", "With --remote: private key file in ~/.ssh on the tool worker (default: SSH agent and ~/.ssh/config)")
+	remoteCwd := flag.String("remote-cwd", "", "With --remote: working directory on the remote machine (default: the current directory's path)")
 	connTimeout := flag.Duration("connection-timeout", 0, "Per-RPC timeout for Temporal calls (e.g. 10s). 0 = no timeout. Env: TCX_CONNECTION_TIMEOUT")
+	var conn temporalclient.ConnectionConfig
+	conn.RegisterFlags(flag.CommandLine)
+	workflowIDOpts := registerWorkflowIDFlags(flag.CommandLine)
 	flag.Parse()
 
+	wfID, err := workflowIDOpts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	outputMode, err := cli.ParseOutputMode(*output)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --output: %v\n", err)
+		os.Exit(2)
+	}
+
 	// Support env var override for connection timeout (used by TUI tests)
 	if *connTimeout == 0 {
 		if envTimeout := os.Getenv("TCX_CONNECTION_TIMEOUT"); envTimeout != "" {
@@ -79,6 +156,28 @@ func main() {
 	if msg == "" {
 		msg = *message2
 	}
+	if *artifactsDir != "" && (!*local || msg == "") {
+		fmt.Fprintln(os.Stderr, "Error: --artifacts-dir needs --local -m")
+		os.Exit(2)
+	}
+	if *verify != "" && *artifactsDir == "" {
+		fmt.Fprintln(os.Stderr, "Error: --verify needs --artifacts-dir")
+		os.Exit(2)
+	}
+
+	var remoteCfg *models.RemoteConfig
+	if *remoteDest != "" {
+		remoteCfg, err = models.ParseRemote(*remoteDest)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --remote: %v\n", err)
+			os.Exit(2)
+		}
+		remoteCfg.KeyPath = *remoteKey
+		remoteCfg.Cwd = *remoteCwd
+	} else if *remoteKey != "" || *remoteCwd != "" {
+		fmt.Fprintln(os.Stderr, "Error: --remote-key and --remote-cwd need --remote")
+		os.Exit(2)
+	}
 
 	var resolvedApproval models.ApprovalMode
 	switch {
@@ -107,12 +206,20 @@ func main() {
 		resolvedProvider = cli.DetectProvider(*model)
 	}
 
+	timezone, locale := userTimezone(*codexHome)
+	personaConfig := filepath.Join(resolveCodexHome(*codexHome), "config.toml")
+	cwd, _ := os.Getwd()
+	resolvedPersona, err := cli.ResolvePersona(personaConfig, cwd, *persona)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --persona: %v\n", err)
+		os.Exit(2)
+	}
 	config := cli.Config{
-		TemporalHost: *temporalHost,
-		Message:      msg,
-		Model:        *model,
-		NoMarkdown:   *noMarkdown,
-		NoColor:      *noColor,
+		Connection: conn,
+		Message:    msg,
+		Model:      *model,
+		NoMarkdown: *noMarkdown,
+		NoColor:    *noColor,
 		Permissions: models.Permissions{
 			ApprovalMode:         resolvedApproval,
 			SandboxMode:          *sandboxMode,
@@ -122,18 +229,122 @@ func main() {
 		CodexHome:          *codexHome,
 		Provider:           resolvedProvider,
 		Inline:             *inline,
+		OutputMode:         outputMode,
+		Accessible:         *accessible || accessibleMode(*codexHome),
 		DisableSuggestions: *noSuggestions,
+		InputPreviewTokens: inputPreviewTokens(*codexHome),
+		Markdown:           markdownOptions(*codexHome),
+		MaxSessionCostUSD:  *maxCost,
+		Ask:                *ask,
 		MemoryEnabled:      *memory,
 		MemoryDbPath:       *memoryDb,
 		ConnectionTimeout:  *connTimeout,
+		WorkflowID:         wfID,
+		Timezone:           timezone,
+		Locale:             locale,
+		Persona:            resolvedPersona,
+		PersonaConfigPath:  personaConfig,
+		ArtifactsDir:       *artifactsDir,
+		Verify:             *verify,
+		Remote:             remoteCfg,
 	}
 
-	if err := cli.Run(config); err != nil {
+	run := cli.Run
+	if *local {
+		run = cli.RunLocal
+	}
+	if err := run(config); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
+// inputPreviewTokens returns the large-message confirmation threshold from
+// config.toml, or the default when unset or unreadable.
+func inputPreviewTokens(codexHome string) int {
+	data, err := os.ReadFile(filepath.Join(resolveCodexHome(codexHome), "config.toml"))
+	if err != nil {
+		return cli.DefaultInputPreviewTokens
+	}
+	tc, err := models.ParseConfigToml(data)
+	if err != nil || tc.InputPreviewTokens == nil {
+		return cli.DefaultInputPreviewTokens
+	}
+	return *tc.InputPreviewTokens
+}
+
+// accessibleMode reports whether config.toml turns on accessibility mode.
+func accessibleMode(codexHome string) bool {
+	data, err := os.ReadFile(filepath.Join(resolveCodexHome(codexHome), "config.toml"))
+	if err != nil {
+		return false
+	}
+	tc, err := models.ParseConfigToml(data)
+	return err == nil && tc.Accessible != nil && *tc.Accessible
+}
+
+// markdownOptions returns the code block rendering options from the
+// [markdown] table of config.toml, or the defaults when unset or unreadable.
+func markdownOptions(codexHome string) cli.MarkdownOptions {
+	data, err := os.ReadFile(filepath.Join(resolveCodexHome(codexHome), "config.toml"))
+	if err != nil {
+		return cli.DefaultMarkdownOptions()
+	}
+	tc, err := models.ParseConfigToml(data)
+	if err != nil {
+		return cli.DefaultMarkdownOptions()
+	}
+	return cli.MarkdownOptionsFromToml(tc.Markdown)
+}
+
+// userTimezone returns the timezone and locale from config.toml, falling
+// back to the host's for values not set there.
+func userTimezone(codexHome string) (timezone, locale string) {
+	if data, err := os.ReadFile(filepath.Join(resolveCodexHome(codexHome), "config.toml")); err == nil {
+		if tc, err := models.ParseConfigToml(data); err == nil {
+			if tc.Timezone != nil {
+				timezone = *tc.Timezone
+			}
+			if tc.Locale != nil {
+				locale = *tc.Locale
+			}
+		}
+	}
+	if timezone == "" {
+		timezone = cli.DetectTimezone()
+	}
+	if locale == "" {
+		locale = cli.DetectLocale()
+	}
+	return timezone, locale
+}
+
+// registerWorkflowIDFlags registers the harness workflow ID flags on fs and
+// returns a function that validates and resolves them after parsing.
+func registerWorkflowIDFlags(fs *flag.FlagSet) func() (cli.WorkflowIDOptions, error) {
+	template := fs.String("workflow-id-template", "",
+		"Harness workflow ID template; placeholders {project} {user} {host} {date} {hash} (default: "+cli.DefaultWorkflowIDTemplate+"). Env: TCX_WORKFLOW_ID_TEMPLATE")
+	reuse := fs.String("workflow-id-reuse-policy", "",
+		"When a closed harness has the same ID: allow-duplicate, allow-duplicate-failed-only (default), reject-duplicate")
+	conflict := fs.String("workflow-id-conflict-policy", "",
+		"When a running harness has the same ID: use-existing (default), fail, terminate-existing")
+
+	return func() (cli.WorkflowIDOptions, error) {
+		opts := cli.WorkflowIDOptions{Template: *template}
+		if opts.Template == "" {
+			opts.Template = os.Getenv("TCX_WORKFLOW_ID_TEMPLATE")
+		}
+		var err error
+		if opts.ReusePolicy, err = cli.ParseWorkflowIDReusePolicy(*reuse); err != nil {
+			return opts, err
+		}
+		if opts.ConflictPolicy, err = cli.ParseWorkflowIDConflictPolicy(*conflict); err != nil {
+			return opts, err
+		}
+		return opts, nil
+	}
+}
+
 // resolveCodexHome returns the codex home directory.
 func resolveCodexHome(override string) string {
 	if override != "" {
@@ -198,20 +409,266 @@ func runCrews() error {
 	return nil
 }
 
+// runSafety handles `tcx safety explain "<command>"`. It classifies the
+// command using the built-in allow-list plus the [command_safety] overrides
+// from config.toml, and prints the result without contacting Temporal.
+func runSafety() error {
+	if len(os.Args) < 3 || os.Args[2] != "explain" {
+		return fmt.Errorf("usage: tcx safety explain [--codex-home DIR] \"<command>\"")
+	}
+	fs := flag.NewFlagSet("safety explain", flag.ExitOnError)
+	codexHome := fs.String("codex-home", "", "Path to codex config directory (default: ~/.codex)")
+	fs.Parse(os.Args[3:])
+
+	command := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if command == "" {
+		return fmt.Errorf("usage: tcx safety explain [--codex-home DIR] \"<command>\"")
+	}
+
+	var safe, unsafe []string
+	configPath := filepath.Join(resolveCodexHome(*codexHome), "config.toml")
+	if data, err := os.ReadFile(configPath); err == nil {
+		tc, err := models.ParseConfigToml(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", configPath, err)
+		}
+		if tc.CommandSafety != nil {
+			safe = tc.CommandSafety.SafeCommands
+			unsafe = tc.CommandSafety.UnsafeCommands
+		}
+	}
+
+	// Resolve the command the same way shell_command does before approval.
+	cmdVec := shell.DetectUserShell().DeriveExecArgs(command, true)
+	classifier := command_safety.NewClassifier(safe, unsafe)
+	fmt.Print(cli.FormatSafetyExplanation(command, classifier.Classify(cmdVec)))
+	return nil
+}
+
+// runSessions handles `tcx sessions prune`.
+func runSessions() error {
+	const usage = "usage: tcx sessions prune [--older-than 30d] [--harness ID,...] [--dry-run] [--yes]"
+	if len(os.Args) < 3 || os.Args[2] != "prune" {
+		return fmt.Errorf(usage)
+	}
+	fs := flag.NewFlagSet("sessions prune", flag.ExitOnError)
+	olderThan := fs.String("older-than", "", "Prune sessions inactive for this long, e.g. 30d or 72h (default: [retention] session_days, else 30d)")
+	dryRun := fs.Bool("dry-run", false, "List sessions that would be pruned without changing anything")
+	yes := fs.Bool("yes", false, "Do not ask for confirmation")
+	harnesses := fs.String("harness", "", "Comma-separated harness workflow IDs whose sessions are pruned (default: the current directory's harness)")
+	template := fs.String("workflow-id-template", "", "Harness workflow ID template of the current directory's harness. Env: TCX_WORKFLOW_ID_TEMPLATE")
+	codexHome := fs.String("codex-home", "", "Path to codex config directory (default: ~/.codex)")
+	memoryDb := fs.String("memory-db", "", "Path to memory SQLite DB (default: ~/.codex/state.sqlite)")
+	var conn temporalclient.ConnectionConfig
+	conn.RegisterFlags(fs)
+	fs.Parse(os.Args[3:])
+
+	home := resolveCodexHome(*codexHome)
+	timezone, _ := userTimezone(home)
+	cli.SetDisplayTimezone(timezone)
+	age := cli.DefaultPruneAge
+	if *olderThan != "" {
+		var err error
+		if age, err = cli.ParseAge(*olderThan); err != nil {
+			return err
+		}
+	} else if data, err := os.ReadFile(filepath.Join(home, "config.toml")); err == nil {
+		if tc, err := models.ParseConfigToml(data); err == nil && tc.Retention != nil &&
+			tc.Retention.SessionDays != nil && *tc.Retention.SessionDays > 0 {
+			age = time.Duration(*tc.Retention.SessionDays) * 24 * time.Hour
+		}
+	}
+
+	var harnessIDs []string
+	if *harnesses != "" {
+		for _, id := range strings.Split(*harnesses, ",") {
+			harnessIDs = append(harnessIDs, strings.TrimSpace(id))
+		}
+	}
+	if *template == "" {
+		*template = os.Getenv("TCX_WORKFLOW_ID_TEMPLATE")
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	return cli.RunSessionsPrune(cli.PruneOptions{
+		Connection:         conn,
+		OlderThan:          age,
+		DryRun:             *dryRun,
+		Yes:                *yes,
+		CodexHome:          home,
+		HarnessIDs:         harnessIDs,
+		Cwd:                cwd,
+		WorkflowIDTemplate: *template,
+		MemoryDbPath:       *memoryDb,
+	})
+}
+
+// runAdmin handles `tcx admin metrics` and `tcx admin reset`.
+func runAdmin() error {
+	const usage = "usage: tcx admin metrics [--query QUERY] [--format table|prometheus]\n       tcx admin reset <workflow-id> [--event-id N] [--reason TEXT]"
+	if len(os.Args) >= 3 && os.Args[2] == "reset" {
+		return runAdminReset()
+	}
+	if len(os.Args) < 3 || os.Args[2] != "metrics" {
+		return fmt.Errorf(usage)
+	}
+	fs := flag.NewFlagSet("admin metrics", flag.ExitOnError)
+	query := fs.String("query", "", "Extra visibility filter ANDed with the session query, e.g. \"StartTime > '2026-01-01T00:00:00Z'\"")
+	format := fs.String("format", "table", "Output format: table or prometheus")
+	var conn temporalclient.ConnectionConfig
+	conn.RegisterFlags(fs)
+	fs.Parse(os.Args[3:])
+
+	return cli.RunAdminMetrics(cli.MetricsOptions{
+		Connection: conn,
+		Query:      *query,
+		Format:     *format,
+	})
+}
+
+// runAdminReset handles `tcx admin reset <workflow-id>`.
+func runAdminReset() error {
+	fs := flag.NewFlagSet("admin reset", flag.ExitOnError)
+	eventID := fs.Int64("event-id", 0, "Workflow task event to reset to (default: the last good point of a stuck session)")
+	reason := fs.String("reason", "", "Reason recorded with the reset")
+	var conn temporalclient.ConnectionConfig
+	conn.RegisterFlags(fs)
+	fs.Parse(os.Args[3:])
+	// Flags may also follow the workflow ID.
+	workflowID := fs.Arg(0)
+	if fs.NArg() > 1 {
+		fs.Parse(fs.Args()[1:])
+	}
+	if workflowID == "" || fs.NArg() > 0 {
+		return fmt.Errorf("usage: tcx admin reset <workflow-id> [--event-id N] [--reason TEXT]")
+	}
+
+	return cli.RunAdminReset(cli.ResetOptions{
+		Connection: conn,
+		WorkflowID: workflowID,
+		EventID:    *eventID,
+		Reason:     *reason,
+	})
+}
+
+// runAudit handles `tcx audit export`.
+func runAudit() error {
+	const usage = "usage: tcx audit export (--session <id> | --all [--query QUERY]) [--format csv|json] [--output FILE]"
+	if len(os.Args) < 3 || os.Args[2] != "export" {
+		return fmt.Errorf(usage)
+	}
+	fs := flag.NewFlagSet("audit export", flag.ExitOnError)
+	session := fs.String("session", "", "Session (workflow) ID to export")
+	all := fs.Bool("all", false, "Export every session")
+	query := fs.String("query", "", "With --all: extra visibility filter ANDed with the session query, e.g. \"StartTime > '2026-01-01T00:00:00Z'\"")
+	format := fs.String("format", "csv", "Output format: csv or json")
+	output := fs.String("output", "", "Write to this file instead of stdout")
+	var conn temporalclient.ConnectionConfig
+	conn.RegisterFlags(fs)
+	fs.Parse(os.Args[3:])
+	if (*session == "") == !*all {
+		return fmt.Errorf(usage)
+	}
+
+	opts := cli.AuditExportOptions{
+		Connection: conn,
+		SessionID:  *session,
+		All:        *all,
+		Query:      *query,
+		Format:     *format,
+	}
+	if *output == "" {
+		return cli.RunAuditExport(opts)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	opts.Out = f
+	if err := cli.RunAuditExport(opts); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// runDemo handles `tcx demo`.
+func runDemo() error {
+	fs := flag.NewFlagSet("demo", flag.ExitOnError)
+	temporalCLI := fs.String("temporal-cli", "", "Path to the temporal CLI running the dev server (default: temporal on PATH, else downloaded once)")
+	inline := fs.Bool("inline", false, "Disable alt-screen mode (inline output)")
+	noColor := fs.Bool("no-color", false, "Disable colored output")
+	fs.Parse(os.Args[2:])
+
+	return demo.Run(demo.Options{
+		TemporalCLI: *temporalCLI,
+		Inline:      *inline,
+		NoColor:     *noColor,
+	})
+}
+
+// runSchema handles `tcx schema`. It prints the JSON Schemas of the
+// workflow's client payloads, or writes one <Name>.json file per payload
+// into --out.
+func runSchema() error {
+	fs := flag.NewFlagSet("schema", flag.ExitOnError)
+	out := fs.String("out", "", "Directory to write <Name>.json files into (default: print to stdout)")
+	fs.Parse(os.Args[2:])
+
+	schemas, err := workflow.ContractSchemas()
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(schemas))
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if *out != "" {
+		if err := os.MkdirAll(*out, 0o755); err != nil {
+			return err
+		}
+	}
+	for _, name := range names {
+		data, err := workflow.MarshalContractSchema(schemas[name])
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if *out == "" {
+			os.Stdout.Write(data)
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(*out, name+".json"), data, 0o644); err != nil {
+			return err
+		}
+	}
+	if *out != "" {
+		fmt.Printf("Wrote %d schemas (protocol version %d) to %s\n", len(names), workflow.ProtocolVersion, *out)
+	}
+	return nil
+}
+
 // runStartCrew starts a crew session.
 func runStartCrew() error {
 	fs := flag.NewFlagSet("start-crew", flag.ExitOnError)
 	codexHome := fs.String("codex-home", "", "Path to codex config directory (default: ~/.codex)")
 	model := fs.String("model", "", "Override model (default: from crew definition)")
 	provider := fs.String("provider", "", "LLM provider override")
-	temporalHost := fs.String("temporal-host", "", "Temporal server address")
 	inline := fs.Bool("inline", false, "Disable alt-screen mode")
+	output := fs.String("output", "auto", "Output: auto (plain when stdout or stderr is not a terminal), tty (interactive TUI), or plain (text output and progress lines, no ANSI)")
 	fullAuto := fs.Bool("full-auto", false, "Auto-approve all tool calls")
 	noMarkdown := fs.Bool("no-markdown", false, "Disable markdown rendering")
 	noColor := fs.Bool("no-color", false, "Disable colored output")
 	connTimeout := fs.Duration("connection-timeout", 0, "Per-RPC timeout for Temporal calls")
 	memory := fs.Bool("memory", false, "Enable cross-session memory subsystem")
 	memoryDb := fs.String("memory-db", "", "Path to memory SQLite DB")
+	var conn temporalclient.ConnectionConfig
+	conn.RegisterFlags(fs)
+	workflowIDOpts := registerWorkflowIDFlags(fs)
 
 	// Custom parsing for --input flags (can appear multiple times).
 	var inputFlags []string
@@ -239,6 +696,15 @@ func runStartCrew() error {
 
 	fs.Parse(filteredArgs)
 
+	wfID, err := workflowIDOpts()
+	if err != nil {
+		return err
+	}
+	outputMode, err := cli.ParseOutputMode(*output)
+	if err != nil {
+		return fmt.Errorf("--output: %w", err)
+	}
+
 	if fs.NArg() < 1 {
 		fmt.Fprintf(os.Stderr, "Usage: tcx start-crew <name> [--input key=value]...\n")
 		os.Exit(1)
@@ -304,21 +770,32 @@ func runStartCrew() error {
 		resolvedApproval = models.ApprovalUnlessTrusted
 	}
 
+	timezone, locale := userTimezone(*codexHome)
+	personaConfig := filepath.Join(resolveCodexHome(*codexHome), "config.toml")
+	cwd, _ := os.Getwd()
+	resolvedPersona, _ := cli.ResolvePersona(personaConfig, cwd, "")
 	cliConfig := cli.Config{
-		TemporalHost: *temporalHost,
-		Message:      msg,
-		Model:        resolvedModel,
-		NoMarkdown:   *noMarkdown,
-		NoColor:      *noColor,
+		Connection: conn,
+		Message:    msg,
+		Model:      resolvedModel,
+		NoMarkdown: *noMarkdown,
+		NoColor:    *noColor,
+		Markdown:   markdownOptions(*codexHome),
 		Permissions: models.Permissions{
 			ApprovalMode: resolvedApproval,
 		},
 		CodexHome:         *codexHome,
 		Provider:          resolvedProvider,
 		Inline:            *inline,
+		OutputMode:        outputMode,
 		MemoryEnabled:     *memory,
 		MemoryDbPath:      *memoryDb,
 		ConnectionTimeout: *connTimeout,
+		WorkflowID:        wfID,
+		Timezone:          timezone,
+		Locale:            locale,
+		Persona:           resolvedPersona,
+		PersonaConfigPath: personaConfig,
 
 		// Crew-specific fields — lightweight, no upfront interpolation.
 		CrewName:   crew.Name,
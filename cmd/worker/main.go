@@ -4,18 +4,27 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"os"
 	"path/filepath"
+	"time"
 
 	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/worker"
 
 	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/agentservice"
+	"github.com/mfateev/temporal-agent-harness/internal/blobstore"
+	"github.com/mfateev/temporal-agent-harness/internal/capabilities"
 	"github.com/mfateev/temporal-agent-harness/internal/execsession"
 	"github.com/mfateev/temporal-agent-harness/internal/llm"
 	"github.com/mfateev/temporal-agent-harness/internal/mcp"
 	"github.com/mfateev/temporal-agent-harness/internal/memories"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/spool"
+	"github.com/mfateev/temporal-agent-harness/internal/telemetry"
 	"github.com/mfateev/temporal-agent-harness/internal/temporalclient"
 	"github.com/mfateev/temporal-agent-harness/internal/tools"
 	"github.com/mfateev/temporal-agent-harness/internal/tools/handlers"
@@ -25,9 +34,20 @@ import (
 
 const (
 	TaskQueue = "temporal-agent-harness"
+
+	// spoolFlushInterval is how often queued side effects are retried.
+	spoolFlushInterval = 30 * time.Second
 )
 
 func main() {
+	var conn temporalclient.ConnectionConfig
+	conn.RegisterFlags(flag.CommandLine)
+	telemetryFlag := flag.Bool("telemetry", false,
+		"Send anonymous aggregate usage counts (sessions, turns, tool failures, model families; never content) to [telemetry] endpoint in config.toml. Off by default; --telemetry=false overrides config.toml. DO_NOT_TRACK=1 always disables it")
+	nexusCwd := flag.String("nexus-cwd", "",
+		"Working directory of sessions started through the tcx-agent Nexus service (default: the worker's working directory)")
+	flag.Parse()
+
 	// Check for at least one LLM provider API key
 	hasOpenAI := os.Getenv("OPENAI_API_KEY") != ""
 	hasAnthropic := os.Getenv("ANTHROPIC_API_KEY") != ""
@@ -43,17 +63,49 @@ func main() {
 		log.Println("Anthropic provider available")
 	}
 
-	// Load Temporal client options via envconfig (supports env vars, config files, TLS)
-	opts := temporalclient.MustLoadClientOptions("", "")
+	// Load Temporal client options via envconfig (supports env vars, config files, TLS),
+	// with command-line flags taking precedence.
+	opts, err := temporalclient.LoadClientOptionsFromConfig(conn)
+	if err != nil {
+		log.Fatalf("Failed to load Temporal client config: %v", err)
+	}
 
-	c, err := client.Dial(opts)
+	c, err := temporalclient.DialWithRetry(opts, conn.DialRetries, conn.DialRetryBackoff)
 	if err != nil {
 		log.Fatalf("Failed to create Temporal client: %v", err)
 	}
 	defer c.Close()
 
+	home, _ := os.UserHomeDir()
+
+	// Create tool registry with handlers
+	// Maps to: codex-rs/core/src/tools/registry.rs ToolRegistry setup
+	toolRegistry := tools.NewToolRegistry()
+
+	// Opt-in usage telemetry: aggregate counts recorded by an activity
+	// interceptor and reported in the background.
+	var workerOpts worker.Options
+	stopTelemetry := func() {}
+	telemetryCfg := resolveTelemetry(home, telemetryFlag)
+	if telemetryCfg.Enabled {
+		collector := telemetry.NewCollector(toolRegistry.HasTool)
+		workerOpts.Interceptors = append(workerOpts.Interceptors, collector.Interceptor())
+		telemetryCtx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			collector.Run(telemetryCtx, telemetryCfg.Endpoint, telemetryCfg.Interval, log.Printf)
+		}()
+		// Send the final report before exiting.
+		stopTelemetry = func() {
+			cancel()
+			<-done
+		}
+		log.Printf("Telemetry enabled (endpoint: %q, every %s)", telemetryCfg.Endpoint, telemetryCfg.Interval)
+	}
+
 	// Create worker
-	w := worker.New(c, TaskQueue, worker.Options{})
+	w := worker.New(c, TaskQueue, workerOpts)
 
 	// Register workflows
 	w.RegisterWorkflow(workflow.AgenticWorkflow)
@@ -63,9 +115,6 @@ func main() {
 	w.RegisterWorkflow(workflow.SessionWorkflow)
 	w.RegisterWorkflow(workflow.SessionWorkflowContinued)
 
-	// Create tool registry with handlers
-	// Maps to: codex-rs/core/src/tools/registry.rs ToolRegistry setup
-	toolRegistry := tools.NewToolRegistry()
 	toolRegistry.Register(handlers.NewShellHandler())        // array-based "shell"
 	toolRegistry.Register(handlers.NewShellCommandHandler()) // string-based "shell_command"
 	toolRegistry.Register(handlers.NewReadFileTool())
@@ -93,28 +142,78 @@ func main() {
 	w.RegisterActivity(llmActivities.ExecuteLLMCall)
 	w.RegisterActivity(llmActivities.ExecuteCompact)
 	w.RegisterActivity(llmActivities.GenerateSuggestions)
+	w.RegisterActivity(llmActivities.GetProviderHealth)
 
 	toolActivities := activities.NewToolActivities(toolRegistry)
 	w.RegisterActivity(toolActivities.ExecuteTool)
+	w.RegisterActivity(toolActivities.PreviewPatch)
 
 	instructionActivities := activities.NewInstructionActivities()
 	w.RegisterActivity(instructionActivities.LoadWorkerInstructions)
+	w.RegisterActivity(instructionActivities.LoadGlossary)
 	w.RegisterActivity(instructionActivities.LoadPersonalInstructions)
 	w.RegisterActivity(instructionActivities.LoadExecPolicy)
 	w.RegisterActivity(instructionActivities.LoadConfigFile)
 	w.RegisterActivity(instructionActivities.LoadSkills)
 	w.RegisterActivity(instructionActivities.ReadSkillContent)
+	w.RegisterActivity(instructionActivities.ResolveFileMentions)
 
 	mcpActivities := activities.NewMcpActivities(mcpStore)
 	w.RegisterActivity(mcpActivities.InitializeMcpServers)
 	w.RegisterActivity(mcpActivities.CleanupMcpServers)
 
+	// Pre-turn context hooks registered by embedders (see internal/hooks)
+	hookActivities := activities.NewHookActivities()
+	w.RegisterActivity(hookActivities.RunPreTurnHooks)
+
+	scratchActivities := activities.NewScratchActivities()
+	w.RegisterActivity(scratchActivities.CleanupScratchDir)
+
+	// Best-effort side effects (history archive writes) are queued on disk
+	// when their sink is unavailable and retried in the background.
+	spoolDir := filepath.Join(home, ".codex", "spool")
+	sideEffects, err := spool.Open(spoolDir)
+	if err != nil {
+		log.Printf("Warning: failed to open spool at %s: %v (side effects are not buffered)", spoolDir, err)
+		sideEffects = nil
+	}
+	archiveActivities := activities.NewArchiveActivities(sideEffects)
+	// [storage] in config.toml may move archives to an object store, for
+	// workers whose disk does not outlive them.
+	store, storageCfg, err := blobstore.Load(filepath.Join(home, ".codex", "config.toml"), filepath.Join(home, ".codex"))
+	switch {
+	case err != nil:
+		log.Printf("Warning: %v (history archives stay on local disk)", err)
+	case storageCfg.Remote():
+		archiveActivities.WithStore(store)
+		log.Printf("Storage: %s", store.URI(""))
+	}
+	w.RegisterActivity(archiveActivities.ArchiveConversationItems)
+	w.RegisterActivity(archiveActivities.FlushArchiveWrites)
+	if sideEffects != nil {
+		metrics := opts.MetricsHandler
+		if metrics == nil {
+			metrics = client.MetricsNopHandler
+		}
+		spoolCtx, stopSpool := context.WithCancel(context.Background())
+		defer stopSpool()
+		go sideEffects.Run(spoolCtx, spoolFlushInterval, metrics, log.Printf)
+	}
+
+	caps := capabilities.Probe()
+	log.Printf("Worker capabilities: os=%s missing=%v sandbox=%v", caps.OS, caps.MissingBinaries, caps.SandboxAvailable)
+	capabilityActivities := activities.NewCapabilityActivities(caps)
+	w.RegisterActivity(capabilityActivities.GetWorkerCapabilities)
+
+	// End-of-session reports ([report] in the session's config.toml)
+	reportActivities := activities.NewReportActivities()
+	w.RegisterActivity(reportActivities.DeliverSessionReport)
+
 	execSessionActivities := activities.NewExecSessionActivities(execStore)
 	w.RegisterActivity(execSessionActivities.ListExecSessions)
 	w.RegisterActivity(execSessionActivities.CleanExecSessions)
 
 	// Memory activities (SQLite DB opened lazily on first use)
-	home, _ := os.UserHomeDir()
 	dbPath := filepath.Join(home, ".codex", "state.sqlite")
 	memoryDB, err := memories.OpenMemoryDB(dbPath)
 	if err != nil {
@@ -146,17 +245,51 @@ func main() {
 	// Register consolidation workflow
 	w.RegisterWorkflow(workflow.ConsolidationWorkflow)
 
+	// Nexus service for sessions started by workflows in other namespaces,
+	// reachable through a Nexus endpoint that targets this task queue.
+	// Callers choose only the model, persona and session type; the rest of
+	// the session's configuration is the worker's.
+	sessionCwd := *nexusCwd
+	if sessionCwd == "" {
+		sessionCwd, _ = os.Getwd()
+	}
+	w.RegisterNexusService(agentservice.NewService(workflow.CLIOverrides{Cwd: sessionCwd}))
+
 	// Start worker
 	log.Printf("Worker version: %s", version.GitCommit)
 	log.Printf("Starting worker on task queue: %s", TaskQueue)
 	if opts.HostPort != "" {
 		log.Printf("Temporal server: %s", opts.HostPort)
 	}
+	if opts.Namespace != "" {
+		log.Printf("Temporal namespace: %s", opts.Namespace)
+	}
 
 	err = w.Run(worker.InterruptCh())
+	stopTelemetry()
 	if err != nil {
 		log.Fatalf("Failed to start worker: %v", err)
 	}
 
 	log.Println("Worker stopped")
 }
+
+// resolveTelemetry reads the [telemetry] table of ~/.codex/config.toml and
+// applies the --telemetry flag when it was given.
+func resolveTelemetry(home string, telemetryFlag *bool) telemetry.Config {
+	var tc *models.TelemetryToml
+	if data, err := os.ReadFile(filepath.Join(home, ".codex", "config.toml")); err == nil {
+		if cfg, err := models.ParseConfigToml(data); err == nil {
+			tc = cfg.Telemetry
+		} else {
+			log.Printf("Warning: failed to parse config.toml: %v (telemetry stays off unless --telemetry is set)", err)
+		}
+	}
+	var override *bool
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "telemetry" {
+			override = telemetryFlag
+		}
+	})
+	return telemetry.Resolve(tc, override, os.Getenv("DO_NOT_TRACK"))
+}
@@ -27,10 +27,12 @@ import (
 	"go.temporal.io/sdk/worker"
 
 	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/capabilities"
 	"github.com/mfateev/temporal-agent-harness/internal/execsession"
 	"github.com/mfateev/temporal-agent-harness/internal/llm"
 	"github.com/mfateev/temporal-agent-harness/internal/mcp"
 	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/temporalclient"
 	"github.com/mfateev/temporal-agent-harness/internal/tools"
 	"github.com/mfateev/temporal-agent-harness/internal/tools/handlers"
 	"github.com/mfateev/temporal-agent-harness/internal/workflow"
@@ -105,8 +107,13 @@ func TestMain(m *testing.M) {
 	log.Println("E2E: Temporal server is ready")
 
 	// 4. Create Temporal client
-	var err error
-	temporalClient, err = client.Dial(client.Options{HostPort: TestHostPort})
+	// Same payload codec as tcx, which compresses large payloads; the
+	// in-process worker shares this client.
+	dataConverter, err := temporalclient.ConnectionConfig{}.DataConverter(nil)
+	if err != nil {
+		log.Fatalf("E2E: Failed to create data converter: %v", err)
+	}
+	temporalClient, err = client.Dial(client.Options{HostPort: TestHostPort, DataConverter: dataConverter})
 	if err != nil {
 		temporalCmd.Process.Kill()
 		log.Fatalf("E2E: Failed to create Temporal client: %v", err)
@@ -532,6 +539,7 @@ func createWorker(c client.Client) worker.Worker {
 	w.RegisterActivity(llmActivities.ExecuteLLMCall)
 	w.RegisterActivity(llmActivities.ExecuteCompact)
 	w.RegisterActivity(llmActivities.GenerateSuggestions)
+	w.RegisterActivity(llmActivities.GetProviderHealth)
 
 	toolActivities := activities.NewToolActivities(toolRegistry)
 	w.RegisterActivity(toolActivities.ExecuteTool)
@@ -543,11 +551,26 @@ func createWorker(c client.Client) worker.Worker {
 	w.RegisterActivity(instructionActivities.LoadConfigFile)
 	w.RegisterActivity(instructionActivities.LoadSkills)
 	w.RegisterActivity(instructionActivities.ReadSkillContent)
+	w.RegisterActivity(instructionActivities.ResolveFileMentions)
 
 	mcpActivities := activities.NewMcpActivities(mcpStore)
 	w.RegisterActivity(mcpActivities.InitializeMcpServers)
 	w.RegisterActivity(mcpActivities.CleanupMcpServers)
 
+	hookActivities := activities.NewHookActivities()
+	w.RegisterActivity(hookActivities.RunPreTurnHooks)
+
+	scratchActivities := activities.NewScratchActivities()
+	w.RegisterActivity(scratchActivities.CleanupScratchDir)
+
+	archiveActivities := activities.NewArchiveActivities(nil)
+	w.RegisterActivity(archiveActivities.ArchiveConversationItems)
+	w.RegisterActivity(archiveActivities.FlushArchiveWrites)
+	w.RegisterActivity(activities.NewReportActivities().DeliverSessionReport)
+
+	capabilityActivities := activities.NewCapabilityActivities(capabilities.Probe())
+	w.RegisterActivity(capabilityActivities.GetWorkerCapabilities)
+
 	execSessionActivities := activities.NewExecSessionActivities(execStore)
 	w.RegisterActivity(execSessionActivities.ListExecSessions)
 	w.RegisterActivity(execSessionActivities.CleanExecSessions)
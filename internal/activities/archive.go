@@ -0,0 +1,136 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+
+	"go.temporal.io/sdk/activity"
+
+	"github.com/mfateev/temporal-agent-harness/internal/archive"
+	"github.com/mfateev/temporal-agent-harness/internal/blobstore"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/spool"
+)
+
+// spoolKindArchive is the spool entry kind of archive batches.
+const spoolKindArchive = "archive"
+
+// ArchiveActivities writes conversation items that a session moves out of
+// workflow memory to its archive on the worker (see internal/archive).
+type ArchiveActivities struct {
+	spool *spool.Spool
+	// store receives the batches when the worker's [storage] table selects
+	// an object store; nil writes under each session's CodexHome.
+	store blobstore.Store
+}
+
+// NewArchiveActivities creates a new ArchiveActivities instance. With a
+// spool, batches that cannot be written are queued on the worker and
+// retried in the background instead of failing the activity; nil writes
+// directly.
+func NewArchiveActivities(sp *spool.Spool) *ArchiveActivities {
+	a := &ArchiveActivities{spool: sp}
+	if sp != nil {
+		sp.Handle(spoolKindArchive, a.writeArchiveBatch)
+	}
+	return a
+}
+
+// WithStore writes batches to store instead of the local disk, for
+// workers whose disk does not outlive them.
+func (a *ArchiveActivities) WithStore(store blobstore.Store) *ArchiveActivities {
+	a.store = store
+	return a
+}
+
+// write stores one batch in the configured store, or under CodexHome.
+func (a *ArchiveActivities) write(ctx context.Context, input ArchiveItemsInput) error {
+	if a.store != nil {
+		_, err := archive.WriteTo(ctx, a.store, input.SessionID, input.First, input.Items)
+		return err
+	}
+	_, err := archive.Write(input.CodexHome, input.SessionID, input.First, input.Items)
+	return err
+}
+
+// ArchiveItemsInput is the input for the ArchiveConversationItems activity.
+type ArchiveItemsInput struct {
+	SessionID string `json:"session_id"`
+	// CodexHome locates the archive root; empty uses ~/.codex.
+	CodexHome string `json:"codex_home,omitempty"`
+	// First is the archive index of the first item, which names the batch.
+	First int                       `json:"first"`
+	Items []models.ConversationItem `json:"items"`
+}
+
+// ArchiveItemsOutput is the output of the ArchiveConversationItems activity.
+type ArchiveItemsOutput struct {
+	// Dir is the session's archive directory, or its object store URI.
+	Dir string `json:"dir"`
+	// Queued is set when the batch was spooled for a later write.
+	Queued bool `json:"queued,omitempty"`
+}
+
+// ArchiveConversationItems writes one batch of items to the session's
+// archive. Idempotent: a retry overwrites the same batch.
+func (a *ArchiveActivities) ArchiveConversationItems(ctx context.Context, input ArchiveItemsInput) (ArchiveItemsOutput, error) {
+	if input.CodexHome == "" {
+		input.CodexHome = defaultCodexHome()
+	}
+	out := ArchiveItemsOutput{Dir: archive.Dir(input.CodexHome, input.SessionID)}
+	if a.store != nil {
+		out.Dir = a.store.URI(archive.Key(input.SessionID))
+	}
+	if a.spool == nil {
+		return out, a.write(ctx, input)
+	}
+
+	payload, err := json.Marshal(input)
+	if err != nil {
+		return ArchiveItemsOutput{}, err
+	}
+	queued, err := a.spool.Deliver(ctx, spoolKindArchive, payload)
+	if err != nil {
+		return ArchiveItemsOutput{}, err
+	}
+	if queued {
+		activity.GetLogger(ctx).Warn("Archive unavailable; batch queued for retry",
+			"session_id", input.SessionID, "first", input.First)
+	}
+	a.spool.ReportBacklog(activity.GetMetricsHandler(ctx))
+	out.Queued = queued
+	return out, nil
+}
+
+// FlushArchiveWritesOutput is the output of the FlushArchiveWrites activity.
+type FlushArchiveWritesOutput struct {
+	// Delivered is the number of queued batches written by this flush.
+	Delivered int `json:"delivered"`
+	// Pending is the number of batches still queued afterwards.
+	Pending int `json:"pending"`
+}
+
+// FlushArchiveWrites retries the archive batches queued on this worker, so
+// a session's transcript is complete before the session ends. A batch that
+// still cannot be written stays queued for the background retry; this is
+// reported in Pending, not as an error.
+func (a *ArchiveActivities) FlushArchiveWrites(ctx context.Context) (FlushArchiveWritesOutput, error) {
+	if a.spool == nil {
+		return FlushArchiveWritesOutput{}, nil
+	}
+	delivered, err := a.spool.Flush(ctx)
+	if err != nil {
+		activity.GetLogger(ctx).Warn("Queued archive batches not flushed", "error", err)
+	}
+	a.spool.ReportBacklog(activity.GetMetricsHandler(ctx))
+	return FlushArchiveWritesOutput{Delivered: delivered, Pending: a.spool.Backlog()[spoolKindArchive]}, nil
+}
+
+// writeArchiveBatch is the spool handler for archive batches.
+func (a *ArchiveActivities) writeArchiveBatch(ctx context.Context, payload []byte) error {
+	var input ArchiveItemsInput
+	if err := json.Unmarshal(payload, &input); err != nil {
+		return spool.Permanent(err)
+	}
+	return a.write(ctx, input)
+}
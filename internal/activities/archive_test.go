@@ -0,0 +1,54 @@
+package activities
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/mfateev/temporal-agent-harness/internal/archive"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/spool"
+)
+
+func TestArchiveConversationItems_QueuesWhileArchiveUnavailable(t *testing.T) {
+	sp, err := spool.Open(t.TempDir())
+	require.NoError(t, err)
+	a := NewArchiveActivities(sp)
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(a.ArchiveConversationItems)
+
+	// A file where the archive root should be makes writes fail.
+	home := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(home, "archive"), nil, 0o600))
+
+	input := ArchiveItemsInput{
+		SessionID: "sess",
+		CodexHome: home,
+		Items:     []models.ConversationItem{{Type: models.ItemTypeUserMessage, Content: "hello"}},
+	}
+	val, err := env.ExecuteActivity(a.ArchiveConversationItems, input)
+	require.NoError(t, err, "an unavailable archive does not fail the activity")
+	var out ArchiveItemsOutput
+	require.NoError(t, val.Get(&out))
+	assert.True(t, out.Queued)
+	assert.Equal(t, map[string]int{spoolKindArchive: 1}, sp.Backlog())
+
+	// Once the archive is writable the queued batch is delivered.
+	require.NoError(t, os.Remove(filepath.Join(home, "archive")))
+	env.RegisterActivity(a.FlushArchiveWrites)
+	val, err = env.ExecuteActivity(a.FlushArchiveWrites)
+	require.NoError(t, err)
+	var flushed FlushArchiveWritesOutput
+	require.NoError(t, val.Get(&flushed))
+	assert.Equal(t, FlushArchiveWritesOutput{Delivered: 1}, flushed)
+	items, err := archive.Read(home, "sess")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "hello", items[0].Content)
+}
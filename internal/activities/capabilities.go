@@ -0,0 +1,24 @@
+package activities
+
+import (
+	"context"
+
+	"github.com/mfateev/temporal-agent-harness/internal/capabilities"
+)
+
+// CapabilityActivities publishes the worker's probed capabilities.
+type CapabilityActivities struct {
+	caps capabilities.Capabilities
+}
+
+// NewCapabilityActivities creates a CapabilityActivities instance serving
+// the result of a startup capabilities.Probe.
+func NewCapabilityActivities(caps capabilities.Capabilities) *CapabilityActivities {
+	return &CapabilityActivities{caps: caps}
+}
+
+// GetWorkerCapabilities returns what this worker can run. Dispatched on the
+// session task queue so the answer comes from the worker that runs tools.
+func (a *CapabilityActivities) GetWorkerCapabilities(_ context.Context) (capabilities.Capabilities, error) {
+	return a.caps, nil
+}
@@ -37,7 +37,11 @@ type ListExecSessionsResponse struct {
 }
 
 // CleanExecSessionsRequest is the payload for the CleanExecSessions activity.
-type CleanExecSessionsRequest struct{}
+// SessionID limits the cleanup to the exec sessions started by that agent
+// session; empty closes every exec session on the worker.
+type CleanExecSessionsRequest struct {
+	SessionID string `json:"session_id,omitempty"`
+}
 
 // CleanExecSessionsResponse is the output of the CleanExecSessions activity.
 type CleanExecSessionsResponse struct {
@@ -61,8 +65,11 @@ func (a *ExecSessionActivities) ListExecSessions(_ context.Context, _ ListExecSe
 	return ListExecSessionsResponse{Sessions: summaries}, nil
 }
 
-// CleanExecSessions closes all exec sessions and returns the count.
-func (a *ExecSessionActivities) CleanExecSessions(_ context.Context, _ CleanExecSessionsRequest) (CleanExecSessionsResponse, error) {
+// CleanExecSessions closes the requested exec sessions and returns the count.
+func (a *ExecSessionActivities) CleanExecSessions(_ context.Context, req CleanExecSessionsRequest) (CleanExecSessionsResponse, error) {
+	if req.SessionID != "" {
+		return CleanExecSessionsResponse{Closed: a.store.CloseOwned(req.SessionID)}, nil
+	}
 	closed := a.store.CloseAll()
 	return CleanExecSessionsResponse{Closed: closed}, nil
 }
@@ -0,0 +1,62 @@
+// Package activities contains Temporal activity implementations.
+//
+// hooks.go runs embedder-registered pre-turn hooks (see internal/hooks).
+package activities
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mfateev/temporal-agent-harness/internal/hooks"
+)
+
+// PreTurnHooksInput is the input for the RunPreTurnHooks activity.
+type PreTurnHooksInput struct {
+	Hooks []string           `json:"hooks"`
+	Turn  hooks.PreTurnInput `json:"turn"`
+}
+
+// PreTurnHooksOutput merges the output of all hooks that ran.
+type PreTurnHooksOutput struct {
+	hooks.PreTurnOutput
+
+	// Errors lists hooks that were missing or failed, as "name: error".
+	// A failing hook never fails the activity; the turn proceeds without it.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// HookActivities contains pre-turn hook activities.
+type HookActivities struct{}
+
+// NewHookActivities creates a new HookActivities instance.
+func NewHookActivities() *HookActivities {
+	return &HookActivities{}
+}
+
+// RunPreTurnHooks runs the named hooks in order and merges their output.
+// Developer instructions are joined with blank lines; items are concatenated.
+func (a *HookActivities) RunPreTurnHooks(ctx context.Context, input PreTurnHooksInput) (PreTurnHooksOutput, error) {
+	var out PreTurnHooksOutput
+	var instructions []string
+
+	for _, name := range input.Hooks {
+		hook, ok := hooks.GetPreTurnHook(name)
+		if !ok {
+			out.Errors = append(out.Errors, fmt.Sprintf("%s: not registered on this worker", name))
+			continue
+		}
+		res, err := hook(ctx, input.Turn)
+		if err != nil {
+			out.Errors = append(out.Errors, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		if s := strings.TrimSpace(res.DeveloperInstructions); s != "" {
+			instructions = append(instructions, s)
+		}
+		out.Items = append(out.Items, res.Items...)
+	}
+
+	out.DeveloperInstructions = strings.Join(instructions, "\n\n")
+	return out, nil
+}
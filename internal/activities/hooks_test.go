@@ -0,0 +1,39 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/hooks"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+func TestRunPreTurnHooks_MergesOutput(t *testing.T) {
+	hooks.RegisterPreTurnHook("test-sprint", func(_ context.Context, in hooks.PreTurnInput) (hooks.PreTurnOutput, error) {
+		return hooks.PreTurnOutput{
+			DeveloperInstructions: "Sprint 42 for " + in.ConversationID,
+			Items:                 []models.ConversationItem{{Type: models.ItemTypeUserMessage, Content: "flags: x=on"}},
+		}, nil
+	})
+	hooks.RegisterPreTurnHook("test-flags", func(_ context.Context, _ hooks.PreTurnInput) (hooks.PreTurnOutput, error) {
+		return hooks.PreTurnOutput{DeveloperInstructions: "  Feature y is off.  "}, nil
+	})
+	hooks.RegisterPreTurnHook("test-broken", func(_ context.Context, _ hooks.PreTurnInput) (hooks.PreTurnOutput, error) {
+		return hooks.PreTurnOutput{}, errors.New("boom")
+	})
+
+	out, err := NewHookActivities().RunPreTurnHooks(context.Background(), PreTurnHooksInput{
+		Hooks: []string{"test-sprint", "test-broken", "test-missing", "test-flags"},
+		Turn:  hooks.PreTurnInput{ConversationID: "conv-1"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Sprint 42 for conv-1\n\nFeature y is off.", out.DeveloperInstructions)
+	require.Len(t, out.Items, 1)
+	assert.Equal(t, "flags: x=on", out.Items[0].Content)
+	assert.Equal(t, []string{"test-broken: boom", "test-missing: not registered on this worker"}, out.Errors)
+}
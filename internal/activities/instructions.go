@@ -60,6 +60,41 @@ func (a *InstructionActivities) LoadWorkerInstructions(
 	}, nil
 }
 
+// LoadGlossaryInput is the input for the LoadGlossary activity.
+type LoadGlossaryInput struct {
+	Cwd string `json:"cwd"`
+}
+
+// LoadGlossaryOutput is the output from the LoadGlossary activity.
+type LoadGlossaryOutput struct {
+	// Content is the glossary file, empty if there is none.
+	Content string `json:"content,omitempty"`
+	// Root is the directory glossary paths are relative to: the git root,
+	// or Cwd outside a git repository.
+	Root string `json:"root,omitempty"`
+}
+
+// LoadGlossary reads the project glossary (instructions.GlossaryFile) at
+// the git root of Cwd, or in Cwd outside a git repository. Parsing happens
+// in the workflow. Non-fatal: a missing or unreadable file yields empty
+// output.
+func (a *InstructionActivities) LoadGlossary(
+	_ context.Context, input LoadGlossaryInput,
+) (LoadGlossaryOutput, error) {
+	if input.Cwd == "" {
+		return LoadGlossaryOutput{}, nil
+	}
+	root, err := instructions.FindGitRoot(input.Cwd)
+	if err != nil || root == "" {
+		root = input.Cwd
+	}
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(instructions.GlossaryFile)))
+	if err != nil {
+		return LoadGlossaryOutput{}, nil
+	}
+	return LoadGlossaryOutput{Content: string(data), Root: root}, nil
+}
+
 // LoadExecPolicyInput is the input for the LoadExecPolicy activity.
 type LoadExecPolicyInput struct {
 	CodexHome string `json:"codex_home"`
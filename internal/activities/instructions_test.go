@@ -132,3 +132,25 @@ func TestLoadConfigFile_EmptyCodexHome(t *testing.T) {
 	require.NoError(t, err)
 	_ = result // RawTOML may or may not be set depending on the environment
 }
+
+func TestLoadGlossary_FromGitRoot(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, ".git"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".codex"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".codex", "glossary.md"), []byte("- PSP: payment service provider\n"), 0o644))
+	sub := filepath.Join(dir, "services", "ledger")
+	require.NoError(t, os.MkdirAll(sub, 0o755))
+
+	a := NewInstructionActivities()
+	result, err := a.LoadGlossary(context.Background(), LoadGlossaryInput{Cwd: sub})
+	require.NoError(t, err)
+	assert.Equal(t, "- PSP: payment service provider\n", result.Content)
+	assert.Equal(t, dir, result.Root)
+}
+
+func TestLoadGlossary_MissingFile(t *testing.T) {
+	a := NewInstructionActivities()
+	result, err := a.LoadGlossary(context.Background(), LoadGlossaryInput{Cwd: t.TempDir()})
+	require.NoError(t, err)
+	assert.Empty(t, result.Content)
+}
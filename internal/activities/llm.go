@@ -5,6 +5,9 @@ package activities
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 
 	"github.com/mfateev/temporal-agent-harness/internal/instructions"
@@ -42,16 +45,24 @@ type LLMActivityOutput struct {
 
 	// OpenAI Responses API: response ID for chaining
 	ResponseID string `json:"response_id,omitempty"`
+
+	// IdempotencyKey identifies this response so the workflow can detect a
+	// replayed result. Derived from ResponseID when present, else a content hash.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+
+	// RateLimits is the provider's rate-limit headroom after this call.
+	RateLimits *models.RateLimitSnapshot `json:"rate_limits,omitempty"`
 }
 
 // LLMActivities contains LLM-related activities.
 type LLMActivities struct {
 	client llm.LLMClient
+	health *llm.HealthTracker
 }
 
 // NewLLMActivities creates a new LLMActivities instance.
 func NewLLMActivities(client llm.LLMClient) *LLMActivities {
-	return &LLMActivities{client: client}
+	return &LLMActivities{client: client, health: llm.NewHealthTracker(llm.DefaultHealthWindow)}
 }
 
 // ExecuteLLMCall executes an LLM call and returns the complete response.
@@ -69,6 +80,7 @@ func (a *LLMActivities) ExecuteLLMCall(ctx context.Context, input LLMActivityInp
 	}
 
 	response, err := a.client.Call(ctx, request)
+	a.health.Record(input.ModelConfig.Provider, err)
 	if err != nil {
 		var activityErr *models.ActivityError
 		if errors.As(err, &activityErr) {
@@ -78,13 +90,40 @@ func (a *LLMActivities) ExecuteLLMCall(ctx context.Context, input LLMActivityInp
 	}
 
 	return LLMActivityOutput{
-		Items:        response.Items,
-		FinishReason: response.FinishReason,
-		TokenUsage:   response.TokenUsage,
-		ResponseID:   response.ResponseID,
+		Items:          response.Items,
+		FinishReason:   response.FinishReason,
+		TokenUsage:     response.TokenUsage,
+		ResponseID:     response.ResponseID,
+		IdempotencyKey: llmIdempotencyKey(response),
+		RateLimits:     response.RateLimits,
 	}, nil
 }
 
+// llmIdempotencyKey returns "resp:<id>" when the provider assigned a response
+// ID, otherwise "sha256:<hash>" of the response items.
+func llmIdempotencyKey(response llm.LLMResponse) string {
+	if response.ResponseID != "" {
+		return "resp:" + response.ResponseID
+	}
+	data, err := json.Marshal(response.Items)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// ProviderHealthOutput is the output of the GetProviderHealth activity.
+type ProviderHealthOutput struct {
+	Providers map[string]models.ProviderHealth `json:"providers"`
+}
+
+// GetProviderHealth reports this worker's recent LLM error rates per
+// provider, as recorded by ExecuteLLMCall.
+func (a *LLMActivities) GetProviderHealth(ctx context.Context) (ProviderHealthOutput, error) {
+	return ProviderHealthOutput{Providers: a.health.Snapshot()}, nil
+}
+
 // CompactActivityInput is the input for the compact activity.
 //
 // Maps to: codex-rs/core/src/compact.rs compact operation input
@@ -137,7 +176,8 @@ type SuggestionInput struct {
 
 // SuggestionOutput is the output from the GenerateSuggestions activity.
 type SuggestionOutput struct {
-	Suggestion string `json:"suggestion"` // Single suggestion or empty string
+	Suggestion string            `json:"suggestion"` // Single suggestion or empty string
+	TokenUsage models.TokenUsage `json:"token_usage"`
 }
 
 // GenerateSuggestions calls a cheap/fast LLM to generate a single prompt
@@ -167,11 +207,11 @@ func (a *LLMActivities) GenerateSuggestions(ctx context.Context, input Suggestio
 	for _, item := range response.Items {
 		if item.Type == models.ItemTypeAssistantMessage && item.Content != "" {
 			suggestion := instructions.ParseSuggestionResponse(item.Content)
-			return SuggestionOutput{Suggestion: suggestion}, nil
+			return SuggestionOutput{Suggestion: suggestion, TokenUsage: response.TokenUsage}, nil
 		}
 	}
 
-	return SuggestionOutput{}, nil
+	return SuggestionOutput{TokenUsage: response.TokenUsage}, nil
 }
 
 // EstimateContextUsage estimates if we're approaching context window limits.
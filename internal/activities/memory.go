@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/client"
@@ -177,6 +178,9 @@ func (a *MemoryActivities) UpsertStage1Output(ctx context.Context, input UpsertS
 // ListStage1Input is the input for the ListStage1Outputs activity.
 type ListStage1Input struct {
 	MaxCount int `json:"max_count"`
+	// RetentionDays, when set, deletes outputs older than that many days
+	// before listing, so their rollout summaries are pruned on disk.
+	RetentionDays int `json:"retention_days,omitempty"`
 }
 
 // ListStage1Output is the result of the ListStage1Outputs activity.
@@ -186,6 +190,16 @@ type ListStage1Result struct {
 
 // ListStage1Outputs returns recent stage-1 outputs from the database.
 func (a *MemoryActivities) ListStage1Outputs(ctx context.Context, input ListStage1Input) (ListStage1Result, error) {
+	if input.RetentionDays > 0 {
+		cutoff := time.Now().Add(-time.Duration(input.RetentionDays) * 24 * time.Hour).Unix()
+		deleted, err := a.db.DeleteStage1OutputsBefore(cutoff)
+		if err != nil {
+			return ListStage1Result{}, err
+		}
+		if deleted > 0 {
+			activity.GetLogger(ctx).Info("Deleted stage1 outputs past retention", "count", deleted)
+		}
+	}
 	outputs, err := a.db.ListStage1OutputsForGlobal(input.MaxCount)
 	if err != nil {
 		return ListStage1Result{}, err
@@ -377,6 +391,7 @@ type SignalConsolidationInput struct {
 	MemoryDbPath      string            `json:"memory_db_path"`
 	ModelConfig       models.ModelConfig `json:"model_config"`
 	MaxRawMemories    int               `json:"max_raw_memories"`
+	RetentionDays     int               `json:"retention_days,omitempty"`
 }
 
 // SignalConsolidation uses SignalWithStartWorkflow to send a signal to the
@@ -398,6 +413,7 @@ func (a *MemoryActivities) SignalConsolidation(ctx context.Context, input Signal
 		"memory_db_path":   input.MemoryDbPath,
 		"model_config":     input.ModelConfig,
 		"max_raw_memories":  maxRaw,
+		"retention_days":    input.RetentionDays,
 	}
 
 	_, err := a.temporalClient.SignalWithStartWorkflow(
@@ -0,0 +1,35 @@
+package activities
+
+import (
+	"context"
+
+	"github.com/mfateev/temporal-agent-harness/internal/exclusion"
+	"github.com/mfateev/temporal-agent-harness/internal/mentions"
+)
+
+// ResolveFileMentionsInput is the input for the ResolveFileMentions activity.
+type ResolveFileMentionsInput struct {
+	Cwd      string                 `json:"cwd"`
+	Mentions []mentions.FileMention `json:"mentions"`
+	Budget   mentions.Budget        `json:"budget,omitempty"`
+
+	// ExcludePaths are config-supplied path exclusion patterns.
+	ExcludePaths []string `json:"exclude_paths,omitempty"`
+}
+
+// ResolveFileMentionsOutput is the output from the ResolveFileMentions activity.
+type ResolveFileMentionsOutput struct {
+	Attachments []mentions.Attachment `json:"attachments,omitempty"`
+}
+
+// ResolveFileMentions reads the files and directories referenced by @path
+// mentions on the worker's filesystem. Per-mention failures are returned in
+// the attachment rather than failing the activity.
+func (a *InstructionActivities) ResolveFileMentions(
+	_ context.Context, input ResolveFileMentionsInput,
+) (ResolveFileMentionsOutput, error) {
+	return ResolveFileMentionsOutput{
+		Attachments: mentions.Resolve(input.Cwd, input.Mentions, input.Budget,
+			exclusion.Load(input.Cwd, input.ExcludePaths)),
+	}, nil
+}
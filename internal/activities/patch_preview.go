@@ -0,0 +1,43 @@
+package activities
+
+import (
+	"context"
+	"os"
+
+	"github.com/mfateev/temporal-agent-harness/internal/tools/patch"
+)
+
+// PatchPreviewInput is the input for the PreviewPatch activity.
+type PatchPreviewInput struct {
+	// Patch is the apply_patch "input" argument.
+	Patch string `json:"patch"`
+	// LineEndings is the line-ending policy the call would be applied with.
+	LineEndings string `json:"line_endings,omitempty"`
+}
+
+// PatchPreviewOutput is what applying the patch would do.
+type PatchPreviewOutput struct {
+	// Diff is a unified diff of the file operations that apply.
+	Diff string `json:"diff,omitempty"`
+	// Conflicts describes the operations that do not apply (or why the
+	// patch does not parse).
+	Conflicts []string `json:"conflicts,omitempty"`
+	// LinesChanged counts the lines the diff adds or removes.
+	LinesChanged int `json:"lines_changed,omitempty"`
+}
+
+// PreviewPatch applies an apply_patch call in memory against the current
+// file contents, for the approval prompt. Nothing is written. Relative
+// paths resolve against the worker's working directory, as in the
+// apply_patch handler.
+func (a *ToolActivities) PreviewPatch(_ context.Context, input PatchPreviewInput) (PatchPreviewOutput, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return PatchPreviewOutput{}, err
+	}
+	res, err := patch.Preview(input.Patch, cwd, patch.Options{LineEndings: input.LineEndings})
+	if err != nil {
+		return PatchPreviewOutput{Conflicts: []string{err.Error()}}, nil
+	}
+	return PatchPreviewOutput{Diff: res.Diff, Conflicts: res.Conflicts, LinesChanged: res.LinesChanged}, nil
+}
@@ -0,0 +1,175 @@
+package activities
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mfateev/temporal-agent-harness/internal/tools"
+)
+
+// readCacheNote is appended to read_file output served from the cache.
+const readCacheNote = "(cached, unchanged since previous read)"
+
+// Read cache limits. Files larger than maxCachedReadBytes are never cached,
+// a turn caches at most maxReadCacheTurnBytes of output, and at most
+// maxReadCacheSessions sessions are cached at once (oldest dropped first).
+const (
+	maxCachedReadBytes    = 4 << 20
+	maxReadCacheTurnBytes = 16 << 20
+	maxReadCacheSessions  = 256
+)
+
+// readCache serves repeated read_file calls within a turn from memory. An
+// entry is keyed by the call's arguments and working directory and is only
+// served while the file's size, mtime and content hash are unchanged, so
+// writes earlier in the turn are always seen. Only the current turn of each
+// session is kept.
+type readCache struct {
+	mu       sync.Mutex
+	sessions map[string]*turnReadCache
+	order    []string // session keys, least recently started turn first
+}
+
+// turnReadCache holds the cached reads of one session's current turn.
+type turnReadCache struct {
+	turnID  string
+	entries map[string]readCacheEntry
+	bytes   int
+}
+
+// readCacheEntry is one cached read_file result and the file state it was
+// produced from.
+type readCacheEntry struct {
+	size    int64
+	modTime time.Time
+	hash    [sha256.Size]byte
+	output  tools.ToolOutput
+}
+
+func newReadCache() *readCache {
+	return &readCache{sessions: make(map[string]*turnReadCache)}
+}
+
+// fileState is the size, mtime and content hash of a file.
+type fileState struct {
+	size    int64
+	modTime time.Time
+	hash    [sha256.Size]byte
+}
+
+// statReadFile returns the state of the file a read_file call reads, or
+// false when it cannot be cached.
+func statReadFile(input ToolActivityInput) (fileState, bool) {
+	path := readFilePath(input.Arguments)
+	if path == "" {
+		return fileState{}, false
+	}
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxCachedReadBytes {
+		return fileState{}, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || int64(len(data)) != info.Size() {
+		return fileState{}, false
+	}
+	return fileState{size: info.Size(), modTime: info.ModTime(), hash: sha256.Sum256(data)}, true
+}
+
+// readFilePath returns the path argument of a read_file call.
+func readFilePath(args map[string]interface{}) string {
+	for _, name := range []string{"file_path", "path"} {
+		if p, ok := args[name].(string); ok {
+			return p
+		}
+	}
+	return ""
+}
+
+// readCacheKey identifies a read_file call by its working directory and
+// arguments. json.Marshal sorts map keys, so equal arguments give equal keys.
+func readCacheKey(input ToolActivityInput) (string, bool) {
+	args, err := json.Marshal(input.Arguments)
+	if err != nil {
+		return "", false
+	}
+	return input.Cwd + "\x00" + string(args), true
+}
+
+// get returns the cached output of a call when the file is unchanged.
+func (c *readCache) get(input ToolActivityInput, state fileState) (tools.ToolOutput, bool) {
+	key, ok := readCacheKey(input)
+	if !ok {
+		return tools.ToolOutput{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	turn := c.sessions[input.SessionID]
+	if turn == nil || turn.turnID != input.TurnID {
+		return tools.ToolOutput{}, false
+	}
+	entry, ok := turn.entries[key]
+	if !ok || entry.size != state.size || !entry.modTime.Equal(state.modTime) || entry.hash != state.hash {
+		return tools.ToolOutput{}, false
+	}
+	return entry.output, true
+}
+
+// put caches a successful call's output. A new turn of a session replaces
+// the previous turn's entries.
+func (c *readCache) put(input ToolActivityInput, state fileState, output tools.ToolOutput) {
+	if output.Success == nil || !*output.Success {
+		return
+	}
+	key, ok := readCacheKey(input)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	turn := c.sessions[input.SessionID]
+	if turn == nil || turn.turnID != input.TurnID {
+		turn = &turnReadCache{turnID: input.TurnID, entries: make(map[string]readCacheEntry)}
+		c.startTurn(input.SessionID, turn)
+	}
+	if old, ok := turn.entries[key]; ok {
+		turn.bytes -= len(old.output.Content)
+	}
+	if turn.bytes+len(output.Content) > maxReadCacheTurnBytes {
+		delete(turn.entries, key)
+		return
+	}
+	turn.entries[key] = readCacheEntry{size: state.size, modTime: state.modTime, hash: state.hash, output: output}
+	turn.bytes += len(output.Content)
+}
+
+// startTurn installs turn as the session's cache and evicts the oldest
+// sessions beyond maxReadCacheSessions. Caller holds mu.
+func (c *readCache) startTurn(sessionID string, turn *turnReadCache) {
+	if _, ok := c.sessions[sessionID]; ok {
+		for i, id := range c.order {
+			if id == sessionID {
+				c.order = append(c.order[:i], c.order[i+1:]...)
+				break
+			}
+		}
+	}
+	c.sessions[sessionID] = turn
+	c.order = append(c.order, sessionID)
+	for len(c.order) > maxReadCacheSessions {
+		delete(c.sessions, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+// withReadCacheNote returns output annotated as served from the cache.
+func withReadCacheNote(output tools.ToolOutput) tools.ToolOutput {
+	if !strings.HasSuffix(output.Content, "\n") {
+		output.Content += "\n"
+	}
+	output.Content += readCacheNote
+	return output
+}
@@ -0,0 +1,109 @@
+package activities
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/tools"
+)
+
+// countingReadFile is a read_file handler that returns the file's contents
+// and counts its calls.
+type countingReadFile struct{ calls int }
+
+func (h *countingReadFile) Name() string                          { return "read_file" }
+func (h *countingReadFile) Kind() tools.ToolKind                  { return tools.ToolKindFunction }
+func (h *countingReadFile) IsMutating(*tools.ToolInvocation) bool { return false }
+func (h *countingReadFile) Handle(_ context.Context, inv *tools.ToolInvocation) (*tools.ToolOutput, error) {
+	h.calls++
+	data, err := os.ReadFile(inv.Arguments["file_path"].(string))
+	success := err == nil
+	return &tools.ToolOutput{Content: string(data), Success: &success}, nil
+}
+
+func newReadCacheTest(t *testing.T) (*ToolActivities, *countingReadFile, string) {
+	handler := &countingReadFile{}
+	registry := tools.NewToolRegistry()
+	registry.Register(handler)
+	path := filepath.Join(t.TempDir(), "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0o644))
+	return NewToolActivities(registry), handler, path
+}
+
+func readInput(path, turnID string) ToolActivityInput {
+	return ToolActivityInput{
+		CallID:    "call-" + turnID,
+		ToolName:  "read_file",
+		Arguments: map[string]interface{}{"file_path": path},
+		SessionID: "session-1",
+		TurnID:    turnID,
+	}
+}
+
+func TestExecuteTool_ReadCacheServesRepeatedRead(t *testing.T) {
+	a, handler, path := newReadCacheTest(t)
+
+	first, err := a.ExecuteTool(context.Background(), readInput(path, "turn-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n", first.Content)
+
+	second, err := a.ExecuteTool(context.Background(), readInput(path, "turn-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n"+readCacheNote, second.Content)
+	assert.True(t, *second.Success)
+	assert.Equal(t, 1, handler.calls)
+
+	// A new turn starts with an empty cache.
+	third, err := a.ExecuteTool(context.Background(), readInput(path, "turn-2"))
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n", third.Content)
+	assert.Equal(t, 2, handler.calls)
+}
+
+func TestExecuteTool_ReadCacheSeesChanges(t *testing.T) {
+	a, handler, path := newReadCacheTest(t)
+
+	_, err := a.ExecuteTool(context.Background(), readInput(path, "turn-1"))
+	require.NoError(t, err)
+
+	// Same size and mtime, different content: the hash catches it.
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, []byte("package test\n"), 0o644))
+	require.NoError(t, os.Chtimes(path, time.Now(), info.ModTime()))
+
+	out, err := a.ExecuteTool(context.Background(), readInput(path, "turn-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "package test\n", out.Content)
+	assert.Equal(t, 2, handler.calls)
+}
+
+func TestExecuteTool_ReadCacheNeedsTurn(t *testing.T) {
+	a, handler, path := newReadCacheTest(t)
+
+	for i := 0; i < 2; i++ {
+		out, err := a.ExecuteTool(context.Background(), readInput(path, ""))
+		require.NoError(t, err)
+		assert.Equal(t, "package main\n", out.Content)
+	}
+	assert.Equal(t, 2, handler.calls)
+}
+
+func TestReadCache_EvictsOldestSession(t *testing.T) {
+	c := newReadCache()
+	success := true
+	out := tools.ToolOutput{Content: "x", Success: &success}
+	for i := 0; i <= maxReadCacheSessions; i++ {
+		in := ToolActivityInput{Arguments: map[string]interface{}{"file_path": "a"}, SessionID: string(rune('A' + i)), TurnID: "t"}
+		c.put(in, fileState{}, out)
+	}
+	assert.Len(t, c.sessions, maxReadCacheSessions)
+	_, ok := c.sessions["A"]
+	assert.False(t, ok)
+}
@@ -0,0 +1,241 @@
+package activities
+
+// remote.go runs the tool calls of sessions with a remote machine
+// (SessionConfiguration.Remote). Command tools run over ssh themselves
+// (ToolInvocation.Remote). The file tools are staged: the remote files a
+// call reads are fetched by sftp into a local mirror, the unchanged handler
+// runs against the mirror with its paths rewritten, and the files it
+// changed are uploaded again.
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/mfateev/temporal-agent-harness/internal/exclusion"
+	"github.com/mfateev/temporal-agent-harness/internal/remote"
+	"github.com/mfateev/temporal-agent-harness/internal/tools"
+	"github.com/mfateev/temporal-agent-harness/internal/tools/patch"
+)
+
+// listDirDefaultDepth matches the list_dir handler's default depth.
+const listDirDefaultDepth = 2
+
+// Patch headers that name files, as in the apply_patch grammar.
+var patchPathMarkers = []string{"*** Add File: ", "*** Delete File: ", "*** Update File: ", "*** Move to: "}
+
+// remoteChanges are the remote files a staged call changes once it
+// succeeds.
+type remoteChanges struct {
+	written []string
+	removed []string
+}
+
+// handleRemote runs a tool call on the remote machine t. An unsafe
+// destination or a key file outside ~/.ssh fails the call before ssh is run.
+func handleRemote(ctx context.Context, handler tools.ToolHandler, invocation *tools.ToolInvocation, t remote.Target) (*tools.ToolOutput, error) {
+	if err := t.Validate(); err != nil {
+		return nil, tools.NewValidationErrorf("remote: %v", err)
+	}
+	if t.KeyPath != "" {
+		keyPath, err := workerKeyPath(t.KeyPath)
+		if err != nil {
+			return nil, err
+		}
+		t.KeyPath = keyPath
+	}
+	switch invocation.ToolName {
+	case "read_file", "write_file", "apply_patch", "list_dir":
+	default:
+		invocation.Remote = &t
+		return handler.Handle(ctx, invocation)
+	}
+
+	mirror, err := remote.NewMirror(t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create remote mirror: %w", err)
+	}
+	defer mirror.Close()
+
+	changes, err := stageRemoteCall(ctx, mirror, invocation)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if tools.IsValidationError(err) {
+			return nil, err
+		}
+		return nil, tools.NewTransientError(err)
+	}
+	output, err := handler.Handle(ctx, invocation)
+	if err != nil {
+		return nil, err
+	}
+	if output.Success == nil || *output.Success {
+		if err := mirror.Push(ctx, changes.written...); err != nil {
+			return nil, tools.NewTransientError(err)
+		}
+		if err := mirror.Remove(ctx, changes.removed...); err != nil {
+			return nil, tools.NewTransientError(err)
+		}
+	}
+	output.Content = mirror.Strip(output.Content)
+	return output, nil
+}
+
+// workerKeyPath resolves a session's key file on the worker. KeyPath comes
+// from the client, so it may only name a file in the worker user's ~/.ssh
+// directory: ssh -i must not be pointed at other files on the worker.
+func workerKeyPath(keyPath string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", tools.NewValidationErrorf("remote: key path %s: %v", keyPath, err)
+	}
+	if rest, ok := strings.CutPrefix(keyPath, "~/"); ok {
+		keyPath = filepath.Join(home, rest)
+	}
+	sshDir, err := filepath.EvalSymlinks(filepath.Join(home, ".ssh"))
+	if err == nil {
+		var resolved string
+		if resolved, err = filepath.EvalSymlinks(keyPath); err == nil {
+			if rel, relErr := filepath.Rel(sshDir, resolved); relErr == nil && rel != "." && filepath.IsLocal(rel) {
+				return resolved, nil
+			}
+		}
+	}
+	return "", tools.NewValidationErrorf("remote: key path %s is not a file in ~/.ssh on the tool worker", keyPath)
+}
+
+// stageRemoteCall fetches what a file tool call needs into the mirror and
+// points the invocation at it. It returns the remote files to upload or
+// delete after the call. Paths that cannot be passed to sftp fail the call
+// with a validation error.
+func stageRemoteCall(ctx context.Context, mirror *remote.Mirror, invocation *tools.ToolInvocation) (remoteChanges, error) {
+	var changes remoteChanges
+	cwd := invocation.Cwd
+	if err := checkRemotePath(cwd); err != nil {
+		return changes, err
+	}
+	var fetch []string
+	if cwd != "" {
+		// The exclusion policy reads the .codexignore of the working
+		// directory; there is no git root to find in the mirror.
+		fetch = append(fetch, path.Join(cwd, exclusion.IgnoreFileName))
+		invocation.Cwd = mirror.Local(cwd)
+	}
+	// Copy the arguments: the caller's map must keep the remote paths.
+	args := make(map[string]interface{}, len(invocation.Arguments))
+	for k, v := range invocation.Arguments {
+		args[k] = v
+	}
+	invocation.Arguments = args
+
+	switch invocation.ToolName {
+	case "read_file", "write_file":
+		// read_file takes file_path (or the legacy path), write_file path.
+		for _, key := range []string{"file_path", "path"} {
+			arg, ok := args[key].(string)
+			if !ok || arg == "" {
+				continue
+			}
+			if err := checkRemotePath(arg); err != nil {
+				return changes, err
+			}
+			p := remotePath(cwd, arg)
+			fetch = append(fetch, p)
+			args[key] = mirror.Local(p)
+			if invocation.ToolName == "write_file" {
+				changes.written = append(changes.written, p)
+			}
+		}
+
+	case "list_dir":
+		p, ok := args["dir_path"].(string)
+		if !ok || !path.IsAbs(p) {
+			break
+		}
+		if err := checkRemotePath(p); err != nil {
+			return changes, err
+		}
+		depth := listDirDefaultDepth
+		if d, ok := args["depth"].(float64); ok && d >= 1 {
+			depth = int(d)
+		}
+		if err := mirror.FetchTree(ctx, p, depth); err != nil {
+			return changes, err
+		}
+		args["dir_path"] = mirror.Local(p)
+
+	case "apply_patch":
+		input, ok := args["input"].(string)
+		if !ok {
+			break
+		}
+		parsed, err := patch.Parse(input)
+		if err != nil {
+			// The handler reports the parse error.
+			break
+		}
+		for _, h := range parsed.Hunks {
+			if err := checkRemotePath(h.Path); err != nil {
+				return changes, err
+			}
+			if err := checkRemotePath(h.MovePath); err != nil {
+				return changes, err
+			}
+			p := remotePath(cwd, h.Path)
+			switch {
+			case h.Type == patch.HunkAdd:
+				changes.written = append(changes.written, p)
+			case h.Type == patch.HunkDelete:
+				fetch = append(fetch, p)
+				changes.removed = append(changes.removed, p)
+			case h.MovePath != "":
+				fetch = append(fetch, p)
+				changes.written = append(changes.written, remotePath(cwd, h.MovePath))
+				changes.removed = append(changes.removed, p)
+			default:
+				fetch = append(fetch, p)
+				changes.written = append(changes.written, p)
+			}
+		}
+		args["input"] = mirrorPatchPaths(input, cwd, mirror)
+	}
+
+	return changes, mirror.Fetch(ctx, fetch...)
+}
+
+// checkRemotePath rejects a path that cannot be passed to sftp safely.
+func checkRemotePath(p string) error {
+	if err := remote.ValidatePath(p); err != nil {
+		return tools.NewValidationErrorf("remote: %v", err)
+	}
+	return nil
+}
+
+// remotePath resolves a path argument against the remote working directory.
+func remotePath(cwd, p string) string {
+	if path.IsAbs(p) {
+		return path.Clean(p)
+	}
+	return path.Join(cwd, p)
+}
+
+// mirrorPatchPaths rewrites the file headers of an apply_patch input to the
+// mirror paths of the remote files they name.
+func mirrorPatchPaths(input, cwd string, mirror *remote.Mirror) string {
+	lines := strings.Split(input, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		for _, marker := range patchPathMarkers {
+			if p, ok := strings.CutPrefix(trimmed, marker); ok {
+				lines[i] = marker + mirror.Local(remotePath(cwd, p))
+				break
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
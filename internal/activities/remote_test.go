@@ -0,0 +1,84 @@
+package activities
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/remote"
+	"github.com/mfateev/temporal-agent-harness/internal/tools"
+)
+
+func TestHandleRemote_RejectsUnsafeTarget(t *testing.T) {
+	// The handler is never reached.
+	_, err := handleRemote(context.Background(), nil, &tools.ToolInvocation{ToolName: "shell"}, remote.Target{Host: "-oProxyCommand=sh"})
+	var validationErr *tools.ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+}
+
+func TestHandleRemote_RejectsControlCharacterPath(t *testing.T) {
+	// The paths are rejected before sftp runs or the handler is reached.
+	target := remote.Target{Host: "devbox"}
+	for _, invocation := range []*tools.ToolInvocation{
+		{ToolName: "read_file", Cwd: "/src/app", Arguments: map[string]interface{}{"file_path": "x\n!touch /tmp/pwned #"}},
+		{ToolName: "write_file", Cwd: "/src/app", Arguments: map[string]interface{}{"path": "a.go\r"}},
+		{ToolName: "read_file", Cwd: "/src/app\n!id", Arguments: map[string]interface{}{"file_path": "a.go"}},
+	} {
+		_, err := handleRemote(context.Background(), nil, invocation, target)
+		var validationErr *tools.ValidationError
+		assert.ErrorAs(t, err, &validationErr, invocation.Arguments)
+	}
+}
+
+func TestWorkerKeyPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	require.NoError(t, os.Mkdir(filepath.Join(home, ".ssh"), 0o700))
+	key := filepath.Join(home, ".ssh", "id_devbox")
+	require.NoError(t, os.WriteFile(key, []byte("key"), 0o600))
+	outside := filepath.Join(home, "secret")
+	require.NoError(t, os.WriteFile(outside, []byte("secret"), 0o600))
+	require.NoError(t, os.Symlink(outside, filepath.Join(home, ".ssh", "link")))
+
+	resolved, err := workerKeyPath("~/.ssh/id_devbox")
+	require.NoError(t, err)
+	wantKey, _ := filepath.EvalSymlinks(key)
+	assert.Equal(t, wantKey, resolved)
+	_, err = workerKeyPath(key)
+	assert.NoError(t, err)
+
+	var validationErr *tools.ValidationError
+	for _, p := range []string{outside, "/etc/passwd", "~/.ssh", "~/.ssh/link", "~/.ssh/missing"} {
+		_, err := workerKeyPath(p)
+		assert.ErrorAs(t, err, &validationErr, p)
+	}
+}
+
+func TestMirrorPatchPaths(t *testing.T) {
+	m := &remote.Mirror{Root: "/tmp/tcx-remote-1"}
+	input := "*** Begin Patch\n" +
+		"*** Update File: pkg/a.go\n" +
+		"*** Move to: pkg/b.go\n" +
+		"@@\n-old\n+new\n" +
+		"*** Add File: /src/other/c.go\n+package other\n" +
+		"*** Delete File: ../d.go\n" +
+		"*** End Patch"
+
+	want := "*** Begin Patch\n" +
+		"*** Update File: " + filepath.Join("/tmp/tcx-remote-1", "src/app/pkg/a.go") + "\n" +
+		"*** Move to: " + filepath.Join("/tmp/tcx-remote-1", "src/app/pkg/b.go") + "\n" +
+		"@@\n-old\n+new\n" +
+		"*** Add File: " + filepath.Join("/tmp/tcx-remote-1", "src/other/c.go") + "\n+package other\n" +
+		"*** Delete File: " + filepath.Join("/tmp/tcx-remote-1", "src/d.go") + "\n" +
+		"*** End Patch"
+	assert.Equal(t, want, mirrorPatchPaths(input, "/src/app", m))
+}
+
+func TestRemotePath(t *testing.T) {
+	assert.Equal(t, "/src/app/pkg/a.go", remotePath("/src/app", "pkg/a.go"))
+	assert.Equal(t, "/etc/hosts", remotePath("/src/app", "/etc/../etc/hosts"))
+}
@@ -0,0 +1,72 @@
+package activities
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+
+	"go.temporal.io/sdk/activity"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/report"
+)
+
+// ReportActivities delivers end-of-session reports (see internal/report).
+type ReportActivities struct {
+	deliverer *report.Deliverer
+}
+
+// NewReportActivities creates a new ReportActivities instance.
+func NewReportActivities() *ReportActivities {
+	return &ReportActivities{deliverer: report.NewDeliverer()}
+}
+
+// DeliverSessionReportInput is the input for the DeliverSessionReport
+// activity.
+type DeliverSessionReportInput struct {
+	Config models.SessionReportConfig `json:"config"`
+	Report report.Report              `json:"report"`
+}
+
+// DeliverSessionReportOutput lists the channels that received the report
+// and the errors of those that did not.
+type DeliverSessionReportOutput struct {
+	Delivered []string `json:"delivered,omitempty"`
+	Failed    []string `json:"failed,omitempty"`
+}
+
+// DeliverSessionReport adds the diffstat of the session's working tree to
+// the report and delivers it. A channel that fails is reported in the
+// output rather than as an error, so a retry does not deliver the report
+// twice to the channels that succeeded.
+func (a *ReportActivities) DeliverSessionReport(ctx context.Context, input DeliverSessionReportInput) (DeliverSessionReportOutput, error) {
+	r := input.Report
+	if r.Cwd != "" {
+		r.Diffstat, r.Untracked = workingTreeDiffstat(ctx, r.Cwd)
+	}
+	delivered, errs := a.deliverer.Deliver(ctx, input.Config, r)
+	out := DeliverSessionReportOutput{Delivered: delivered}
+	for _, err := range errs {
+		activity.GetLogger(ctx).Warn("Failed to deliver session report", "error", err)
+		out.Failed = append(out.Failed, err.Error())
+	}
+	return out, nil
+}
+
+// workingTreeDiffstat returns `git diff --stat HEAD` in cwd and the number
+// of untracked files. Both are empty outside a git work tree.
+func workingTreeDiffstat(ctx context.Context, cwd string) (string, int) {
+	stat, err := exec.CommandContext(ctx, "git", "-C", cwd, "diff", "--stat", "HEAD").Output()
+	if err != nil {
+		return "", 0
+	}
+	untracked := 0
+	if out, err := exec.CommandContext(ctx, "git", "-C", cwd, "ls-files", "--others", "--exclude-standard").Output(); err == nil {
+		for _, line := range strings.Split(string(out), "\n") {
+			if line != "" {
+				untracked++
+			}
+		}
+	}
+	return strings.TrimRight(string(stat), "\n"), untracked
+}
@@ -0,0 +1,56 @@
+package activities
+
+import (
+	"context"
+
+	"go.temporal.io/sdk/activity"
+
+	"github.com/mfateev/temporal-agent-harness/internal/scratch"
+	"github.com/mfateev/temporal-agent-harness/internal/tools"
+)
+
+// ScratchActivities manages per-session scratch directories on the worker.
+type ScratchActivities struct{}
+
+// NewScratchActivities creates a new ScratchActivities instance.
+func NewScratchActivities() *ScratchActivities {
+	return &ScratchActivities{}
+}
+
+// CleanupScratchDirInput is the input for the CleanupScratchDir activity.
+type CleanupScratchDirInput struct {
+	SessionID string `json:"session_id"`
+}
+
+// CleanupScratchDir removes a session's scratch directory. Called when the
+// workflow completes. Directories left by sessions that never cleaned up
+// are removed by `tcx sessions prune` once the session is known to be
+// closed, never by age alone: an idle session may still use its directory.
+func (a *ScratchActivities) CleanupScratchDir(ctx context.Context, input CleanupScratchDirInput) error {
+	logger := activity.GetLogger(ctx)
+	if err := scratch.Remove(input.SessionID); err != nil {
+		logger.Warn("Failed to remove scratch directory", "error", err)
+		return err
+	}
+	return nil
+}
+
+// ensureScratchDir creates the scratch directory for a tool call. Returns
+// "" when the call has no session (e.g. direct activity tests).
+func ensureScratchDir(scratchID string) (string, error) {
+	if scratchID == "" {
+		return "", nil
+	}
+	return scratch.Ensure(scratchID)
+}
+
+// withScratchWritable returns a copy of policy that also allows writes to
+// the scratch directory, so sandboxed commands can still use $SCRATCH.
+func withScratchWritable(policy *tools.SandboxPolicyRef, scratchDir string) *tools.SandboxPolicyRef {
+	if policy == nil || scratchDir == "" {
+		return policy
+	}
+	cp := *policy
+	cp.WritableRoots = append(append([]string(nil), policy.WritableRoots...), scratchDir)
+	return &cp
+}
@@ -24,7 +24,24 @@ type ToolActivityInput struct {
 
 	// MCP fields — populated for mcp__* tool calls.
 	McpToolRef *tools.McpToolRef `json:"mcp_tool_ref,omitempty"` // Server/tool routing
-	SessionID  string            `json:"session_id,omitempty"`   // Session ID for MCP store lookup
+	SessionID  string            `json:"session_id,omitempty"`   // Session ID for MCP store lookup, exec session ownership and the read cache
+
+	// ScratchID names the session scratch directory (see internal/scratch).
+	ScratchID string `json:"scratch_id,omitempty"`
+
+	// ExcludePaths are config-supplied path exclusion patterns.
+	ExcludePaths []string `json:"exclude_paths,omitempty"`
+
+	// LineEndings is the line-ending policy for file-writing tools.
+	LineEndings string `json:"line_endings,omitempty"`
+
+	// TurnID scopes per-turn caches on the worker, e.g. repeated read_file
+	// results (see readCache).
+	TurnID string `json:"turn_id,omitempty"`
+
+	// Remote runs the call on the session's remote machine over SSH (see
+	// remote.go); Cwd and path arguments are remote paths then.
+	Remote *models.RemoteConfig `json:"remote,omitempty"`
 }
 
 // ToolActivityOutput is the output from tool execution.
@@ -36,16 +53,23 @@ type ToolActivityOutput struct {
 	CallID  string `json:"call_id"`
 	Content string `json:"content,omitempty"`
 	Success *bool  `json:"success,omitempty"`
+	// ExitCode is the exit status of a command tool's process, if it exited.
+	ExitCode *int `json:"exit_code,omitempty"`
+
+	// DurationMs is the wall time from dispatch to completion, measured by
+	// the workflow (not the activity) so it includes queueing and retries.
+	DurationMs int64 `json:"duration_ms,omitempty"`
 }
 
 // ToolActivities contains tool-related activities.
 type ToolActivities struct {
 	registry *tools.ToolRegistry
+	reads    *readCache
 }
 
 // NewToolActivities creates a new ToolActivities instance.
 func NewToolActivities(registry *tools.ToolRegistry) *ToolActivities {
-	return &ToolActivities{registry: registry}
+	return &ToolActivities{registry: registry, reads: newReadCache()}
 }
 
 // ExecuteTool executes a single tool call.
@@ -70,24 +94,61 @@ func (a *ToolActivities) ExecuteTool(ctx context.Context, input ToolActivityInpu
 		return ToolActivityOutput{}, models.NewToolNotFoundError(input.ToolName)
 	}
 
+	// ExecuteTool is also called directly, outside an activity, by local
+	// sessions (see workflow.LocalSession); activity calls are skipped then.
+	inActivity := activity.IsActivity(ctx)
+
+	scratchDir, err := ensureScratchDir(input.ScratchID)
+	if err != nil && inActivity {
+		activity.GetLogger(ctx).Warn("Failed to create scratch directory", "error", err)
+	}
+
 	invocation := &tools.ToolInvocation{
 		CallID:        input.CallID,
 		ToolName:      input.ToolName,
 		Arguments:     input.Arguments,
 		Cwd:           input.Cwd,
-		SandboxPolicy: input.SandboxPolicy,
+		SandboxPolicy: withScratchWritable(input.SandboxPolicy, scratchDir),
 		EnvPolicy:     input.EnvPolicy,
 		McpToolRef:    input.McpToolRef,
 		SessionID:     input.SessionID,
-		Heartbeat: func(details ...interface{}) {
+		ScratchDir:    scratchDir,
+		ExcludePaths:  input.ExcludePaths,
+		LineEndings:   input.LineEndings,
+	}
+	if inActivity {
+		invocation.Heartbeat = func(details ...interface{}) {
 			activity.RecordHeartbeat(ctx, details...)
-		},
+		}
+	}
+
+	// Repeated reads of an unchanged file within a turn are served from
+	// the read cache.
+	var readState fileState
+	cacheRead := false
+	if input.ToolName == "read_file" && input.SessionID != "" && input.TurnID != "" && input.Remote == nil {
+		readState, cacheRead = statReadFile(input)
+	}
+	if cacheRead {
+		if cached, ok := a.reads.get(input, readState); ok {
+			cached = withReadCacheNote(cached)
+			return ToolActivityOutput{
+				CallID:  input.CallID,
+				Content: cached.Content,
+				Success: cached.Success,
+			}, nil
+		}
 	}
 
 	// Pass the activity context to the handler. Temporal manages timeouts
 	// via StartToCloseTimeout — when it fires, ctx is cancelled, the handler
 	// returns ctx.Err(), and Temporal retries per the RetryPolicy.
-	output, err := handler.Handle(ctx, invocation)
+	var output *tools.ToolOutput
+	if input.Remote != nil {
+		output, err = handleRemote(ctx, handler, invocation, input.Remote.Target())
+	} else {
+		output, err = handler.Handle(ctx, invocation)
+	}
 	if err != nil {
 		// Context errors (deadline/cancellation) are returned as-is so
 		// Temporal recognizes them and applies the retry policy.
@@ -107,10 +168,14 @@ func (a *ToolActivities) ExecuteTool(ctx context.Context, input ToolActivityInpu
 		// Validation errors and unknown errors are non-retryable
 		return ToolActivityOutput{}, models.NewToolValidationError(input.ToolName, err)
 	}
+	if cacheRead {
+		a.reads.put(input, readState, *output)
+	}
 
 	return ToolActivityOutput{
-		CallID:  input.CallID,
-		Content: output.Content,
-		Success: output.Success,
+		CallID:   input.CallID,
+		Content:  output.Content,
+		Success:  output.Success,
+		ExitCode: output.ExitCode,
 	}, nil
 }
@@ -0,0 +1,264 @@
+// Package agentservice exposes agent sessions as a Nexus service, so that
+// workflows in other namespaces can start a session, send it messages and
+// await its WorkflowResult through a Nexus endpoint targeting the worker's
+// task queue.
+//
+// Sessions are named by the caller: SessionID is chosen when the session
+// is started and used by the later operations. Session workflow IDs are
+// prefixed with the namespace of the calling workflow, taken from the
+// workflow-event link of the request; requests without one are rejected.
+// The link is not authenticated, so the prefix keeps callers' session IDs
+// from colliding but does not isolate them: who may drive sessions is
+// decided by the endpoint's access policy. Each operation records its
+// caller (see workflow.CallerContext) in the session's metadata and event
+// log.
+//
+// Callers choose only the model, persona and session type of a session
+// (SessionOverrides). Its working directory, permissions, task queue and
+// remote machine come from the worker (see NewService).
+//
+// NOTE: Temporal-specific addition (not in Codex Rust).
+package agentservice
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/nexus-rpc/sdk-go/nexus"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporalnexus"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/workflow"
+)
+
+// ServiceName is the name of the Nexus service registered by the worker.
+const ServiceName = "tcx-agent"
+
+// Operation names of the service.
+const (
+	// OperationRunSession starts a session and completes with its
+	// workflow.WorkflowResult when the session ends.
+	OperationRunSession = "run-session"
+
+	// OperationSendMessage sends a user message to a running session and
+	// returns the turn it started.
+	OperationSendMessage = "send-message"
+
+	// OperationEndSession ends a running session, completing its
+	// run-session operation.
+	OperationEndSession = "end-session"
+)
+
+// RunSessionInput is the input of the run-session operation.
+type RunSessionInput struct {
+	// SessionID names the session in send-message and end-session. It must
+	// not contain "/"; starting a session whose ID is already running in
+	// the caller's namespace fails.
+	SessionID string `json:"session_id"`
+
+	// UserMessage is the session's first message.
+	UserMessage string `json:"user_message"`
+
+	// Overrides are applied on top of the worker's configuration.
+	Overrides SessionOverrides `json:"overrides,omitempty"`
+
+	// Principal and Attributes describe who the caller acts for (a user,
+	// a team); they are recorded as the session's caller, not verified.
+	Principal  string            `json:"principal,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// SessionOverrides are the session settings a caller may choose.
+type SessionOverrides struct {
+	Model       string             `json:"model,omitempty"`
+	Persona     string             `json:"persona,omitempty"`
+	SessionType models.SessionType `json:"session_type,omitempty"`
+}
+
+// apply returns base with the settings of o that are set.
+func (o SessionOverrides) apply(base workflow.CLIOverrides) workflow.CLIOverrides {
+	if o.Model != "" {
+		base.Model = o.Model
+	}
+	if o.Persona != "" {
+		base.Persona = o.Persona
+	}
+	if o.SessionType != "" {
+		base.SessionType = o.SessionType
+	}
+	return base
+}
+
+// SendMessageInput is the input of the send-message operation.
+type SendMessageInput struct {
+	SessionID string `json:"session_id"`
+	Content   string `json:"content"`
+
+	Principal  string            `json:"principal,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// SendMessageOutput is the result of the send-message operation.
+type SendMessageOutput struct {
+	// TurnID is the turn the message started. Its TurnComplete item, or
+	// the session's get_turn_status query, tells when it is answered.
+	TurnID string `json:"turn_id"`
+}
+
+// EndSessionInput is the input of the end-session operation.
+type EndSessionInput struct {
+	SessionID string `json:"session_id"`
+	Reason    string `json:"reason,omitempty"`
+
+	Principal  string            `json:"principal,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// EndSessionOutput is the result of the end-session operation.
+type EndSessionOutput struct {
+	Acknowledged bool `json:"acknowledged"`
+}
+
+// NewService returns the Nexus service to register on the worker. base
+// configures every session it starts (working directory, permissions, task
+// queue, remote machine); callers may only override the fields of
+// SessionOverrides.
+func NewService(base workflow.CLIOverrides) *nexus.Service {
+	svc := nexus.NewService(ServiceName)
+	svc.MustRegister(
+		temporalnexus.MustNewWorkflowRunOperationWithOptions(temporalnexus.WorkflowRunOperationOptions[RunSessionInput, workflow.WorkflowResult]{
+			Name: OperationRunSession,
+			Handler: func(ctx context.Context, in RunSessionInput, opts nexus.StartOperationOptions) (temporalnexus.WorkflowHandle[workflow.WorkflowResult], error) {
+				return runSession(ctx, base, in, opts)
+			},
+		}),
+		nexus.NewSyncOperation(OperationSendMessage, sendMessage),
+		nexus.NewSyncOperation(OperationEndSession, endSession),
+	)
+	return svc
+}
+
+// SessionWorkflowID returns the ID of the SessionWorkflow that runs the
+// session sessionID started by a caller in namespace. Its AgenticWorkflow,
+// which send-message and end-session target, is this ID + "/main".
+func SessionWorkflowID(namespace, sessionID string) string {
+	return "nexus/" + namespace + "/" + sessionID
+}
+
+// runSession starts the SessionWorkflow of a run-session operation with
+// the caller's overrides applied to base.
+func runSession(ctx context.Context, base workflow.CLIOverrides, in RunSessionInput, opts nexus.StartOperationOptions) (temporalnexus.WorkflowHandle[workflow.WorkflowResult], error) {
+	if err := checkSessionID(in.SessionID); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(in.UserMessage) == "" {
+		return nil, nexus.HandlerErrorf(nexus.HandlerErrorTypeBadRequest, "user_message must not be empty")
+	}
+	caller, err := callerContext(opts, in.Principal, in.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	return temporalnexus.ExecuteWorkflow(ctx, opts, client.StartWorkflowOptions{
+		ID:   SessionWorkflowID(caller.Namespace, in.SessionID),
+		Memo: map[string]interface{}{"caller": caller},
+	}, workflow.SessionWorkflow, workflow.SessionWorkflowInput{
+		SessionID:   in.SessionID,
+		UserMessage: in.UserMessage,
+		Overrides:   in.Overrides.apply(base),
+		Caller:      caller,
+	})
+}
+
+// sendMessage delivers a send-message operation as a user_input update. The
+// Nexus request ID is the update's idempotency key, so a retried request
+// does not start a second turn.
+func sendMessage(ctx context.Context, in SendMessageInput, opts nexus.StartOperationOptions) (SendMessageOutput, error) {
+	if err := checkSessionID(in.SessionID); err != nil {
+		return SendMessageOutput{}, err
+	}
+	caller, err := callerContext(opts, in.Principal, in.Attributes)
+	if err != nil {
+		return SendMessageOutput{}, err
+	}
+	var resp workflow.StateUpdateResponse
+	err = updateSession(ctx, caller.Namespace, in.SessionID, workflow.UpdateUserInput, workflow.UserInput{
+		Version:        workflow.ProtocolVersion,
+		Content:        in.Content,
+		IdempotencyKey: opts.RequestID,
+		Caller:         caller,
+	}, &resp)
+	if err != nil {
+		return SendMessageOutput{}, err
+	}
+	return SendMessageOutput{TurnID: resp.TurnID}, nil
+}
+
+// endSession delivers an end-session operation as a shutdown update.
+func endSession(ctx context.Context, in EndSessionInput, opts nexus.StartOperationOptions) (EndSessionOutput, error) {
+	if err := checkSessionID(in.SessionID); err != nil {
+		return EndSessionOutput{}, err
+	}
+	caller, err := callerContext(opts, in.Principal, in.Attributes)
+	if err != nil {
+		return EndSessionOutput{}, err
+	}
+	var resp workflow.ShutdownResponse
+	err = updateSession(ctx, caller.Namespace, in.SessionID, workflow.UpdateShutdown, workflow.ShutdownRequest{
+		Reason: in.Reason,
+		Caller: caller,
+	}, &resp)
+	if err != nil {
+		return EndSessionOutput{}, err
+	}
+	return EndSessionOutput{Acknowledged: resp.Acknowledged}, nil
+}
+
+// updateSession sends an update to the AgenticWorkflow of a session and
+// waits for its result.
+func updateSession(ctx context.Context, namespace, sessionID, name string, arg, result interface{}) error {
+	handle, err := temporalnexus.GetClient(ctx).UpdateWorkflow(ctx, client.UpdateWorkflowOptions{
+		WorkflowID:   SessionWorkflowID(namespace, sessionID) + "/main",
+		UpdateName:   name,
+		Args:         []interface{}{arg},
+		WaitForStage: client.WorkflowUpdateStageCompleted,
+	})
+	if err == nil {
+		err = handle.Get(ctx, result)
+	}
+	var notFound *serviceerror.NotFound
+	if errors.As(err, &notFound) {
+		return nexus.HandlerErrorf(nexus.HandlerErrorTypeNotFound, "no running session %q", sessionID)
+	}
+	return err
+}
+
+// checkSessionID rejects an empty session ID or one that would escape its
+// namespace's workflow ID prefix.
+func checkSessionID(id string) error {
+	if id == "" || strings.Contains(id, "/") {
+		return nexus.HandlerErrorf(nexus.HandlerErrorTypeBadRequest, "session_id must be non-empty and must not contain \"/\"")
+	}
+	return nil
+}
+
+// callerContext builds the caller of an operation: the namespace and
+// workflow from the workflow-event link Temporal attaches to requests made
+// by workflows, and the identity the caller declared. A request without a
+// workflow link is rejected, so session IDs are always scoped by a
+// namespace.
+func callerContext(opts nexus.StartOperationOptions, principal string, attributes map[string]string) (*workflow.CallerContext, error) {
+	caller := &workflow.CallerContext{Principal: principal, Attributes: attributes}
+	for _, link := range opts.Links {
+		event, err := temporalnexus.ConvertNexusLinkToLinkWorkflowEvent(link)
+		if err != nil || event.GetNamespace() == "" {
+			continue
+		}
+		caller.Namespace = event.GetNamespace()
+		caller.WorkflowID = event.GetWorkflowId()
+		return caller, nil
+	}
+	return nil, nexus.HandlerErrorf(nexus.HandlerErrorTypeBadRequest, "requests must be made by a Temporal workflow (no workflow link)")
+}
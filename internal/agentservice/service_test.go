@@ -0,0 +1,79 @@
+package agentservice
+
+import (
+	"testing"
+
+	"github.com/nexus-rpc/sdk-go/nexus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/temporalnexus"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/workflow"
+)
+
+func TestCallerContext_FromWorkflowLink(t *testing.T) {
+	link := temporalnexus.ConvertLinkWorkflowEventToNexusLink(&commonpb.Link_WorkflowEvent{
+		Namespace:  "billing",
+		WorkflowId: "invoice-42",
+		RunId:      "run-1",
+		Reference: &commonpb.Link_WorkflowEvent_EventRef{EventRef: &commonpb.Link_WorkflowEvent_EventReference{
+			EventId:   5,
+			EventType: enumspb.EVENT_TYPE_NEXUS_OPERATION_SCHEDULED,
+		}},
+	})
+	opts := nexus.StartOperationOptions{Links: []nexus.Link{link}}
+
+	caller, err := callerContext(opts, "alice", map[string]string{"team": "payments"})
+	require.NoError(t, err)
+	assert.Equal(t, &workflow.CallerContext{
+		Namespace:  "billing",
+		WorkflowID: "invoice-42",
+		Principal:  "alice",
+		Attributes: map[string]string{"team": "payments"},
+	}, caller)
+
+	_, err = callerContext(nexus.StartOperationOptions{}, "bob", nil)
+	var handlerErr *nexus.HandlerError
+	require.ErrorAs(t, err, &handlerErr, "no link: the caller is not a workflow")
+	assert.Equal(t, nexus.HandlerErrorTypeBadRequest, handlerErr.Type)
+}
+
+func TestSessionOverrides_Apply(t *testing.T) {
+	base := workflow.CLIOverrides{
+		Cwd:         "/srv/agent",
+		Model:       "gpt-4o",
+		Permissions: models.Permissions{ApprovalMode: models.ApprovalUnlessTrusted},
+	}
+	got := SessionOverrides{Model: "claude-sonnet-4-0", SessionType: models.SessionTypeAsk}.apply(base)
+	assert.Equal(t, "claude-sonnet-4-0", got.Model)
+	assert.Equal(t, models.SessionTypeAsk, got.SessionType)
+	assert.Equal(t, "/srv/agent", got.Cwd, "the working directory comes from the worker")
+	assert.Equal(t, base.Permissions, got.Permissions, "permissions come from the worker")
+
+	assert.Equal(t, base, SessionOverrides{}.apply(base))
+}
+
+func TestSessionWorkflowID_ScopedByNamespace(t *testing.T) {
+	assert.Equal(t, "nexus/billing/review-1", SessionWorkflowID("billing", "review-1"))
+	assert.NotEqual(t, SessionWorkflowID("billing", "s"), SessionWorkflowID("search", "s"))
+}
+
+func TestCheckSessionID(t *testing.T) {
+	require.NoError(t, checkSessionID("review-1"))
+
+	var handlerErr *nexus.HandlerError
+	require.ErrorAs(t, checkSessionID(""), &handlerErr)
+	assert.Equal(t, nexus.HandlerErrorTypeBadRequest, handlerErr.Type)
+	assert.Error(t, checkSessionID("../other"))
+}
+
+func TestNewService_Operations(t *testing.T) {
+	svc := NewService(workflow.CLIOverrides{})
+	assert.Equal(t, ServiceName, svc.Name)
+	for _, name := range []string{OperationRunSession, OperationSendMessage, OperationEndSession} {
+		assert.NotNil(t, svc.Operation(name), name)
+	}
+}
@@ -0,0 +1,155 @@
+// Package archive stores conversation items a session has moved out of
+// workflow memory. When a session's history outgrows its retention window,
+// the oldest turns are written here as JSON Lines batches and replaced in
+// workflow state by a short summary, so queries and ContinueAsNew payloads
+// stay small however long the session runs.
+//
+// Each session has a directory under <codex_home>/archive holding one file
+// per batch, named by the index of its first item in the session's archive
+// (00000000.jsonl, 00000042.jsonl, ...). Writing a batch is idempotent, so a
+// retried activity never duplicates items. With a [storage] object store
+// the same layout is kept under the bucket prefix (see internal/blobstore).
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/mfateev/temporal-agent-harness/internal/blobstore"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/scratch"
+)
+
+// batchExt is the extension of batch files.
+const batchExt = ".jsonl"
+
+// rootKey is the store key prefix of all session archives.
+const rootKey = "archive"
+
+// Root returns the directory holding all session archives.
+func Root(codexHome string) string {
+	return filepath.Join(codexHome, rootKey)
+}
+
+// Dir returns the archive directory of a session. The session ID is
+// sanitized so it always maps to a single path element under Root.
+func Dir(codexHome, sessionID string) string {
+	return filepath.Join(Root(codexHome), scratch.SafeName(sessionID))
+}
+
+// Key returns the store key prefix of a session's archive, e.g.
+// "archive/sess-1". A local store rooted at codex_home keeps it in Dir.
+func Key(sessionID string) string {
+	return rootKey + "/" + scratch.SafeName(sessionID)
+}
+
+// Write stores items as the batch starting at archive index first under
+// codex_home and returns the batch file path. An existing batch at that
+// index is replaced.
+func Write(codexHome, sessionID string, first int, items []models.ConversationItem) (string, error) {
+	return WriteTo(context.Background(), blobstore.NewDir(codexHome), sessionID, first, items)
+}
+
+// WriteTo stores items as the batch starting at archive index first in
+// store and returns the batch's URI.
+func WriteTo(ctx context.Context, store blobstore.Store, sessionID string, first int, items []models.ConversationItem) (string, error) {
+	if sessionID == "" {
+		return "", fmt.Errorf("archive: empty session ID")
+	}
+	var b bytes.Buffer
+	for _, item := range items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return "", fmt.Errorf("archive: encode item: %w", err)
+		}
+		b.Write(data)
+		b.WriteByte('\n')
+	}
+
+	key := Key(sessionID) + "/" + fmt.Sprintf("%08d%s", first, batchExt)
+	if err := store.Put(ctx, key, b.Bytes()); err != nil {
+		return "", fmt.Errorf("archive: %w", err)
+	}
+	return store.URI(key), nil
+}
+
+// Read returns every archived item of a session under codex_home in
+// order. A session with no archive has no items.
+func Read(codexHome, sessionID string) ([]models.ConversationItem, error) {
+	return ReadFrom(context.Background(), blobstore.NewDir(codexHome), sessionID)
+}
+
+// ReadFrom returns every archived item of a session in store in order.
+func ReadFrom(ctx context.Context, store blobstore.Store, sessionID string) ([]models.ConversationItem, error) {
+	objects, err := store.List(ctx, Key(sessionID)+"/")
+	if err != nil {
+		return nil, fmt.Errorf("archive: %w", err)
+	}
+	// Zero-padded names sort in archive order, as List returns them.
+	var items []models.ConversationItem
+	for _, obj := range objects {
+		if !strings.HasSuffix(obj.Key, batchExt) {
+			continue
+		}
+		data, err := store.Get(ctx, obj.Key)
+		if err != nil {
+			return nil, fmt.Errorf("archive: read %s: %w", store.URI(obj.Key), err)
+		}
+		batch, err := decodeBatch(data)
+		if err != nil {
+			return nil, fmt.Errorf("archive: decode %s: %w", store.URI(obj.Key), err)
+		}
+		items = append(items, batch...)
+	}
+	return items, nil
+}
+
+// decodeBatch decodes one batch.
+func decodeBatch(data []byte) ([]models.ConversationItem, error) {
+	var items []models.ConversationItem
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var item models.ConversationItem
+		if err := json.Unmarshal(scanner.Bytes(), &item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, scanner.Err()
+}
+
+// Remove deletes a session's archive under codex_home and returns the
+// number of bytes it held. Removing an archive that does not exist is not
+// an error.
+func Remove(codexHome, sessionID string) (int64, error) {
+	return RemoveFrom(context.Background(), blobstore.NewDir(codexHome), sessionID)
+}
+
+// RemoveFrom deletes a session's archive in store and returns the number
+// of bytes it held.
+func RemoveFrom(ctx context.Context, store blobstore.Store, sessionID string) (int64, error) {
+	if sessionID == "" {
+		return 0, nil
+	}
+	objects, err := store.List(ctx, Key(sessionID)+"/")
+	if err != nil {
+		return 0, fmt.Errorf("archive: %w", err)
+	}
+	var size int64
+	for _, obj := range objects {
+		if err := store.Delete(ctx, obj.Key); err != nil {
+			return size, fmt.Errorf("archive: remove: %w", err)
+		}
+		size += obj.Size
+	}
+	return size, nil
+}
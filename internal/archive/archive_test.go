@@ -0,0 +1,84 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+func TestDir_StaysUnderRoot(t *testing.T) {
+	home := t.TempDir()
+	for _, id := range []string{"sess-1", "parent/child", "../../etc", ".."} {
+		assert.Equal(t, Root(home), filepath.Dir(Dir(home, id)), "id %q", id)
+	}
+}
+
+func TestWriteAndRead_BatchesInOrder(t *testing.T) {
+	home := t.TempDir()
+	first := []models.ConversationItem{
+		{Type: models.ItemTypeTurnStarted, TurnID: "turn-1"},
+		{Type: models.ItemTypeUserMessage, Content: "hello\nworld", TurnID: "turn-1"},
+	}
+	second := []models.ConversationItem{
+		{Type: models.ItemTypeAssistantMessage, Content: "hi", TurnID: "turn-2"},
+	}
+
+	// Written out of order; read back in archive order.
+	_, err := Write(home, "sess", 2, second)
+	require.NoError(t, err)
+	path, err := Write(home, "sess", 0, first)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(Dir(home, "sess"), "00000000.jsonl"), path)
+
+	items, err := Read(home, "sess")
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+	assert.Equal(t, "hello\nworld", items[1].Content)
+	assert.Equal(t, "turn-2", items[2].TurnID)
+}
+
+func TestWrite_RetryReplacesBatch(t *testing.T) {
+	home := t.TempDir()
+	batch := []models.ConversationItem{{Type: models.ItemTypeUserMessage, Content: "once"}}
+	_, err := Write(home, "sess", 0, batch)
+	require.NoError(t, err)
+	_, err = Write(home, "sess", 0, batch)
+	require.NoError(t, err)
+
+	items, err := Read(home, "sess")
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+}
+
+func TestWrite_EmptySessionID(t *testing.T) {
+	_, err := Write(t.TempDir(), "", 0, nil)
+	assert.Error(t, err)
+}
+
+func TestReadAndRemove_Missing(t *testing.T) {
+	home := t.TempDir()
+	items, err := Read(home, "nope")
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	size, err := Remove(home, "nope")
+	require.NoError(t, err)
+	assert.Zero(t, size)
+}
+
+func TestRemove(t *testing.T) {
+	home := t.TempDir()
+	_, err := Write(home, "sess", 0, []models.ConversationItem{{Type: models.ItemTypeUserMessage, Content: "x"}})
+	require.NoError(t, err)
+
+	size, err := Remove(home, "sess")
+	require.NoError(t, err)
+	assert.Positive(t, size)
+	_, err = os.Stat(Dir(home, "sess"))
+	assert.True(t, os.IsNotExist(err))
+}
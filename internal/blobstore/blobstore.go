@@ -0,0 +1,204 @@
+// Package blobstore stores the files a worker keeps beyond a single
+// activity — history archive batches (session transcripts) and CLI
+// artifact bundles — behind one interface, so ephemeral cloud workers can
+// keep them in an object store instead of on local disk.
+//
+// Backends are selected by the [storage] table of config.toml:
+//
+//	local   a directory (the default: ~/.codex)
+//	s3      Amazon S3
+//	gcs     Google Cloud Storage, through its S3-compatible XML API
+//	minio   MinIO or another S3-compatible server (path-style URLs)
+//
+// The object store backends speak the S3 REST API with Signature Version 4,
+// so they need no cloud SDK. Credentials come from the environment:
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN for s3 and
+// minio; GCS_HMAC_ACCESS_ID and GCS_HMAC_SECRET (an HMAC key of a service
+// account) for gcs.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// Backend names accepted in [storage] backend.
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+	BackendGCS   = "gcs"
+	BackendMinIO = "minio"
+)
+
+// DefaultSignedURLTTL is how long signed URLs stay valid unless
+// [storage] signed_url_ttl_minutes says otherwise.
+const DefaultSignedURLTTL = 24 * time.Hour
+
+// ErrNotFound is returned by Get for a key that does not exist.
+var ErrNotFound = errors.New("blobstore: object not found")
+
+// Object is one stored object.
+type Object struct {
+	Key  string
+	Size int64
+}
+
+// Store is a flat key-value object store. Keys are slash-separated paths
+// relative to the store's root (a directory or a bucket prefix).
+type Store interface {
+	// Put stores data under key, replacing any existing object.
+	Put(ctx context.Context, key string, data []byte) error
+	// Get returns the object under key, or ErrNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// List returns the objects whose keys start with prefix, sorted by key.
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// Delete removes the object under key. Deleting a missing object is
+	// not an error.
+	Delete(ctx context.Context, key string) error
+	// URI names the object under key for people and logs, e.g.
+	// /home/u/.codex/archive/s1 or s3://bucket/prefix/archive/s1.
+	URI(key string) string
+	// SignedURL returns a URL that reads the object under key without
+	// credentials until ttl has passed. Local stores return a file:// URL.
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// Config is a resolved [storage] table.
+type Config struct {
+	Backend string
+	// LocalRoot is the directory of the local backend.
+	LocalRoot string
+	Bucket    string
+	Prefix    string
+	// Endpoint is the base URL of the object store; empty uses the
+	// backend's public endpoint.
+	Endpoint  string
+	Region    string
+	PathStyle bool
+	// SignedURLTTL is how long signed URLs in reports stay valid.
+	SignedURLTTL time.Duration
+
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+}
+
+// Remote reports whether the backend is an object store.
+func (c Config) Remote() bool {
+	return c.Backend != BackendLocal
+}
+
+// Resolve turns a [storage] table into a Config. localRoot is the
+// directory of the local backend; getenv reads credentials (os.Getenv
+// when nil). A nil table selects the local backend.
+func Resolve(tc *models.StorageToml, localRoot string, getenv func(string) string) (Config, error) {
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	cfg := Config{Backend: BackendLocal, LocalRoot: localRoot, SignedURLTTL: DefaultSignedURLTTL}
+	if tc == nil {
+		return cfg, nil
+	}
+	if tc.Backend != nil && *tc.Backend != "" {
+		cfg.Backend = *tc.Backend
+	}
+	if tc.Bucket != nil {
+		cfg.Bucket = *tc.Bucket
+	}
+	if tc.Prefix != nil {
+		cfg.Prefix = strings.Trim(*tc.Prefix, "/")
+	}
+	if tc.Endpoint != nil {
+		cfg.Endpoint = strings.TrimSuffix(*tc.Endpoint, "/")
+	}
+	if tc.Region != nil {
+		cfg.Region = *tc.Region
+	}
+	if tc.SignedURLTTLMinutes != nil && *tc.SignedURLTTLMinutes > 0 {
+		cfg.SignedURLTTL = time.Duration(*tc.SignedURLTTLMinutes) * time.Minute
+	}
+
+	switch cfg.Backend {
+	case BackendLocal:
+		return cfg, nil
+	case BackendS3:
+		if cfg.Region == "" {
+			cfg.Region = getenv("AWS_REGION")
+		}
+		if cfg.Region == "" {
+			cfg.Region = "us-east-1"
+		}
+		if cfg.Endpoint == "" {
+			cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+		}
+		cfg.AccessKey, cfg.SecretKey = getenv("AWS_ACCESS_KEY_ID"), getenv("AWS_SECRET_ACCESS_KEY")
+		cfg.SessionToken = getenv("AWS_SESSION_TOKEN")
+	case BackendGCS:
+		if cfg.Region == "" {
+			cfg.Region = "auto"
+		}
+		if cfg.Endpoint == "" {
+			cfg.Endpoint = "https://storage.googleapis.com"
+		}
+		cfg.PathStyle = true
+		cfg.AccessKey, cfg.SecretKey = getenv("GCS_HMAC_ACCESS_ID"), getenv("GCS_HMAC_SECRET")
+	case BackendMinIO:
+		if cfg.Endpoint == "" {
+			return cfg, errors.New("storage: minio needs an endpoint")
+		}
+		if cfg.Region == "" {
+			cfg.Region = "us-east-1"
+		}
+		cfg.PathStyle = true
+		cfg.AccessKey, cfg.SecretKey = getenv("AWS_ACCESS_KEY_ID"), getenv("AWS_SECRET_ACCESS_KEY")
+		cfg.SessionToken = getenv("AWS_SESSION_TOKEN")
+	default:
+		return cfg, fmt.Errorf("storage: unknown backend %q (use local, s3, gcs or minio)", cfg.Backend)
+	}
+	if tc.PathStyle != nil {
+		cfg.PathStyle = *tc.PathStyle
+	}
+	if cfg.Bucket == "" {
+		return cfg, fmt.Errorf("storage: %s needs a bucket", cfg.Backend)
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return cfg, fmt.Errorf("storage: %s credentials are not set in the environment", cfg.Backend)
+	}
+	return cfg, nil
+}
+
+// Open returns the store of a resolved configuration.
+func Open(cfg Config) (Store, error) {
+	if !cfg.Remote() {
+		if cfg.LocalRoot == "" {
+			return nil, errors.New("storage: no local directory")
+		}
+		return NewDir(cfg.LocalRoot), nil
+	}
+	return NewS3(cfg)
+}
+
+// Load reads the [storage] table of the config.toml at path and opens its
+// store. A missing file or table selects the local backend at localRoot.
+func Load(path, localRoot string) (Store, Config, error) {
+	var tc *models.StorageToml
+	if data, err := os.ReadFile(path); err == nil {
+		parsed, err := models.ParseConfigToml(data)
+		if err != nil {
+			return nil, Config{}, fmt.Errorf("storage: parse %s: %w", path, err)
+		}
+		tc = parsed.Storage
+	}
+	cfg, err := Resolve(tc, localRoot, nil)
+	if err != nil {
+		return nil, cfg, err
+	}
+	store, err := Open(cfg)
+	return store, cfg, err
+}
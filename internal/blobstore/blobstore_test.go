@@ -0,0 +1,83 @@
+package blobstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(k string) string { return vars[k] }
+}
+
+func TestResolve(t *testing.T) {
+	cfg, err := Resolve(nil, "/home/u/.codex", env(nil))
+	require.NoError(t, err)
+	assert.Equal(t, Config{Backend: BackendLocal, LocalRoot: "/home/u/.codex", SignedURLTTL: DefaultSignedURLTTL}, cfg)
+
+	s3, bucket, ttl := BackendS3, "transcripts", 60
+	cfg, err = Resolve(&models.StorageToml{Backend: &s3, Bucket: &bucket, SignedURLTTLMinutes: &ttl}, "",
+		env(map[string]string{"AWS_REGION": "eu-west-1", "AWS_ACCESS_KEY_ID": "AK", "AWS_SECRET_ACCESS_KEY": "SK"}))
+	require.NoError(t, err)
+	assert.Equal(t, "https://s3.eu-west-1.amazonaws.com", cfg.Endpoint)
+	assert.False(t, cfg.PathStyle)
+	assert.Equal(t, time.Hour, cfg.SignedURLTTL)
+
+	gcs := BackendGCS
+	cfg, err = Resolve(&models.StorageToml{Backend: &gcs, Bucket: &bucket}, "",
+		env(map[string]string{"GCS_HMAC_ACCESS_ID": "GOOG1", "GCS_HMAC_SECRET": "secret"}))
+	require.NoError(t, err)
+	assert.Equal(t, "https://storage.googleapis.com", cfg.Endpoint)
+	assert.Equal(t, "auto", cfg.Region)
+	assert.True(t, cfg.PathStyle)
+
+	minio := BackendMinIO
+	_, err = Resolve(&models.StorageToml{Backend: &minio, Bucket: &bucket}, "", env(nil))
+	assert.ErrorContains(t, err, "needs an endpoint")
+	_, err = Resolve(&models.StorageToml{Backend: &s3, Bucket: &bucket}, "", env(nil))
+	assert.ErrorContains(t, err, "credentials")
+	ftp := "ftp"
+	_, err = Resolve(&models.StorageToml{Backend: &ftp}, "", env(nil))
+	assert.ErrorContains(t, err, "unknown backend")
+}
+
+func TestDir_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	d := NewDir(root)
+
+	require.NoError(t, d.Put(ctx, "archive/s1/00000002.jsonl", []byte("b")))
+	require.NoError(t, d.Put(ctx, "archive/s1/00000000.jsonl", []byte("aa")))
+	require.NoError(t, d.Put(ctx, "archive/s2/00000000.jsonl", []byte("c")))
+
+	data, err := d.Get(ctx, "archive/s1/00000000.jsonl")
+	require.NoError(t, err)
+	assert.Equal(t, "aa", string(data))
+	_, err = d.Get(ctx, "archive/s1/missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	objects, err := d.List(ctx, "archive/s1/")
+	require.NoError(t, err)
+	assert.Equal(t, []Object{{Key: "archive/s1/00000000.jsonl", Size: 2}, {Key: "archive/s1/00000002.jsonl", Size: 1}}, objects)
+
+	require.NoError(t, d.Delete(ctx, "archive/s1/00000000.jsonl"))
+	require.NoError(t, d.Delete(ctx, "archive/s1/00000002.jsonl"))
+	require.NoError(t, d.Delete(ctx, "archive/s1/00000002.jsonl"), "deleting twice is not an error")
+	_, err = os.Stat(filepath.Join(root, "archive", "s1"))
+	assert.True(t, os.IsNotExist(err), "empty directories are removed")
+
+	_, err = d.Get(ctx, "../escape")
+	assert.Error(t, err)
+
+	signed, err := d.SignedURL(ctx, "archive/s2/00000000.jsonl", time.Hour)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(signed, "file://"), signed)
+}
@@ -0,0 +1,143 @@
+package blobstore
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Dir is the local backend: each key is a file under a root directory.
+type Dir struct {
+	root string
+}
+
+// NewDir returns a store rooted at dir. The directory is created on the
+// first Put.
+func NewDir(dir string) *Dir {
+	return &Dir{root: dir}
+}
+
+// path maps a key to its file, refusing keys that leave the root.
+func (d *Dir) path(key string) (string, error) {
+	rel := filepath.FromSlash(key)
+	if key == "" || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("blobstore: invalid key %q", key)
+	}
+	return filepath.Join(d.root, rel), nil
+}
+
+// Put writes the file atomically through a temporary file.
+func (d *Dir) Put(_ context.Context, key string, data []byte) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("blobstore: create %s: %w", filepath.Dir(path), err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("blobstore: write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("blobstore: rename %s: %w", tmp, err)
+	}
+	return nil
+}
+
+func (d *Dir) Get(_ context.Context, key string) ([]byte, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// List walks the directory of prefix. Temporary files of unfinished Puts
+// are left out.
+func (d *Dir) List(_ context.Context, prefix string) ([]Object, error) {
+	start := d.root
+	if dir := prefix[:strings.LastIndex(prefix, "/")+1]; dir != "" {
+		p, err := d.path(strings.TrimSuffix(dir, "/"))
+		if err != nil {
+			return nil, err
+		}
+		start = p
+	}
+	var objects []Object
+	err := filepath.WalkDir(start, func(path string, e fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if e.IsDir() || strings.HasSuffix(path, ".tmp") {
+			return nil
+		}
+		rel, err := filepath.Rel(d.root, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, Object{Key: key, Size: info.Size()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("blobstore: list %s: %w", start, err)
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// Delete removes the file and the directories it leaves empty.
+func (d *Dir) Delete(_ context.Context, key string) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("blobstore: remove %s: %w", path, err)
+	}
+	for dir := filepath.Dir(path); dir != d.root && strings.HasPrefix(dir, d.root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// URI returns the file path of key.
+func (d *Dir) URI(key string) string {
+	return filepath.Join(d.root, filepath.FromSlash(key))
+}
+
+// SignedURL returns a file:// URL; local files need no signature.
+func (d *Dir) SignedURL(_ context.Context, key string, _ time.Duration) (string, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String(), nil
+}
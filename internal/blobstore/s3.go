@@ -0,0 +1,335 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// s3RequestTimeout bounds one request to the object store.
+const s3RequestTimeout = 2 * time.Minute
+
+// unsignedPayload is the payload hash of presigned URLs.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3 is an object store speaking the S3 REST API: Amazon S3, Google Cloud
+// Storage (XML API with HMAC keys) or MinIO. Keys are stored under the
+// configured prefix.
+type S3 struct {
+	cfg    Config
+	base   *url.URL
+	client *http.Client
+	// now is the signing clock; tests pin it.
+	now func() time.Time
+}
+
+// NewS3 returns an S3-compatible store for cfg.
+func NewS3(cfg Config) (*S3, error) {
+	base, err := url.Parse(cfg.Endpoint)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("storage: endpoint %q is not an http(s) URL", cfg.Endpoint)
+	}
+	return &S3{cfg: cfg, base: base, client: &http.Client{Timeout: s3RequestTimeout}, now: time.Now}, nil
+}
+
+// objectKey prepends the configured prefix.
+func (s *S3) objectKey(key string) string {
+	if s.cfg.Prefix == "" {
+		return key
+	}
+	return s.cfg.Prefix + "/" + key
+}
+
+// objectURL returns the URL of an object key (without prefix handling),
+// or of the bucket when key is empty.
+func (s *S3) objectURL(key string) *url.URL {
+	u := *s.base
+	if s.cfg.PathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.cfg.Bucket + "/" + key
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	}
+	return &u
+}
+
+func (s *S3) Put(ctx context.Context, key string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, s.objectURL(s.objectKey(key)), data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return s3Error(resp, key)
+}
+
+func (s *S3) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, s.objectURL(s.objectKey(key)), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if err := s3Error(resp, key); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// listResult is the part of a ListObjectsV2 response that List reads.
+type listResult struct {
+	Contents []struct {
+		Key  string `xml:"Key"`
+		Size int64  `xml:"Size"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List pages through ListObjectsV2.
+func (s *S3) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	token := ""
+	for {
+		u := s.objectURL("")
+		q := url.Values{"list-type": {"2"}, "prefix": {s.objectKey(prefix)}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		u.RawQuery = q.Encode()
+		resp, err := s.do(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page listResult
+		err = s3Error(resp, prefix)
+		if err == nil {
+			err = xml.NewDecoder(resp.Body).Decode(&page)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("blobstore: list %s: %w", prefix, err)
+		}
+		for _, c := range page.Contents {
+			key := c.Key
+			if s.cfg.Prefix != "" {
+				key = strings.TrimPrefix(key, s.cfg.Prefix+"/")
+			}
+			objects = append(objects, Object{Key: key, Size: c.Size})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			break
+		}
+		token = page.NextContinuationToken
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, s.objectURL(s.objectKey(key)), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return s3Error(resp, key)
+}
+
+// URI returns s3://bucket/key, or gs://bucket/key for gcs.
+func (s *S3) URI(key string) string {
+	scheme := "s3"
+	if s.cfg.Backend == BackendGCS {
+		scheme = "gs"
+	}
+	return scheme + "://" + s.cfg.Bucket + "/" + s.objectKey(key)
+}
+
+// SignedURL presigns a GET of the object (SigV4 query authentication).
+func (s *S3) SignedURL(_ context.Context, key string, ttl time.Duration) (string, error) {
+	if ttl <= 0 {
+		ttl = s.cfg.SignedURLTTL
+	}
+	u := s.objectURL(s.objectKey(key))
+	return s.presign(u, ttl), nil
+}
+
+// do sends a signed request.
+func (s *S3) do(ctx context.Context, method string, u *url.URL, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	s.sign(req, body)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("blobstore: %s %s: %w", method, s.cfg.Backend, err)
+	}
+	return resp, nil
+}
+
+// s3Error turns a non-2xx response into an error carrying the service's
+// message.
+func s3Error(resp *http.Response, key string) error {
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if xml.Unmarshal(data, &e) == nil && e.Code != "" {
+		return fmt.Errorf("blobstore: %s: %s: %s", key, e.Code, e.Message)
+	}
+	return fmt.Errorf("blobstore: %s: %s", key, resp.Status)
+}
+
+// sign adds SigV4 header authentication to req.
+func (s *S3) sign(req *http.Request, body []byte) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.cfg.SessionToken)
+	}
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	if s.cfg.SessionToken != "" {
+		headers["x-amz-security-token"] = s.cfg.SessionToken
+	}
+	signedHeaders, canonicalHeaders := canonicalizeHeaders(headers)
+	scope := now.Format("20060102") + "/" + s.cfg.Region + "/s3/aws4_request"
+	canonical := strings.Join([]string{
+		req.Method, canonicalPath(req.URL), canonicalQuery(req.URL.Query()),
+		canonicalHeaders, signedHeaders, payloadHash,
+	}, "\n")
+	signature := s.signature(now, scope, amzDate, canonical)
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.cfg.AccessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// presign returns u with SigV4 query authentication valid for ttl.
+func (s *S3) presign(u *url.URL, ttl time.Duration) string {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := now.Format("20060102") + "/" + s.cfg.Region + "/s3/aws4_request"
+	q := u.Query()
+	q.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	q.Set("X-Amz-Credential", s.cfg.AccessKey+"/"+scope)
+	q.Set("X-Amz-Date", amzDate)
+	q.Set("X-Amz-Expires", strconv.Itoa(int(ttl/time.Second)))
+	q.Set("X-Amz-SignedHeaders", "host")
+	if s.cfg.SessionToken != "" {
+		q.Set("X-Amz-Security-Token", s.cfg.SessionToken)
+	}
+	canonical := strings.Join([]string{
+		http.MethodGet, canonicalPath(u), canonicalQuery(q),
+		"host:" + u.Host + "\n", "host", unsignedPayload,
+	}, "\n")
+	q.Set("X-Amz-Signature", s.signature(now, scope, amzDate, canonical))
+	signed := *u
+	signed.RawQuery = canonicalQuery(q)
+	return signed.String()
+}
+
+// signature signs a canonical request with the derived SigV4 key.
+func (s *S3) signature(now time.Time, scope, amzDate, canonical string) string {
+	hash := sha256.Sum256([]byte(canonical))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalizeHeaders returns the signed header list and the canonical
+// header block of lowercase header names.
+func canonicalizeHeaders(headers map[string]string) (string, string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	return strings.Join(names, ";"), b.String()
+}
+
+// canonicalPath URI-encodes each segment of the path, keeping slashes.
+func canonicalPath(u *url.URL) string {
+	path := u.Path
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		segments[i] = uriEncode(seg)
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery sorts and URI-encodes query parameters.
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), q[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, uriEncode(k)+"="+uriEncode(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes everything but the unreserved characters, as
+// SigV4 requires.
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,134 @@
+// Package capabilities probes what a worker host can actually run and maps
+// missing capabilities onto the tools that depend on them.
+//
+// Workers differ: a slim container may lack rg, a CI runner may lack
+// docker. The probe runs once at worker startup; workflows fetch the result
+// and stop advertising tools that would only fail at runtime.
+package capabilities
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"sort"
+
+	"github.com/mfateev/temporal-agent-harness/internal/sandbox"
+)
+
+// ProbedBinaries are the executables checked at startup.
+var ProbedBinaries = []string{"bash", "sh", "rg", "git", "docker", "python3"}
+
+// Capabilities describes one worker host.
+// The zero value means "nothing known to be missing", so sessions talking to
+// a worker that predates the probe keep every tool.
+type Capabilities struct {
+	OS   string `json:"os,omitempty"`
+	Arch string `json:"arch,omitempty"`
+	// MissingBinaries lists ProbedBinaries not found on PATH.
+	MissingBinaries []string `json:"missing_binaries,omitempty"`
+	// SandboxAvailable reports whether a platform sandbox (bwrap/seatbelt)
+	// can be used for restricted sandbox modes.
+	SandboxAvailable bool `json:"sandbox_available"`
+}
+
+// toolRequirement lists alternatives: the tool works if any binary is present.
+type toolRequirement struct {
+	anyOf []string
+}
+
+// requirements maps built-in tools to the binaries they shell out to.
+var requirements = map[string]toolRequirement{
+	"grep_files":    {anyOf: []string{"rg"}},
+	"shell":         {anyOf: []string{"bash", "sh"}},
+	"shell_command": {anyOf: []string{"bash", "sh"}},
+	"exec_command":  {anyOf: []string{"bash", "sh"}},
+	"write_stdin":   {anyOf: []string{"bash", "sh"}},
+}
+
+// Probe inspects the current host.
+func Probe() Capabilities {
+	return probe(func(name string) bool {
+		_, err := exec.LookPath(name)
+		return err == nil
+	})
+}
+
+// probe is Probe with an injectable PATH lookup.
+func probe(has func(string) bool) Capabilities {
+	caps := Capabilities{OS: runtime.GOOS, Arch: runtime.GOARCH}
+	for _, bin := range ProbedBinaries {
+		if !has(bin) {
+			caps.MissingBinaries = append(caps.MissingBinaries, bin)
+		}
+	}
+	_, noop := sandbox.NewSandboxManager().(*sandbox.NoopSandbox)
+	caps.SandboxAvailable = !noop
+	return caps
+}
+
+// Missing reports whether bin was probed and found absent.
+func (c Capabilities) Missing(bin string) bool {
+	for _, m := range c.MissingBinaries {
+		if m == bin {
+			return true
+		}
+	}
+	return false
+}
+
+// UnavailableReason returns why toolName cannot run on this worker, or ""
+// if nothing it needs is known to be missing. Shell tools are not gated on
+// Windows, where they use cmd/powershell rather than bash/sh.
+func (c Capabilities) UnavailableReason(toolName string) string {
+	req, ok := requirements[toolName]
+	if !ok {
+		return ""
+	}
+	if c.OS == "windows" && toolName != "grep_files" {
+		return ""
+	}
+	for _, bin := range req.anyOf {
+		if !c.Missing(bin) {
+			return ""
+		}
+	}
+	if len(req.anyOf) == 1 {
+		return fmt.Sprintf("`%s` is not installed on the worker", req.anyOf[0])
+	}
+	return fmt.Sprintf("none of %v is installed on the worker", req.anyOf)
+}
+
+// HasRequirements reports whether any of names depends on a probed binary.
+func HasRequirements(names []string) bool {
+	for _, name := range names {
+		if _, ok := requirements[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Unavailable returns toolName -> reason for each of names that cannot run,
+// or nil if all can.
+func (c Capabilities) Unavailable(names []string) map[string]string {
+	var out map[string]string
+	for _, name := range names {
+		if reason := c.UnavailableReason(name); reason != "" {
+			if out == nil {
+				out = make(map[string]string)
+			}
+			out[name] = reason
+		}
+	}
+	return out
+}
+
+// SortedNames returns the keys of an Unavailable result in stable order.
+func SortedNames(unavailable map[string]string) []string {
+	names := make([]string, 0, len(unavailable))
+	for name := range unavailable {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
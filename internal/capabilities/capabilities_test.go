@@ -0,0 +1,41 @@
+package capabilities
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProbe_RecordsMissingBinaries(t *testing.T) {
+	caps := probe(func(name string) bool { return name != "rg" && name != "docker" })
+	assert.Equal(t, []string{"rg", "docker"}, caps.MissingBinaries)
+	assert.NotEmpty(t, caps.OS)
+	assert.True(t, caps.Missing("rg"))
+	assert.False(t, caps.Missing("git"))
+}
+
+func TestUnavailableReason(t *testing.T) {
+	caps := Capabilities{OS: "linux", MissingBinaries: []string{"rg", "bash"}}
+	assert.Contains(t, caps.UnavailableReason("grep_files"), "`rg`")
+	// sh is still present, so shell tools keep working.
+	assert.Empty(t, caps.UnavailableReason("shell_command"))
+	assert.Empty(t, caps.UnavailableReason("read_file"))
+
+	caps.MissingBinaries = append(caps.MissingBinaries, "sh")
+	assert.Contains(t, caps.UnavailableReason("exec_command"), "none of")
+
+	caps.OS = "windows"
+	assert.Empty(t, caps.UnavailableReason("shell_command"))
+}
+
+func TestUnavailable_ZeroValueGatesNothing(t *testing.T) {
+	assert.Nil(t, Capabilities{}.Unavailable([]string{"grep_files", "shell", "read_file"}))
+
+	got := Capabilities{MissingBinaries: []string{"rg"}}.Unavailable([]string{"grep_files", "read_file"})
+	assert.Equal(t, []string{"grep_files"}, SortedNames(got))
+}
+
+func TestHasRequirements(t *testing.T) {
+	assert.True(t, HasRequirements([]string{"read_file", "grep_files"}))
+	assert.False(t, HasRequirements([]string{"read_file", "request_user_input"}))
+}
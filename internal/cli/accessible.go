@@ -0,0 +1,208 @@
+package cli
+
+// Accessibility mode (--accessible, or accessible = true in config.toml) is
+// for screen readers. It builds on plain output: no alt screen, spinner or
+// ANSI escapes, and nothing is redrawn in place. On top of that,
+// conversation items are written as labeled blocks ("ASSISTANT:",
+// "TOOL shell: ls", "RESULT (ok):"), every state transition is announced
+// once on its own line ("STATUS: Waiting for approval"), and each choice
+// prompt lists its options with the keys that pick them, so no prompt needs
+// cursor movement.
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/mfateev/temporal-agent-harness/internal/mentions"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/workflow"
+)
+
+// accessibleOutputLines is how many lines of a tool result accessibility
+// mode reads out before summarizing the rest.
+const accessibleOutputLines = 5
+
+// WithAccessible makes the renderer write labeled plain-text blocks.
+func (r *ItemRenderer) WithAccessible(accessible bool) *ItemRenderer {
+	r.accessible = accessible
+	return r
+}
+
+// accessibleToolLabel names a tool in a "TOOL" label, e.g. "shell" or
+// "read file".
+func accessibleToolLabel(name string) string {
+	switch name {
+	case "shell", "shell_command", "exec_command":
+		return "shell"
+	}
+	return strings.ReplaceAll(name, "_", " ")
+}
+
+// renderAccessibleCall renders a tool call, e.g. "TOOL shell: ls -la".
+func renderAccessibleCall(label, verb, detail string) string {
+	if detail == "" {
+		return "\nTOOL " + label + ": " + verb + "\n"
+	}
+	return "\nTOOL " + label + ": " + detail + "\n"
+}
+
+// renderAccessibleOutput renders a tool result, reading out its first
+// lines and the number of lines left out.
+func renderAccessibleOutput(item models.ConversationItem) string {
+	status := "ok"
+	if item.Output.Success != nil && !*item.Output.Success {
+		status = "failed"
+	}
+	content := strings.TrimRight(item.Output.Content, "\n")
+	if content == "" {
+		return "RESULT (" + status + "): no output\n"
+	}
+	lines := strings.Split(content, "\n")
+	var b strings.Builder
+	b.WriteString("RESULT (" + status + "):\n")
+	shown := lines
+	if len(lines) > accessibleOutputLines {
+		shown = lines[:accessibleOutputLines]
+	}
+	for _, line := range shown {
+		b.WriteString(line + "\n")
+	}
+	if omitted := len(lines) - len(shown); omitted > 0 {
+		fmt.Fprintf(&b, "(%d more lines not shown)\n", omitted)
+	}
+	return b.String()
+}
+
+// renderAccessibleUserMessage renders a user message, e.g. "USER: hi".
+func renderAccessibleUserMessage(content string) string {
+	if strings.HasPrefix(content, "<environment_context>") {
+		return ""
+	}
+	if strings.HasPrefix(content, mentions.ContextPrefix) {
+		label, _ := mentions.Label(content)
+		return "ATTACHED: " + label + "\n"
+	}
+	return "\nUSER: " + content + "\n"
+}
+
+// renderAccessiblePostMortem renders the summary of a failed turn.
+func renderAccessiblePostMortem(pm *models.PostMortem) string {
+	var b strings.Builder
+	b.WriteString("\nERROR: Turn failed (" + string(pm.ErrorClass) + ")\n")
+	b.WriteString("Error: " + pm.Error + "\n")
+	if pm.Attempted != "" {
+		b.WriteString("Attempted: " + pm.Attempted + "\n")
+	}
+	if pm.ToolCalls > 0 {
+		fmt.Fprintf(&b, "Tool calls: %d (%d failed)\n", pm.ToolCalls, pm.FailedToolCalls)
+	}
+	if pm.LastSuccessfulStep != "" {
+		b.WriteString("Last successful step: " + pm.LastSuccessfulStep + "\n")
+	}
+	b.WriteString("Next: " + pm.SuggestedAction + "\n")
+	return b.String()
+}
+
+// renderAccessibleApprovalEntry writes one call awaiting approval, with its
+// preview between "Preview:" and "End of preview." lines.
+func renderAccessibleApprovalEntry(b *strings.Builder, index int, info approvalInfo, reason string) {
+	fmt.Fprintf(b, "Call %d: %s\n", index, info.Title)
+	if len(info.Preview) > 0 {
+		b.WriteString("Preview:\n")
+		for _, line := range info.Preview {
+			b.WriteString(line + "\n")
+		}
+		b.WriteString("End of preview.\n")
+	}
+	if reason != "" {
+		b.WriteString("Reason: " + reason + "\n")
+	}
+}
+
+// renderAccessiblePlan renders the plan with each step's status in words.
+func renderAccessiblePlan(plan *workflow.PlanState) string {
+	var b strings.Builder
+	if plan.Explanation != "" {
+		b.WriteString("\nPLAN: " + plan.Explanation + "\n")
+	} else {
+		b.WriteString("\nPLAN:\n")
+	}
+	for i, step := range plan.Steps {
+		status := "pending"
+		switch step.Status {
+		case workflow.PlanStepCompleted:
+			status = "done"
+		case workflow.PlanStepInProgress:
+			status = "in progress"
+		}
+		fmt.Fprintf(&b, "Step %d (%s): %s\n", i+1, status, step.Step)
+	}
+	return b.String()
+}
+
+// accessibleChoices lists the options of a selector with the keys that
+// pick them.
+func accessibleChoices(s *SelectorModel) string {
+	var b strings.Builder
+	b.WriteString("CHOICES:\n")
+	for i, opt := range s.options {
+		fmt.Fprintf(&b, "%d. %s", i+1, ansi.Strip(opt.Label))
+		if opt.ShortcutKey != 0 {
+			fmt.Fprintf(&b, " (key %c)", opt.ShortcutKey)
+		}
+		b.WriteString("\n")
+	}
+	if len(s.options) <= 9 {
+		b.WriteString("Press an option's number or key to choose it, or Escape to cancel.\n")
+	} else {
+		b.WriteString("Press an option's number or key to choose it, or use the Up and Down arrows and Enter; Escape cancels.\n")
+	}
+	return b.String()
+}
+
+// announceProgress replaces plain mode's progress lines: each phase is
+// announced once, and each new selector's choices are listed.
+func (m *Model) announceProgress(now time.Time) {
+	if phase, _ := m.progressPhase(); phase != "" && phase != m.progressPhaseName {
+		m.progressPhaseName = phase
+		m.progressSince = now
+		fmt.Fprintln(m.progressOut, "STATUS: "+phase)
+	}
+	m.announceSelector()
+}
+
+// announceSelector lists the choices of a selector that has not been
+// announced yet.
+func (m *Model) announceSelector() {
+	if m.selector == nil || m.selector == m.announcedSelector {
+		return
+	}
+	m.announcedSelector = m.selector
+	fmt.Fprint(m.progressOut, accessibleChoices(m.selector))
+}
+
+// handleAccessibleKey handles a key press and says what it changed that
+// would otherwise only be drawn: the option an arrow key moved to, a new
+// selector's choices, or the typed answer an approval now expects.
+func (m *Model) handleAccessibleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	sel, cursor := m.selector, 0
+	if sel != nil {
+		cursor = sel.Selected()
+	}
+	model, cmd := m.handleKeyMsg(msg)
+
+	switch {
+	case m.selector != nil && m.selector == sel:
+		if c := sel.Selected(); c != cursor && !sel.Confirmed() && !sel.Cancelled() {
+			fmt.Fprintf(m.progressOut, "Option %d of %d: %s\n", c+1, len(sel.options), ansi.Strip(sel.options[c].Label))
+		}
+	case sel != nil && m.selector == nil && m.state == StateApproval:
+		fmt.Fprintln(m.progressOut, "Type y, n, a, or the numbers of the calls to allow (e.g. 1,3), then press Enter.")
+	}
+	m.announceSelector()
+	return model, cmd
+}
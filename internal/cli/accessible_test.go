@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/workflow"
+)
+
+func TestAccessibleRenderer_LabeledBlocks(t *testing.T) {
+	r := NewItemRenderer(plainWidth, true, true, NoColorStyles()).WithAccessible(true)
+	ok, failed := true, false
+
+	assert.Equal(t, "\nASSISTANT:\nHello **there**\n",
+		r.RenderItem(models.ConversationItem{Type: models.ItemTypeAssistantMessage, Content: "Hello **there**\n"}, false))
+	assert.Equal(t, "\nTOOL shell: ls -la\n",
+		r.RenderItem(models.ConversationItem{Type: models.ItemTypeFunctionCall, Name: "shell", Arguments: `{"command":"ls -la"}`}, false))
+	assert.Equal(t, "\nTOOL read file: main.go\n",
+		r.RenderItem(models.ConversationItem{Type: models.ItemTypeFunctionCall, Name: "read_file", Arguments: `{"file_path":"main.go"}`}, false))
+	assert.Equal(t, "RESULT (ok):\na\nb\n",
+		r.RenderItem(models.ConversationItem{Type: models.ItemTypeFunctionCallOutput, Output: &models.FunctionCallOutputPayload{Content: "a\nb\n", Success: &ok}}, false))
+	assert.Equal(t, "RESULT (failed): no output\n",
+		r.RenderItem(models.ConversationItem{Type: models.ItemTypeFunctionCallOutput, Output: &models.FunctionCallOutputPayload{Success: &failed}}, false))
+	assert.Equal(t, "RESULT (ok):\n1\n2\n3\n4\n5\n(2 more lines not shown)\n",
+		r.RenderItem(models.ConversationItem{Type: models.ItemTypeFunctionCallOutput, Output: &models.FunctionCallOutputPayload{Content: "1\n2\n3\n4\n5\n6\n7"}}, false))
+	assert.Equal(t, "\nUSER: fix it\n", r.RenderUserMessage(models.ConversationItem{Content: "fix it"}))
+	assert.Equal(t, "NOTE: Model switched\n", r.RenderSystemMessage("Model switched"))
+
+	plan := r.RenderPlan(&workflow.PlanState{Steps: []workflow.PlanStep{
+		{Step: "Read", Status: workflow.PlanStepCompleted},
+		{Step: "Fix", Status: workflow.PlanStepInProgress},
+		{Step: "Test", Status: workflow.PlanStepPending},
+	}})
+	assert.Equal(t, "\nPLAN:\nStep 1 (done): Read\nStep 2 (in progress): Fix\nStep 3 (pending): Test\n", plan)
+}
+
+func TestAccessibleRenderer_ApprovalHasNoBoxDrawing(t *testing.T) {
+	r := NewItemRenderer(plainWidth, true, true, NoColorStyles()).WithAccessible(true)
+	out := r.RenderApprovalContext([]workflow.PendingApproval{{
+		CallID: "c1", ToolName: "write_file", Arguments: `{"path":"a.txt","content":"hi"}`, Reason: "writes a file",
+	}})
+
+	assert.True(t, strings.HasPrefix(out, "\nAPPROVAL NEEDED:\nCall 1: "), out)
+	assert.Contains(t, out, "Preview:\n")
+	assert.Contains(t, out, "End of preview.\n")
+	assert.Contains(t, out, "Reason: writes a file\n")
+	assert.NotContains(t, out, "╭")
+	assert.NotContains(t, out, "│")
+}
+
+func TestAccessibleMode_AnnouncesTransitionsAndChoices(t *testing.T) {
+	m := NewModel(Config{Model: "gpt-4o-mini", Message: "hello", OutputMode: OutputPlain, Accessible: true}, nil)
+	var out, progress strings.Builder
+	m.plainOut = &out
+	m.progressOut = &progress
+
+	start := time.Now()
+	m.state = StateWatching
+	m.spinnerMsg = "Thinking..."
+	m.updatePlainProgress(start)
+	m.updatePlainProgress(start.Add(plainProgressInterval + time.Second))
+
+	approvals := []workflow.PendingApproval{{CallID: "c1", ToolName: "shell", Arguments: `{"command":"ls"}`}}
+	m.state = StateApproval
+	m.pendingApprovals = approvals
+	m.selector = m.buildApprovalSelector(approvals)
+	m.updatePlainProgress(start.Add(20 * time.Second))
+	m.updatePlainProgress(start.Add(21 * time.Second))
+
+	assert.Equal(t, "STATUS: Thinking...\n"+
+		"STATUS: Waiting for approval\n"+
+		"CHOICES:\n"+
+		"1. Yes, allow (key y)\n"+
+		"2. No, deny (key n)\n"+
+		"3. Always allow for this session (key a)\n"+
+		"Press an option's number or key to choose it, or Escape to cancel.\n", progress.String(),
+		"busy phases are not repeated and choices are listed once")
+
+	progress.Reset()
+	m.handleAccessibleKey(tea.KeyMsg{Type: tea.KeyDown})
+	assert.Equal(t, "Option 2 of 3: No, deny\n", progress.String())
+}
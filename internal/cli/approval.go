@@ -128,6 +128,75 @@ func parseApprovalIndices(input string, maxIndex int) []int {
 	return indices
 }
 
+// approvalGroup is a set of pending approvals decided together: calls whose
+// PendingApproval.Group matches, or a single ungrouped call.
+type approvalGroup struct {
+	Label   string // e.g. "Run `go test <path>` (4 calls)"
+	Indices []int  // 0-based indices into the pending list
+}
+
+// indexList renders the group's 1-based indices, e.g. "1,2,4".
+func (g approvalGroup) indexList() string {
+	parts := make([]string, len(g.Indices))
+	for i, idx := range g.Indices {
+		parts[i] = fmt.Sprintf("%d", idx+1)
+	}
+	return strings.Join(parts, ",")
+}
+
+// callIDs returns the call IDs of the group's members.
+func (g approvalGroup) callIDs(pending []workflow.PendingApproval) []string {
+	ids := make([]string, len(g.Indices))
+	for i, idx := range g.Indices {
+		ids[i] = pending[idx].CallID
+	}
+	return ids
+}
+
+// groupApprovals partitions pending approvals by tool and Group, in order of
+// first appearance. Ungrouped approvals form single-member groups.
+func groupApprovals(pending []workflow.PendingApproval) []approvalGroup {
+	var groups []approvalGroup
+	byKey := make(map[string]int)
+	for i, ap := range pending {
+		if ap.Group == "" {
+			groups = append(groups, approvalGroup{
+				Label:   formatApprovalInfo(ap.ToolName, ap.Arguments).Title,
+				Indices: []int{i},
+			})
+			continue
+		}
+		key := ap.ToolName + "\x00" + ap.Group
+		if gi, ok := byKey[key]; ok {
+			groups[gi].Indices = append(groups[gi].Indices, i)
+			continue
+		}
+		byKey[key] = len(groups)
+		groups = append(groups, approvalGroup{Indices: []int{i}})
+	}
+	for gi := range groups {
+		if ap := pending[groups[gi].Indices[0]]; ap.Group != "" {
+			groups[gi].Label = fmt.Sprintf("Run `%s` (%d calls)", ap.Group, len(groups[gi].Indices))
+		}
+	}
+	return groups
+}
+
+// hasMultiCallGroups reports whether pending approvals form more than one
+// group and at least one group has several calls — the case where deciding
+// per group saves prompts.
+func hasMultiCallGroups(groups []approvalGroup) bool {
+	if len(groups) < 2 {
+		return false
+	}
+	for _, g := range groups {
+		if len(g.Indices) > 1 {
+			return true
+		}
+	}
+	return false
+}
+
 // ApprovalSelectionToResponse maps a selector index to an ApprovalResponse.
 // Options: 0=approve all, 1=deny all, 2=always approve, 3=select individually (returns nil).
 func ApprovalSelectionToResponse(selected int, pending []workflow.PendingApproval) (*workflow.ApprovalResponse, bool) {
@@ -180,7 +249,7 @@ func formatApprovalInfo(toolName, arguments string) approvalInfo {
 	var args map[string]interface{}
 	if json.Unmarshal([]byte(arguments), &args) == nil {
 		switch toolName {
-		case "shell":
+		case "shell", "shell_command":
 			if cmd, ok := args["command"].(string); ok {
 				return approvalInfo{Title: "Shell: " + cmd}
 			}
@@ -232,6 +301,36 @@ func formatApprovalInfo(toolName, arguments string) approvalInfo {
 	return approvalInfo{Title: toolName + ": " + display}
 }
 
+// maxApprovalPreviewLines caps the git preview shown in an approval prompt.
+const maxApprovalPreviewLines = 12
+
+// approvalPreviewLines renders the workflow's git preview for an approval:
+// the preview command followed by its (middle-truncated) output.
+func approvalPreviewLines(ap workflow.PendingApproval) []string {
+	lines := []string{"$ " + ap.PreviewCommand}
+	if strings.TrimSpace(ap.Preview) == "" {
+		return append(lines, "(no output)")
+	}
+	return append(lines, contentPreview(ap.Preview, maxApprovalPreviewLines)...)
+}
+
+// maxPatchPreviewLines caps the patch diff shown in an approval prompt,
+// as formatPatchDiff does for the raw patch.
+const maxPatchPreviewLines = 100
+
+// patchPreviewLines renders the workflow's patch preview for an approval:
+// the conflict, if any, followed by the diff the patch would make.
+func patchPreviewLines(ap workflow.PendingApproval) []string {
+	var lines []string
+	if ap.PreviewError != "" {
+		lines = append(lines, "! "+ap.PreviewError)
+	}
+	if strings.TrimSpace(ap.Preview) != "" {
+		lines = append(lines, contentPreview(ap.Preview, maxPatchPreviewLines)...)
+	}
+	return lines
+}
+
 // contentPreview splits content into lines and returns at most maxLines,
 // using middle truncation if the content exceeds the limit.
 func contentPreview(content string, maxLines int) []string {
@@ -413,3 +512,14 @@ func classifyPollError(err error) pollErrorKind {
 
 	return pollErrorFatal
 }
+
+// approvalExpired reports whether an approval response was rejected because
+// the approvals it answers are no longer pending (they expired or were
+// resolved elsewhere).
+func approvalExpired(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "no longer pending") || strings.Contains(msg, "no approval pending")
+}
@@ -400,3 +400,42 @@ func TestHandleEscalationInput_Invalid(t *testing.T) {
 	resp := HandleEscalationInput("maybe", pending)
 	assert.Nil(t, resp)
 }
+
+// --- Approval grouping tests ---
+
+func groupedApprovals() []workflow.PendingApproval {
+	return []workflow.PendingApproval{
+		{CallID: "c1", ToolName: "shell_command", Arguments: `{"command":"go test ./a"}`, Group: "go test <path>"},
+		{CallID: "c2", ToolName: "write_file", Arguments: `{"path":"x.go"}`},
+		{CallID: "c3", ToolName: "shell_command", Arguments: `{"command":"go test ./b"}`, Group: "go test <path>"},
+		{CallID: "c4", ToolName: "shell_command", Arguments: `{"command":"go test ./c"}`, Group: "go test <path>"},
+	}
+}
+
+func TestGroupApprovals(t *testing.T) {
+	groups := groupApprovals(groupedApprovals())
+	require.Len(t, groups, 2)
+	assert.Equal(t, []int{0, 2, 3}, groups[0].Indices)
+	assert.Equal(t, "Run `go test <path>` (3 calls)", groups[0].Label)
+	assert.Equal(t, "1,3,4", groups[0].indexList())
+	assert.Equal(t, []string{"c1", "c3", "c4"}, groups[0].callIDs(groupedApprovals()))
+	assert.Equal(t, []int{1}, groups[1].Indices)
+	assert.Equal(t, "Write file: x.go", groups[1].Label)
+	assert.True(t, hasMultiCallGroups(groups))
+}
+
+func TestHasMultiCallGroups_SingleGroupOrAllSingletons(t *testing.T) {
+	pending := groupedApprovals()
+	assert.False(t, hasMultiCallGroups(groupApprovals([]workflow.PendingApproval{pending[0], pending[2]})),
+		"one group is already a single decision")
+	assert.False(t, hasMultiCallGroups(groupApprovals([]workflow.PendingApproval{
+		{CallID: "a", ToolName: "write_file"}, {CallID: "b", ToolName: "apply_patch"},
+	})))
+}
+
+func TestApprovalExpired(t *testing.T) {
+	assert.False(t, approvalExpired(nil))
+	assert.False(t, approvalExpired(fmt.Errorf("connection refused")))
+	assert.True(t, approvalExpired(fmt.Errorf("approval for call-1 is no longer pending (it may have expired)")))
+	assert.True(t, approvalExpired(fmt.Errorf("no approval pending")))
+}
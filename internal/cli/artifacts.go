@@ -0,0 +1,374 @@
+package cli
+
+// An artifacts bundle (--artifacts-dir, with --local -m) is the
+// machine-readable record of a headless run, for CI jobs that apply the
+// patch, post comments or gate a merge without parsing terminal output:
+//
+//	summary.json       commands run, verification results, usage, final message
+//	diff.patch         the working tree changes, for git apply
+//	final_message.md   the model's last message
+//
+// The bundle can also go to a bucket (--artifacts-dir s3://bucket/prefix or
+// gs://bucket/prefix); summary.json then links the other files by signed
+// URLs.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mfateev/temporal-agent-harness/internal/blobstore"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/workflow"
+)
+
+// Names of the files in an artifacts bundle.
+const (
+	artifactsSummaryFile = "summary.json"
+	artifactsPatchFile   = "diff.patch"
+	artifactsMessageFile = "final_message.md"
+)
+
+// artifactsVersion is the summary.json format version.
+const artifactsVersion = 1
+
+// verifyOutputLimit is how many trailing bytes of a verification command's
+// output are kept.
+const verifyOutputLimit = 16 * 1024
+
+// ArtifactsSummary is the content of summary.json.
+type ArtifactsSummary struct {
+	Version  int    `json:"version"`
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Cwd      string `json:"cwd"`
+	Prompt   string `json:"prompt"`
+	// FinalMessage is the model's last message, also in final_message.md.
+	FinalMessage string `json:"final_message"`
+	// Patch names the patch file, or is empty when the tree is unchanged
+	// or not a git repository (PatchError says why).
+	Patch      string `json:"patch,omitempty"`
+	PatchError string `json:"patch_error,omitempty"`
+	// PatchURL and FinalMessageURL are signed URLs of the bundle's files
+	// when it was written to an object store.
+	PatchURL        string                 `json:"patch_url,omitempty"`
+	FinalMessageURL string                 `json:"final_message_url,omitempty"`
+	ChangedFiles    []string               `json:"changed_files"`
+	Commands        []ArtifactCommand      `json:"commands"`
+	Verification    []ArtifactVerification `json:"verification"`
+	// VerificationPassed is set when there are verification results: true
+	// if all of them passed.
+	VerificationPassed *bool         `json:"verification_passed,omitempty"`
+	Usage              ArtifactUsage `json:"usage"`
+}
+
+// ArtifactCommand is a shell command the model ran.
+type ArtifactCommand struct {
+	CallID  string `json:"call_id"`
+	Tool    string `json:"tool"`
+	Command string `json:"command"`
+	Success bool   `json:"success"`
+	// ExitCode is 0 for a successful command and the reported code for
+	// one that failed; it is omitted when the tool did not report it (a
+	// denied call, or a shell failure without a code).
+	ExitCode *int `json:"exit_code,omitempty"`
+}
+
+// ArtifactVerification is the result of a verification command: a plan
+// step's verify command, or --verify after the turn.
+type ArtifactVerification struct {
+	// Step is the 1-based plan step, or 0 for --verify.
+	Step     int    `json:"step,omitempty"`
+	Command  string `json:"command"`
+	Passed   bool   `json:"passed"`
+	ExitCode *int   `json:"exit_code,omitempty"`
+	Output   string `json:"output"`
+}
+
+// ArtifactUsage is the token and cost summary of the run.
+type ArtifactUsage struct {
+	models.TokenUsage
+	// EstimatedCostUSD is omitted when the model has no known price.
+	EstimatedCostUSD *float64 `json:"estimated_cost_usd,omitempty"`
+}
+
+var exitCodeLine = regexp.MustCompile(`--- Exit code: (-?\d+) ---`)
+
+// buildArtifactsSummary collects the commands, plan verifications and final
+// message of a run from its history.
+func buildArtifactsSummary(items []models.ConversationItem) ArtifactsSummary {
+	summary := ArtifactsSummary{
+		Version:      artifactsVersion,
+		ChangedFiles: []string{},
+		Commands:     []ArtifactCommand{},
+		Verification: []ArtifactVerification{},
+	}
+	calls := make(map[string]models.ConversationItem)
+	for _, item := range items {
+		switch item.Type {
+		case models.ItemTypeFunctionCall:
+			calls[item.CallID] = item
+		case models.ItemTypeFunctionCallOutput:
+			call, ok := calls[item.CallID]
+			if !ok || item.Output == nil {
+				continue
+			}
+			if cmd := artifactShellCommand(call.Name, call.Arguments); cmd != "" {
+				summary.Commands = append(summary.Commands, artifactCommand(call, cmd, item.Output))
+			}
+		case models.ItemTypeUserMessage:
+			if v, ok := parsePlanVerification(item.Content); ok {
+				summary.Verification = append(summary.Verification, v)
+			}
+		case models.ItemTypeAssistantMessage:
+			summary.FinalMessage = item.Content
+		}
+	}
+	return summary
+}
+
+// artifactShellCommand returns the command line of a shell tool call, or ""
+// for other tools.
+func artifactShellCommand(name, arguments string) string {
+	var args map[string]interface{}
+	if json.Unmarshal([]byte(arguments), &args) != nil {
+		return ""
+	}
+	switch name {
+	case "shell":
+		arr, _ := args["command"].([]interface{})
+		parts := make([]string, 0, len(arr))
+		for _, v := range arr {
+			if s, ok := v.(string); ok {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, " ")
+	case "shell_command":
+		cmd, _ := args["command"].(string)
+		return cmd
+	case "exec_command":
+		cmd, _ := args["cmd"].(string)
+		return cmd
+	}
+	return ""
+}
+
+func artifactCommand(call models.ConversationItem, command string, out *models.FunctionCallOutputPayload) ArtifactCommand {
+	c := ArtifactCommand{CallID: call.CallID, Tool: call.Name, Command: command}
+	c.Success = out.Success == nil || *out.Success
+	if m := exitCodeLine.FindStringSubmatch(out.Content); m != nil {
+		code, _ := strconv.Atoi(m[1])
+		c.ExitCode = &code
+	} else if c.Success {
+		code := 0
+		c.ExitCode = &code
+	}
+	return c
+}
+
+// parsePlanVerification parses the history item runPlanVerification records.
+func parsePlanVerification(content string) (ArtifactVerification, bool) {
+	v, ok := workflow.ParsePlanVerification(content)
+	if !ok {
+		return ArtifactVerification{}, false
+	}
+	return ArtifactVerification{Step: v.Step, Command: v.Command, Passed: v.Passed, Output: v.Output}, true
+}
+
+// runVerification runs command with sh in cwd and records its result.
+func runVerification(cwd, command string) ArtifactVerification {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Dir = cwd
+	out, err := cmd.CombinedOutput()
+	v := ArtifactVerification{Command: command, Passed: err == nil}
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		code := 0
+		v.ExitCode = &code
+	case errors.As(err, &exitErr):
+		code := exitErr.ExitCode()
+		v.ExitCode = &code
+	default:
+		out = append(out, []byte(err.Error())...)
+	}
+	if len(out) > verifyOutputLimit {
+		out = out[len(out)-verifyOutputLimit:]
+	}
+	v.Output = string(out)
+	return v
+}
+
+// gitPatch returns the changes of the working tree containing cwd against
+// HEAD, untracked files included, as a patch git apply accepts at the
+// repository root, and the changed paths relative to the root.
+func gitPatch(cwd string) (string, []string, error) {
+	root := execGit(cwd, "rev-parse", "--show-toplevel")
+	if root == "" {
+		return "", nil, errors.New("not in a git repository")
+	}
+	cwd = root
+	patch, err := gitOutput(cwd, "diff", "--binary", "HEAD")
+	names, _ := gitOutput(cwd, "diff", "--name-only", "HEAD")
+	if err != nil { // no commits yet: diff the index against the empty tree
+		patch, err = gitOutput(cwd, "diff", "--binary", "--cached")
+		if err != nil {
+			return "", nil, err
+		}
+		names, _ = gitOutput(cwd, "diff", "--name-only", "--cached")
+	}
+	var b strings.Builder
+	b.WriteString(patch)
+	var files []string
+	for _, name := range strings.Split(names, "\n") {
+		if name != "" {
+			files = append(files, name)
+		}
+	}
+	for _, file := range untrackedFiles(cwd) {
+		b.WriteString(diffUntracked(cwd, file, "--binary"))
+		files = append(files, file)
+	}
+	return b.String(), files, nil
+}
+
+// gitOutput runs a git command in cwd and returns its stdout untrimmed.
+func gitOutput(cwd string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = cwd
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errors.New(strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// finishArtifacts completes summary with the working tree's patch and the
+// overall verification result, and returns the patch ("" when unchanged).
+func finishArtifacts(cwd string, summary *ArtifactsSummary) string {
+	summary.Cwd = cwd
+	patch, files, err := gitPatch(cwd)
+	if err != nil {
+		summary.PatchError = err.Error()
+	} else if patch != "" {
+		summary.Patch = artifactsPatchFile
+		summary.ChangedFiles = files
+	}
+	if len(summary.Verification) > 0 {
+		passed := true
+		for _, v := range summary.Verification {
+			passed = passed && v.Passed
+		}
+		summary.VerificationPassed = &passed
+	}
+	return patch
+}
+
+// writeArtifacts writes the bundle of a finished run to dir, creating it if
+// needed. The patch is taken from the working tree at cwd.
+func writeArtifacts(dir, cwd string, summary ArtifactsSummary) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	if patch := finishArtifacts(cwd, &summary); patch != "" {
+		if err := os.WriteFile(filepath.Join(dir, artifactsPatchFile), []byte(patch), 0o644); err != nil {
+			return err
+		}
+	} else if err := os.Remove(filepath.Join(dir, artifactsPatchFile)); err != nil && !os.IsNotExist(err) {
+		// A patch left by an earlier run must not be applied.
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, artifactsMessageFile), []byte(summary.FinalMessage), 0o644); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, artifactsSummaryFile), append(data, '\n'), 0o644)
+}
+
+// isArtifactsURL reports whether --artifacts-dir names a bucket location
+// (s3://bucket/prefix or gs://bucket/prefix) rather than a directory.
+func isArtifactsURL(dir string) bool {
+	return strings.HasPrefix(dir, "s3://") || strings.HasPrefix(dir, "gs://")
+}
+
+// openArtifactsStore opens the bucket location of --artifacts-dir. The
+// endpoint, region and path style come from [storage] in config.toml when
+// it names the same kind of store; credentials from the environment.
+func openArtifactsStore(location, codexHome string) (blobstore.Store, time.Duration, error) {
+	u, err := url.Parse(location)
+	if err != nil || u.Host == "" {
+		return nil, 0, fmt.Errorf("invalid artifacts location %q", location)
+	}
+	var tc models.StorageToml
+	if data, err := os.ReadFile(filepath.Join(codexHome, "config.toml")); err == nil {
+		if parsed, err := models.ParseConfigToml(data); err == nil && parsed.Storage != nil {
+			tc = *parsed.Storage
+		}
+	}
+	backend := blobstore.BackendS3
+	if u.Scheme == "gs" {
+		backend = blobstore.BackendGCS
+	} else if tc.Backend != nil && *tc.Backend == blobstore.BackendMinIO {
+		backend = blobstore.BackendMinIO
+	}
+	if tc.Backend == nil || *tc.Backend != backend {
+		// The endpoint of another kind of store does not apply.
+		tc.Endpoint, tc.Region, tc.PathStyle = nil, nil, nil
+	}
+	bucket, prefix := u.Host, strings.Trim(u.Path, "/")
+	tc.Backend, tc.Bucket, tc.Prefix = &backend, &bucket, &prefix
+	cfg, err := blobstore.Resolve(&tc, "", nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	store, err := blobstore.Open(cfg)
+	return store, cfg.SignedURLTTL, err
+}
+
+// storeArtifacts writes the bundle of a finished run to an object store.
+// summary.json references the patch and final message by signed URLs valid
+// for ttl, so a report can link them without embedding them.
+func storeArtifacts(ctx context.Context, store blobstore.Store, ttl time.Duration, cwd string, summary ArtifactsSummary) error {
+	patch := finishArtifacts(cwd, &summary)
+	if patch != "" {
+		if err := store.Put(ctx, artifactsPatchFile, []byte(patch)); err != nil {
+			return err
+		}
+		signed, err := store.SignedURL(ctx, artifactsPatchFile, ttl)
+		if err != nil {
+			return err
+		}
+		summary.PatchURL = signed
+	} else if err := store.Delete(ctx, artifactsPatchFile); err != nil {
+		return err
+	}
+	if err := store.Put(ctx, artifactsMessageFile, []byte(summary.FinalMessage)); err != nil {
+		return err
+	}
+	signed, err := store.SignedURL(ctx, artifactsMessageFile, ttl)
+	if err != nil {
+		return err
+	}
+	summary.FinalMessageURL = signed
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	return store.Put(ctx, artifactsSummaryFile, append(data, '\n'))
+}
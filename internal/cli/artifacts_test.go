@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+func TestBuildArtifactsSummary(t *testing.T) {
+	ok, failed := true, false
+	items := []models.ConversationItem{
+		{Type: models.ItemTypeUserMessage, Content: "fix the build"},
+		{Type: models.ItemTypeFunctionCall, CallID: "c1", Name: "shell", Arguments: `{"command":["go","build","./..."]}`},
+		{Type: models.ItemTypeFunctionCall, CallID: "c2", Name: "exec_command", Arguments: `{"cmd":"go test ./..."}`},
+		{Type: models.ItemTypeFunctionCall, CallID: "c3", Name: "read_file", Arguments: `{"file_path":"main.go"}`},
+		{Type: models.ItemTypeFunctionCallOutput, CallID: "c1", Output: &models.FunctionCallOutputPayload{Content: "", Success: &ok}},
+		{Type: models.ItemTypeFunctionCallOutput, CallID: "c2", Output: &models.FunctionCallOutputPayload{Content: "--- Wall time: 1.000s ---\n--- Exit code: 2 ---\n--- Output ---\nFAIL\n", Success: &failed}},
+		{Type: models.ItemTypeFunctionCallOutput, CallID: "c3", Output: &models.FunctionCallOutputPayload{Content: "package main", Success: &ok}},
+		{Type: models.ItemTypeUserMessage, Content: "<plan_verification step=\"1\" command=\"go vet \\\"./...\\\"\" passed=\"false\">\nvet: bad\n</plan_verification>"},
+		{Type: models.ItemTypeAssistantMessage, Content: "First try"},
+		{Type: models.ItemTypeAssistantMessage, Content: "Fixed the build."},
+	}
+
+	summary := buildArtifactsSummary(items)
+
+	zero, two := 0, 2
+	assert.Equal(t, []ArtifactCommand{
+		{CallID: "c1", Tool: "shell", Command: "go build ./...", Success: true, ExitCode: &zero},
+		{CallID: "c2", Tool: "exec_command", Command: "go test ./...", Success: false, ExitCode: &two},
+	}, summary.Commands)
+	assert.Equal(t, []ArtifactVerification{
+		{Step: 1, Command: `go vet "./..."`, Passed: false, Output: "vet: bad"},
+	}, summary.Verification)
+	assert.Equal(t, "Fixed the build.", summary.FinalMessage)
+}
+
+func TestRunVerification(t *testing.T) {
+	dir := t.TempDir()
+	v := runVerification(dir, "echo checking; exit 3")
+	assert.False(t, v.Passed)
+	require.NotNil(t, v.ExitCode)
+	assert.Equal(t, 3, *v.ExitCode)
+	assert.Equal(t, "checking\n", v.Output)
+
+	v = runVerification(dir, "true")
+	assert.True(t, v.Passed)
+	assert.Equal(t, 0, *v.ExitCode)
+}
+
+func TestWriteArtifacts_PatchApplies(t *testing.T) {
+	repo := initTestGitRepo(t)
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "file.txt"), []byte("changed\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "new.txt"), []byte("new\n"), 0644))
+
+	out := t.TempDir()
+	summary := ArtifactsSummary{FinalMessage: "done", Verification: []ArtifactVerification{{Command: "true", Passed: true}}}
+	require.NoError(t, writeArtifacts(out, repo, summary))
+
+	var written ArtifactsSummary
+	data, err := os.ReadFile(filepath.Join(out, artifactsSummaryFile))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, artifactsPatchFile, written.Patch)
+	assert.Equal(t, []string{"file.txt", "new.txt"}, written.ChangedFiles)
+	require.NotNil(t, written.VerificationPassed)
+	assert.True(t, *written.VerificationPassed)
+
+	message, err := os.ReadFile(filepath.Join(out, artifactsMessageFile))
+	require.NoError(t, err)
+	assert.Equal(t, "done", string(message))
+
+	// The patch recreates the changes on a clean checkout.
+	gitCmd(t, repo, "checkout", "--", "file.txt")
+	require.NoError(t, os.Remove(filepath.Join(repo, "new.txt")))
+	gitCmd(t, repo, "apply", filepath.Join(out, artifactsPatchFile))
+	content, err := os.ReadFile(filepath.Join(repo, "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "changed\n", string(content))
+	assert.FileExists(t, filepath.Join(repo, "new.txt"))
+}
+
+func TestWriteArtifacts_UnchangedTreeRemovesStalePatch(t *testing.T) {
+	repo := initTestGitRepo(t)
+	out := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(out, artifactsPatchFile), []byte("stale"), 0644))
+
+	require.NoError(t, writeArtifacts(out, repo, ArtifactsSummary{}))
+
+	assert.NoFileExists(t, filepath.Join(out, artifactsPatchFile))
+}
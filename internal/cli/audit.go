@@ -0,0 +1,178 @@
+package cli
+
+// audit.go implements `tcx audit export`: it reads the audit log of one or
+// all sessions with the get_audit_log query and writes every mutating tool
+// call — time, tool, command or path, approver, decision, sandbox mode and
+// exit code — as CSV or JSON, as evidence for compliance reviews.
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.temporal.io/sdk/client"
+
+	"github.com/mfateev/temporal-agent-harness/internal/temporalclient"
+	"github.com/mfateev/temporal-agent-harness/internal/workflow"
+)
+
+// AuditExportOptions configures `tcx audit export`.
+type AuditExportOptions struct {
+	Connection temporalclient.ConnectionConfig
+	// SessionID is the session to export; ignored when All is set.
+	SessionID string
+	// All exports every session, optionally narrowed by Query, an extra
+	// visibility filter ANDed with the session query.
+	All   bool
+	Query string
+	// Format is "csv" (default) or "json".
+	Format string
+
+	Out io.Writer
+	Err io.Writer
+}
+
+// auditSessionsQuery selects every session's latest run; ContinuedAsNew
+// runs are excluded because their audit log is carried into the next run.
+const auditSessionsQuery = `WorkflowType = 'AgenticWorkflow' AND ExecutionStatus != 'ContinuedAsNew'`
+
+// auditQueryTimeout bounds the get_audit_log query of one session.
+const auditQueryTimeout = 30 * time.Second
+
+// AuditRow is one exported audit record with its session.
+type AuditRow struct {
+	SessionID string `json:"session_id"`
+	workflow.AuditRecord
+}
+
+// auditCSVHeader is the header row of the CSV export.
+var auditCSVHeader = []string{"session_id", "time", "turn_id", "call_id", "tool", "target", "approver", "decision", "sandbox", "exit_code", "success"}
+
+// RunAuditExport implements `tcx audit export`.
+func RunAuditExport(opts AuditExportOptions) error {
+	if opts.Out == nil {
+		opts.Out = os.Stdout
+	}
+	if opts.Err == nil {
+		opts.Err = os.Stderr
+	}
+	if opts.Format == "" {
+		opts.Format = "csv"
+	}
+	if opts.Format != "csv" && opts.Format != "json" {
+		return fmt.Errorf("unknown format %q (use csv or json)", opts.Format)
+	}
+	if !opts.All && opts.SessionID == "" {
+		return fmt.Errorf("pass --session <id> or --all")
+	}
+
+	clientOpts, err := temporalclient.LoadClientOptionsFromConfig(opts.Connection)
+	if err != nil {
+		return fmt.Errorf("failed to load Temporal client config: %w", err)
+	}
+	c, err := temporalclient.DialWithRetry(clientOpts, opts.Connection.DialRetries, opts.Connection.DialRetryBackoff)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Temporal: %w", err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+
+	sessions := []string{opts.SessionID}
+	if opts.All {
+		query := auditSessionsQuery
+		if opts.Query != "" {
+			query += " AND (" + opts.Query + ")"
+		}
+		sessions = nil
+		err := listExecutions(ctx, c, query, func(exec *workflowInfo) {
+			sessions = append(sessions, exec.WorkflowID)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list sessions: %w", err)
+		}
+		sort.Strings(sessions)
+	}
+
+	var rows []AuditRow
+	for _, id := range sessions {
+		log, err := queryAuditLog(ctx, c, id)
+		if err != nil {
+			if !opts.All {
+				return fmt.Errorf("failed to read the audit log of %s: %w", id, err)
+			}
+			// Sessions of older workers have no audit log; keep going.
+			fmt.Fprintf(opts.Err, "warning: skipping %s: %v\n", id, err)
+			continue
+		}
+		if log.Dropped > 0 {
+			fmt.Fprintf(opts.Err, "warning: %s: the %d oldest record(s) were dropped from the bounded audit log\n", id, log.Dropped)
+		}
+		for _, r := range log.Records {
+			rows = append(rows, AuditRow{SessionID: id, AuditRecord: r})
+		}
+	}
+	return writeAuditRows(opts.Out, opts.Format, rows)
+}
+
+// queryAuditLog reads the audit log of a session's latest run.
+func queryAuditLog(ctx context.Context, c client.Client, sessionID string) (workflow.AuditLog, error) {
+	qctx, cancel := context.WithTimeout(ctx, auditQueryTimeout)
+	defer cancel()
+	var log workflow.AuditLog
+	resp, err := c.QueryWorkflow(qctx, sessionID, "", workflow.QueryGetAuditLog)
+	if err != nil {
+		return log, err
+	}
+	err = resp.Get(&log)
+	return log, err
+}
+
+// writeAuditRows writes rows as CSV with a header row, or as a JSON array.
+func writeAuditRows(w io.Writer, format string, rows []AuditRow) error {
+	if format == "json" {
+		if rows == nil {
+			rows = []AuditRow{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(auditCSVHeader); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		exitCode, success := "", ""
+		if r.ExitCode != nil {
+			exitCode = strconv.Itoa(*r.ExitCode)
+		}
+		if r.Success != nil {
+			success = strconv.FormatBool(*r.Success)
+		}
+		err := cw.Write([]string{
+			r.SessionID, r.Time.UTC().Format(time.RFC3339), r.TurnID, r.CallID, r.Tool, r.Target,
+			r.Approver, string(r.Decision), r.Sandbox, exitCode, success,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// localApprover names the local user in approval and escalation responses,
+// for the audit log.
+func localApprover() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
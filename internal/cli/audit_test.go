@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/workflow"
+)
+
+func sampleAuditRows() []AuditRow {
+	exitCode, ok := 1, false
+	return []AuditRow{
+		{SessionID: "s-1", AuditRecord: workflow.AuditRecord{
+			Time: time.Date(2026, 3, 1, 6, 0, 0, 0, time.UTC), TurnID: "turn-1", CallID: "c1", Tool: "shell_command",
+			Target: "go test ./...\necho \"done\"", Approver: "alice", Decision: workflow.AuditApproved,
+			Sandbox: "workspace-write", ExitCode: &exitCode, Success: &ok,
+		}},
+		{SessionID: "s-1", AuditRecord: workflow.AuditRecord{
+			Time: time.Date(2026, 3, 1, 6, 1, 0, 0, time.UTC), CallID: "c2", Tool: "write_file",
+			Target: "a.txt", Approver: workflow.AuditApproverPolicy, Decision: workflow.AuditForbidden,
+		}},
+	}
+}
+
+func TestWriteAuditRows_CSV(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, writeAuditRows(&b, "csv", sampleAuditRows()))
+
+	assert.Equal(t, "session_id,time,turn_id,call_id,tool,target,approver,decision,sandbox,exit_code,success\n"+
+		"s-1,2026-03-01T06:00:00Z,turn-1,c1,shell_command,\"go test ./...\necho \"\"done\"\"\",alice,approved,workspace-write,1,false\n"+
+		"s-1,2026-03-01T06:01:00Z,,c2,write_file,a.txt,policy,forbidden,,,\n", b.String())
+}
+
+func TestWriteAuditRows_JSON(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, writeAuditRows(&b, "json", sampleAuditRows()))
+
+	var rows []map[string]interface{}
+	require.NoError(t, json.Unmarshal(b.Bytes(), &rows))
+	require.Len(t, rows, 2)
+	assert.Equal(t, "s-1", rows[0]["session_id"])
+	assert.Equal(t, "alice", rows[0]["approver"])
+	assert.Equal(t, float64(1), rows[0]["exit_code"])
+	assert.NotContains(t, rows[1], "exit_code")
+
+	b.Reset()
+	require.NoError(t, writeAuditRows(&b, "json", nil))
+	assert.Equal(t, "[]\n", b.String())
+}
+
+func TestRunAuditExport_Validation(t *testing.T) {
+	assert.ErrorContains(t, RunAuditExport(AuditExportOptions{SessionID: "s", Format: "xml"}), `unknown format "xml"`)
+	assert.ErrorContains(t, RunAuditExport(AuditExportOptions{}), "--session <id> or --all")
+}
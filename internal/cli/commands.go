@@ -2,13 +2,15 @@ package cli
 
 import (
 	"context"
-	"crypto/sha256"
+	"errors"
 	"fmt"
 	"os"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
 	enums "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
 	"go.temporal.io/api/workflowservice/v1"
 	"go.temporal.io/sdk/client"
 
@@ -18,16 +20,24 @@ import (
 	"github.com/mfateev/temporal-agent-harness/internal/workflow"
 )
 
-// harnessWorkflowID returns a stable harness workflow ID derived from the
-// working directory path. If TCX_HARNESS_ID is set, it is used directly
-// (enables tests to predict the workflow ID for monitoring).
-func harnessWorkflowID(cwd string) string {
-	if id := os.Getenv("TCX_HARNESS_ID"); id != "" {
-		return id
+// cliOverrides returns the session overrides for a session in cwd.
+func (c Config) cliOverrides(cwd string) workflow.CLIOverrides {
+	return workflow.CLIOverrides{
+		Provider:           c.Provider,
+		Model:              c.Model,
+		Permissions:        c.Permissions,
+		CodexHome:          c.CodexHome,
+		Cwd:                cwd,
+		DisableSuggestions: c.DisableSuggestions,
+		MemoryEnabled:      c.MemoryEnabled,
+		MemoryDbPath:       c.MemoryDbPath,
+		SessionType:        c.sessionType(),
+		MaxSessionCostUSD:  c.MaxSessionCostUSD,
+		Timezone:           c.Timezone,
+		Locale:             c.Locale,
+		Persona:            c.personaOverride(),
+		Remote:             c.Remote,
 	}
-	h := sha256.New()
-	h.Write([]byte(cwd))
-	return fmt.Sprintf("harness-%x", h.Sum(nil)[:8])
 }
 
 // startWorkflowCmd starts (or re-attaches to) a HarnessWorkflow and sends a
@@ -41,28 +51,26 @@ func startWorkflowCmd(c client.Client, config Config) tea.Cmd {
 			cwd, _ = os.Getwd()
 		}
 
-		harnessID := harnessWorkflowID(cwd)
+		harnessID := harnessWorkflowID(cwd, config.WorkflowID.Template)
 
 		input := workflow.HarnessWorkflowInput{
 			HarnessID: harnessID,
-			Overrides: workflow.CLIOverrides{
-				Provider:           config.Provider,
-				Model:              config.Model,
-				Permissions:        config.Permissions,
-				CodexHome:          config.CodexHome,
-				Cwd:                cwd,
-				DisableSuggestions: config.DisableSuggestions,
-				MemoryEnabled:      config.MemoryEnabled,
-				MemoryDbPath:       config.MemoryDbPath,
-			},
+			Overrides: config.cliOverrides(cwd),
 		}
 
 		ctx := context.Background()
 		_, err := c.ExecuteWorkflow(ctx, client.StartWorkflowOptions{
-			ID:                    harnessID,
-			TaskQueue:             TaskQueue,
-			WorkflowIDReusePolicy: enums.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE_FAILED_ONLY,
+			ID:                       harnessID,
+			TaskQueue:                TaskQueue,
+			WorkflowIDReusePolicy:    config.WorkflowID.resolvedReusePolicy(),
+			WorkflowIDConflictPolicy: config.WorkflowID.resolvedConflictPolicy(),
+			// Surface collisions instead of silently attaching to a closed run.
+			WorkflowExecutionErrorWhenAlreadyStarted: true,
 		}, "HarnessWorkflow", input)
+		var alreadyStarted *serviceerror.WorkflowExecutionAlreadyStarted
+		if errors.As(err, &alreadyStarted) {
+			return workflowIDCollisionMsg(c, harnessID)
+		}
 		if err != nil {
 			return WorkflowStartErrorMsg{Err: fmt.Errorf("failed to start harness workflow: %w", err)}
 		}
@@ -82,6 +90,12 @@ func startWorkflowCmd(c client.Client, config Config) tea.Cmd {
 					DisableSuggestions: config.DisableSuggestions,
 					MemoryEnabled:      config.MemoryEnabled,
 					MemoryDbPath:       config.MemoryDbPath,
+					SessionType:        config.sessionType(),
+					MaxSessionCostUSD:  config.MaxSessionCostUSD,
+					Timezone:           config.Timezone,
+					Locale:             config.Locale,
+					Persona:            config.personaOverride(),
+					Remote:             config.Remote,
 					Cwd:                cwd,
 				},
 				CrewName:   config.CrewName,
@@ -125,16 +139,79 @@ func resumeWorkflowCmd(c client.Client, workflowID string) tea.Cmd {
 	}
 }
 
-// sendUserInputCmd sends user input to the workflow.
-func sendUserInputCmd(c client.Client, workflowID, content string) tea.Cmd {
+// sendUserInputCmd sends user input to the workflow. The input carries an
+// idempotency key, also used as the update ID, so a retry after a timeout
+// returns the turn the first attempt started instead of starting another.
+func sendUserInputCmd(c client.Client, workflowID string, version int, content string) tea.Cmd {
+	key := uuid.NewString()
+	return func() tea.Msg {
+		var resp workflow.StateUpdateResponse
+		err := updateWithRetry(c, client.UpdateWorkflowOptions{
+			UpdateID:     key,
+			WorkflowID:   workflowID,
+			UpdateName:   workflow.UpdateUserInput,
+			Args:         []interface{}{workflow.UserInput{Version: version, Content: content, IdempotencyKey: key}},
+			WaitForStage: client.WorkflowUpdateStageCompleted,
+		}, &resp)
+		if err != nil {
+			return UserInputErrorMsg{Err: err}
+		}
+
+		return UserInputSentMsg{Response: resp}
+	}
+}
+
+// updateRetries is how many times updateWithRetry retries an update, and
+// updateRetryBackoff the delay before the first retry (doubled each time).
+const (
+	updateRetries      = 2
+	updateRetryBackoff = time.Second
+)
+
+// updateWithRetry sends an idempotent update and waits for its result,
+// retrying when the call timed out or the server was unavailable. opts must
+// carry a fixed UpdateID (and the payload its idempotency key) so retries
+// cannot apply the update twice.
+func updateWithRetry(c client.Client, opts client.UpdateWorkflowOptions, result interface{}) error {
+	for attempt := 0; ; attempt++ {
+		err := func() error {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			handle, err := c.UpdateWorkflow(ctx, opts)
+			if err != nil {
+				return err
+			}
+			return handle.Get(ctx, result)
+		}()
+		if err == nil || attempt >= updateRetries || !retryableUpdateError(err) {
+			return err
+		}
+		time.Sleep(updateRetryBackoff << attempt)
+	}
+}
+
+// retryableUpdateError reports whether an update failed in a way worth
+// retrying with the same idempotency key.
+func retryableUpdateError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var unavailable *serviceerror.Unavailable
+	var deadline *serviceerror.DeadlineExceeded
+	return errors.As(err, &unavailable) || errors.As(err, &deadline)
+}
+
+// sendExecutePlanCmd starts or resumes step-by-step execution of the
+// session plan via the execute_plan Update.
+func sendExecutePlanCmd(c client.Client, workflowID string) tea.Cmd {
 	return func() tea.Msg {
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
 		updateHandle, err := c.UpdateWorkflow(ctx, client.UpdateWorkflowOptions{
 			WorkflowID:   workflowID,
-			UpdateName:   workflow.UpdateUserInput,
-			Args:         []interface{}{workflow.UserInput{Content: content}},
+			UpdateName:   workflow.UpdateExecutePlan,
+			Args:         []interface{}{workflow.ExecutePlanRequest{}},
 			WaitForStage: client.WorkflowUpdateStageCompleted,
 		})
 		if err != nil {
@@ -150,8 +227,35 @@ func sendUserInputCmd(c client.Client, workflowID, content string) tea.Cmd {
 	}
 }
 
-// sendInterruptCmd sends an interrupt signal to the workflow.
-func sendInterruptCmd(c client.Client, workflowID string) tea.Cmd {
+// sendRaiseCostCapCmd raises the session cost cap via the raise_cost_cap
+// Update, resuming a session paused by the cap. A zero cap doubles it.
+func sendRaiseCostCapCmd(c client.Client, workflowID string, capUSD float64) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		updateHandle, err := c.UpdateWorkflow(ctx, client.UpdateWorkflowOptions{
+			WorkflowID:   workflowID,
+			UpdateName:   workflow.UpdateRaiseCostCap,
+			Args:         []interface{}{workflow.RaiseCostCapRequest{MaxSessionCostUSD: capUSD}},
+			WaitForStage: client.WorkflowUpdateStageCompleted,
+		})
+		if err != nil {
+			return UserInputErrorMsg{Err: err}
+		}
+
+		var resp workflow.StateUpdateResponse
+		if err := updateHandle.Get(ctx, &resp); err != nil {
+			return UserInputErrorMsg{Err: err}
+		}
+
+		return UserInputSentMsg{Response: resp}
+	}
+}
+
+// sendInterruptCmd sends an interrupt signal to the workflow. A soft
+// interrupt lets in-flight tools finish; a hard one cancels them immediately.
+func sendInterruptCmd(c client.Client, workflowID string, mode workflow.InterruptMode) tea.Cmd {
 	return func() tea.Msg {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
@@ -159,7 +263,7 @@ func sendInterruptCmd(c client.Client, workflowID string) tea.Cmd {
 		updateHandle, err := c.UpdateWorkflow(ctx, client.UpdateWorkflowOptions{
 			WorkflowID:   workflowID,
 			UpdateName:   workflow.UpdateInterrupt,
-			Args:         []interface{}{workflow.InterruptRequest{}},
+			Args:         []interface{}{workflow.InterruptRequest{Mode: mode}},
 			WaitForStage: client.WorkflowUpdateStageCompleted,
 		})
 		if err != nil {
@@ -200,33 +304,33 @@ func sendShutdownCmd(c client.Client, workflowID string) tea.Cmd {
 	}
 }
 
-// sendApprovalResponseCmd sends an approval response to the workflow.
-func sendApprovalResponseCmd(c client.Client, workflowID string, resp workflow.ApprovalResponse) tea.Cmd {
+// sendApprovalResponseCmd sends an approval response to the workflow,
+// retrying with the same idempotency key on timeouts.
+func sendApprovalResponseCmd(c client.Client, workflowID string, version int, resp workflow.ApprovalResponse) tea.Cmd {
+	resp.Version = version
+	resp.Approver = localApprover()
+	resp.IdempotencyKey = uuid.NewString()
 	return func() tea.Msg {
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
-
-		updateHandle, err := c.UpdateWorkflow(ctx, client.UpdateWorkflowOptions{
+		var ack workflow.ApprovalResponseAck
+		err := updateWithRetry(c, client.UpdateWorkflowOptions{
+			UpdateID:     resp.IdempotencyKey,
 			WorkflowID:   workflowID,
 			UpdateName:   workflow.UpdateApprovalResponse,
 			Args:         []interface{}{resp},
 			WaitForStage: client.WorkflowUpdateStageCompleted,
-		})
+		}, &ack)
 		if err != nil {
 			return ApprovalErrorMsg{Err: err}
 		}
 
-		var ack workflow.ApprovalResponseAck
-		if err := updateHandle.Get(ctx, &ack); err != nil {
-			return ApprovalErrorMsg{Err: err}
-		}
-
 		return ApprovalSentMsg{}
 	}
 }
 
 // sendEscalationResponseCmd sends an escalation response to the workflow.
-func sendEscalationResponseCmd(c client.Client, workflowID string, resp workflow.EscalationResponse) tea.Cmd {
+func sendEscalationResponseCmd(c client.Client, workflowID string, version int, resp workflow.EscalationResponse) tea.Cmd {
+	resp.Version = version
+	resp.Approver = localApprover()
 	return func() tea.Msg {
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
@@ -361,42 +465,94 @@ func startNewSessionCmd(c client.Client, harnessID, message string, config Confi
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
-		cwd := config.Cwd
-		if cwd == "" {
-			cwd, _ = os.Getwd()
+		workflowID, err := startSession(ctx, c, harnessID, newSessionRequest(config, message))
+		if err != nil {
+			return NewSessionErrorMsg{Err: err}
 		}
+		return NewSessionStartedMsg{WorkflowID: workflowID}
+	}
+}
+
+// handoffCmd asks the planner session for its handoff brief and starts an
+// executor session seeded with it. Returns HandoffStartedMsg on success.
+func handoffCmd(c client.Client, harnessID, workflowID, note string, config Config) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
 
 		updateHandle, err := c.UpdateWorkflow(ctx, client.UpdateWorkflowOptions{
-			WorkflowID: harnessID,
-			UpdateName: workflow.UpdateStartSession,
-			Args: []interface{}{workflow.StartSessionRequest{
-				UserMessage: message,
-				OverrideConfig: &workflow.CLIOverrides{
-					Provider:           config.Provider,
-					Model:              config.Model,
-					Permissions:        config.Permissions,
-					DisableSuggestions: config.DisableSuggestions,
-					MemoryEnabled:      config.MemoryEnabled,
-					MemoryDbPath:       config.MemoryDbPath,
-					Cwd:                cwd,
-				},
-				CrewName:   config.CrewName,
-				CrewInputs: config.CrewInputs,
-				CrewType:   config.CrewType,
-			}},
+			WorkflowID:   workflowID,
+			UpdateName:   workflow.UpdateHandoff,
+			Args:         []interface{}{workflow.HandoffRequest{Note: note}},
 			WaitForStage: client.WorkflowUpdateStageCompleted,
 		})
 		if err != nil {
-			return NewSessionErrorMsg{Err: fmt.Errorf("failed to send start_session: %w", err)}
+			return HandoffErrorMsg{Err: err}
+		}
+		var brief workflow.HandoffBrief
+		if err := updateHandle.Get(ctx, &brief); err != nil {
+			return HandoffErrorMsg{Err: err}
 		}
 
-		var resp workflow.StartSessionResponse
-		if err := updateHandle.Get(ctx, &resp); err != nil {
-			return NewSessionErrorMsg{Err: fmt.Errorf("start_session failed: %w", err)}
+		req := newSessionRequest(config, brief.Message)
+		req.HandoffParentID = brief.PlannerWorkflowID
+		executorID, err := startSession(ctx, c, harnessID, req)
+		if err != nil {
+			return HandoffErrorMsg{Err: err}
 		}
+		return HandoffStartedMsg{WorkflowID: executorID, Brief: brief}
+	}
+}
+
+// newSessionRequest builds the start_session payload for a new session with
+// this invocation's overrides, so each session gets its own model, approval
+// and sandbox config even when tcx processes share a HarnessWorkflow.
+func newSessionRequest(config Config, message string) workflow.StartSessionRequest {
+	cwd := config.Cwd
+	if cwd == "" {
+		cwd, _ = os.Getwd()
+	}
+	return workflow.StartSessionRequest{
+		UserMessage: message,
+		OverrideConfig: &workflow.CLIOverrides{
+			Provider:           config.Provider,
+			Model:              config.Model,
+			Permissions:        config.Permissions,
+			DisableSuggestions: config.DisableSuggestions,
+			MemoryEnabled:      config.MemoryEnabled,
+			MemoryDbPath:       config.MemoryDbPath,
+			SessionType:        config.sessionType(),
+			MaxSessionCostUSD:  config.MaxSessionCostUSD,
+			Timezone:           config.Timezone,
+			Locale:             config.Locale,
+			Persona:            config.personaOverride(),
+			Remote:             config.Remote,
+			Cwd:                cwd,
+		},
+		CrewName:   config.CrewName,
+		CrewInputs: config.CrewInputs,
+		CrewType:   config.CrewType,
+	}
+}
 
-		return NewSessionStartedMsg{WorkflowID: resp.SessionWorkflowID}
+// startSession sends a start_session Update to the harness workflow and
+// returns the new session's AgenticWorkflow ID.
+func startSession(ctx context.Context, c client.Client, harnessID string, req workflow.StartSessionRequest) (string, error) {
+	updateHandle, err := c.UpdateWorkflow(ctx, client.UpdateWorkflowOptions{
+		WorkflowID:   harnessID,
+		UpdateName:   workflow.UpdateStartSession,
+		Args:         []interface{}{req},
+		WaitForStage: client.WorkflowUpdateStageCompleted,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to send start_session: %w", err)
 	}
+
+	var resp workflow.StartSessionResponse
+	if err := updateHandle.Get(ctx, &resp); err != nil {
+		return "", fmt.Errorf("start_session failed: %w", err)
+	}
+	return resp.SessionWorkflowID, nil
 }
 
 // sendUpdatePersonalityCmd sends an update_personality Update to the workflow.
@@ -424,6 +580,60 @@ func sendUpdatePersonalityCmd(c client.Client, workflowID, personality string) t
 	}
 }
 
+// sendSetTrivialRoutingCmd turns trivial-turn routing on or off for the
+// session via the set_trivial_routing Update.
+func sendSetTrivialRoutingCmd(c client.Client, workflowID string, enabled bool) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		updateHandle, err := c.UpdateWorkflow(ctx, client.UpdateWorkflowOptions{
+			WorkflowID:   workflowID,
+			UpdateName:   workflow.UpdateTrivialRouting,
+			Args:         []interface{}{workflow.SetTrivialRoutingRequest{Enabled: enabled}},
+			WaitForStage: client.WorkflowUpdateStageCompleted,
+		})
+		if err != nil {
+			return TrivialRoutingErrorMsg{Err: err}
+		}
+		var resp workflow.SetTrivialRoutingResponse
+		if err := updateHandle.Get(ctx, &resp); err != nil {
+			return TrivialRoutingErrorMsg{Err: err}
+		}
+		return TrivialRoutingSetMsg{Enabled: enabled}
+	}
+}
+
+// sendUpdatePersonaCmd switches the session's persona via the
+// update_persona Update and then saves it for the project in config.toml.
+func sendUpdatePersonaCmd(c client.Client, workflowID, configPath, cwd, persona string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		updateHandle, err := c.UpdateWorkflow(ctx, client.UpdateWorkflowOptions{
+			WorkflowID:   workflowID,
+			UpdateName:   workflow.UpdatePersona,
+			Args:         []interface{}{workflow.UpdatePersonaRequest{Persona: persona}},
+			WaitForStage: client.WorkflowUpdateStageCompleted,
+		})
+		if err != nil {
+			return PersonaUpdateErrorMsg{Err: err}
+		}
+		var resp workflow.UpdatePersonaResponse
+		if err := updateHandle.Get(ctx, &resp); err != nil {
+			return PersonaUpdateErrorMsg{Err: err}
+		}
+
+		msg := PersonaUpdateSentMsg{Persona: persona}
+		if configPath != "" {
+			msg.Project = personaProjectRoot(cwd)
+			msg.SaveErr = saveProjectPersona(configPath, msg.Project, persona)
+		}
+		return msg
+	}
+}
+
 // sendUpdateApprovalModeCmd sends an update_approval_mode Update to the workflow.
 func sendUpdateApprovalModeCmd(c client.Client, workflowID, mode string) tea.Cmd {
 	return func() tea.Msg {
@@ -469,6 +679,117 @@ func queryMcpToolsCmd(c client.Client, workflowID string) tea.Cmd {
 	}
 }
 
+// queryToolStatsCmd queries the workflow for per-tool call statistics.
+func queryToolStatsCmd(c client.Client, workflowID string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		resp, err := c.QueryWorkflow(ctx, workflowID, "", workflow.QueryGetToolStats)
+		if err != nil {
+			return ToolStatsErrorMsg{Err: err}
+		}
+
+		var stats []workflow.ToolStatSummary
+		if err := resp.Get(&stats); err != nil {
+			return ToolStatsErrorMsg{Err: err}
+		}
+
+		return ToolStatsResultMsg{Stats: stats}
+	}
+}
+
+// queryLastPromptCmd queries the workflow for the prompt behind the last
+// LLM response.
+func queryLastPromptCmd(c client.Client, workflowID string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		resp, err := c.QueryWorkflow(ctx, workflowID, "", workflow.QueryGetLastPrompt)
+		if err != nil {
+			return LastPromptErrorMsg{Err: err}
+		}
+
+		var snapshot workflow.PromptSnapshot
+		if err := resp.Get(&snapshot); err != nil {
+			return LastPromptErrorMsg{Err: err}
+		}
+
+		return LastPromptResultMsg{Snapshot: snapshot}
+	}
+}
+
+// queryFingerprintCmd queries the workflow for its session fingerprint.
+func queryFingerprintCmd(c client.Client, workflowID string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		resp, err := c.QueryWorkflow(ctx, workflowID, "", workflow.QueryGetFingerprint)
+		if err != nil {
+			return FingerprintErrorMsg{Err: err}
+		}
+
+		var fp workflow.SessionFingerprint
+		if err := resp.Get(&fp); err != nil {
+			return FingerprintErrorMsg{Err: err}
+		}
+
+		return FingerprintResultMsg{Fingerprint: fp}
+	}
+}
+
+// queryItemCmd fetches one conversation item for /raw and /expand: the
+// item with seq, or the last assistant message when seq is negative.
+func queryItemCmd(c client.Client, workflowID string, seq int, expand bool) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		resp, err := c.QueryWorkflow(ctx, workflowID, "", workflow.QueryGetConversationItems)
+		if err != nil {
+			return ItemErrorMsg{Err: err}
+		}
+
+		var items models.ConversationItemList
+		if err := resp.Get(&items); err != nil {
+			return ItemErrorMsg{Err: err}
+		}
+
+		item, err := findItem(items, seq)
+		if err != nil {
+			return ItemErrorMsg{Err: err}
+		}
+		return ItemResultMsg{Item: item, Expand: expand}
+	}
+}
+
+// sendTodoCmd sends an update_todo Update to the workflow.
+func sendTodoCmd(c client.Client, workflowID string, req workflow.UpdateTodoRequest) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		updateHandle, err := c.UpdateWorkflow(ctx, client.UpdateWorkflowOptions{
+			WorkflowID:   workflowID,
+			UpdateName:   workflow.UpdateTodo,
+			Args:         []interface{}{req},
+			WaitForStage: client.WorkflowUpdateStageCompleted,
+		})
+		if err != nil {
+			return TodoErrorMsg{Err: err}
+		}
+
+		var resp workflow.UpdateTodoResponse
+		if err := updateHandle.Get(ctx, &resp); err != nil {
+			return TodoErrorMsg{Err: err}
+		}
+
+		return TodoResultMsg{Todos: resp.Todos}
+	}
+}
+
 // queryExecSessionsCmd sends a list_exec_sessions Update to the workflow.
 func queryExecSessionsCmd(c client.Client, workflowID string) tea.Cmd {
 	return func() tea.Msg {
@@ -531,7 +852,7 @@ func queryChildConversationItems(c client.Client, childWorkflowID string) tea.Cm
 			return PlannerCompletedMsg{PlanText: ""}
 		}
 
-		var items []models.ConversationItem
+		var items models.ConversationItemList
 		if err := resp.Get(&items); err != nil {
 			return PlannerCompletedMsg{PlanText: ""}
 		}
@@ -574,6 +895,17 @@ func fetchModelsCmd() tea.Cmd {
 	}
 }
 
+// workflowIDCollisionMsg builds a WorkflowIDCollisionMsg for a harness ID
+// that could not be (re)started, listing its running sessions so the user can
+// resume one instead.
+func workflowIDCollisionMsg(c client.Client, harnessID string) tea.Msg {
+	msg := WorkflowIDCollisionMsg{WorkflowID: harnessID}
+	if list, ok := fetchSessionsCmd(c, harnessID)().(HarnessSessionsListMsg); ok && list.Err == nil {
+		msg.Entries = list.Entries
+	}
+	return msg
+}
+
 // fetchSessionsCmd lists sessions for the session picker via the Temporal
 // visibility API. This is fast and works even without a running harness.
 func fetchSessionsCmd(c client.Client, harnessID string) tea.Cmd {
@@ -0,0 +1,21 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseRaiseCap parses the argument of /resume --raise-cap: a new cap in
+// USD ("10", "$10.50"), or empty to double the current cap (returns 0).
+func parseRaiseCap(arg string) (float64, error) {
+	arg = strings.TrimSpace(arg)
+	if arg == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseFloat(strings.TrimPrefix(arg, "$"), 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid cap %q: want a positive USD amount", arg)
+	}
+	return v, nil
+}
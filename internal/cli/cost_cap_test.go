@@ -0,0 +1,23 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRaiseCap(t *testing.T) {
+	v, err := parseRaiseCap("")
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, v, "empty doubles the current cap")
+
+	v, err = parseRaiseCap(" $10.50")
+	require.NoError(t, err)
+	assert.Equal(t, 10.5, v)
+
+	for _, bad := range []string{"ten", "-3", "0", "$"} {
+		_, err := parseRaiseCap(bad)
+		assert.Error(t, err, bad)
+	}
+}
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mfateev/temporal-agent-harness/internal/workflow"
+)
+
+// fingerprintShortLen is how much of the component hashes /fingerprint
+// shows; the saved file has them in full.
+const fingerprintShortLen = 12
+
+// formatFingerprintDisplay summarizes a session fingerprint for the
+// /fingerprint command. The config and transcript hashes are shown in full
+// so they can be compared with another session's.
+func formatFingerprintDisplay(fp workflow.SessionFingerprint) string {
+	var b strings.Builder
+	b.WriteString("Session Fingerprint\n")
+	b.WriteString("───────────────────\n")
+	fmt.Fprintf(&b, "  Config:       %s\n", fp.ConfigHash)
+	fmt.Fprintf(&b, "  Transcript:   %s (%d requests)\n", fp.TranscriptHash, len(fp.Calls))
+	fmt.Fprintf(&b, "  Model:        %s/%s\n", fp.Provider, fp.Model)
+	fmt.Fprintf(&b, "  Settings:     %s\n", shortHash(fp.SettingsHash))
+	fmt.Fprintf(&b, "  Instructions: base %s, developer %s, user %s\n",
+		shortHash(fp.Instructions.Base), shortHash(fp.Instructions.Developer), shortHash(fp.Instructions.User))
+	fmt.Fprintf(&b, "  Tools:        %d\n", len(fp.Tools))
+	return b.String()
+}
+
+// shortHash abbreviates a hash for display, or returns "-" when empty.
+func shortHash(hash string) string {
+	if hash == "" {
+		return "-"
+	}
+	if len(hash) > fingerprintShortLen {
+		return hash[:fingerprintShortLen]
+	}
+	return hash
+}
+
+// writeFingerprintArtifact saves the full fingerprint as JSON to a file in
+// the temp directory and returns its path, so it can be diffed against
+// another session's or attached to a report.
+func writeFingerprintArtifact(workflowID string, fp workflow.SessionFingerprint) (string, error) {
+	data, err := json.MarshalIndent(fp, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(os.TempDir(), fmt.Sprintf("fingerprint-%s.json", sanitizeIDComponent(workflowID)))
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/workflow"
+)
+
+func testFingerprint() workflow.SessionFingerprint {
+	return workflow.SessionFingerprint{
+		ConfigHash:     strings.Repeat("a", 64),
+		Provider:       "openai",
+		Model:          "gpt-4o",
+		SettingsHash:   strings.Repeat("b", 64),
+		Instructions:   workflow.InstructionHashes{Base: strings.Repeat("c", 64)},
+		Tools:          map[string]string{"shell_command": strings.Repeat("d", 64)},
+		Calls:          []workflow.RequestFingerprint{{TurnID: "turn-1", Model: "openai/gpt-4o", Hash: strings.Repeat("e", 64)}},
+		TranscriptHash: strings.Repeat("f", 64),
+	}
+}
+
+func TestFormatFingerprintDisplay(t *testing.T) {
+	out := formatFingerprintDisplay(testFingerprint())
+
+	assert.Contains(t, out, "Config:       "+strings.Repeat("a", 64))
+	assert.Contains(t, out, strings.Repeat("f", 64)+" (1 requests)")
+	assert.Contains(t, out, "Model:        openai/gpt-4o")
+	assert.Contains(t, out, "base cccccccccccc, developer -, user -")
+	assert.Contains(t, out, "Tools:        1")
+}
+
+func TestWriteFingerprintArtifact(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	path, err := writeFingerprintArtifact("tcx/session 1", testFingerprint())
+	require.NoError(t, err)
+	assert.Equal(t, os.TempDir(), filepath.Dir(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var fp workflow.SessionFingerprint
+	require.NoError(t, json.Unmarshal(data, &fp))
+	assert.Equal(t, testFingerprint(), fp)
+}
@@ -34,23 +34,9 @@ func runGitDiff(cwd string) string {
 	}
 
 	// 3. Untracked files — show their content as diffs.
-	untracked := execGit(cwd, "ls-files", "--others", "--exclude-standard")
-	if untracked != "" {
-		for _, file := range strings.Split(strings.TrimSpace(untracked), "\n") {
-			file = strings.TrimSpace(file)
-			if file == "" {
-				continue
-			}
-			// git diff --no-index exits 1 when files differ (normal).
-			cmd := exec.Command("git", "diff", "--no-index", "--", "/dev/null", file)
-			cmd.Dir = cwd
-			var out bytes.Buffer
-			cmd.Stdout = &out
-			cmd.Stderr = &out
-			_ = cmd.Run()
-			if s := strings.TrimSpace(out.String()); s != "" {
-				sections = append(sections, s)
-			}
+	for _, file := range untrackedFiles(cwd) {
+		if s := strings.TrimSpace(diffUntracked(cwd, file)); s != "" {
+			sections = append(sections, s)
 		}
 	}
 
@@ -60,6 +46,31 @@ func runGitDiff(cwd string) string {
 	return strings.Join(sections, "\n")
 }
 
+// untrackedFiles lists the untracked, not ignored files under cwd.
+func untrackedFiles(cwd string) []string {
+	var files []string
+	for _, file := range strings.Split(execGit(cwd, "ls-files", "--others", "--exclude-standard"), "\n") {
+		if file = strings.TrimSpace(file); file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// diffUntracked returns the diff that creates an untracked file, with
+// extra flags passed to git diff (e.g. --binary).
+func diffUntracked(cwd, file string, extra ...string) string {
+	args := append([]string{"diff", "--no-index"}, extra...)
+	// git diff --no-index exits 1 when files differ (normal).
+	cmd := exec.Command("git", append(args, "--", "/dev/null", file)...)
+	cmd.Dir = cwd
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	_ = cmd.Run()
+	return out.String()
+}
+
 // execGit runs a git command in cwd and returns trimmed stdout, or "" on error.
 func execGit(cwd string, args ...string) string {
 	cmd := exec.Command("git", args...)
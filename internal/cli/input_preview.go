@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/mfateev/temporal-agent-harness/internal/exclusion"
+	"github.com/mfateev/temporal-agent-harness/internal/mentions"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// DefaultInputPreviewTokens is the estimated size at which a message needs
+// confirmation before it is sent, unless config.toml sets
+// input_preview_tokens.
+const DefaultInputPreviewTokens = 10000
+
+// estimateInputTokens estimates how many prompt tokens a user message adds:
+// the message itself plus the files its @path mentions attach. Mentions are
+// resolved locally with the worker's default budget, so the estimate matches
+// what the workflow injects when the CLI and worker share a filesystem.
+// Uses the same ~4 characters per token heuristic as history estimates.
+func estimateInputTokens(line, cwd string) int {
+	chars := len(line)
+	if parsed := mentions.ParseFileMentions(line); len(parsed) > 0 {
+		for _, att := range mentions.Resolve(cwd, parsed, mentions.Budget{}, exclusion.Load(cwd, nil)) {
+			chars += len(att.Render())
+		}
+	}
+	return chars / 4
+}
+
+// formatInputPreview is the confirmation prompt for a large message, e.g.
+// "This message will add ~18k tokens (~$0.05). Send? (y/n)".
+func formatInputPreview(tokens int, provider, model string) string {
+	size := fmt.Sprintf("~%d", tokens)
+	if tokens >= 1000 {
+		size = fmt.Sprintf("~%dk", (tokens+500)/1000)
+	}
+	cost := ""
+	if price, ok := models.InputPricePerMTok(provider, model); ok {
+		usd := float64(tokens) * price / 1e6
+		cost = fmt.Sprintf(" (~$%.2f)", usd)
+		if usd < 0.005 {
+			cost = " (<$0.01)"
+		}
+	}
+	return fmt.Sprintf("This message will add %s tokens%s. Send? (y/n)", size, cost)
+}
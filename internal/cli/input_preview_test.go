@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateInputTokens_IncludesMentionedFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "big.log"), []byte(strings.Repeat("x", 8000)), 0o644))
+
+	plain := estimateInputTokens("look at the log", dir)
+	withMention := estimateInputTokens("look at @big.log", dir)
+	assert.Less(t, plain, 10)
+	assert.Greater(t, withMention, 2000)
+}
+
+func TestFormatInputPreview(t *testing.T) {
+	assert.Equal(t, "This message will add ~18k tokens (~$0.05). Send? (y/n)",
+		formatInputPreview(18000, "anthropic", "claude-sonnet-4-0"))
+	assert.Equal(t, "This message will add ~950 tokens. Send? (y/n)",
+		formatInputPreview(950, "custom", "local-model"))
+	assert.Equal(t, "This message will add ~12k tokens (<$0.01). Send? (y/n)",
+		formatInputPreview(12000, "openai", "gpt-4o-mini"))
+}
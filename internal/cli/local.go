@@ -0,0 +1,218 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/mfateev/temporal-agent-harness/internal/execsession"
+	"github.com/mfateev/temporal-agent-harness/internal/llm"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/tools"
+	"github.com/mfateev/temporal-agent-harness/internal/tools/handlers"
+	"github.com/mfateev/temporal-agent-harness/internal/workflow"
+)
+
+// localIO is the terminal a local session talks to.
+type localIO struct {
+	in     io.Reader
+	out    io.Writer
+	errOut io.Writer
+	// plain strips ANSI escapes, for pipes and files.
+	plain bool
+}
+
+// RunLocal runs a session in this process instead of on a Temporal worker:
+// the same instructions, tools and approval prompts, but no durability, so
+// nothing survives the process. With config.Message it runs that one turn
+// and exits; otherwise it reads one message per line until EOF or /exit.
+func RunLocal(config Config) error {
+	if os.Getenv("OPENAI_API_KEY") == "" && os.Getenv("ANTHROPIC_API_KEY") == "" {
+		return errors.New("--local needs an LLM provider API key: OPENAI_API_KEY or ANTHROPIC_API_KEY")
+	}
+	SetDisplayTimezone(config.Timezone)
+
+	execStore := execsession.NewStore()
+	defer execStore.CloseAll()
+	return runLocal(config, llm.NewMultiProviderClient(), newLocalToolRegistry(execStore), localIO{
+		in:     os.Stdin,
+		out:    os.Stdout,
+		errOut: os.Stderr,
+		plain:  config.Accessible || !isTerminal(os.Stdout),
+	})
+}
+
+// newLocalToolRegistry returns the worker's built-in tools. MCP servers are
+// started by the workflow, so MCP tools are not available locally.
+func newLocalToolRegistry(execStore *execsession.Store) *tools.ToolRegistry {
+	registry := tools.NewToolRegistry()
+	registry.Register(handlers.NewShellHandler())
+	registry.Register(handlers.NewShellCommandHandler())
+	registry.Register(handlers.NewReadFileTool())
+	registry.Register(handlers.NewWriteFileTool())
+	registry.Register(handlers.NewListDirTool())
+	registry.Register(handlers.NewGrepFilesTool())
+	registry.Register(handlers.NewApplyPatchTool())
+	registry.Register(handlers.NewExecCommandHandler(execStore))
+	registry.Register(handlers.NewWriteStdinHandler(execStore))
+	return registry
+}
+
+func runLocal(config Config, llmClient llm.LLMClient, registry *tools.ToolRegistry, tio localIO) error {
+	cwd := config.Cwd
+	if cwd == "" {
+		cwd, _ = os.Getwd()
+	}
+	session, warnings := workflow.NewLocalSession(context.Background(), config.cliOverrides(cwd), llmClient, registry)
+	defer session.Close()
+	for _, w := range warnings {
+		fmt.Fprintf(tio.errOut, "Warning: %s\n", w)
+	}
+
+	styles := DefaultStyles()
+	width := 0
+	if config.NoColor || tio.plain {
+		styles = NoColorStyles()
+	}
+	if tio.plain {
+		width = plainWidth
+	}
+	renderer := NewItemRenderer(width, config.NoColor || tio.plain, config.NoMarkdown, styles).
+		WithMarkdownOptions(config.Markdown).
+		WithAccessible(config.Accessible)
+	write := func(s string) {
+		if tio.plain {
+			s = ansi.Strip(s)
+		}
+		io.WriteString(tio.out, s)
+	}
+
+	lines := readLines(tio.in)
+	autoApprove := false
+	hooks := workflow.LocalHooks{
+		ItemAdded: func(item models.ConversationItem) {
+			if s := renderer.RenderItem(item, false); s != "" {
+				write(s)
+			}
+		},
+		Approve: func(ctx context.Context, pending []workflow.PendingApproval) *workflow.ApprovalResponse {
+			if autoApprove {
+				resp, _ := HandleApprovalInput("y", pending)
+				return resp
+			}
+			for {
+				write(renderer.RenderApprovalPrompt(pending))
+				line, ok := nextLine(ctx, lines)
+				if !ok {
+					return nil
+				}
+				resp, always := HandleApprovalInput(line, pending)
+				if resp == nil {
+					write("Please answer y, n, a, or the numbers of the calls to allow.\n")
+					continue
+				}
+				autoApprove = always
+				return resp
+			}
+		},
+	}
+
+	runTurn := func(message string) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if err := session.RunTurn(ctx, message, hooks); errors.Is(err, context.Canceled) {
+			fmt.Fprintln(tio.errOut, "Interrupted.")
+		}
+	}
+
+	if config.Message != "" {
+		runTurn(config.Message)
+	} else {
+		for {
+			write("> ")
+			line, ok := nextLine(context.Background(), lines)
+			if !ok {
+				break
+			}
+			line = strings.TrimSpace(line)
+			if line == "/exit" || line == "/quit" {
+				break
+			}
+			if line != "" {
+				runTurn(line)
+			}
+		}
+	}
+	fmt.Fprintf(tio.errOut, "Tokens used: %d\n", session.TotalTokens())
+
+	if config.ArtifactsDir != "" {
+		summary := buildArtifactsSummary(session.Items())
+		summary.Provider = session.Config().Model.Provider
+		summary.Model = session.Config().Model.Model
+		summary.Prompt = config.Message
+		summary.Usage.TokenUsage = session.Usage()
+		if cost, ok := session.CostUSD(); ok {
+			summary.Usage.EstimatedCostUSD = &cost
+		}
+		if config.Verify != "" {
+			fmt.Fprintf(tio.errOut, "Verifying: %s\n", config.Verify)
+			summary.Verification = append(summary.Verification, runVerification(cwd, config.Verify))
+		}
+		if err := saveArtifacts(config, cwd, summary); err != nil {
+			return fmt.Errorf("writing artifacts: %w", err)
+		}
+		fmt.Fprintf(tio.errOut, "Artifacts written to %s\n", config.ArtifactsDir)
+	}
+	return nil
+}
+
+// saveArtifacts writes the bundle to the directory or bucket location of
+// config.ArtifactsDir.
+func saveArtifacts(config Config, cwd string, summary ArtifactsSummary) error {
+	if !isArtifactsURL(config.ArtifactsDir) {
+		return writeArtifacts(config.ArtifactsDir, cwd, summary)
+	}
+	codexHome := config.CodexHome
+	if codexHome == "" {
+		home, _ := os.UserHomeDir()
+		codexHome = filepath.Join(home, ".codex")
+	}
+	store, ttl, err := openArtifactsStore(config.ArtifactsDir, codexHome)
+	if err != nil {
+		return err
+	}
+	return storeArtifacts(context.Background(), store, ttl, cwd, summary)
+}
+
+// readLines sends each line of r on the returned channel, closing it at EOF.
+// Reading happens in the background so an interrupted approval prompt does
+// not leave the session stuck in a read.
+func readLines(r io.Reader) <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return lines
+}
+
+// nextLine returns the next line, or false at EOF or when ctx is done.
+func nextLine(ctx context.Context, lines <-chan string) (string, bool) {
+	select {
+	case line, ok := <-lines:
+		return line, ok
+	case <-ctx.Done():
+		return "", false
+	}
+}
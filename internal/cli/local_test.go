@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/execsession"
+	"github.com/mfateev/temporal-agent-harness/internal/llm"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// writeFileScript asks to write path on a user message and answers "done"
+// once the tool result is back.
+func writeFileScript(path string) *llm.ScriptedClient {
+	args, _ := json.Marshal(map[string]string{"path": path, "content": "hi\n"})
+	return &llm.ScriptedClient{Respond: func(req llm.LLMRequest) llm.LLMResponse {
+		if req.History[len(req.History)-1].Type == models.ItemTypeUserMessage {
+			return llm.LLMResponse{
+				Items: []models.ConversationItem{{
+					Type: models.ItemTypeFunctionCall, CallID: "call-1", Name: "write_file", Arguments: string(args),
+				}},
+				FinishReason: models.FinishReasonToolCalls,
+			}
+		}
+		return llm.LLMResponse{
+			Items:        []models.ConversationItem{{Type: models.ItemTypeAssistantMessage, Content: "done"}},
+			FinishReason: models.FinishReasonStop,
+			TokenUsage:   models.TokenUsage{TotalTokens: 7},
+		}
+	}}
+}
+
+func runLocalForTest(t *testing.T, config Config, client llm.LLMClient, stdin string) (string, string) {
+	config.Provider = "openai"
+	config.Model = "gpt-4o"
+	config.CodexHome = t.TempDir()
+	config.Permissions = models.Permissions{ApprovalMode: models.ApprovalUnlessTrusted}
+	config.NoMarkdown = true
+	var out, errOut bytes.Buffer
+	err := runLocal(config, client, newLocalToolRegistry(execsession.NewStore()), localIO{
+		in:     strings.NewReader(stdin),
+		out:    &out,
+		errOut: &errOut,
+		plain:  true,
+	})
+	require.NoError(t, err)
+	return out.String(), errOut.String()
+}
+
+func TestRunLocal_ApprovedToolRuns(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "out.txt")
+	out, errOut := runLocalForTest(t, Config{Cwd: dir}, writeFileScript(target), "write it\ny\n/exit\n")
+
+	content, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "hi\n", string(content))
+	assert.Contains(t, out, "Allow? [y]es / [n]o / [a]lways: ")
+	assert.Contains(t, out, "done")
+	assert.Contains(t, errOut, "Tokens used: 7")
+}
+
+func TestRunLocal_OneShotDeniedAtEOF(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "out.txt")
+	out, _ := runLocalForTest(t, Config{Cwd: dir, Message: "write it"}, writeFileScript(target), "")
+
+	assert.NoFileExists(t, target)
+	assert.Contains(t, out, "Allow?")
+	assert.NotContains(t, out, "done")
+}
+
+func TestRunLocal_UnrecognizedAnswerAsksAgain(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "out.txt")
+	out, _ := runLocalForTest(t, Config{Cwd: dir, Message: "write it"}, writeFileScript(target), "maybe\nn\n")
+
+	assert.NoFileExists(t, target)
+	assert.Equal(t, 2, strings.Count(out, "Allow?"))
+}
+
+func TestRunLocal_WritesArtifacts(t *testing.T) {
+	repo := initTestGitRepo(t)
+	artifacts := t.TempDir()
+	_, errOut := runLocalForTest(t, Config{
+		Cwd: repo, Message: "write it", ArtifactsDir: artifacts, Verify: "test -f out.txt",
+	}, writeFileScript(filepath.Join(repo, "out.txt")), "y\n")
+
+	assert.Contains(t, errOut, "Artifacts written to "+artifacts)
+	var summary ArtifactsSummary
+	data, err := os.ReadFile(filepath.Join(artifacts, artifactsSummaryFile))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &summary))
+	assert.Equal(t, "write it", summary.Prompt)
+	assert.Equal(t, "done", summary.FinalMessage)
+	assert.Equal(t, []string{"out.txt"}, summary.ChangedFiles)
+	assert.Equal(t, 7, summary.Usage.TotalTokens)
+	require.Len(t, summary.Verification, 1)
+	require.NotNil(t, summary.VerificationPassed)
+	assert.True(t, *summary.VerificationPassed)
+
+	patch, err := os.ReadFile(filepath.Join(artifacts, artifactsPatchFile))
+	require.NoError(t, err)
+	assert.Contains(t, string(patch), "+hi")
+}
@@ -0,0 +1,305 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	gansi "github.com/charmbracelet/glamour/ansi"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// DefaultMaxCodeLines is the height at which fenced code blocks are cut
+// when config.toml does not set [markdown] max_code_lines.
+const DefaultMaxCodeLines = 40
+
+// codeIndent is the left margin of fenced code blocks, matching glamour's.
+const codeIndent = "  "
+
+// MarkdownOptions controls how fenced code blocks in assistant messages
+// are rendered. Prose around them is rendered by glamour as before.
+type MarkdownOptions struct {
+	// WrapCode wraps code lines wider than the viewport. Off, they are
+	// kept whole and scroll horizontally.
+	WrapCode bool
+	// Highlight colors code blocks by their language tag.
+	Highlight bool
+	// MaxCodeLines cuts code blocks taller than this; /expand shows the
+	// whole message. 0 shows every line.
+	MaxCodeLines int
+}
+
+// DefaultMarkdownOptions returns the options used when config.toml has no
+// [markdown] table.
+func DefaultMarkdownOptions() MarkdownOptions {
+	return MarkdownOptions{Highlight: true, MaxCodeLines: DefaultMaxCodeLines}
+}
+
+// MarkdownOptionsFromToml applies the [markdown] table of config.toml to
+// the defaults.
+func MarkdownOptionsFromToml(t *models.MarkdownToml) MarkdownOptions {
+	opts := DefaultMarkdownOptions()
+	if t == nil {
+		return opts
+	}
+	if t.WrapCode != nil {
+		opts.WrapCode = *t.WrapCode
+	}
+	if t.Highlight != nil {
+		opts.Highlight = *t.Highlight
+	}
+	if t.MaxCodeLines != nil {
+		opts.MaxCodeLines = *t.MaxCodeLines
+	}
+	return opts
+}
+
+// markdownSegment is a run of prose or one fenced code block of a message.
+type markdownSegment struct {
+	text     string // prose, or the code without its fences
+	code     bool
+	language string // first word of the code block's info string
+}
+
+// splitFencedCode splits markdown into prose and fenced code blocks
+// (``` or ~~~). An unclosed fence runs to the end of the text, and the
+// fence's indentation is removed from the code lines.
+func splitFencedCode(text string) []markdownSegment {
+	var segments []markdownSegment
+	var prose, code []string
+	var fence, language, indent string
+	inCode := false
+
+	flushProse := func() {
+		if len(prose) > 0 {
+			segments = append(segments, markdownSegment{text: strings.Join(prose, "\n")})
+			prose = nil
+		}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		if !inCode {
+			marker := fenceMarker(trimmed)
+			if marker == "" {
+				prose = append(prose, line)
+				continue
+			}
+			flushProse()
+			inCode, fence, indent = true, marker, line[:len(line)-len(trimmed)]
+			language = ""
+			if fields := strings.Fields(trimmed[len(marker):]); len(fields) > 0 {
+				language = fields[0]
+			}
+			continue
+		}
+		if isClosingFence(trimmed, fence) {
+			segments = append(segments, markdownSegment{text: strings.Join(code, "\n"), code: true, language: language})
+			inCode, code = false, nil
+			continue
+		}
+		code = append(code, strings.TrimPrefix(line, indent))
+	}
+	if inCode {
+		segments = append(segments, markdownSegment{text: strings.Join(code, "\n"), code: true, language: language})
+	}
+	flushProse()
+	return segments
+}
+
+// fenceMarker returns the opening fence (three or more backticks or
+// tildes) that line starts with, or "".
+func fenceMarker(line string) string {
+	for _, c := range []string{"`", "~"} {
+		n := len(line) - len(strings.TrimLeft(line, c))
+		if n >= 3 {
+			return line[:n]
+		}
+	}
+	return ""
+}
+
+// isClosingFence reports whether line closes a code block opened with
+// fence: at least as many of the same character and nothing else.
+func isClosingFence(line, fence string) bool {
+	rest := strings.TrimLeft(line, fence[:1])
+	return len(line)-len(rest) >= len(fence) && strings.TrimSpace(rest) == ""
+}
+
+// renderMarkdown renders an assistant message: prose through glamour and
+// fenced code blocks as configured by r.markdown. seq identifies the
+// message in the /expand hint of cut code blocks; full shows every line.
+func (r *ItemRenderer) renderMarkdown(content string, seq int, full bool) (string, error) {
+	var b strings.Builder
+	for _, seg := range splitFencedCode(content) {
+		if !seg.code {
+			if strings.TrimSpace(seg.text) == "" {
+				continue
+			}
+			rendered, err := r.mdRenderer.Render(seg.text)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(strings.Trim(rendered, "\n") + "\n\n")
+			continue
+		}
+		b.WriteString(r.renderCodeBlock(seg, seq, full) + "\n")
+	}
+	return b.String(), nil
+}
+
+// renderCodeBlock renders one fenced code block, cut at MaxCodeLines
+// unless full.
+func (r *ItemRenderer) renderCodeBlock(seg markdownSegment, seq int, full bool) string {
+	lines := strings.Split(strings.TrimRight(seg.text, "\n"), "\n")
+	var omitted int
+	if max := r.markdown.MaxCodeLines; !full && max > 0 && len(lines) > max {
+		omitted = len(lines) - max
+		lines = lines[:max]
+	}
+
+	code := strings.Join(lines, "\n")
+	if r.markdown.Highlight && !r.noColor {
+		code = highlightCode(code, seg.language)
+	}
+	out := strings.Split(code, "\n")
+	if r.markdown.WrapCode && r.width > len(codeIndent) {
+		out = strings.Split(ansi.Hardwrap(code, r.width-len(codeIndent), true), "\n")
+	}
+
+	var b strings.Builder
+	for _, line := range out {
+		b.WriteString(codeIndent + line + "\n")
+	}
+	if omitted > 0 {
+		b.WriteString(codeIndent + r.styles.OutputDim.Render(
+			fmt.Sprintf("… %d more lines (/expand %d to show all)", omitted, seq)) + "\n")
+	}
+	return b.String()
+}
+
+var (
+	codeStyleOnce sync.Once
+	codeStyle     *chroma.Style
+)
+
+// highlightCode colors code for a 256-color terminal by its language tag,
+// using the code block colors of the markdown style. Code in unknown
+// languages is returned as is.
+func highlightCode(code, language string) string {
+	lexer := lexers.Get(language)
+	if language == "" || lexer == nil {
+		return code
+	}
+	codeStyleOnce.Do(func() {
+		codeStyle = chromaStyleFrom(darkStyleCleanHeadings().CodeBlock.Chroma)
+	})
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		return code
+	}
+	var b strings.Builder
+	if err := formatters.TTY256.Format(&b, codeStyle, iterator); err != nil {
+		return code
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// chromaStyleFrom converts the foreground colors of glamour's code block
+// settings to a chroma style.
+func chromaStyleFrom(c *gansi.Chroma) *chroma.Style {
+	entries := chroma.StyleEntries{}
+	if c == nil {
+		return chroma.MustNewStyle("tcx", entries)
+	}
+	for token, p := range map[chroma.TokenType]gansi.StylePrimitive{
+		chroma.Text:                c.Text,
+		chroma.Error:               c.Error,
+		chroma.Comment:             c.Comment,
+		chroma.CommentPreproc:      c.CommentPreproc,
+		chroma.Keyword:             c.Keyword,
+		chroma.KeywordReserved:     c.KeywordReserved,
+		chroma.KeywordNamespace:    c.KeywordNamespace,
+		chroma.KeywordType:         c.KeywordType,
+		chroma.Operator:            c.Operator,
+		chroma.Punctuation:         c.Punctuation,
+		chroma.Name:                c.Name,
+		chroma.NameBuiltin:         c.NameBuiltin,
+		chroma.NameTag:             c.NameTag,
+		chroma.NameAttribute:       c.NameAttribute,
+		chroma.NameClass:           c.NameClass,
+		chroma.NameConstant:        c.NameConstant,
+		chroma.NameDecorator:       c.NameDecorator,
+		chroma.NameException:       c.NameException,
+		chroma.NameFunction:        c.NameFunction,
+		chroma.NameOther:           c.NameOther,
+		chroma.Literal:             c.Literal,
+		chroma.LiteralNumber:       c.LiteralNumber,
+		chroma.LiteralDate:         c.LiteralDate,
+		chroma.LiteralString:       c.LiteralString,
+		chroma.LiteralStringEscape: c.LiteralStringEscape,
+		chroma.GenericDeleted:      c.GenericDeleted,
+		chroma.GenericEmph:         c.GenericEmph,
+		chroma.GenericInserted:     c.GenericInserted,
+		chroma.GenericStrong:       c.GenericStrong,
+		chroma.GenericSubheading:   c.GenericSubheading,
+	} {
+		if p.Color != nil {
+			entries[token] = *p.Color
+		}
+	}
+	return chroma.MustNewStyle("tcx", entries)
+}
+
+// rawItemContent returns the unrendered content of an item, as /raw
+// prints it.
+func rawItemContent(item models.ConversationItem) string {
+	switch item.Type {
+	case models.ItemTypeFunctionCall:
+		return item.Name + " " + item.Arguments
+	case models.ItemTypeFunctionCallOutput:
+		if item.Output != nil {
+			return item.Output.Content
+		}
+		return ""
+	default:
+		return item.Content
+	}
+}
+
+// parseItemCommand parses "/raw [seq]" or "/expand [seq]". Without a seq
+// it returns -1: the last assistant message.
+func parseItemCommand(line, command string) (int, error) {
+	rest := strings.TrimSpace(strings.TrimPrefix(line, command))
+	if rest == "" {
+		return -1, nil
+	}
+	seq, err := strconv.Atoi(rest)
+	if err != nil || seq < 0 {
+		return 0, fmt.Errorf("usage: %s [seq]", command)
+	}
+	return seq, nil
+}
+
+// findItem returns the item with seq, or the last assistant message when
+// seq is negative.
+func findItem(items []models.ConversationItem, seq int) (models.ConversationItem, error) {
+	for i := len(items) - 1; i >= 0; i-- {
+		item := items[i]
+		if seq < 0 && item.Type == models.ItemTypeAssistantMessage && item.Content != "" {
+			return item, nil
+		}
+		if seq >= 0 && item.Seq == seq {
+			return item, nil
+		}
+	}
+	if seq < 0 {
+		return models.ConversationItem{}, fmt.Errorf("no assistant message yet")
+	}
+	return models.ConversationItem{}, fmt.Errorf("no item with seq %d", seq)
+}
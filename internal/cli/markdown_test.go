@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+func newMarkdownTestRenderer(width int, opts MarkdownOptions) *ItemRenderer {
+	return NewItemRenderer(width, true, false, NoColorStyles()).WithMarkdownOptions(opts)
+}
+
+func TestSplitFencedCode(t *testing.T) {
+	segments := splitFencedCode("Intro\n\n```go\nfunc main() {}\n```\nMiddle\n  ~~~~\n  indented\n  ~~~~\nunclosed:\n```sh\necho hi")
+	assert.Equal(t, []markdownSegment{
+		{text: "Intro\n"},
+		{text: "func main() {}", code: true, language: "go"},
+		{text: "Middle"},
+		{text: "indented", code: true},
+		{text: "unclosed:"},
+		{text: "echo hi", code: true, language: "sh"},
+	}, segments)
+}
+
+func TestSplitFencedCode_ShorterFenceDoesNotClose(t *testing.T) {
+	segments := splitFencedCode("````md\n```go\nx\n```\n````")
+	assert.Equal(t, []markdownSegment{
+		{text: "```go\nx\n```", code: true, language: "md"},
+	}, segments)
+}
+
+func TestRenderAssistantMessage_CodeIsNotWrapped(t *testing.T) {
+	long := "fmt.Println(\"" + strings.Repeat("x", 60) + "\")"
+	r := newMarkdownTestRenderer(40, MarkdownOptions{})
+	out := stripANSI(r.RenderAssistantMessage(models.ConversationItem{
+		Type:    models.ItemTypeAssistantMessage,
+		Content: "Run this:\n\n```go\n" + long + "\n```\n",
+	}))
+
+	assert.Contains(t, out, "Run this:")
+	assert.Contains(t, out, codeIndent+long+"\n")
+}
+
+func TestRenderAssistantMessage_WrapCode(t *testing.T) {
+	long := strings.Repeat("x", 60)
+	r := newMarkdownTestRenderer(40, MarkdownOptions{WrapCode: true})
+	out := stripANSI(r.RenderAssistantMessage(models.ConversationItem{
+		Type:    models.ItemTypeAssistantMessage,
+		Content: "```\n" + long + "\n```",
+	}))
+
+	assert.NotContains(t, out, long)
+	assert.Contains(t, out, strings.Repeat("x", 38)+"\n"+codeIndent+strings.Repeat("x", 22)+"\n")
+}
+
+func TestRenderAssistantMessage_CodeHeightCap(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 10; i++ {
+		lines = append(lines, "line"+strings.Repeat("!", i))
+	}
+	item := models.ConversationItem{
+		Type:    models.ItemTypeAssistantMessage,
+		Seq:     7,
+		Content: "```text\n" + strings.Join(lines, "\n") + "\n```",
+	}
+	r := newMarkdownTestRenderer(80, MarkdownOptions{MaxCodeLines: 3})
+
+	out := stripANSI(r.RenderAssistantMessage(item))
+	assert.Contains(t, out, lines[2])
+	assert.NotContains(t, out, lines[3])
+	assert.Contains(t, out, "… 7 more lines (/expand 7 to show all)")
+
+	full := stripANSI(r.RenderExpandedMessage(item))
+	assert.Contains(t, full, lines[9])
+	assert.NotContains(t, full, "/expand")
+}
+
+func TestHighlightCode(t *testing.T) {
+	code := "package main"
+	assert.NotEqual(t, code, highlightCode(code, "go"))
+	assert.Equal(t, code, stripANSI(highlightCode(code, "go")))
+	assert.Equal(t, code, highlightCode(code, ""), "no language tag")
+	assert.Equal(t, code, highlightCode(code, "no-such-language"))
+}
+
+func TestMarkdownOptionsFromToml(t *testing.T) {
+	assert.Equal(t, DefaultMarkdownOptions(), MarkdownOptionsFromToml(nil))
+
+	wrap, zero := true, 0
+	opts := MarkdownOptionsFromToml(&models.MarkdownToml{WrapCode: &wrap, MaxCodeLines: &zero})
+	assert.Equal(t, MarkdownOptions{WrapCode: true, Highlight: true}, opts)
+}
+
+func TestParseItemCommand(t *testing.T) {
+	seq, err := parseItemCommand("/raw", "/raw")
+	require.NoError(t, err)
+	assert.Equal(t, -1, seq)
+
+	seq, err = parseItemCommand("/expand 12", "/expand")
+	require.NoError(t, err)
+	assert.Equal(t, 12, seq)
+
+	_, err = parseItemCommand("/raw last", "/raw")
+	assert.EqualError(t, err, "usage: /raw [seq]")
+}
+
+func TestFindItem(t *testing.T) {
+	items := []models.ConversationItem{
+		{Seq: 1, Type: models.ItemTypeUserMessage, Content: "hi"},
+		{Seq: 2, Type: models.ItemTypeAssistantMessage, Content: "first"},
+		{Seq: 3, Type: models.ItemTypeAssistantMessage, Content: "second"},
+		{Seq: 4, Type: models.ItemTypeTurnComplete},
+	}
+
+	item, err := findItem(items, -1)
+	require.NoError(t, err)
+	assert.Equal(t, "second", item.Content)
+
+	item, err = findItem(items, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "first", item.Content)
+
+	_, err = findItem(items, 9)
+	assert.EqualError(t, err, "no item with seq 9")
+	_, err = findItem(items[:1], -1)
+	assert.EqualError(t, err, "no assistant message yet")
+}
+
+func TestRawItemContent(t *testing.T) {
+	assert.Equal(t, "**bold**", rawItemContent(models.ConversationItem{
+		Type: models.ItemTypeAssistantMessage, Content: "**bold**",
+	}))
+	assert.Equal(t, `shell {"command":"ls"}`, rawItemContent(models.ConversationItem{
+		Type: models.ItemTypeFunctionCall, Name: "shell", Arguments: `{"command":"ls"}`,
+	}))
+	assert.Equal(t, "out", rawItemContent(models.ConversationItem{
+		Type: models.ItemTypeFunctionCallOutput, Output: &models.FunctionCallOutputPayload{Content: "out"},
+	}))
+}
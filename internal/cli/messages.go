@@ -21,6 +21,14 @@ type WorkflowStartErrorMsg struct {
 	Err error
 }
 
+// WorkflowIDCollisionMsg is sent when the harness workflow ID is already in
+// use and the configured reuse/conflict policy forbids starting it again.
+// Entries lists running sessions under that ID that can be resumed instead.
+type WorkflowIDCollisionMsg struct {
+	WorkflowID string
+	Entries    []SessionListEntry
+}
+
 // PollResultMsg wraps a PollResult from the polling goroutine.
 type PollResultMsg struct {
 	Result PollResult
@@ -29,6 +37,8 @@ type PollResultMsg struct {
 // WatchResultMsg wraps a WatchResult from the blocking watcher goroutine.
 type WatchResultMsg struct {
 	Result WatchResult
+	// Gen is the generation of the watcher that produced Result.
+	Gen int
 }
 
 // UserInputSentMsg is sent after user input has been successfully sent.
@@ -184,6 +194,31 @@ type PersonalityUpdateErrorMsg struct {
 	Err error
 }
 
+// TrivialRoutingSetMsg is sent after /route turns trivial-turn routing on
+// or off.
+type TrivialRoutingSetMsg struct {
+	Enabled bool
+}
+
+// TrivialRoutingErrorMsg is sent when a set_trivial_routing update fails.
+type TrivialRoutingErrorMsg struct {
+	Err error
+}
+
+// PersonaUpdateSentMsg is sent after a persona update succeeds. Project is
+// the project root the persona was saved for; SaveErr reports a failure to
+// save it to config.toml.
+type PersonaUpdateSentMsg struct {
+	Persona string
+	Project string
+	SaveErr error
+}
+
+// PersonaUpdateErrorMsg is sent when a persona update fails.
+type PersonaUpdateErrorMsg struct {
+	Err error
+}
+
 // NewSessionStartedMsg is sent when a /new session has been started.
 type NewSessionStartedMsg struct {
 	WorkflowID string
@@ -194,6 +229,17 @@ type NewSessionErrorMsg struct {
 	Err error
 }
 
+// HandoffStartedMsg is sent when /handoff has started the executor session.
+type HandoffStartedMsg struct {
+	WorkflowID string
+	Brief      workflow.HandoffBrief
+}
+
+// HandoffErrorMsg is sent when /handoff fails.
+type HandoffErrorMsg struct {
+	Err error
+}
+
 // McpToolsResultMsg is sent when the MCP tools query completes.
 type McpToolsResultMsg struct {
 	Tools []workflow.McpToolSummary
@@ -204,6 +250,63 @@ type McpToolsErrorMsg struct {
 	Err error
 }
 
+// ToolStatsResultMsg is sent when the tool stats query completes.
+type ToolStatsResultMsg struct {
+	Stats []workflow.ToolStatSummary
+}
+
+// ToolStatsErrorMsg is sent when the tool stats query fails.
+type ToolStatsErrorMsg struct {
+	Err error
+}
+
+// FingerprintResultMsg is sent when the get_fingerprint query completes.
+type FingerprintResultMsg struct {
+	Fingerprint workflow.SessionFingerprint
+}
+
+// FingerprintErrorMsg is sent when the get_fingerprint query fails.
+type FingerprintErrorMsg struct {
+	Err error
+}
+
+// LastPromptResultMsg is sent when the get_last_prompt query completes.
+type LastPromptResultMsg struct {
+	Snapshot workflow.PromptSnapshot
+}
+
+// LastPromptErrorMsg is sent when the get_last_prompt query fails.
+type LastPromptErrorMsg struct {
+	Err error
+}
+
+// ItemResultMsg is sent when the item for /raw or /expand is fetched.
+type ItemResultMsg struct {
+	Item   models.ConversationItem
+	Expand bool
+}
+
+// ItemErrorMsg is sent when the item for /raw or /expand cannot be fetched.
+type ItemErrorMsg struct {
+	Err error
+}
+
+// WhyPagerClosedMsg is sent when the /why pager exits.
+type WhyPagerClosedMsg struct {
+	Path string
+	Err  error
+}
+
+// TodoResultMsg is sent when an update_todo Update completes.
+type TodoResultMsg struct {
+	Todos []workflow.TodoItem
+}
+
+// TodoErrorMsg is sent when an update_todo Update fails.
+type TodoErrorMsg struct {
+	Err error
+}
+
 // ExecSessionsResultMsg is sent when the exec sessions list is fetched.
 type ExecSessionsResultMsg struct {
 	Sessions []workflow.ExecSessionSummary
@@ -0,0 +1,224 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+
+	"github.com/mfateev/temporal-agent-harness/internal/temporalclient"
+	"github.com/mfateev/temporal-agent-harness/internal/workflow"
+)
+
+// MetricsOptions configures `tcx admin metrics`.
+type MetricsOptions struct {
+	Connection temporalclient.ConnectionConfig
+	// Query is an extra visibility filter ANDed with the session query,
+	// e.g. "StartTime > '2026-01-01T00:00:00Z'".
+	Query string
+	// Format is "table" (default) or "prometheus".
+	Format string
+
+	Out io.Writer
+}
+
+// metricsSessionsQuery selects sessions that publish metrics search
+// attributes. ContinuedAsNew runs are excluded: their values are carried
+// into the session's latest run.
+const metricsSessionsQuery = `WorkflowType = 'AgenticWorkflow' AND ExecutionStatus != 'ContinuedAsNew' AND TcxTotalTokens IS NOT NULL`
+
+// sessionMetricsRecord is the metrics of one session read from visibility.
+type sessionMetricsRecord struct {
+	Status       string
+	Model        string
+	TotalTokens  int64
+	CostUSD      float64
+	Turns        int64
+	ToolFailures int64
+	FailedTurns  int64
+}
+
+// metricsGroup aggregates the sessions of one model.
+type metricsGroup struct {
+	Model        string
+	Sessions     int
+	Running      int
+	TotalTokens  int64
+	CostUSD      float64
+	Turns        int64
+	ToolFailures int64
+	FailedTurns  int64
+	ByStatus     map[string]int
+}
+
+func (g *metricsGroup) add(r sessionMetricsRecord) {
+	g.Sessions++
+	if r.Status == "running" {
+		g.Running++
+	}
+	g.TotalTokens += r.TotalTokens
+	g.CostUSD += r.CostUSD
+	g.Turns += r.Turns
+	g.ToolFailures += r.ToolFailures
+	g.FailedTurns += r.FailedTurns
+	if g.ByStatus == nil {
+		g.ByStatus = make(map[string]int)
+	}
+	g.ByStatus[r.Status]++
+}
+
+// RunAdminMetrics implements `tcx admin metrics`: it aggregates the metrics
+// search attributes of all sessions via the visibility API, per model.
+func RunAdminMetrics(opts MetricsOptions) error {
+	if opts.Out == nil {
+		opts.Out = os.Stdout
+	}
+	if opts.Format != "" && opts.Format != "table" && opts.Format != "prometheus" {
+		return fmt.Errorf("unknown format %q (use table or prometheus)", opts.Format)
+	}
+	clientOpts, err := temporalclient.LoadClientOptionsFromConfig(opts.Connection)
+	if err != nil {
+		return fmt.Errorf("failed to load Temporal client config: %w", err)
+	}
+	c, err := temporalclient.DialWithRetry(clientOpts, opts.Connection.DialRetries, opts.Connection.DialRetryBackoff)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Temporal: %w", err)
+	}
+	defer c.Close()
+
+	query := metricsSessionsQuery
+	if opts.Query != "" {
+		query += " AND (" + opts.Query + ")"
+	}
+	var records []sessionMetricsRecord
+	err = listExecutions(context.Background(), c, query, func(exec *workflowInfo) {
+		records = append(records, decodeSessionMetrics(exec.Status, exec.SearchAttributes))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list sessions (are the metrics search attributes registered?): %w", err)
+	}
+
+	groups := aggregateMetrics(records)
+	if opts.Format == "prometheus" {
+		_, err = io.WriteString(opts.Out, formatMetricsPrometheus(groups))
+	} else {
+		_, err = io.WriteString(opts.Out, formatMetricsTable(groups))
+	}
+	return err
+}
+
+// decodeSessionMetrics reads the metrics search attributes of a session.
+// Missing or undecodable attributes count as zero.
+func decodeSessionMetrics(status string, attrs map[string]*commonpb.Payload) sessionMetricsRecord {
+	dc := converter.GetDefaultDataConverter()
+	decode := func(name string, v interface{}) {
+		if p, ok := attrs[name]; ok {
+			_ = dc.FromPayload(p, v)
+		}
+	}
+	r := sessionMetricsRecord{Status: status}
+	decode(workflow.SearchAttrModel.GetName(), &r.Model)
+	decode(workflow.SearchAttrTotalTokens.GetName(), &r.TotalTokens)
+	decode(workflow.SearchAttrCostUSD.GetName(), &r.CostUSD)
+	decode(workflow.SearchAttrTurns.GetName(), &r.Turns)
+	decode(workflow.SearchAttrToolFailures.GetName(), &r.ToolFailures)
+	decode(workflow.SearchAttrFailedTurns.GetName(), &r.FailedTurns)
+	if r.Model == "" {
+		r.Model = "unknown"
+	}
+	return r
+}
+
+// aggregateMetrics groups records by model, sorted by model name.
+func aggregateMetrics(records []sessionMetricsRecord) []*metricsGroup {
+	byModel := make(map[string]*metricsGroup)
+	for _, r := range records {
+		g := byModel[r.Model]
+		if g == nil {
+			g = &metricsGroup{Model: r.Model}
+			byModel[r.Model] = g
+		}
+		g.add(r)
+	}
+	groups := make([]*metricsGroup, 0, len(byModel))
+	for _, g := range byModel {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Model < groups[j].Model })
+	return groups
+}
+
+// formatMetricsTable renders one row per model and a total row.
+func formatMetricsTable(groups []*metricsGroup) string {
+	if len(groups) == 0 {
+		return "No sessions with metrics search attributes.\n"
+	}
+	var b strings.Builder
+	row := func(g *metricsGroup) {
+		fmt.Fprintf(&b, "%-28s %8d %8d %8d %14s %10s %13d %12d\n",
+			g.Model, g.Sessions, g.Running, g.Turns, formatTokens(int(g.TotalTokens)),
+			fmt.Sprintf("$%.2f", g.CostUSD), g.ToolFailures, g.FailedTurns)
+	}
+	fmt.Fprintf(&b, "%-28s %8s %8s %8s %14s %10s %13s %12s\n",
+		"MODEL", "SESSIONS", "RUNNING", "TURNS", "TOKENS", "COST", "TOOL FAILURES", "FAILED TURNS")
+	total := &metricsGroup{Model: "TOTAL"}
+	for _, g := range groups {
+		row(g)
+		total.Sessions += g.Sessions
+		total.Running += g.Running
+		total.Turns += g.Turns
+		total.TotalTokens += g.TotalTokens
+		total.CostUSD += g.CostUSD
+		total.ToolFailures += g.ToolFailures
+		total.FailedTurns += g.FailedTurns
+	}
+	if len(groups) > 1 {
+		row(total)
+	}
+	return b.String()
+}
+
+// formatMetricsPrometheus renders the groups in the Prometheus text
+// exposition format, labelled by model (and status for session counts).
+func formatMetricsPrometheus(groups []*metricsGroup) string {
+	var b strings.Builder
+	gauge := func(name, help string, value func(*metricsGroup) string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for _, g := range groups {
+			fmt.Fprintf(&b, "%s{model=\"%s\"} %s\n", name, promLabel(g.Model), value(g))
+		}
+	}
+
+	fmt.Fprintf(&b, "# HELP tcx_sessions Sessions publishing metrics search attributes.\n# TYPE tcx_sessions gauge\n")
+	for _, g := range groups {
+		statuses := make([]string, 0, len(g.ByStatus))
+		for status := range g.ByStatus {
+			statuses = append(statuses, status)
+		}
+		sort.Strings(statuses)
+		for _, status := range statuses {
+			fmt.Fprintf(&b, "tcx_sessions{model=\"%s\",status=\"%s\"} %d\n", promLabel(g.Model), promLabel(status), g.ByStatus[status])
+		}
+	}
+	gauge("tcx_session_tokens", "Total LLM tokens used by sessions.",
+		func(g *metricsGroup) string { return fmt.Sprint(g.TotalTokens) })
+	gauge("tcx_session_cost_usd", "Estimated LLM spend of sessions in USD.",
+		func(g *metricsGroup) string { return fmt.Sprintf("%g", g.CostUSD) })
+	gauge("tcx_session_turns", "Turns run by sessions.",
+		func(g *metricsGroup) string { return fmt.Sprint(g.Turns) })
+	gauge("tcx_session_tool_failures", "Failed tool calls of sessions.",
+		func(g *metricsGroup) string { return fmt.Sprint(g.ToolFailures) })
+	gauge("tcx_session_failed_turns", "Turns of sessions that ended in error.",
+		func(g *metricsGroup) string { return fmt.Sprint(g.FailedTurns) })
+	return b.String()
+}
+
+// promLabel escapes a Prometheus label value.
+func promLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
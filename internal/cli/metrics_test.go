@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+)
+
+func metricsPayloads(t *testing.T, values map[string]interface{}) map[string]*commonpb.Payload {
+	attrs := make(map[string]*commonpb.Payload, len(values))
+	for name, v := range values {
+		p, err := converter.GetDefaultDataConverter().ToPayload(v)
+		require.NoError(t, err)
+		attrs[name] = p
+	}
+	return attrs
+}
+
+func TestDecodeSessionMetrics(t *testing.T) {
+	r := decodeSessionMetrics("running", metricsPayloads(t, map[string]interface{}{
+		"TcxModel":        "gpt-4o",
+		"TcxTotalTokens":  int64(1200),
+		"TcxCostUsd":      0.25,
+		"TcxTurns":        int64(3),
+		"TcxToolFailures": int64(2),
+	}))
+	assert.Equal(t, sessionMetricsRecord{
+		Status: "running", Model: "gpt-4o", TotalTokens: 1200, CostUSD: 0.25, Turns: 3, ToolFailures: 2,
+	}, r)
+
+	assert.Equal(t, "unknown", decodeSessionMetrics("completed", nil).Model)
+}
+
+func TestFormatMetrics(t *testing.T) {
+	groups := aggregateMetrics([]sessionMetricsRecord{
+		{Status: "running", Model: "gpt-4o", TotalTokens: 1000, CostUSD: 0.5, Turns: 2},
+		{Status: "completed", Model: "gpt-4o", TotalTokens: 500, CostUSD: 0.25, Turns: 1, FailedTurns: 1},
+		{Status: "completed", Model: "claude-sonnet-4-0", TotalTokens: 2000, Turns: 4, ToolFailures: 3},
+	})
+	require.Len(t, groups, 2)
+	assert.Equal(t, "claude-sonnet-4-0", groups[0].Model)
+	assert.Equal(t, 2, groups[1].Sessions)
+	assert.Equal(t, 1, groups[1].Running)
+
+	table := formatMetricsTable(groups)
+	assert.Contains(t, table, "MODEL")
+	assert.Contains(t, table, "TOTAL")
+	assert.Contains(t, table, "3,500")
+	assert.Contains(t, table, "$0.75")
+
+	prom := formatMetricsPrometheus(groups)
+	assert.Contains(t, prom, "# TYPE tcx_sessions gauge\n")
+	assert.Contains(t, prom, `tcx_sessions{model="gpt-4o",status="completed"} 1`)
+	assert.Contains(t, prom, `tcx_session_tokens{model="gpt-4o"} 1500`)
+	assert.Contains(t, prom, `tcx_session_cost_usd{model="gpt-4o"} 0.75`)
+	assert.Contains(t, prom, `tcx_session_tool_failures{model="claude-sonnet-4-0"} 3`)
+
+	assert.Equal(t, `a\"b\\c`, promLabel(`a"b\c`))
+	assert.Equal(t, "No sessions with metrics search attributes.\n", formatMetricsTable(nil))
+}
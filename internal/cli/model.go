@@ -3,6 +3,7 @@ package cli
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"time"
@@ -92,11 +93,14 @@ const (
 
 // Config holds CLI configuration.
 type Config struct {
-	TemporalHost string
+	// Temporal connection (host, namespace, TLS/mTLS, API key, dial retries)
+	Connection temporalclient.ConnectionConfig
+
 	Message      string // Initial message for new workflow
 	Model        string
 	NoMarkdown   bool
 	NoColor      bool
+	Markdown     MarkdownOptions // code block rendering, from [markdown] in config.toml
 	Cwd          string
 
 	// Permissions (approval, sandbox, env)
@@ -114,17 +118,75 @@ type Config struct {
 	Inline             bool   // Disable alt-screen mode
 	DisableSuggestions bool   // Disable prompt suggestions
 
+	// OutputMode selects the interactive TUI or plain-text output for
+	// non-TTY environments. Run resolves auto (and empty) from the terminal.
+	OutputMode OutputMode
+
+	// Accessible selects accessibility mode for screen readers: plain
+	// output with labeled blocks, announced state transitions and choice
+	// prompts answered by key (see accessible.go). It implies plain output.
+	Accessible bool
+
+	// ArtifactsDir receives the artifacts bundle of a headless run
+	// (--local -m): summary.json, diff.patch and final_message.md (see
+	// artifacts.go). Verify is a shell command run after the turn whose
+	// result the bundle records.
+	ArtifactsDir string
+	Verify       string
+
+	// InputPreviewTokens asks for confirmation before sending a message
+	// estimated to add at least this many tokens. 0 disables the prompt.
+	InputPreviewTokens int
+
+	// MaxSessionCostUSD pauses a session once its estimated LLM spend
+	// reaches this amount. 0 keeps the config.toml setting.
+	MaxSessionCostUSD float64
+
+	// Timezone (IANA name) and Locale (BCP 47 tag) of the user, sent to new
+	// sessions for the current date given to the model. Empty lets the
+	// worker's config.toml decide.
+	Timezone string
+	Locale   string
+
+	// Persona is the persona preset of new sessions, resolved from --persona
+	// and the [persona] table of PersonaConfigPath. /persona switches it and
+	// saves the choice for the project in PersonaConfigPath.
+	Persona           string
+	PersonaConfigPath string
+
+	// Remote runs the tools of new sessions on a remote machine over SSH
+	// (--remote). Nil leaves it to [remote] of the worker's config.toml.
+	Remote *models.RemoteConfig
+
+	// Ask starts Q&A sessions: no tools, no approvals, lighter prompt.
+	Ask bool
+
+	// Ephemeral marks sessions that cannot be resumed after exit (tcx demo
+	// stops its server), so no resume hint is printed.
+	Ephemeral bool
+
 	// ConnectionTimeout limits how long each Temporal RPC waits before giving up.
 	// 0 means no per-call timeout (default for interactive use).
 	// Short values (e.g. 10s) make tests fail fast when the server is dead.
 	ConnectionTimeout time.Duration
 
+	// Harness workflow ID template and reuse/conflict policies
+	WorkflowID WorkflowIDOptions
+
 	// Crew configuration (set by start-crew subcommand)
 	CrewName   string            // Crew template name (e.g. "bug-fixer")
 	CrewInputs map[string]string // Raw user-provided inputs for crew interpolation
 	CrewType   string            // Name of the crew template (for display)
 }
 
+// sessionType maps CLI flags to the workflow session type.
+func (c Config) sessionType() models.SessionType {
+	if c.Ask {
+		return models.SessionTypeAsk
+	}
+	return models.SessionTypeAgent
+}
+
 // Model is the bubbletea model for the interactive CLI.
 type Model struct {
 	// Configuration
@@ -159,7 +221,10 @@ type Model struct {
 	reasoningEffort   string
 	totalTokens       int
 	totalCachedTokens int
+	suggestionTokens  int // Tokens spent on prompt suggestions (not in totalTokens)
 	contextWindowPct  int
+	lastTurnLatency   *workflow.TurnLatency
+	latency           *workflow.LatencyStats
 	turnCount         int
 	spinnerMsg        string
 	workerVersion     string
@@ -168,6 +233,10 @@ type Model struct {
 	// Approval state
 	pendingApprovals   []workflow.PendingApproval
 	autoApprove        bool
+	// Per-group decisions ("Decide per group..."): groups still to decide
+	// and the response accumulated so far.
+	approvalGroupQueue []approvalGroup
+	approvalGroupResp  workflow.ApprovalResponse
 	pendingEscalations []workflow.EscalationRequest
 
 	// User input question state
@@ -186,16 +255,27 @@ type Model struct {
 
 	// Prompt suggestion (ghost text shown as placeholder after turn completes)
 	suggestion string
+	// Set after Enter on an empty line with a suggestion; a second Enter sends it.
+	suggestionConfirm bool
 
 	// Paste buffering: multi-line pastes show "[N lines pasted]" placeholder
 	pastedContent string
 	pasteLabel    string
 
+	// Large message awaiting y/n confirmation (see formatInputPreview)
+	pendingLargeInput string
+
+	// Executor session started by /handoff, awaiting y/n to switch to it
+	pendingHandoff string
+
 	// Ctrl+C tracking
 	lastInterruptTime time.Time
 
-	// Watching (blocking get_state_update)
+	// Watching (blocking get_state_update). Each watcher gets its own
+	// channel and generation so a stopped watcher's last result, or a reader
+	// still waiting on its channel, cannot reach the current one.
 	watchCh           chan WatchResult
+	watchGen          int
 	watchCancel       context.CancelFunc
 	lastPhase         workflow.TurnPhase
 	consecutiveErrors int
@@ -237,12 +317,35 @@ type Model struct {
 
 	// /resume command state — distinguishes resume picker from startup picker
 	resumingSession bool
+	// resumingAfterCollision marks the resume picker shown after a workflow ID
+	// collision at startup; there is no session to return to, so Esc quits.
+	resumingAfterCollision bool
+
+	// Plain output mode: viewport content is also written to plainOut, and
+	// progress lines replace the spinner on progressOut.
+	plainOut          io.Writer
+	progressOut       io.Writer
+	progressPhaseName string
+	progressSince     time.Time
+	progressPrinted   time.Time
+
+	// announcedSelector is the last selector whose choices accessibility
+	// mode listed.
+	announcedSelector *SelectorModel
+
+	// workflowStuck is set while the session's workflow task keeps failing
+	// (see stuck.go), so the error is shown once.
+	workflowStuck bool
+
+	// workerProtocol is the last protocol version the worker reported in
+	// TurnStatus (see protocol.go); zero until the first status arrives.
+	workerProtocol int
 }
 
 // NewModel creates a new bubbletea model.
 func NewModel(config Config, c client.Client) Model {
 	styles := DefaultStyles()
-	if config.NoColor {
+	if config.NoColor || config.OutputMode == OutputPlain {
 		styles = NoColorStyles()
 	}
 
@@ -281,7 +384,7 @@ func NewModel(config Config, c client.Client) Model {
 		watchCh:         make(chan WatchResult, 1),
 		modelName:       config.Model,
 		provider:        config.Provider,
-		harnessID:       harnessWorkflowID(cwd),
+		harnessID:       harnessWorkflowID(cwd, config.WorkflowID.Template),
 	}
 
 	// Initialize reasoning effort from model profile
@@ -291,6 +394,17 @@ func NewModel(config Config, c client.Client) Model {
 		model.reasoningEffort = string(*profile.DefaultReasoningEffort)
 	}
 
+	// Plain mode gets no window size messages; size the view up front.
+	if model.plain() {
+		model.plainOut = os.Stdout
+		model.progressOut = os.Stderr
+		model.width = plainWidth
+		model.viewport = viewport.New(plainWidth, 1)
+		model.renderer = NewItemRenderer(plainWidth, true, true, model.styles).
+			WithAccessible(config.Accessible)
+		model.ready = true
+	}
+
 	return model
 }
 
@@ -299,18 +413,19 @@ func (m Model) Init() tea.Cmd {
 	cmds := []tea.Cmd{
 		m.spinner.Tick,
 	}
+	if m.plain() {
+		cmds = []tea.Cmd{plainProgressTick()}
+	}
+	if m.client != nil {
+		cmds = append(cmds, workflowHealthTick())
+	}
 
 	if m.config.Message != "" {
 		// -m provided: start new session immediately (skip picker)
 		cmds = append(cmds, startWorkflowCmd(m.client, m.config))
 	} else {
 		// No message: show session picker, fetch sessions in background
-		cwd := m.config.Cwd
-		if cwd == "" {
-			cwd, _ = os.Getwd()
-		}
-		harnessID := harnessWorkflowID(cwd)
-		cmds = append(cmds, fetchSessionsCmd(m.client, harnessID))
+		cmds = append(cmds, fetchSessionsCmd(m.client, m.harnessID))
 	}
 
 	return tea.Batch(cmds...)
@@ -325,8 +440,21 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handleWindowSize(msg)
 
 	case tea.KeyMsg:
+		if m.config.Accessible {
+			return m.handleAccessibleKey(msg)
+		}
 		return m.handleKeyMsg(msg)
 
+	case plainProgressTickMsg:
+		m.updatePlainProgress(time.Time(msg))
+		cmds = append(cmds, plainProgressTick())
+
+	case workflowHealthTickMsg:
+		cmds = append(cmds, m.checkWorkflowHealthCmd(), workflowHealthTick())
+
+	case WorkflowHealthMsg:
+		m.handleWorkflowHealth(msg)
+
 	case spinner.TickMsg:
 		if m.state == StateWatching || m.state == StateStartup || m.state == StateSessionPicker {
 			var cmd tea.Cmd
@@ -370,6 +498,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.quitting = true
 		return &m, tea.Quit
 
+	case WorkflowIDCollisionMsg:
+		return m.handleWorkflowIDCollision(msg)
+
 	case PollResultMsg:
 		return m.handlePollResult(msg)
 
@@ -384,6 +515,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Update status from snapshot
 		m.totalTokens = msg.Response.Status.TotalTokens
 		m.totalCachedTokens = msg.Response.Status.TotalCachedTokens
+		m.suggestionTokens = msg.Response.Status.SuggestionTokens
+		m.lastTurnLatency = msg.Response.Status.LastTurnLatency
+		m.latency = msg.Response.Status.Latency
 		m.contextWindowPct = msg.Response.Status.ContextWindowRemaining
 		m.turnCount = msg.Response.Status.TurnCount
 		if msg.Response.Status.WorkerVersion != "" {
@@ -419,12 +553,24 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case ApprovalSentMsg:
 		m.pendingApprovals = nil
+		m.approvalGroupQueue = nil
 		m.selector = nil
 		m.state = StateWatching
 		m.spinnerMsg = "Running tools..."
 		cmds = append(cmds, m.startWatching())
 
 	case ApprovalErrorMsg:
+		if approvalExpired(msg.Err) {
+			// The workflow already denied the calls; resume watching to
+			// show the outcome.
+			m.appendToViewport(m.renderer.RenderSystemMessage("Approval expired before the decision arrived; the calls were not run."))
+			m.pendingApprovals = nil
+			m.approvalGroupQueue = nil
+			m.selector = nil
+			m.state = StateWatching
+			cmds = append(cmds, m.startWatching())
+			break
+		}
 		m.appendToViewport(fmt.Sprintf("Error sending approval: %v\n", msg.Err))
 
 	case EscalationSentMsg:
@@ -524,7 +670,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if msg.Result.TotalCachedTokens > 0 {
 				sessionEndMsg += fmt.Sprintf(" (%d cached)", msg.Result.TotalCachedTokens)
 			}
-			sessionEndMsg += fmt.Sprintf(", Tools: %d\n", len(msg.Result.ToolCallsExecuted))
+			sessionEndMsg += fmt.Sprintf(", Tools: %d", len(msg.Result.ToolCallsExecuted))
+			if l := msg.Result.Latency; l != nil {
+				sessionEndMsg += fmt.Sprintf(", Avg turn: %s (first response %s)",
+					formatStatDuration(l.AvgTurnMs()), formatStatDuration(l.AvgFirstResponseMs()))
+			}
+			sessionEndMsg += "\n"
 			m.appendToViewport(sessionEndMsg)
 		} else {
 			m.appendToViewport("Session ended.\n")
@@ -554,12 +705,17 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.lastRenderedSeq = -1
 		m.totalTokens = 0
 		m.totalCachedTokens = 0
+		m.suggestionTokens = 0
+		m.lastTurnLatency = nil
+		m.latency = nil
 		m.contextWindowPct = 100
 		m.turnCount = 0
 		m.workerVersion = ""
 		m.lastPhase = ""
 		m.consecutiveErrors = 0
 		m.plannerActive = false
+		m.parentWorkflowID = ""
+		m.plannerAgentID = ""
 		m.suggestion = ""
 		m.workflowID = msg.WorkflowID
 		m.appendToViewport(m.renderer.RenderSystemMessage(
@@ -568,6 +724,19 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.spinnerMsg = "Thinking..."
 		cmds = append(cmds, m.startWatching())
 
+	case HandoffStartedMsg:
+		m.appendToViewport(m.renderer.RenderSystemMessage(fmt.Sprintf(
+			"Handed off the plan and %d explored files to executor session %s. Switch to it now? [y/n]",
+			len(msg.Brief.ExploredFiles), msg.WorkflowID)))
+		m.pendingHandoff = msg.WorkflowID
+		m.state = StateInput
+		m.textarea.Blur()
+
+	case HandoffErrorMsg:
+		m.appendToViewport(fmt.Sprintf("Error handing off: %v\n", msg.Err))
+		m.state = StateInput
+		cmds = append(cmds, m.focusTextarea())
+
 	case NewSessionErrorMsg:
 		m.appendToViewport(fmt.Sprintf("Error starting new session: %v\n", msg.Err))
 		m.state = StateInput
@@ -588,6 +757,44 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.state = StateInput
 		cmds = append(cmds, m.focusTextarea())
 
+	case TrivialRoutingSetMsg:
+		if msg.Enabled {
+			m.appendToViewport(m.renderer.RenderSystemMessage("Trivial questions will be answered by the cheaper model."))
+		} else {
+			m.appendToViewport(m.renderer.RenderSystemMessage("Every turn will use the session's model."))
+		}
+		m.state = StateInput
+		cmds = append(cmds, m.focusTextarea())
+
+	case TrivialRoutingErrorMsg:
+		m.appendToViewport(fmt.Sprintf("Error updating routing: %v\n", msg.Err))
+		m.state = StateInput
+		cmds = append(cmds, m.focusTextarea())
+
+	case PersonaUpdateSentMsg:
+		m.config.Persona = msg.Persona
+		if m.config.Persona == models.PersonaNone {
+			m.config.Persona = ""
+		}
+		text := "Persona turned off."
+		if m.config.Persona != "" {
+			text = fmt.Sprintf("Persona set to: %s", m.config.Persona)
+		}
+		if msg.Project != "" && msg.SaveErr == nil {
+			text += fmt.Sprintf(" (saved for %s)", msg.Project)
+		}
+		m.appendToViewport(m.renderer.RenderSystemMessage(text))
+		if msg.SaveErr != nil {
+			m.appendToViewport(fmt.Sprintf("Could not save persona to config.toml: %v\n", msg.SaveErr))
+		}
+		m.state = StateInput
+		cmds = append(cmds, m.focusTextarea())
+
+	case PersonaUpdateErrorMsg:
+		m.appendToViewport(fmt.Sprintf("Error updating persona: %v\n", msg.Err))
+		m.state = StateInput
+		cmds = append(cmds, m.focusTextarea())
+
 	case SessionNameSentMsg:
 		m.sessionName = msg.Name
 		m.appendToViewport(m.renderer.RenderSystemMessage(
@@ -656,7 +863,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.state = StateWatching
 			m.spinnerMsg = "Thinking..."
 			m.textarea.Blur()
-			return &m, sendUserInputCmd(m.client, m.workflowID, reviewMsg)
+			return &m, sendUserInputCmd(m.client, m.workflowID, m.protocol(), reviewMsg)
 		}
 
 	case McpToolsResultMsg:
@@ -669,6 +876,81 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.state = StateInput
 		cmds = append(cmds, m.focusTextarea())
 
+	case ToolStatsResultMsg:
+		m.appendToViewport(formatToolStatsDisplay(msg.Stats))
+		m.state = StateInput
+		cmds = append(cmds, m.focusTextarea())
+
+	case ToolStatsErrorMsg:
+		m.appendToViewport(fmt.Sprintf("Error fetching tool stats: %v\n", msg.Err))
+		m.state = StateInput
+		cmds = append(cmds, m.focusTextarea())
+
+	case FingerprintResultMsg:
+		m.appendToViewport(formatFingerprintDisplay(msg.Fingerprint))
+		if path, err := writeFingerprintArtifact(m.workflowID, msg.Fingerprint); err != nil {
+			m.appendToViewport(fmt.Sprintf("Error saving fingerprint: %v\n", err))
+		} else {
+			m.appendToViewport(fmt.Sprintf("Full fingerprint saved to %s\n", path))
+		}
+		m.state = StateInput
+		cmds = append(cmds, m.focusTextarea())
+
+	case FingerprintErrorMsg:
+		m.appendToViewport(fmt.Sprintf("Error fetching fingerprint: %v\n", msg.Err))
+		m.state = StateInput
+		cmds = append(cmds, m.focusTextarea())
+
+	case LastPromptResultMsg:
+		text := formatPromptSnapshot(msg.Snapshot)
+		path, err := writeWhyArtifact(m.workflowID, msg.Snapshot, text)
+		if err != nil {
+			m.appendToViewport(fmt.Sprintf("Error saving prompt: %v\n", err))
+		} else {
+			m.appendToViewport(fmt.Sprintf("Prompt for %s saved to %s\n", msg.Snapshot.TurnID, path))
+			if pager := whyPagerCmd(path); pager != nil {
+				cmds = append(cmds, pager)
+			}
+		}
+		m.state = StateInput
+		cmds = append(cmds, m.focusTextarea())
+
+	case LastPromptErrorMsg:
+		m.appendToViewport(fmt.Sprintf("Error fetching last prompt: %v\n", msg.Err))
+		m.state = StateInput
+		cmds = append(cmds, m.focusTextarea())
+
+	case ItemResultMsg:
+		if msg.Expand && msg.Item.Type == models.ItemTypeAssistantMessage {
+			m.appendToViewport(m.renderer.RenderExpandedMessage(msg.Item))
+		} else {
+			m.appendToViewport(m.renderer.RenderSystemMessage(
+				fmt.Sprintf("Item %d (%s), unrendered:", msg.Item.Seq, msg.Item.Type)))
+			m.appendToViewport(strings.TrimRight(rawItemContent(msg.Item), "\n") + "\n")
+		}
+		m.state = StateInput
+		cmds = append(cmds, m.focusTextarea())
+
+	case ItemErrorMsg:
+		m.appendToViewport(fmt.Sprintf("Error fetching item: %v\n", msg.Err))
+		m.state = StateInput
+		cmds = append(cmds, m.focusTextarea())
+
+	case WhyPagerClosedMsg:
+		if msg.Err != nil {
+			m.appendToViewport(fmt.Sprintf("Pager failed: %v (prompt is in %s)\n", msg.Err, msg.Path))
+		}
+
+	case TodoResultMsg:
+		m.appendToViewport(formatTodoDisplay(msg.Todos))
+		m.state = StateInput
+		cmds = append(cmds, m.focusTextarea())
+
+	case TodoErrorMsg:
+		m.appendToViewport(fmt.Sprintf("Error updating TODOs: %v\n", msg.Err))
+		m.state = StateInput
+		cmds = append(cmds, m.focusTextarea())
+
 	case ExecSessionsResultMsg:
 		m.appendToViewport(formatExecSessionsDisplay(msg.Sessions))
 		m.state = StateInput
@@ -877,7 +1159,8 @@ func (m *Model) handleWindowSize(msg tea.WindowSizeMsg) (tea.Model, tea.Cmd) {
 		m.viewport = viewport.New(m.width, vpHeight)
 		m.viewport.SetContent(m.viewportContent)
 
-		m.renderer = NewItemRenderer(m.width, m.config.NoColor, m.config.NoMarkdown, m.styles)
+		m.renderer = NewItemRenderer(m.width, m.config.NoColor, m.config.NoMarkdown, m.styles).
+			WithMarkdownOptions(m.config.Markdown)
 
 		m.textarea.SetWidth(m.width)
 		m.ready = true
@@ -1111,6 +1394,16 @@ func (m *Model) handleInputKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
+	// Large message preview: y/Enter sends, n/Esc returns it to the editor.
+	if m.pendingLargeInput != "" {
+		return m.handleLargeInputConfirmKey(msg)
+	}
+
+	// Handoff: y/Enter switches to the executor session, n/Esc stays.
+	if m.pendingHandoff != "" {
+		return m.handleHandoffConfirmKey(msg)
+	}
+
 	// Intercept multi-line paste: show "[N lines pasted]" placeholder
 	if msg.Paste && msg.Type == tea.KeyRunes && strings.ContainsRune(string(msg.Runes), '\n') {
 		content := string(msg.Runes)
@@ -1124,16 +1417,38 @@ func (m *Model) handleInputKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, cmd
 	}
 
+	// Any key other than Enter cancels a pending "send suggestion" confirm.
+	if m.suggestionConfirm && msg.Type != tea.KeyEnter {
+		m.suggestionConfirm = false
+		m.textarea.Placeholder = m.suggestion
+	}
+
 	// Tab key: accept suggestion if present and textarea is empty
 	if msg.Type == tea.KeyTab {
 		if m.suggestion != "" && m.textarea.Value() == "" {
-			m.textarea.SetValue(m.suggestion)
-			m.textarea.CursorEnd()
-			m.clearSuggestion()
+			m.acceptSuggestion()
 		}
 		return m, nil
 	}
 
+	// Right arrow on an empty line also accepts the suggestion for editing
+	if msg.Type == tea.KeyRight && m.suggestion != "" && m.textarea.Value() == "" {
+		m.acceptSuggestion()
+		return m, nil
+	}
+
+	// Enter on an empty line with a suggestion: first press asks for
+	// confirmation, second press sends the suggestion as typed input.
+	if msg.Type == tea.KeyEnter && !msg.Paste && m.suggestion != "" &&
+		strings.TrimSpace(m.textarea.Value()) == "" {
+		if !m.suggestionConfirm {
+			m.suggestionConfirm = true
+			m.textarea.Placeholder = "Enter again to send: " + m.suggestion
+			return m, nil
+		}
+		m.textarea.SetValue(m.suggestion)
+	}
+
 	// Ignore Enter during a bracketed paste (don't submit mid-paste)
 	if msg.Paste && msg.Type == tea.KeyEnter {
 		return m, nil
@@ -1223,6 +1538,17 @@ func (m *Model) handleInputKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.textarea.Blur()
 			return m, sendPlanRequestCmd(m.client, m.workflowID, planMsg)
 		}
+		if line == "/run-plan" {
+			if m.workflowID == "" {
+				m.appendToViewport("No active session. Start a session first.\n")
+				return m, nil
+			}
+			m.appendToViewport(m.renderer.RenderSystemMessage("Executing plan..."))
+			m.spinnerMsg = "Starting plan step..."
+			m.state = StateWatching
+			m.textarea.Blur()
+			return m, sendExecutePlanCmd(m.client, m.workflowID)
+		}
 		if line == "/done" {
 			if !m.plannerActive {
 				m.appendToViewport("Not in plan mode. Use /plan <message> to start.\n")
@@ -1255,6 +1581,79 @@ func (m *Model) handleInputKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.textarea.Blur()
 			return m, queryMcpToolsCmd(m.client, m.workflowID)
 		}
+		if line == "/stats" {
+			if m.workflowID == "" {
+				m.appendToViewport("No active session.\n")
+				return m, nil
+			}
+			m.spinnerMsg = "Fetching tool stats..."
+			m.state = StateWatching
+			m.textarea.Blur()
+			return m, queryToolStatsCmd(m.client, m.workflowID)
+		}
+		if line == "/why" {
+			if m.workflowID == "" {
+				m.appendToViewport("No active session.\n")
+				return m, nil
+			}
+			m.spinnerMsg = "Fetching last prompt..."
+			m.state = StateWatching
+			m.textarea.Blur()
+			return m, queryLastPromptCmd(m.client, m.workflowID)
+		}
+		if line == "/fingerprint" {
+			if m.workflowID == "" {
+				m.appendToViewport("No active session.\n")
+				return m, nil
+			}
+			m.spinnerMsg = "Fetching fingerprint..."
+			m.state = StateWatching
+			m.textarea.Blur()
+			return m, queryFingerprintCmd(m.client, m.workflowID)
+		}
+		if line == "/raw" || strings.HasPrefix(line, "/raw ") || line == "/expand" || strings.HasPrefix(line, "/expand ") {
+			command := strings.Fields(line)[0]
+			if m.workflowID == "" {
+				m.appendToViewport("No active session.\n")
+				return m, nil
+			}
+			seq, err := parseItemCommand(line, command)
+			if err != nil {
+				m.appendToViewport(err.Error() + "\n")
+				return m, nil
+			}
+			m.spinnerMsg = "Fetching item..."
+			m.state = StateWatching
+			m.textarea.Blur()
+			return m, queryItemCmd(m.client, m.workflowID, seq, command == "/expand")
+		}
+		if line == "/todo" || strings.HasPrefix(line, "/todo ") {
+			if m.workflowID == "" {
+				m.appendToViewport("No active session.\n")
+				return m, nil
+			}
+			req, err := parseTodoCommand(line)
+			if err != nil {
+				m.appendToViewport(fmt.Sprintf("%v\n%s", err, todoUsage))
+				return m, nil
+			}
+			m.spinnerMsg = "Updating TODOs..."
+			m.state = StateWatching
+			m.textarea.Blur()
+			return m, sendTodoCmd(m.client, m.workflowID, req)
+		}
+		if line == "/stop" || line == "/abort" {
+			if m.workflowID == "" {
+				m.appendToViewport("No active session.\n")
+				return m, nil
+			}
+			mode := workflow.InterruptModeSoft
+			if line == "/abort" {
+				mode = workflow.InterruptModeHard
+			}
+			m.appendToViewport(fmt.Sprintf("Sending %s interrupt...\n", mode))
+			return m, sendInterruptCmd(m.client, m.workflowID, mode)
+		}
 		if line == "/ps" {
 			if m.workflowID == "" {
 				m.appendToViewport("No active session.\n")
@@ -1275,6 +1674,22 @@ func (m *Model) handleInputKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.textarea.Blur()
 			return m, cleanExecSessionsCmd(m.client, m.workflowID)
 		}
+		if strings.HasPrefix(line, "/resume --raise-cap") {
+			if m.workflowID == "" {
+				m.appendToViewport("No active session.\n")
+				return m, nil
+			}
+			capUSD, err := parseRaiseCap(strings.TrimPrefix(line, "/resume --raise-cap"))
+			if err != nil {
+				m.appendToViewport("Usage: /resume --raise-cap [usd]\n")
+				return m, nil
+			}
+			m.appendToViewport(m.renderer.RenderSystemMessage("Raising cost cap..."))
+			m.spinnerMsg = "Resuming..."
+			m.state = StateWatching
+			m.textarea.Blur()
+			return m, sendRaiseCostCapCmd(m.client, m.workflowID, capUSD)
+		}
 		if line == "/resume" {
 			m.appendToViewport(m.renderer.RenderSystemMessage("Fetching sessions..."))
 			m.resumingSession = true
@@ -1295,6 +1710,56 @@ func (m *Model) handleInputKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.textarea.Blur()
 			return m, startNewSessionCmd(m.client, m.harnessID, newMsg, m.config)
 		}
+		if line == "/handoff" || strings.HasPrefix(line, "/handoff ") {
+			if m.workflowID == "" {
+				m.appendToViewport("No active session.\n")
+				return m, nil
+			}
+			note := strings.TrimSpace(strings.TrimPrefix(line, "/handoff"))
+			m.appendToViewport(m.renderer.RenderSystemMessage("Handing off to a new executor session..."))
+			m.spinnerMsg = "Starting executor session..."
+			m.state = StateWatching
+			m.textarea.Blur()
+			return m, handoffCmd(m.client, m.harnessID, m.workflowID, note, m.config)
+		}
+		if line == "/persona" || strings.HasPrefix(line, "/persona ") {
+			persona := strings.TrimSpace(strings.TrimPrefix(line, "/persona"))
+			if persona == "" {
+				m.appendToViewport(formatPersonaList(m.config.Persona))
+				return m, nil
+			}
+			if err := models.ValidatePersona(persona); err != nil {
+				m.appendToViewport(fmt.Sprintf("%v\n", err))
+				return m, nil
+			}
+			if m.workflowID == "" {
+				m.appendToViewport("No active session.\n")
+				return m, nil
+			}
+			cwd := m.config.Cwd
+			if cwd == "" {
+				cwd, _ = os.Getwd()
+			}
+			m.spinnerMsg = "Setting persona..."
+			m.state = StateWatching
+			m.textarea.Blur()
+			return m, sendUpdatePersonaCmd(m.client, m.workflowID, m.config.PersonaConfigPath, cwd, persona)
+		}
+		if line == "/route" || strings.HasPrefix(line, "/route ") {
+			if m.workflowID == "" {
+				m.appendToViewport("No active session.\n")
+				return m, nil
+			}
+			arg := strings.TrimSpace(strings.TrimPrefix(line, "/route"))
+			if arg != "on" && arg != "off" {
+				m.appendToViewport("usage: /route on|off\n")
+				return m, nil
+			}
+			m.spinnerMsg = "Updating routing..."
+			m.state = StateWatching
+			m.textarea.Blur()
+			return m, sendSetTrivialRoutingCmd(m.client, m.workflowID, arg == "on")
+		}
 		if strings.HasPrefix(line, "/personality") {
 			if m.workflowID == "" {
 				m.appendToViewport("No active session.\n")
@@ -1405,22 +1870,20 @@ func (m *Model) handleInputKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m, querySkillsCmd(m.client, m.workflowID)
 		}
 
-		// Show user message in viewport (❯ prefix, no separators)
-		m.appendToViewport(m.renderer.RenderUserMessage(models.ConversationItem{
-			Type:    models.ItemTypeUserMessage,
-			Content: line,
-		}))
-
-		m.state = StateWatching
-		m.spinnerMsg = "Thinking..."
-		m.textarea.Blur()
-
-		// If no workflow yet, start one with this message
-		if m.workflowID == "" {
-			m.config.Message = line
-			return m, startWorkflowCmd(m.client, m.config)
+		if m.config.InputPreviewTokens > 0 {
+			cwd := m.config.Cwd
+			if cwd == "" {
+				cwd, _ = os.Getwd()
+			}
+			if tokens := estimateInputTokens(line, cwd); tokens >= m.config.InputPreviewTokens {
+				m.pendingLargeInput = line
+				m.appendToViewport(m.renderer.RenderSystemMessage(
+					formatInputPreview(tokens, m.provider, m.modelName)))
+				m.textarea.Blur()
+				return m, nil
+			}
 		}
-		return m, sendUserInputCmd(m.client, m.workflowID, line)
+		return m.sendUserMessage(line)
 	}
 
 	// Pre-expand textarea height for newline insertion (Shift+Enter / ctrl+j)
@@ -1465,8 +1928,69 @@ func (m *Model) handleInputKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// sendUserMessage shows a submitted message and sends it to the workflow,
+// starting the session if there is none yet.
+func (m *Model) sendUserMessage(line string) (tea.Model, tea.Cmd) {
+	// Show user message in viewport (❯ prefix, no separators)
+	m.appendToViewport(m.renderer.RenderUserMessage(models.ConversationItem{
+		Type:    models.ItemTypeUserMessage,
+		Content: line,
+	}))
+
+	m.state = StateWatching
+	m.spinnerMsg = "Thinking..."
+	m.textarea.Blur()
+
+	// If no workflow yet, start one with this message
+	if m.workflowID == "" {
+		m.config.Message = line
+		return m, startWorkflowCmd(m.client, m.config)
+	}
+	return m, sendUserInputCmd(m.client, m.workflowID, m.protocol(), line)
+}
+
+// handleLargeInputConfirmKey answers the large message preview.
+func (m *Model) handleLargeInputConfirmKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	line := m.pendingLargeInput
+	switch {
+	case msg.Type == tea.KeyEnter || (msg.Type == tea.KeyRunes && (msg.String() == "y" || msg.String() == "Y")):
+		m.pendingLargeInput = ""
+		return m.sendUserMessage(line)
+	case msg.Type == tea.KeyEsc || (msg.Type == tea.KeyRunes && (msg.String() == "n" || msg.String() == "N")):
+		m.pendingLargeInput = ""
+		m.appendToViewport(m.renderer.RenderSystemMessage("Not sent."))
+		m.textarea.SetValue(line)
+		return m, m.focusTextarea()
+	}
+	return m, nil
+}
+
+// handleHandoffConfirmKey answers the offer to switch to the executor
+// session started by /handoff.
+func (m *Model) handleHandoffConfirmKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	workflowID := m.pendingHandoff
+	switch {
+	case msg.Type == tea.KeyEnter || (msg.Type == tea.KeyRunes && (msg.String() == "y" || msg.String() == "Y")):
+		m.pendingHandoff = ""
+		m.textarea.Blur()
+		return m, func() tea.Msg { return NewSessionStartedMsg{WorkflowID: workflowID} }
+	case msg.Type == tea.KeyEsc || (msg.Type == tea.KeyRunes && (msg.String() == "n" || msg.String() == "N")):
+		m.pendingHandoff = ""
+		m.appendToViewport(m.renderer.RenderSystemMessage(
+			fmt.Sprintf("Staying here; the executor session %s keeps running.", workflowID)))
+		return m, m.focusTextarea()
+	}
+	return m, nil
+}
+
 func (m *Model) handleWatchingKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	// During watching, only allow viewport scrolling
+	// Esc requests a soft interrupt: finish running tools, skip the next LLM call.
+	if msg.Type == tea.KeyEsc && m.workflowID != "" {
+		m.appendToViewport("\nStopping after the current step... (Ctrl+C to abort now)\n")
+		return m, sendInterruptCmd(m.client, m.workflowID, workflow.InterruptModeSoft)
+	}
+
+	// Otherwise, only allow viewport scrolling
 	var cmd tea.Cmd
 	m.viewport, cmd = m.viewport.Update(msg)
 	return m, cmd
@@ -1489,7 +2013,7 @@ func (m *Model) handleSessionPickerKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if m.selector.Cancelled() {
 			m.selector = nil
 			m.selectingSession = false
-			if m.resumingSession {
+			if m.resumingSession && !m.resumingAfterCollision {
 				// Esc during /resume — go back to input
 				m.resumingSession = false
 				m.state = StateInput
@@ -1506,6 +2030,7 @@ func (m *Model) handleSessionPickerKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if m.resumingSession {
 			// /resume picker — no "New session" option, direct index mapping
 			m.resumingSession = false
+			m.resumingAfterCollision = false
 			if idx < 0 || idx >= len(m.sessionEntries) {
 				m.appendToViewport("Invalid selection.\n")
 				m.state = StateInput
@@ -1519,6 +2044,9 @@ func (m *Model) handleSessionPickerKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.lastRenderedSeq = -1
 			m.totalTokens = 0
 			m.totalCachedTokens = 0
+			m.suggestionTokens = 0
+			m.lastTurnLatency = nil
+			m.latency = nil
 			m.contextWindowPct = 100
 			m.turnCount = 0
 			m.workerVersion = ""
@@ -1560,18 +2088,27 @@ func (m *Model) handleApprovalKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if done {
 			if m.selector.Confirmed() {
 				selected := m.selector.Selected()
+				if m.approvalGroupQueue != nil {
+					return m.decideApprovalGroup(selected == 0)
+				}
 				if len(m.pendingApprovals) > 1 && selected == 3 {
 					m.selector = nil
 					m.textarea.SetValue("")
 					return m, m.focusTextarea()
 				}
+				if selected == 4 {
+					m.approvalGroupQueue = groupApprovals(m.pendingApprovals)
+					m.approvalGroupResp = workflow.ApprovalResponse{}
+					m.showApprovalGroupSelector()
+					return m, nil
+				}
 				response, setAutoApprove := ApprovalSelectionToResponse(selected, m.pendingApprovals)
 				if response != nil {
 					if setAutoApprove {
 						m.autoApprove = true
 					}
 					m.selector = nil
-					return m, sendApprovalResponseCmd(m.client, m.workflowID, *response)
+					return m, sendApprovalResponseCmd(m.client, m.workflowID, m.protocol(), *response)
 				}
 			}
 			if m.selector.Cancelled() {
@@ -1580,7 +2117,8 @@ func (m *Model) handleApprovalKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 					allCallIDs[i] = ap.CallID
 				}
 				m.selector = nil
-				return m, sendApprovalResponseCmd(m.client, m.workflowID, workflow.ApprovalResponse{Denied: allCallIDs})
+				m.approvalGroupQueue = nil
+				return m, sendApprovalResponseCmd(m.client, m.workflowID, m.protocol(), workflow.ApprovalResponse{Denied: allCallIDs})
 			}
 		}
 		vpHeight := m.height - m.inputAreaHeight() - 2
@@ -1602,7 +2140,7 @@ func (m *Model) handleApprovalKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				m.autoApprove = true
 			}
 			m.textarea.Blur()
-			return m, sendApprovalResponseCmd(m.client, m.workflowID, *response)
+			return m, sendApprovalResponseCmd(m.client, m.workflowID, m.protocol(), *response)
 		}
 		m.appendToViewport("Please enter y(es), n(o), a(lways), or indices (e.g. 1,3):\n")
 		return m, nil
@@ -1633,7 +2171,7 @@ func (m *Model) handleEscalationKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				response := EscalationSelectionToResponse(m.selector.Selected(), m.pendingEscalations)
 				if response != nil {
 					m.selector = nil
-					return m, sendEscalationResponseCmd(m.client, m.workflowID, *response)
+					return m, sendEscalationResponseCmd(m.client, m.workflowID, m.protocol(), *response)
 				}
 			}
 			if m.selector.Cancelled() {
@@ -1642,7 +2180,7 @@ func (m *Model) handleEscalationKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 					allCallIDs[i] = esc.CallID
 				}
 				m.selector = nil
-				return m, sendEscalationResponseCmd(m.client, m.workflowID, workflow.EscalationResponse{Denied: allCallIDs})
+				return m, sendEscalationResponseCmd(m.client, m.workflowID, m.protocol(), workflow.EscalationResponse{Denied: allCallIDs})
 			}
 		}
 		return m, nil
@@ -1655,7 +2193,7 @@ func (m *Model) handleEscalationKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		response := HandleEscalationInput(line, m.pendingEscalations)
 		if response != nil {
 			m.textarea.Blur()
-			return m, sendEscalationResponseCmd(m.client, m.workflowID, *response)
+			return m, sendEscalationResponseCmd(m.client, m.workflowID, m.protocol(), *response)
 		}
 		m.appendToViewport("Please enter y(es) or n(o):\n")
 		return m, nil
@@ -1714,6 +2252,10 @@ func (m *Model) handleUserInputQuestionKey(msg tea.KeyMsg) (tea.Model, tea.Cmd)
 			m.textarea.Blur()
 			return m, sendUserInputQuestionResponseCmd(m.client, m.workflowID, *response)
 		}
+		if isFreeTextRequest(m.pendingUserInputReq) {
+			m.appendToViewport("Please type an answer:\n")
+			return m, nil
+		}
 		m.appendToViewport("Please enter a valid option number:\n")
 		return m, nil
 	}
@@ -1768,18 +2310,19 @@ func (m *Model) handleCtrlC() (tea.Model, tea.Cmd) {
 		} else {
 			m.appendToViewport("\nInterrupting... (Ctrl+C again to disconnect)\n")
 		}
-		return m, sendInterruptCmd(m.client, m.workflowID)
+		return m, sendInterruptCmd(m.client, m.workflowID, workflow.InterruptModeHard)
 
 	case StateApproval:
 		m.lastInterruptTime = now
 		m.appendToViewport("\nInterrupting...\n")
 		m.pendingApprovals = nil
+		m.approvalGroupQueue = nil
 		m.selector = nil
 		m.state = StateWatching
 		m.spinnerMsg = "Interrupting..."
 		m.textarea.Blur()
 		cmds := []tea.Cmd{
-			sendInterruptCmd(m.client, m.workflowID),
+			sendInterruptCmd(m.client, m.workflowID, workflow.InterruptModeHard),
 			m.startWatching(),
 		}
 		return m, tea.Batch(cmds...)
@@ -1793,7 +2336,7 @@ func (m *Model) handleCtrlC() (tea.Model, tea.Cmd) {
 		m.spinnerMsg = "Interrupting..."
 		m.textarea.Blur()
 		cmds := []tea.Cmd{
-			sendInterruptCmd(m.client, m.workflowID),
+			sendInterruptCmd(m.client, m.workflowID, workflow.InterruptModeHard),
 			m.startWatching(),
 		}
 		return m, tea.Batch(cmds...)
@@ -1807,7 +2350,7 @@ func (m *Model) handleCtrlC() (tea.Model, tea.Cmd) {
 		m.spinnerMsg = "Interrupting..."
 		m.textarea.Blur()
 		cmds := []tea.Cmd{
-			sendInterruptCmd(m.client, m.workflowID),
+			sendInterruptCmd(m.client, m.workflowID, workflow.InterruptModeHard),
 			m.startWatching(),
 		}
 		return m, tea.Batch(cmds...)
@@ -1826,6 +2369,25 @@ func (m *Model) handleCtrlC() (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// handleWorkflowIDCollision offers to resume a running session when the
+// harness workflow ID cannot be started, instead of failing outright.
+func (m *Model) handleWorkflowIDCollision(msg WorkflowIDCollisionMsg) (tea.Model, tea.Cmd) {
+	if len(msg.Entries) == 0 {
+		m.err = fmt.Errorf("workflow ID %q is already in use and the reuse/conflict policy does not allow starting it again; "+
+			"use a different --workflow-id-template or --workflow-id-reuse-policy", msg.WorkflowID)
+		m.quitting = true
+		return m, tea.Quit
+	}
+	m.appendToViewport(fmt.Sprintf("Workflow ID %s is already in use. Select a running session to resume (Esc to quit).\n", msg.WorkflowID))
+	m.sessionEntries = msg.Entries
+	m.resumingSession = true
+	m.resumingAfterCollision = true
+	m.selectingSession = true
+	m.selector = m.buildResumeSessionSelector(msg.Entries)
+	m.state = StateSessionPicker
+	return m, nil
+}
+
 func (m *Model) handleWorkflowStarted(msg WorkflowStartedMsg) (tea.Model, tea.Cmd) {
 	m.workflowID = msg.WorkflowID
 
@@ -1856,6 +2418,11 @@ func (m *Model) handleWorkflowStarted(msg WorkflowStartedMsg) (tea.Model, tea.Cm
 			m.lastRenderedPlan = msg.Status.Plan
 		}
 
+		// Remind the user of open TODOs when resuming a session
+		if hasOpenTodos(msg.Status.Todos) {
+			m.appendToViewport(formatTodoDisplay(msg.Status.Todos))
+		}
+
 		// Set state based on turn status
 		switch msg.Status.Phase {
 		case workflow.PhaseWaitingForInput:
@@ -1941,9 +2508,12 @@ func (m *Model) handlePollResult(msg PollResultMsg) (tea.Model, tea.Cmd) {
 	m.renderNewItems(result.Items)
 
 	// Update status
-	m.spinnerMsg = PhaseMessage(result.Status.Phase, result.Status.ToolsInFlight)
+	m.spinnerMsg = StatusMessage(result.Status)
 	m.totalTokens = result.Status.TotalTokens
 	m.totalCachedTokens = result.Status.TotalCachedTokens
+	m.suggestionTokens = result.Status.SuggestionTokens
+	m.lastTurnLatency = result.Status.LastTurnLatency
+	m.latency = result.Status.Latency
 	m.contextWindowPct = result.Status.ContextWindowRemaining
 	m.turnCount = result.Status.TurnCount
 	if result.Status.WorkerVersion != "" {
@@ -1967,7 +2537,7 @@ func (m *Model) handlePollResult(msg PollResultMsg) (tea.Model, tea.Cmd) {
 			for i, ap := range result.Status.PendingApprovals {
 				callIDs[i] = ap.CallID
 			}
-			return m, sendApprovalResponseCmd(m.client, m.workflowID, workflow.ApprovalResponse{Approved: callIDs})
+			return m, sendApprovalResponseCmd(m.client, m.workflowID, m.protocol(), workflow.ApprovalResponse{Approved: callIDs})
 		}
 		m.stopWatching()
 		m.state = StateApproval
@@ -2000,7 +2570,7 @@ func (m *Model) handlePollResult(msg PollResultMsg) (tea.Model, tea.Cmd) {
 			m.selector = sel
 			return m, nil
 		}
-		// Multi-question: fall back to textarea
+		// Multi-question or free-text: fall back to textarea
 		m.appendToViewport(m.renderer.RenderUserInputQuestionPrompt(result.Status.PendingUserInputRequest))
 		return m, m.focusTextarea()
 	}
@@ -2028,6 +2598,11 @@ func (m *Model) handlePollResult(msg PollResultMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m *Model) handleWatchResult(msg WatchResultMsg) (tea.Model, tea.Cmd) {
+	// A result from a watcher that has since been stopped or replaced is
+	// stale; the current watcher has its own reader.
+	if msg.Gen != m.watchGen {
+		return m, nil
+	}
 	result := msg.Result
 
 	if result.Err != nil {
@@ -2067,14 +2642,18 @@ func (m *Model) handleWatchResult(msg WatchResultMsg) (tea.Model, tea.Cmd) {
 	m.renderNewItems(result.Items)
 
 	// Update status
-	m.spinnerMsg = PhaseMessage(result.Status.Phase, result.Status.ToolsInFlight)
+	m.spinnerMsg = StatusMessage(result.Status)
 	m.totalTokens = result.Status.TotalTokens
 	m.totalCachedTokens = result.Status.TotalCachedTokens
+	m.suggestionTokens = result.Status.SuggestionTokens
+	m.lastTurnLatency = result.Status.LastTurnLatency
+	m.latency = result.Status.Latency
 	m.contextWindowPct = result.Status.ContextWindowRemaining
 	m.turnCount = result.Status.TurnCount
 	if result.Status.WorkerVersion != "" {
 		m.workerVersion = result.Status.WorkerVersion
 	}
+	m.noteWorkerProtocol(result.Status.Version)
 	m.lastPhase = result.Status.Phase
 
 	// Check for plan changes and render
@@ -2094,7 +2673,7 @@ func (m *Model) handleWatchResult(msg WatchResultMsg) (tea.Model, tea.Cmd) {
 			for i, ap := range result.Status.PendingApprovals {
 				callIDs[i] = ap.CallID
 			}
-			return m, sendApprovalResponseCmd(m.client, m.workflowID, workflow.ApprovalResponse{Approved: callIDs})
+			return m, sendApprovalResponseCmd(m.client, m.workflowID, m.protocol(), workflow.ApprovalResponse{Approved: callIDs})
 		}
 		m.stopWatching()
 		m.state = StateApproval
@@ -2220,7 +2799,7 @@ func (m *Model) handlePlannerCompleted(msg PlannerCompletedMsg) (tea.Model, tea.
 		planInput := "Implement the following plan:\n\n" + msg.PlanText
 		m.state = StateWatching
 		m.spinnerMsg = "Thinking..."
-		return m, sendUserInputCmd(m.client, m.workflowID, planInput)
+		return m, sendUserInputCmd(m.client, m.workflowID, m.protocol(), planInput)
 	}
 
 	m.appendToViewport(m.renderer.RenderSystemMessage("Plan mode ended (no plan produced)."))
@@ -2229,6 +2808,9 @@ func (m *Model) handlePlannerCompleted(msg PlannerCompletedMsg) (tea.Model, tea.
 }
 
 func (m *Model) appendToViewport(content string) {
+	if m.plain() {
+		m.writePlain(content)
+	}
 	wasAtBottom := m.viewport.AtBottom()
 
 	if m.viewportContent != "" {
@@ -2266,19 +2848,29 @@ func (m *Model) startWatching() tea.Cmd {
 	if m.config.ConnectionTimeout > 0 {
 		watcher.WithRPCTimeout(m.config.ConnectionTimeout)
 	}
-	go watcher.RunWatching(watchCtx, m.watchCh, m.lastRenderedSeq, m.lastPhase)
+	ch := make(chan WatchResult, 1)
+	m.watchCh = ch
+	m.watchGen++
+	sinceSeq, sincePhase := m.lastRenderedSeq, m.lastPhase
+	go func() {
+		watcher.RunWatching(watchCtx, ch, sinceSeq, sincePhase)
+		// Releases the reader waiting on this watcher's channel.
+		close(ch)
+	}()
 
 	return m.waitForWatchResult()
 }
 
+// waitForWatchResult returns a command that reads the next result of the
+// current watcher. It yields no message once that watcher has stopped.
 func (m *Model) waitForWatchResult() tea.Cmd {
-	ch := m.watchCh
+	ch, gen := m.watchCh, m.watchGen
 	return func() tea.Msg {
 		result, ok := <-ch
 		if !ok {
-			return SessionCompletedMsg{}
+			return nil
 		}
-		return WatchResultMsg{Result: result}
+		return WatchResultMsg{Result: result, Gen: gen}
 	}
 }
 
@@ -2329,11 +2921,53 @@ func (m *Model) buildApprovalSelector(approvals []workflow.PendingApproval) *Sel
 			ShortcutKey: 's',
 		})
 	}
+	if hasMultiCallGroups(groupApprovals(approvals)) {
+		options = append(options, SelectorOption{
+			Label:       "Decide per group...",
+			Shortcut:    "g",
+			ShortcutKey: 'g',
+		})
+	}
 	sel := NewSelectorModel(options, m.styles)
 	sel.SetWidth(m.width)
 	return sel
 }
 
+// showApprovalGroupSelector prompts for the next group in approvalGroupQueue.
+func (m *Model) showApprovalGroupSelector() {
+	total := len(groupApprovals(m.pendingApprovals))
+	g := m.approvalGroupQueue[0]
+	n := total - len(m.approvalGroupQueue) + 1
+	m.appendToViewport(m.renderer.RenderSystemMessage(fmt.Sprintf("Group %d of %d [%s]: %s", n, total, g.indexList(), g.Label)))
+	sel := NewSelectorModel([]SelectorOption{
+		{Label: "Yes, allow", Shortcut: "y", ShortcutKey: 'y'},
+		{Label: "No, deny", Shortcut: "n", ShortcutKey: 'n'},
+	}, m.styles)
+	sel.SetWidth(m.width)
+	m.selector = sel
+}
+
+// decideApprovalGroup records the decision for the current group and either
+// prompts for the next one or sends the combined response, which still
+// lists every call ID individually.
+func (m *Model) decideApprovalGroup(approve bool) (tea.Model, tea.Cmd) {
+	ids := m.approvalGroupQueue[0].callIDs(m.pendingApprovals)
+	if approve {
+		m.approvalGroupResp.Approved = append(m.approvalGroupResp.Approved, ids...)
+	} else {
+		m.approvalGroupResp.Denied = append(m.approvalGroupResp.Denied, ids...)
+	}
+	m.approvalGroupQueue = m.approvalGroupQueue[1:]
+	if len(m.approvalGroupQueue) > 0 {
+		m.showApprovalGroupSelector()
+		return m, nil
+	}
+	resp := m.approvalGroupResp
+	m.approvalGroupQueue = nil
+	m.selector = nil
+	return m, sendApprovalResponseCmd(m.client, m.workflowID, m.protocol(), resp)
+}
+
 // buildEscalationSelector creates a selector for escalation prompts.
 func (m *Model) buildEscalationSelector() *SelectorModel {
 	options := []SelectorOption{
@@ -2346,9 +2980,9 @@ func (m *Model) buildEscalationSelector() *SelectorModel {
 }
 
 // buildUserInputSelector creates a selector for single-question user input prompts.
-// Returns nil for multi-question requests (fall back to textarea).
+// Returns nil for multi-question and free-text requests (fall back to textarea).
 func (m *Model) buildUserInputSelector(req *workflow.PendingUserInputRequest) *SelectorModel {
-	if req == nil || len(req.Questions) != 1 {
+	if req == nil || len(req.Questions) != 1 || isFreeTextRequest(req) {
 		return nil
 	}
 	q := req.Questions[0]
@@ -2385,7 +3019,7 @@ func (m *Model) buildSessionSelector(entries []SessionListEntry) *SelectorModel
 		}
 		icon := sessionStatusIcon(e.Status)
 		label := fmt.Sprintf("%-32s %s %-10s  %s",
-			displayName, icon, e.Status, e.StartTime.Local().Format("Jan 02, 15:04"))
+			displayName, icon, e.Status, displayTime(e.StartTime).Format("Jan 02, 15:04"))
 		opts = append(opts, SelectorOption{Label: label})
 	}
 	sel := NewSelectorModel(opts, m.styles)
@@ -2406,7 +3040,7 @@ func (m *Model) buildResumeSessionSelector(entries []SessionListEntry) *Selector
 		}
 		icon := sessionStatusIcon(e.Status)
 		label := fmt.Sprintf("%-32s %s %-10s  %s",
-			displayName, icon, e.Status, e.StartTime.Local().Format("Jan 02, 15:04"))
+			displayName, icon, e.Status, displayTime(e.StartTime).Format("Jan 02, 15:04"))
 		opts = append(opts, SelectorOption{Label: label})
 	}
 	sel := NewSelectorModel(opts, m.styles)
@@ -2453,14 +3087,23 @@ func (m *Model) inputAreaHeight() int {
 // applySuggestion sets the suggestion and updates the textarea placeholder.
 func (m *Model) applySuggestion(suggestion string) {
 	m.suggestion = suggestion
+	m.suggestionConfirm = false
 	if suggestion != "" {
 		m.textarea.Placeholder = suggestion
 	}
 }
 
+// acceptSuggestion inserts the suggestion into the textarea for editing.
+func (m *Model) acceptSuggestion() {
+	m.textarea.SetValue(m.suggestion)
+	m.textarea.CursorEnd()
+	m.clearSuggestion()
+}
+
 // clearSuggestion resets the suggestion and restores the default placeholder.
 func (m *Model) clearSuggestion() {
 	m.suggestion = ""
+	m.suggestionConfirm = false
 	m.textarea.Placeholder = "Type a message..."
 }
 
@@ -2523,31 +3166,37 @@ func planChanged(old, new *workflow.PlanState) bool {
 
 // Run is the main entry point for the CLI.
 func Run(config Config) error {
+	SetDisplayTimezone(config.Timezone)
+
 	// Create Temporal client
-	clientOpts, err := temporalclient.LoadClientOptions(config.TemporalHost, "")
-	if err != nil {
-		return fmt.Errorf("failed to load Temporal client config: %w", err)
-	}
-	c, err := client.Dial(clientOpts)
+	c, err := temporalclient.Dial(config.Connection)
 	if err != nil {
 		return fmt.Errorf("failed to connect to Temporal: %w", err)
 	}
 	defer c.Close()
 
+	if config.Accessible {
+		config.OutputMode = OutputPlain
+	}
+	config.OutputMode = config.OutputMode.resolve(isTerminal(os.Stdout), isTerminal(os.Stderr))
 	model := NewModel(config, c)
 
 	var opts []tea.ProgramOption
-	if !config.Inline {
-		opts = append(opts, tea.WithAltScreen())
+	if config.OutputMode == OutputPlain {
+		// Nothing is drawn; the model writes plain text itself.
+		opts = append(opts, tea.WithoutRenderer())
+	} else {
+		if !config.Inline {
+			opts = append(opts, tea.WithAltScreen())
+		}
+		// Enable CSI 1007 alternate scroll mode: the terminal translates mouse
+		// wheel events into arrow key sequences. This gives us wheel scrolling
+		// without capturing the mouse, so normal text selection keeps working.
+		fmt.Fprint(os.Stderr, "\x1b[?1007h")
+		defer fmt.Fprint(os.Stderr, "\x1b[?1007l")
 	}
 	p := tea.NewProgram(model, opts...)
 
-	// Enable CSI 1007 alternate scroll mode: the terminal translates mouse
-	// wheel events into arrow key sequences. This gives us wheel scrolling
-	// without capturing the mouse, so normal text selection keeps working.
-	fmt.Fprint(os.Stderr, "\x1b[?1007h")
-	defer fmt.Fprint(os.Stderr, "\x1b[?1007l")
-
 	finalModel, err := p.Run()
 	if err != nil {
 		return fmt.Errorf("TUI error: %w", err)
@@ -2555,7 +3204,7 @@ func Run(config Config) error {
 
 	// Print resume hint after exiting TUI
 	fm := finalModel.(*Model)
-	if fm.workflowID != "" && fm.err == nil {
+	if fm.workflowID != "" && fm.err == nil && !config.Ephemeral {
 		fmt.Fprintf(os.Stderr, "\nSession suspended. Run tcx to resume from the session picker.\n")
 	}
 
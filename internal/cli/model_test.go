@@ -393,6 +393,41 @@ func TestModel_DoubleCtrlCDuringWatchingDisconnects(t *testing.T) {
 	assert.True(t, rm.quitting)
 }
 
+func TestModel_EscDuringWatchingSoftInterrupts(t *testing.T) {
+	m := newTestModel()
+	m.state = StateWatching
+	m.workflowID = "test-wf"
+
+	result, cmd := m.handleWatchingKey(tea.KeyMsg{Type: tea.KeyEsc})
+	rm := result.(*Model)
+	assert.Equal(t, StateWatching, rm.state)
+	assert.Contains(t, rm.viewportContent, "Stopping after the current step")
+	assert.NotNil(t, cmd)
+}
+
+func TestModel_StopAbortCommands(t *testing.T) {
+	for _, line := range []string{"/stop", "/abort"} {
+		m := newTestModel()
+		m.workflowID = ""
+		m.textarea.SetValue(line)
+		result, _ := m.handleInputKey(tea.KeyMsg{Type: tea.KeyEnter})
+		assert.Contains(t, result.(*Model).viewportContent, "No active session", line)
+
+		m = newTestModel()
+		m.workflowID = "test-wf"
+		m.textarea.SetValue(line)
+		result, cmd := m.handleInputKey(tea.KeyMsg{Type: tea.KeyEnter})
+		rm := result.(*Model)
+		assert.Equal(t, StateInput, rm.state, line)
+		assert.NotNil(t, cmd, line)
+	}
+	m := newTestModel()
+	m.workflowID = "test-wf"
+	m.textarea.SetValue("/abort")
+	result, _ := m.handleInputKey(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Contains(t, result.(*Model).viewportContent, "Sending hard interrupt")
+}
+
 func TestModel_CtrlCDuringApprovalInterrupts(t *testing.T) {
 	m := newTestModel()
 	m.state = StateApproval
@@ -699,6 +734,55 @@ func TestModel_TabNoOpWithExistingText(t *testing.T) {
 	assert.Equal(t, "run the tests", rm.suggestion, "suggestion should remain")
 }
 
+func TestModel_RightArrowAcceptsSuggestion(t *testing.T) {
+	m := newTestModel()
+	m.state = StateInput
+	m.applySuggestion("run the tests")
+
+	result, _ := m.handleInputKey(tea.KeyMsg{Type: tea.KeyRight})
+	rm := result.(*Model)
+	assert.Equal(t, "run the tests", rm.textarea.Value())
+	assert.Equal(t, "", rm.suggestion)
+}
+
+func TestModel_EnterOnEmptyLineConfirmsThenSendsSuggestion(t *testing.T) {
+	m := newTestModel()
+	m.state = StateInput
+	m.workflowID = "test-wf"
+	m.applySuggestion("run the tests")
+
+	result, cmd := m.handleInputKey(tea.KeyMsg{Type: tea.KeyEnter})
+	rm := result.(*Model)
+	assert.Nil(t, cmd, "first Enter should only ask for confirmation")
+	assert.True(t, rm.suggestionConfirm)
+	assert.Contains(t, rm.textarea.Placeholder, "Enter again to send: run the tests")
+	assert.Equal(t, StateInput, rm.state)
+
+	result, cmd = rm.handleInputKey(tea.KeyMsg{Type: tea.KeyEnter})
+	rm = result.(*Model)
+	assert.NotNil(t, cmd, "second Enter should send the suggestion")
+	assert.Equal(t, StateWatching, rm.state)
+	assert.Equal(t, "", rm.suggestion)
+	assert.False(t, rm.suggestionConfirm)
+	assert.Contains(t, rm.viewportContent, "run the tests")
+}
+
+func TestModel_SuggestionConfirmCancelledByOtherKey(t *testing.T) {
+	m := newTestModel()
+	m.state = StateInput
+	m.applySuggestion("run the tests")
+
+	result, _ := m.handleInputKey(tea.KeyMsg{Type: tea.KeyEnter})
+	rm := result.(*Model)
+	assert.True(t, rm.suggestionConfirm)
+
+	result, _ = rm.handleInputKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	rm = result.(*Model)
+	assert.False(t, rm.suggestionConfirm)
+	assert.Equal(t, "run the tests", rm.suggestion)
+	assert.Equal(t, "run the tests", rm.textarea.Placeholder)
+}
+
 func TestModel_SubmitClearsSuggestion(t *testing.T) {
 	m := newTestModel()
 	m.state = StateInput
@@ -1134,3 +1218,141 @@ func TestPlanChanged(t *testing.T) {
 		})
 	}
 }
+
+func TestModel_ApprovalDecidePerGroup(t *testing.T) {
+	m := newTestModel()
+	m.state = StateApproval
+	m.pendingApprovals = groupedApprovals()
+	m.selector = m.buildApprovalSelector(m.pendingApprovals)
+
+	// "g" starts per-group decisions.
+	result, cmd := m.handleApprovalKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'g'}})
+	rm := result.(*Model)
+	assert.Nil(t, cmd)
+	assert.Len(t, rm.approvalGroupQueue, 2)
+	assert.Contains(t, rm.viewportContent, "Group 1 of 2 [1,3,4]: Run `go test <path>` (3 calls)")
+
+	// Approve the go test group, deny the write.
+	result, cmd = rm.handleApprovalKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'y'}})
+	rm = result.(*Model)
+	assert.Nil(t, cmd)
+	assert.Contains(t, rm.viewportContent, "Group 2 of 2 [2]: Write file: x.go")
+
+	result, cmd = rm.handleApprovalKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}})
+	rm = result.(*Model)
+	assert.NotNil(t, cmd, "combined response is sent")
+	assert.Nil(t, rm.approvalGroupQueue)
+	assert.Equal(t, []string{"c1", "c3", "c4"}, rm.approvalGroupResp.Approved)
+	assert.Equal(t, []string{"c2"}, rm.approvalGroupResp.Denied)
+}
+
+
+func TestModel_LargeInputPreview(t *testing.T) {
+	m := newTestModel()
+	m.workflowID = "test-wf"
+	m.config.InputPreviewTokens = 100
+	big := strings.Repeat("log line ", 100)
+
+	m.textarea.SetValue(big)
+	result, cmd := m.handleInputKey(tea.KeyMsg{Type: tea.KeyEnter})
+	rm := result.(*Model)
+	assert.Nil(t, cmd, "large message should wait for confirmation")
+	assert.Equal(t, StateInput, rm.state)
+	assert.Contains(t, rm.viewportContent, "This message will add ~224 tokens")
+	assert.Equal(t, strings.TrimSpace(big), rm.pendingLargeInput)
+
+	// Other keys are ignored while the preview is shown.
+	result, _ = rm.handleInputKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	assert.NotEmpty(t, result.(*Model).pendingLargeInput)
+
+	result, cmd = rm.handleInputKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	rm = result.(*Model)
+	assert.NotNil(t, cmd)
+	assert.Equal(t, StateWatching, rm.state)
+	assert.Empty(t, rm.pendingLargeInput)
+}
+
+func TestModel_LargeInputPreviewDeclined(t *testing.T) {
+	m := newTestModel()
+	m.workflowID = "test-wf"
+	m.config.InputPreviewTokens = 100
+	big := strings.Repeat("log line ", 100)
+
+	m.textarea.SetValue(big)
+	result, _ := m.handleInputKey(tea.KeyMsg{Type: tea.KeyEnter})
+	result, _ = result.(*Model).handleInputKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	rm := result.(*Model)
+	assert.Equal(t, StateInput, rm.state)
+	assert.Empty(t, rm.pendingLargeInput)
+	assert.Equal(t, strings.TrimSpace(big), rm.textarea.Value(), "declined message returns to the editor")
+	assert.Contains(t, rm.viewportContent, "Not sent.")
+}
+
+func TestModel_SmallInputSkipsPreview(t *testing.T) {
+	m := newTestModel()
+	m.workflowID = "test-wf"
+	m.config.InputPreviewTokens = 100
+
+	m.textarea.SetValue("hello")
+	result, cmd := m.handleInputKey(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.NotNil(t, cmd)
+	assert.Equal(t, StateWatching, result.(*Model).state)
+}
+
+func TestModel_WatchResult_StaleGenerationDropped(t *testing.T) {
+	m := newTestModel()
+	m.state = StateWatching
+	m.watchGen = 2
+
+	items := []models.ConversationItem{{Type: models.ItemTypeAssistantMessage, Seq: 0, Content: "from the old watcher"}}
+	result, cmd := m.Update(WatchResultMsg{Result: WatchResult{Items: items}, Gen: 1})
+	rm := result.(*Model)
+	assert.Nil(t, cmd, "a stale result must not re-arm a reader")
+	assert.NotContains(t, rm.viewportContent, "from the old watcher")
+	assert.Equal(t, -1, rm.lastRenderedSeq)
+
+	items[0].Content = "from the current watcher"
+	result, _ = rm.Update(WatchResultMsg{Result: WatchResult{Items: items}, Gen: 2})
+	rm = result.(*Model)
+	assert.Contains(t, rm.viewportContent, "from the current watcher")
+}
+
+func TestModel_HandoffOffersSwitch(t *testing.T) {
+	m := newTestModel()
+	m.workflowID = "planner-wf"
+	m.plannerActive = true
+	m.parentWorkflowID = "main-wf"
+
+	result, _ := m.Update(HandoffStartedMsg{
+		WorkflowID: "executor-wf",
+		Brief:      workflow.HandoffBrief{ExploredFiles: []string{"a.go", "b.go"}},
+	})
+	rm := result.(*Model)
+	assert.Equal(t, StateInput, rm.state)
+	assert.Equal(t, "executor-wf", rm.pendingHandoff)
+	assert.Contains(t, rm.viewportContent, "2 explored files to executor session executor-wf")
+
+	_, cmd := rm.handleInputKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	if !assert.NotNil(t, cmd) {
+		return
+	}
+	assert.Empty(t, rm.pendingHandoff)
+
+	result, _ = rm.Update(cmd())
+	rm = result.(*Model)
+	assert.Equal(t, "executor-wf", rm.workflowID)
+	assert.False(t, rm.plannerActive)
+	assert.Empty(t, rm.parentWorkflowID)
+}
+
+func TestModel_HandoffDeclinedStays(t *testing.T) {
+	m := newTestModel()
+	m.workflowID = "planner-wf"
+
+	result, _ := m.Update(HandoffStartedMsg{WorkflowID: "executor-wf"})
+	result, _ = result.(*Model).handleInputKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	rm := result.(*Model)
+	assert.Empty(t, rm.pendingHandoff)
+	assert.Equal(t, "planner-wf", rm.workflowID)
+	assert.Contains(t, rm.viewportContent, "executor session executor-wf keeps running")
+}
@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"golang.org/x/term"
+)
+
+// OutputMode selects how the TUI draws: full-screen with a spinner, or
+// plain text for CI logs and pipes.
+type OutputMode string
+
+const (
+	// OutputAuto picks plain output when stdout or stderr is not a terminal.
+	OutputAuto OutputMode = "auto"
+	// OutputTTY always draws the interactive TUI.
+	OutputTTY OutputMode = "tty"
+	// OutputPlain writes conversation output to stdout and periodic
+	// progress lines to stderr, without ANSI escape sequences.
+	OutputPlain OutputMode = "plain"
+)
+
+const (
+	// plainWidth is the render width in plain mode, where there is no
+	// terminal to size against.
+	plainWidth = 100
+
+	// plainProgressInterval is how often plain mode repeats the progress
+	// line of a phase that is still running.
+	plainProgressInterval = 15 * time.Second
+)
+
+// ParseOutputMode parses an --output value; empty means auto.
+func ParseOutputMode(s string) (OutputMode, error) {
+	switch OutputMode(s) {
+	case "", OutputAuto:
+		return OutputAuto, nil
+	case OutputTTY, OutputPlain:
+		return OutputMode(s), nil
+	}
+	return "", fmt.Errorf("unknown output mode %q (use auto, tty or plain)", s)
+}
+
+// resolve returns tty or plain for m, given whether stdout and stderr are
+// terminals.
+func (m OutputMode) resolve(stdoutTTY, stderrTTY bool) OutputMode {
+	switch m {
+	case OutputTTY, OutputPlain:
+		return m
+	}
+	if stdoutTTY && stderrTTY {
+		return OutputTTY
+	}
+	return OutputPlain
+}
+
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// plain reports whether the model writes plain output.
+func (m *Model) plain() bool {
+	return m.config.OutputMode == OutputPlain
+}
+
+// writePlain writes viewport content to stdout in plain mode.
+func (m *Model) writePlain(content string) {
+	if m.plainOut != nil {
+		io.WriteString(m.plainOut, ansi.Strip(content))
+	}
+}
+
+// plainProgressTickMsg drives the progress lines of plain mode.
+type plainProgressTickMsg time.Time
+
+func plainProgressTick() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg { return plainProgressTickMsg(t) })
+}
+
+// progressPhase describes what the session is doing, and whether that is
+// work in progress (repeated with the elapsed time) or a wait for the user.
+func (m *Model) progressPhase() (phase string, busy bool) {
+	switch m.state {
+	case StateStartup, StateWatching:
+		if m.spinnerMsg == "" {
+			return "Starting...", true
+		}
+		return m.spinnerMsg, true
+	case StateSessionPicker:
+		return "Loading sessions...", true
+	case StateApproval, StateEscalation:
+		return "Waiting for approval", false
+	case StateUserInputQuestion:
+		return "Waiting for an answer", false
+	case StateInput:
+		return "Ready for input", false
+	}
+	return "", false
+}
+
+// updatePlainProgress prints a progress line when the phase changes and,
+// while a busy phase lasts, every plainProgressInterval.
+func (m *Model) updatePlainProgress(now time.Time) {
+	if m.config.Accessible {
+		m.announceProgress(now)
+		return
+	}
+	phase, busy := m.progressPhase()
+	if phase == "" {
+		return
+	}
+	if phase != m.progressPhaseName {
+		m.progressPhaseName = phase
+		m.progressSince = now
+		m.progressPrinted = now
+		fmt.Fprintln(m.progressOut, formatProgressLine(phase, 0))
+		// Show the choices of a prompt, which has no drawn view here.
+		if !busy && m.selector != nil {
+			fmt.Fprintln(m.progressOut, ansi.Strip(m.selector.View()))
+		}
+		return
+	}
+	if busy && now.Sub(m.progressPrinted) >= plainProgressInterval {
+		m.progressPrinted = now
+		fmt.Fprintln(m.progressOut, formatProgressLine(phase, now.Sub(m.progressSince)))
+	}
+}
+
+// formatProgressLine renders a plain progress line, e.g.
+// "tcx: Thinking... (45s elapsed)".
+func formatProgressLine(phase string, elapsed time.Duration) string {
+	if elapsed < time.Second {
+		return "tcx: " + phase
+	}
+	return fmt.Sprintf("tcx: %s (%s elapsed)", phase, elapsed.Truncate(time.Second))
+}
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOutputMode(t *testing.T) {
+	for in, want := range map[string]OutputMode{"": OutputAuto, "auto": OutputAuto, "tty": OutputTTY, "plain": OutputPlain} {
+		got, err := ParseOutputMode(in)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	_, err := ParseOutputMode("fancy")
+	assert.Error(t, err)
+}
+
+func TestOutputMode_Resolve(t *testing.T) {
+	assert.Equal(t, OutputTTY, OutputAuto.resolve(true, true))
+	assert.Equal(t, OutputPlain, OutputAuto.resolve(false, true))
+	assert.Equal(t, OutputPlain, OutputAuto.resolve(true, false))
+	assert.Equal(t, OutputTTY, OutputTTY.resolve(false, false), "tty forces the TUI")
+	assert.Equal(t, OutputPlain, OutputPlain.resolve(true, true), "plain forces plain output")
+}
+
+func TestPlainMode_OutputAndProgress(t *testing.T) {
+	m := NewModel(Config{Model: "gpt-4o-mini", Message: "hello", OutputMode: OutputPlain}, nil)
+	var out, progress strings.Builder
+	m.plainOut = &out
+	m.progressOut = &progress
+	require.True(t, m.ready, "plain mode needs no window size message")
+
+	m.appendToViewport("\x1b[31mboom\x1b[0m\n")
+	assert.Equal(t, "boom\n", out.String(), "content is written without ANSI")
+
+	start := time.Now()
+	m.state = StateWatching
+	m.spinnerMsg = "Thinking..."
+	m.updatePlainProgress(start)
+	m.updatePlainProgress(start.Add(5 * time.Second))
+	m.updatePlainProgress(start.Add(plainProgressInterval + time.Second))
+	m.state = StateInput
+	m.updatePlainProgress(start.Add(20 * time.Second))
+	m.updatePlainProgress(start.Add(60 * time.Second))
+
+	assert.Equal(t, "tcx: Thinking...\n"+
+		"tcx: Thinking... (16s elapsed)\n"+
+		"tcx: Ready for input\n", progress.String())
+	assert.NotContains(t, progress.String(), "\x1b[")
+}
@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mfateev/temporal-agent-harness/internal/instructions"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// personaProjectsTable is the config.toml table /persona saves to.
+const personaProjectsTable = "[persona.projects]"
+
+// ResolvePersona returns the persona for sessions started in cwd: flag when
+// set, else the [persona] policy of the config.toml at configPath. A missing
+// or unreadable config means no persona.
+func ResolvePersona(configPath, cwd, flag string) (string, error) {
+	if flag != "" {
+		if err := models.ValidatePersona(flag); err != nil {
+			return "", err
+		}
+		if flag == models.PersonaNone {
+			return "", nil
+		}
+		return flag, nil
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return "", nil
+	}
+	tc, err := models.ParseConfigToml(data)
+	if err != nil {
+		return "", nil
+	}
+	return tc.Persona.ToPolicy().For(cwd), nil
+}
+
+// personaOverride is the persona sent to the workflow. An unset persona is
+// sent as "none" so it also clears a persona of the shared harness.
+func (c Config) personaOverride() string {
+	if c.Persona == "" {
+		return models.PersonaNone
+	}
+	return c.Persona
+}
+
+// personaProjectRoot returns the project a persona is saved for: the git
+// root containing cwd, else cwd itself.
+func personaProjectRoot(cwd string) string {
+	if root, err := instructions.FindGitRoot(cwd); err == nil && root != "" {
+		return root
+	}
+	if abs, err := filepath.Abs(cwd); err == nil {
+		return abs
+	}
+	return cwd
+}
+
+// saveProjectPersona records persona for the project root in the
+// [persona.projects] table of the config.toml at path, editing the file in
+// place so comments and other settings are kept. The file is created if it
+// does not exist and left untouched if the result would not parse.
+func saveProjectPersona(path, root, persona string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	updated := setPersonaProject(string(data), root, persona)
+	if _, err := models.ParseConfigToml([]byte(updated)); err != nil {
+		return fmt.Errorf("%s would no longer parse: %w", path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(updated), 0o644)
+}
+
+// setPersonaProject returns content with the root's entry in the
+// [persona.projects] table set to persona, replacing an existing entry and
+// appending the table when it is missing.
+func setPersonaProject(content, root, persona string) string {
+	entry := fmt.Sprintf("%s = %s", strconv.Quote(root), strconv.Quote(persona))
+	lines := strings.Split(content, "\n")
+
+	start := -1
+	for i, line := range lines {
+		if strings.TrimSpace(line) == personaProjectsTable {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		content = strings.TrimRight(content, "\n")
+		if content != "" {
+			content += "\n\n"
+		}
+		return content + personaProjectsTable + "\n" + entry + "\n"
+	}
+
+	insert := start + 1
+	for i := start + 1; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trimmed, "[") {
+			break
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		key, _, ok := strings.Cut(trimmed, "=")
+		if !ok {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(strings.TrimSpace(key)); err == nil && unquoted == root {
+			lines[i] = entry
+			return strings.Join(lines, "\n")
+		}
+		insert = i + 1
+	}
+	lines = append(lines[:insert], append([]string{entry}, lines[insert:]...)...)
+	return strings.Join(lines, "\n")
+}
+
+// formatPersonaList describes the current persona and the presets.
+func formatPersonaList(current string) string {
+	var b strings.Builder
+	if current == "" {
+		b.WriteString("Persona: none\n")
+	} else {
+		fmt.Fprintf(&b, "Persona: %s\n", current)
+	}
+	fmt.Fprintf(&b, "Presets: %s, %s. Use /persona <name> to switch; the choice is saved for this project.\n",
+		strings.Join(instructions.PersonaNames(), ", "), models.PersonaNone)
+	return b.String()
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetPersonaProject(t *testing.T) {
+	assert.Equal(t, "[persona.projects]\n\"/work/app\" = \"mentor\"\n",
+		setPersonaProject("", "/work/app", "mentor"))
+
+	existing := `# my settings
+model = "gpt-4o"
+
+[persona.projects]
+# course material
+"/work/course" = "mentor"
+
+[metrics]
+search_attributes = true
+`
+	added := setPersonaProject(existing, "/work/app", "concise")
+	assert.Equal(t, `# my settings
+model = "gpt-4o"
+
+[persona.projects]
+# course material
+"/work/course" = "mentor"
+"/work/app" = "concise"
+
+[metrics]
+search_attributes = true
+`, added)
+
+	replaced := setPersonaProject(added, "/work/course", "none")
+	assert.Contains(t, replaced, "\"/work/course\" = \"none\"\n")
+	assert.NotContains(t, replaced, "\"mentor\"")
+}
+
+func TestSaveProjectPersona_ResolvePersona(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "codex", "config.toml")
+	project := filepath.Join(dir, "app")
+
+	require.NoError(t, saveProjectPersona(path, project, "mentor"))
+	persona, err := ResolvePersona(path, filepath.Join(project, "cmd"), "")
+	require.NoError(t, err)
+	assert.Equal(t, "mentor", persona)
+
+	persona, err = ResolvePersona(path, project, "concise")
+	require.NoError(t, err)
+	assert.Equal(t, "concise", persona, "the flag wins over config.toml")
+	persona, err = ResolvePersona(path, project, "none")
+	require.NoError(t, err)
+	assert.Equal(t, "", persona)
+	_, err = ResolvePersona(path, project, "pirate")
+	assert.Error(t, err)
+
+	// An edit that would break the file is refused.
+	require.NoError(t, os.WriteFile(path, []byte("[persona]\nprojects = { \"/x\" = \"mentor\" }\n"), 0o644))
+	assert.Error(t, saveProjectPersona(path, project, "concise"))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "concise")
+}
@@ -49,10 +49,12 @@ func (p *Poller) Poll(ctx context.Context) PollResult {
 		result.Err = err
 		return result
 	}
-	if err := resp.Get(&result.Items); err != nil {
+	var items models.ConversationItemList
+	if err := resp.Get(&items); err != nil {
 		result.Err = err
 		return result
 	}
+	result.Items = items
 
 	// Query turn status
 	statusResp, err := p.client.QueryWorkflow(queryCtx, p.workflowID, "", workflow.QueryGetTurnStatus)
@@ -0,0 +1,60 @@
+package cli
+
+// Protocol negotiation with the worker. Each TurnStatus carries the
+// worker's protocol version; the CLI sends its payloads with the newer
+// version both sides speak, and says once when the two differ instead of
+// failing on the first payload the other side does not know.
+
+import (
+	"fmt"
+
+	"github.com/mfateev/temporal-agent-harness/internal/workflow"
+)
+
+// protocol returns the protocol version to stamp on outgoing payloads.
+// Before the worker has reported its version, the CLI's own is used.
+func (m *Model) protocol() int {
+	if m.workerProtocol == 0 {
+		return workflow.ProtocolVersion
+	}
+	v, err := workflow.NegotiateProtocol(m.workerProtocol)
+	if err != nil {
+		return workflow.ProtocolVersion
+	}
+	return v
+}
+
+// noteWorkerProtocol records the protocol version from a TurnStatus and
+// reports a mismatch the first time it is seen. Zero is a worker that
+// predates versioning.
+func (m *Model) noteWorkerProtocol(version int) {
+	if version == 0 {
+		version = 1
+	}
+	if version == m.workerProtocol {
+		return
+	}
+	m.workerProtocol = version
+	if msg := protocolMismatchMessage(version); msg != "" {
+		m.appendToViewport(msg)
+	}
+}
+
+// protocolMismatchMessage describes what a protocol mismatch means for
+// the session, or returns "" when the versions agree.
+func protocolMismatchMessage(workerVersion int) string {
+	if _, err := workflow.NegotiateProtocol(workerVersion); err != nil {
+		return fmt.Sprintf("Error: %v\n", err)
+	}
+	switch {
+	case workerVersion > workflow.ProtocolVersion:
+		return fmt.Sprintf("Note: the worker speaks protocol version %d and this tcx speaks %d. "+
+			"Items this tcx cannot display are shown as notices; upgrade tcx to see them.\n",
+			workerVersion, workflow.ProtocolVersion)
+	case workerVersion < workflow.ProtocolVersion:
+		return fmt.Sprintf("Note: the worker speaks protocol version %d and this tcx speaks %d. "+
+			"Features the worker does not support are unavailable; upgrade the worker to use them.\n",
+			workerVersion, workflow.ProtocolVersion)
+	}
+	return ""
+}
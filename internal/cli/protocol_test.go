@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mfateev/temporal-agent-harness/internal/workflow"
+)
+
+func TestNoteWorkerProtocol_ReportsNewerWorkerOnce(t *testing.T) {
+	m := NewModel(Config{Model: "gpt-4o-mini", OutputMode: OutputPlain}, nil)
+	var out strings.Builder
+	m.plainOut = &out
+
+	m.noteWorkerProtocol(0)
+	assert.Empty(t, out.String(), "an unversioned worker speaks version 1")
+	assert.Equal(t, workflow.ProtocolVersion, m.protocol())
+
+	newer := workflow.ProtocolVersion + 1
+	m.noteWorkerProtocol(newer)
+	m.noteWorkerProtocol(newer)
+
+	assert.Equal(t, 1, strings.Count(out.String(), "upgrade tcx"))
+	assert.Equal(t, workflow.ProtocolVersion, m.protocol(), "payloads use the version both sides speak")
+}
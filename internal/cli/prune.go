@@ -0,0 +1,429 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+
+	"github.com/mfateev/temporal-agent-harness/internal/archive"
+	"github.com/mfateev/temporal-agent-harness/internal/blobstore"
+	"github.com/mfateev/temporal-agent-harness/internal/memories"
+	"github.com/mfateev/temporal-agent-harness/internal/scratch"
+	"github.com/mfateev/temporal-agent-harness/internal/temporalclient"
+	"github.com/mfateev/temporal-agent-harness/internal/workflow"
+)
+
+// DefaultPruneAge is the age used by `tcx sessions prune` when neither
+// --older-than nor [retention] session_days is set.
+const DefaultPruneAge = 30 * 24 * time.Hour
+
+// PruneOptions configures `tcx sessions prune`.
+type PruneOptions struct {
+	Connection temporalclient.ConnectionConfig
+	OlderThan  time.Duration
+	DryRun     bool // List what would be pruned without changing anything
+	Yes        bool // Skip the confirmation prompt
+	CodexHome  string
+	// HarnessIDs limits pruning to the sessions of these harnesses. Empty
+	// means the harness of Cwd, named by WorkflowIDTemplate.
+	HarnessIDs         []string
+	Cwd                string
+	WorkflowIDTemplate string
+	// MemoryDbPath overrides codex_home/state.sqlite for transcript cleanup.
+	MemoryDbPath string
+
+	In  io.Reader
+	Out io.Writer
+}
+
+// pruneCandidate is a session selected for pruning.
+type pruneCandidate struct {
+	WorkflowID string
+	RunID      string
+	Status     string
+	LastActive time.Time
+	// Abandoned sessions are still running but idle past the cutoff; they
+	// are terminated rather than deleted.
+	Abandoned bool
+}
+
+// ParseAge parses a prune age: a Go duration ("36h") or a whole number of
+// days ("30d").
+func ParseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid age %q (use e.g. 30d or 72h)", s)
+	}
+	return d, nil
+}
+
+// harnessScope is the visibility clause that limits a query to the
+// sessions of harnessIDs, whose workflow IDs are "<harness>/<session>...".
+func harnessScope(harnessIDs []string) string {
+	clauses := make([]string, len(harnessIDs))
+	for i, id := range harnessIDs {
+		clauses[i] = fmt.Sprintf("WorkflowId STARTS_WITH '%s/'", id)
+	}
+	return "(" + strings.Join(clauses, " OR ") + ")"
+}
+
+// closedSessionsQuery selects finished sessions of harnessIDs that closed
+// before cutoff. ContinuedAsNew runs are excluded: they belong to sessions
+// still running.
+func closedSessionsQuery(harnessIDs []string, cutoff time.Time) string {
+	return fmt.Sprintf(
+		`WorkflowType = 'AgenticWorkflow' AND %s AND ExecutionStatus != 'Running' AND ExecutionStatus != 'ContinuedAsNew' AND CloseTime < '%s'`,
+		harnessScope(harnessIDs), cutoff.UTC().Format(time.RFC3339))
+}
+
+// runningSessionsQuery selects running sessions of harnessIDs; abandonment
+// is decided per session from get_turn_status.
+func runningSessionsQuery(harnessIDs []string) string {
+	return `WorkflowType = 'AgenticWorkflow' AND ` + harnessScope(harnessIDs) + ` AND ExecutionStatus = 'Running'`
+}
+
+// pruneHarnessIDs returns the harnesses opts prunes, rejecting IDs that
+// could not have been generated by a workflow ID template.
+func pruneHarnessIDs(opts PruneOptions) ([]string, error) {
+	if len(opts.HarnessIDs) == 0 {
+		return []string{harnessWorkflowID(opts.Cwd, opts.WorkflowIDTemplate)}, nil
+	}
+	for _, id := range opts.HarnessIDs {
+		if id == "" || invalidIDChars.MatchString(id) {
+			return nil, fmt.Errorf("invalid harness ID %q", id)
+		}
+	}
+	return opts.HarnessIDs, nil
+}
+
+// isAbandoned reports whether a running session has been idle, waiting for
+// input, since before cutoff. Sessions from workers that do not report
+// LastActivityAt are never considered abandoned.
+func isAbandoned(status workflow.TurnStatus, cutoff time.Time) bool {
+	return status.Phase == workflow.PhaseWaitingForInput &&
+		!status.LastActivityAt.IsZero() &&
+		status.LastActivityAt.Before(cutoff)
+}
+
+// RunSessionsPrune implements `tcx sessions prune`: it finds the sessions
+// of the selected harnesses that closed before the cutoff and those running
+// but idle since before it, confirms, then deletes the closed ones,
+// terminates the abandoned ones, and removes their local scratch
+// directories and memory transcripts.
+func RunSessionsPrune(opts PruneOptions) error {
+	harnessIDs, err := pruneHarnessIDs(opts)
+	if err != nil {
+		return err
+	}
+	if opts.In == nil {
+		opts.In = os.Stdin
+	}
+	if opts.Out == nil {
+		opts.Out = os.Stdout
+	}
+	clientOpts, err := temporalclient.LoadClientOptionsFromConfig(opts.Connection)
+	if err != nil {
+		return fmt.Errorf("failed to load Temporal client config: %w", err)
+	}
+	namespace := clientOpts.Namespace
+	if namespace == "" {
+		namespace = client.DefaultNamespace
+	}
+	c, err := temporalclient.DialWithRetry(clientOpts, opts.Connection.DialRetries, opts.Connection.DialRetryBackoff)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Temporal: %w", err)
+	}
+	defer c.Close()
+
+	ctx := context.Background()
+	cutoff := time.Now().Add(-opts.OlderThan)
+	candidates, err := listPruneCandidates(ctx, c, harnessIDs, cutoff)
+	if err != nil {
+		return err
+	}
+
+	out := opts.Out
+	if len(candidates) == 0 {
+		fmt.Fprintf(out, "No sessions older than %s in %s.\n", formatAge(opts.OlderThan), strings.Join(harnessIDs, ", "))
+	} else {
+		fmt.Fprintf(out, "Sessions older than %s in %s:\n", formatAge(opts.OlderThan), strings.Join(harnessIDs, ", "))
+		for _, cand := range candidates {
+			action := "delete"
+			if cand.Abandoned {
+				action = "terminate"
+			}
+			fmt.Fprintf(out, "  %-9s  %-10s  %s  %s\n", action, cand.Status,
+				displayTime(cand.LastActive).Format("2006-01-02"), cand.WorkflowID)
+		}
+	}
+	if opts.DryRun {
+		return nil
+	}
+	question := fmt.Sprintf("Prune %d sessions and local artifacts older than %s?", len(candidates), formatAge(opts.OlderThan))
+	if !opts.Yes && !confirm(opts.In, out, question) {
+		fmt.Fprintln(out, "Aborted.")
+		return nil
+	}
+
+	var pruned []string
+	var terminated, deleted int
+	for _, cand := range candidates {
+		if cand.Abandoned {
+			reason := "pruned: inactive since " + cand.LastActive.UTC().Format(time.RFC3339)
+			if err := c.TerminateWorkflow(ctx, cand.WorkflowID, cand.RunID, reason); err != nil {
+				fmt.Fprintf(out, "  failed to terminate %s: %v\n", cand.WorkflowID, err)
+				continue
+			}
+			terminated++
+		} else {
+			_, err := c.WorkflowService().DeleteWorkflowExecution(ctx, &workflowservice.DeleteWorkflowExecutionRequest{
+				Namespace:         namespace,
+				WorkflowExecution: &commonpb.WorkflowExecution{WorkflowId: cand.WorkflowID, RunId: cand.RunID},
+			})
+			if err != nil {
+				fmt.Fprintf(out, "  failed to delete %s: %v\n", cand.WorkflowID, err)
+				continue
+			}
+			deleted++
+		}
+		pruned = append(pruned, cand.WorkflowID)
+	}
+
+	report := pruneLocalArtifacts(pruned, opts.CodexHome, opts.MemoryDbPath)
+	pruneStoredArchives(ctx, pruned, opts.CodexHome, &report)
+	fmt.Fprintf(out, "Pruned %d sessions (%d deleted, %d terminated); removed %d scratch dirs and %d transcripts, reclaimed %s.\n",
+		len(pruned), deleted, terminated, report.ScratchDirs, report.Transcripts, formatBytes(report.Bytes))
+	for _, w := range report.Warnings {
+		fmt.Fprintf(out, "  warning: %s\n", w)
+	}
+	return nil
+}
+
+// listPruneCandidates lists the closed sessions of harnessIDs older than
+// cutoff and their running sessions abandoned since before it.
+func listPruneCandidates(ctx context.Context, c client.Client, harnessIDs []string, cutoff time.Time) ([]pruneCandidate, error) {
+	var candidates []pruneCandidate
+	err := listExecutions(ctx, c, closedSessionsQuery(harnessIDs, cutoff), func(exec *workflowInfo) {
+		candidates = append(candidates, pruneCandidate{
+			WorkflowID: exec.WorkflowID,
+			RunID:      exec.RunID,
+			Status:     exec.Status,
+			LastActive: exec.CloseTime,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list closed sessions: %w", err)
+	}
+
+	err = listExecutions(ctx, c, runningSessionsQuery(harnessIDs), func(exec *workflowInfo) {
+		qctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		resp, err := c.QueryWorkflow(qctx, exec.WorkflowID, exec.RunID, workflow.QueryGetTurnStatus)
+		if err != nil {
+			return
+		}
+		var status workflow.TurnStatus
+		if err := resp.Get(&status); err != nil || !isAbandoned(status, cutoff) {
+			return
+		}
+		candidates = append(candidates, pruneCandidate{
+			WorkflowID: exec.WorkflowID,
+			RunID:      exec.RunID,
+			Status:     exec.Status,
+			LastActive: status.LastActivityAt,
+			Abandoned:  true,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list running sessions: %w", err)
+	}
+	return candidates, nil
+}
+
+// workflowInfo is the part of a visibility record prune needs.
+type workflowInfo struct {
+	WorkflowID string
+	RunID      string
+	Status     string
+	CloseTime  time.Time
+	// SearchAttributes are the execution's indexed search attributes.
+	SearchAttributes map[string]*commonpb.Payload
+}
+
+// listExecutions pages through a visibility query.
+func listExecutions(ctx context.Context, c client.Client, query string, fn func(*workflowInfo)) error {
+	var token []byte
+	for {
+		lctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		resp, err := c.ListWorkflow(lctx, &workflowservice.ListWorkflowExecutionsRequest{
+			Query:         query,
+			PageSize:      100,
+			NextPageToken: token,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		for _, exec := range resp.GetExecutions() {
+			if exec.GetExecution() == nil {
+				continue
+			}
+			fn(&workflowInfo{
+				WorkflowID: exec.GetExecution().GetWorkflowId(),
+				RunID:      exec.GetExecution().GetRunId(),
+				Status:     mapWorkflowStatus(exec.GetStatus()),
+				CloseTime:  exec.GetCloseTime().AsTime(),
+
+				SearchAttributes: exec.GetSearchAttributes().GetIndexedFields(),
+			})
+		}
+		token = resp.GetNextPageToken()
+		if len(token) == 0 {
+			return nil
+		}
+	}
+}
+
+// pruneReport summarizes local cleanup.
+type pruneReport struct {
+	ScratchDirs int
+	Transcripts int
+	Bytes       int64
+	Warnings    []string
+}
+
+// pruneStoredArchives removes the history archives of pruned sessions from
+// the object store that [storage] in config.toml selects, if any.
+func pruneStoredArchives(ctx context.Context, workflowIDs []string, codexHome string, report *pruneReport) {
+	if len(workflowIDs) == 0 {
+		return
+	}
+	store, cfg, err := blobstore.Load(filepath.Join(codexHome, "config.toml"), codexHome)
+	if err != nil {
+		report.Warnings = append(report.Warnings, err.Error())
+		return
+	}
+	if !cfg.Remote() {
+		return
+	}
+	for _, id := range workflowIDs {
+		size, err := archive.RemoveFrom(ctx, store, id)
+		if err != nil {
+			report.Warnings = append(report.Warnings, err.Error())
+		} else if size > 0 {
+			report.Transcripts++
+			report.Bytes += size
+		}
+	}
+}
+
+// pruneLocalArtifacts removes local artifacts of pruned sessions: their
+// scratch directories, their history archives, and their memory transcripts
+// (stage-1 outputs and rollout summary files).
+// Artifacts that live on a worker on another machine are left to that
+// worker's retention policy.
+func pruneLocalArtifacts(workflowIDs []string, codexHome, memoryDbPath string) pruneReport {
+	var report pruneReport
+	for _, id := range workflowIDs {
+		if _, err := os.Stat(scratch.Dir(id)); err != nil {
+			continue
+		}
+		size := scratch.Size(id)
+		if err := scratch.Remove(id); err != nil {
+			report.Warnings = append(report.Warnings, err.Error())
+			continue
+		}
+		report.ScratchDirs++
+		report.Bytes += size
+	}
+	for _, id := range workflowIDs {
+		size, err := archive.Remove(codexHome, id)
+		if err != nil {
+			report.Warnings = append(report.Warnings, err.Error())
+		} else if size > 0 {
+			report.Transcripts++
+			report.Bytes += size
+		}
+	}
+
+	if memoryDbPath == "" {
+		memoryDbPath = filepath.Join(codexHome, "state.sqlite")
+	}
+	if len(workflowIDs) == 0 {
+		return report
+	}
+	if _, err := os.Stat(memoryDbPath); err != nil {
+		return report
+	}
+	db, err := memories.OpenMemoryDB(memoryDbPath)
+	if err != nil {
+		report.Warnings = append(report.Warnings, err.Error())
+		return report
+	}
+	defer db.Close()
+	summariesDir := filepath.Join(codexHome, "memories", memories.RolloutSummariesSubdir)
+	for _, id := range workflowIDs {
+		out, err := db.GetStage1Output(id)
+		if err != nil || out == nil {
+			continue
+		}
+		file := filepath.Join(summariesDir, memories.RolloutSummaryFileStem(*out)+".md")
+		if info, err := os.Stat(file); err == nil {
+			report.Bytes += info.Size()
+			_ = os.Remove(file)
+		}
+		if ok, err := db.DeleteStage1Output(id); err != nil {
+			report.Warnings = append(report.Warnings, err.Error())
+		} else if ok {
+			report.Transcripts++
+		}
+	}
+	return report
+}
+
+// confirm asks a yes/no question on out and reads the answer from in.
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// formatAge renders a prune age in days when it is a whole number of days.
+func formatAge(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	return d.String()
+}
+
+// formatBytes renders a byte count with a binary unit.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
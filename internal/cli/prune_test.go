@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/archive"
+	"github.com/mfateev/temporal-agent-harness/internal/memories"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/scratch"
+	"github.com/mfateev/temporal-agent-harness/internal/workflow"
+)
+
+func TestParseAge(t *testing.T) {
+	d, err := ParseAge("30d")
+	require.NoError(t, err)
+	assert.Equal(t, 30*24*time.Hour, d)
+
+	d, err = ParseAge("72h")
+	require.NoError(t, err)
+	assert.Equal(t, 72*time.Hour, d)
+
+	for _, bad := range []string{"", "d", "-3d", "0d", "soon", "-1h"} {
+		_, err := ParseAge(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestIsAbandoned(t *testing.T) {
+	cutoff := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	old := cutoff.Add(-time.Hour)
+
+	assert.True(t, isAbandoned(workflow.TurnStatus{Phase: workflow.PhaseWaitingForInput, LastActivityAt: old}, cutoff))
+	assert.False(t, isAbandoned(workflow.TurnStatus{Phase: workflow.PhaseWaitingForInput, LastActivityAt: cutoff.Add(time.Hour)}, cutoff))
+	assert.False(t, isAbandoned(workflow.TurnStatus{Phase: workflow.PhaseApprovalPending, LastActivityAt: old}, cutoff),
+		"a session in the middle of a turn is not abandoned")
+	assert.False(t, isAbandoned(workflow.TurnStatus{Phase: workflow.PhaseWaitingForInput}, cutoff),
+		"unknown last activity is never abandoned")
+}
+
+func TestClosedSessionsQuery(t *testing.T) {
+	q := closedSessionsQuery([]string{"harness-abc"}, time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC))
+	assert.Contains(t, q, "CloseTime < '2026-01-10T00:00:00Z'")
+	assert.Contains(t, q, "ExecutionStatus != 'ContinuedAsNew'")
+	assert.Contains(t, q, "(WorkflowId STARTS_WITH 'harness-abc/')")
+}
+
+func TestRunningSessionsQuery_ScopedByHarness(t *testing.T) {
+	q := runningSessionsQuery([]string{"harness-a", "harness-b"})
+	assert.Contains(t, q, "(WorkflowId STARTS_WITH 'harness-a/' OR WorkflowId STARTS_WITH 'harness-b/')")
+	assert.Contains(t, q, "ExecutionStatus = 'Running'")
+}
+
+func TestPruneHarnessIDs(t *testing.T) {
+	t.Setenv("TCX_HARNESS_ID", "")
+	ids, err := pruneHarnessIDs(PruneOptions{Cwd: "/home/dev/app"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{harnessWorkflowID("/home/dev/app", "")}, ids, "defaults to the directory's harness")
+
+	ids, err = pruneHarnessIDs(PruneOptions{HarnessIDs: []string{"tcx-app"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tcx-app"}, ids)
+
+	_, err = pruneHarnessIDs(PruneOptions{HarnessIDs: []string{"x' OR WorkflowId != '"}})
+	assert.Error(t, err)
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "512 B", formatBytes(512))
+	assert.Equal(t, "1.5 KiB", formatBytes(1536))
+	assert.Equal(t, "2.0 MiB", formatBytes(2<<20))
+}
+
+func TestConfirm(t *testing.T) {
+	var out bytes.Buffer
+	assert.True(t, confirm(strings.NewReader("y\n"), &out, "Prune?"))
+	assert.Equal(t, "Prune? [y/N] ", out.String())
+	assert.False(t, confirm(strings.NewReader("\n"), &out, "Prune?"))
+	assert.False(t, confirm(strings.NewReader(""), &out, "Prune?"))
+}
+
+func TestPruneLocalArtifacts(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	codexHome := t.TempDir()
+
+	dir, err := scratch.Ensure("sess-old")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "big.bin"), make([]byte, 2048), 0o600))
+	_, err = scratch.Ensure("sess-keep")
+	require.NoError(t, err)
+
+	db, err := memories.OpenMemoryDB(filepath.Join(codexHome, "state.sqlite"))
+	require.NoError(t, err)
+	out := memories.Stage1Output{WorkflowID: "sess-old", SourceUpdatedAt: 1, RolloutSummary: "did things"}
+	require.NoError(t, db.UpsertStage1Output(out))
+	memRoot := filepath.Join(codexHome, "memories")
+	require.NoError(t, memories.EnsureLayout(memRoot))
+	require.NoError(t, memories.WriteRolloutSummaryForThread(memRoot, out))
+	require.NoError(t, db.Close())
+
+	report := pruneLocalArtifacts([]string{"sess-old", "sess-missing"}, codexHome, "")
+	assert.Empty(t, report.Warnings)
+	assert.Equal(t, 1, report.ScratchDirs)
+	assert.Equal(t, 1, report.Transcripts)
+	assert.Greater(t, report.Bytes, int64(2048))
+
+	_, err = os.Stat(scratch.Dir("sess-old"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(scratch.Dir("sess-keep"))
+	assert.NoError(t, err, "scratch dirs of other sessions are kept")
+	summaries, err := os.ReadDir(filepath.Join(memRoot, memories.RolloutSummariesSubdir))
+	require.NoError(t, err)
+	assert.Empty(t, summaries)
+}
+
+func TestPruneLocalArtifacts_RemovesHistoryArchive(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	codexHome := t.TempDir()
+
+	_, err := archive.Write(codexHome, "sess-old", 0, []models.ConversationItem{
+		{Type: models.ItemTypeUserMessage, Content: "old request"},
+	})
+	require.NoError(t, err)
+	_, err = archive.Write(codexHome, "sess-keep", 0, []models.ConversationItem{
+		{Type: models.ItemTypeUserMessage, Content: "kept request"},
+	})
+	require.NoError(t, err)
+
+	report := pruneLocalArtifacts([]string{"sess-old"}, codexHome, "")
+	assert.Empty(t, report.Warnings)
+	assert.Equal(t, 1, report.Transcripts)
+	assert.Positive(t, report.Bytes)
+
+	_, err = os.Stat(archive.Dir(codexHome, "sess-old"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(archive.Dir(codexHome, "sess-keep"))
+	assert.NoError(t, err)
+}
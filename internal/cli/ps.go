@@ -31,7 +31,7 @@ func formatExecSessionsDisplay(sessions []workflow.ExecSessionSummary) string {
 			status = fmt.Sprintf("exit(%d)", s.ExitCode)
 		}
 
-		started := s.StartedAt.Format(time.Kitchen)
+		started := displayTime(s.StartedAt).Format(time.Kitchen)
 		b.WriteString(fmt.Sprintf("  %-10s %-30s %-10s %s\n", s.ProcessID, cmd, status, started))
 	}
 
@@ -10,6 +10,7 @@ import (
 	"github.com/charmbracelet/glamour"
 	gansi "github.com/charmbracelet/glamour/ansi"
 	glamourstyles "github.com/charmbracelet/glamour/styles"
+	"github.com/mfateev/temporal-agent-harness/internal/mentions"
 	"github.com/mfateev/temporal-agent-harness/internal/models"
 	"github.com/mfateev/temporal-agent-harness/internal/workflow"
 	"golang.org/x/term"
@@ -22,6 +23,8 @@ type ItemRenderer struct {
 	noMarkdown bool
 	styles     Styles
 	mdRenderer *glamour.TermRenderer
+	markdown   MarkdownOptions
+	accessible bool // labeled plain-text blocks (see accessible.go)
 }
 
 // NewItemRenderer creates a renderer for conversation items.
@@ -51,6 +54,12 @@ func NewItemRenderer(width int, noColor, noMarkdown bool, styles Styles) *ItemRe
 	return r
 }
 
+// WithMarkdownOptions sets how fenced code blocks are rendered.
+func (r *ItemRenderer) WithMarkdownOptions(opts MarkdownOptions) *ItemRenderer {
+	r.markdown = opts
+	return r
+}
+
 // RenderItem renders a single conversation item as a string.
 // isResume controls whether user messages are shown (they are during resume).
 // Returns empty string if the item produces no visible output.
@@ -74,6 +83,10 @@ func (r *ItemRenderer) RenderItem(item models.ConversationItem, isResume bool) s
 		return r.RenderWebSearchCall(item)
 	case models.ItemTypeCompaction:
 		return r.RenderCompaction(item)
+	case models.ItemTypeTurnFailure:
+		return r.RenderPostMortem(item)
+	case models.ItemTypeNotice:
+		return r.RenderSystemMessage(item.Content)
 	case models.ItemTypeTurnComplete:
 		return ""
 	default:
@@ -83,10 +96,39 @@ func (r *ItemRenderer) RenderItem(item models.ConversationItem, isResume bool) s
 
 // RenderCompaction renders a compaction marker.
 func (r *ItemRenderer) RenderCompaction(item models.ConversationItem) string {
+	if r.accessible {
+		return "NOTE: Context compacted\n"
+	}
 	bullet := r.styles.SystemBullet.Render("●")
 	return bullet + " [Context compacted]\n"
 }
 
+// RenderPostMortem renders the summary of a turn that ended in error.
+func (r *ItemRenderer) RenderPostMortem(item models.ConversationItem) string {
+	pm := item.PostMortem
+	if pm == nil {
+		return ""
+	}
+	if r.accessible {
+		return renderAccessiblePostMortem(pm)
+	}
+	prefix := r.styles.OutputPrefix.Render("  └ ")
+	var b strings.Builder
+	b.WriteString("\n" + r.styles.OutputFailure.Render("✗ Turn failed ("+string(pm.ErrorClass)+")") + "\n")
+	b.WriteString(prefix + "Error: " + pm.Error + "\n")
+	if pm.Attempted != "" {
+		b.WriteString(prefix + r.styles.OutputDim.Render("Attempted: "+pm.Attempted) + "\n")
+	}
+	if pm.ToolCalls > 0 {
+		b.WriteString(prefix + r.styles.OutputDim.Render(fmt.Sprintf("Tool calls: %d (%d failed)", pm.ToolCalls, pm.FailedToolCalls)) + "\n")
+	}
+	if pm.LastSuccessfulStep != "" {
+		b.WriteString(prefix + r.styles.OutputDim.Render("Last successful step: "+pm.LastSuccessfulStep) + "\n")
+	}
+	b.WriteString(prefix + "Next: " + pm.SuggestedAction + "\n")
+	return b.String()
+}
+
 // RenderTurnSeparator renders a horizontal rule to visually separate turns.
 func (r *ItemRenderer) RenderTurnSeparator() string {
 	w := r.width
@@ -98,19 +140,34 @@ func (r *ItemRenderer) RenderTurnSeparator() string {
 
 // RenderSystemMessage renders a system-level message with a yellow bullet.
 func (r *ItemRenderer) RenderSystemMessage(text string) string {
+	if r.accessible {
+		return "NOTE: " + text + "\n"
+	}
 	bullet := r.styles.SystemBullet.Render("●")
 	return bullet + " " + text + "\n"
 }
 
 // RenderUserMessage renders a user message with a chevron prefix.
 // Skips internal messages like environment context that aren't user-visible.
+// @path mentions are highlighted, and their attached file context collapses
+// to a one-line summary.
 func (r *ItemRenderer) RenderUserMessage(item models.ConversationItem) string {
+	if r.accessible {
+		return renderAccessibleUserMessage(item.Content)
+	}
 	// Hide internal context messages from display
 	if strings.HasPrefix(item.Content, "<environment_context>") {
 		return ""
 	}
+	if strings.HasPrefix(item.Content, mentions.ContextPrefix) {
+		label, _ := mentions.Label(item.Content)
+		return r.styles.OutputPrefix.Render("  └ ") + r.styles.OutputDim.Render("Attached "+label) + "\n"
+	}
 	chevron := r.styles.UserChevron.Render("❯")
-	return chevron + " " + item.Content + "\n"
+	content := mentions.HighlightFileMentions(item.Content, func(s string) string {
+		return r.styles.FileMention.Render(s)
+	})
+	return chevron + " " + content + "\n"
 }
 
 // RenderAssistantMessage renders an assistant message with optional markdown.
@@ -119,9 +176,26 @@ func (r *ItemRenderer) RenderAssistantMessage(item models.ConversationItem) stri
 	if content == "" {
 		return ""
 	}
+	return r.renderAssistantContent(item, false)
+}
+
+// RenderExpandedMessage renders an assistant message with its code blocks
+// in full, for /expand.
+func (r *ItemRenderer) RenderExpandedMessage(item models.ConversationItem) string {
+	if item.Content == "" {
+		return ""
+	}
+	return r.renderAssistantContent(item, true)
+}
+
+func (r *ItemRenderer) renderAssistantContent(item models.ConversationItem, full bool) string {
+	content := item.Content
+	if r.accessible {
+		return "\nASSISTANT:\n" + strings.TrimSpace(content) + "\n"
+	}
 	bullet := r.styles.AssistantBullet.Render("●")
 	if r.mdRenderer != nil {
-		rendered, err := r.mdRenderer.Render(content)
+		rendered, err := r.renderMarkdown(content, item.Seq, full)
 		if err == nil {
 			return "\n" + bullet + " " + strings.TrimLeft(rendered, " \n")
 		}
@@ -133,6 +207,9 @@ func (r *ItemRenderer) RenderAssistantMessage(item models.ConversationItem) stri
 // Example: "● Ran echo hello"
 func (r *ItemRenderer) RenderFunctionCall(item models.ConversationItem) string {
 	verb, detail := formatToolCall(item.Name, item.Arguments)
+	if r.accessible {
+		return renderAccessibleCall(accessibleToolLabel(item.Name), verb, detail)
+	}
 	bullet := r.styles.ToolBullet.Render("●")
 	styledVerb := r.styles.ToolVerb.Render(verb)
 	if detail != "" {
@@ -147,6 +224,9 @@ func (r *ItemRenderer) RenderFunctionCallOutput(item models.ConversationItem) st
 	if item.Output == nil {
 		return ""
 	}
+	if r.accessible {
+		return renderAccessibleOutput(item)
+	}
 
 	isFailure := item.Output.Success != nil && !*item.Output.Success
 	content := strings.TrimRight(item.Output.Content, "\n")
@@ -183,6 +263,9 @@ func (r *ItemRenderer) RenderFunctionCallOutput(item models.ConversationItem) st
 // Maps to: codex-rs/tui/src/history_cell.rs WebSearchCell
 func (r *ItemRenderer) RenderWebSearchCall(item models.ConversationItem) string {
 	verb, detail := formatWebSearchCall(item.WebSearchAction, item.Content, item.WebSearchURL)
+	if r.accessible {
+		return renderAccessibleCall("web search", verb, detail)
+	}
 	bullet := r.styles.ToolBullet.Render("●")
 	styledVerb := r.styles.ToolVerb.Render(verb)
 	if detail != "" {
@@ -221,6 +304,10 @@ func formatWebSearchCall(action, content, url string) (verb, detail string) {
 // renderApprovalEntry writes a single tool entry (title + optional preview box + reason)
 // into the provided builder.
 func (r *ItemRenderer) renderApprovalEntry(b *strings.Builder, index int, info approvalInfo, reason string) {
+	if r.accessible {
+		renderAccessibleApprovalEntry(b, index, info, reason)
+		return
+	}
 	idx := r.styles.ApprovalIndex.Render(fmt.Sprintf("[%d]", index))
 	title := r.styles.ApprovalTool.Render(info.Title)
 	b.WriteString(fmt.Sprintf("  %s %s\n", idx, title))
@@ -238,6 +325,48 @@ func (r *ItemRenderer) renderApprovalEntry(b *strings.Builder, index int, info a
 	}
 }
 
+// renderApprovalList renders pending approvals, collapsing calls that share
+// a command template into one group entry listing each member's index.
+func (r *ItemRenderer) renderApprovalList(b *strings.Builder, approvals []workflow.PendingApproval) {
+	for _, g := range groupApprovals(approvals) {
+		if len(g.Indices) == 1 {
+			ap := approvals[g.Indices[0]]
+			info := formatApprovalInfo(ap.ToolName, ap.Arguments)
+			switch {
+			case ap.PreviewCommand != "":
+				info.Preview = approvalPreviewLines(ap)
+			case ap.ToolName == "apply_patch" && (ap.Preview != "" || ap.PreviewError != ""):
+				info.Preview = patchPreviewLines(ap)
+			}
+			r.renderApprovalEntry(b, g.Indices[0]+1, info, ap.Reason)
+			b.WriteString("\n")
+			continue
+		}
+		if r.accessible {
+			fmt.Fprintf(b, "Calls %s: %s\n", g.indexList(), g.Label)
+			for _, i := range g.Indices {
+				info := formatApprovalInfo(approvals[i].ToolName, approvals[i].Arguments)
+				fmt.Fprintf(b, "Call %d: %s\n", i+1, info.Title)
+			}
+			if reason := approvals[g.Indices[0]].Reason; reason != "" {
+				b.WriteString("Reason: " + reason + "\n")
+			}
+			b.WriteString("\n")
+			continue
+		}
+		idx := r.styles.ApprovalIndex.Render("[" + g.indexList() + "]")
+		b.WriteString(fmt.Sprintf("  %s %s\n", idx, r.styles.ApprovalTool.Render(g.Label)))
+		for _, i := range g.Indices {
+			info := formatApprovalInfo(approvals[i].ToolName, approvals[i].Arguments)
+			b.WriteString(fmt.Sprintf("      %s %s\n", r.styles.OutputDim.Render(fmt.Sprintf("%d.", i+1)), info.Title))
+		}
+		if reason := approvals[g.Indices[0]].Reason; reason != "" {
+			b.WriteString(fmt.Sprintf("      %s %s\n", r.styles.ApprovalReason.Render("Reason:"), reason))
+		}
+		b.WriteString("\n")
+	}
+}
+
 // styleDiffLine applies DiffAdd/DiffRemove/OutputDim styling based on line prefix.
 func (r *ItemRenderer) styleDiffLine(line string) string {
 	if len(line) > 0 {
@@ -251,15 +380,30 @@ func (r *ItemRenderer) styleDiffLine(line string) string {
 	return r.styles.OutputDim.Render(line)
 }
 
+// escalationHeader introduces calls that failed in the sandbox.
+func (r *ItemRenderer) escalationHeader() string {
+	if r.accessible {
+		return "ESCALATION NEEDED: these calls failed in the sandbox.\n"
+	}
+	return r.styles.EscalationHeader.Render("Sandbox failure — escalation needed:") + "\n\n"
+}
+
+// questionHeader introduces a request_user_input question.
+func (r *ItemRenderer) questionHeader() string {
+	if r.accessible {
+		return "QUESTION:\n"
+	}
+	return r.styles.EscalationHeader.Render("The assistant has a question for you:") + "\n\n"
+}
+
 // RenderApprovalPrompt renders the approval prompt for pending tool calls.
 func (r *ItemRenderer) RenderApprovalPrompt(approvals []workflow.PendingApproval) string {
 	var b strings.Builder
 	b.WriteString("\n")
-	for i, ap := range approvals {
-		info := formatApprovalInfo(ap.ToolName, ap.Arguments)
-		r.renderApprovalEntry(&b, i+1, info, ap.Reason)
-		b.WriteString("\n")
+	if r.accessible {
+		b.WriteString("APPROVAL NEEDED:\n")
 	}
+	r.renderApprovalList(&b, approvals)
 	if len(approvals) > 1 {
 		b.WriteString("Allow? [y]es / [n]o / [a]lways / 1,2 (select by index): ")
 	} else {
@@ -272,7 +416,7 @@ func (r *ItemRenderer) RenderApprovalPrompt(approvals []workflow.PendingApproval
 func (r *ItemRenderer) RenderEscalationPrompt(escalations []workflow.EscalationRequest) string {
 	var b strings.Builder
 	b.WriteString("\n")
-	b.WriteString(r.styles.EscalationHeader.Render("Sandbox failure — escalation needed:") + "\n\n")
+	b.WriteString(r.escalationHeader())
 	for i, esc := range escalations {
 		info := formatApprovalInfo(esc.ToolName, esc.Arguments)
 		r.renderApprovalEntry(&b, i+1, info, "")
@@ -291,10 +435,17 @@ func (r *ItemRenderer) RenderEscalationPrompt(escalations []workflow.EscalationR
 }
 
 // RenderUserInputQuestionPrompt renders the question prompt for a request_user_input call.
+// A free-text question (ask_user) renders as a single inline line.
 func (r *ItemRenderer) RenderUserInputQuestionPrompt(req *workflow.PendingUserInputRequest) string {
+	if isFreeTextRequest(req) {
+		if r.accessible {
+			return "\nQUESTION: " + req.Questions[0].Question + "\n"
+		}
+		return "\n" + r.styles.EscalationHeader.Render("The assistant asks:") + " " + req.Questions[0].Question + "\n"
+	}
 	var b strings.Builder
 	b.WriteString("\n")
-	b.WriteString(r.styles.EscalationHeader.Render("The assistant has a question for you:") + "\n\n")
+	b.WriteString(r.questionHeader())
 
 	for i, q := range req.Questions {
 		if len(req.Questions) > 1 {
@@ -322,11 +473,10 @@ func (r *ItemRenderer) RenderUserInputQuestionPrompt(req *workflow.PendingUserIn
 func (r *ItemRenderer) RenderApprovalContext(approvals []workflow.PendingApproval) string {
 	var b strings.Builder
 	b.WriteString("\n")
-	for i, ap := range approvals {
-		info := formatApprovalInfo(ap.ToolName, ap.Arguments)
-		r.renderApprovalEntry(&b, i+1, info, ap.Reason)
-		b.WriteString("\n")
+	if r.accessible {
+		b.WriteString("APPROVAL NEEDED:\n")
 	}
+	r.renderApprovalList(&b, approvals)
 	return b.String()
 }
 
@@ -335,7 +485,7 @@ func (r *ItemRenderer) RenderApprovalContext(approvals []workflow.PendingApprova
 func (r *ItemRenderer) RenderEscalationContext(escalations []workflow.EscalationRequest) string {
 	var b strings.Builder
 	b.WriteString("\n")
-	b.WriteString(r.styles.EscalationHeader.Render("Sandbox failure — escalation needed:") + "\n\n")
+	b.WriteString(r.escalationHeader())
 	for i, esc := range escalations {
 		info := formatApprovalInfo(esc.ToolName, esc.Arguments)
 		r.renderApprovalEntry(&b, i+1, info, "")
@@ -357,7 +507,7 @@ func (r *ItemRenderer) RenderEscalationContext(escalations []workflow.Escalation
 func (r *ItemRenderer) RenderUserInputQuestionContext(req *workflow.PendingUserInputRequest) string {
 	var b strings.Builder
 	b.WriteString("\n")
-	b.WriteString(r.styles.EscalationHeader.Render("The assistant has a question for you:") + "\n\n")
+	b.WriteString(r.questionHeader())
 
 	for i, q := range req.Questions {
 		if len(req.Questions) > 1 {
@@ -376,6 +526,9 @@ func (r *ItemRenderer) RenderPlan(plan *workflow.PlanState) string {
 	if plan == nil || len(plan.Steps) == 0 {
 		return ""
 	}
+	if r.accessible {
+		return renderAccessiblePlan(plan)
+	}
 
 	var b strings.Builder
 	bullet := r.styles.PlanBullet.Render("●")
@@ -428,11 +581,66 @@ func PhaseMessage(phase workflow.TurnPhase, toolsInFlight []string) string {
 		return "Waiting for your answer..."
 	case workflow.PhaseCompacting:
 		return "Compacting context..."
+	case workflow.PhaseCleaningUp:
+		return "Cleaning up..."
 	default:
 		return "Working..."
 	}
 }
 
+// focusTargetWidth caps the file or command shown in the status area.
+const focusTargetWidth = 60
+
+// StatusMessage returns the status area message for a turn status: the
+// phase, with the agent's current focus when the workflow reports one
+// (the file or command being worked on and the plan step in progress).
+func StatusMessage(status workflow.TurnStatus) string {
+	msg := PhaseMessage(status.Phase, status.ToolsInFlight)
+	f := status.Focus
+	if f == nil {
+		return msg
+	}
+	if status.Phase == workflow.PhaseToolExecuting && f.Tool != "" {
+		msg = focusMessage(f)
+	}
+	if f.PlanStep > 0 {
+		step := fmt.Sprintf("step %d", f.PlanStep)
+		if status.Plan != nil && len(status.Plan.Steps) >= f.PlanStep {
+			step += fmt.Sprintf("/%d", len(status.Plan.Steps))
+		}
+		if f.PlanStepText != "" {
+			step += ": " + truncateString(f.PlanStepText, focusTargetWidth)
+		}
+		msg += " · " + step
+	}
+	return msg
+}
+
+// focusMessage describes the running tool call of f, e.g.
+// "Editing internal/cli/model.go..." or "Running `go test ./...`...".
+func focusMessage(f *workflow.Focus) string {
+	target := truncateString(f.Target, focusTargetWidth)
+	var msg string
+	switch {
+	case target == "":
+		msg = fmt.Sprintf("Running %s...", f.Tool)
+	case f.Action == workflow.FocusRead:
+		msg = fmt.Sprintf("Reading %s...", target)
+	case f.Action == workflow.FocusEdit:
+		msg = fmt.Sprintf("Editing %s...", target)
+	case f.Action == workflow.FocusRun:
+		msg = fmt.Sprintf("Running `%s`...", target)
+	case f.Action == workflow.FocusSearch:
+		msg = fmt.Sprintf("Searching for %s...", target)
+	default:
+		msg = fmt.Sprintf("Running %s (%s)...", f.Tool, target)
+	}
+	if f.OtherTools > 0 {
+		msg += fmt.Sprintf(" (+%d more)", f.OtherTools)
+	}
+	return msg
+}
+
 // formatToolCall parses the tool name and JSON arguments, returning a
 // human-readable verb and detail string matching the Codex output style.
 //
@@ -496,8 +704,18 @@ func formatToolCall(name, argsJSON string) (verb, detail string) {
 		return "Searched", ""
 	case "request_user_input":
 		return "Asked", "user a question"
+	case "ask_user":
+		if q, ok := args["question"].(string); ok {
+			return "Asked", truncateString(q, 120)
+		}
+		return "Asked", "user a question"
 	case "update_plan":
 		return "Updated", "plan"
+	case "read_artifact":
+		if id, ok := args["id"].(string); ok {
+			return "Re-read", "output " + id
+		}
+		return "Re-read", "an earlier output"
 	default:
 		detail := name + "(" + truncateString(argsJSON, 80) + ")"
 		return "Ran", detail
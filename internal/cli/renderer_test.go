@@ -132,6 +132,17 @@ func TestItemRenderer_UserMessageRenderedOnResume(t *testing.T) {
 	assert.Contains(t, result, "Hello from resume")
 }
 
+func TestItemRenderer_FileContextCollapsedOnResume(t *testing.T) {
+	r := newTestRenderer()
+	result := r.RenderItem(models.ConversationItem{
+		Type:    models.ItemTypeUserMessage,
+		Content: "<file_context path=\"main.go\" lines=\"10-12\">\n    10\tfunc main() {\n</file_context>",
+	}, true)
+
+	assert.Contains(t, result, "Attached main.go:10-12")
+	assert.NotContains(t, result, "func main()")
+}
+
 func TestItemRenderer_RenderStatusLine(t *testing.T) {
 	r := newTestRenderer()
 	result := r.RenderStatusLine("gpt-4o-mini", 1234, 3)
@@ -273,6 +284,7 @@ func TestPhaseMessage(t *testing.T) {
 		{"tool_executing", []string{"shell"}, "Running shell..."},
 		{"tool_executing", nil, "Running tool..."},
 		{"waiting_for_input", nil, "Working..."},
+		{"cleaning_up", nil, "Cleaning up..."},
 	}
 
 	for _, tt := range tests {
@@ -281,6 +293,34 @@ func TestPhaseMessage(t *testing.T) {
 	}
 }
 
+func TestStatusMessage_Focus(t *testing.T) {
+	plan := &workflow.PlanState{Steps: []workflow.PlanStep{{Step: "Read"}, {Step: "Fix the race"}, {Step: "Test"}}}
+	tests := []struct {
+		name     string
+		status   workflow.TurnStatus
+		expected string
+	}{
+		{"no focus", workflow.TurnStatus{Phase: workflow.PhaseToolExecuting, ToolsInFlight: []string{"shell"}}, "Running shell..."},
+		{"edit", workflow.TurnStatus{Phase: workflow.PhaseToolExecuting,
+			Focus: &workflow.Focus{Tool: "apply_patch", Action: workflow.FocusEdit, Target: "pkg/x.go", OtherTools: 2}},
+			"Editing pkg/x.go... (+2 more)"},
+		{"command with plan step", workflow.TurnStatus{Phase: workflow.PhaseToolExecuting, Plan: plan,
+			Focus: &workflow.Focus{Tool: "shell", Action: workflow.FocusRun, Target: "go test ./...", PlanStep: 2, PlanStepText: "Fix the race"}},
+			"Running `go test ./...`... · step 2/3: Fix the race"},
+		{"unknown tool", workflow.TurnStatus{Phase: workflow.PhaseToolExecuting,
+			Focus: &workflow.Focus{Tool: "mcp__db__query", Target: "select 1"}},
+			"Running mcp__db__query (select 1)..."},
+		{"plan step while thinking", workflow.TurnStatus{Phase: workflow.PhaseLLMCalling, Plan: plan,
+			Focus: &workflow.Focus{PlanStep: 1, PlanStepText: "Read"}},
+			"Thinking... · step 1/3: Read"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, StatusMessage(tt.status))
+		})
+	}
+}
+
 func TestItemRenderer_RenderApprovalPrompt(t *testing.T) {
 	r := newTestRenderer()
 	result := r.RenderApprovalPrompt([]workflow.PendingApproval{
@@ -643,6 +683,24 @@ func TestFormatToolCall_RequestUserInput(t *testing.T) {
 	assert.Equal(t, "user a question", detail)
 }
 
+func TestFormatToolCall_AskUser(t *testing.T) {
+	verb, detail := formatToolCall("ask_user", `{"question": "Which port should the server use?"}`)
+	assert.Equal(t, "Asked", verb)
+	assert.Equal(t, "Which port should the server use?", detail)
+}
+
+func TestItemRenderer_RenderUserInputQuestionPrompt_FreeText(t *testing.T) {
+	r := newTestRenderer()
+	result := r.RenderUserInputQuestionPrompt(&workflow.PendingUserInputRequest{
+		CallID:    "call-1",
+		Questions: []workflow.RequestUserInputQuestion{{ID: "answer", Question: "Which port?"}},
+	})
+
+	assert.Contains(t, result, "Which port?")
+	assert.NotContains(t, result, "option number")
+	assert.Equal(t, 2, strings.Count(result, "\n"), "rendered as one inline line")
+}
+
 func TestPhaseMessage_UserInputPending(t *testing.T) {
 	result := PhaseMessage(workflow.PhaseUserInputPending, nil)
 	assert.Equal(t, "Waiting for your answer...", result)
@@ -861,3 +919,72 @@ func TestFormatWebSearchCall(t *testing.T) {
 		})
 	}
 }
+
+func TestItemRenderer_RenderPostMortem(t *testing.T) {
+	r := newTestRenderer()
+	result := r.RenderItem(models.ConversationItem{
+		Type: models.ItemTypeTurnFailure,
+		PostMortem: &models.PostMortem{
+			ErrorClass:         models.TurnErrorIterationLimit,
+			Error:              "reached maximum of 20 iterations",
+			Attempted:          "fix the build",
+			LastSuccessfulStep: "shell_command: go build ./...",
+			ToolCalls:          20,
+			FailedToolCalls:    3,
+			SuggestedAction:    "Reply continue.",
+		},
+	}, false)
+
+	assert.Contains(t, result, "Turn failed (iteration_limit)")
+	assert.Contains(t, result, "Error: reached maximum of 20 iterations")
+	assert.Contains(t, result, "Attempted: fix the build")
+	assert.Contains(t, result, "Tool calls: 20 (3 failed)")
+	assert.Contains(t, result, "Last successful step: shell_command: go build ./...")
+	assert.Contains(t, result, "Next: Reply continue.")
+
+	assert.Empty(t, r.RenderItem(models.ConversationItem{Type: models.ItemTypeTurnFailure}, false))
+}
+
+func TestItemRenderer_RenderApprovalContext_Grouped(t *testing.T) {
+	r := newTestRenderer()
+	result := r.RenderApprovalContext([]workflow.PendingApproval{
+		{CallID: "c1", ToolName: "shell_command", Arguments: `{"command":"go test ./a"}`, Group: "go test <path>", Reason: "not allow-listed"},
+		{CallID: "c2", ToolName: "shell_command", Arguments: `{"command":"go test ./b"}`, Group: "go test <path>", Reason: "not allow-listed"},
+		{CallID: "c3", ToolName: "write_file", Arguments: `{"path":"x.go"}`},
+	})
+	assert.Contains(t, result, "[1,2] Run `go test <path>` (2 calls)")
+	assert.Contains(t, result, "1. Shell: go test ./a")
+	assert.Contains(t, result, "2. Shell: go test ./b")
+	assert.Equal(t, 1, strings.Count(result, "Reason:"), "group reason shown once")
+	assert.Contains(t, result, "[3] Write file: x.go")
+}
+
+
+func TestItemRenderer_RenderApprovalContext_GitPreview(t *testing.T) {
+	r := newTestRenderer()
+	result := r.RenderApprovalContext([]workflow.PendingApproval{{
+		CallID:         "c1",
+		ToolName:       "shell_command",
+		Arguments:      `{"command":"git push origin main"}`,
+		PreviewCommand: "git push --dry-run origin main",
+		Preview:        "To origin\n   1a2b..3c4d  main -> main",
+	}})
+	assert.Contains(t, result, "[1] Shell: git push origin main")
+	assert.Contains(t, result, "│ $ git push --dry-run origin main")
+	assert.Contains(t, result, "│    1a2b..3c4d  main -> main")
+}
+
+func TestItemRenderer_RenderApprovalContext_PatchPreview(t *testing.T) {
+	r := newTestRenderer()
+	result := r.RenderApprovalContext([]workflow.PendingApproval{{
+		CallID:       "c1",
+		ToolName:     "apply_patch",
+		Arguments:    `{"input":"*** Begin Patch\n*** Update File: a.go\n@@\n-old\n+new\n*** End Patch"}`,
+		Preview:      "--- a/a.go\n+++ b/a.go\n@@ -3,1 +3,1 @@\n-old\n+new",
+		PreviewError: "patch does not apply cleanly: Failed to find expected lines in b.go",
+	}})
+	assert.Contains(t, result, "[1] Update(a.go)")
+	assert.Contains(t, result, "│ ! patch does not apply cleanly: Failed to find expected lines in b.go")
+	assert.Contains(t, result, "│ @@ -3,1 +3,1 @@")
+	assert.Contains(t, result, "│ --- a/a.go")
+}
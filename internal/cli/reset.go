@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/google/uuid"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+
+	"github.com/mfateev/temporal-agent-harness/internal/temporalclient"
+)
+
+// ResetOptions configures `tcx admin reset`.
+type ResetOptions struct {
+	Connection temporalclient.ConnectionConfig
+	WorkflowID string
+	// EventID is the workflow task event to reset to. 0 resets a stuck
+	// workflow to the last workflow task completed before its failure.
+	EventID int64
+	Reason  string
+
+	Out io.Writer
+}
+
+// RunAdminReset implements `tcx admin reset`: it resets a session's
+// workflow to a workflow task event, starting a new run that replays the
+// history up to that point. It is the way out for a session whose workflow
+// task keeps failing (see stuck.go).
+func RunAdminReset(opts ResetOptions) error {
+	if opts.Out == nil {
+		opts.Out = os.Stdout
+	}
+	if opts.WorkflowID == "" {
+		return errors.New("a workflow ID is required")
+	}
+	clientOpts, err := temporalclient.LoadClientOptionsFromConfig(opts.Connection)
+	if err != nil {
+		return fmt.Errorf("failed to load Temporal client config: %w", err)
+	}
+	namespace := clientOpts.Namespace
+	if namespace == "" {
+		namespace = client.DefaultNamespace
+	}
+	c, err := temporalclient.DialWithRetry(clientOpts, opts.Connection.DialRetries, opts.Connection.DialRetryBackoff)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Temporal: %w", err)
+	}
+	defer c.Close()
+
+	ctx := context.Background()
+	eventID := opts.EventID
+	if eventID == 0 {
+		stuck, err := NewPoller(c, opts.WorkflowID, 0).CheckWorkflowTask(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to check %s: %w", opts.WorkflowID, err)
+		}
+		if stuck == nil {
+			return fmt.Errorf("%s is not stuck; pass --event-id to reset it anyway", opts.WorkflowID)
+		}
+		if stuck.ResetEventID == 0 {
+			return fmt.Errorf("%s has no completed workflow task to reset to", opts.WorkflowID)
+		}
+		fmt.Fprintf(opts.Out, "%s: %s\n", opts.WorkflowID, stuck.Error())
+		eventID = stuck.ResetEventID
+	}
+
+	reason := opts.Reason
+	if reason == "" {
+		reason = "tcx admin reset"
+	}
+	resp, err := c.ResetWorkflowExecution(ctx, &workflowservice.ResetWorkflowExecutionRequest{
+		Namespace:                 namespace,
+		WorkflowExecution:         &commonpb.WorkflowExecution{WorkflowId: opts.WorkflowID},
+		Reason:                    reason,
+		WorkflowTaskFinishEventId: eventID,
+		RequestId:                 uuid.NewString(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reset %s: %w", opts.WorkflowID, err)
+	}
+	fmt.Fprintf(opts.Out, "Reset %s to event %d; new run %s. Resume it with tcx.\n", opts.WorkflowID, eventID, resp.GetRunId())
+	return nil
+}
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mfateev/temporal-agent-harness/internal/command_safety"
+)
+
+// FormatSafetyExplanation formats a command safety classification for
+// `tcx safety explain`.
+func FormatSafetyExplanation(command string, c command_safety.Classification) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Command:        %s\n", command))
+
+	classification := "requires approval"
+	switch {
+	case c.ForcedPrompt:
+		classification = "always requires approval (unsafe override)"
+	case c.Safe:
+		classification = "safe (auto-approved in unless-trusted mode)"
+	}
+	b.WriteString(fmt.Sprintf("Classification: %s\n", classification))
+	b.WriteString(fmt.Sprintf("Reason:         %s\n", c.Reason))
+	if c.Dangerous {
+		b.WriteString("Warning:        matches the destructive-command heuristic\n")
+	}
+
+	if len(c.Commands) > 1 {
+		b.WriteString("Parsed commands:\n")
+		for _, cmd := range c.Commands {
+			b.WriteString(fmt.Sprintf("  %s\n", strings.Join(cmd, " ")))
+		}
+	}
+	return b.String()
+}
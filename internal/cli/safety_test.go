@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mfateev/temporal-agent-harness/internal/command_safety"
+)
+
+func TestFormatSafetyExplanation_Safe(t *testing.T) {
+	c := command_safety.NewClassifier([]string{"make lint"}, nil)
+	out := FormatSafetyExplanation("make lint", c.Classify([]string{"make", "lint"}))
+
+	assert.Contains(t, out, "Command:        make lint")
+	assert.Contains(t, out, "safe (auto-approved")
+	assert.Contains(t, out, `matches safe pattern "make lint"`)
+	assert.NotContains(t, out, "Parsed commands")
+}
+
+func TestFormatSafetyExplanation_UnsafeOverride(t *testing.T) {
+	c := command_safety.NewClassifier(nil, []string{"terraform apply"})
+	out := FormatSafetyExplanation("terraform apply",
+		c.Classify([]string{"bash", "-lc", "terraform init && terraform apply"}))
+
+	assert.Contains(t, out, "always requires approval")
+	assert.Contains(t, out, `matches unsafe override "terraform apply"`)
+	assert.Contains(t, out, "Parsed commands:\n  terraform init\n  terraform apply\n")
+}
+
+func TestFormatSafetyExplanation_Dangerous(t *testing.T) {
+	out := FormatSafetyExplanation("rm -rf /", command_safety.NewClassifier(nil, nil).Classify([]string{"rm", "-rf", "/"}))
+
+	assert.Contains(t, out, "Classification: requires approval")
+	assert.Contains(t, out, "destructive-command heuristic")
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mfateev/temporal-agent-harness/internal/workflow"
+)
+
+// formatToolStatsDisplay formats per-tool call statistics as a table for display.
+func formatToolStatsDisplay(stats []workflow.ToolStatSummary) string {
+	if len(stats) == 0 {
+		return "No tool calls yet.\n"
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("Tool Stats (%d tools)\n", len(stats)))
+	b.WriteString("────────────────────\n")
+	b.WriteString(fmt.Sprintf("  %-24s %6s %8s %10s %10s %10s\n", "Tool", "Calls", "Failed", "Avg", "Total", "Output"))
+	b.WriteString(fmt.Sprintf("  %-24s %6s %8s %10s %10s %10s\n", "────", "─────", "──────", "───", "─────", "──────"))
+
+	for _, s := range stats {
+		name := s.ToolName
+		if len(name) > 24 {
+			name = name[:21] + "..."
+		}
+		b.WriteString(fmt.Sprintf("  %-24s %6d %7.0f%% %10s %10s %10s\n",
+			name,
+			s.Calls,
+			s.FailureRate*100,
+			formatStatDuration(s.AvgDurationMs),
+			formatStatDuration(s.TotalDurationMs),
+			formatStatBytes(s.OutputBytes),
+		))
+	}
+
+	return b.String()
+}
+
+// formatStatDuration renders milliseconds compactly (e.g. "850ms", "1.2s", "3m4s").
+func formatStatDuration(ms int64) string {
+	d := time.Duration(ms) * time.Millisecond
+	switch {
+	case d < time.Second:
+		return fmt.Sprintf("%dms", ms)
+	case d < time.Minute:
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	default:
+		return d.Truncate(time.Second).String()
+	}
+}
+
+// formatStatBytes renders a byte count with a binary unit suffix.
+func formatStatBytes(n int64) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%dB", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%.1fKB", float64(n)/1024)
+	default:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1024*1024))
+	}
+}
@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mfateev/temporal-agent-harness/internal/workflow"
+)
+
+func TestFormatToolStatsDisplay_Empty(t *testing.T) {
+	assert.Equal(t, "No tool calls yet.\n", formatToolStatsDisplay(nil))
+}
+
+func TestFormatToolStatsDisplay_WithStats(t *testing.T) {
+	result := formatToolStatsDisplay([]workflow.ToolStatSummary{
+		{ToolName: "grep_files", Calls: 4, Failures: 4, FailureRate: 1, AvgDurationMs: 12, TotalDurationMs: 48, OutputBytes: 80},
+		{ToolName: "shell_command", Calls: 10, Failures: 1, FailureRate: 0.1, AvgDurationMs: 2500, TotalDurationMs: 185000, OutputBytes: 3 * 1024 * 1024},
+	})
+
+	assert.Contains(t, result, "Tool Stats (2 tools)")
+	assert.Contains(t, result, "grep_files")
+	assert.Contains(t, result, "100%")
+	assert.Contains(t, result, "10%")
+	assert.Contains(t, result, "2.5s")
+	assert.Contains(t, result, "3m5s")
+	assert.Contains(t, result, "3.0MB")
+	assert.Contains(t, result, "80B")
+}
+
+func TestFormatStatDuration(t *testing.T) {
+	assert.Equal(t, "850ms", formatStatDuration(850))
+	assert.Equal(t, "1.2s", formatStatDuration(1200))
+	assert.Equal(t, "2m0s", formatStatDuration(120400))
+}
@@ -18,8 +18,12 @@ func (m *Model) formatStatusDisplay() string {
 	if m.reasoningEffort != "" {
 		b.WriteString(fmt.Sprintf("  Reasoning:       %s\n", m.reasoningEffort))
 	}
-	b.WriteString(fmt.Sprintf("  Approval mode:   %s\n", m.config.Permissions.ApprovalMode))
-	b.WriteString(fmt.Sprintf("  Sandbox:         %s\n", m.config.Permissions.SandboxMode))
+	if m.config.Ask {
+		b.WriteString("  Session type:    ask (no tools)\n")
+	} else {
+		b.WriteString(fmt.Sprintf("  Approval mode:   %s\n", m.config.Permissions.ApprovalMode))
+		b.WriteString(fmt.Sprintf("  Sandbox:         %s\n", m.config.Permissions.SandboxMode))
+	}
 	b.WriteString(fmt.Sprintf("  Working dir:     %s\n", m.config.Cwd))
 
 	if m.sessionName != "" {
@@ -34,12 +38,25 @@ func (m *Model) formatStatusDisplay() string {
 		b.WriteString(fmt.Sprintf(" (%d cached)", m.totalCachedTokens))
 	}
 	b.WriteString("\n")
+	if m.suggestionTokens > 0 {
+		b.WriteString(fmt.Sprintf("  Suggestions:     %d tokens\n", m.suggestionTokens))
+	}
 
 	if m.contextWindowPct > 0 {
 		b.WriteString(fmt.Sprintf("  Context window:  %d%% remaining\n", m.contextWindowPct))
 	}
 
 	b.WriteString(fmt.Sprintf("  Turn count:      %d\n", m.turnCount))
+	if t := m.lastTurnLatency; t != nil {
+		b.WriteString(fmt.Sprintf("  Last turn:       %s (first response %s, LLM %s, tools %s, approvals %s)\n",
+			formatStatDuration(t.TotalMs), formatStatDuration(t.FirstResponseMs), formatStatDuration(t.LLMMs),
+			formatStatDuration(t.ToolMs), formatStatDuration(t.ApprovalWaitMs)))
+	}
+	if l := m.latency; l != nil && l.Turns > 1 {
+		b.WriteString(fmt.Sprintf("  Avg turn:        %s (first response avg %s, max %s)\n",
+			formatStatDuration(l.AvgTurnMs()), formatStatDuration(l.AvgFirstResponseMs()),
+			formatStatDuration(l.MaxFirstResponseMs)))
+	}
 
 	if m.workerVersion != "" {
 		b.WriteString(fmt.Sprintf("  Worker version:  %s\n", m.workerVersion))
@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/workflow"
 )
 
 func TestFormatStatusDisplay_Basic(t *testing.T) {
@@ -56,6 +57,22 @@ func TestFormatStatusDisplay_CachedTokensHidden(t *testing.T) {
 	assert.False(t, strings.Contains(result, "cached"))
 }
 
+func TestFormatStatusDisplay_SuggestionTokensShown(t *testing.T) {
+	m := &Model{
+		modelName:        "gpt-4o",
+		provider:         "openai",
+		totalTokens:      1000,
+		suggestionTokens: 42,
+		config:           Config{Permissions: models.Permissions{}},
+	}
+
+	result := m.formatStatusDisplay()
+	assert.Contains(t, result, "Suggestions:     42 tokens")
+
+	m.suggestionTokens = 0
+	assert.NotContains(t, m.formatStatusDisplay(), "Suggestions:")
+}
+
 func TestFormatStatusDisplay_PlannerActive(t *testing.T) {
 	m := &Model{
 		modelName:     "gpt-4o",
@@ -68,3 +85,30 @@ func TestFormatStatusDisplay_PlannerActive(t *testing.T) {
 	assert.Contains(t, result, "Plan mode")
 	assert.Contains(t, result, "active")
 }
+
+func TestFormatStatusDisplay_AskSession(t *testing.T) {
+	m := &Model{
+		modelName: "gpt-4o",
+		provider:  "openai",
+		config:    Config{Ask: true, Permissions: models.Permissions{ApprovalMode: models.ApprovalNever}},
+	}
+
+	result := m.formatStatusDisplay()
+	assert.Contains(t, result, "Session type:    ask (no tools)")
+	assert.NotContains(t, result, "Approval mode")
+	assert.Equal(t, models.SessionTypeAsk, m.config.sessionType())
+}
+
+func TestFormatStatusDisplay_Latency(t *testing.T) {
+	m := &Model{
+		config: Config{Permissions: models.Permissions{}},
+		lastTurnLatency: &workflow.TurnLatency{
+			TotalMs: 12_300, FirstResponseMs: 1_200, LLMMs: 8_000, ToolMs: 3_100, ApprovalWaitMs: 900,
+		},
+		latency: &workflow.LatencyStats{Turns: 2, RespondedTurns: 2, FirstResponseMs: 3_000, MaxFirstResponseMs: 1_800, TotalMs: 20_000},
+	}
+
+	result := m.formatStatusDisplay()
+	assert.Contains(t, result, "Last turn:       12.3s (first response 1.2s, LLM 8.0s, tools 3.1s, approvals 900ms)")
+	assert.Contains(t, result, "Avg turn:        10.0s (first response avg 1.5s, max 1.8s)")
+}
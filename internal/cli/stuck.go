@@ -0,0 +1,190 @@
+package cli
+
+// A workflow whose workflow task keeps failing (a payload it cannot decode,
+// non-deterministic code after a worker upgrade) makes no progress, and
+// every Update the CLI sends waits for it. While a session is open, the CLI
+// checks the workflow's pending workflow task every workflowHealthInterval;
+// once it has failed stuckWorkflowTaskAttempts times, the cause is shown
+// with the way out: fix or roll back the worker, or reset the session to its
+// last good point with `tcx admin reset`.
+//
+// NOTE: Temporal-specific addition (not in Codex Rust).
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	enumspb "go.temporal.io/api/enums/v1"
+	historypb "go.temporal.io/api/history/v1"
+)
+
+// stuckWorkflowTaskAttempts is the attempt of the pending workflow task at
+// which a workflow counts as stuck. Earlier attempts may be transient (a
+// worker restarting mid-task).
+const stuckWorkflowTaskAttempts = 3
+
+// workflowHealthInterval is how often an open session's workflow task is
+// checked.
+const workflowHealthInterval = 20 * time.Second
+
+// StuckWorkflow describes a workflow whose workflow task keeps failing.
+type StuckWorkflow struct {
+	// Attempt is the attempt of the pending workflow task.
+	Attempt int32
+	// Cause is the failure cause in words, e.g. "non-deterministic
+	// workflow code"; Message is the worker's error.
+	Cause   string
+	Message string
+	// ResetEventID is the last workflow task completed before the failure:
+	// the last good point to reset to. 0 if there is none.
+	ResetEventID int64
+}
+
+// Error returns the one-line description shown to the user.
+func (s *StuckWorkflow) Error() string {
+	msg := "workflow is stuck: " + s.Cause
+	if s.Message != "" {
+		msg += ": " + s.Message
+	}
+	return msg
+}
+
+// CheckWorkflowTask reports whether the workflow's pending workflow task
+// has failed at least stuckWorkflowTaskAttempts times. Returns nil when the
+// workflow is healthy or closed.
+func (p *Poller) CheckWorkflowTask(ctx context.Context) (*StuckWorkflow, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+	desc, err := p.client.DescribeWorkflowExecution(queryCtx, p.workflowID, "")
+	if err != nil {
+		return nil, err
+	}
+	task := desc.GetPendingWorkflowTask()
+	if task == nil || task.GetAttempt() < stuckWorkflowTaskAttempts {
+		return nil, nil
+	}
+
+	// Only the first failure of a retried workflow task is written to the
+	// history, so its last WorkflowTaskFailed event has the cause.
+	var events []*historypb.HistoryEvent
+	iter := p.client.GetWorkflowHistory(ctx, p.workflowID, desc.GetWorkflowExecutionInfo().GetExecution().GetRunId(),
+		false, enumspb.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT)
+	for iter.HasNext() {
+		event, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return stuckFromHistory(task.GetAttempt(), events), nil
+}
+
+// stuckFromHistory describes a stuck workflow from its history: the cause
+// of the last workflow task failure and the last workflow task completed
+// before it.
+func stuckFromHistory(attempt int32, events []*historypb.HistoryEvent) *StuckWorkflow {
+	stuck := &StuckWorkflow{Attempt: attempt, Cause: "the worker fails every workflow task"}
+	var lastCompleted int64
+	for _, event := range events {
+		switch event.GetEventType() {
+		case enumspb.EVENT_TYPE_WORKFLOW_TASK_COMPLETED:
+			lastCompleted = event.GetEventId()
+		case enumspb.EVENT_TYPE_WORKFLOW_TASK_FAILED:
+			attrs := event.GetWorkflowTaskFailedEventAttributes()
+			stuck.Cause = workflowTaskFailedCause(attrs.GetCause())
+			stuck.Message = attrs.GetFailure().GetMessage()
+			stuck.ResetEventID = lastCompleted
+		}
+	}
+	if stuck.ResetEventID == 0 {
+		stuck.ResetEventID = lastCompleted
+	}
+	return stuck
+}
+
+// workflowTaskFailedCause names a workflow task failure cause in words.
+func workflowTaskFailedCause(cause enumspb.WorkflowTaskFailedCause) string {
+	switch cause {
+	case enumspb.WORKFLOW_TASK_FAILED_CAUSE_NON_DETERMINISTIC_ERROR:
+		return "non-deterministic workflow code"
+	case enumspb.WORKFLOW_TASK_FAILED_CAUSE_WORKFLOW_WORKER_UNHANDLED_FAILURE:
+		return "the workflow code failed"
+	case enumspb.WORKFLOW_TASK_FAILED_CAUSE_UNSPECIFIED:
+		return "the worker fails every workflow task"
+	}
+	// e.g. BadScheduleActivityAttributes → "bad schedule activity attributes"
+	var b strings.Builder
+	for i, r := range cause.String() {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// formatStuckWorkflow is the error and remediation shown for a stuck
+// workflow.
+func formatStuckWorkflow(workflowID string, stuck *StuckWorkflow) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Error: %s\n", stuck.Error())
+	fmt.Fprintf(&b, "The worker has failed this session's workflow task %d times, so the session cannot make progress.\n", stuck.Attempt)
+	b.WriteString("Fix or roll back the worker and restart it; the session then continues on its own.\n")
+	if stuck.ResetEventID > 0 {
+		b.WriteString("Or reset the session to its last good point (the work after it is replayed or lost):\n")
+		fmt.Fprintf(&b, "  tcx admin reset %s\n", workflowID)
+	}
+	return b.String()
+}
+
+// workflowHealthTickMsg triggers a check of the session's workflow task.
+type workflowHealthTickMsg struct{}
+
+func workflowHealthTick() tea.Cmd {
+	return tea.Tick(workflowHealthInterval, func(time.Time) tea.Msg { return workflowHealthTickMsg{} })
+}
+
+// WorkflowHealthMsg is the result of a workflow task check.
+type WorkflowHealthMsg struct {
+	WorkflowID string
+	Stuck      *StuckWorkflow
+	Err        error
+}
+
+// checkWorkflowHealthCmd checks the session's workflow task in the
+// background.
+func (m *Model) checkWorkflowHealthCmd() tea.Cmd {
+	if m.client == nil || m.workflowID == "" {
+		return nil
+	}
+	poller := NewPoller(m.client, m.workflowID, 0)
+	workflowID := m.workflowID
+	return func() tea.Msg {
+		stuck, err := poller.CheckWorkflowTask(context.Background())
+		return WorkflowHealthMsg{WorkflowID: workflowID, Stuck: stuck, Err: err}
+	}
+}
+
+// handleWorkflowHealth reports a workflow that became stuck, once, and
+// notes when it recovers. Failed checks are ignored: the watcher reports
+// connection problems.
+func (m *Model) handleWorkflowHealth(msg WorkflowHealthMsg) {
+	if msg.Err != nil || msg.WorkflowID != m.workflowID {
+		return
+	}
+	switch {
+	case msg.Stuck != nil && !m.workflowStuck:
+		m.workflowStuck = true
+		m.appendToViewport(formatStuckWorkflow(m.workflowID, msg.Stuck))
+	case msg.Stuck == nil && m.workflowStuck:
+		m.workflowStuck = false
+		m.appendToViewport("The workflow is making progress again.\n")
+	}
+}
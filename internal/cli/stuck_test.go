@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	enumspb "go.temporal.io/api/enums/v1"
+	failurepb "go.temporal.io/api/failure/v1"
+	historypb "go.temporal.io/api/history/v1"
+)
+
+func wftEvent(id int64, eventType enumspb.EventType) *historypb.HistoryEvent {
+	return &historypb.HistoryEvent{EventId: id, EventType: eventType}
+}
+
+func TestStuckFromHistory(t *testing.T) {
+	failed := wftEvent(9, enumspb.EVENT_TYPE_WORKFLOW_TASK_FAILED)
+	failed.Attributes = &historypb.HistoryEvent_WorkflowTaskFailedEventAttributes{
+		WorkflowTaskFailedEventAttributes: &historypb.WorkflowTaskFailedEventAttributes{
+			Cause:   enumspb.WORKFLOW_TASK_FAILED_CAUSE_NON_DETERMINISTIC_ERROR,
+			Failure: &failurepb.Failure{Message: "unknown command ScheduleActivityTask"},
+		},
+	}
+	events := []*historypb.HistoryEvent{
+		wftEvent(1, enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_STARTED),
+		wftEvent(4, enumspb.EVENT_TYPE_WORKFLOW_TASK_COMPLETED),
+		wftEvent(7, enumspb.EVENT_TYPE_WORKFLOW_TASK_STARTED),
+		failed,
+	}
+
+	stuck := stuckFromHistory(5, events)
+
+	assert.Equal(t, int32(5), stuck.Attempt)
+	assert.Equal(t, int64(4), stuck.ResetEventID)
+	assert.Equal(t, "workflow is stuck: non-deterministic workflow code: unknown command ScheduleActivityTask", stuck.Error())
+}
+
+func TestWorkflowTaskFailedCause(t *testing.T) {
+	assert.Equal(t, "the workflow code failed",
+		workflowTaskFailedCause(enumspb.WORKFLOW_TASK_FAILED_CAUSE_WORKFLOW_WORKER_UNHANDLED_FAILURE))
+	assert.Equal(t, "bad schedule activity attributes",
+		workflowTaskFailedCause(enumspb.WORKFLOW_TASK_FAILED_CAUSE_BAD_SCHEDULE_ACTIVITY_ATTRIBUTES))
+}
+
+func TestHandleWorkflowHealth_ReportsOnceAndRecovery(t *testing.T) {
+	m := NewModel(Config{Model: "gpt-4o-mini", OutputMode: OutputPlain}, nil)
+	var out strings.Builder
+	m.plainOut = &out
+	m.workflowID = "wf-1"
+
+	stuck := &StuckWorkflow{Attempt: 3, Cause: "the workflow code failed", Message: "cannot decode payload", ResetEventID: 12}
+	m.handleWorkflowHealth(WorkflowHealthMsg{WorkflowID: "wf-1", Stuck: stuck})
+	m.handleWorkflowHealth(WorkflowHealthMsg{WorkflowID: "wf-1", Stuck: stuck})
+	m.handleWorkflowHealth(WorkflowHealthMsg{WorkflowID: "wf-1", Err: errors.New("unavailable")})
+
+	assert.Equal(t, 1, strings.Count(out.String(), "Error: workflow is stuck: the workflow code failed: cannot decode payload"))
+	assert.Contains(t, out.String(), "tcx admin reset wf-1")
+
+	out.Reset()
+	m.handleWorkflowHealth(WorkflowHealthMsg{WorkflowID: "wf-1"})
+	assert.Equal(t, "The workflow is making progress again.\n", out.String())
+}
@@ -64,6 +64,8 @@ type Styles struct {
 	DiffAdd lipgloss.Style
 	// Diff removed line (red)
 	DiffRemove lipgloss.Style
+	// @path file mention in user input
+	FileMention lipgloss.Style
 }
 
 // DefaultStyles returns styles with colors enabled.
@@ -99,6 +101,7 @@ func DefaultStyles() Styles {
 		PlanPending:      lipgloss.NewStyle().Faint(true),
 		DiffAdd:          lipgloss.NewStyle().Foreground(lipgloss.Color("2")), // green
 		DiffRemove:       lipgloss.NewStyle().Foreground(lipgloss.Color("1")), // red
+		FileMention:      lipgloss.NewStyle().Foreground(lipgloss.Color("6")), // cyan
 	}
 }
 
@@ -135,5 +138,6 @@ func NoColorStyles() Styles {
 		PlanPending:      lipgloss.NewStyle(),
 		DiffAdd:          lipgloss.NewStyle(),
 		DiffRemove:       lipgloss.NewStyle(),
+		FileMention:      lipgloss.NewStyle(),
 	}
 }
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// displayLocation is the zone CLI timestamps are rendered in.
+var displayLocation = time.Local
+
+// SetDisplayTimezone renders CLI timestamps (session picker, ps, prune
+// listings, /why artifacts) in the named IANA zone. Empty or unknown names
+// keep the local zone.
+func SetDisplayTimezone(name string) {
+	if name == "" || models.ValidateTimezone(name) != nil {
+		displayLocation = time.Local
+		return
+	}
+	displayLocation = models.LoadTimezone(name)
+}
+
+// displayTime returns t in the display timezone.
+func displayTime(t time.Time) time.Time {
+	return t.In(displayLocation)
+}
+
+// DetectTimezone returns the IANA name of the host's timezone from $TZ or
+// the /etc/localtime link, or "" when it cannot be determined.
+func DetectTimezone() string {
+	if tz, ok := os.LookupEnv("TZ"); ok {
+		return zoneName(strings.TrimPrefix(tz, ":"))
+	}
+	if link, err := os.Readlink("/etc/localtime"); err == nil {
+		return zoneName(link)
+	}
+	return ""
+}
+
+// zoneName extracts a known IANA name from a zone name or a path into a
+// zoneinfo directory, e.g. /usr/share/zoneinfo/Europe/Berlin.
+func zoneName(s string) string {
+	if i := strings.Index(s, "zoneinfo/"); i >= 0 {
+		s = s[i+len("zoneinfo/"):]
+	}
+	if s == "" || filepath.IsAbs(s) || models.ValidateTimezone(s) != nil {
+		return ""
+	}
+	return s
+}
+
+// DetectLocale returns the BCP 47 tag of the host's locale from $LC_ALL,
+// $LC_TIME or $LANG (en_US.UTF-8 becomes en-US), or "" for the C/POSIX
+// locale or when unset.
+func DetectLocale() string {
+	for _, name := range []string{"LC_ALL", "LC_TIME", "LANG"} {
+		v := os.Getenv(name)
+		if v == "" {
+			continue
+		}
+		if i := strings.IndexAny(v, ".@"); i >= 0 {
+			v = v[:i]
+		}
+		if v == "C" || v == "POSIX" {
+			return ""
+		}
+		return strings.ReplaceAll(v, "_", "-")
+	}
+	return ""
+}
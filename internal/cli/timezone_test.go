@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisplayTime_ConfiguredTimezone(t *testing.T) {
+	t.Cleanup(func() { SetDisplayTimezone("") })
+	p := testPromptSnapshot()
+
+	SetDisplayTimezone("Asia/Tokyo")
+	assert.Contains(t, formatPromptSnapshot(p), "2026-01-02T12:04:05+09:00")
+	assert.Equal(t, "12:04PM", displayTime(p.Time).Format(time.Kitchen))
+
+	SetDisplayTimezone("Nowhere/Special")
+	assert.Equal(t, time.Local, displayTime(p.Time).Location(), "unknown zones keep the local zone")
+}
+
+func TestDetectTimezone(t *testing.T) {
+	t.Setenv("TZ", "America/Chicago")
+	assert.Equal(t, "America/Chicago", DetectTimezone())
+
+	t.Setenv("TZ", ":/usr/share/zoneinfo/Europe/Berlin")
+	assert.Equal(t, "Europe/Berlin", DetectTimezone())
+
+	t.Setenv("TZ", "Not/AZone")
+	assert.Equal(t, "", DetectTimezone())
+}
+
+func TestDetectLocale(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_TIME", "")
+	t.Setenv("LANG", "de_DE.UTF-8")
+	assert.Equal(t, "de-DE", DetectLocale())
+
+	t.Setenv("LC_TIME", "en_GB")
+	assert.Equal(t, "en-GB", DetectLocale(), "LC_TIME wins over LANG")
+
+	t.Setenv("LC_ALL", "C.UTF-8")
+	assert.Equal(t, "", DetectLocale())
+}
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mfateev/temporal-agent-harness/internal/workflow"
+)
+
+// todoUsage is shown when a /todo command cannot be parsed.
+const todoUsage = "Usage: /todo | /todo add <text> | /todo done <id> | /todo rm <id>\n"
+
+// parseTodoCommand parses a /todo input line into an update_todo request.
+// Plain "/todo" lists the items.
+func parseTodoCommand(line string) (workflow.UpdateTodoRequest, error) {
+	rest := strings.TrimSpace(strings.TrimPrefix(line, "/todo"))
+	if rest == "" || rest == "list" {
+		return workflow.UpdateTodoRequest{Action: workflow.TodoActionList}, nil
+	}
+
+	verb, arg, _ := strings.Cut(rest, " ")
+	arg = strings.TrimSpace(arg)
+	switch verb {
+	case "add":
+		if arg == "" {
+			return workflow.UpdateTodoRequest{}, fmt.Errorf("missing TODO text")
+		}
+		return workflow.UpdateTodoRequest{Action: workflow.TodoActionAdd, Text: arg}, nil
+	case "done", "rm", "remove":
+		id, err := strconv.Atoi(arg)
+		if err != nil || id <= 0 {
+			return workflow.UpdateTodoRequest{}, fmt.Errorf("invalid TODO id %q", arg)
+		}
+		action := workflow.TodoActionDone
+		if verb != "done" {
+			action = workflow.TodoActionRemove
+		}
+		return workflow.UpdateTodoRequest{Action: action, ID: id}, nil
+	default:
+		return workflow.UpdateTodoRequest{}, fmt.Errorf("unknown /todo subcommand %q", verb)
+	}
+}
+
+// formatTodoDisplay formats the session TODO list for display.
+// Completed items are shown checked so the user can see recent progress.
+func formatTodoDisplay(todos []workflow.TodoItem) string {
+	if len(todos) == 0 {
+		return "No TODOs.\n"
+	}
+
+	open := 0
+	for _, t := range todos {
+		if !t.Done {
+			open++
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("TODOs (%d open)\n", open))
+	b.WriteString("───────────────\n")
+	for _, t := range todos {
+		mark := " "
+		if t.Done {
+			mark = "x"
+		}
+		line := fmt.Sprintf("  [%s] %d. %s", mark, t.ID, t.Text)
+		if t.Source == "model" {
+			line += " (model)"
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+// hasOpenTodos reports whether any item in todos is not done.
+func hasOpenTodos(todos []workflow.TodoItem) bool {
+	for _, t := range todos {
+		if !t.Done {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/workflow"
+)
+
+func TestParseTodoCommand(t *testing.T) {
+	tests := []struct {
+		line string
+		want workflow.UpdateTodoRequest
+	}{
+		{"/todo", workflow.UpdateTodoRequest{Action: workflow.TodoActionList}},
+		{"/todo list", workflow.UpdateTodoRequest{Action: workflow.TodoActionList}},
+		{"/todo add  update the docs ", workflow.UpdateTodoRequest{Action: workflow.TodoActionAdd, Text: "update the docs"}},
+		{"/todo done 3", workflow.UpdateTodoRequest{Action: workflow.TodoActionDone, ID: 3}},
+		{"/todo rm 2", workflow.UpdateTodoRequest{Action: workflow.TodoActionRemove, ID: 2}},
+	}
+	for _, tt := range tests {
+		got, err := parseTodoCommand(tt.line)
+		require.NoError(t, err, tt.line)
+		assert.Equal(t, tt.want, got, tt.line)
+	}
+}
+
+func TestParseTodoCommand_Errors(t *testing.T) {
+	for _, line := range []string{"/todo add", "/todo done x", "/todo rm 0", "/todo frob"} {
+		_, err := parseTodoCommand(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func TestFormatTodoDisplay(t *testing.T) {
+	assert.Equal(t, "No TODOs.\n", formatTodoDisplay(nil))
+
+	out := formatTodoDisplay([]workflow.TodoItem{
+		{ID: 1, Text: "write tests", Done: true, Source: "user"},
+		{ID: 2, Text: "check lint", Source: "model"},
+	})
+	assert.Contains(t, out, "TODOs (1 open)")
+	assert.Contains(t, out, "[x] 1. write tests")
+	assert.Contains(t, out, "[ ] 2. check lint (model)")
+}
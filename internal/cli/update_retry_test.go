@@ -0,0 +1,21 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.temporal.io/api/serviceerror"
+)
+
+func TestRetryableUpdateError(t *testing.T) {
+	assert.True(t, retryableUpdateError(context.DeadlineExceeded))
+	assert.True(t, retryableUpdateError(fmt.Errorf("update: %w", context.DeadlineExceeded)))
+	assert.True(t, retryableUpdateError(serviceerror.NewUnavailable("frontend restarting")))
+	assert.True(t, retryableUpdateError(serviceerror.NewDeadlineExceeded("timeout")))
+
+	assert.False(t, retryableUpdateError(errors.New("turn already active")))
+	assert.False(t, retryableUpdateError(serviceerror.NewNotFound("workflow not found")))
+}
@@ -7,6 +7,12 @@ import (
 	"github.com/mfateev/temporal-agent-harness/internal/workflow"
 )
 
+// isFreeTextRequest reports whether req is a single question without options
+// (an ask_user call), answered by typing text rather than picking an option.
+func isFreeTextRequest(req *workflow.PendingUserInputRequest) bool {
+	return req != nil && len(req.Questions) == 1 && len(req.Questions[0].Options) == 0
+}
+
 // UserInputSelectionToResponse maps a selector index to a UserInputQuestionResponse.
 // Returns nil if "Other" is selected (last option), meaning fall back to textarea.
 // Only handles single-question requests.
@@ -44,6 +50,15 @@ func HandleUserInputQuestionInput(line string, req *workflow.PendingUserInputReq
 	if len(req.Questions) == 1 {
 		q := req.Questions[0]
 
+		// Free-text question: the line is the answer, even if numeric
+		if len(q.Options) == 0 {
+			return &workflow.UserInputQuestionResponse{
+				Answers: map[string]workflow.UserInputQuestionAnswer{
+					q.ID: {Answers: []string{line}},
+				},
+			}
+		}
+
 		// Try parsing as a number (1-based index)
 		var idx int
 		if n, err := fmt.Sscanf(line, "%d", &idx); err == nil && n == 1 {
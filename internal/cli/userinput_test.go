@@ -134,3 +134,25 @@ func TestHandleUserInputQuestion_MultiQuestionFreeform(t *testing.T) {
 	assert.Equal(t, []string{"custom lib"}, resp.Answers["q1"].Answers)
 	assert.Equal(t, []string{"custom lang"}, resp.Answers["q2"].Answers)
 }
+
+func freeTextReq() *workflow.PendingUserInputRequest {
+	return &workflow.PendingUserInputRequest{
+		CallID:    "call-1",
+		Questions: []workflow.RequestUserInputQuestion{{ID: "answer", Question: "Which port?"}},
+	}
+}
+
+func TestHandleUserInputQuestion_FreeTextKeepsNumbers(t *testing.T) {
+	resp := HandleUserInputQuestionInput(" 8080 ", freeTextReq())
+	require.NotNil(t, resp)
+	assert.Equal(t, []string{"8080"}, resp.Answers["answer"].Answers)
+
+	assert.Nil(t, HandleUserInputQuestionInput("  ", freeTextReq()))
+}
+
+func TestIsFreeTextRequest(t *testing.T) {
+	assert.True(t, isFreeTextRequest(freeTextReq()))
+	assert.False(t, isFreeTextRequest(singleQuestionReq()))
+	assert.False(t, isFreeTextRequest(multiQuestionReq()))
+	assert.False(t, isFreeTextRequest(nil))
+}
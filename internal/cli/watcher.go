@@ -57,7 +57,7 @@ func (w *Watcher) Watch(ctx context.Context, sinceSeq int, sincePhase workflow.T
 	updateHandle, err := w.client.UpdateWorkflow(callCtx, client.UpdateWorkflowOptions{
 		WorkflowID:   w.workflowID,
 		UpdateName:   workflow.UpdateGetStateUpdate,
-		Args:         []interface{}{workflow.StateUpdateRequest{SinceSeq: sinceSeq, SincePhase: sincePhase}},
+		Args:         []interface{}{workflow.StateUpdateRequest{SinceSeq: sinceSeq, SincePhase: sincePhase, Version: workflow.ProtocolVersion}},
 		WaitForStage: client.WorkflowUpdateStageCompleted,
 	})
 	if err != nil {
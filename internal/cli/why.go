@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/workflow"
+)
+
+// formatPromptSnapshot renders the prompt behind the last LLM response as
+// plain text for the /why command: instructions, tools, history and the
+// response, each item numbered as the model saw it.
+func formatPromptSnapshot(p workflow.PromptSnapshot) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Prompt for %s, iteration %d (%s/%s, %s)\n",
+		p.TurnID, p.Iteration, p.Provider, p.Model, displayTime(p.Time).Format(time.RFC3339))
+	if p.RequestHash != "" {
+		fmt.Fprintf(&b, "Request hash: %s\n", p.RequestHash)
+	}
+
+	writeWhySection(&b, "Base instructions", p.BaseInstructions)
+	writeWhySection(&b, "Developer instructions", p.DeveloperInstructions)
+	writeWhySection(&b, "User instructions", p.UserInstructions)
+
+	fmt.Fprintf(&b, "\n=== Tools (%d) ===\n", len(p.ToolSpecs))
+	for _, spec := range p.ToolSpecs {
+		fmt.Fprintf(&b, "- %s: %s\n", spec.Name, strings.TrimSpace(spec.Description))
+		var params interface{} = spec.Parameters
+		if spec.RawJSONSchema != nil {
+			params = spec.RawJSONSchema
+		}
+		if data, err := json.Marshal(params); err == nil && string(data) != "null" {
+			fmt.Fprintf(&b, "  parameters: %s\n", data)
+		}
+	}
+
+	fmt.Fprintf(&b, "\n=== History (%d items) ===\n", len(p.History))
+	if p.SentFrom > 0 {
+		fmt.Fprintf(&b, "Items [0]-[%d] were already held by the provider (previous response %s); only the rest was sent.\n",
+			p.SentFrom-1, p.PreviousResponseID)
+	}
+	for i, item := range p.History {
+		writeWhyItem(&b, fmt.Sprintf("[%d]", i), item)
+	}
+
+	title := "Response"
+	if p.FinishReason != "" {
+		title += " (finish: " + string(p.FinishReason) + ")"
+	}
+	fmt.Fprintf(&b, "\n=== %s ===\n", title)
+	for i, item := range p.Response {
+		writeWhyItem(&b, fmt.Sprintf("[r%d]", i), item)
+	}
+	return b.String()
+}
+
+// writeWhySection writes a titled block, skipping empty text.
+func writeWhySection(b *strings.Builder, title, text string) {
+	if text == "" {
+		return
+	}
+	fmt.Fprintf(b, "\n=== %s ===\n%s\n", title, strings.TrimRight(text, "\n"))
+}
+
+// writeWhyItem writes one conversation item with its full content.
+func writeWhyItem(b *strings.Builder, label string, item models.ConversationItem) {
+	header := label + " " + string(item.Type)
+	if item.Name != "" {
+		header += " " + item.Name
+	}
+	if item.CallID != "" {
+		header += " (" + item.CallID + ")"
+	}
+	if item.TurnID != "" {
+		header += " [" + item.TurnID + "]"
+	}
+	b.WriteString(header + "\n")
+
+	var body string
+	switch {
+	case item.Output != nil:
+		body = item.Output.Content
+	case item.Arguments != "":
+		body = item.Arguments
+	default:
+		body = item.Content
+	}
+	if body == "" {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimRight(body, "\n"), "\n") {
+		b.WriteString("    " + line + "\n")
+	}
+}
+
+// writeWhyArtifact saves a rendered prompt to a file in the temp directory
+// and returns its path, so it can be paged, diffed or shared.
+func writeWhyArtifact(workflowID string, p workflow.PromptSnapshot, text string) (string, error) {
+	name := fmt.Sprintf("why-%s-%s-%d.txt", sanitizeIDComponent(workflowID), sanitizeIDComponent(p.TurnID), p.Iteration)
+	path := filepath.Join(os.TempDir(), name)
+	if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// whyPagerCmd opens path in $PAGER (default less). Returns nil if no pager
+// is available, in which case the caller only reports the path.
+func whyPagerCmd(path string) tea.Cmd {
+	pager := strings.Fields(os.Getenv("PAGER"))
+	if len(pager) == 0 {
+		pager = []string{"less"}
+	}
+	bin, err := exec.LookPath(pager[0])
+	if err != nil {
+		return nil
+	}
+	cmd := exec.Command(bin, append(pager[1:], path)...)
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return WhyPagerClosedMsg{Path: path, Err: err}
+	})
+}
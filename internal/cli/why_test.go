@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/tools"
+	"github.com/mfateev/temporal-agent-harness/internal/workflow"
+)
+
+func testPromptSnapshot() workflow.PromptSnapshot {
+	return workflow.PromptSnapshot{
+		TurnID:                "turn-3",
+		Iteration:             2,
+		Time:                  time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Provider:              "anthropic",
+		Model:                 "claude-sonnet-4",
+		BaseInstructions:      "You are a coding agent.",
+		DeveloperInstructions: "<todo_reminder>\n- fix tests\n</todo_reminder>",
+		ToolSpecs: []tools.ToolSpec{{
+			Name:        "shell_command",
+			Description: "Run a command.",
+			Parameters:  []tools.ToolParameter{{Name: "command", Type: "string", Required: true}},
+		}},
+		History: []models.ConversationItem{
+			{Type: models.ItemTypeUserMessage, Content: "fix it", TurnID: "turn-3"},
+			{Type: models.ItemTypeFunctionCall, CallID: "call_1", Name: "shell_command", Arguments: `{"command":"go test"}`},
+			{Type: models.ItemTypeFunctionCallOutput, CallID: "call_1", Output: &models.FunctionCallOutputPayload{Content: "FAIL\nok"}},
+		},
+		SentFrom:           2,
+		PreviousResponseID: "resp_9",
+		Response:           []models.ConversationItem{{Type: models.ItemTypeAssistantMessage, Content: "Deleting the test."}},
+		FinishReason:       models.FinishReasonStop,
+	}
+}
+
+func TestFormatPromptSnapshot(t *testing.T) {
+	SetDisplayTimezone("UTC")
+	t.Cleanup(func() { SetDisplayTimezone("") })
+	text := formatPromptSnapshot(testPromptSnapshot())
+
+	assert.Contains(t, text, "Prompt for turn-3, iteration 2 (anthropic/claude-sonnet-4, 2026-01-02T03:04:05Z)")
+	assert.Contains(t, text, "=== Base instructions ===\nYou are a coding agent.\n")
+	assert.Contains(t, text, "=== Developer instructions ===\n<todo_reminder>")
+	assert.NotContains(t, text, "User instructions")
+	assert.Contains(t, text, "=== Tools (1) ===\n- shell_command: Run a command.\n  parameters: [")
+	assert.Contains(t, text, "Items [0]-[1] were already held by the provider (previous response resp_9)")
+	assert.Contains(t, text, "[1] function_call shell_command (call_1)\n    {\"command\":\"go test\"}\n")
+	assert.Contains(t, text, "[2] function_call_output (call_1)\n    FAIL\n    ok\n")
+	assert.Contains(t, text, "=== Response (finish: stop) ===\n[r0] assistant_message\n    Deleting the test.\n")
+}
+
+func TestWriteWhyArtifact(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	p := testPromptSnapshot()
+
+	path, err := writeWhyArtifact("codex/my repo", p, "prompt text")
+	require.NoError(t, err)
+	assert.Contains(t, path, "why-codex-my-repo-turn-3-2.txt")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "prompt text", string(data))
+}
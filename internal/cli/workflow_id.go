@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	enums "go.temporal.io/api/enums/v1"
+)
+
+// DefaultWorkflowIDTemplate is the harness workflow ID template used when
+// none is configured. {hash} keeps one harness per working directory.
+const DefaultWorkflowIDTemplate = "harness-{hash}"
+
+// WorkflowIDOptions controls how the CLI names and (re)starts the harness
+// workflow.
+type WorkflowIDOptions struct {
+	// Template for the harness workflow ID. Supported placeholders:
+	//   {hash}    — short hash of the working directory
+	//   {project} — basename of the working directory
+	//   {user}    — current OS user name
+	//   {host}    — host name
+	//   {date}    — current date (YYYYMMDD, UTC)
+	// Empty means DefaultWorkflowIDTemplate.
+	Template string
+
+	// ReusePolicy applies when a *closed* harness with the same ID exists.
+	// Unspecified means allow-duplicate-failed-only.
+	ReusePolicy enums.WorkflowIdReusePolicy
+
+	// ConflictPolicy applies when a *running* harness with the same ID exists.
+	// Unspecified means use-existing (re-attach).
+	ConflictPolicy enums.WorkflowIdConflictPolicy
+}
+
+// resolvedReusePolicy returns the reuse policy with the default applied.
+func (o WorkflowIDOptions) resolvedReusePolicy() enums.WorkflowIdReusePolicy {
+	if o.ReusePolicy == enums.WORKFLOW_ID_REUSE_POLICY_UNSPECIFIED {
+		return enums.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE_FAILED_ONLY
+	}
+	return o.ReusePolicy
+}
+
+// resolvedConflictPolicy returns the conflict policy with the default applied.
+func (o WorkflowIDOptions) resolvedConflictPolicy() enums.WorkflowIdConflictPolicy {
+	if o.ConflictPolicy == enums.WORKFLOW_ID_CONFLICT_POLICY_UNSPECIFIED {
+		return enums.WORKFLOW_ID_CONFLICT_POLICY_USE_EXISTING
+	}
+	return o.ConflictPolicy
+}
+
+// ParseWorkflowIDReusePolicy parses a --workflow-id-reuse-policy value.
+func ParseWorkflowIDReusePolicy(s string) (enums.WorkflowIdReusePolicy, error) {
+	switch s {
+	case "":
+		return enums.WORKFLOW_ID_REUSE_POLICY_UNSPECIFIED, nil
+	case "allow-duplicate":
+		return enums.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE, nil
+	case "allow-duplicate-failed-only":
+		return enums.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE_FAILED_ONLY, nil
+	case "reject-duplicate":
+		return enums.WORKFLOW_ID_REUSE_POLICY_REJECT_DUPLICATE, nil
+	default:
+		return enums.WORKFLOW_ID_REUSE_POLICY_UNSPECIFIED, fmt.Errorf(
+			"invalid workflow ID reuse policy %q (want allow-duplicate, allow-duplicate-failed-only or reject-duplicate)", s)
+	}
+}
+
+// ParseWorkflowIDConflictPolicy parses a --workflow-id-conflict-policy value.
+func ParseWorkflowIDConflictPolicy(s string) (enums.WorkflowIdConflictPolicy, error) {
+	switch s {
+	case "":
+		return enums.WORKFLOW_ID_CONFLICT_POLICY_UNSPECIFIED, nil
+	case "use-existing":
+		return enums.WORKFLOW_ID_CONFLICT_POLICY_USE_EXISTING, nil
+	case "fail":
+		return enums.WORKFLOW_ID_CONFLICT_POLICY_FAIL, nil
+	case "terminate-existing":
+		return enums.WORKFLOW_ID_CONFLICT_POLICY_TERMINATE_EXISTING, nil
+	default:
+		return enums.WORKFLOW_ID_CONFLICT_POLICY_UNSPECIFIED, fmt.Errorf(
+			"invalid workflow ID conflict policy %q (want use-existing, fail or terminate-existing)", s)
+	}
+}
+
+// harnessWorkflowID returns the harness workflow ID for a working directory,
+// expanding the configured template. If TCX_HARNESS_ID is set, it is used
+// directly (enables tests to predict the workflow ID for monitoring).
+func harnessWorkflowID(cwd, template string) string {
+	if id := os.Getenv("TCX_HARNESS_ID"); id != "" {
+		return id
+	}
+	return expandWorkflowIDTemplate(template, cwd, time.Now())
+}
+
+// invalidIDChars matches characters not allowed in a harness ID component.
+// Session workflow IDs are built as "<harness>/<session>" and listed with a
+// STARTS_WITH visibility query, so slashes and quotes must not leak in.
+var invalidIDChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// expandWorkflowIDTemplate substitutes the supported placeholders in template.
+func expandWorkflowIDTemplate(template, cwd string, now time.Time) string {
+	if template == "" {
+		template = DefaultWorkflowIDTemplate
+	}
+
+	h := sha256.Sum256([]byte(cwd))
+	username := "unknown"
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		username = u.Username
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+
+	r := strings.NewReplacer(
+		"{hash}", fmt.Sprintf("%x", h[:8]),
+		"{project}", sanitizeIDComponent(filepath.Base(cwd)),
+		"{user}", sanitizeIDComponent(username),
+		"{host}", sanitizeIDComponent(host),
+		"{date}", now.UTC().Format("20060102"),
+	)
+	return sanitizeIDComponent(r.Replace(template))
+}
+
+// sanitizeIDComponent replaces unsupported characters with '-'.
+func sanitizeIDComponent(s string) string {
+	s = invalidIDChars.ReplaceAllString(s, "-")
+	s = strings.Trim(s, "-")
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
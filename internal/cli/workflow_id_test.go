@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	enums "go.temporal.io/api/enums/v1"
+)
+
+func TestExpandWorkflowIDTemplate_Default(t *testing.T) {
+	id := expandWorkflowIDTemplate("", "/home/dev/my-project", time.Now())
+	assert.Regexp(t, `^harness-[0-9a-f]{16}$`, id)
+	assert.Equal(t, id, expandWorkflowIDTemplate(DefaultWorkflowIDTemplate, "/home/dev/my-project", time.Now()),
+		"default template must be stable per cwd")
+	assert.NotEqual(t, id, expandWorkflowIDTemplate("", "/home/dev/other", time.Now()))
+}
+
+func TestExpandWorkflowIDTemplate_Placeholders(t *testing.T) {
+	now := time.Date(2026, 3, 4, 23, 0, 0, 0, time.UTC)
+	id := expandWorkflowIDTemplate("tcx-{project}-{date}", "/home/dev/My Project", now)
+	assert.Equal(t, "tcx-My-Project-20260304", id)
+
+	id = expandWorkflowIDTemplate("{user}@{host}", "/tmp", now)
+	assert.NotContains(t, id, "{")
+	assert.NotContains(t, id, "@", "unsupported characters are replaced")
+}
+
+func TestExpandWorkflowIDTemplate_StripsSeparators(t *testing.T) {
+	id := expandWorkflowIDTemplate("team/'{project}'", "/src/app", time.Now())
+	assert.Equal(t, "team-app", id)
+}
+
+func TestHarnessWorkflowID_EnvOverride(t *testing.T) {
+	t.Setenv("TCX_HARNESS_ID", "harness-test")
+	assert.Equal(t, "harness-test", harnessWorkflowID("/any", "{project}"))
+}
+
+func TestParseWorkflowIDPolicies(t *testing.T) {
+	reuse, err := ParseWorkflowIDReusePolicy("reject-duplicate")
+	require.NoError(t, err)
+	assert.Equal(t, enums.WORKFLOW_ID_REUSE_POLICY_REJECT_DUPLICATE, reuse)
+	_, err = ParseWorkflowIDReusePolicy("sometimes")
+	assert.Error(t, err)
+
+	conflict, err := ParseWorkflowIDConflictPolicy("fail")
+	require.NoError(t, err)
+	assert.Equal(t, enums.WORKFLOW_ID_CONFLICT_POLICY_FAIL, conflict)
+	_, err = ParseWorkflowIDConflictPolicy("merge")
+	assert.Error(t, err)
+
+	var opts WorkflowIDOptions
+	assert.Equal(t, enums.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE_FAILED_ONLY, opts.resolvedReusePolicy())
+	assert.Equal(t, enums.WORKFLOW_ID_CONFLICT_POLICY_USE_EXISTING, opts.resolvedConflictPolicy())
+}
+
+func TestModel_WorkflowIDCollision_OffersResume(t *testing.T) {
+	m := newTestModel()
+	m.state = StateStartup
+
+	entries := []SessionListEntry{
+		{WorkflowID: "harness-abc/sess-1/main", StartTime: time.Now(), Status: "running"},
+	}
+	result, _ := m.Update(WorkflowIDCollisionMsg{WorkflowID: "harness-abc", Entries: entries})
+	rm := result.(*Model)
+	assert.Equal(t, StateSessionPicker, rm.state)
+	assert.True(t, rm.resumingSession)
+	assert.NotNil(t, rm.selector)
+	assert.Contains(t, rm.viewportContent, "harness-abc is already in use")
+}
+
+func TestModel_WorkflowIDCollision_NoSessionsQuits(t *testing.T) {
+	m := newTestModel()
+	m.state = StateStartup
+
+	result, cmd := m.Update(WorkflowIDCollisionMsg{WorkflowID: "harness-abc"})
+	rm := result.(*Model)
+	assert.True(t, rm.quitting)
+	require.Error(t, rm.err)
+	assert.Contains(t, rm.err.Error(), "--workflow-id-template")
+	assert.NotNil(t, cmd)
+}
@@ -0,0 +1,200 @@
+package command_safety
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Classifier extends the built-in IsKnownSafeCommand allow-list with
+// user-configured command prefixes.
+//
+// Safe patterns (e.g. "make lint", "cargo check") are auto-approved in
+// addition to the built-in read-only commands. Unsafe patterns (e.g.
+// "terraform apply") always require approval, even when the built-in
+// allow-list or a user safe pattern would otherwise accept them.
+//
+// A pattern is a whitespace-separated command. The first token is compared
+// against the basename of the program, so "make lint" matches both
+// `make lint` and `/usr/bin/make lint`. A safe pattern matches only the exact
+// arguments it lists; a trailing "*" token allows any further arguments, so
+// "make lint *" also matches `make lint --fix`. Unsafe patterns are prefixes:
+// "terraform apply" also matches `terraform apply -auto-approve`.
+//
+// Codex only exposes the fixed allow-list; user-defined rules live in the
+// exec policy engine.
+type Classifier struct {
+	safe   [][]string
+	unsafe [][]string
+}
+
+// Classification is the result of explaining a command's safety.
+type Classification struct {
+	// Safe is true if the command would be auto-approved in unless-trusted mode.
+	Safe bool
+	// ForcedPrompt is true if a user unsafe override matched.
+	ForcedPrompt bool
+	// Dangerous is true if the command matches the destructive-command heuristic.
+	Dangerous bool
+	// Reason is a human-readable explanation of the classification.
+	Reason string
+	// Commands are the individual commands that were classified (after
+	// splitting `bash -lc "a && b"` scripts).
+	Commands [][]string
+}
+
+// NewClassifier builds a Classifier from safe and unsafe patterns.
+// Empty or whitespace-only patterns are ignored.
+func NewClassifier(safePatterns, unsafePatterns []string) *Classifier {
+	return &Classifier{
+		safe:   parsePatterns(safePatterns),
+		unsafe: parsePatterns(unsafePatterns),
+	}
+}
+
+// HasOverrides returns true if any user patterns are configured.
+func (c *Classifier) HasOverrides() bool {
+	return c != nil && (len(c.safe) > 0 || len(c.unsafe) > 0)
+}
+
+// IsSafe returns true if the command can be auto-approved. A nil Classifier
+// behaves exactly like IsKnownSafeCommand.
+func (c *Classifier) IsSafe(command []string) bool {
+	return c.Classify(command).Safe
+}
+
+// RequiresApproval returns true if any part of the command matches a user
+// unsafe override. Such commands must be prompted regardless of exec policy
+// rules or the built-in allow-list.
+func (c *Classifier) RequiresApproval(command []string) bool {
+	if c == nil || len(c.unsafe) == 0 {
+		return false
+	}
+	for _, cmd := range splitCommands(normalizeZsh(command)) {
+		if _, ok := matchPattern(c.unsafe, cmd, true); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Classify returns the full classification of a command, including the
+// reason it was (or was not) considered safe.
+func (c *Classifier) Classify(command []string) Classification {
+	normalized := normalizeZsh(command)
+	cmds := splitCommands(normalized)
+	result := Classification{
+		Commands:  cmds,
+		Dangerous: CommandMightBeDangerous(normalized),
+	}
+
+	if c != nil {
+		for _, cmd := range cmds {
+			if pattern, ok := matchPattern(c.unsafe, cmd, true); ok {
+				result.ForcedPrompt = true
+				result.Reason = fmt.Sprintf("matches unsafe override %q", pattern)
+				return result
+			}
+		}
+	}
+
+	if IsKnownSafeCommand(normalized) {
+		result.Safe = true
+		result.Reason = "built-in read-only command"
+		return result
+	}
+
+	if len(cmds) == 0 {
+		result.Reason = "empty command"
+		return result
+	}
+
+	var userPatterns []string
+	for _, cmd := range cmds {
+		if isSafeToCallWithExec(cmd) {
+			continue
+		}
+		if c != nil {
+			if pattern, ok := matchPattern(c.safe, cmd, false); ok {
+				userPatterns = append(userPatterns, pattern)
+				continue
+			}
+		}
+		result.Reason = fmt.Sprintf("%q is not in the safe command list", strings.Join(cmd, " "))
+		return result
+	}
+
+	result.Safe = true
+	result.Reason = fmt.Sprintf("matches safe pattern %q", strings.Join(userPatterns, `", "`))
+	return result
+}
+
+// splitCommands returns the individual commands of a `bash -lc "<script>"`
+// invocation, or the command itself if it is not a plain shell script.
+func splitCommands(command []string) [][]string {
+	if all := ParseShellLcPlainCommands(command); len(all) > 0 {
+		return all
+	}
+	if len(command) == 0 {
+		return nil
+	}
+	return [][]string{command}
+}
+
+// normalizeZsh maps zsh to bash, mirroring IsKnownSafeCommand.
+func normalizeZsh(command []string) []string {
+	normalized := make([]string, len(command))
+	for i, s := range command {
+		if s == "zsh" {
+			normalized[i] = "bash"
+		} else {
+			normalized[i] = s
+		}
+	}
+	return normalized
+}
+
+func parsePatterns(patterns []string) [][]string {
+	var parsed [][]string
+	for _, p := range patterns {
+		if fields := strings.Fields(p); len(fields) > 0 {
+			parsed = append(parsed, fields)
+		}
+	}
+	return parsed
+}
+
+// matchPattern returns the first pattern that matches command. With prefix
+// set a pattern matches any command it is a token prefix of; otherwise the
+// command must have exactly the pattern's arguments unless the pattern ends
+// with a "*" token.
+func matchPattern(patterns [][]string, command []string, prefix bool) (string, bool) {
+	if len(command) == 0 {
+		return "", false
+	}
+	for _, pattern := range patterns {
+		tokens := pattern
+		rest := prefix
+		if len(tokens) > 1 && tokens[len(tokens)-1] == "*" {
+			tokens = tokens[:len(tokens)-1]
+			rest = true
+		}
+		if len(tokens) > len(command) || (!rest && len(tokens) != len(command)) {
+			continue
+		}
+		if filepath.Base(command[0]) != tokens[0] {
+			continue
+		}
+		matched := true
+		for i := 1; i < len(tokens); i++ {
+			if command[i] != tokens[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return strings.Join(pattern, " "), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,101 @@
+package command_safety
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifier_NilBehavesLikeBuiltIn(t *testing.T) {
+	var c *Classifier
+	assert.True(t, c.IsSafe([]string{"ls", "-la"}))
+	assert.False(t, c.IsSafe([]string{"make", "lint"}))
+	assert.False(t, c.RequiresApproval([]string{"terraform", "apply"}))
+}
+
+func TestClassifier_SafePatternExtendsAllowList(t *testing.T) {
+	c := NewClassifier([]string{"make lint", "cargo check"}, nil)
+
+	assert.True(t, c.IsSafe([]string{"make", "lint"}))
+	assert.True(t, c.IsSafe([]string{"/usr/bin/make", "lint"}))
+	assert.True(t, c.IsSafe([]string{"cargo", "check"}))
+	assert.False(t, c.IsSafe([]string{"make", "install"}))
+	assert.False(t, c.IsSafe([]string{"make"}))
+}
+
+func TestClassifier_SafePatternRejectsExtraArguments(t *testing.T) {
+	c := NewClassifier([]string{"make lint"}, nil)
+
+	assert.False(t, c.IsSafe([]string{"make", "lint", "install"}))
+	assert.False(t, c.IsSafe([]string{"make", "lint", "deploy"}))
+	assert.False(t, c.IsSafe([]string{"bash", "-lc", "make lint install"}))
+}
+
+func TestClassifier_SafePatternTrailingWildcard(t *testing.T) {
+	c := NewClassifier([]string{"make lint *"}, nil)
+
+	assert.True(t, c.IsSafe([]string{"make", "lint"}))
+	assert.True(t, c.IsSafe([]string{"/usr/bin/make", "lint", "--keep-going"}))
+	assert.False(t, c.IsSafe([]string{"make", "install"}))
+
+	got := c.Classify([]string{"make", "lint", "--fix"})
+	assert.Equal(t, `matches safe pattern "make lint *"`, got.Reason)
+}
+
+func TestClassifier_SafePatternInShellScript(t *testing.T) {
+	c := NewClassifier([]string{"make lint"}, nil)
+
+	assert.True(t, c.IsSafe([]string{"bash", "-lc", "make lint && git status"}))
+	assert.True(t, c.IsSafe([]string{"zsh", "-lc", "make lint"}))
+	assert.False(t, c.IsSafe([]string{"bash", "-lc", "make lint && make install"}))
+}
+
+func TestClassifier_UnsafeOverrideWins(t *testing.T) {
+	c := NewClassifier([]string{"terraform *"}, []string{"terraform apply", "git log"})
+
+	assert.True(t, c.IsSafe([]string{"terraform", "plan"}))
+	assert.False(t, c.IsSafe([]string{"terraform", "apply"}))
+	assert.True(t, c.RequiresApproval([]string{"terraform", "apply", "-auto-approve"}))
+
+	// Unsafe overrides also apply to built-in safe commands.
+	assert.False(t, c.IsSafe([]string{"git", "log"}))
+	assert.True(t, c.RequiresApproval([]string{"bash", "-lc", "ls && git log -n 1"}))
+	assert.False(t, c.RequiresApproval([]string{"git", "status"}))
+}
+
+func TestClassifier_IgnoresBlankPatterns(t *testing.T) {
+	c := NewClassifier([]string{"", "   "}, []string{""})
+	assert.False(t, c.HasOverrides())
+	assert.False(t, c.IsSafe([]string{"make"}))
+}
+
+func TestClassifier_ClassifyReasons(t *testing.T) {
+	c := NewClassifier([]string{"make lint"}, []string{"terraform apply"})
+
+	got := c.Classify([]string{"ls"})
+	assert.True(t, got.Safe)
+	assert.Equal(t, "built-in read-only command", got.Reason)
+
+	got = c.Classify([]string{"make", "lint"})
+	assert.True(t, got.Safe)
+	assert.Equal(t, `matches safe pattern "make lint"`, got.Reason)
+
+	got = c.Classify([]string{"terraform", "apply"})
+	assert.False(t, got.Safe)
+	assert.True(t, got.ForcedPrompt)
+	assert.Equal(t, `matches unsafe override "terraform apply"`, got.Reason)
+
+	got = c.Classify([]string{"bash", "-lc", "ls && npm install"})
+	assert.False(t, got.Safe)
+	assert.False(t, got.ForcedPrompt)
+	assert.Equal(t, `"npm install" is not in the safe command list`, got.Reason)
+	assert.Len(t, got.Commands, 2)
+
+	got = c.Classify([]string{"rm", "-rf", "/tmp/x"})
+	assert.True(t, got.Dangerous)
+	assert.False(t, got.Safe)
+
+	got = c.Classify(nil)
+	assert.False(t, got.Safe)
+	assert.Equal(t, "empty command", got.Reason)
+}
@@ -10,14 +10,7 @@ import (
 // Maps to: codex-rs/core/src/command_safety/is_safe_command.rs is_known_safe_command
 func IsKnownSafeCommand(command []string) bool {
 	// Normalize zsh → bash for consistent handling.
-	normalized := make([]string, len(command))
-	for i, s := range command {
-		if s == "zsh" {
-			normalized[i] = "bash"
-		} else {
-			normalized[i] = s
-		}
-	}
+	normalized := normalizeZsh(command)
 
 	if isSafeToCallWithExec(normalized) {
 		return true
@@ -0,0 +1,118 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"go.temporal.io/sdk/client"
+	tlog "go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/mfateev/temporal-agent-harness/internal/cli"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/temporalclient"
+)
+
+// Options configures `tcx demo`.
+type Options struct {
+	// TemporalCLI is the path of the temporal CLI that runs the dev server.
+	// Empty uses `temporal` from PATH, else downloads the CLI once into the
+	// user's temp directory.
+	TemporalCLI string
+
+	Inline  bool
+	NoColor bool
+
+	// Out receives progress messages (default: stderr).
+	Out io.Writer
+}
+
+// Run implements `tcx demo`. It starts a throwaway dev server and an
+// in-process worker whose LLM is the scripted scenario, then runs the TUI
+// on a new session in a temporary demo project. Everything is removed on
+// exit; nothing reads API keys or the user's ~/.codex.
+func Run(opts Options) error {
+	if opts.Out == nil {
+		opts.Out = os.Stderr
+	}
+
+	root, err := os.MkdirTemp("", "tcx-demo-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(root)
+	workspace := filepath.Join(root, "greeter")
+	codexHome := filepath.Join(root, "codex-home")
+	for _, dir := range []string{workspace, codexHome} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	if err := writeWorkspace(workspace); err != nil {
+		return fmt.Errorf("failed to create the demo project: %w", err)
+	}
+
+	fmt.Fprintln(opts.Out, "Starting a local Temporal dev server for the demo...")
+	server, err := startDevServer(opts.TemporalCLI)
+	if err != nil {
+		return fmt.Errorf("failed to start the Temporal dev server: %w", err)
+	}
+	defer server.Stop()
+
+	scenario := &Scenario{Workspace: workspace}
+	w := newWorker(server.Client(), scenario.Client())
+	if err := w.Start(); err != nil {
+		return fmt.Errorf("failed to start the demo worker: %w", err)
+	}
+	defer w.Stop()
+
+	return cli.Run(cli.Config{
+		Connection: temporalclient.ConnectionConfig{
+			HostPort:  server.FrontendHostPort(),
+			Namespace: "default",
+		},
+		Message: Prompt,
+		Model:   "demo",
+		NoColor: opts.NoColor,
+		Cwd:     workspace,
+		Permissions: models.Permissions{
+			ApprovalMode:         models.ApprovalUnlessTrusted,
+			SandboxNetworkAccess: true,
+		},
+		CodexHome:          codexHome,
+		Provider:           "openai",
+		Inline:             opts.Inline,
+		InputPreviewTokens: cli.DefaultInputPreviewTokens,
+		Markdown:           cli.DefaultMarkdownOptions(),
+		Timezone:           cli.DetectTimezone(),
+		Locale:             cli.DetectLocale(),
+		Ephemeral:          true,
+	})
+}
+
+// startDevServer starts a dev server with in-memory persistence and its
+// output and logs discarded, so they do not draw over the TUI.
+func startDevServer(temporalCLI string) (*testsuite.DevServer, error) {
+	if temporalCLI == "" {
+		temporalCLI, _ = exec.LookPath("temporal")
+	}
+	logger := tlog.NewStructuredLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	// The worker shares the server's client, so it needs the codec the CLI
+	// dials with.
+	dataConverter, err := temporalclient.ConnectionConfig{}.DataConverter(nil)
+	if err != nil {
+		return nil, err
+	}
+	return testsuite.StartDevServer(context.Background(), testsuite.DevServerOptions{
+		ExistingPath:  temporalCLI,
+		ClientOptions: &client.Options{Namespace: "default", Logger: logger, DataConverter: dataConverter},
+		LogLevel:      "error",
+		Stdout:        io.Discard,
+		Stderr:        io.Discard,
+	})
+}
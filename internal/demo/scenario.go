@@ -0,0 +1,258 @@
+// Package demo runs `tcx demo`: a fully offline, scripted session that shows
+// the tcx UX (plan updates, tool calls, an approval prompt and compaction)
+// without API keys or a Temporal cluster.
+package demo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mfateev/temporal-agent-harness/internal/instructions"
+	"github.com/mfateev/temporal-agent-harness/internal/llm"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// Prompt is the first user message of the demo session.
+const Prompt = "Add a -name flag to the greeter so it can greet anyone."
+
+// callIDPrefix marks tool calls made by the script; the step that made a
+// call is encoded after it ("demo-2-1" is the second call of step 2).
+const callIDPrefix = "demo-"
+
+const (
+	finalMessage = "Done! `main.go` now takes a `-name` flag (`go run . -name Ada` prints `Hello, Ada!`).\n\n" +
+		"That was the whole scripted scenario. Things to try next:\n" +
+		"- `/compact` to compact the conversation into a summary\n" +
+		"- `/status` for tokens, cost and turn latency\n" +
+		"- `/diff` to see the change in the demo project\n" +
+		"- `/exit` to end the session (the demo project is deleted)"
+	deniedMessage = "Understood, I left `main.go` unchanged. Denied calls are reported back to the model, " +
+		"so a real agent would now ask how you would like to proceed.\n\n" +
+		"Try `/compact` to compact the conversation, `/status` for usage, or `/exit` to end the demo."
+	compactionSummary = "The user is trying tcx with a scripted demo. The agent planned the change, " +
+		"listed and read the demo project, and was asked to add a -name flag to the greeter in main.go."
+)
+
+const (
+	readmeContent = "# greeter\n\nA tiny Go program used by `tcx demo`.\n"
+	mainContent   = `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println("Hello, world!")
+}
+`
+	updatedMainContent = `package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+func main() {
+	name := flag.String("name", "world", "who to greet")
+	flag.Parse()
+	fmt.Printf("Hello, %s!\n", *name)
+}
+`
+)
+
+// writeWorkspace creates the demo project in dir.
+func writeWorkspace(dir string) error {
+	files := map[string]string{
+		"README.md": readmeContent,
+		"main.go":   mainContent,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Scenario scripts the demo session for a project in Workspace.
+type Scenario struct {
+	Workspace string
+}
+
+// Client returns the offline LLM client playing the scenario.
+func (s *Scenario) Client() *llm.ScriptedClient {
+	return &llm.ScriptedClient{
+		Respond:   s.Respond,
+		Summarize: func([]models.ConversationItem) string { return compactionSummary },
+	}
+}
+
+type planStep struct {
+	Step   string `json:"step"`
+	Status string `json:"status"`
+}
+
+// Respond picks the next response of the script from the request history:
+// each step runs once, in order, and later messages get a canned reply.
+func (s *Scenario) Respond(req llm.LLMRequest) llm.LLMResponse {
+	if req.BaseInstructions == instructions.SuggestionSystemPrompt {
+		return textResponse("Run /compact", 20)
+	}
+
+	step, done := progress(req.History)
+	if done {
+		return textResponse(cannedReply(lastUserMessage(req.History)), 1200)
+	}
+	main := filepath.Join(s.Workspace, "main.go")
+	switch step {
+	case 0:
+		return callsResponse(step, "I'll make a plan, then look at the project before changing anything.",
+			call("update_plan", map[string]interface{}{
+				"explanation": "Add a -name flag to the greeter",
+				"plan": []planStep{
+					{"Explore the project", "in_progress"},
+					{"Add the -name flag", "pending"},
+					{"Verify the change", "pending"},
+				},
+			}),
+			call("list_dir", map[string]interface{}{"dir_path": s.Workspace}))
+	case 1:
+		return callsResponse(step, "",
+			call("read_file", map[string]interface{}{"file_path": main}))
+	case 2:
+		return callsResponse(step, "The greeting is hard-coded in `main.go`. Writing files needs your approval:",
+			call("update_plan", map[string]interface{}{
+				"plan": []planStep{
+					{"Explore the project", "completed"},
+					{"Add the -name flag", "in_progress"},
+					{"Verify the change", "pending"},
+				},
+			}),
+			call("write_file", map[string]interface{}{"path": main, "content": updatedMainContent}))
+	case 3:
+		if !callSucceeded(req.History, "write_file") {
+			return textResponse(deniedMessage, 1800)
+		}
+		return callsResponse(step, "",
+			call("update_plan", map[string]interface{}{
+				"plan": []planStep{
+					{"Explore the project", "completed"},
+					{"Add the -name flag", "completed"},
+					{"Verify the change", "in_progress"},
+				},
+			}),
+			call("shell_command", map[string]interface{}{
+				"command": "grep -n flag main.go",
+				"workdir": s.Workspace,
+			}))
+	case 4:
+		return callsResponse(step, "",
+			call("update_plan", map[string]interface{}{
+				"plan": []planStep{
+					{"Explore the project", "completed"},
+					{"Add the -name flag", "completed"},
+					{"Verify the change", "completed"},
+				},
+			}))
+	}
+	return textResponse(finalMessage, 2100)
+}
+
+// progress returns the next script step and whether the script is over:
+// its last message was sent or the history was compacted.
+func progress(history []models.ConversationItem) (step int, done bool) {
+	for _, item := range history {
+		switch item.Type {
+		case models.ItemTypeCompaction:
+			return 0, true
+		case models.ItemTypeAssistantMessage:
+			if item.Content == finalMessage || item.Content == deniedMessage {
+				return 0, true
+			}
+		case models.ItemTypeFunctionCall:
+			var n, i int
+			if _, err := fmt.Sscanf(item.CallID, callIDPrefix+"%d-%d", &n, &i); err == nil && n+1 > step {
+				step = n + 1
+			}
+		}
+	}
+	return step, false
+}
+
+// callSucceeded reports whether the script's last call of the named tool
+// has a successful output.
+func callSucceeded(history []models.ConversationItem, name string) bool {
+	var callID string
+	for _, item := range history {
+		if item.Type == models.ItemTypeFunctionCall && item.Name == name && strings.HasPrefix(item.CallID, callIDPrefix) {
+			callID = item.CallID
+		}
+	}
+	for _, item := range history {
+		if item.Type == models.ItemTypeFunctionCallOutput && item.CallID == callID && item.Output != nil {
+			return item.Output.Success == nil || *item.Output.Success
+		}
+	}
+	return false
+}
+
+func lastUserMessage(history []models.ConversationItem) string {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Type == models.ItemTypeUserMessage {
+			return history[i].Content
+		}
+	}
+	return ""
+}
+
+// cannedReply answers messages sent after the script ended.
+func cannedReply(message string) string {
+	return fmt.Sprintf("This is a scripted demo, so I can't really answer %q. "+
+		"Connect tcx to a worker with an API key for real answers.\n\n"+
+		"Try `/compact`, `/status`, `/diff`, or `/exit` to end the demo.", message)
+}
+
+type scriptedCall struct {
+	name string
+	args map[string]interface{}
+}
+
+func call(name string, args map[string]interface{}) scriptedCall {
+	return scriptedCall{name: name, args: args}
+}
+
+// callsResponse returns a response of step making the calls, preceded by
+// text when it is non-empty.
+func callsResponse(step int, text string, calls ...scriptedCall) llm.LLMResponse {
+	var items []models.ConversationItem
+	if text != "" {
+		items = append(items, models.ConversationItem{Type: models.ItemTypeAssistantMessage, Content: text})
+	}
+	for i, c := range calls {
+		args, _ := json.Marshal(c.args)
+		items = append(items, models.ConversationItem{
+			Type:      models.ItemTypeFunctionCall,
+			CallID:    fmt.Sprintf("%s%d-%d", callIDPrefix, step, i+1),
+			Name:      c.name,
+			Arguments: string(args),
+		})
+	}
+	return llm.LLMResponse{
+		Items:        items,
+		FinishReason: models.FinishReasonToolCalls,
+		TokenUsage:   usage(1500 + 300*step),
+	}
+}
+
+func textResponse(text string, promptTokens int) llm.LLMResponse {
+	return llm.LLMResponse{
+		Items:        []models.ConversationItem{{Type: models.ItemTypeAssistantMessage, Content: text}},
+		FinishReason: models.FinishReasonStop,
+		TokenUsage:   usage(promptTokens),
+	}
+}
+
+func usage(promptTokens int) models.TokenUsage {
+	return models.TokenUsage{PromptTokens: promptTokens, CompletionTokens: 120, TotalTokens: promptTokens + 120}
+}
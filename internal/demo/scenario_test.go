@@ -0,0 +1,86 @@
+package demo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/instructions"
+	"github.com/mfateev/temporal-agent-harness/internal/llm"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// play runs the scenario like the agentic loop does, answering every call
+// with an output whose success is decided by succeed, and returns the
+// history and the names of the calls in order.
+func play(t *testing.T, s *Scenario, succeed func(name string) bool) ([]models.ConversationItem, []string) {
+	history := []models.ConversationItem{{Type: models.ItemTypeUserMessage, Content: Prompt}}
+	var calls []string
+	for i := 0; i < 10; i++ {
+		resp := s.Respond(llm.LLMRequest{History: history})
+		history = append(history, resp.Items...)
+		if resp.FinishReason == models.FinishReasonStop {
+			return history, calls
+		}
+		for _, item := range resp.Items {
+			if item.Type != models.ItemTypeFunctionCall {
+				continue
+			}
+			calls = append(calls, item.Name)
+			ok := succeed(item.Name)
+			history = append(history, models.ConversationItem{
+				Type:   models.ItemTypeFunctionCallOutput,
+				CallID: item.CallID,
+				Output: &models.FunctionCallOutputPayload{Content: "output", Success: &ok},
+			})
+		}
+	}
+	t.Fatal("scenario did not finish")
+	return nil, nil
+}
+
+func TestScenario_Approved(t *testing.T) {
+	s := &Scenario{Workspace: "/tmp/greeter"}
+	history, calls := play(t, s, func(string) bool { return true })
+
+	assert.Equal(t, []string{
+		"update_plan", "list_dir", "read_file", "update_plan", "write_file",
+		"update_plan", "shell_command", "update_plan",
+	}, calls)
+	assert.Equal(t, finalMessage, history[len(history)-1].Content)
+
+	// Follow-up messages get the canned reply instead of replaying the script.
+	history = append(history, models.ConversationItem{Type: models.ItemTypeUserMessage, Content: "what now?"})
+	resp := s.Respond(llm.LLMRequest{History: history})
+	require.Len(t, resp.Items, 1)
+	assert.Contains(t, resp.Items[0].Content, `"what now?"`)
+}
+
+func TestScenario_WriteDenied(t *testing.T) {
+	s := &Scenario{Workspace: "/tmp/greeter"}
+	history, calls := play(t, s, func(name string) bool { return name != "write_file" })
+
+	assert.Equal(t, []string{"update_plan", "list_dir", "read_file", "update_plan", "write_file"}, calls)
+	assert.Equal(t, deniedMessage, history[len(history)-1].Content)
+}
+
+func TestScenario_AfterCompaction(t *testing.T) {
+	s := &Scenario{Workspace: "/tmp/greeter"}
+	resp, err := s.Client().Compact(t.Context(), llm.CompactRequest{
+		Input: []models.ConversationItem{{Type: models.ItemTypeUserMessage, Content: Prompt}},
+	})
+	require.NoError(t, err)
+
+	history := append(resp.Items, models.ConversationItem{Type: models.ItemTypeUserMessage, Content: "hi"})
+	next := s.Respond(llm.LLMRequest{History: history})
+	assert.Equal(t, models.FinishReasonStop, next.FinishReason)
+	assert.True(t, strings.HasPrefix(next.Items[0].Content, "This is a scripted demo"))
+}
+
+func TestScenario_Suggestion(t *testing.T) {
+	s := &Scenario{Workspace: "/tmp/greeter"}
+	resp := s.Respond(llm.LLMRequest{BaseInstructions: instructions.SuggestionSystemPrompt})
+	assert.Equal(t, "Run /compact", resp.Items[0].Content)
+}
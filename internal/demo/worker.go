@@ -0,0 +1,96 @@
+package demo
+
+import (
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/capabilities"
+	"github.com/mfateev/temporal-agent-harness/internal/cli"
+	"github.com/mfateev/temporal-agent-harness/internal/execsession"
+	"github.com/mfateev/temporal-agent-harness/internal/llm"
+	"github.com/mfateev/temporal-agent-harness/internal/mcp"
+	"github.com/mfateev/temporal-agent-harness/internal/tools"
+	"github.com/mfateev/temporal-agent-harness/internal/tools/handlers"
+	"github.com/mfateev/temporal-agent-harness/internal/workflow"
+)
+
+// newWorker returns a worker like cmd/worker's, with llmClient in place of
+// the provider client. The memory subsystem (off by default) and telemetry
+// are left out, and archive writes are not spooled.
+func newWorker(c client.Client, llmClient llm.LLMClient) worker.Worker {
+	w := worker.New(c, cli.TaskQueue, worker.Options{})
+
+	w.RegisterWorkflow(workflow.AgenticWorkflow)
+	w.RegisterWorkflow(workflow.AgenticWorkflowContinued)
+	w.RegisterWorkflow(workflow.HarnessWorkflow)
+	w.RegisterWorkflow(workflow.HarnessWorkflowContinued)
+	w.RegisterWorkflow(workflow.SessionWorkflow)
+	w.RegisterWorkflow(workflow.SessionWorkflowContinued)
+
+	toolRegistry := tools.NewToolRegistry()
+	toolRegistry.Register(handlers.NewShellHandler())
+	toolRegistry.Register(handlers.NewShellCommandHandler())
+	toolRegistry.Register(handlers.NewReadFileTool())
+	toolRegistry.Register(handlers.NewWriteFileTool())
+	toolRegistry.Register(handlers.NewListDirTool())
+	toolRegistry.Register(handlers.NewGrepFilesTool())
+	toolRegistry.Register(handlers.NewApplyPatchTool())
+	execStore := execsession.NewStore()
+	toolRegistry.Register(handlers.NewExecCommandHandler(execStore))
+	toolRegistry.Register(handlers.NewWriteStdinHandler(execStore))
+	mcpStore := mcp.NewMcpStore()
+	toolRegistry.Register(handlers.NewMCPHandler(mcpStore))
+
+	llmActivities := activities.NewLLMActivities(llmClient)
+	w.RegisterActivity(llmActivities.ExecuteLLMCall)
+	w.RegisterActivity(llmActivities.ExecuteCompact)
+	w.RegisterActivity(llmActivities.GenerateSuggestions)
+	w.RegisterActivity(llmActivities.GetProviderHealth)
+
+	toolActivities := activities.NewToolActivities(toolRegistry)
+	w.RegisterActivity(toolActivities.ExecuteTool)
+	w.RegisterActivity(toolActivities.PreviewPatch)
+
+	instructionActivities := activities.NewInstructionActivities()
+	w.RegisterActivity(instructionActivities.LoadWorkerInstructions)
+	w.RegisterActivity(instructionActivities.LoadPersonalInstructions)
+	w.RegisterActivity(instructionActivities.LoadExecPolicy)
+	w.RegisterActivity(instructionActivities.LoadConfigFile)
+	w.RegisterActivity(instructionActivities.LoadSkills)
+	w.RegisterActivity(instructionActivities.ReadSkillContent)
+	w.RegisterActivity(instructionActivities.ResolveFileMentions)
+
+	mcpActivities := activities.NewMcpActivities(mcpStore)
+	w.RegisterActivity(mcpActivities.InitializeMcpServers)
+	w.RegisterActivity(mcpActivities.CleanupMcpServers)
+
+	hookActivities := activities.NewHookActivities()
+	w.RegisterActivity(hookActivities.RunPreTurnHooks)
+
+	scratchActivities := activities.NewScratchActivities()
+	w.RegisterActivity(scratchActivities.CleanupScratchDir)
+
+	archiveActivities := activities.NewArchiveActivities(nil)
+	w.RegisterActivity(archiveActivities.ArchiveConversationItems)
+	w.RegisterActivity(archiveActivities.FlushArchiveWrites)
+	w.RegisterActivity(activities.NewReportActivities().DeliverSessionReport)
+
+	capabilityActivities := activities.NewCapabilityActivities(capabilities.Probe())
+	w.RegisterActivity(capabilityActivities.GetWorkerCapabilities)
+
+	execSessionActivities := activities.NewExecSessionActivities(execStore)
+	w.RegisterActivity(execSessionActivities.ListExecSessions)
+	w.RegisterActivity(execSessionActivities.CleanExecSessions)
+
+	crewActivities := activities.NewCrewActivities()
+	w.RegisterActivity(crewActivities.DiscoverCrews)
+	w.RegisterActivity(crewActivities.LoadCrew)
+	w.RegisterActivity(crewActivities.ResolveCrewMain)
+	w.RegisterActivity(crewActivities.ResolveCrewAgent)
+
+	sessionActivities := activities.NewSessionActivities(c)
+	w.RegisterActivity(sessionActivities.WaitForSessionReady)
+
+	return w
+}
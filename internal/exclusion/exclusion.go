@@ -0,0 +1,158 @@
+// Package exclusion decides which paths must never be read or sent to the
+// LLM (credentials, key material, secrets directories).
+//
+// Patterns come from three sources, in order:
+//   - DefaultPatterns (always on)
+//   - the exclude_paths list in config.toml
+//   - a .codexignore file at the git root (one pattern per line, '#' comments)
+//
+// Pattern syntax is a subset of .gitignore: a pattern without '/' matches any
+// path component ("*.pem", ".env"); a trailing '/' marks a directory
+// ("secrets/"); a pattern containing '/' is anchored at the git root
+// ("config/prod.yaml", "/build"). Negation and "**" are not supported.
+package exclusion
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFileName is the per-repository exclusion file, read from the git root.
+const IgnoreFileName = ".codexignore"
+
+// ExcludedMessage is returned by tools in place of excluded content.
+const ExcludedMessage = "path excluded by policy"
+
+// DefaultPatterns are excluded in every session.
+var DefaultPatterns = []string{".env", ".env.*", "*.pem", "*.key"}
+
+// pattern is a single parsed exclusion rule.
+type pattern struct {
+	glob     string
+	anchored bool
+}
+
+// Policy matches paths against exclusion patterns.
+// A nil *Policy excludes nothing.
+type Policy struct {
+	root     string
+	patterns []pattern
+}
+
+// Load builds the policy for a session working in cwd: defaults, then extra
+// (from config), then the .codexignore at the git root containing cwd.
+// A missing or unreadable ignore file is not an error.
+func Load(cwd string, extra []string) *Policy {
+	p := &Policy{root: gitRoot(cwd)}
+	p.add(DefaultPatterns)
+	p.add(extra)
+	if p.root != "" {
+		p.add(readIgnoreFile(filepath.Join(p.root, IgnoreFileName)))
+	}
+	return p
+}
+
+// Excluded reports whether path (absolute, or relative to the policy root)
+// matches any exclusion pattern.
+func (p *Policy) Excluded(target string) bool {
+	if p == nil || target == "" {
+		return false
+	}
+
+	// Anchored patterns only apply to paths inside the root.
+	var rel string
+	if p.root != "" {
+		abs := target
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(p.root, abs)
+		}
+		if r, err := filepath.Rel(p.root, abs); err == nil && r != ".." && !strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+			rel = filepath.ToSlash(r)
+		}
+	}
+
+	components := strings.Split(filepath.ToSlash(filepath.Clean(target)), "/")
+	if rel != "" {
+		components = strings.Split(rel, "/")
+	}
+
+	for _, pat := range p.patterns {
+		if pat.anchored {
+			if rel == "" {
+				continue
+			}
+			for i := 1; i <= len(components); i++ {
+				if ok, _ := path.Match(pat.glob, strings.Join(components[:i], "/")); ok {
+					return true
+				}
+			}
+			continue
+		}
+		for _, c := range components {
+			if c == "" {
+				continue
+			}
+			if ok, _ := path.Match(pat.glob, c); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// add parses and appends raw patterns, skipping blanks and comments.
+func (p *Policy) add(raw []string) {
+	for _, r := range raw {
+		r = strings.TrimSpace(r)
+		if r == "" || strings.HasPrefix(r, "#") {
+			continue
+		}
+		// Directory markers match the component itself; contents are covered
+		// because every component of a path is checked.
+		r = strings.TrimSuffix(r, "/")
+		anchored := strings.Contains(r, "/")
+		r = strings.TrimPrefix(r, "/")
+		if r == "" {
+			continue
+		}
+		p.patterns = append(p.patterns, pattern{glob: r, anchored: anchored})
+	}
+}
+
+// readIgnoreFile returns the lines of an ignore file, or nil if absent.
+func readIgnoreFile(file string) []string {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+// gitRoot walks up from dir to the nearest directory containing .git.
+// Falls back to dir itself when no repository is found.
+func gitRoot(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	dir = filepath.Clean(dir)
+	for d := dir; ; {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return d
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return dir
+		}
+		d = parent
+	}
+}
@@ -0,0 +1,51 @@
+package exclusion
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExcluded_Defaults(t *testing.T) {
+	p := Load(t.TempDir(), nil)
+	for _, path := range []string{".env", "app/.env", ".env.local", "certs/server.pem", "id.key"} {
+		assert.True(t, p.Excluded(path), path)
+	}
+	for _, path := range []string{"main.go", "env.go", "docs/keys.md"} {
+		assert.False(t, p.Excluded(path), path)
+	}
+}
+
+func TestExcluded_ConfigAndIgnoreFile(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, ".git"), 0o755))
+	sub := filepath.Join(root, "pkg")
+	require.NoError(t, os.Mkdir(sub, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, IgnoreFileName),
+		[]byte("# comment\n\n/config/prod.yaml\nfixtures/\n"), 0o644))
+
+	// cwd below the git root still picks up the root ignore file.
+	p := Load(sub, []string{"secrets/"})
+
+	assert.True(t, p.Excluded(filepath.Join(root, "secrets", "db.txt")))
+	assert.True(t, p.Excluded(filepath.Join(root, "pkg", "secrets")))
+	assert.True(t, p.Excluded(filepath.Join(root, "config", "prod.yaml")))
+	assert.True(t, p.Excluded(filepath.Join(root, "a", "fixtures", "x.json")))
+	// Anchored pattern only matches from the root.
+	assert.False(t, p.Excluded(filepath.Join(root, "pkg", "config", "prod.yaml")))
+	assert.False(t, p.Excluded(filepath.Join(root, "pkg", "main.go")))
+}
+
+func TestExcluded_NilPolicy(t *testing.T) {
+	var p *Policy
+	assert.False(t, p.Excluded("/repo/.env"))
+}
+
+func TestExcluded_OutsideRoot(t *testing.T) {
+	p := Load(t.TempDir(), []string{"/build"})
+	assert.True(t, p.Excluded("/elsewhere/.env"))
+	assert.False(t, p.Excluded("/elsewhere/build"))
+}
@@ -15,6 +15,7 @@ import (
 // Maps to: codex-rs/execpolicy combined policy manager
 type ExecPolicyManager struct {
 	policy *Policy
+	safety *command_safety.Classifier // nil = built-in allow-list only
 	mu     sync.RWMutex
 }
 
@@ -23,6 +24,21 @@ func NewExecPolicyManager(policy *Policy) *ExecPolicyManager {
 	return &ExecPolicyManager{policy: policy}
 }
 
+// SetSafetyClassifier installs a classifier that extends the built-in
+// safe-command allow-list used by the unless-trusted heuristic fallback.
+func (m *ExecPolicyManager) SetSafetyClassifier(c *command_safety.Classifier) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.safety = c
+}
+
+// SafetyClassifier returns the installed classifier, or nil if none.
+func (m *ExecPolicyManager) SafetyClassifier() *command_safety.Classifier {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.safety
+}
+
 // LoadExecPolicy reads all *.rules files from {codexHome}/rules/ and parses them
 // into an ExecPolicyManager.
 //
@@ -76,7 +92,7 @@ func LoadExecPolicyFromSource(source string) (*ExecPolicyManager, error) {
 // EvaluateCommand evaluates a shell command against the policy.
 //
 // The approvalMode determines the heuristic fallback when no rules match:
-//   - "unless-trusted": IsKnownSafeCommand → Allow, else Prompt
+//   - "unless-trusted": IsKnownSafeCommand (or a configured safe pattern) → Allow, else Prompt
 //   - "never":          Allow (auto-approve everything)
 //   - "on-failure":     Allow (runs in sandbox, escalate on failure)
 //
@@ -151,8 +167,9 @@ func (m *ExecPolicyManager) heuristicFallback(approvalMode string) func([]string
 			return DecisionAllow
 		}
 	case "unless-trusted":
+		safety := m.safety
 		return func(cmd []string) Decision {
-			if command_safety.IsKnownSafeCommand(cmd) {
+			if safety.IsSafe(cmd) {
 				return DecisionAllow
 			}
 			return DecisionPrompt
@@ -25,6 +25,10 @@ type SessionOpts struct {
 	Cwd       string
 	Env       []string // Full environment (nil = inherit)
 	TTY       bool
+
+	// Owner identifies the agent session that started the process, so its
+	// processes can be closed when it ends. Empty for unowned sessions.
+	Owner string
 }
 
 // ExecSession wraps a running process (PTY or pipes) with background output
@@ -36,6 +40,7 @@ type ExecSession struct {
 	Command   []string
 	Cwd       string
 	TTY       bool
+	Owner     string // Agent session that started the process (see SessionOpts)
 	StartedAt time.Time
 	LastUsed  time.Time
 
@@ -62,6 +67,7 @@ func StartSession(opts SessionOpts) (*ExecSession, error) {
 		Command:   opts.Command,
 		Cwd:       opts.Cwd,
 		TTY:       opts.TTY,
+		Owner:     opts.Owner,
 		StartedAt: time.Now(),
 		LastUsed:  time.Now(),
 		outputBuf: NewHeadTailBuffer(DefaultMaxBytes),
@@ -126,6 +126,25 @@ func (s *Store) ListAll() []SessionSummary {
 	return summaries
 }
 
+// CloseOwned closes the sessions started by owner and returns the number
+// closed.
+func (s *Store) CloseOwned(owner string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for id, sess := range s.sessions {
+		if sess.Owner != owner {
+			continue
+		}
+		sess.Close()
+		delete(s.sessions, id)
+		delete(s.reserved, id)
+		count++
+	}
+	return count
+}
+
 // CloseAll closes all sessions and returns the number closed.
 func (s *Store) CloseAll() int {
 	s.mu.Lock()
@@ -270,3 +270,23 @@ func TestStore_CloseAll(t *testing.T) {
 	assert.Equal(t, 3, closed)
 	assert.Equal(t, 0, store.Count())
 }
+
+func TestStore_CloseOwned(t *testing.T) {
+	store := NewStore()
+	for i, owner := range []string{"sess-a", "sess-b", "sess-a", ""} {
+		store.Store(&ExecSession{
+			ProcessID: strconv.Itoa(6000 + i),
+			Owner:     owner,
+			StartedAt: time.Now(),
+			LastUsed:  time.Now(),
+			exitCh:    make(chan struct{}),
+			outputBuf: NewHeadTailBuffer(1024),
+		})
+	}
+
+	assert.Equal(t, 2, store.CloseOwned("sess-a"))
+	assert.Equal(t, 2, store.Count())
+	_, err := store.Get("6001")
+	assert.NoError(t, err, "other sessions' processes keep running")
+	assert.Equal(t, 0, store.CloseOwned("sess-a"))
+}
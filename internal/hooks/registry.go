@@ -0,0 +1,72 @@
+// Package hooks provides a registry of pre-turn context injection hooks.
+//
+// Embedding applications register hooks at worker startup (typically via
+// init(), like tool specs) and enable them per session with the
+// pre_turn_hooks config.toml key. Enabled hooks run in the RunPreTurnHooks
+// activity before each LLM call, so their output is recorded in workflow
+// history and replays deterministically.
+package hooks
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// PreTurnInput describes the LLM call a hook is contributing context to.
+type PreTurnInput struct {
+	ConversationID string `json:"conversation_id"`
+	TurnID         string `json:"turn_id"`
+	Cwd            string `json:"cwd,omitempty"`
+	Iteration      int    `json:"iteration"`
+}
+
+// PreTurnOutput is a hook's contribution to the next LLM call.
+type PreTurnOutput struct {
+	// DeveloperInstructions is appended to the developer instructions for
+	// this LLM call only.
+	DeveloperInstructions string `json:"developer_instructions,omitempty"`
+
+	// Items are synthetic conversation items added to history before the
+	// call. Only the items returned before a turn's first LLM call are
+	// added; later calls in the turn get the developer instructions alone.
+	Items []models.ConversationItem `json:"items,omitempty"`
+}
+
+// PreTurnHook computes extra context for an upcoming LLM call.
+type PreTurnHook func(ctx context.Context, input PreTurnInput) (PreTurnOutput, error)
+
+var (
+	mu              sync.RWMutex
+	preTurnRegistry = map[string]PreTurnHook{}
+)
+
+// RegisterPreTurnHook adds a named hook to the global registry, replacing any
+// hook previously registered under the same name.
+func RegisterPreTurnHook(name string, hook PreTurnHook) {
+	mu.Lock()
+	defer mu.Unlock()
+	preTurnRegistry[name] = hook
+}
+
+// GetPreTurnHook returns the hook registered under name.
+func GetPreTurnHook(name string) (PreTurnHook, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	h, ok := preTurnRegistry[name]
+	return h, ok
+}
+
+// PreTurnHookNames returns the registered hook names, sorted.
+func PreTurnHookNames() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(preTurnRegistry))
+	for name := range preTurnRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
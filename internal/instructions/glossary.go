@@ -0,0 +1,186 @@
+package instructions
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// A project glossary (.codex/glossary.md at the git root) defines the
+// domain terms, internal service names and acronyms of a codebase. Entries
+// are given to the model in the developer instructions of each LLM call,
+// within a token budget: entries that apply to the files the session has
+// touched, or that the latest user messages mention, come first, then
+// entries that apply everywhere.
+//
+// An entry is either a list item or a heading followed by its definition:
+//
+//	- **PSP**: payment service provider (Adyen or Stripe).
+//
+//	## Ledger
+//	The double-entry accounting service, not the ledger table.
+//	Paths: services/ledger/, pkg/ledgerclient/
+//
+// A "Paths:" line scopes an entry to files under those paths (or matching
+// those globs), relative to the git root; a scoped entry is only given to
+// the model once the session touches such a file or mentions the term.
+
+// GlossaryFile is the path of the project glossary relative to the git root.
+const GlossaryFile = ".codex/glossary.md"
+
+// GlossaryEntry is one term of the project glossary.
+type GlossaryEntry struct {
+	Term       string   `json:"term"`
+	Definition string   `json:"definition"`
+	Paths      []string `json:"paths,omitempty"`
+}
+
+var (
+	glossaryHeading = regexp.MustCompile(`^#{2,6}\s+(.+?)\s*#*$`)
+	glossaryItem    = regexp.MustCompile(`^[-*]\s+(?:\*\*(.+?)\*\*|([^:]+?))\s*(?:[:—–]|\s-)\s*(.*)$`)
+)
+
+// ParseGlossary parses the entries of a glossary file. Lines outside an
+// entry (the title, introductory text) are ignored.
+func ParseGlossary(content string) []GlossaryEntry {
+	var entries []GlossaryEntry
+	var cur *GlossaryEntry
+	var body []string
+	flush := func() {
+		if cur != nil {
+			cur.Definition = strings.TrimSpace(strings.Join(body, " "))
+			if cur.Term != "" && cur.Definition != "" {
+				entries = append(entries, *cur)
+			}
+		}
+		cur, body = nil, nil
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if m := glossaryHeading.FindStringSubmatch(trimmed); m != nil {
+			flush()
+			cur = &GlossaryEntry{Term: m[1]}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") {
+			flush()
+			continue
+		}
+		if line == trimmed { // list items start at column 0
+			if m := glossaryItem.FindStringSubmatch(trimmed); m != nil {
+				flush()
+				cur = &GlossaryEntry{Term: strings.TrimSpace(m[1] + m[2])}
+				body = append(body, m[3])
+				continue
+			}
+		}
+		if cur == nil || trimmed == "" {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(trimmed, "Paths:"); ok {
+			for _, p := range strings.Split(rest, ",") {
+				if p = strings.Trim(strings.TrimSpace(p), "`"); p != "" {
+					cur.Paths = append(cur.Paths, p)
+				}
+			}
+			continue
+		}
+		body = append(body, trimmed)
+	}
+	flush()
+	return entries
+}
+
+// appliesTo reports whether the entry is scoped to one of paths (slash
+// separated, relative to the git root).
+func (e GlossaryEntry) appliesTo(paths []string) bool {
+	for _, scope := range e.Paths {
+		scope = strings.TrimPrefix(scope, "./")
+		dir := strings.TrimSuffix(scope, "/")
+		for _, p := range paths {
+			if p == dir || strings.HasPrefix(p, dir+"/") {
+				return true
+			}
+			if ok, _ := path.Match(scope, p); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mentionedIn reports whether the term appears as a whole word in text,
+// ignoring case.
+func (e GlossaryEntry) mentionedIn(text string) bool {
+	if text == "" {
+		return false
+	}
+	re, err := regexp.Compile(`(?i)(^|[^\pL\pN_])` + regexp.QuoteMeta(e.Term) + `($|[^\pL\pN_])`)
+	return err == nil && re.MatchString(text)
+}
+
+// render is the entry's line in the glossary block.
+func (e GlossaryEntry) render() string {
+	return "- " + e.Term + ": " + e.Definition
+}
+
+// SelectGlossary returns the entries to give the model, in file order, and
+// how many were left out. touched are the paths the session has read or
+// written (slash separated, relative to the git root) and text is the
+// latest user input. Relevant entries — scoped to a touched path, or whose
+// term appears in text or a touched path — are taken first, then unscoped
+// entries, until maxTokens (at ~4 characters per token) is used up.
+func SelectGlossary(entries []GlossaryEntry, touched []string, text string, maxTokens int) ([]GlossaryEntry, int) {
+	if len(entries) == 0 || maxTokens <= 0 {
+		return nil, len(entries)
+	}
+	pathText := strings.Join(touched, " ")
+	var relevant, general []int
+	for i, e := range entries {
+		switch {
+		case e.appliesTo(touched) || e.mentionedIn(text) || e.mentionedIn(pathText):
+			relevant = append(relevant, i)
+		case len(e.Paths) == 0:
+			general = append(general, i)
+		}
+	}
+
+	budget := maxTokens * 4
+	var picked []int
+	for _, i := range append(relevant, general...) {
+		size := len(entries[i].render()) + 1
+		if size > budget {
+			continue
+		}
+		budget -= size
+		picked = append(picked, i)
+	}
+	sort.Ints(picked)
+	selected := make([]GlossaryEntry, len(picked))
+	for j, i := range picked {
+		selected[j] = entries[i]
+	}
+	return selected, len(entries) - len(selected)
+}
+
+// FormatGlossary renders selected entries as a developer-instruction block.
+// omitted is the number of entries left out of the selection.
+func FormatGlossary(selected []GlossaryEntry, omitted int) string {
+	if len(selected) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("<glossary>\n")
+	b.WriteString("Terms specific to this project (from " + GlossaryFile + "). Read them with these meanings, not their general ones:\n")
+	for _, e := range selected {
+		b.WriteString(e.render() + "\n")
+	}
+	if omitted > 0 {
+		fmt.Fprintf(&b, "%d more terms are defined in %s; read it when an unfamiliar term comes up.\n", omitted, GlossaryFile)
+	}
+	b.WriteString("</glossary>")
+	return b.String()
+}
@@ -0,0 +1,73 @@
+package instructions
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testGlossary = `# Glossary
+
+Terms used across the monorepo.
+
+- **PSP**: payment service provider (Adyen or Stripe).
+- Tenant - a customer organization, not a Unix user.
+
+## Ledger
+The double-entry accounting service,
+not the ledger table.
+Paths: services/ledger/, pkg/ledgerclient/
+
+## Shard map
+Routing table of tenants to database shards.
+Paths: ` + "`db/*.sql`" + `
+`
+
+func TestParseGlossary(t *testing.T) {
+	entries := ParseGlossary(testGlossary)
+	assert.Equal(t, []GlossaryEntry{
+		{Term: "PSP", Definition: "payment service provider (Adyen or Stripe)."},
+		{Term: "Tenant", Definition: "a customer organization, not a Unix user."},
+		{Term: "Ledger", Definition: "The double-entry accounting service, not the ledger table.", Paths: []string{"services/ledger/", "pkg/ledgerclient/"}},
+		{Term: "Shard map", Definition: "Routing table of tenants to database shards.", Paths: []string{"db/*.sql"}},
+	}, entries)
+}
+
+func TestSelectGlossary_Relevance(t *testing.T) {
+	entries := ParseGlossary(testGlossary)
+	terms := func(selected []GlossaryEntry) []string {
+		var names []string
+		for _, e := range selected {
+			names = append(names, e.Term)
+		}
+		return names
+	}
+
+	selected, omitted := SelectGlossary(entries, nil, "", 1000)
+	assert.Equal(t, []string{"PSP", "Tenant"}, terms(selected), "scoped entries wait for a touched file")
+	assert.Equal(t, 2, omitted)
+
+	selected, _ = SelectGlossary(entries, []string{"services/ledger/post.go", "db/shards.sql"}, "", 1000)
+	assert.Equal(t, []string{"PSP", "Tenant", "Ledger", "Shard map"}, terms(selected))
+
+	selected, _ = SelectGlossary(entries, nil, "why does the shard map skip tenants?", 1000)
+	assert.Equal(t, []string{"PSP", "Tenant", "Shard map"}, terms(selected), "a mentioned term is relevant")
+
+	// A budget that fits one entry keeps the relevant one.
+	selected, omitted = SelectGlossary(entries, []string{"pkg/ledgerclient/client.go"}, "", 20)
+	assert.Equal(t, []string{"Ledger"}, terms(selected))
+	assert.Equal(t, 3, omitted)
+
+	selected, _ = SelectGlossary(entries, nil, "", 0)
+	assert.Empty(t, selected)
+}
+
+func TestFormatGlossary(t *testing.T) {
+	out := FormatGlossary([]GlossaryEntry{{Term: "PSP", Definition: "payment service provider."}}, 2)
+	assert.True(t, strings.HasPrefix(out, "<glossary>\n"))
+	assert.Contains(t, out, "\n- PSP: payment service provider.\n")
+	assert.Contains(t, out, "2 more terms are defined in .codex/glossary.md")
+	assert.True(t, strings.HasSuffix(out, "</glossary>"))
+	assert.Empty(t, FormatGlossary(nil, 3))
+}
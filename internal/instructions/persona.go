@@ -0,0 +1,35 @@
+package instructions
+
+import "sort"
+
+// Persona presets tune the assistant's tone and verbosity. Unlike model
+// profiles they never change tools, approvals or sandboxing; the preset text
+// is only appended to the user instructions of each LLM request.
+const (
+	PersonaConcise     = "concise"
+	PersonaExplanatory = "explanatory"
+	PersonaMentor      = "mentor"
+)
+
+var personaPresets = map[string]string{
+	PersonaConcise:     `Response style (persona: concise): keep replies as short as possible. Lead with the result, skip preamble and recaps, and use a few bullets at most. Only explain reasoning when asked or when a decision is surprising.`,
+	PersonaExplanatory: `Response style (persona: explanatory): explain what you are doing and why. Before changing code, briefly describe the approach; afterwards, summarize what changed, the trade-offs considered, and anything the user should verify.`,
+	PersonaMentor:      `Response style (persona: mentor): the user is learning this codebase or language. Explain relevant concepts as you go, point to the files and idioms worth studying, and when a task is small, suggest how the user could do similar changes themselves. Stay encouraging and precise.`,
+}
+
+// PersonaInstructions returns the instruction text of the named persona
+// preset, or false when the name is unknown. The empty name means no persona.
+func PersonaInstructions(name string) (string, bool) {
+	text, ok := personaPresets[name]
+	return text, ok
+}
+
+// PersonaNames returns the preset names, sorted.
+func PersonaNames() []string {
+	names := make([]string, 0, len(personaPresets))
+	for name := range personaPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
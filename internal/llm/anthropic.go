@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
 
@@ -51,20 +52,24 @@ func (c *AnthropicClient) Call(ctx context.Context, request LLMRequest) (LLMResp
 		params.Temperature = anthropic.Float(request.ModelConfig.Temperature)
 	}
 
-	// Add tools if provided
+	// Add tools if provided. Anthropic's built-in bash and text editor
+	// tools replace the specs of the tools they cover (see anthropic_tools.go).
+	native := nativeToolsFor(request.ModelConfig.Model, request.ToolSpecs)
 	if len(request.ToolSpecs) > 0 {
-		toolDefs := c.buildToolDefinitions(request.ToolSpecs)
-		params.Tools = toolDefs
+		toolDefs := c.buildToolDefinitions(native.filterSpecs(request.ToolSpecs))
+		params.Tools = append(native.definitions(), toolDefs...)
 	}
 
 	// Call Anthropic API
-	response, err := c.client.Messages.New(ctx, params)
+	var httpResp *http.Response
+	response, err := c.client.Messages.New(ctx, params, option.WithResponseInto(&httpResp))
 	if err != nil {
 		return LLMResponse{}, classifyAnthropicError(err)
 	}
 
 	// Convert response to our format
 	items, finishReason := c.parseResponse(response)
+	native.fromNative(items)
 
 	return LLMResponse{
 		Items:        items,
@@ -76,6 +81,7 @@ func (c *AnthropicClient) Call(ctx context.Context, request LLMRequest) (LLMResp
 			CachedTokens:        int(response.Usage.CacheReadInputTokens),
 			CacheCreationTokens: int(response.Usage.CacheCreationInputTokens),
 		},
+		RateLimits: anthropicRateLimits(responseHeader(httpResp)),
 	}, nil
 }
 
@@ -155,6 +161,7 @@ func (c *AnthropicClient) buildMessages(request LLMRequest) ([]anthropic.Message
 	if err != nil {
 		return nil, err
 	}
+	nativeToolsFor(request.ModelConfig.Model, request.ToolSpecs).toNative(historyMessages)
 	messages = append(messages, historyMessages...)
 
 	// Add cache breakpoint to the last content block of the penultimate message.
@@ -224,7 +231,7 @@ func (c *AnthropicClient) convertHistoryToMessages(history []models.Conversation
 
 				content = append(content, anthropic.ContentBlockParamUnion{
 					OfToolUse: &anthropic.ToolUseBlockParam{
-						ID:    toolCall.CallID,
+						ID:    anthropicToolUseID(toolCall.CallID),
 						Name:  toolCall.Name,
 						Input: inputMap,
 					},
@@ -255,7 +262,7 @@ func (c *AnthropicClient) convertHistoryToMessages(history []models.Conversation
 
 				content = append(content, anthropic.ContentBlockParamUnion{
 					OfToolUse: &anthropic.ToolUseBlockParam{
-						ID:    toolCall.CallID,
+						ID:    anthropicToolUseID(toolCall.CallID),
 						Name:  toolCall.Name,
 						Input: inputMap,
 					},
@@ -277,7 +284,7 @@ func (c *AnthropicClient) convertHistoryToMessages(history []models.Conversation
 
 			content := []anthropic.ContentBlockParamUnion{{
 				OfToolResult: &anthropic.ToolResultBlockParam{
-					ToolUseID: item.CallID,
+					ToolUseID: anthropicToolUseID(item.CallID),
 					Content: []anthropic.ToolResultBlockParamContentUnion{{
 					OfText: &anthropic.TextBlockParam{
 						Text: item.Output.Content,
@@ -404,7 +411,7 @@ func (c *AnthropicClient) parseResponse(response *anthropic.Message) ([]models.C
 
 			items = append(items, models.ConversationItem{
 				Type:      models.ItemTypeFunctionCall,
-				CallID:    toolBlock.ID,
+				CallID:    models.CanonicalCallID(toolBlock.ID),
 				Name:      toolBlock.Name,
 				Arguments: string(argsJSON),
 			})
@@ -530,7 +537,9 @@ func collectRecentUserMessages(items []models.ConversationItem, tokenBudget int)
 		// Skip compaction markers, turn markers
 		if item.Type == models.ItemTypeCompaction ||
 			item.Type == models.ItemTypeTurnStarted ||
-			item.Type == models.ItemTypeTurnComplete {
+			item.Type == models.ItemTypeTurnComplete ||
+			item.Type == models.ItemTypeTurnFailure ||
+			item.Type == models.ItemTypeNotice {
 			continue
 		}
 
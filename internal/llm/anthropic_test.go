@@ -390,3 +390,48 @@ func TestCall_CachedTokensReported(t *testing.T) {
 	assert.Equal(t, 20, resp.TokenUsage.PromptTokens)
 	assert.Equal(t, 5, resp.TokenUsage.CompletionTokens)
 }
+
+// TestCallIDs_ReplayAcrossProviders verifies that history recorded under
+// either provider, including IDs neither provider would issue, maps to the
+// same call IDs on both wire formats with calls and outputs still paired.
+func TestCallIDs_ReplayAcrossProviders(t *testing.T) {
+	ok := true
+	history := []models.ConversationItem{
+		{Type: models.ItemTypeUserMessage, Content: "go"},
+		{Type: models.ItemTypeFunctionCall, CallID: "call_openai1", Name: "shell", Arguments: `{}`},
+		{Type: models.ItemTypeFunctionCallOutput, CallID: "call_openai1", Output: &models.FunctionCallOutputPayload{Content: "a", Success: &ok}},
+		{Type: models.ItemTypeFunctionCall, CallID: "toolu_01anthropic", Name: "shell", Arguments: `{}`},
+		{Type: models.ItemTypeFunctionCallOutput, CallID: "toolu_01anthropic", Output: &models.FunctionCallOutputPayload{Content: "b", Success: &ok}},
+		{Type: models.ItemTypeFunctionCall, CallID: "agent:1/call.2", Name: "shell", Arguments: `{}`},
+		{Type: models.ItemTypeFunctionCallOutput, CallID: "agent:1/call.2", Output: &models.FunctionCallOutputPayload{Content: "c", Success: &ok}},
+	}
+	want := []string{"call_openai1", "toolu_01anthropic", models.CanonicalCallID("agent:1/call.2")}
+
+	messages, err := (&AnthropicClient{}).convertHistoryToMessages(history)
+	require.NoError(t, err)
+	var toolUses, toolResults []string
+	for _, m := range messages {
+		for _, block := range m.Content {
+			if block.OfToolUse != nil {
+				toolUses = append(toolUses, block.OfToolUse.ID)
+			}
+			if block.OfToolResult != nil {
+				toolResults = append(toolResults, block.OfToolResult.ToolUseID)
+			}
+		}
+	}
+	assert.Equal(t, want, toolUses)
+	assert.Equal(t, want, toolResults)
+
+	var calls, outputs []string
+	for _, item := range (&OpenAIClient{}).buildInput(history) {
+		if item.OfFunctionCall != nil {
+			calls = append(calls, item.OfFunctionCall.CallID)
+		}
+		if item.OfFunctionCallOutput != nil {
+			outputs = append(outputs, item.OfFunctionCallOutput.CallID)
+		}
+	}
+	assert.Equal(t, want, calls)
+	assert.Equal(t, want, outputs)
+}
@@ -0,0 +1,181 @@
+package llm
+
+import (
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/tools"
+)
+
+// Anthropic's built-in bash and text editor tools. Claude is trained on
+// their schemas, so a request that offers shell_command advertises the bash
+// tool in its place, and one that offers read_file, write_file and
+// apply_patch advertises the text editor in place of all three. Calls to
+// them are translated into calls to our tools when the response is parsed,
+// and back into the native shape when history is replayed, so the rest of
+// the harness (approval, exec policy, handlers) only sees our tools.
+
+// Names of Anthropic's built-in tools, as they appear in tool_use blocks.
+const (
+	anthropicBashTool         = "bash"
+	anthropicEditorTool       = "str_replace_based_edit_tool" // text_editor_20250728 (Claude 4)
+	anthropicLegacyEditorTool = "str_replace_editor"          // text_editor_20250124 (Claude 3.7)
+)
+
+// anthropicEditedTools are the tools the text editor replaces.
+var anthropicEditedTools = []string{"read_file", "write_file", "apply_patch"}
+
+// anthropicNativeTools is the set of built-in tools advertised in one
+// request. The zero value advertises none.
+type anthropicNativeTools struct {
+	bash   bool
+	editor string // name of the text editor tool, "" when not advertised
+}
+
+// nativeToolsFor returns the built-in tools a request to model with specs
+// advertises. Claude 3 models before 3.7 have none.
+func nativeToolsFor(model string, specs []tools.ToolSpec) anthropicNativeTools {
+	legacy := strings.Contains(model, "3.7") || strings.Contains(model, "3-7")
+	if strings.HasPrefix(model, "claude-3") && !legacy {
+		return anthropicNativeTools{}
+	}
+	offered := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		if spec.RawJSONSchema == nil {
+			offered[spec.Name] = true
+		}
+	}
+
+	var n anthropicNativeTools
+	n.bash = offered["shell_command"]
+	n.editor = anthropicEditorTool
+	if legacy {
+		n.editor = anthropicLegacyEditorTool
+	}
+	for _, name := range anthropicEditedTools {
+		if !offered[name] {
+			n.editor = ""
+		}
+	}
+	return n
+}
+
+// replaces reports whether the spec of our tool name is advertised as a
+// built-in tool instead.
+func (n anthropicNativeTools) replaces(name string) bool {
+	if name == "shell_command" {
+		return n.bash
+	}
+	for _, edited := range anthropicEditedTools {
+		if name == edited {
+			return n.editor != ""
+		}
+	}
+	return false
+}
+
+// isNative reports whether name is one of the built-in tools advertised.
+func (n anthropicNativeTools) isNative(name string) bool {
+	return (n.bash && name == anthropicBashTool) || (n.editor != "" && name == n.editor)
+}
+
+// filterSpecs returns specs without the ones advertised as built-in tools.
+func (n anthropicNativeTools) filterSpecs(specs []tools.ToolSpec) []tools.ToolSpec {
+	filtered := make([]tools.ToolSpec, 0, len(specs))
+	for _, spec := range specs {
+		if spec.RawJSONSchema != nil || !n.replaces(spec.Name) {
+			filtered = append(filtered, spec)
+		}
+	}
+	return filtered
+}
+
+// definitions returns the tool definitions of the built-in tools.
+func (n anthropicNativeTools) definitions() []anthropic.ToolUnionParam {
+	var defs []anthropic.ToolUnionParam
+	if n.bash {
+		defs = append(defs, anthropic.ToolUnionParam{OfBashTool20250124: &anthropic.ToolBash20250124Param{}})
+	}
+	switch n.editor {
+	case anthropicEditorTool:
+		defs = append(defs, anthropic.ToolUnionParam{OfTextEditor20250728: &anthropic.ToolTextEditor20250728Param{}})
+	case anthropicLegacyEditorTool:
+		defs = append(defs, anthropic.ToolUnionParam{OfTextEditor20250124: &anthropic.ToolTextEditor20250124Param{}})
+	}
+	return defs
+}
+
+// fromNative rewrites calls to built-in tools in items into calls to our
+// tools. A call that cannot be translated (e.g. the editor's insert
+// command) keeps its native name, and fails in dispatch as an unknown tool.
+func (n anthropicNativeTools) fromNative(items []models.ConversationItem) {
+	for i := range items {
+		item := &items[i]
+		if item.Type != models.ItemTypeFunctionCall || !n.isNative(item.Name) {
+			continue
+		}
+		target, args, ok := tools.ResolveToolAlias(item.Name, item.Arguments, nil, n.replaces)
+		if !ok {
+			continue
+		}
+		item.Name = target
+		item.Arguments = args
+	}
+}
+
+// toNative rewrites the tool_use blocks of replayed calls to our tools into
+// calls to the built-in tools that replace them, so the model sees its own
+// calls in the shape it made them. Calls with no native equivalent (an
+// apply_patch that is not a single replacement) are left unchanged.
+func (n anthropicNativeTools) toNative(messages []anthropic.MessageParam) {
+	for _, msg := range messages {
+		for _, block := range msg.Content {
+			use := block.OfToolUse
+			if use == nil || !n.replaces(use.Name) {
+				continue
+			}
+			args, _ := use.Input.(map[string]interface{})
+			if name, input, ok := n.nativeCall(use.Name, args); ok {
+				use.Name = name
+				use.Input = input
+			}
+		}
+	}
+}
+
+// nativeCall maps a call to one of our tools onto the built-in tool that
+// replaces it.
+func (n anthropicNativeTools) nativeCall(name string, args map[string]interface{}) (string, map[string]interface{}, bool) {
+	str := func(key string) string {
+		s, _ := args[key].(string)
+		return s
+	}
+	switch name {
+	case "shell_command":
+		if str("command") == "" {
+			return "", nil, false
+		}
+		return anthropicBashTool, map[string]interface{}{"command": str("command")}, true
+	case "read_file":
+		input := map[string]interface{}{"command": "view", "path": str("file_path")}
+		if offset, ok := args["offset"].(float64); ok && offset >= 1 {
+			end := float64(-1)
+			if limit, ok := args["limit"].(float64); ok && limit >= 1 {
+				end = offset + limit - 1
+			}
+			input["view_range"] = []interface{}{offset, end}
+		}
+		return n.editor, input, str("file_path") != ""
+	case "write_file":
+		return n.editor, map[string]interface{}{"command": "create", "path": str("path"), "file_text": str("content")}, str("path") != ""
+	case "apply_patch":
+		path, oldStr, newStr, ok := tools.ParseReplacementPatch(str("input"))
+		if !ok {
+			return "", nil, false
+		}
+		return n.editor, map[string]interface{}{"command": "str_replace", "path": path, "old_str": oldStr, "new_str": newStr}, true
+	}
+	return "", nil, false
+}
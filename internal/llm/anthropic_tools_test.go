@@ -0,0 +1,128 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/tools"
+)
+
+func nativeTestSpecs() []tools.ToolSpec {
+	return []tools.ToolSpec{
+		tools.NewShellCommandToolSpec(false),
+		tools.NewReadFileToolSpec(),
+		tools.NewWriteFileToolSpec(),
+		tools.NewApplyPatchToolSpec(),
+		tools.NewListDirToolSpec(),
+	}
+}
+
+// TestCall_NativeTools verifies that a request advertises Anthropic's bash
+// and text editor tools in place of the tools they cover, and that calls to
+// them come back as calls to our tools.
+func TestCall_NativeTools(t *testing.T) {
+	var capturedBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(body, &capturedBody))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{
+			"id": "msg_test123", "type": "message", "role": "assistant",
+			"model": "claude-sonnet-4-5",
+			"content": [
+				{"type": "tool_use", "id": "toolu_01", "name": "bash", "input": {"command": "ls"}},
+				{"type": "tool_use", "id": "toolu_02", "name": "str_replace_based_edit_tool",
+				 "input": {"command": "view", "path": "main.go"}},
+				{"type": "tool_use", "id": "toolu_03", "name": "str_replace_based_edit_tool",
+				 "input": {"command": "insert", "path": "main.go", "insert_line": 1, "new_str": "x"}}
+			],
+			"stop_reason": "tool_use", "stop_sequence": null,
+			"usage": {"input_tokens": 10, "output_tokens": 5}
+		}`)
+	}))
+	defer server.Close()
+
+	c := &AnthropicClient{client: anthropic.NewClient(option.WithBaseURL(server.URL), option.WithAPIKey("test-key"))}
+	resp, err := c.Call(context.Background(), LLMRequest{
+		ModelConfig: models.ModelConfig{Model: "claude-sonnet-4.5", MaxTokens: 1024},
+		History:     []models.ConversationItem{{Type: models.ItemTypeUserMessage, Content: "look around"}},
+		ToolSpecs:   nativeTestSpecs(),
+	})
+	require.NoError(t, err)
+
+	var advertised []string
+	for _, raw := range capturedBody["tools"].([]interface{}) {
+		def := raw.(map[string]interface{})
+		kind, _ := def["type"].(string)
+		advertised = append(advertised, fmt.Sprintf("%s:%s", def["name"], kind))
+	}
+	assert.Equal(t, []string{"bash:bash_20250124", "str_replace_based_edit_tool:text_editor_20250728", "list_dir:"}, advertised)
+
+	require.Len(t, resp.Items, 3)
+	assert.Equal(t, "shell_command", resp.Items[0].Name)
+	assert.JSONEq(t, `{"command": "ls"}`, resp.Items[0].Arguments)
+	assert.Equal(t, "read_file", resp.Items[1].Name)
+	assert.JSONEq(t, `{"file_path": "main.go"}`, resp.Items[1].Arguments)
+	assert.Equal(t, "str_replace_based_edit_tool", resp.Items[2].Name, "insert has no equivalent")
+}
+
+// TestBuildMessages_ReplaysNativeCalls verifies that replayed calls to the
+// covered tools are sent in the built-in tools' shape.
+func TestBuildMessages_ReplaysNativeCalls(t *testing.T) {
+	ok := true
+	output := &models.FunctionCallOutputPayload{Content: "done", Success: &ok}
+	patch := "*** Begin Patch\n*** Update File: a.go\n@@\n-x := 1\n+x := 2\n*** End Patch"
+	req := LLMRequest{
+		ModelConfig: models.ModelConfig{Model: "claude-sonnet-4.5"},
+		ToolSpecs:   nativeTestSpecs(),
+		History: []models.ConversationItem{
+			{Type: models.ItemTypeUserMessage, Content: "fix it"},
+			{Type: models.ItemTypeFunctionCall, CallID: "c1", Name: "shell_command", Arguments: `{"command": "go test", "workdir": "/repo"}`},
+			{Type: models.ItemTypeFunctionCallOutput, CallID: "c1", Output: output},
+			{Type: models.ItemTypeFunctionCall, CallID: "c2", Name: "read_file", Arguments: `{"file_path": "a.go", "offset": 3, "limit": 4}`},
+			{Type: models.ItemTypeFunctionCallOutput, CallID: "c2", Output: output},
+			{Type: models.ItemTypeFunctionCall, CallID: "c3", Name: "apply_patch", Arguments: fmt.Sprintf(`{"input": %q}`, patch)},
+			{Type: models.ItemTypeFunctionCallOutput, CallID: "c3", Output: output},
+			{Type: models.ItemTypeFunctionCall, CallID: "c4", Name: "apply_patch", Arguments: `{"input": "*** Begin Patch\n*** Add File: b.go\n+package b\n*** End Patch"}`},
+			{Type: models.ItemTypeFunctionCallOutput, CallID: "c4", Output: output},
+		},
+	}
+
+	messages, err := (&AnthropicClient{}).buildMessages(req)
+	require.NoError(t, err)
+	var uses []*anthropic.ToolUseBlockParam
+	for _, m := range messages {
+		for _, block := range m.Content {
+			if block.OfToolUse != nil {
+				uses = append(uses, block.OfToolUse)
+			}
+		}
+	}
+	require.Len(t, uses, 4)
+	assert.Equal(t, "bash", uses[0].Name)
+	assert.Equal(t, map[string]interface{}{"command": "go test"}, uses[0].Input)
+	assert.Equal(t, "str_replace_based_edit_tool", uses[1].Name)
+	assert.Equal(t, map[string]interface{}{"command": "view", "path": "a.go", "view_range": []interface{}{float64(3), float64(6)}}, uses[1].Input)
+	assert.Equal(t, map[string]interface{}{"command": "str_replace", "path": "a.go", "old_str": "x := 1", "new_str": "x := 2"}, uses[2].Input)
+	assert.Equal(t, "apply_patch", uses[3].Name, "a patch that is not a replacement stays as is")
+}
+
+func TestNativeToolsFor(t *testing.T) {
+	specs := nativeTestSpecs()
+	assert.Equal(t, anthropicNativeTools{bash: true, editor: anthropicEditorTool}, nativeToolsFor("claude-opus-4-6", specs))
+	assert.Equal(t, anthropicNativeTools{bash: true, editor: anthropicLegacyEditorTool}, nativeToolsFor("claude-3.7-sonnet-20250219", specs))
+	assert.Equal(t, anthropicNativeTools{}, nativeToolsFor("claude-3.5-haiku-20241022", specs))
+	assert.Equal(t, anthropicNativeTools{bash: true}, nativeToolsFor("claude-sonnet-4.5", specs[:2]), "editor needs all three file tools")
+}
@@ -0,0 +1,19 @@
+package llm
+
+import "github.com/mfateev/temporal-agent-harness/internal/models"
+
+// Provider mapping layers for function-call IDs. History stores canonical
+// IDs (see models.CanonicalCallID); each client maps them to its wire format
+// when building a request and canonicalizes the IDs it receives, so a
+// session can continue on another provider after a model switch.
+
+// openAICallID maps a history call ID to a Responses API call_id.
+func openAICallID(id string) string {
+	return models.CanonicalCallID(id)
+}
+
+// anthropicToolUseID maps a history call ID to an Anthropic tool_use ID,
+// which must match ^[a-zA-Z0-9_-]+$.
+func anthropicToolUseID(id string) string {
+	return models.CanonicalCallID(id)
+}
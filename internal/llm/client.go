@@ -44,6 +44,10 @@ type LLMResponse struct {
 
 	// OpenAI Responses API: response ID for chaining via PreviousResponseID
 	ResponseID string `json:"response_id,omitempty"`
+
+	// RateLimits is the provider's rate-limit headroom after this call,
+	// from the response headers; nil when the provider sent none.
+	RateLimits *models.RateLimitSnapshot `json:"rate_limits,omitempty"`
 }
 
 // CompactRequest represents a request to compact conversation history.
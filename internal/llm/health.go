@@ -0,0 +1,101 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// DefaultHealthWindow is how far back HealthTracker looks when computing
+// a provider's error rate.
+const DefaultHealthWindow = 5 * time.Minute
+
+// maxHealthSamples bounds the samples kept per provider.
+const maxHealthSamples = 500
+
+type healthSample struct {
+	at     time.Time
+	failed bool
+}
+
+// HealthTracker keeps a rolling record of LLM call outcomes per provider,
+// so the workflow can route new turns away from a provider that is failing.
+// Outcomes are classified from the errors returned by the clients'
+// classifyError: transient errors and rate limits count as failures,
+// while context overflows and fatal (invalid request) errors show the
+// provider answered and count as successes. Cancellations are ignored.
+type HealthTracker struct {
+	mu      sync.Mutex
+	window  time.Duration
+	now     func() time.Time
+	samples map[string][]healthSample
+}
+
+// NewHealthTracker creates a tracker over the given rolling window.
+func NewHealthTracker(window time.Duration) *HealthTracker {
+	return &HealthTracker{
+		window:  window,
+		now:     time.Now,
+		samples: make(map[string][]healthSample),
+	}
+}
+
+// Record adds the outcome of one call to provider.
+func (h *HealthTracker) Record(provider string, err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	if provider == "" {
+		provider = "openai"
+	}
+	failed := false
+	if err != nil {
+		var activityErr *models.ActivityError
+		failed = !errors.As(err, &activityErr) ||
+			activityErr.Type == models.ErrorTypeTransient ||
+			activityErr.Type == models.ErrorTypeAPILimit
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	samples := append(h.prune(h.samples[provider]), healthSample{at: h.now(), failed: failed})
+	if len(samples) > maxHealthSamples {
+		samples = samples[len(samples)-maxHealthSamples:]
+	}
+	h.samples[provider] = samples
+}
+
+// Snapshot returns the health of every provider with calls in the window.
+func (h *HealthTracker) Snapshot() map[string]models.ProviderHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make(map[string]models.ProviderHealth, len(h.samples))
+	for provider, samples := range h.samples {
+		samples = h.prune(samples)
+		h.samples[provider] = samples
+		if len(samples) == 0 {
+			continue
+		}
+		health := models.ProviderHealth{Requests: len(samples)}
+		for _, s := range samples {
+			if s.failed {
+				health.Errors++
+			}
+		}
+		out[provider] = health
+	}
+	return out
+}
+
+// prune drops samples older than the window. Callers hold mu.
+func (h *HealthTracker) prune(samples []healthSample) []healthSample {
+	cutoff := h.now().Add(-h.window)
+	i := 0
+	for i < len(samples) && samples[i].at.Before(cutoff) {
+		i++
+	}
+	return samples[i:]
+}
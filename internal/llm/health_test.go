@@ -0,0 +1,36 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+func TestHealthTracker(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	h := NewHealthTracker(time.Minute)
+	h.now = func() time.Time { return now }
+
+	h.Record("openai", models.NewTransientError("502"))
+	h.Record("openai", models.NewAPILimitError("429"))
+	h.Record("openai", models.NewFatalError("400"))
+	h.Record("openai", nil)
+	h.Record("openai", context.Canceled)
+	h.Record("", errors.New("connection reset"))
+	h.Record("anthropic", nil)
+
+	snap := h.Snapshot()
+	assert.Equal(t, models.ProviderHealth{Requests: 5, Errors: 3}, snap["openai"])
+	assert.Equal(t, models.ProviderHealth{Requests: 1}, snap["anthropic"])
+
+	now = now.Add(2 * time.Minute)
+	h.Record("anthropic", nil)
+	snap = h.Snapshot()
+	assert.NotContains(t, snap, "openai", "samples outside the window are dropped")
+	assert.Equal(t, models.ProviderHealth{Requests: 1}, snap["anthropic"])
+}
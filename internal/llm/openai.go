@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/mfateev/temporal-agent-harness/internal/models"
 	"github.com/mfateev/temporal-agent-harness/internal/tools"
@@ -82,7 +84,8 @@ func (c *OpenAIClient) Call(ctx context.Context, request LLMRequest) (LLMRespons
 	// Store for response persistence
 	params.Store = param.NewOpt(true)
 
-	resp, err := c.client.Responses.New(ctx, params)
+	var httpResp *http.Response
+	resp, err := c.client.Responses.New(ctx, params, option.WithResponseInto(&httpResp))
 	if err != nil {
 		return LLMResponse{}, classifyError(err)
 	}
@@ -99,6 +102,7 @@ func (c *OpenAIClient) Call(ctx context.Context, request LLMRequest) (LLMRespons
 			TotalTokens:      int(resp.Usage.TotalTokens),
 			CachedTokens:     int(resp.Usage.InputTokensDetails.CachedTokens),
 		},
+		RateLimits: openAIRateLimits(responseHeader(httpResp), time.Now()),
 	}, nil
 }
 
@@ -143,7 +147,7 @@ func (c *OpenAIClient) buildInput(history []models.ConversationItem) []responses
 		case models.ItemTypeFunctionCall:
 			items = append(items, responses.ResponseInputItemUnionParam{
 				OfFunctionCall: &responses.ResponseFunctionToolCallParam{
-					CallID:    item.CallID,
+					CallID:    openAICallID(item.CallID),
 					Name:      item.Name,
 					Arguments: item.Arguments,
 				},
@@ -156,7 +160,7 @@ func (c *OpenAIClient) buildInput(history []models.ConversationItem) []responses
 			}
 			items = append(items, responses.ResponseInputItemUnionParam{
 				OfFunctionCallOutput: &responses.ResponseInputItemFunctionCallOutputParam{
-					CallID: item.CallID,
+					CallID: openAICallID(item.CallID),
 					Output: responses.ResponseInputItemFunctionCallOutputOutputUnionParam{
 						OfString: param.NewOpt(content),
 					},
@@ -255,7 +259,7 @@ func (c *OpenAIClient) parseOutput(resp *responses.Response) ([]models.Conversat
 			hasFunctionCalls = true
 			items = append(items, models.ConversationItem{
 				Type:      models.ItemTypeFunctionCall,
-				CallID:    outputItem.CallID,
+				CallID:    models.CanonicalCallID(outputItem.CallID),
 				Name:      outputItem.Name,
 				Arguments: outputItem.Arguments,
 			})
@@ -282,7 +286,12 @@ func (c *OpenAIClient) parseOutput(resp *responses.Response) ([]models.Conversat
 	}
 
 	finishReason := models.FinishReasonStop
-	if hasFunctionCalls {
+	switch {
+	case resp.Status == responses.ResponseStatusIncomplete && resp.IncompleteDetails.Reason == "max_output_tokens":
+		finishReason = models.FinishReasonLength
+	case resp.Status == responses.ResponseStatusIncomplete && resp.IncompleteDetails.Reason == "content_filter":
+		finishReason = models.FinishReasonContentFilter
+	case hasFunctionCalls:
 		finishReason = models.FinishReasonToolCalls
 	}
 
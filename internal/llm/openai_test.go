@@ -295,6 +295,31 @@ func TestParseOutput_Message(t *testing.T) {
 	assert.Equal(t, models.FinishReasonStop, finishReason)
 }
 
+// TestParseOutput_MaxOutputTokens verifies an incomplete response truncated by
+// the output token limit maps to FinishReasonLength.
+func TestParseOutput_MaxOutputTokens(t *testing.T) {
+	client := &OpenAIClient{}
+	resp := &responses.Response{
+		ID:                "resp_789",
+		Status:            responses.ResponseStatusIncomplete,
+		IncompleteDetails: responses.ResponseIncompleteDetails{Reason: "max_output_tokens"},
+		Output: []responses.ResponseOutputItemUnion{
+			{
+				Type: "message",
+				Content: []responses.ResponseOutputMessageContentUnion{
+					{Type: "output_text", Text: "```go\nfunc main() {"},
+				},
+			},
+		},
+	}
+
+	items, finishReason := client.parseOutput(resp)
+
+	require.Len(t, items, 1)
+	assert.Equal(t, "```go\nfunc main() {", items[0].Content)
+	assert.Equal(t, models.FinishReasonLength, finishReason)
+}
+
 // TestParseOutput_FunctionCalls verifies ResponseFunctionToolCall → ConversationItem.
 func TestParseOutput_FunctionCalls(t *testing.T) {
 	client := &OpenAIClient{}
@@ -0,0 +1,81 @@
+package llm
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// openAIRateLimits reads OpenAI's x-ratelimit-* headers. OpenAI reports
+// resets as durations ("6m0s", "20ms"); they are converted to timestamps
+// relative to now. Returns nil when the headers are absent.
+func openAIRateLimits(h http.Header, now time.Time) *models.RateLimitSnapshot {
+	window := func(kind string) *models.RateLimitWindow {
+		w := &models.RateLimitWindow{
+			Limit:     headerInt(h, "x-ratelimit-limit-"+kind),
+			Remaining: headerInt(h, "x-ratelimit-remaining-"+kind),
+		}
+		if d, err := time.ParseDuration(h.Get("x-ratelimit-reset-" + kind)); err == nil {
+			reset := now.Add(d).UTC().Format(time.RFC3339Nano)
+			w.Reset = &reset
+		}
+		return nonEmptyWindow(w)
+	}
+	return snapshotOf(window("requests"), window("tokens"))
+}
+
+// anthropicRateLimits reads Anthropic's anthropic-ratelimit-* headers,
+// whose resets are already RFC 3339 timestamps. The combined token window
+// is preferred; input tokens are used when it is absent, since the prompt
+// is what grows with the session. Returns nil when the headers are absent.
+func anthropicRateLimits(h http.Header) *models.RateLimitSnapshot {
+	window := func(kind string) *models.RateLimitWindow {
+		w := &models.RateLimitWindow{
+			Limit:     headerInt(h, "anthropic-ratelimit-"+kind+"-limit"),
+			Remaining: headerInt(h, "anthropic-ratelimit-"+kind+"-remaining"),
+		}
+		if reset := h.Get("anthropic-ratelimit-" + kind + "-reset"); reset != "" {
+			if _, err := time.Parse(time.RFC3339, reset); err == nil {
+				w.Reset = &reset
+			}
+		}
+		return nonEmptyWindow(w)
+	}
+	tokens := window("tokens")
+	if tokens == nil {
+		tokens = window("input-tokens")
+	}
+	return snapshotOf(window("requests"), tokens)
+}
+
+// responseHeader returns the headers of r, or nil when there is no response.
+func responseHeader(r *http.Response) http.Header {
+	if r == nil {
+		return nil
+	}
+	return r.Header
+}
+
+func headerInt(h http.Header, key string) *int {
+	n, err := strconv.Atoi(h.Get(key))
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+func nonEmptyWindow(w *models.RateLimitWindow) *models.RateLimitWindow {
+	if w.Limit == nil && w.Remaining == nil && w.Reset == nil {
+		return nil
+	}
+	return w
+}
+
+func snapshotOf(requests, tokens *models.RateLimitWindow) *models.RateLimitSnapshot {
+	if requests == nil && tokens == nil {
+		return nil
+	}
+	return &models.RateLimitSnapshot{Requests: requests, Tokens: tokens}
+}
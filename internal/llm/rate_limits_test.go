@@ -0,0 +1,52 @@
+package llm
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAIRateLimits(t *testing.T) {
+	h := http.Header{}
+	h.Set("x-ratelimit-limit-requests", "500")
+	h.Set("x-ratelimit-remaining-requests", "499")
+	h.Set("x-ratelimit-reset-requests", "120ms")
+	h.Set("x-ratelimit-limit-tokens", "30000")
+	h.Set("x-ratelimit-remaining-tokens", "1200")
+	h.Set("x-ratelimit-reset-tokens", "6m0s")
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	rl := openAIRateLimits(h, now)
+	require.NotNil(t, rl)
+	assert.Equal(t, 499, *rl.Requests.Remaining)
+	assert.Equal(t, 500, *rl.Requests.Limit)
+	assert.Equal(t, "2026-03-01T12:00:00.12Z", *rl.Requests.Reset)
+	assert.Equal(t, 1200, *rl.Tokens.Remaining)
+	reset, ok := rl.Tokens.ResetAt()
+	require.True(t, ok)
+	assert.Equal(t, now.Add(6*time.Minute), reset)
+}
+
+func TestAnthropicRateLimits(t *testing.T) {
+	h := http.Header{}
+	h.Set("anthropic-ratelimit-requests-limit", "50")
+	h.Set("anthropic-ratelimit-requests-remaining", "0")
+	h.Set("anthropic-ratelimit-requests-reset", "2026-03-01T12:00:30Z")
+	h.Set("anthropic-ratelimit-input-tokens-remaining", "8000")
+	h.Set("anthropic-ratelimit-input-tokens-reset", "not a time")
+
+	rl := anthropicRateLimits(h)
+	require.NotNil(t, rl)
+	assert.Equal(t, 0, *rl.Requests.Remaining)
+	assert.Equal(t, "2026-03-01T12:00:30Z", *rl.Requests.Reset)
+	assert.Equal(t, 8000, *rl.Tokens.Remaining)
+	assert.Nil(t, rl.Tokens.Reset)
+}
+
+func TestRateLimits_NoHeaders(t *testing.T) {
+	assert.Nil(t, openAIRateLimits(nil, time.Now()))
+	assert.Nil(t, anthropicRateLimits(http.Header{"Content-Type": {"application/json"}}))
+}
@@ -0,0 +1,39 @@
+package llm
+
+import (
+	"context"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// ScriptedClient is an offline LLMClient that answers from a script instead
+// of a provider API. It backs `tcx demo`, which needs no API keys.
+type ScriptedClient struct {
+	// Respond returns the response to a request, typically chosen from the
+	// history the request carries.
+	Respond func(request LLMRequest) LLMResponse
+
+	// Summarize returns the summary that replaces the compacted history.
+	Summarize func(input []models.ConversationItem) string
+}
+
+// Call returns the scripted response.
+func (c *ScriptedClient) Call(ctx context.Context, request LLMRequest) (LLMResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return LLMResponse{}, err
+	}
+	return c.Respond(request), nil
+}
+
+// Compact replaces the history with the scripted summary and the recent user
+// messages, like the local compaction of the real providers.
+func (c *ScriptedClient) Compact(ctx context.Context, request CompactRequest) (CompactResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return CompactResponse{}, err
+	}
+	summary := c.Summarize(request.Input)
+	return CompactResponse{
+		Items:      buildCompactedHistory(summary, collectRecentUserMessages(request.Input, 20_000)),
+		TokenUsage: models.TokenUsage{PromptTokens: 400, CompletionTokens: 80, TotalTokens: 480},
+	}, nil
+}
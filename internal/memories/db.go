@@ -142,3 +142,26 @@ func (m *MemoryDB) GetStage1Output(workflowID string) (*Stage1Output, error) {
 	}
 	return &o, nil
 }
+
+// DeleteStage1Output removes the stage-1 output for a workflow. Deleting a
+// missing row is not an error. Returns whether a row was deleted.
+func (m *MemoryDB) DeleteStage1Output(workflowID string) (bool, error) {
+	res, err := m.db.Exec(`DELETE FROM stage1_outputs WHERE workflow_id = ?`, workflowID)
+	if err != nil {
+		return false, fmt.Errorf("memories: delete stage1_output: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// DeleteStage1OutputsBefore removes stage-1 outputs whose source was last
+// updated before cutoff (epoch seconds), enforcing the retention policy.
+// Returns the number of rows deleted.
+func (m *MemoryDB) DeleteStage1OutputsBefore(cutoff int64) (int64, error) {
+	res, err := m.db.Exec(`DELETE FROM stage1_outputs WHERE source_updated_at < ?`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("memories: delete stale stage1_outputs: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n, nil
+}
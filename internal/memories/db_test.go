@@ -215,3 +215,39 @@ func TestMultipleWorkflows(t *testing.T) {
 		assert.Equal(t, "memory "+id, got.RawMemory)
 	}
 }
+
+func TestDeleteStage1Output(t *testing.T) {
+	db := tempDB(t)
+	require.NoError(t, db.UpsertStage1Output(Stage1Output{WorkflowID: "wf-1", SourceUpdatedAt: 1000}))
+
+	ok, err := db.DeleteStage1Output("wf-1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	got, err := db.GetStage1Output("wf-1")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	ok, err = db.DeleteStage1Output("wf-1")
+	require.NoError(t, err)
+	assert.False(t, ok, "deleting a missing row is not an error")
+}
+
+func TestDeleteStage1OutputsBefore(t *testing.T) {
+	db := tempDB(t)
+	for i, ts := range []int64{100, 200, 300} {
+		require.NoError(t, db.UpsertStage1Output(Stage1Output{
+			WorkflowID:      fmt.Sprintf("wf-%d", i),
+			SourceUpdatedAt: ts,
+		}))
+	}
+
+	n, err := db.DeleteStage1OutputsBefore(250)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	remaining, err := db.ListStage1OutputsForGlobal(10)
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	assert.Equal(t, "wf-2", remaining[0].WorkflowID)
+}
@@ -67,7 +67,9 @@ func shouldIncludeForMemory(item models.ConversationItem) bool {
 	case models.ItemTypeTurnStarted,
 		models.ItemTypeTurnComplete,
 		models.ItemTypeCompaction,
-		models.ItemTypeModelSwitch:
+		models.ItemTypeModelSwitch,
+		models.ItemTypeTurnFailure,
+		models.ItemTypeNotice:
 		return false
 	default:
 		return false
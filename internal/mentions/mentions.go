@@ -0,0 +1,99 @@
+// Package mentions parses and resolves @path file mentions in user input.
+//
+// A mention is "@" followed by a path, at the start of the input or after
+// whitespace (so e-mail addresses are not matched). Forms:
+//
+//	@path/to/file.go        whole file
+//	@path/to/file.go:42     single line
+//	@path/to/file.go:10-80  line range (inclusive)
+//	@path/to/dir/           directory listing
+//
+// Resolved mentions are attached to the turn as context so the model does
+// not need a read_file round-trip.
+package mentions
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// mentionPattern matches @path tokens preceded by start-of-text or whitespace.
+var mentionPattern = regexp.MustCompile(`(^|\s)@([^\s@]+)`)
+
+// rangePattern matches an optional :start or :start-end suffix.
+var rangePattern = regexp.MustCompile(`^(.+?):(\d+)(?:-(\d+))?$`)
+
+// trailingPunct is stripped from the end of a mention ("see @main.go.").
+const trailingPunct = ".,;:!?)]}'\""
+
+// FileMention is a single parsed @path mention.
+type FileMention struct {
+	Raw       string `json:"raw"`                  // Mention as typed, without the "@"
+	Path      string `json:"path"`                 // Path without line range
+	StartLine int    `json:"start_line,omitempty"` // 1-indexed, 0 = from start
+	EndLine   int    `json:"end_line,omitempty"`   // 1-indexed inclusive, 0 = to end
+	IsDir     bool   `json:"is_dir,omitempty"`     // Trailing "/" requests a listing
+}
+
+// ParseFileMentions extracts @path mentions from user input.
+// Returns unique mentions (by Raw) in order of first appearance.
+func ParseFileMentions(text string) []FileMention {
+	matches := mentionPattern.FindAllStringSubmatch(text, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var out []FileMention
+	for _, m := range matches {
+		raw := trimMention(m[2])
+		if raw == "" || seen[raw] {
+			continue
+		}
+		seen[raw] = true
+		out = append(out, parseMention(raw))
+	}
+	return out
+}
+
+// HighlightFileMentions rewrites each @path mention in text with style
+// applied to the "@path" token, leaving the rest of the text untouched.
+func HighlightFileMentions(text string, style func(string) string) string {
+	return mentionPattern.ReplaceAllStringFunc(text, func(match string) string {
+		// Preserve the leading whitespace captured by the pattern.
+		at := strings.IndexByte(match, '@')
+		token := match[at:]
+		trimmed := "@" + trimMention(token[1:])
+		if trimmed == "@" {
+			return match
+		}
+		return match[:at] + style(trimmed) + token[len(trimmed):]
+	})
+}
+
+// trimMention strips trailing sentence punctuation from a raw mention.
+func trimMention(raw string) string {
+	return strings.TrimRight(raw, trailingPunct)
+}
+
+// parseMention splits a raw mention into path and optional line range.
+func parseMention(raw string) FileMention {
+	fm := FileMention{Raw: raw, Path: raw}
+	if m := rangePattern.FindStringSubmatch(raw); m != nil {
+		start, _ := strconv.Atoi(m[2])
+		end := start
+		if m[3] != "" {
+			end, _ = strconv.Atoi(m[3])
+		}
+		if start > 0 && end >= start {
+			fm.Path = m[1]
+			fm.StartLine = start
+			fm.EndLine = end
+		}
+	}
+	if strings.HasSuffix(fm.Path, "/") {
+		fm.IsDir = true
+	}
+	return fm
+}
@@ -0,0 +1,117 @@
+package mentions
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/exclusion"
+)
+
+func TestParseFileMentions(t *testing.T) {
+	got := ParseFileMentions("look at @main.go and @internal/cli/, then @pkg/a.go:10-80.")
+	require.Len(t, got, 3)
+	assert.Equal(t, FileMention{Raw: "main.go", Path: "main.go"}, got[0])
+	assert.Equal(t, FileMention{Raw: "internal/cli/", Path: "internal/cli/", IsDir: true}, got[1])
+	assert.Equal(t, FileMention{Raw: "pkg/a.go:10-80", Path: "pkg/a.go", StartLine: 10, EndLine: 80}, got[2])
+}
+
+func TestParseFileMentions_Forms(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []FileMention
+	}{
+		{"@a.go:42", []FileMention{{Raw: "a.go:42", Path: "a.go", StartLine: 42, EndLine: 42}}},
+		{"@src/", []FileMention{{Raw: "src/", Path: "src/", IsDir: true}}},
+		{"mail me@example.com", nil},
+		{"@a.go @a.go", []FileMention{{Raw: "a.go", Path: "a.go"}}},
+		{"@a.go:20-10", []FileMention{{Raw: "a.go:20-10", Path: "a.go:20-10"}}},
+		{"just @", nil},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ParseFileMentions(tt.input), "input %q", tt.input)
+	}
+}
+
+func TestHighlightFileMentions(t *testing.T) {
+	style := func(s string) string { return "[" + s + "]" }
+	assert.Equal(t, "see [@main.go:1-2], ok", HighlightFileMentions("see @main.go:1-2, ok", style))
+	assert.Equal(t, "me@example.com", HighlightFileMentions("me@example.com", style))
+}
+
+func TestResolve_FileWithRange(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\ntwo\nthree\nfour\n"), 0o644))
+
+	atts := Resolve(dir, ParseFileMentions("@a.txt:2-3"), Budget{}, nil)
+	require.Len(t, atts, 1)
+	assert.Empty(t, atts[0].Error)
+	assert.Equal(t, "     2\ttwo\n     3\tthree\n", atts[0].Content)
+	assert.Equal(t, "<file_context path=\"a.txt\" lines=\"2-3\">\n     2\ttwo\n     3\tthree\n</file_context>", atts[0].Render())
+}
+
+func TestResolve_Directory(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "b.go"), nil, 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub", "inner"), 0o755))
+
+	atts := Resolve(dir, ParseFileMentions("@sub/"), Budget{}, nil)
+	require.Len(t, atts, 1)
+	assert.Equal(t, "b.go\ninner/\n", atts[0].Content)
+}
+
+func TestResolve_Errors(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\n"), 0o644))
+
+	atts := Resolve(dir, ParseFileMentions("@missing.go @a.txt/ @a.txt:5"), Budget{}, nil)
+	require.Len(t, atts, 3)
+	for _, att := range atts {
+		assert.NotEmpty(t, att.Error, att.Mention.Raw)
+	}
+	assert.Contains(t, atts[0].Render(), "Could not attach:")
+}
+
+func TestResolve_Budget(t *testing.T) {
+	dir := t.TempDir()
+	big := strings.Repeat("0123456789\n", 100)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte(big), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.txt"), []byte(big), 0o644))
+
+	atts := Resolve(dir, ParseFileMentions("@a.txt @b.txt"), Budget{MaxBytesPerFile: 200, MaxTotalBytes: 200}, nil)
+	require.Len(t, atts, 2)
+	assert.True(t, atts[0].Truncated)
+	assert.LessOrEqual(t, len(atts[0].Content), 200)
+	assert.Contains(t, atts[0].Render(), `truncated="true"`)
+	assert.Equal(t, "context budget exhausted", atts[1].Error)
+}
+
+func TestLabel(t *testing.T) {
+	label, ok := Label(Attachment{Mention: FileMention{Path: "a.go", StartLine: 3, EndLine: 9}}.Render())
+	assert.True(t, ok)
+	assert.Equal(t, "a.go:3-9", label)
+
+	label, ok = Label(Attachment{Mention: FileMention{Path: "src/", IsDir: true}}.Render())
+	assert.True(t, ok)
+	assert.Equal(t, "src/", label)
+
+	_, ok = Label("hello")
+	assert.False(t, ok)
+}
+
+func TestResolve_ExcludedByPolicy(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte("TOKEN=secret\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.go"), nil, 0o644))
+
+	atts := Resolve(dir, ParseFileMentions("@.env @./"), Budget{}, exclusion.Load(dir, nil))
+	require.Len(t, atts, 2)
+	assert.Equal(t, exclusion.ExcludedMessage, atts[0].Error)
+	assert.NotContains(t, atts[0].Render(), "secret")
+	assert.Equal(t, "a.go\n", atts[1].Content)
+}
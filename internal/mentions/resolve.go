@@ -0,0 +1,211 @@
+package mentions
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mfateev/temporal-agent-harness/internal/exclusion"
+)
+
+// ContextPrefix opens every rendered attachment.
+const ContextPrefix = "<file_context "
+
+// contextHeaderPattern extracts path and lines from a rendered attachment.
+var contextHeaderPattern = regexp.MustCompile(`^<file_context path="([^"]*)"(?: lines="([^"]*)")?`)
+
+// Default budgets for resolved mentions. Sizes are in bytes of rendered
+// content, which keeps the check cheap and roughly proportional to tokens.
+const (
+	DefaultMaxBytesPerFile = 32 * 1024
+	DefaultMaxTotalBytes   = 96 * 1024
+	maxDirEntries          = 200
+)
+
+// Budget limits how much content resolved mentions may attach.
+type Budget struct {
+	MaxBytesPerFile int `json:"max_bytes_per_file,omitempty"`
+	MaxTotalBytes   int `json:"max_total_bytes,omitempty"`
+}
+
+// withDefaults fills zero fields with the package defaults.
+func (b Budget) withDefaults() Budget {
+	if b.MaxBytesPerFile <= 0 {
+		b.MaxBytesPerFile = DefaultMaxBytesPerFile
+	}
+	if b.MaxTotalBytes <= 0 {
+		b.MaxTotalBytes = DefaultMaxTotalBytes
+	}
+	return b
+}
+
+// Attachment is the resolved content of one mention.
+type Attachment struct {
+	Mention   FileMention `json:"mention"`
+	Content   string      `json:"content,omitempty"`
+	Truncated bool        `json:"truncated,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// Resolve reads each mention relative to cwd and returns one attachment per
+// mention. Files are rendered with line numbers (matching read_file output);
+// directories as a one-level listing. Failures are reported per attachment
+// rather than aborting the batch, so a typo in one path does not drop the rest.
+// Paths excluded by policy are never read.
+func Resolve(cwd string, mentions []FileMention, budget Budget, policy *exclusion.Policy) []Attachment {
+	budget = budget.withDefaults()
+	remaining := budget.MaxTotalBytes
+
+	out := make([]Attachment, 0, len(mentions))
+	for _, m := range mentions {
+		att := Attachment{Mention: m}
+		if remaining <= 0 {
+			att.Error = "context budget exhausted"
+			out = append(out, att)
+			continue
+		}
+		limit := budget.MaxBytesPerFile
+		if remaining < limit {
+			limit = remaining
+		}
+
+		path := m.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(cwd, path)
+		}
+		if policy.Excluded(path) {
+			att.Error = exclusion.ExcludedMessage
+			out = append(out, att)
+			continue
+		}
+		info, err := os.Stat(path)
+		switch {
+		case err != nil:
+			att.Error = err.Error()
+		case info.IsDir():
+			att.Content, att.Truncated = listDir(path, limit, policy)
+			att.Mention.IsDir = true
+		case m.IsDir:
+			att.Error = "not a directory"
+		default:
+			att.Content, att.Truncated, err = readLines(path, m.StartLine, m.EndLine, limit)
+			if err != nil {
+				att.Error = err.Error()
+			}
+		}
+		if att.Truncated && limit == remaining {
+			// Cut short by the total budget: nothing more fits.
+			remaining = 0
+		} else {
+			remaining -= len(att.Content)
+		}
+		out = append(out, att)
+	}
+	return out
+}
+
+// readLines renders lines [start, end] of path (1-indexed, 0 = open-ended)
+// with line numbers, stopping once limit bytes have been written.
+func readLines(path string, start, end, limit int) (string, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false, err
+	}
+	defer f.Close()
+
+	var b strings.Builder
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		if start > 0 && lineNum < start {
+			continue
+		}
+		if end > 0 && lineNum > end {
+			break
+		}
+		line := fmt.Sprintf("%6d\t%s\n", lineNum, scanner.Text())
+		if b.Len()+len(line) > limit {
+			return b.String(), true, nil
+		}
+		b.WriteString(line)
+	}
+	if err := scanner.Err(); err != nil {
+		return b.String(), false, err
+	}
+	if start > 0 && lineNum < start {
+		return "", false, fmt.Errorf("line %d is past end of file (%d lines)", start, lineNum)
+	}
+	return b.String(), false, nil
+}
+
+// listDir renders a sorted one-level listing of dir, directories suffixed
+// with "/". Excluded entries are omitted.
+func listDir(dir string, limit int, policy *exclusion.Policy) (string, bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		name := e.Name()
+		if policy.Excluded(filepath.Join(dir, name)) {
+			continue
+		}
+		if e.IsDir() {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for i, name := range names {
+		if i >= maxDirEntries || b.Len()+len(name)+1 > limit {
+			return b.String(), true
+		}
+		b.WriteString(name)
+		b.WriteByte('\n')
+	}
+	return b.String(), false
+}
+
+// Render formats an attachment as a context block for the model.
+func (a Attachment) Render() string {
+	var b strings.Builder
+	b.WriteString(ContextPrefix + `path="`)
+	b.WriteString(a.Mention.Path)
+	b.WriteString(`"`)
+	if a.Mention.StartLine > 0 {
+		fmt.Fprintf(&b, ` lines="%d-%d"`, a.Mention.StartLine, a.Mention.EndLine)
+	}
+	if a.Truncated {
+		b.WriteString(` truncated="true"`)
+	}
+	b.WriteString(">\n")
+	if a.Error != "" {
+		fmt.Fprintf(&b, "Could not attach: %s\n", a.Error)
+	} else {
+		b.WriteString(a.Content)
+	}
+	b.WriteString("</file_context>")
+	return b.String()
+}
+
+// Label returns a short display label ("main.go:10-80") for a rendered
+// attachment, or false if content is not an attachment.
+func Label(content string) (string, bool) {
+	m := contextHeaderPattern.FindStringSubmatch(content)
+	if m == nil {
+		return "", false
+	}
+	if m[2] != "" {
+		return m[1] + ":" + m[2], true
+	}
+	return m[1], true
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// maxCallIDLen is the longest call ID every provider accepts.
+const maxCallIDLen = 64
+
+// CanonicalCallID returns id in the canonical call-ID form: 1-64 characters
+// from [A-Za-z0-9_-], which both the OpenAI Responses API (call_id) and the
+// Anthropic Messages API (tool_use id) accept. Native "call_…" and
+// "toolu_…" IDs are already canonical and returned unchanged, so history
+// built under one provider replays into the other after a model switch.
+// Other IDs are sanitized and suffixed with a short hash of the original,
+// so distinct IDs stay distinct.
+func CanonicalCallID(id string) string {
+	if isCanonicalCallID(id) {
+		return id
+	}
+	sum := sha256.Sum256([]byte(id))
+	suffix := "_" + hex.EncodeToString(sum[:4])
+	var b strings.Builder
+	b.WriteString("call_")
+	for _, r := range id {
+		if b.Len() >= maxCallIDLen-len(suffix) {
+			break
+		}
+		if isCallIDChar(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	b.WriteString(suffix)
+	return b.String()
+}
+
+func isCanonicalCallID(id string) bool {
+	if id == "" || len(id) > maxCallIDLen {
+		return false
+	}
+	for _, r := range id {
+		if !isCallIDChar(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isCallIDChar(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-'
+}
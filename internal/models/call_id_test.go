@@ -0,0 +1,24 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalCallID(t *testing.T) {
+	for _, native := range []string{"call_abc123", "toolu_01A09q90qw90lq917835lq9", "plan-verify-1"} {
+		assert.Equal(t, native, CanonicalCallID(native))
+	}
+
+	got := CanonicalCallID("collab:agent.1/call#2")
+	assert.Regexp(t, `^call_collab_agent_1_call_2_[0-9a-f]{8}$`, got)
+	assert.Equal(t, got, CanonicalCallID("collab:agent.1/call#2"), "stable")
+	assert.NotEqual(t, got, CanonicalCallID("collab_agent_1_call_2"), "distinct IDs stay distinct")
+
+	long := CanonicalCallID(strings.Repeat("x", 100))
+	assert.Len(t, long, maxCallIDLen)
+	assert.Regexp(t, `^[A-Za-z0-9_-]+$`, long)
+	assert.NotEqual(t, "", CanonicalCallID(""))
+}
@@ -1,7 +1,12 @@
 package models
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/mfateev/temporal-agent-harness/internal/mcp"
+	"github.com/mfateev/temporal-agent-harness/internal/remote"
 	"github.com/mfateev/temporal-agent-harness/internal/tools"
 )
 
@@ -36,6 +41,57 @@ func DefaultModelConfig() ModelConfig {
 // Maps to: codex-rs/core/src/codex.rs SessionConfiguration (tools config part)
 type ToolsConfig struct {
 	EnabledTools []string `json:"enabled_tools"`
+
+	// Argument size limits (bytes) enforced before dispatch. 0 = default.
+	// File-writing tools (write_file, apply_patch) get the larger limit.
+	// The limits protect the Temporal payload size limits and worker memory.
+	MaxArgumentBytes     int `json:"max_argument_bytes,omitempty"`
+	MaxFileArgumentBytes int `json:"max_file_argument_bytes,omitempty"`
+
+	// ExcludePaths are extra patterns for paths that must never be read or
+	// sent to the LLM (see internal/exclusion).
+	ExcludePaths []string `json:"exclude_paths,omitempty"`
+
+	// Aliases maps extra tool names the model may call to enabled tools
+	// (e.g. "run" → "shell_command"). Arguments pass through unchanged.
+	// An empty target disables a built-in alias (see tools.ResolveToolAlias).
+	Aliases map[string]string `json:"aliases,omitempty"`
+
+	// OutputWindow is how many of the most recent tool outputs are sent to
+	// the LLM verbatim. Older large outputs are replaced in the prompt by a
+	// one-line stub that the model can expand with read_artifact; history
+	// itself keeps the full output. 0 = disabled.
+	OutputWindow int `json:"output_window,omitempty"`
+
+	// LineEndings is the line-ending policy for write_file and apply_patch.
+	LineEndings LineEndingPolicy `json:"line_endings,omitempty"`
+
+	// InjectionGuard is the prompt-injection guard mode for tool outputs:
+	// "off", "warn" or "approve" (see InjectionGuardOff).
+	InjectionGuard string `json:"injection_guard,omitempty"`
+}
+
+// Default tool argument size limits. Temporal rejects payloads over 2 MiB,
+// and every argument is repeated in history and later LLM inputs.
+const (
+	DefaultMaxToolArgumentBytes     = 256 << 10
+	DefaultMaxFileToolArgumentBytes = 1 << 20
+)
+
+// ArgumentLimit returns the argument size limit in bytes for the named tool.
+func (c ToolsConfig) ArgumentLimit(toolName string) int {
+	switch toolName {
+	case "write_file", "apply_patch":
+		if c.MaxFileArgumentBytes > 0 {
+			return c.MaxFileArgumentBytes
+		}
+		return DefaultMaxFileToolArgumentBytes
+	default:
+		if c.MaxArgumentBytes > 0 {
+			return c.MaxArgumentBytes
+		}
+		return DefaultMaxToolArgumentBytes
+	}
 }
 
 // HasTool returns true if the named tool (or any member of a group with that
@@ -93,6 +149,16 @@ const (
 	WebSearchLive     WebSearchMode = "live"
 )
 
+// SessionType selects between a full agentic session and lighter variants.
+type SessionType string
+
+const (
+	// SessionTypeAgent is the default agentic session with tools.
+	SessionTypeAgent SessionType = ""
+	// SessionTypeAsk is a Q&A session: no tools, no approvals, lighter prompt.
+	SessionTypeAsk SessionType = "ask"
+)
+
 // ApprovalMode controls when the user is prompted before tool execution.
 //
 // Maps to: codex-rs/protocol/src/protocol.rs AskForApproval
@@ -122,6 +188,12 @@ type Permissions struct {
 	EnvExclude               []string          `json:"env_exclude,omitempty"`                 // Wildcard patterns to exclude
 	EnvSet                   map[string]string `json:"env_set,omitempty"`                     // Explicit overrides
 	EnvIncludeOnly           []string          `json:"env_include_only,omitempty"`             // Whitelist (if non-empty)
+
+	// User extensions to the built-in safe-command allow-list. Each entry is
+	// a whitespace-separated command (e.g. "make lint"); a safe entry needs a
+	// trailing "*" to allow further arguments, an unsafe one is a prefix.
+	SafeCommands   []string `json:"safe_commands,omitempty"`   // Auto-approved in addition to built-ins
+	UnsafeCommands []string `json:"unsafe_commands,omitempty"` // Always prompt (e.g. "terraform apply")
 }
 
 // SessionConfiguration configures a complete agentic session.
@@ -168,6 +240,12 @@ type SessionConfiguration struct {
 	// Disable post-turn prompt suggestions
 	DisableSuggestions bool `json:"disable_suggestions,omitempty"`
 
+	// Suggestions configures the post-turn prompt suggestion call.
+	Suggestions SuggestionsConfig `json:"suggestions,omitempty"`
+
+	// SessionType selects agentic (default) or ask (Q&A, no tools) sessions.
+	SessionType SessionType `json:"session_type,omitempty"`
+
 	// Session metadata
 	SessionSource string `json:"session_source,omitempty"` // "cli", "api", "exec" — for logging/tracking
 
@@ -191,6 +269,12 @@ type SessionConfiguration struct {
 	// Set via the CLI /personality command. Empty means no personality override.
 	Personality string `json:"personality,omitempty"`
 
+	// Persona is a preset (instructions.PersonaNames) that tunes tone and
+	// verbosity; its text is appended to the user instructions of each LLM
+	// request. Set via --persona, [persona] in the CLI's config.toml and the
+	// CLI /persona command. Empty means no persona.
+	Persona string `json:"persona,omitempty"`
+
 	// Memory subsystem configuration.
 	// Maps to: codex-rs MemoriesConfig
 	MemoryEnabled bool           `json:"memory_enabled,omitempty"`
@@ -198,11 +282,199 @@ type SessionConfiguration struct {
 	MemoryDbPath  string         `json:"memory_db_path,omitempty"` // SQLite DB path (default: codex_home/state.sqlite)
 	MemoryRoot    string         `json:"memory_root,omitempty"`    // Memory folder root (default: codex_home/memories)
 
+	// MaxSessionCostUSD pauses the session once its estimated LLM spend
+	// reaches this amount, until the cap is raised. 0 = no cap.
+	// CostWarnPercents are the percentages of the cap at which a warning
+	// is shown; nil = DefaultCostWarnPercents.
+	MaxSessionCostUSD float64 `json:"max_session_cost_usd,omitempty"`
+	CostWarnPercents  []int   `json:"cost_warn_percents,omitempty"`
+
+	// DiffBudgetLines is how many lines a turn's file edits may change
+	// before further edits need confirmation, even in full-auto mode.
+	// 0 = DefaultDiffBudgetLines; negative disables the budget.
+	DiffBudgetLines int `json:"diff_budget_lines,omitempty"`
+
+	// GlossaryMaxTokens is how many tokens of the project glossary
+	// (.codex/glossary.md) each LLM call may carry.
+	// 0 = DefaultGlossaryMaxTokens; negative disables the glossary.
+	GlossaryMaxTokens int `json:"glossary_max_tokens,omitempty"`
+
+	// Retention controls how long session artifacts are kept and which
+	// conversation content is persisted. See RetentionConfig.
+	Retention RetentionConfig `json:"retention,omitempty"`
+
+	// ProviderFailover routes new turns to an equivalent model on another
+	// provider while the current provider is failing. nil = disabled.
+	ProviderFailover *ProviderFailoverConfig `json:"provider_failover,omitempty"`
+
+	// RateLimits decides whether an LLM call waits, or moves to a smaller
+	// model, when the provider reports too little headroom for it.
+	RateLimits RateLimitConfig `json:"rate_limits,omitempty"`
+
+	// TrivialRouting sends trivial turns to a cheaper model. nil = disabled.
+	TrivialRouting *TrivialRoutingConfig `json:"trivial_routing,omitempty"`
+
 	// Skills configuration.
 	// Maps to: codex-rs SkillsConfig
 	DisabledSkills []string `json:"disabled_skills,omitempty"` // Skill paths that are toggled off
+
+	// PreTurnHooks names embedder-registered hooks (see internal/hooks) to run
+	// before each LLM call. Hooks not registered on the worker are skipped.
+	PreTurnHooks []string `json:"pre_turn_hooks,omitempty"`
+
+	// Timezone (IANA name) and Locale (BCP 47 tag) of the user. The current
+	// date is given to the model in Timezone at the start of each turn;
+	// empty Timezone means UTC. See ValidateTimezone.
+	Timezone string `json:"timezone,omitempty"`
+	Locale   string `json:"locale,omitempty"`
+
+	// MetricsSearchAttributes publishes token, cost, turn and failure
+	// counts as search attributes (see workflow/search_attributes.go). The
+	// attributes must be registered on the namespace first.
+	MetricsSearchAttributes bool `json:"metrics_search_attributes,omitempty"`
+
+	// Report delivers an end-of-session report when the session completes
+	// (see workflow/report.go). Nil sends none.
+	Report *SessionReportConfig `json:"report,omitempty"`
+
+	// Remote runs the shell, exec and file tools on a remote machine over
+	// SSH (see internal/remote); Cwd is then a path on that machine. Nil
+	// runs them on the tool worker.
+	Remote *RemoteConfig `json:"remote,omitempty"`
+}
+
+// RemoteConfig is the SSH destination of a remote session. The tool worker
+// connects with its OpenSSH client, so ~/.ssh/config, known_hosts and the
+// SSH agent of the worker apply.
+type RemoteConfig struct {
+	Host string `json:"host"`
+	// User defaults to the worker's SSH configuration for Host.
+	User string `json:"user,omitempty"`
+	// Port defaults to 22 (or the worker's SSH configuration).
+	Port int `json:"port,omitempty"`
+	// KeyPath is a private key file in ~/.ssh on the tool worker; empty
+	// uses the SSH agent and the worker's SSH configuration.
+	KeyPath string `json:"key_path,omitempty"`
+	// Cwd is the session's working directory on the remote machine. Empty
+	// keeps the CLI's working directory, for trees at the same path on
+	// both machines.
+	Cwd string `json:"cwd,omitempty"`
+}
+
+// Validate checks that the destination can be passed to ssh safely.
+func (c *RemoteConfig) Validate() error {
+	return c.Target().Validate()
+}
+
+// Target returns the SSH target the tool worker connects to.
+func (c *RemoteConfig) Target() remote.Target {
+	return remote.Target{Host: c.Host, User: c.User, Port: c.Port, KeyPath: c.KeyPath}
 }
 
+// ParseRemote parses a --remote destination: [user@]host[:port].
+func ParseRemote(dest string) (*RemoteConfig, error) {
+	cfg := &RemoteConfig{Host: dest}
+	if user, host, ok := strings.Cut(cfg.Host, "@"); ok {
+		cfg.User, cfg.Host = user, host
+	}
+	if host, port, ok := strings.Cut(cfg.Host, ":"); ok {
+		n, err := strconv.Atoi(port)
+		if err != nil {
+			return nil, fmt.Errorf("remote %q: invalid port %q", dest, port)
+		}
+		cfg.Host, cfg.Port = host, n
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("remote %q: %w", dest, err)
+	}
+	return cfg, nil
+}
+
+// Formats of the end-of-session report.
+const (
+	ReportFormatMarkdown = "markdown"
+	ReportFormatHTML     = "html"
+)
+
+// SessionReportConfig selects the format and delivery channels of the
+// end-of-session report. Every channel that is set receives the report.
+// SMTP credentials are read on the worker (SMTP_USERNAME, SMTP_PASSWORD)
+// so they never enter workflow history.
+type SessionReportConfig struct {
+	// Format is ReportFormatMarkdown (the default) or ReportFormatHTML.
+	Format string `json:"format,omitempty"`
+	// Path is a file on the tool worker; {session} expands to the session
+	// ID and a leading ~/ to the worker's home directory.
+	Path string `json:"path,omitempty"`
+	// WebhookURL receives the report as a JSON POST.
+	WebhookURL string `json:"webhook_url,omitempty"`
+	// SMTPServer (host:port) sends the report from EmailFrom to EmailTo.
+	SMTPServer string   `json:"smtp_server,omitempty"`
+	EmailFrom  string   `json:"email_from,omitempty"`
+	EmailTo    []string `json:"email_to,omitempty"`
+}
+
+// Enabled reports whether any delivery channel is configured.
+func (c *SessionReportConfig) Enabled() bool {
+	return c != nil && (c.Path != "" || c.WebhookURL != "" || len(c.EmailTo) > 0)
+}
+
+// SuggestionsConfig configures the post-turn prompt suggestion LLM call.
+// Zero value keeps the defaults: enabled, with a cheap model picked from
+// the session's provider.
+type SuggestionsConfig struct {
+	Enabled   *bool  `json:"enabled,omitempty"`    // nil = true
+	Provider  string `json:"provider,omitempty"`   // Defaults to the session provider
+	Model     string `json:"model,omitempty"`      // Defaults to a cheap model for the provider
+	MaxTokens int    `json:"max_tokens,omitempty"` // 0 = DefaultSuggestionMaxTokens
+}
+
+// DefaultSuggestionMaxTokens caps suggestion output when MaxTokens is unset.
+const DefaultSuggestionMaxTokens = 50
+
+// SuggestionsEnabled reports whether the post-turn suggestion call should
+// run. Either DisableSuggestions or Suggestions.Enabled=false opts out.
+func (c SessionConfiguration) SuggestionsEnabled() bool {
+	if c.DisableSuggestions {
+		return false
+	}
+	return c.Suggestions.Enabled == nil || *c.Suggestions.Enabled
+}
+
+// DefaultDiffBudgetLines is the diff budget when DiffBudgetLines is unset.
+const DefaultDiffBudgetLines = 1500
+
+// DiffBudget returns the lines a turn's edits may change before
+// confirmation, or 0 when the budget is disabled.
+func (c SessionConfiguration) DiffBudget() int {
+	switch {
+	case c.DiffBudgetLines < 0:
+		return 0
+	case c.DiffBudgetLines == 0:
+		return DefaultDiffBudgetLines
+	}
+	return c.DiffBudgetLines
+}
+
+// DefaultGlossaryMaxTokens is the glossary budget when GlossaryMaxTokens is
+// unset.
+const DefaultGlossaryMaxTokens = 800
+
+// GlossaryBudget returns the tokens of glossary each LLM call may carry, or
+// 0 when the glossary is disabled.
+func (c SessionConfiguration) GlossaryBudget() int {
+	switch {
+	case c.GlossaryMaxTokens < 0:
+		return 0
+	case c.GlossaryMaxTokens == 0:
+		return DefaultGlossaryMaxTokens
+	}
+	return c.GlossaryMaxTokens
+}
+
+// DefaultCostWarnPercents is used when CostWarnPercents is unset.
+var DefaultCostWarnPercents = []int{80}
+
 // DefaultSessionConfiguration returns sensible defaults.
 func DefaultSessionConfiguration() SessionConfiguration {
 	return SessionConfiguration{
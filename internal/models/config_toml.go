@@ -1,6 +1,10 @@
 package models
 
 import (
+	"fmt"
+	"net"
+	"net/url"
+
 	"github.com/BurntSushi/toml"
 	"github.com/mfateev/temporal-agent-harness/internal/mcp"
 )
@@ -19,9 +23,348 @@ type ConfigToml struct {
 	SandboxMode                *string                        `toml:"sandbox_mode"`
 	SandboxWorkspaceWrite      *SandboxWorkspaceWriteToml     `toml:"sandbox_workspace_write"`
 	DisableSuggestions         *bool                          `toml:"disable_suggestions"`
+	Suggestions                *SuggestionsToml               `toml:"suggestions"`
 	McpServers                 map[string]McpServerConfigToml `toml:"mcp_servers"`
 	Memory                     *MemoryToml                    `toml:"memory"`
 	DisabledSkills             []string                       `toml:"disabled_skills"`
+	CommandSafety              *CommandSafetyToml             `toml:"command_safety"`
+	PreTurnHooks               []string                       `toml:"pre_turn_hooks"`
+	ToolLimits                 *ToolLimitsToml                `toml:"tool_limits"`
+	ExcludePaths               []string                       `toml:"exclude_paths"`
+	ToolAliases                map[string]string              `toml:"tool_aliases"`
+	ToolOutputWindow           *int                           `toml:"tool_output_window"`
+	InputPreviewTokens         *int                           `toml:"input_preview_tokens"` // read by the CLI only
+	Retention                  *RetentionToml                 `toml:"retention"`
+	MaxSessionCostUSD          *float64                       `toml:"max_session_cost_usd"`
+	CostWarnPercents           []int                          `toml:"cost_warn_percents"`
+	DiffBudgetLines            *int                           `toml:"diff_budget_lines"`   // 0 disables
+	GlossaryMaxTokens          *int                           `toml:"glossary_max_tokens"` // 0 disables
+	ProviderFailover           *ProviderFailoverToml          `toml:"provider_failover"`
+	RateLimits                 *RateLimitsToml                `toml:"rate_limits"`
+	TrivialRouting             *TrivialRoutingToml            `toml:"trivial_routing"`
+	LineEndings                *LineEndingsToml               `toml:"line_endings"`
+	InjectionGuard             *string                        `toml:"injection_guard"`
+	Timezone                   *string                        `toml:"timezone"`
+	Locale                     *string                        `toml:"locale"`
+	Telemetry                  *TelemetryToml                 `toml:"telemetry"` // read by the worker only
+	Metrics                    *MetricsToml                   `toml:"metrics"`
+	Persona                    *PersonaToml                   `toml:"persona"`    // read by the CLI only
+	Markdown                   *MarkdownToml                  `toml:"markdown"`   // read by the CLI only
+	Accessible                 *bool                          `toml:"accessible"` // read by the CLI only
+	Storage                    *StorageToml                   `toml:"storage"`    // read by the worker and CLI
+	Report                     *ReportToml                    `toml:"report"`
+	Remote                     *RemoteToml                    `toml:"remote"`
+}
+
+// MarkdownToml configures how the CLI renders fenced code blocks in
+// assistant messages.
+type MarkdownToml struct {
+	WrapCode     *bool `toml:"wrap_code"`
+	Highlight    *bool `toml:"highlight"`
+	MaxCodeLines *int  `toml:"max_code_lines"` // 0 shows every line
+}
+
+// validate checks that max_code_lines is not negative.
+func (t *MarkdownToml) validate() error {
+	if t != nil && t.MaxCodeLines != nil && *t.MaxCodeLines < 0 {
+		return fmt.Errorf("max_code_lines must not be negative")
+	}
+	return nil
+}
+
+// PersonaToml configures the persona preset of new sessions. Projects maps
+// project root directories to a persona; `/persona` in the CLI saves the
+// current project's choice there.
+type PersonaToml struct {
+	Default  *string           `toml:"default"`
+	Projects map[string]string `toml:"projects"`
+}
+
+// ToPolicy returns the persona policy.
+func (t *PersonaToml) ToPolicy() PersonaPolicy {
+	if t == nil {
+		return PersonaPolicy{}
+	}
+	p := PersonaPolicy{Projects: t.Projects}
+	if t.Default != nil {
+		p.Default = *t.Default
+	}
+	return p
+}
+
+// MetricsToml configures per-session metrics published as search
+// attributes.
+type MetricsToml struct {
+	SearchAttributes *bool `toml:"search_attributes"`
+}
+
+// LineEndingsToml configures the line endings of files written by tools.
+// Projects maps project root directories to a policy.
+type LineEndingsToml struct {
+	Default  *string           `toml:"default"`
+	Projects map[string]string `toml:"projects"`
+}
+
+// toPolicy returns the line-ending policy.
+func (t *LineEndingsToml) toPolicy() LineEndingPolicy {
+	if t == nil {
+		return LineEndingPolicy{}
+	}
+	p := LineEndingPolicy{Projects: t.Projects}
+	if t.Default != nil {
+		p.Default = *t.Default
+	}
+	return p
+}
+
+// TelemetryToml configures the worker's opt-in usage telemetry (see
+// internal/telemetry). Reports go to Endpoint every IntervalHours; without
+// an endpoint they are only logged.
+type TelemetryToml struct {
+	Enabled       *bool   `toml:"enabled"`
+	Endpoint      *string `toml:"endpoint"`
+	IntervalHours *int    `toml:"interval_hours"`
+}
+
+// validate checks the endpoint URL and interval.
+func (t *TelemetryToml) validate() error {
+	if t == nil {
+		return nil
+	}
+	if t.Endpoint != nil && *t.Endpoint != "" {
+		u, err := url.Parse(*t.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("endpoint %q is not an http(s) URL", *t.Endpoint)
+		}
+	}
+	if t.IntervalHours != nil && *t.IntervalHours <= 0 {
+		return fmt.Errorf("interval_hours must be positive")
+	}
+	return nil
+}
+
+// ReportToml configures the end-of-session report and where it is
+// delivered: a file on the worker, a webhook, and/or email over SMTP.
+type ReportToml struct {
+	Format     *string  `toml:"format"`
+	Path       *string  `toml:"path"`
+	WebhookURL *string  `toml:"webhook_url"`
+	SMTPServer *string  `toml:"smtp_server"`
+	EmailFrom  *string  `toml:"email_from"`
+	EmailTo    []string `toml:"email_to"`
+}
+
+// validate checks the format, the webhook URL and that email has a server
+// and a sender.
+func (t *ReportToml) validate() error {
+	if t == nil {
+		return nil
+	}
+	if t.Format != nil && *t.Format != ReportFormatMarkdown && *t.Format != ReportFormatHTML {
+		return fmt.Errorf("format %q must be %q or %q", *t.Format, ReportFormatMarkdown, ReportFormatHTML)
+	}
+	if t.WebhookURL != nil && *t.WebhookURL != "" {
+		u, err := url.Parse(*t.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook_url %q is not an http(s) URL", *t.WebhookURL)
+		}
+	}
+	if len(t.EmailTo) > 0 {
+		if t.SMTPServer == nil || *t.SMTPServer == "" {
+			return fmt.Errorf("email_to needs smtp_server")
+		}
+		if _, _, err := net.SplitHostPort(*t.SMTPServer); err != nil {
+			return fmt.Errorf("smtp_server %q must be host:port", *t.SMTPServer)
+		}
+		if t.EmailFrom == nil || *t.EmailFrom == "" {
+			return fmt.Errorf("email_to needs email_from")
+		}
+	}
+	return nil
+}
+
+// toConfig returns the session report configuration, or nil when no
+// channel is set.
+func (t *ReportToml) toConfig() *SessionReportConfig {
+	if t == nil {
+		return nil
+	}
+	cfg := &SessionReportConfig{EmailTo: t.EmailTo}
+	if t.Format != nil {
+		cfg.Format = *t.Format
+	}
+	if t.Path != nil {
+		cfg.Path = *t.Path
+	}
+	if t.WebhookURL != nil {
+		cfg.WebhookURL = *t.WebhookURL
+	}
+	if t.SMTPServer != nil {
+		cfg.SMTPServer = *t.SMTPServer
+	}
+	if t.EmailFrom != nil {
+		cfg.EmailFrom = *t.EmailFrom
+	}
+	if !cfg.Enabled() {
+		return nil
+	}
+	return cfg
+}
+
+// RemoteToml runs the session's tools on a remote machine over SSH.
+type RemoteToml struct {
+	Host    *string `toml:"host"`
+	User    *string `toml:"user"`
+	Port    *int    `toml:"port"`
+	KeyPath *string `toml:"key_path"`
+	Cwd     *string `toml:"cwd"`
+}
+
+// toConfig returns the remote destination, or nil without a host.
+func (t *RemoteToml) toConfig() *RemoteConfig {
+	if t == nil || t.Host == nil || *t.Host == "" {
+		return nil
+	}
+	cfg := &RemoteConfig{Host: *t.Host}
+	if t.User != nil {
+		cfg.User = *t.User
+	}
+	if t.Port != nil {
+		cfg.Port = *t.Port
+	}
+	if t.KeyPath != nil {
+		cfg.KeyPath = *t.KeyPath
+	}
+	if t.Cwd != nil {
+		cfg.Cwd = *t.Cwd
+	}
+	return cfg
+}
+
+// validate checks the destination when a host is set.
+func (t *RemoteToml) validate() error {
+	if cfg := t.toConfig(); cfg != nil {
+		return cfg.Validate()
+	}
+	return nil
+}
+
+// StorageToml selects where history archives and artifact bundles are
+// stored (see internal/blobstore): the local disk by default, or an S3,
+// GCS or MinIO bucket for ephemeral cloud workers.
+type StorageToml struct {
+	Backend             *string `toml:"backend"` // local, s3, gcs or minio
+	Bucket              *string `toml:"bucket"`
+	Prefix              *string `toml:"prefix"`
+	Endpoint            *string `toml:"endpoint"`
+	Region              *string `toml:"region"`
+	PathStyle           *bool   `toml:"path_style"`
+	SignedURLTTLMinutes *int    `toml:"signed_url_ttl_minutes"`
+}
+
+// validate checks the endpoint URL and signed URL lifetime.
+func (t *StorageToml) validate() error {
+	if t == nil {
+		return nil
+	}
+	if t.Endpoint != nil && *t.Endpoint != "" {
+		u, err := url.Parse(*t.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("endpoint %q is not an http(s) URL", *t.Endpoint)
+		}
+	}
+	if t.SignedURLTTLMinutes != nil && *t.SignedURLTTLMinutes <= 0 {
+		return fmt.Errorf("signed_url_ttl_minutes must be positive")
+	}
+	return nil
+}
+
+// ProviderFailoverToml configures health-based routing between equivalent
+// models on different providers. Equivalents holds "provider/model" groups.
+type ProviderFailoverToml struct {
+	Equivalents        [][]string `toml:"equivalents"`
+	ErrorRateThreshold *float64   `toml:"error_rate_threshold"`
+	MinRequests        *int       `toml:"min_requests"`
+}
+
+// toConfig returns the failover policy, or nil when no equivalents are set.
+func (t *ProviderFailoverToml) toConfig() *ProviderFailoverConfig {
+	if t == nil || len(t.Equivalents) == 0 {
+		return nil
+	}
+	cfg := &ProviderFailoverConfig{Equivalents: t.Equivalents}
+	if t.ErrorRateThreshold != nil {
+		cfg.ErrorRateThreshold = *t.ErrorRateThreshold
+	}
+	if t.MinRequests != nil {
+		cfg.MinRequests = *t.MinRequests
+	}
+	return cfg
+}
+
+// RateLimitsToml configures how sessions act on low provider rate-limit
+// headroom.
+type RateLimitsToml struct {
+	MaxWaitSeconds *int    `toml:"max_wait_seconds"`
+	DowngradeModel *string `toml:"downgrade_model"`
+}
+
+// toConfig returns the rate-limit policy; nil fields keep the defaults.
+func (t *RateLimitsToml) toConfig() RateLimitConfig {
+	var cfg RateLimitConfig
+	if t == nil {
+		return cfg
+	}
+	if t.MaxWaitSeconds != nil {
+		cfg.MaxWaitSeconds = *t.MaxWaitSeconds
+	}
+	if t.DowngradeModel != nil {
+		cfg.DowngradeModel = *t.DowngradeModel
+	}
+	return cfg
+}
+
+// TrivialRoutingToml configures routing of trivial turns to a cheaper
+// model.
+type TrivialRoutingToml struct {
+	Model    *string `toml:"model"`
+	MaxChars *int    `toml:"max_chars"`
+}
+
+// toConfig returns the routing policy, or nil when no model is set.
+func (t *TrivialRoutingToml) toConfig() *TrivialRoutingConfig {
+	if t == nil || t.Model == nil || *t.Model == "" {
+		return nil
+	}
+	cfg := &TrivialRoutingConfig{Model: *t.Model}
+	if t.MaxChars != nil {
+		cfg.MaxChars = *t.MaxChars
+	}
+	return cfg
+}
+
+// RetentionToml configures how long session artifacts are kept and which
+// conversation content is persisted.
+type RetentionToml struct {
+	SessionDays        *int  `toml:"session_days"`
+	StoreToolOutputs   *bool `toml:"store_tool_outputs"`
+	StoreUserMessages  *bool `toml:"store_user_messages"`
+	HistoryWindowItems *int  `toml:"history_window_items"`
+	HistoryWindowBytes *int  `toml:"history_window_bytes"`
+}
+
+// ToolLimitsToml configures tool-call argument size limits (bytes).
+type ToolLimitsToml struct {
+	MaxArgumentBytes     *int `toml:"max_argument_bytes"`
+	MaxFileArgumentBytes *int `toml:"max_file_argument_bytes"`
+}
+
+// SuggestionsToml configures the post-turn prompt suggestion call.
+type SuggestionsToml struct {
+	Enabled   *bool   `toml:"enabled"`
+	Provider  *string `toml:"provider"`
+	Model     *string `toml:"model"`
+	MaxTokens *int    `toml:"max_tokens"`
 }
 
 // SandboxWorkspaceWriteToml configures workspace-write sandbox settings.
@@ -30,6 +373,12 @@ type SandboxWorkspaceWriteToml struct {
 	NetworkAccess *bool    `toml:"network_access"`
 }
 
+// CommandSafetyToml extends the built-in safe-command allow-list.
+type CommandSafetyToml struct {
+	SafeCommands   []string `toml:"safe_commands"`
+	UnsafeCommands []string `toml:"unsafe_commands"`
+}
+
 // MemoryToml configures the cross-session memory subsystem.
 type MemoryToml struct {
 	Enabled *bool   `toml:"enabled"`
@@ -57,6 +406,48 @@ func ParseConfigToml(data []byte) (*ConfigToml, error) {
 	if err := toml.Unmarshal(data, &cfg); err != nil {
 		return nil, err
 	}
+	if failover := cfg.ProviderFailover.toConfig(); failover != nil {
+		if err := failover.Validate(); err != nil {
+			return nil, fmt.Errorf("provider_failover: %w", err)
+		}
+	}
+	if err := cfg.RateLimits.toConfig().Validate(); err != nil {
+		return nil, fmt.Errorf("rate_limits: %w", err)
+	}
+	if err := cfg.TrivialRouting.toConfig().Validate(); err != nil {
+		return nil, fmt.Errorf("trivial_routing: %w", err)
+	}
+	if err := cfg.LineEndings.toPolicy().Validate(); err != nil {
+		return nil, fmt.Errorf("line_endings: %w", err)
+	}
+	if cfg.InjectionGuard != nil {
+		if err := ValidateInjectionGuard(*cfg.InjectionGuard); err != nil {
+			return nil, fmt.Errorf("injection_guard: %w", err)
+		}
+	}
+	if cfg.Timezone != nil {
+		if err := ValidateTimezone(*cfg.Timezone); err != nil {
+			return nil, fmt.Errorf("timezone: %w", err)
+		}
+	}
+	if err := cfg.Telemetry.validate(); err != nil {
+		return nil, fmt.Errorf("telemetry: %w", err)
+	}
+	if err := cfg.Storage.validate(); err != nil {
+		return nil, fmt.Errorf("storage: %w", err)
+	}
+	if err := cfg.Report.validate(); err != nil {
+		return nil, fmt.Errorf("report: %w", err)
+	}
+	if err := cfg.Remote.validate(); err != nil {
+		return nil, fmt.Errorf("remote: %w", err)
+	}
+	if err := cfg.Persona.ToPolicy().Validate(); err != nil {
+		return nil, fmt.Errorf("persona: %w", err)
+	}
+	if err := cfg.Markdown.validate(); err != nil {
+		return nil, fmt.Errorf("markdown: %w", err)
+	}
 	return &cfg, nil
 }
 
@@ -102,6 +493,21 @@ func (c *ConfigToml) ApplyToConfig(cfg *SessionConfiguration) {
 	if c.DisableSuggestions != nil {
 		cfg.DisableSuggestions = *c.DisableSuggestions
 	}
+	if c.Suggestions != nil {
+		if c.Suggestions.Enabled != nil {
+			enabled := *c.Suggestions.Enabled
+			cfg.Suggestions.Enabled = &enabled
+		}
+		if c.Suggestions.Provider != nil {
+			cfg.Suggestions.Provider = *c.Suggestions.Provider
+		}
+		if c.Suggestions.Model != nil {
+			cfg.Suggestions.Model = *c.Suggestions.Model
+		}
+		if c.Suggestions.MaxTokens != nil {
+			cfg.Suggestions.MaxTokens = *c.Suggestions.MaxTokens
+		}
+	}
 	if len(c.McpServers) > 0 {
 		if cfg.McpServers == nil {
 			cfg.McpServers = make(map[string]mcp.McpServerConfig, len(c.McpServers))
@@ -113,6 +519,99 @@ func (c *ConfigToml) ApplyToConfig(cfg *SessionConfiguration) {
 	if len(c.DisabledSkills) > 0 {
 		cfg.DisabledSkills = c.DisabledSkills
 	}
+	if len(c.PreTurnHooks) > 0 {
+		cfg.PreTurnHooks = c.PreTurnHooks
+	}
+	if c.ToolLimits != nil {
+		if c.ToolLimits.MaxArgumentBytes != nil {
+			cfg.Tools.MaxArgumentBytes = *c.ToolLimits.MaxArgumentBytes
+		}
+		if c.ToolLimits.MaxFileArgumentBytes != nil {
+			cfg.Tools.MaxFileArgumentBytes = *c.ToolLimits.MaxFileArgumentBytes
+		}
+	}
+	if len(c.ExcludePaths) > 0 {
+		cfg.Tools.ExcludePaths = c.ExcludePaths
+	}
+	if len(c.ToolAliases) > 0 {
+		cfg.Tools.Aliases = c.ToolAliases
+	}
+	if c.ToolOutputWindow != nil {
+		cfg.Tools.OutputWindow = *c.ToolOutputWindow
+	}
+	if c.LineEndings != nil {
+		cfg.Tools.LineEndings = c.LineEndings.toPolicy()
+	}
+	if c.InjectionGuard != nil {
+		cfg.Tools.InjectionGuard = *c.InjectionGuard
+	}
+	if c.Timezone != nil {
+		cfg.Timezone = *c.Timezone
+	}
+	if c.Locale != nil {
+		cfg.Locale = *c.Locale
+	}
+	if c.Metrics != nil && c.Metrics.SearchAttributes != nil {
+		cfg.MetricsSearchAttributes = *c.Metrics.SearchAttributes
+	}
+	if report := c.Report.toConfig(); report != nil {
+		cfg.Report = report
+	}
+	if remote := c.Remote.toConfig(); remote != nil {
+		cfg.Remote = remote
+	}
+	if c.CommandSafety != nil {
+		if len(c.CommandSafety.SafeCommands) > 0 {
+			cfg.Permissions.SafeCommands = c.CommandSafety.SafeCommands
+		}
+		if len(c.CommandSafety.UnsafeCommands) > 0 {
+			cfg.Permissions.UnsafeCommands = c.CommandSafety.UnsafeCommands
+		}
+	}
+	if c.MaxSessionCostUSD != nil {
+		cfg.MaxSessionCostUSD = *c.MaxSessionCostUSD
+	}
+	if len(c.CostWarnPercents) > 0 {
+		cfg.CostWarnPercents = c.CostWarnPercents
+	}
+	if c.DiffBudgetLines != nil {
+		cfg.DiffBudgetLines = *c.DiffBudgetLines
+		if cfg.DiffBudgetLines == 0 {
+			cfg.DiffBudgetLines = -1
+		}
+	}
+	if c.GlossaryMaxTokens != nil {
+		cfg.GlossaryMaxTokens = *c.GlossaryMaxTokens
+		if cfg.GlossaryMaxTokens == 0 {
+			cfg.GlossaryMaxTokens = -1
+		}
+	}
+	if failover := c.ProviderFailover.toConfig(); failover != nil {
+		cfg.ProviderFailover = failover
+	}
+	if c.RateLimits != nil {
+		cfg.RateLimits = c.RateLimits.toConfig()
+	}
+	if routing := c.TrivialRouting.toConfig(); routing != nil {
+		cfg.TrivialRouting = routing
+	}
+	if c.Retention != nil {
+		if c.Retention.SessionDays != nil {
+			cfg.Retention.TTLDays = *c.Retention.SessionDays
+		}
+		if c.Retention.StoreToolOutputs != nil {
+			cfg.Retention.StoreToolOutputs = c.Retention.StoreToolOutputs
+		}
+		if c.Retention.StoreUserMessages != nil {
+			cfg.Retention.StoreUserMessages = c.Retention.StoreUserMessages
+		}
+		if c.Retention.HistoryWindowItems != nil {
+			cfg.Retention.HistoryWindowItems = *c.Retention.HistoryWindowItems
+		}
+		if c.Retention.HistoryWindowBytes != nil {
+			cfg.Retention.HistoryWindowBytes = *c.Retention.HistoryWindowBytes
+		}
+	}
 	if c.Memory != nil {
 		if c.Memory.Enabled != nil {
 			cfg.MemoryEnabled = *c.Memory.Enabled
@@ -2,6 +2,7 @@ package models
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -211,3 +212,359 @@ KEY = "val"
 	assert.Equal(t, []string{"tool1"}, srv.EnabledTools)
 	assert.Equal(t, []string{"tool2"}, srv.DisabledTools)
 }
+
+func TestApplyToConfig_CommandSafety(t *testing.T) {
+	input := `
+[command_safety]
+safe_commands = ["make lint", "cargo check"]
+unsafe_commands = ["terraform apply"]
+`
+	tc, err := ParseConfigToml([]byte(input))
+	require.NoError(t, err)
+
+	var cfg SessionConfiguration
+	tc.ApplyToConfig(&cfg)
+
+	assert.Equal(t, []string{"make lint", "cargo check"}, cfg.Permissions.SafeCommands)
+	assert.Equal(t, []string{"terraform apply"}, cfg.Permissions.UnsafeCommands)
+}
+
+func TestApplyToConfig_PreTurnHooks(t *testing.T) {
+	tc, err := ParseConfigToml([]byte(`pre_turn_hooks = ["sprint", "flags"]`))
+	require.NoError(t, err)
+
+	var cfg SessionConfiguration
+	tc.ApplyToConfig(&cfg)
+
+	assert.Equal(t, []string{"sprint", "flags"}, cfg.PreTurnHooks)
+}
+
+func TestApplyToConfig_ToolLimits(t *testing.T) {
+	input := `
+[tool_limits]
+max_argument_bytes = 4096
+max_file_argument_bytes = 65536
+`
+	tc, err := ParseConfigToml([]byte(input))
+	require.NoError(t, err)
+
+	var cfg SessionConfiguration
+	tc.ApplyToConfig(&cfg)
+
+	assert.Equal(t, 4096, cfg.Tools.ArgumentLimit("shell_command"))
+	assert.Equal(t, 65536, cfg.Tools.ArgumentLimit("apply_patch"))
+	assert.Equal(t, DefaultMaxToolArgumentBytes, ToolsConfig{}.ArgumentLimit("shell_command"))
+	assert.Equal(t, DefaultMaxFileToolArgumentBytes, ToolsConfig{}.ArgumentLimit("write_file"))
+}
+
+func TestApplyToConfig_ExcludePaths(t *testing.T) {
+	tc, err := ParseConfigToml([]byte(`exclude_paths = ["secrets/", "*.p12"]`))
+	require.NoError(t, err)
+
+	var cfg SessionConfiguration
+	tc.ApplyToConfig(&cfg)
+
+	assert.Equal(t, []string{"secrets/", "*.p12"}, cfg.Tools.ExcludePaths)
+}
+
+func TestApplyToConfig_Retention(t *testing.T) {
+	input := `
+[retention]
+session_days = 14
+store_tool_outputs = false
+history_window_items = 200
+`
+	tc, err := ParseConfigToml([]byte(input))
+	require.NoError(t, err)
+
+	var cfg SessionConfiguration
+	tc.ApplyToConfig(&cfg)
+
+	assert.Equal(t, 14, cfg.Retention.TTLDays)
+	assert.False(t, cfg.Retention.KeepToolOutputs())
+	assert.True(t, cfg.Retention.KeepUserMessages(), "unset keeps the default")
+	assert.Equal(t, 200, cfg.Retention.HistoryWindowItems)
+	assert.True(t, cfg.Retention.HistoryWindowed())
+}
+
+func TestApplyToConfig_ToolAliases(t *testing.T) {
+	input := `
+[tool_aliases]
+run = "shell_command"
+bash = ""
+`
+	tc, err := ParseConfigToml([]byte(input))
+	require.NoError(t, err)
+
+	var cfg SessionConfiguration
+	tc.ApplyToConfig(&cfg)
+
+	assert.Equal(t, map[string]string{"run": "shell_command", "bash": ""}, cfg.Tools.Aliases)
+}
+
+func TestApplyToConfig_Suggestions(t *testing.T) {
+	input := `
+[suggestions]
+enabled = true
+provider = "anthropic"
+model = "claude-haiku-4-5"
+max_tokens = 80
+`
+	tc, err := ParseConfigToml([]byte(input))
+	require.NoError(t, err)
+
+	var cfg SessionConfiguration
+	tc.ApplyToConfig(&cfg)
+
+	assert.True(t, cfg.SuggestionsEnabled())
+	assert.Equal(t, "anthropic", cfg.Suggestions.Provider)
+	assert.Equal(t, "claude-haiku-4-5", cfg.Suggestions.Model)
+	assert.Equal(t, 80, cfg.Suggestions.MaxTokens)
+
+	tc, err = ParseConfigToml([]byte("[suggestions]\nenabled = false\n"))
+	require.NoError(t, err)
+	tc.ApplyToConfig(&cfg)
+	assert.False(t, cfg.SuggestionsEnabled())
+}
+
+func TestSuggestionsEnabled_Defaults(t *testing.T) {
+	assert.True(t, SessionConfiguration{}.SuggestionsEnabled())
+	assert.False(t, SessionConfiguration{DisableSuggestions: true}.SuggestionsEnabled())
+}
+
+func TestApplyToConfig_CostCap(t *testing.T) {
+	input := `
+max_session_cost_usd = 5.0
+cost_warn_percents = [50, 80]
+`
+	tc, err := ParseConfigToml([]byte(input))
+	require.NoError(t, err)
+
+	var cfg SessionConfiguration
+	tc.ApplyToConfig(&cfg)
+
+	assert.Equal(t, 5.0, cfg.MaxSessionCostUSD)
+	assert.Equal(t, []int{50, 80}, cfg.CostWarnPercents)
+}
+
+func TestApplyToConfig_ToolOutputWindow(t *testing.T) {
+	tc, err := ParseConfigToml([]byte("tool_output_window = 12\n"))
+	require.NoError(t, err)
+
+	var cfg SessionConfiguration
+	tc.ApplyToConfig(&cfg)
+
+	assert.Equal(t, 12, cfg.Tools.OutputWindow)
+}
+
+func TestApplyToConfig_ProviderFailover(t *testing.T) {
+	input := `
+[provider_failover]
+error_rate_threshold = 0.3
+equivalents = [["openai/gpt-4.1", "anthropic/claude-sonnet-4-5"]]
+`
+	tc, err := ParseConfigToml([]byte(input))
+	require.NoError(t, err)
+
+	var cfg SessionConfiguration
+	tc.ApplyToConfig(&cfg)
+
+	require.NotNil(t, cfg.ProviderFailover)
+	assert.Equal(t, [][]string{{"openai/gpt-4.1", "anthropic/claude-sonnet-4-5"}}, cfg.ProviderFailover.Equivalents)
+	assert.Equal(t, 0.3, cfg.ProviderFailover.ErrorRateThreshold)
+	assert.Zero(t, cfg.ProviderFailover.MinRequests)
+
+	_, err = ParseConfigToml([]byte("[provider_failover]\nequivalents = [[\"gpt-4.1\"]]\n"))
+	assert.Error(t, err, "equivalents must name the provider")
+}
+
+func TestApplyToConfig_LineEndings(t *testing.T) {
+	input := `
+[line_endings]
+default = "lf"
+
+[line_endings.projects]
+"/work/winapp" = "crlf"
+`
+	tc, err := ParseConfigToml([]byte(input))
+	require.NoError(t, err)
+
+	var cfg SessionConfiguration
+	tc.ApplyToConfig(&cfg)
+
+	assert.Equal(t, "lf", cfg.Tools.LineEndings.Default)
+	assert.Equal(t, "crlf", cfg.Tools.LineEndings.For("/work/winapp/src"))
+
+	_, err = ParseConfigToml([]byte("[line_endings]\ndefault = \"dos\"\n"))
+	assert.Error(t, err)
+}
+
+func TestApplyToConfig_InjectionGuard(t *testing.T) {
+	tc, err := ParseConfigToml([]byte(`injection_guard = "approve"`))
+	require.NoError(t, err)
+
+	var cfg SessionConfiguration
+	tc.ApplyToConfig(&cfg)
+	assert.Equal(t, InjectionGuardApprove, cfg.Tools.InjectionGuard)
+	assert.True(t, InjectionGuardEnabled(cfg.Tools.InjectionGuard))
+	assert.False(t, InjectionGuardEnabled(InjectionGuardOff))
+
+	_, err = ParseConfigToml([]byte(`injection_guard = "block"`))
+	assert.ErrorContains(t, err, "injection_guard")
+}
+
+func TestApplyToConfig_TimezoneAndLocale(t *testing.T) {
+	tc, err := ParseConfigToml([]byte("timezone = \"America/New_York\"\nlocale = \"en-US\"\n"))
+	require.NoError(t, err)
+
+	var cfg SessionConfiguration
+	tc.ApplyToConfig(&cfg)
+	assert.Equal(t, "America/New_York", cfg.Timezone)
+	assert.Equal(t, "en-US", cfg.Locale)
+	assert.Equal(t, "America/New_York", LoadTimezone(cfg.Timezone).String())
+	assert.Equal(t, time.UTC, LoadTimezone(""))
+
+	_, err = ParseConfigToml([]byte(`timezone = "Mars/Olympus_Mons"`))
+	assert.ErrorContains(t, err, "timezone")
+}
+
+func TestApplyToConfig_MetricsSearchAttributes(t *testing.T) {
+	tc, err := ParseConfigToml([]byte("[metrics]\nsearch_attributes = true\n"))
+	require.NoError(t, err)
+
+	var cfg SessionConfiguration
+	tc.ApplyToConfig(&cfg)
+	assert.True(t, cfg.MetricsSearchAttributes)
+}
+
+func TestParseConfigToml_Telemetry(t *testing.T) {
+	tc, err := ParseConfigToml([]byte("[telemetry]\nenabled = true\nendpoint = \"https://telemetry.example.com/v1\"\ninterval_hours = 6\n"))
+	require.NoError(t, err)
+	require.NotNil(t, tc.Telemetry)
+	assert.True(t, *tc.Telemetry.Enabled)
+	assert.Equal(t, "https://telemetry.example.com/v1", *tc.Telemetry.Endpoint)
+	assert.Equal(t, 6, *tc.Telemetry.IntervalHours)
+
+	_, err = ParseConfigToml([]byte("[telemetry]\nendpoint = \"telemetry.example.com\"\n"))
+	assert.ErrorContains(t, err, "telemetry")
+	_, err = ParseConfigToml([]byte("[telemetry]\ninterval_hours = 0\n"))
+	assert.ErrorContains(t, err, "interval_hours")
+}
+
+func TestParseConfigToml_Report(t *testing.T) {
+	tc, err := ParseConfigToml([]byte("[report]\nformat = \"html\"\npath = \"~/reports/{session}.html\"\n" +
+		"smtp_server = \"smtp.example.com:587\"\nemail_from = \"agent@example.com\"\nemail_to = [\"dev@example.com\"]\n"))
+	require.NoError(t, err)
+	var cfg SessionConfiguration
+	tc.ApplyToConfig(&cfg)
+	require.NotNil(t, cfg.Report)
+	assert.Equal(t, ReportFormatHTML, cfg.Report.Format)
+	assert.Equal(t, "~/reports/{session}.html", cfg.Report.Path)
+	assert.Equal(t, []string{"dev@example.com"}, cfg.Report.EmailTo)
+
+	tc, err = ParseConfigToml([]byte("[report]\nformat = \"markdown\"\n"))
+	require.NoError(t, err)
+	cfg = SessionConfiguration{}
+	tc.ApplyToConfig(&cfg)
+	assert.Nil(t, cfg.Report, "no channel, no report")
+
+	_, err = ParseConfigToml([]byte("[report]\nformat = \"pdf\"\n"))
+	assert.ErrorContains(t, err, "report: format")
+	_, err = ParseConfigToml([]byte("[report]\nwebhook_url = \"hooks.example.com\"\n"))
+	assert.ErrorContains(t, err, "webhook_url")
+	_, err = ParseConfigToml([]byte("[report]\nemail_to = [\"dev@example.com\"]\n"))
+	assert.ErrorContains(t, err, "smtp_server")
+}
+
+func TestParseConfigToml_Remote(t *testing.T) {
+	tc, err := ParseConfigToml([]byte("[remote]\nhost = \"devbox\"\nuser = \"ana\"\nport = 2222\nkey_path = \"~/.ssh/devbox\"\ncwd = \"/home/ana/src/app\"\n"))
+	require.NoError(t, err)
+	var cfg SessionConfiguration
+	tc.ApplyToConfig(&cfg)
+	require.NotNil(t, cfg.Remote)
+	assert.Equal(t, RemoteConfig{Host: "devbox", User: "ana", Port: 2222, KeyPath: "~/.ssh/devbox", Cwd: "/home/ana/src/app"}, *cfg.Remote)
+
+	tc, err = ParseConfigToml([]byte("[remote]\nuser = \"ana\"\n"))
+	require.NoError(t, err)
+	cfg = SessionConfiguration{}
+	tc.ApplyToConfig(&cfg)
+	assert.Nil(t, cfg.Remote, "no host, no remote")
+
+	_, err = ParseConfigToml([]byte("[remote]\nhost = \"-oProxyCommand=x\"\n"))
+	assert.ErrorContains(t, err, "remote: invalid host")
+	_, err = ParseConfigToml([]byte("[remote]\nhost = \"devbox\"\nport = 70000\n"))
+	assert.ErrorContains(t, err, "port")
+}
+
+func TestParseRemote(t *testing.T) {
+	cfg, err := ParseRemote("ana@devbox.example.com:2222")
+	require.NoError(t, err)
+	assert.Equal(t, RemoteConfig{Host: "devbox.example.com", User: "ana", Port: 2222}, *cfg)
+
+	cfg, err = ParseRemote("devbox")
+	require.NoError(t, err)
+	assert.Equal(t, RemoteConfig{Host: "devbox"}, *cfg)
+
+	for _, bad := range []string{"", "ana@", "devbox:ssh", "-oProxyCommand=x", "a@b@c", "dev box"} {
+		_, err := ParseRemote(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestApplyToConfig_DiffBudget(t *testing.T) {
+	var cfg SessionConfiguration
+	assert.Equal(t, DefaultDiffBudgetLines, cfg.DiffBudget())
+
+	tc, err := ParseConfigToml([]byte("diff_budget_lines = 400\n"))
+	require.NoError(t, err)
+	tc.ApplyToConfig(&cfg)
+	assert.Equal(t, 400, cfg.DiffBudget())
+
+	tc, err = ParseConfigToml([]byte("diff_budget_lines = 0\n"))
+	require.NoError(t, err)
+	tc.ApplyToConfig(&cfg)
+	assert.Equal(t, 0, cfg.DiffBudget(), "0 disables the budget")
+}
+
+func TestApplyToConfig_GlossaryMaxTokens(t *testing.T) {
+	var cfg SessionConfiguration
+	assert.Equal(t, DefaultGlossaryMaxTokens, cfg.GlossaryBudget())
+
+	tc, err := ParseConfigToml([]byte("glossary_max_tokens = 300\n"))
+	require.NoError(t, err)
+	tc.ApplyToConfig(&cfg)
+	assert.Equal(t, 300, cfg.GlossaryBudget())
+
+	tc, err = ParseConfigToml([]byte("glossary_max_tokens = 0\n"))
+	require.NoError(t, err)
+	tc.ApplyToConfig(&cfg)
+	assert.Equal(t, 0, cfg.GlossaryBudget(), "0 disables the glossary")
+}
+
+func TestParseConfigToml_Markdown(t *testing.T) {
+	tc, err := ParseConfigToml([]byte("[markdown]\nwrap_code = true\nhighlight = false\nmax_code_lines = 0\n"))
+	require.NoError(t, err)
+	require.NotNil(t, tc.Markdown)
+	assert.True(t, *tc.Markdown.WrapCode)
+	assert.False(t, *tc.Markdown.Highlight)
+	assert.Equal(t, 0, *tc.Markdown.MaxCodeLines)
+
+	_, err = ParseConfigToml([]byte("[markdown]\nmax_code_lines = -1\n"))
+	assert.ErrorContains(t, err, "max_code_lines")
+}
+
+func TestApplyToConfig_TrivialRouting(t *testing.T) {
+	tc, err := ParseConfigToml([]byte("[trivial_routing]\nmodel = \"openai/gpt-4o-mini\"\nmax_chars = 120\n"))
+	require.NoError(t, err)
+
+	var cfg SessionConfiguration
+	tc.ApplyToConfig(&cfg)
+	provider, model, ok := cfg.TrivialRouting.Target()
+	require.True(t, ok)
+	assert.Equal(t, "openai", provider)
+	assert.Equal(t, "gpt-4o-mini", model)
+	assert.Equal(t, 120, cfg.TrivialRouting.MaxMessageChars())
+
+	_, err = ParseConfigToml([]byte("[trivial_routing]\nmodel = \"gpt-4o-mini\"\n"))
+	assert.ErrorContains(t, err, "trivial_routing")
+}
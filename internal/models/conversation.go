@@ -3,6 +3,11 @@
 // Corresponds to: codex-rs/core/src/protocol (ResponseItem, ToolCall, etc.)
 package models
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 // ConversationItemType matches Codex's ResponseItem enum variants.
 //
 // See: codex-rs/core/src/protocol ResponseItem
@@ -23,6 +28,14 @@ const (
 	// Turn lifecycle markers (maps to Codex EventMsg::TurnStarted / EventMsg::TurnComplete)
 	ItemTypeTurnStarted  ConversationItemType = "turn_started"  // Codex: EventMsg::TurnStarted
 	ItemTypeTurnComplete ConversationItemType = "turn_complete"  // Codex: EventMsg::TurnComplete
+
+	// Post-mortem recorded when a turn ends in error (see PostMortem).
+	// Display only; never sent to the LLM.
+	ItemTypeTurnFailure ConversationItemType = "turn_failure"
+
+	// Notice from the harness to the user (e.g. a cost warning), in Content.
+	// Display only; never sent to the LLM.
+	ItemTypeNotice ConversationItemType = "notice"
 )
 
 // FunctionCallOutputPayload matches Codex's FunctionCallOutputPayload.
@@ -43,6 +56,8 @@ type FunctionCallOutputPayload struct {
 //   AssistantMessage:   Content
 //   FunctionCall:       CallID, Name, Arguments
 //   FunctionCallOutput: CallID, Output
+//   TurnFailure:        PostMortem
+//   TurnComplete:       Model
 type ConversationItem struct {
 	Type ConversationItemType `json:"type"`
 
@@ -70,6 +85,102 @@ type ConversationItem struct {
 
 	// Turn tracking (maps to Codex TurnContext.turn_id)
 	TurnID string `json:"turn_id,omitempty"`
+
+	// TurnFailure fields
+	PostMortem *PostMortem `json:"post_mortem,omitempty"`
+
+	// TurnComplete fields: the "provider/model" that answered the turn.
+	Model string `json:"model,omitempty"`
+
+	// SchemaVersion is the protocol version the item was encoded for. The
+	// workflow stamps it on the items its queries and Updates return; it
+	// is not stored in history. Zero means an older worker (version 1).
+	SchemaVersion int `json:"schema_version,omitempty"`
+}
+
+// itemTypeSince is the protocol version that introduced each item type.
+// A type added in a later version gets that version here, so a worker can
+// tell which items an older client cannot render.
+var itemTypeSince = map[ConversationItemType]int{
+	ItemTypeUserMessage:        1,
+	ItemTypeAssistantMessage:   1,
+	ItemTypeFunctionCall:       1,
+	ItemTypeFunctionCallOutput: 1,
+	ItemTypeWebSearchCall:      1,
+	ItemTypeCompaction:         1,
+	ItemTypeModelSwitch:        1,
+	ItemTypeTurnStarted:        1,
+	ItemTypeTurnComplete:       1,
+	ItemTypeTurnFailure:        1,
+	ItemTypeNotice:             1,
+}
+
+// Since returns the protocol version that introduced the item type, or 0
+// for a type this build does not know.
+func (t ConversationItemType) Since() int {
+	return itemTypeSince[t]
+}
+
+// UnsupportedItemNotice returns the notice shown in place of an item a
+// client cannot render: an unknown type, or fields it cannot decode. Seq
+// and TurnID are kept so the item still advances rendering.
+func UnsupportedItemNotice(item ConversationItem) ConversationItem {
+	content := fmt.Sprintf("[%s item not shown: it needs a newer client", item.Type)
+	if item.SchemaVersion > 0 {
+		content += fmt.Sprintf(" (protocol version %d)", item.SchemaVersion)
+	}
+	return ConversationItem{
+		Type:          ItemTypeNotice,
+		Seq:           item.Seq,
+		TurnID:        item.TurnID,
+		Content:       content + "]",
+		SchemaVersion: item.SchemaVersion,
+	}
+}
+
+// ConversationItemList is a list of conversation items that decodes
+// tolerantly: an item of an unknown type, or one whose fields do not
+// decode, becomes an UnsupportedItemNotice instead of failing the whole
+// response. Clients decode query and Update results into it so they keep
+// working against a newer worker.
+type ConversationItemList []ConversationItem
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (l *ConversationItemList) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw == nil {
+		*l = nil
+		return nil
+	}
+	items := make(ConversationItemList, 0, len(raw))
+	for _, r := range raw {
+		var item ConversationItem
+		if err := json.Unmarshal(r, &item); err != nil {
+			// Keep what identifies the item; fields that failed are dropped.
+			var head struct {
+				Type          ConversationItemType `json:"type"`
+				Seq           int                  `json:"seq"`
+				TurnID        string               `json:"turn_id"`
+				SchemaVersion int                  `json:"schema_version"`
+			}
+			if json.Unmarshal(r, &head) != nil {
+				return err
+			}
+			items = append(items, UnsupportedItemNotice(ConversationItem{
+				Type: head.Type, Seq: head.Seq, TurnID: head.TurnID, SchemaVersion: head.SchemaVersion,
+			}))
+			continue
+		}
+		if item.Type.Since() == 0 {
+			item = UnsupportedItemNotice(item)
+		}
+		items = append(items, item)
+	}
+	*l = items
+	return nil
 }
 
 // ToolCall represents a parsed tool call for internal dispatch.
@@ -122,9 +233,9 @@ type CreditsSnapshot struct {
 }
 
 // RateLimitSnapshot captures rate-limit state from the most recent API response.
-// All fields are pointers so they are omitted when not populated.
-// Go SDKs do not currently expose HTTP response headers, so this struct will
-// remain nil until that support is added.
+// All fields are pointers so they are omitted when not populated. The LLM
+// clients fill it from the provider's rate-limit headers, with Reset as an
+// RFC 3339 timestamp.
 //
 // Maps to: codex-rs/protocol/src/protocol.rs RateLimitSnapshot
 type RateLimitSnapshot struct {
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ProviderFailoverConfig is the opt-in policy for routing around a failing
+// LLM provider. Before each turn the workflow asks a worker for recent
+// provider error rates; when the current provider's rate reaches the
+// threshold, the session switches to the first equivalent model on a
+// healthy provider.
+type ProviderFailoverConfig struct {
+	// Equivalents lists groups of interchangeable models as
+	// "provider/model", e.g. {"openai/gpt-4.1", "anthropic/claude-sonnet-4-5"}.
+	// Routing only happens between members of the current model's group.
+	Equivalents [][]string `json:"equivalents"`
+
+	ErrorRateThreshold float64 `json:"error_rate_threshold,omitempty"` // 0 = DefaultFailoverErrorRate
+	MinRequests        int     `json:"min_requests,omitempty"`         // 0 = DefaultFailoverMinRequests
+}
+
+// Failover defaults: a provider is unhealthy once at least 5 recent calls
+// were seen and half of them failed.
+const (
+	DefaultFailoverErrorRate   = 0.5
+	DefaultFailoverMinRequests = 5
+)
+
+// ProviderHealth is a worker's view of one provider's recent LLM calls.
+// Errors counts transient failures and rate limits; requests the provider
+// rejected as invalid count as answered.
+type ProviderHealth struct {
+	Requests int `json:"requests"`
+	Errors   int `json:"errors"`
+}
+
+// ErrorRate returns Errors/Requests, or 0 with no requests.
+func (h ProviderHealth) ErrorRate() float64 {
+	if h.Requests == 0 {
+		return 0
+	}
+	return float64(h.Errors) / float64(h.Requests)
+}
+
+// Unhealthy reports whether h crosses the policy's error-rate threshold.
+func (c ProviderFailoverConfig) Unhealthy(h ProviderHealth) bool {
+	minRequests := c.MinRequests
+	if minRequests <= 0 {
+		minRequests = DefaultFailoverMinRequests
+	}
+	threshold := c.ErrorRateThreshold
+	if threshold <= 0 {
+		threshold = DefaultFailoverErrorRate
+	}
+	return h.Requests >= minRequests && h.ErrorRate() >= threshold
+}
+
+// Route returns the model to switch to when current's provider is
+// unhealthy: the first member of current's equivalence group on another
+// provider that is not itself unhealthy. ok is false when no switch is
+// needed or possible.
+func (c ProviderFailoverConfig) Route(current ModelConfig, health map[string]ProviderHealth) (provider, model string, ok bool) {
+	if !c.Unhealthy(health[current.Provider]) {
+		return "", "", false
+	}
+	for _, group := range c.Equivalents {
+		if !containsModelRef(group, current.Provider, current.Model) {
+			continue
+		}
+		for _, ref := range group {
+			p, m, err := ParseModelRef(ref)
+			if err != nil || p == current.Provider || c.Unhealthy(health[p]) {
+				continue
+			}
+			return p, m, true
+		}
+	}
+	return "", "", false
+}
+
+// Validate checks that every equivalent is a well-formed "provider/model".
+func (c ProviderFailoverConfig) Validate() error {
+	for _, group := range c.Equivalents {
+		for _, ref := range group {
+			if _, _, err := ParseModelRef(ref); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ParseModelRef splits "provider/model" into its parts.
+func ParseModelRef(ref string) (provider, model string, err error) {
+	provider, model, found := strings.Cut(ref, "/")
+	if !found || provider == "" || model == "" {
+		return "", "", fmt.Errorf("invalid model %q: want provider/model", ref)
+	}
+	return provider, model, nil
+}
+
+func containsModelRef(group []string, provider, model string) bool {
+	for _, ref := range group {
+		if p, m, err := ParseModelRef(ref); err == nil && p == provider && m == model {
+			return true
+		}
+	}
+	return false
+}
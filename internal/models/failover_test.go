@@ -0,0 +1,45 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProviderFailoverConfig_Route(t *testing.T) {
+	cfg := ProviderFailoverConfig{Equivalents: [][]string{
+		{"openai/gpt-4.1", "openai/gpt-4o", "anthropic/claude-sonnet-4-5"},
+	}}
+	current := ModelConfig{Provider: "openai", Model: "gpt-4.1"}
+	failing := ProviderHealth{Requests: 10, Errors: 6}
+	healthy := ProviderHealth{Requests: 10, Errors: 1}
+
+	_, _, ok := cfg.Route(current, map[string]ProviderHealth{"openai": healthy})
+	assert.False(t, ok, "healthy provider stays")
+
+	_, _, ok = cfg.Route(current, map[string]ProviderHealth{"openai": {Requests: 3, Errors: 3}})
+	assert.False(t, ok, "too few requests to judge")
+
+	provider, model, ok := cfg.Route(current, map[string]ProviderHealth{"openai": failing})
+	assert.True(t, ok)
+	assert.Equal(t, "anthropic", provider, "skips equivalents on the same provider")
+	assert.Equal(t, "claude-sonnet-4-5", model)
+
+	_, _, ok = cfg.Route(current, map[string]ProviderHealth{"openai": failing, "anthropic": failing})
+	assert.False(t, ok, "no healthy alternative")
+
+	_, _, ok = cfg.Route(ModelConfig{Provider: "openai", Model: "o3"}, map[string]ProviderHealth{"openai": failing})
+	assert.False(t, ok, "model without equivalents")
+}
+
+func TestParseModelRef(t *testing.T) {
+	provider, model, err := ParseModelRef("anthropic/claude-sonnet-4-5")
+	assert.NoError(t, err)
+	assert.Equal(t, "anthropic", provider)
+	assert.Equal(t, "claude-sonnet-4-5", model)
+
+	for _, bad := range []string{"", "gpt-4o", "/gpt-4o", "openai/"} {
+		_, _, err := ParseModelRef(bad)
+		assert.Error(t, err, bad)
+	}
+}
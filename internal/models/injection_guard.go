@@ -0,0 +1,30 @@
+package models
+
+import "fmt"
+
+// Prompt-injection guard modes for tool outputs (see internal/injection).
+//
+// "off" (the default) sends tool outputs as-is. "warn" wraps every tool
+// output in an untrusted-content block, tells the model not to follow
+// instructions inside them, and adds a warning notice when an output looks
+// like an injection attempt. "approve" also requires user approval for every
+// further tool call in a turn after such an output.
+const (
+	InjectionGuardOff     = "off"
+	InjectionGuardWarn    = "warn"
+	InjectionGuardApprove = "approve"
+)
+
+// InjectionGuardEnabled reports whether mode wraps and scans tool outputs.
+func InjectionGuardEnabled(mode string) bool {
+	return mode == InjectionGuardWarn || mode == InjectionGuardApprove
+}
+
+// ValidateInjectionGuard checks that mode is known.
+func ValidateInjectionGuard(mode string) error {
+	switch mode {
+	case "", InjectionGuardOff, InjectionGuardWarn, InjectionGuardApprove:
+		return nil
+	}
+	return fmt.Errorf("unknown injection guard mode %q (want off, warn or approve)", mode)
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Line-ending policies for file-writing tools.
+const (
+	LineEndingsPreserve = "preserve"
+	LineEndingsLF       = "lf"
+	LineEndingsCRLF     = "crlf"
+)
+
+// LineEndingPolicy chooses the line endings write_file and apply_patch use.
+// "preserve" (the default) keeps each file's dominant line ending; "lf" and
+// "crlf" convert every file written. Projects maps project root directories
+// to a policy that overrides Default for sessions working inside them.
+type LineEndingPolicy struct {
+	Default  string            `json:"default,omitempty"`
+	Projects map[string]string `json:"projects,omitempty"`
+}
+
+// For returns the policy for a session whose working directory is cwd: the
+// policy of the deepest project root containing cwd, else Default, else
+// "preserve".
+func (p LineEndingPolicy) For(cwd string) string {
+	policy, ok := projectValue(p.Projects, cwd)
+	if !ok {
+		policy = p.Default
+	}
+	if policy == "" {
+		return LineEndingsPreserve
+	}
+	return policy
+}
+
+// projectValue returns the value of the deepest project root in projects
+// that contains cwd.
+func projectValue(projects map[string]string, cwd string) (string, bool) {
+	best, value, found := "", "", false
+	cwd = filepath.Clean(cwd)
+	for root, v := range projects {
+		root = filepath.Clean(root)
+		if cwd != root && !strings.HasPrefix(cwd, root+string(filepath.Separator)) {
+			continue
+		}
+		if !found || len(root) > len(best) {
+			best, value, found = root, v, true
+		}
+	}
+	return value, found
+}
+
+// Validate checks that every policy is known.
+func (p LineEndingPolicy) Validate() error {
+	if err := validateLineEndings(p.Default); err != nil {
+		return err
+	}
+	for root, v := range p.Projects {
+		if err := validateLineEndings(v); err != nil {
+			return fmt.Errorf("project %s: %w", root, err)
+		}
+	}
+	return nil
+}
+
+func validateLineEndings(v string) error {
+	switch v {
+	case "", LineEndingsPreserve, LineEndingsLF, LineEndingsCRLF:
+		return nil
+	}
+	return fmt.Errorf("unknown line ending policy %q (want preserve, lf or crlf)", v)
+}
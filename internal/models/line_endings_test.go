@@ -0,0 +1,31 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineEndingPolicy_For(t *testing.T) {
+	assert.Equal(t, LineEndingsPreserve, LineEndingPolicy{}.For("/work/app"))
+
+	p := LineEndingPolicy{
+		Default: LineEndingsLF,
+		Projects: map[string]string{
+			"/work/win":        LineEndingsCRLF,
+			"/work/win/legacy": LineEndingsPreserve,
+		},
+	}
+	assert.Equal(t, LineEndingsLF, p.For("/work/app"))
+	assert.Equal(t, LineEndingsCRLF, p.For("/work/win"))
+	assert.Equal(t, LineEndingsCRLF, p.For("/work/win/src/"))
+	assert.Equal(t, LineEndingsPreserve, p.For("/work/win/legacy/lib"), "the deepest project root wins")
+	assert.Equal(t, LineEndingsLF, p.For("/work/window"), "a root only matches whole path components")
+}
+
+func TestLineEndingPolicy_Validate(t *testing.T) {
+	assert.NoError(t, LineEndingPolicy{}.Validate())
+	assert.NoError(t, LineEndingPolicy{Default: "crlf", Projects: map[string]string{"/a": "preserve"}}.Validate())
+	assert.Error(t, LineEndingPolicy{Default: "cr"}.Validate())
+	assert.ErrorContains(t, LineEndingPolicy{Projects: map[string]string{"/a": "unix"}}.Validate(), "project /a")
+}
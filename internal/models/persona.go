@@ -0,0 +1,59 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mfateev/temporal-agent-harness/internal/instructions"
+)
+
+// PersonaNone turns the persona off for a project even when a default is
+// configured.
+const PersonaNone = "none"
+
+// PersonaPolicy chooses the persona preset (see instructions.PersonaNames)
+// of new sessions. Projects maps project root directories to a persona that
+// overrides Default for sessions working inside them.
+type PersonaPolicy struct {
+	Default  string
+	Projects map[string]string
+}
+
+// For returns the persona for a session whose working directory is cwd: the
+// persona of the deepest project root containing cwd, else Default. The
+// empty string means no persona.
+func (p PersonaPolicy) For(cwd string) string {
+	persona, ok := projectValue(p.Projects, cwd)
+	if !ok {
+		persona = p.Default
+	}
+	if persona == PersonaNone {
+		return ""
+	}
+	return persona
+}
+
+// Validate checks that every persona is a known preset.
+func (p PersonaPolicy) Validate() error {
+	if err := ValidatePersona(p.Default); err != nil {
+		return err
+	}
+	for root, v := range p.Projects {
+		if err := ValidatePersona(v); err != nil {
+			return fmt.Errorf("project %s: %w", root, err)
+		}
+	}
+	return nil
+}
+
+// ValidatePersona checks that name is empty, "none" or a known preset.
+func ValidatePersona(name string) error {
+	if name == "" || name == PersonaNone {
+		return nil
+	}
+	if _, ok := instructions.PersonaInstructions(name); ok {
+		return nil
+	}
+	return fmt.Errorf("unknown persona %q (want %s or %s)", name,
+		strings.Join(instructions.PersonaNames(), ", "), PersonaNone)
+}
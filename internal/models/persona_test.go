@@ -0,0 +1,39 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersonaPolicy_For(t *testing.T) {
+	assert.Equal(t, "", PersonaPolicy{}.For("/work/app"))
+
+	p := PersonaPolicy{
+		Default: "concise",
+		Projects: map[string]string{
+			"/work/course":        "mentor",
+			"/work/course/legacy": PersonaNone,
+		},
+	}
+	assert.Equal(t, "concise", p.For("/work/app"))
+	assert.Equal(t, "mentor", p.For("/work/course/src"))
+	assert.Equal(t, "", p.For("/work/course/legacy"), "none overrides the default")
+}
+
+func TestParseConfigToml_Persona(t *testing.T) {
+	tc, err := ParseConfigToml([]byte(`
+[persona]
+default = "explanatory"
+
+[persona.projects]
+"/work/course" = "mentor"
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "mentor", tc.Persona.ToPolicy().For("/work/course"))
+	assert.Equal(t, "explanatory", tc.Persona.ToPolicy().For("/tmp"))
+
+	_, err = ParseConfigToml([]byte("[persona]\ndefault = \"pirate\"\n"))
+	assert.ErrorContains(t, err, `persona: unknown persona "pirate"`)
+}
@@ -0,0 +1,30 @@
+package models
+
+// TurnErrorClass identifies why a turn ended in error.
+type TurnErrorClass string
+
+const (
+	TurnErrorLLMFatal          TurnErrorClass = "llm_fatal"           // Non-retryable LLM error (auth, bad request)
+	TurnErrorLLMFailure        TurnErrorClass = "llm_failure"         // LLM activity failed (timeout, retries exhausted)
+	TurnErrorRepeatedToolCalls TurnErrorClass = "repeated_tool_calls" // Model looped on identical tool calls
+	TurnErrorIterationLimit    TurnErrorClass = "iteration_limit"     // Per-turn iteration cap reached
+)
+
+// PostMortem is a structured summary of a turn that ended in error.
+// It is recorded in history as an ItemTypeTurnFailure item and returned in
+// the WorkflowResult so headless callers can triage failures without
+// parsing assistant messages.
+type PostMortem struct {
+	TurnID     string         `json:"turn_id,omitempty"`
+	ErrorClass TurnErrorClass `json:"error_class"`
+	Error      string         `json:"error"`
+	// Attempted is the user request that started the turn (truncated).
+	Attempted string `json:"attempted,omitempty"`
+	// LastSuccessfulStep describes the last tool call in the turn that
+	// succeeded, e.g. "shell_command: go build ./...".
+	LastSuccessfulStep string `json:"last_successful_step,omitempty"`
+	ToolCalls          int    `json:"tool_calls"`
+	FailedToolCalls    int    `json:"failed_tool_calls"`
+	// SuggestedAction is a short next step for the user.
+	SuggestedAction string `json:"suggested_action"`
+}
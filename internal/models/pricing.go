@@ -0,0 +1,78 @@
+package models
+
+import "strings"
+
+// modelPrice is the list price of a provider's models whose name starts
+// with Prefix, in USD per million tokens.
+type modelPrice struct {
+	Provider string
+	Prefix   string
+	Input    float64
+	Output   float64
+}
+
+// modelPrices is matched in order, so longer prefixes come first.
+// Prices are approximate list prices and only used for estimates.
+var modelPrices = []modelPrice{
+	{Provider: "openai", Prefix: "gpt-4o-mini", Input: 0.15, Output: 0.60},
+	{Provider: "openai", Prefix: "gpt-4o", Input: 2.50, Output: 10.00},
+	{Provider: "openai", Prefix: "gpt-4.1-mini", Input: 0.40, Output: 1.60},
+	{Provider: "openai", Prefix: "gpt-4.1", Input: 2.00, Output: 8.00},
+	{Provider: "openai", Prefix: "gpt-4-turbo", Input: 10.00, Output: 30.00},
+	{Provider: "openai", Prefix: "gpt-3.5-turbo", Input: 0.50, Output: 1.50},
+	{Provider: "openai", Prefix: "gpt-5-mini", Input: 0.25, Output: 2.00},
+	{Provider: "openai", Prefix: "gpt-5", Input: 1.25, Output: 10.00},
+	{Provider: "openai", Prefix: "o4-mini", Input: 1.10, Output: 4.40},
+	{Provider: "openai", Prefix: "o3", Input: 2.00, Output: 8.00},
+	{Provider: "anthropic", Prefix: "claude-opus-4-5", Input: 5.00, Output: 25.00},
+	{Provider: "anthropic", Prefix: "claude-opus-4-6", Input: 5.00, Output: 25.00},
+	{Provider: "anthropic", Prefix: "claude-opus-4", Input: 15.00, Output: 75.00},
+	{Provider: "anthropic", Prefix: "claude-sonnet-4", Input: 3.00, Output: 15.00},
+	{Provider: "anthropic", Prefix: "claude-haiku-4", Input: 1.00, Output: 5.00},
+	{Provider: "anthropic", Prefix: "claude-3-5-haiku", Input: 0.80, Output: 4.00},
+}
+
+// Cache pricing relative to the input price. OpenAI discounts vary by model
+// (50-90%); the smallest discount is used so estimates err on the high side.
+const (
+	anthropicCacheReadFactor  = 0.10
+	anthropicCacheWriteFactor = 1.25
+	openAICachedInputFactor   = 0.50
+)
+
+func lookupPrice(provider, model string) (modelPrice, bool) {
+	for _, p := range modelPrices {
+		if p.Provider == provider && strings.HasPrefix(model, p.Prefix) {
+			return p, true
+		}
+	}
+	return modelPrice{}, false
+}
+
+// InputPricePerMTok returns the approximate input price of a model in USD
+// per million tokens, or false if the model is not known.
+func InputPricePerMTok(provider, model string) (float64, bool) {
+	p, ok := lookupPrice(provider, model)
+	return p.Input, ok
+}
+
+// EstimateCostUSD returns the approximate cost of one LLM call's token
+// usage, or false if the model is not known. Anthropic reports cache reads
+// and writes separately from PromptTokens; OpenAI includes cached tokens in
+// PromptTokens.
+func EstimateCostUSD(provider, model string, usage TokenUsage) (float64, bool) {
+	p, ok := lookupPrice(provider, model)
+	if !ok {
+		return 0, false
+	}
+	input := float64(usage.PromptTokens)
+	switch provider {
+	case "anthropic":
+		input += float64(usage.CachedTokens)*anthropicCacheReadFactor +
+			float64(usage.CacheCreationTokens)*anthropicCacheWriteFactor
+	default:
+		cached := float64(usage.CachedTokens)
+		input += cached*openAICachedInputFactor - cached
+	}
+	return (input*p.Input + float64(usage.CompletionTokens)*p.Output) / 1e6, true
+}
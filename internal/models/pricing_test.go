@@ -0,0 +1,41 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInputPricePerMTok(t *testing.T) {
+	price, ok := InputPricePerMTok("openai", "gpt-4o-mini")
+	assert.True(t, ok)
+	assert.Equal(t, 0.15, price, "gpt-4o-mini must not match the gpt-4o entry")
+
+	price, ok = InputPricePerMTok("anthropic", "claude-sonnet-4.5-20250929")
+	assert.True(t, ok)
+	assert.Equal(t, 3.00, price)
+
+	_, ok = InputPricePerMTok("openai", "claude-sonnet-4-0")
+	assert.False(t, ok, "provider must match")
+	_, ok = InputPricePerMTok("ollama", "llama3")
+	assert.False(t, ok)
+}
+
+func TestEstimateCostUSD(t *testing.T) {
+	cost, ok := EstimateCostUSD("openai", "gpt-4o", TokenUsage{PromptTokens: 1_000_000, CompletionTokens: 100_000})
+	assert.True(t, ok)
+	assert.InDelta(t, 3.50, cost, 1e-9)
+
+	cost, ok = EstimateCostUSD("openai", "gpt-4o", TokenUsage{PromptTokens: 1_000_000, CachedTokens: 1_000_000})
+	assert.True(t, ok)
+	assert.InDelta(t, 1.25, cost, 1e-9, "cached tokens are part of PromptTokens")
+
+	cost, ok = EstimateCostUSD("anthropic", "claude-sonnet-4-5", TokenUsage{
+		PromptTokens: 1_000_000, CachedTokens: 1_000_000, CacheCreationTokens: 1_000_000, CompletionTokens: 1_000_000,
+	})
+	assert.True(t, ok)
+	assert.InDelta(t, 3.00+0.30+3.75+15.00, cost, 1e-9)
+
+	_, ok = EstimateCostUSD("ollama", "llama3", TokenUsage{PromptTokens: 10})
+	assert.False(t, ok)
+}
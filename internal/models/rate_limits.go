@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+// RateLimitConfig configures how a session acts on the provider's reported
+// rate-limit headroom before an LLM call: when the next call would exceed
+// what is left, it waits for the window to reset, or, when that is longer
+// than MaxWaitSeconds, moves to DowngradeModel until the reset.
+type RateLimitConfig struct {
+	MaxWaitSeconds int    `json:"max_wait_seconds,omitempty"` // 0 = DefaultRateLimitMaxWaitSeconds
+	DowngradeModel string `json:"downgrade_model,omitempty"`  // "provider/model"; "" = never downgrade
+}
+
+// DefaultRateLimitMaxWaitSeconds is the longest a call waits for a rate-limit
+// window to reset.
+const DefaultRateLimitMaxWaitSeconds = 60
+
+// MaxWait returns the longest wait for a reset.
+func (c RateLimitConfig) MaxWait() time.Duration {
+	if c.MaxWaitSeconds <= 0 {
+		return DefaultRateLimitMaxWaitSeconds * time.Second
+	}
+	return time.Duration(c.MaxWaitSeconds) * time.Second
+}
+
+// Downgrade returns the provider and model of DowngradeModel; ok is false
+// when none is set or it is malformed.
+func (c RateLimitConfig) Downgrade() (provider, model string, ok bool) {
+	provider, model, err := ParseModelRef(c.DowngradeModel)
+	return provider, model, err == nil
+}
+
+// Validate checks DowngradeModel, when set, is "provider/model".
+func (c RateLimitConfig) Validate() error {
+	if c.DowngradeModel == "" {
+		return nil
+	}
+	_, _, err := ParseModelRef(c.DowngradeModel)
+	return err
+}
+
+// ResetAt returns when the window resets, if the provider said.
+func (w *RateLimitWindow) ResetAt() (time.Time, bool) {
+	if w == nil || w.Reset == nil {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, *w.Reset)
+	return t, err == nil
+}
+
+// ExhaustedUntil reports whether a call needing tokens would exceed the
+// headroom in s — no requests left, or fewer tokens left than it needs —
+// and until when. Windows without a reset time are ignored, since there
+// is nothing to wait for.
+func (s *RateLimitSnapshot) ExhaustedUntil(tokens int) (time.Time, bool) {
+	if s == nil {
+		return time.Time{}, false
+	}
+	var until time.Time
+	check := func(w *RateLimitWindow, need int) {
+		if w == nil || w.Remaining == nil || *w.Remaining >= need {
+			return
+		}
+		if reset, ok := w.ResetAt(); ok && reset.After(until) {
+			until = reset
+		}
+	}
+	check(s.Requests, 1)
+	check(s.Tokens, tokens)
+	return until, !until.IsZero()
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rateLimitWindow(remaining int, reset time.Time) *RateLimitWindow {
+	r := reset.Format(time.RFC3339Nano)
+	return &RateLimitWindow{Remaining: &remaining, Reset: &r}
+}
+
+func TestRateLimitSnapshot_ExhaustedUntil(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := &RateLimitSnapshot{
+		Requests: rateLimitWindow(3, now.Add(time.Second)),
+		Tokens:   rateLimitWindow(5000, now.Add(time.Minute)),
+	}
+
+	_, exhausted := s.ExhaustedUntil(4000)
+	assert.False(t, exhausted)
+
+	until, exhausted := s.ExhaustedUntil(6000)
+	assert.True(t, exhausted)
+	assert.Equal(t, now.Add(time.Minute), until)
+
+	s.Requests = rateLimitWindow(0, now.Add(2*time.Minute))
+	until, _ = s.ExhaustedUntil(6000)
+	assert.Equal(t, now.Add(2*time.Minute), until, "the later reset wins")
+
+	zero := 0
+	_, exhausted = (&RateLimitSnapshot{Requests: &RateLimitWindow{Remaining: &zero}}).ExhaustedUntil(0)
+	assert.False(t, exhausted, "no reset time, nothing to wait for")
+	_, exhausted = (*RateLimitSnapshot)(nil).ExhaustedUntil(0)
+	assert.False(t, exhausted)
+}
+
+func TestParseConfigToml_RateLimits(t *testing.T) {
+	cfg, err := ParseConfigToml([]byte("[rate_limits]\nmax_wait_seconds = 20\ndowngrade_model = \"anthropic/claude-haiku-4-5\"\n"))
+	require.NoError(t, err)
+	var sc SessionConfiguration
+	cfg.ApplyToConfig(&sc)
+	assert.Equal(t, 20*time.Second, sc.RateLimits.MaxWait())
+	provider, model, ok := sc.RateLimits.Downgrade()
+	assert.True(t, ok)
+	assert.Equal(t, "anthropic", provider)
+	assert.Equal(t, "claude-haiku-4-5", model)
+
+	assert.Equal(t, DefaultRateLimitMaxWaitSeconds*time.Second, RateLimitConfig{}.MaxWait())
+
+	_, err = ParseConfigToml([]byte("[rate_limits]\ndowngrade_model = \"gpt-4o-mini\"\n"))
+	assert.ErrorContains(t, err, "rate_limits")
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// RetentionConfig controls how long session artifacts are kept and which
+// conversation content is persisted beyond the live context window: in
+// memory transcripts (phase-1 extraction input and the summaries derived
+// from it) and in workflow state after compaction. Content that is not
+// stored is replaced by a placeholder carrying its length and a short
+// hash, so items still line up and identical content can be correlated.
+//
+// Temporal's own event history is governed by the namespace retention
+// period, not by this config.
+type RetentionConfig struct {
+	// TTLDays is how long session artifacts are kept: memory rollout
+	// summaries older than this are removed, and `tcx sessions prune` uses
+	// it as the default age. 0 = keep forever.
+	TTLDays int `json:"ttl_days,omitempty"`
+
+	StoreToolOutputs  *bool `json:"store_tool_outputs,omitempty"`  // nil = true
+	StoreUserMessages *bool `json:"store_user_messages,omitempty"` // nil = true
+
+	// HistoryWindowItems and HistoryWindowBytes bound the conversation items
+	// held in workflow state (item count and JSON-encoded size). Once a
+	// limit is exceeded after a turn, the oldest whole turns are moved to
+	// the session archive on the worker and replaced by a short summary.
+	// 0 = unbounded.
+	HistoryWindowItems int `json:"history_window_items,omitempty"`
+	HistoryWindowBytes int `json:"history_window_bytes,omitempty"`
+}
+
+// HistoryWindowed reports whether a history window limit is set.
+func (r RetentionConfig) HistoryWindowed() bool {
+	return r.HistoryWindowItems > 0 || r.HistoryWindowBytes > 0
+}
+
+// KeepToolOutputs reports whether tool outputs are persisted in full.
+func (r RetentionConfig) KeepToolOutputs() bool {
+	return r.StoreToolOutputs == nil || *r.StoreToolOutputs
+}
+
+// KeepUserMessages reports whether user messages are persisted in full.
+func (r RetentionConfig) KeepUserMessages() bool {
+	return r.StoreUserMessages == nil || *r.StoreUserMessages
+}
+
+// RedactItem returns item with its content replaced by a placeholder if
+// the policy does not store that kind of content. The input is not modified.
+func (r RetentionConfig) RedactItem(item ConversationItem) ConversationItem {
+	switch item.Type {
+	case ItemTypeUserMessage:
+		if !r.KeepUserMessages() && item.Content != "" {
+			item.Content = withheld("user message", item.Content)
+		}
+	case ItemTypeFunctionCallOutput:
+		if !r.KeepToolOutputs() && item.Output != nil && item.Output.Content != "" {
+			out := *item.Output
+			out.Content = withheld("tool output", out.Content)
+			item.Output = &out
+		}
+	}
+	return item
+}
+
+// Redact applies RedactItem to every item, returning a new slice.
+func (r RetentionConfig) Redact(items []ConversationItem) []ConversationItem {
+	out := make([]ConversationItem, len(items))
+	for i, item := range items {
+		out[i] = r.RedactItem(item)
+	}
+	return out
+}
+
+// withheld is the placeholder for content that is not stored, e.g.
+// "[tool output withheld: 1234 bytes, sha256:0123456789ab]".
+func withheld(kind, content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return fmt.Sprintf("[%s withheld: %d bytes, sha256:%s]", kind, len(content), hex.EncodeToString(sum[:6]))
+}
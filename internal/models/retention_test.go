@@ -0,0 +1,44 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetentionConfig_DefaultsKeepEverything(t *testing.T) {
+	var r RetentionConfig
+	assert.True(t, r.KeepToolOutputs())
+	assert.True(t, r.KeepUserMessages())
+
+	items := []ConversationItem{
+		{Type: ItemTypeUserMessage, Content: "hello"},
+		{Type: ItemTypeFunctionCallOutput, Output: &FunctionCallOutputPayload{Content: "out"}},
+	}
+	assert.Equal(t, items, r.Redact(items))
+}
+
+func TestRetentionConfig_Redact(t *testing.T) {
+	off := false
+	r := RetentionConfig{StoreToolOutputs: &off, StoreUserMessages: &off}
+
+	items := []ConversationItem{
+		{Type: ItemTypeUserMessage, Content: "my api key is hunter2"},
+		{Type: ItemTypeAssistantMessage, Content: "noted"},
+		{Type: ItemTypeFunctionCall, Name: "shell_command", Arguments: `{"command":"env"}`},
+		{Type: ItemTypeFunctionCallOutput, CallID: "c1", Output: &FunctionCallOutputPayload{Content: "SECRET=1"}},
+	}
+	got := r.Redact(items)
+
+	assert.True(t, strings.HasPrefix(got[0].Content, "[user message withheld: 21 bytes, sha256:"), got[0].Content)
+	assert.NotContains(t, got[0].Content, "hunter2")
+	assert.Equal(t, "noted", got[1].Content)
+	assert.Equal(t, items[2], got[2])
+	assert.Equal(t, "c1", got[3].CallID)
+	assert.True(t, strings.HasPrefix(got[3].Output.Content, "[tool output withheld: 8 bytes, sha256:"), got[3].Output.Content)
+	assert.Equal(t, "SECRET=1", items[3].Output.Content, "input is not modified")
+
+	// Identical content hashes identically, so redacted items can be correlated.
+	assert.Equal(t, got[0].Content, r.RedactItem(items[0]).Content)
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	// Embedded zone database, so a zone resolves the same on every worker
+	// and CLI host regardless of the installed tzdata.
+	_ "time/tzdata"
+)
+
+// Timezone and locale of the user, used for the current date given to the
+// model at the start of each turn and for timestamps rendered by the CLI.
+// A timezone is an IANA name ("Europe/Berlin"); empty means UTC. A locale
+// is a BCP 47 tag ("de-DE") passed to the model as a formatting hint.
+
+// ValidateTimezone checks that name is empty or a known IANA zone.
+func ValidateTimezone(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("unknown timezone %q (want an IANA name such as Europe/Berlin)", name)
+	}
+	return nil
+}
+
+// LoadTimezone returns the location named name, or UTC when name is empty
+// or unknown.
+func LoadTimezone(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
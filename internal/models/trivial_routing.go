@@ -0,0 +1,48 @@
+package models
+
+import "fmt"
+
+// DefaultTrivialMaxChars is the longest user message a turn can have and
+// still be routed as trivial.
+const DefaultTrivialMaxChars = 200
+
+// TrivialRoutingConfig sends obviously trivial turns — a short question
+// with no files mentioned and nothing that asks for tools — to a cheaper
+// model. The session's own model takes over when the cheap model requests
+// tools or the user objects to its answer.
+type TrivialRoutingConfig struct {
+	Model    string `json:"model"`               // "provider/model" of the cheap model
+	MaxChars int    `json:"max_chars,omitempty"` // 0 = DefaultTrivialMaxChars
+}
+
+// Target returns the provider and model trivial turns are routed to; ok is
+// false when the routing is not configured or Model is malformed.
+func (c *TrivialRoutingConfig) Target() (provider, model string, ok bool) {
+	if c == nil {
+		return "", "", false
+	}
+	provider, model, err := ParseModelRef(c.Model)
+	return provider, model, err == nil
+}
+
+// MaxMessageChars returns the longest user message of a trivial turn.
+func (c *TrivialRoutingConfig) MaxMessageChars() int {
+	if c == nil || c.MaxChars <= 0 {
+		return DefaultTrivialMaxChars
+	}
+	return c.MaxChars
+}
+
+// Validate checks Model is "provider/model" and MaxChars is not negative.
+func (c *TrivialRoutingConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if _, _, err := ParseModelRef(c.Model); err != nil {
+		return err
+	}
+	if c.MaxChars < 0 {
+		return fmt.Errorf("max_chars must not be negative")
+	}
+	return nil
+}
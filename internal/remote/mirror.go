@@ -0,0 +1,156 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Mirror stages remote files in a local directory so the file tools can
+// work on them unchanged: the remote file /src/app/main.go is
+// <Root>/src/app/main.go. Only the files a call needs are fetched, and
+// changed files are pushed back after it.
+type Mirror struct {
+	Target Target
+	Root   string
+}
+
+// NewMirror creates an empty mirror of t in a new temporary directory.
+// Close removes it.
+func NewMirror(t Target) (*Mirror, error) {
+	root, err := os.MkdirTemp("", "tcx-remote-")
+	if err != nil {
+		return nil, err
+	}
+	// Resolve symlinks (e.g. /var on macOS) so that paths reported by
+	// the tools start with Root.
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	return &Mirror{Target: t, Root: root}, nil
+}
+
+// Close removes the mirror directory.
+func (m *Mirror) Close() error {
+	return os.RemoveAll(m.Root)
+}
+
+// Local returns the mirror path of an absolute remote path.
+func (m *Mirror) Local(remotePath string) string {
+	return filepath.Join(m.Root, filepath.FromSlash(path.Clean("/"+remotePath)))
+}
+
+// Strip replaces mirror paths in s with the remote paths they stand for.
+func (m *Mirror) Strip(s string) string {
+	return strings.ReplaceAll(s, m.Root, "")
+}
+
+// Fetch downloads remote files into the mirror; files missing on the
+// target are skipped.
+func (m *Mirror) Fetch(ctx context.Context, remotePaths ...string) error {
+	transfers, err := m.transfers(remotePaths)
+	if err != nil {
+		return err
+	}
+	return m.Target.Fetch(ctx, transfers)
+}
+
+// Push uploads mirror files to their remote paths.
+func (m *Mirror) Push(ctx context.Context, remotePaths ...string) error {
+	transfers, err := m.transfers(remotePaths)
+	if err != nil {
+		return err
+	}
+	return m.Target.Push(ctx, transfers)
+}
+
+// Remove deletes remote files.
+func (m *Mirror) Remove(ctx context.Context, remotePaths ...string) error {
+	return m.Target.Remove(ctx, remotePaths)
+}
+
+// transfers pairs remote paths with their mirror paths, creating the
+// mirror's parent directories.
+func (m *Mirror) transfers(remotePaths []string) ([]Transfer, error) {
+	transfers := make([]Transfer, 0, len(remotePaths))
+	for _, p := range remotePaths {
+		local := m.Local(p)
+		if err := os.MkdirAll(filepath.Dir(local), 0o755); err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, Transfer{Remote: p, Local: local})
+	}
+	return transfers, nil
+}
+
+// FetchTree mirrors the entries under the remote directory dir, up to
+// depth levels deep, without their contents: directories are created,
+// files are empty and symlinks dangle. It is enough for listing. The
+// remote find must support -printf (GNU findutils).
+func (m *Mirror) FetchTree(ctx context.Context, dir string, depth int) error {
+	script := "find " + Quote(dir) + " -mindepth 1 -maxdepth " + strconv.Itoa(depth) + ` -printf '%y %p\0'`
+	out, err := m.Target.Output(ctx, script)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() == ConnectionFailedExitCode {
+			return fmt.Errorf("ssh %s: %s", m.Target.Destination(), ErrorText(err))
+		}
+		// find reports unreadable entries and a missing dir with a
+		// non-zero exit; mirror what it printed.
+	}
+	if len(out) > 0 || err == nil {
+		if err := os.MkdirAll(m.Local(dir), 0o755); err != nil {
+			return err
+		}
+	}
+	return m.createEntries(out)
+}
+
+// createEntries creates the entries of find -printf '%y %p\0' output.
+func (m *Mirror) createEntries(out []byte) error {
+	for _, rec := range bytes.Split(out, []byte{0}) {
+		kind, p, ok := strings.Cut(string(rec), " ")
+		if !ok || p == "" {
+			continue
+		}
+		local := m.Local(p)
+		if err := os.MkdirAll(filepath.Dir(local), 0o755); err != nil {
+			return err
+		}
+		var err error
+		switch kind {
+		case "d":
+			err = os.MkdirAll(local, 0o755)
+		case "l":
+			err = os.Symlink(".remote-link", local)
+		default:
+			err = os.WriteFile(local, nil, 0o644)
+		}
+		if err != nil && !os.IsExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// ErrorText describes an error of Output: the stderr of the failed
+// command when there is one, else the error itself.
+func ErrorText(err error) string {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if msg := strings.TrimSpace(string(exitErr.Stderr)); msg != "" {
+			return msg
+		}
+	}
+	return err.Error()
+}
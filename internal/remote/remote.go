@@ -0,0 +1,346 @@
+// Package remote runs tool commands and file transfers on a remote machine
+// over SSH, so a session's shell, exec and file tools operate on a remote
+// dev box while the workflow, the LLM activities and the CLI stay local.
+//
+// It drives the tool worker's OpenSSH client (ssh and sftp) rather than an
+// SSH library: ~/.ssh/config, known_hosts, ProxyJump and the SSH agent of
+// the worker apply as they would in a terminal, and connections can be
+// shared with ControlMaster.
+package remote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ConnectionFailedExitCode is the exit status of ssh when it could not
+// connect or the remote side dropped the connection.
+const ConnectionFailedExitCode = 255
+
+// sshOptions are passed to every ssh and sftp invocation. BatchMode makes a
+// missing key or unknown host fail instead of prompting on the worker.
+var sshOptions = []string{"-o", "BatchMode=yes", "-o", "ServerAliveInterval=15"}
+
+// Target is an SSH destination.
+type Target struct {
+	Host string
+	// User defaults to the worker's SSH configuration for Host.
+	User string
+	// Port defaults to 22 (or the worker's SSH configuration).
+	Port int
+	// KeyPath is a private key file on the worker: an absolute path or one
+	// relative to the worker's home directory ("~/.ssh/id_devbox").
+	KeyPath string
+}
+
+// Destination returns the [user@]host argument of ssh and sftp.
+func (t Target) Destination() string {
+	if t.User != "" {
+		return t.User + "@" + t.Host
+	}
+	return t.Host
+}
+
+// Validate checks that the destination can be passed to ssh and sftp
+// safely: neither host nor user may be taken for an option or split into
+// another destination, and KeyPath must be a clean absolute or ~/ path.
+func (t Target) Validate() error {
+	if t.Host == "" {
+		return fmt.Errorf("host is required")
+	}
+	for _, f := range []struct{ name, value string }{{"host", t.Host}, {"user", t.User}} {
+		if strings.HasPrefix(f.value, "-") || strings.ContainsAny(f.value, "@ \t\n") {
+			return fmt.Errorf("invalid %s %q", f.name, f.value)
+		}
+	}
+	if t.Port < 0 || t.Port > 65535 {
+		return fmt.Errorf("port %d out of range", t.Port)
+	}
+	if t.KeyPath != "" {
+		p := strings.TrimPrefix(t.KeyPath, "~/")
+		if (p == t.KeyPath && !filepath.IsAbs(p)) || filepath.Clean(p) != p || ValidatePath(p) != nil {
+			return fmt.Errorf("invalid key path %q", t.KeyPath)
+		}
+	}
+	return nil
+}
+
+// options returns the shared connection options; portFlag is "-p" for ssh
+// and "-P" for sftp.
+func (t Target) options(portFlag string) []string {
+	opts := append([]string(nil), sshOptions...)
+	if t.Port > 0 {
+		opts = append(opts, portFlag, strconv.Itoa(t.Port))
+	}
+	if t.KeyPath != "" {
+		opts = append(opts, "-i", t.KeyPath)
+	}
+	return opts
+}
+
+// Command returns the local argv that runs script on the target with the
+// remote user's shell. tty allocates a remote terminal for interactive
+// programs.
+func (t Target) Command(script string, tty bool) []string {
+	argv := append([]string{"ssh"}, t.options("-p")...)
+	if tty {
+		argv = append(argv, "-tt")
+	} else {
+		argv = append(argv, "-T")
+	}
+	// "--" ends the options, so the destination is never parsed as one.
+	return append(argv, "--", t.Destination(), script)
+}
+
+// Output runs script on the target and returns its stdout. A non-zero exit
+// is returned as *exec.ExitError with the captured stderr.
+func (t Target) Output(ctx context.Context, script string) ([]byte, error) {
+	argv := t.Command(script, false)
+	return exec.CommandContext(ctx, argv[0], argv[1:]...).Output()
+}
+
+// Script builds the remote shell script that runs command in cwd with env
+// (KEY=VALUE entries) exported. An empty cwd keeps the login directory.
+func Script(cwd string, env []string, command string) string {
+	var parts []string
+	if cwd != "" {
+		parts = append(parts, "cd "+Quote(cwd))
+	}
+	if len(env) > 0 {
+		assignments := make([]string, 0, len(env))
+		for _, kv := range env {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok || !validEnvName(k) {
+				continue
+			}
+			assignments = append(assignments, k+"="+Quote(v))
+		}
+		if len(assignments) > 0 {
+			parts = append(parts, "export "+strings.Join(assignments, " "))
+		}
+	}
+	parts = append(parts, command)
+	return strings.Join(parts, " && ")
+}
+
+// ShellCommand returns the remote command that runs command through the
+// remote user's shell ($SHELL), as a login shell when login is set.
+func ShellCommand(command string, login bool) string {
+	flag := "-c"
+	if login {
+		flag = "-lc"
+	}
+	return `exec "${SHELL:-/bin/sh}" ` + flag + " " + Quote(command)
+}
+
+// ExecCommand returns the remote command that executes argv directly.
+func ExecCommand(argv []string) string {
+	return "exec " + QuoteArgv(argv)
+}
+
+// Quote quotes s for a POSIX shell.
+func Quote(s string) string {
+	if s != "" && strings.IndexFunc(s, needsQuote) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// QuoteArgv quotes each argument of argv for a POSIX shell.
+func QuoteArgv(argv []string) string {
+	quoted := make([]string, len(argv))
+	for i, a := range argv {
+		quoted[i] = Quote(a)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// needsQuote reports whether r is outside the set of characters a POSIX
+// shell takes literally.
+func needsQuote(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return false
+	}
+	return !strings.ContainsRune("@%+=:,./-_", r)
+}
+
+// validEnvName reports whether name can be exported by a POSIX shell.
+func validEnvName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, r := range name {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Transfer is one file copied by SFTP: Remote is the path on the target,
+// Local the path on the worker.
+type Transfer struct {
+	Remote string
+	Local  string
+}
+
+// Validate checks both paths of the transfer with ValidatePath.
+func (f Transfer) Validate() error {
+	if err := ValidatePath(f.Remote); err != nil {
+		return err
+	}
+	return ValidatePath(f.Local)
+}
+
+// ValidatePath checks that p can be written to an sftp batch. Each batch
+// command is one line, so a newline in a path would start another command,
+// and sftp runs a line starting with '!' as a local shell command.
+func ValidatePath(p string) error {
+	if strings.IndexFunc(p, unicode.IsControl) >= 0 {
+		return fmt.Errorf("path %q contains a control character", p)
+	}
+	return nil
+}
+
+// Fetch downloads files from the target. Files that do not exist on the
+// target are skipped.
+func (t Target) Fetch(ctx context.Context, files []Transfer) error {
+	if len(files) == 0 {
+		return nil
+	}
+	for _, f := range files {
+		if err := f.Validate(); err != nil {
+			return err
+		}
+	}
+	return t.sftp(ctx, fetchBatch(files))
+}
+
+// Push uploads files to the target, creating missing parent directories.
+func (t Target) Push(ctx context.Context, files []Transfer) error {
+	if len(files) == 0 {
+		return nil
+	}
+	for _, f := range files {
+		if err := f.Validate(); err != nil {
+			return err
+		}
+	}
+	return t.sftp(ctx, pushBatch(files))
+}
+
+// Remove deletes files on the target.
+func (t Target) Remove(ctx context.Context, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	for _, p := range paths {
+		if err := ValidatePath(p); err != nil {
+			return err
+		}
+	}
+	return t.sftp(ctx, removeBatch(paths))
+}
+
+// sftp runs an sftp batch against the target. Commands prefixed with '-'
+// may fail without aborting the batch.
+func (t Target) sftp(ctx context.Context, batch []string) error {
+	argv := append([]string{"sftp", "-b", "-"}, t.options("-P")...)
+	argv = append(argv, "--", t.Destination())
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Stdin = strings.NewReader(strings.Join(batch, "\n") + "\n")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return fmt.Errorf("sftp %s: %s", t.Destination(), strings.TrimSpace(stderr.String()))
+		}
+		return fmt.Errorf("failed to launch sftp: %w", err)
+	}
+	return nil
+}
+
+// fetchBatch returns the sftp commands that download files, ignoring
+// missing ones.
+func fetchBatch(files []Transfer) []string {
+	batch := make([]string, 0, len(files))
+	for _, f := range files {
+		batch = append(batch, "-get "+sftpQuote(f.Remote)+" "+sftpQuote(f.Local))
+	}
+	return batch
+}
+
+// pushBatch returns the sftp commands that upload files after creating
+// their parent directories; mkdir of an existing directory is ignored.
+func pushBatch(files []Transfer) []string {
+	dirs := make(map[string]bool)
+	for _, f := range files {
+		for dir := parentDir(f.Remote); dir != "" && dir != "/" && dir != "."; dir = parentDir(dir) {
+			dirs[dir] = true
+		}
+	}
+	sorted := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		sorted = append(sorted, dir)
+	}
+	// Parents sort before their children.
+	sort.Strings(sorted)
+
+	batch := make([]string, 0, len(sorted)+len(files))
+	for _, dir := range sorted {
+		batch = append(batch, "-mkdir "+sftpQuote(dir))
+	}
+	for _, f := range files {
+		batch = append(batch, "put "+sftpQuote(f.Local)+" "+sftpQuote(f.Remote))
+	}
+	return batch
+}
+
+// removeBatch returns the sftp commands that delete paths.
+func removeBatch(paths []string) []string {
+	batch := make([]string, 0, len(paths))
+	for _, p := range paths {
+		batch = append(batch, "rm "+sftpQuote(p))
+	}
+	return batch
+}
+
+// parentDir returns the parent of a slash-separated remote path.
+func parentDir(p string) string {
+	i := strings.LastIndex(strings.TrimSuffix(p, "/"), "/")
+	switch {
+	case i < 0:
+		return ""
+	case i == 0:
+		return "/"
+	}
+	return p[:i]
+}
+
+// sftpQuote quotes a path for an sftp batch file. Glob characters are
+// escaped because sftp expands them in get and rm.
+func sftpQuote(p string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range p {
+		if strings.ContainsRune(`\"*?[]`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
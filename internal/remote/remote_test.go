@@ -0,0 +1,120 @@
+package remote
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuote(t *testing.T) {
+	assert.Equal(t, "/src/app", Quote("/src/app"))
+	assert.Equal(t, "''", Quote(""))
+	assert.Equal(t, "'a b'", Quote("a b"))
+	assert.Equal(t, `'it'\''s'`, Quote("it's"))
+	assert.Equal(t, "'$HOME'", Quote("$HOME"))
+	assert.Equal(t, `go test ./... -run 'Test(A|B)'`, QuoteArgv([]string{"go", "test", "./...", "-run", "Test(A|B)"}))
+}
+
+func TestScript(t *testing.T) {
+	script := Script("/src/my app", []string{"PAGER=cat", "NO_COLOR=1", "BAD-NAME=x", "LANG=C.UTF-8"}, ShellCommand("make test", true))
+	assert.Equal(t, `cd '/src/my app' && export PAGER=cat NO_COLOR=1 LANG=C.UTF-8 && exec "${SHELL:-/bin/sh}" -lc 'make test'`, script)
+
+	assert.Equal(t, "exec ls -la", Script("", nil, ExecCommand([]string{"ls", "-la"})))
+	assert.Equal(t, `exec "${SHELL:-/bin/sh}" -c 'echo "$X"'`, ShellCommand(`echo "$X"`, false))
+}
+
+func TestTarget_Command(t *testing.T) {
+	target := Target{Host: "devbox", User: "ana", Port: 2222, KeyPath: "/keys/devbox"}
+	assert.Equal(t, "ana@devbox", target.Destination())
+	assert.Equal(t, []string{
+		"ssh", "-o", "BatchMode=yes", "-o", "ServerAliveInterval=15", "-p", "2222", "-i", "/keys/devbox",
+		"-tt", "--", "ana@devbox", "exec top",
+	}, target.Command("exec top", true))
+
+	argv := Target{Host: "devbox"}.Command("true", false)
+	assert.Equal(t, []string{"ssh", "-o", "BatchMode=yes", "-o", "ServerAliveInterval=15", "-T", "--", "devbox", "true"}, argv)
+}
+
+func TestTarget_Validate(t *testing.T) {
+	assert.NoError(t, Target{Host: "devbox", User: "ana", Port: 22}.Validate())
+	assert.Error(t, Target{}.Validate())
+	assert.Error(t, Target{Host: "-oProxyCommand=sh"}.Validate())
+	assert.Error(t, Target{Host: "devbox", User: "-lroot"}.Validate())
+	assert.Error(t, Target{Host: "eve@devbox"}.Validate())
+	assert.Error(t, Target{Host: "dev box"}.Validate())
+	assert.Error(t, Target{Host: "devbox", Port: 70000}.Validate())
+
+	assert.NoError(t, Target{Host: "devbox", KeyPath: "/home/ana/.ssh/id_devbox"}.Validate())
+	assert.NoError(t, Target{Host: "devbox", KeyPath: "~/.ssh/id_devbox"}.Validate())
+	assert.Error(t, Target{Host: "devbox", KeyPath: "id_devbox"}.Validate())
+	assert.Error(t, Target{Host: "devbox", KeyPath: "-oProxyCommand=sh"}.Validate())
+	assert.Error(t, Target{Host: "devbox", KeyPath: "~/.ssh/../../../etc/shadow"}.Validate())
+	assert.Error(t, Target{Host: "devbox", KeyPath: "/home/ana/.ssh/id\n"}.Validate())
+}
+
+func TestTransfer_Validate(t *testing.T) {
+	assert.NoError(t, Transfer{Remote: "/src/app/my file.go", Local: "/tmp/m/x"}.Validate())
+	assert.Error(t, Transfer{Remote: "/src/app/x\n!touch /tmp/pwned #", Local: "/tmp/m/x"}.Validate())
+	assert.Error(t, Transfer{Remote: "/src/app/x", Local: "/tmp/m/x\r"}.Validate())
+
+	// A path with a newline never reaches sftp.
+	target := Target{Host: "devbox"}
+	assert.Error(t, target.Fetch(context.Background(), []Transfer{{Remote: "x\n!id #", Local: "/tmp/m/x"}}))
+	assert.Error(t, target.Push(context.Background(), []Transfer{{Remote: "x\n!id #", Local: "/tmp/m/x"}}))
+	assert.Error(t, target.Remove(context.Background(), []string{"x\n!id #"}))
+}
+
+func TestSFTPBatches(t *testing.T) {
+	assert.Equal(t, []string{
+		`-get "/src/app/a.go" "/tmp/m/src/app/a.go"`,
+		`-get "/src/app/we\"ird \*.go" "/tmp/m/x"`,
+	}, fetchBatch([]Transfer{
+		{Remote: "/src/app/a.go", Local: "/tmp/m/src/app/a.go"},
+		{Remote: `/src/app/we"ird *.go`, Local: "/tmp/m/x"},
+	}))
+
+	assert.Equal(t, []string{
+		`-mkdir "/src"`,
+		`-mkdir "/src/app"`,
+		`-mkdir "/src/app/pkg"`,
+		`put "/tmp/m/a.go" "/src/app/a.go"`,
+		`put "/tmp/m/b.go" "/src/app/pkg/b.go"`,
+	}, pushBatch([]Transfer{
+		{Remote: "/src/app/a.go", Local: "/tmp/m/a.go"},
+		{Remote: "/src/app/pkg/b.go", Local: "/tmp/m/b.go"},
+	}))
+
+	assert.Equal(t, []string{`rm "/src/app/old.go"`}, removeBatch([]string{"/src/app/old.go"}))
+}
+
+func TestMirror_Paths(t *testing.T) {
+	m := &Mirror{Root: "/tmp/tcx-remote-1"}
+	assert.Equal(t, filepath.Join("/tmp/tcx-remote-1", "src", "app", "a.go"), m.Local("/src/app/a.go"))
+	assert.Equal(t, filepath.Join("/tmp/tcx-remote-1", "etc", "passwd"), m.Local("/src/../../etc/passwd"), "paths stay inside the mirror")
+	assert.Equal(t, "Wrote /src/app/a.go", m.Strip("Wrote /tmp/tcx-remote-1/src/app/a.go"))
+}
+
+func TestMirror_CreateEntries(t *testing.T) {
+	m, err := NewMirror(Target{Host: "devbox"})
+	require.NoError(t, err)
+	defer m.Close()
+
+	require.NoError(t, m.createEntries([]byte("d /src/app/pkg\x00f /src/app/pkg/a.go\x00l /src/app/link\x00f /src/app/my file.txt\x00")))
+
+	info, err := os.Stat(m.Local("/src/app/pkg"))
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+	info, err = os.Lstat(m.Local("/src/app/link"))
+	require.NoError(t, err)
+	assert.NotZero(t, info.Mode()&os.ModeSymlink)
+	_, err = os.Stat(m.Local("/src/app/my file.txt"))
+	assert.NoError(t, err)
+
+	require.NoError(t, m.Close())
+	_, err = os.Stat(m.Root)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,158 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// webhookTimeout bounds one webhook POST.
+const webhookTimeout = 30 * time.Second
+
+// Deliverer sends rendered reports to the channels of a
+// models.SessionReportConfig.
+type Deliverer struct {
+	client   *http.Client
+	getenv   func(string) string
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+	now      func() time.Time
+}
+
+// NewDeliverer returns a Deliverer that reads SMTP credentials from the
+// environment (SMTP_USERNAME, SMTP_PASSWORD).
+func NewDeliverer() *Deliverer {
+	return &Deliverer{
+		client:   &http.Client{Timeout: webhookTimeout},
+		getenv:   os.Getenv,
+		sendMail: smtp.SendMail,
+		now:      time.Now,
+	}
+}
+
+// Deliver renders r and sends it to every configured channel. It returns
+// the channels that received it and an error per channel that failed, so
+// one unreachable channel does not keep the report from the others.
+func (d *Deliverer) Deliver(ctx context.Context, cfg models.SessionReportConfig, r Report) (delivered []string, errs []error) {
+	body, err := Render(r, cfg.Format)
+	if err != nil {
+		return nil, []error{err}
+	}
+	if cfg.Path != "" {
+		if path, err := d.writeFile(cfg.Path, r.SessionID, body); err != nil {
+			errs = append(errs, err)
+		} else {
+			delivered = append(delivered, path)
+		}
+	}
+	if cfg.WebhookURL != "" {
+		if err := d.postWebhook(ctx, cfg, r, body); err != nil {
+			errs = append(errs, err)
+		} else {
+			delivered = append(delivered, "webhook")
+		}
+	}
+	if len(cfg.EmailTo) > 0 {
+		if err := d.sendEmail(cfg, r, body); err != nil {
+			errs = append(errs, err)
+		} else {
+			delivered = append(delivered, "email to "+strings.Join(cfg.EmailTo, ", "))
+		}
+	}
+	return delivered, errs
+}
+
+// ExpandPath expands {session} and a leading ~/ in a report path.
+func ExpandPath(path, sessionID string) (string, error) {
+	path = strings.ReplaceAll(path, "{session}", sessionID)
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, rest)
+	}
+	return path, nil
+}
+
+func (d *Deliverer) writeFile(pattern, sessionID, body string) (string, error) {
+	path, err := ExpandPath(pattern, sessionID)
+	if err != nil {
+		return "", fmt.Errorf("report: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("report: create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		return "", fmt.Errorf("report: write %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// webhookPayload is the JSON body of the webhook POST. Text carries the
+// rendered report, which chat webhooks (Slack, Mattermost) display as is.
+type webhookPayload struct {
+	Title  string `json:"title"`
+	Format string `json:"format"`
+	Text   string `json:"text"`
+	Report Report `json:"report"`
+}
+
+func (d *Deliverer) postWebhook(ctx context.Context, cfg models.SessionReportConfig, r Report, body string) error {
+	format := cfg.Format
+	if format == "" {
+		format = models.ReportFormatMarkdown
+	}
+	data, err := json.Marshal(webhookPayload{Title: r.Title(), Format: format, Text: body, Report: r})
+	if err != nil {
+		return fmt.Errorf("report: webhook: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.WebhookURL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("report: webhook: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("report: webhook: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("report: webhook: %s", resp.Status)
+	}
+	return nil
+}
+
+func (d *Deliverer) sendEmail(cfg models.SessionReportConfig, r Report, body string) error {
+	var auth smtp.Auth
+	if user := d.getenv("SMTP_USERNAME"); user != "" {
+		host, _, _ := net.SplitHostPort(cfg.SMTPServer)
+		auth = smtp.PlainAuth("", user, d.getenv("SMTP_PASSWORD"), host)
+	}
+	contentType := "text/markdown; charset=utf-8"
+	if cfg.Format == models.ReportFormatHTML {
+		contentType = "text/html; charset=utf-8"
+	}
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.EmailFrom)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(cfg.EmailTo, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", r.Title()))
+	fmt.Fprintf(&msg, "Date: %s\r\n", d.now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: %s\r\n\r\n", contentType)
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	if err := d.sendMail(cfg.SMTPServer, auth, cfg.EmailFrom, cfg.EmailTo, msg.Bytes()); err != nil {
+		return fmt.Errorf("report: email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+// Package report renders the end-of-session report: the turns of a
+// session, the working tree diffstat, token usage and cost, and plan
+// verification results, as Markdown or HTML. It is written for async
+// review of unattended runs, where nobody watched the terminal.
+//
+// The workflow collects the session data when it completes and an activity
+// on the tool worker adds the diffstat and delivers the report (see
+// Deliverer).
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// Turn statuses.
+const (
+	TurnCompleted   = "completed"
+	TurnFailed      = "failed"
+	TurnInterrupted = "interrupted"
+)
+
+// Report is the data of one end-of-session report.
+type Report struct {
+	SessionID string    `json:"session_id"`
+	Model     string    `json:"model"`
+	Cwd       string    `json:"cwd"`
+	EndedAt   time.Time `json:"ended_at"`
+	// EndReason is how the session ended: "shutdown", "completed",
+	// "error" or "cancelled".
+	EndReason string `json:"end_reason"`
+	// Error summarizes the failure of the last turn, if it failed.
+	Error string `json:"error,omitempty"`
+
+	// TotalTurns counts every turn of the session; Turns lists those still
+	// in history (older turns may have been archived or compacted).
+	TotalTurns int    `json:"total_turns"`
+	Turns      []Turn `json:"turns"`
+
+	TotalTokens  int     `json:"total_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+	ToolCalls    int     `json:"tool_calls"`
+	ToolFailures int     `json:"tool_failures"`
+
+	Verifications []Verification `json:"verifications,omitempty"`
+
+	// Diffstat is `git diff --stat HEAD` in Cwd at the end of the session,
+	// and Untracked the number of untracked files; set by the activity.
+	Diffstat  string `json:"diffstat,omitempty"`
+	Untracked int    `json:"untracked,omitempty"`
+}
+
+// Turn summarizes one turn.
+type Turn struct {
+	TurnID    string `json:"turn_id"`
+	Request   string `json:"request"`
+	Response  string `json:"response,omitempty"`
+	ToolCalls int    `json:"tool_calls"`
+	Status    string `json:"status"`
+}
+
+// Verification is the result of one plan step verification command.
+type Verification struct {
+	Step    int    `json:"step"`
+	Command string `json:"command"`
+	Passed  bool   `json:"passed"`
+}
+
+// VerificationStatus returns "passed" when every verification passed,
+// "failed" when one failed, and "none" when nothing was verified.
+func (r Report) VerificationStatus() string {
+	if len(r.Verifications) == 0 {
+		return "none"
+	}
+	for _, v := range r.Verifications {
+		if !v.Passed {
+			return "failed"
+		}
+	}
+	return "passed"
+}
+
+// Title is the one-line summary used as the email subject.
+func (r Report) Title() string {
+	title := fmt.Sprintf("Session %s %s", r.SessionID, r.EndReason)
+	if status := r.VerificationStatus(); status != "none" {
+		title += ", verification " + status
+	}
+	return title
+}
+
+// Render renders the report in format (models.ReportFormatMarkdown or
+// models.ReportFormatHTML; empty means Markdown).
+func Render(r Report, format string) (string, error) {
+	if format == models.ReportFormatHTML {
+		return HTML(r)
+	}
+	return Markdown(r), nil
+}
+
+// Markdown renders the report as Markdown.
+func Markdown(r Report) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", r.Title())
+	for _, f := range r.facts() {
+		fmt.Fprintf(&b, "- **%s:** %s\n", f[0], f[1])
+	}
+	if r.Error != "" {
+		fmt.Fprintf(&b, "\n**Error:** %s\n", r.Error)
+	}
+
+	b.WriteString("\n## Changes\n\n")
+	if r.Diffstat == "" && r.Untracked == 0 {
+		b.WriteString("No uncommitted changes.\n")
+	} else {
+		if r.Diffstat != "" {
+			fmt.Fprintf(&b, "```\n%s\n```\n", r.Diffstat)
+		}
+		if r.Untracked > 0 {
+			fmt.Fprintf(&b, "\n%d untracked file(s).\n", r.Untracked)
+		}
+	}
+
+	if len(r.Verifications) > 0 {
+		b.WriteString("\n## Verification\n\n")
+		for _, v := range r.Verifications {
+			fmt.Fprintf(&b, "- Step %d `%s`: %s\n", v.Step, v.Command, passedWord(v.Passed))
+		}
+	}
+
+	b.WriteString("\n## Turns\n")
+	if r.TotalTurns > len(r.Turns) {
+		fmt.Fprintf(&b, "\n%d earlier turn(s) are no longer in history.\n", r.TotalTurns-len(r.Turns))
+	}
+	for i, t := range r.Turns {
+		fmt.Fprintf(&b, "\n### %d. %s\n\n", i+1, firstLine(t.Request))
+		fmt.Fprintf(&b, "_%s, %d tool call(s)_\n", t.Status, t.ToolCalls)
+		if t.Response != "" {
+			fmt.Fprintf(&b, "\n%s\n", t.Response)
+		}
+	}
+	return b.String()
+}
+
+// facts are the label/value pairs at the top of the report.
+func (r Report) facts() [][2]string {
+	facts := [][2]string{
+		{"Ended", r.EndedAt.UTC().Format(time.RFC3339)},
+		{"Model", r.Model},
+		{"Directory", r.Cwd},
+		{"Turns", fmt.Sprintf("%d", r.TotalTurns)},
+		{"Tool calls", fmt.Sprintf("%d (%d failed)", r.ToolCalls, r.ToolFailures)},
+		{"Tokens", fmt.Sprintf("%d", r.TotalTokens)},
+		{"Estimated cost", fmt.Sprintf("$%.2f", r.CostUSD)},
+		{"Verification", r.VerificationStatus()},
+	}
+	out := facts[:0]
+	for _, f := range facts {
+		if f[1] != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"add":       func(a, b int) int { return a + b },
+	"firstLine": firstLine,
+	"passed":    passedWord,
+}).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.R.Title}}</title></head>
+<body style="font-family: sans-serif">
+<h1>{{.R.Title}}</h1>
+<table>{{range .Facts}}<tr><th align="left">{{index . 0}}</th><td>{{index . 1}}</td></tr>{{end}}</table>
+{{if .R.Error}}<p><b>Error:</b> {{.R.Error}}</p>{{end}}
+<h2>Changes</h2>
+{{if or .R.Diffstat .R.Untracked}}{{if .R.Diffstat}}<pre>{{.R.Diffstat}}</pre>{{end}}{{if .R.Untracked}}<p>{{.R.Untracked}} untracked file(s).</p>{{end}}{{else}}<p>No uncommitted changes.</p>{{end}}
+{{if .R.Verifications}}<h2>Verification</h2>
+<ul>{{range .R.Verifications}}<li>Step {{.Step}} <code>{{.Command}}</code>: {{passed .Passed}}</li>{{end}}</ul>{{end}}
+<h2>Turns</h2>
+{{if .Archived}}<p>{{.Archived}} earlier turn(s) are no longer in history.</p>{{end}}
+{{range $i, $t := .R.Turns}}<h3>{{add $i 1}}. {{firstLine $t.Request}}</h3>
+<p><i>{{$t.Status}}, {{$t.ToolCalls}} tool call(s)</i></p>
+{{if $t.Response}}<pre style="white-space: pre-wrap">{{$t.Response}}</pre>{{end}}
+{{end}}</body></html>
+`))
+
+// HTML renders the report as a standalone HTML page.
+func HTML(r Report) (string, error) {
+	var b bytes.Buffer
+	err := htmlTemplate.Execute(&b, struct {
+		R        Report
+		Facts    [][2]string
+		Archived int
+	}{r, r.facts(), max(r.TotalTurns-len(r.Turns), 0)})
+	if err != nil {
+		return "", fmt.Errorf("report: render html: %w", err)
+	}
+	return b.String(), nil
+}
+
+func passedWord(passed bool) string {
+	if passed {
+		return "passed"
+	}
+	return "failed"
+}
+
+// firstLine returns the first line of s, shortened for a heading.
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	if r := []rune(s); len(r) > 80 {
+		s = string(r[:79]) + "…"
+	}
+	if s == "" {
+		return "(no request)"
+	}
+	return s
+}
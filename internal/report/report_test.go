@@ -0,0 +1,127 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+func sampleReport() Report {
+	return Report{
+		SessionID:  "s-1",
+		Model:      "gpt-4o",
+		Cwd:        "/src/app",
+		EndedAt:    time.Date(2026, 3, 1, 6, 0, 0, 0, time.UTC),
+		EndReason:  "completed",
+		TotalTurns: 3,
+		Turns: []Turn{
+			{TurnID: "turn-3", Request: "Fix the flaky test\nin pkg/x", Response: "Fixed <the> race.", ToolCalls: 4, Status: TurnCompleted},
+		},
+		TotalTokens:   1200,
+		CostUSD:       0.42,
+		ToolCalls:     9,
+		ToolFailures:  1,
+		Verifications: []Verification{{Step: 1, Command: "go test ./...", Passed: true}, {Step: 2, Command: "go vet ./...", Passed: false}},
+		Diffstat:      " pkg/x/x.go | 4 ++--\n 1 file changed, 2 insertions(+), 2 deletions(-)",
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	md := Markdown(sampleReport())
+
+	assert.True(t, strings.HasPrefix(md, "# Session s-1 completed, verification failed\n"), md)
+	assert.Contains(t, md, "- **Estimated cost:** $0.42\n")
+	assert.Contains(t, md, "- **Tool calls:** 9 (1 failed)\n")
+	assert.Contains(t, md, "pkg/x/x.go | 4 ++--")
+	assert.Contains(t, md, "- Step 2 `go vet ./...`: failed\n")
+	assert.Contains(t, md, "2 earlier turn(s) are no longer in history.")
+	assert.Contains(t, md, "### 1. Fix the flaky test\n\n_completed, 4 tool call(s)_\n\nFixed <the> race.\n")
+}
+
+func TestHTML_EscapesContent(t *testing.T) {
+	html, err := HTML(sampleReport())
+	require.NoError(t, err)
+
+	assert.Contains(t, html, "<title>Session s-1 completed, verification failed</title>")
+	assert.Contains(t, html, "Fixed &lt;the&gt; race.")
+	assert.Contains(t, html, "<code>go test ./...</code>: passed")
+}
+
+func TestVerificationStatus(t *testing.T) {
+	assert.Equal(t, "none", Report{}.VerificationStatus())
+	assert.Equal(t, "passed", Report{Verifications: []Verification{{Passed: true}}}.VerificationStatus())
+}
+
+func TestDeliver_AllChannels(t *testing.T) {
+	var payload webhookPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+	}))
+	defer srv.Close()
+
+	var mailTo []string
+	var mail string
+	var auth smtp.Auth
+	d := NewDeliverer()
+	d.getenv = func(k string) string { return map[string]string{"SMTP_USERNAME": "bot", "SMTP_PASSWORD": "pw"}[k] }
+	d.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		assert.Equal(t, "smtp.example.com:587", addr)
+		auth, mailTo, mail = a, to, string(msg)
+		return nil
+	}
+
+	dir := t.TempDir()
+	cfg := models.SessionReportConfig{
+		Format:     models.ReportFormatHTML,
+		Path:       filepath.Join(dir, "reports", "{session}.html"),
+		WebhookURL: srv.URL,
+		SMTPServer: "smtp.example.com:587",
+		EmailFrom:  "agent@example.com",
+		EmailTo:    []string{"dev@example.com"},
+	}
+	delivered, errs := d.Deliver(context.Background(), cfg, sampleReport())
+
+	require.Empty(t, errs)
+	assert.Equal(t, []string{filepath.Join(dir, "reports", "s-1.html"), "webhook", "email to dev@example.com"}, delivered)
+
+	data, err := os.ReadFile(filepath.Join(dir, "reports", "s-1.html"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "<!DOCTYPE html>")
+
+	assert.Equal(t, "Session s-1 completed, verification failed", payload.Title)
+	assert.Equal(t, "html", payload.Format)
+	assert.Equal(t, 1200, payload.Report.TotalTokens)
+
+	assert.NotNil(t, auth)
+	assert.Equal(t, []string{"dev@example.com"}, mailTo)
+	assert.Contains(t, mail, "Subject: Session s-1 completed, verification failed\r\n")
+	assert.Contains(t, mail, "Content-Type: text/html; charset=utf-8\r\n")
+}
+
+func TestDeliver_ReportsFailedChannels(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "report.md")
+	delivered, errs := NewDeliverer().Deliver(context.Background(),
+		models.SessionReportConfig{Path: path, WebhookURL: srv.URL}, sampleReport())
+
+	assert.Equal(t, []string{path}, delivered, "the file is written although the webhook failed")
+	require.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], "webhook: 403")
+}
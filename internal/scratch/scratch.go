@@ -0,0 +1,100 @@
+// Package scratch manages per-session scratch directories on the worker.
+//
+// Each session gets a private directory under the OS temp dir where the
+// agent can put throwaway scripts and intermediate artifacts instead of
+// writing them into the user's repository. The directory is exposed to
+// command tools as $SCRATCH, created lazily on first tool call, and
+// removed when the session shuts down.
+package scratch
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EnvVar is the environment variable command tools use to locate the
+// session's scratch directory.
+const EnvVar = "SCRATCH"
+
+// rootName is the directory under os.TempDir() holding all session dirs.
+const rootName = "tcx-scratch"
+
+// Root returns the parent directory of all session scratch directories.
+func Root() string {
+	return filepath.Join(os.TempDir(), rootName)
+}
+
+// Dir returns the scratch directory path for a session. The session ID is
+// sanitized so it always maps to a single path element under Root.
+func Dir(sessionID string) string {
+	return filepath.Join(Root(), SafeName(sessionID))
+}
+
+// Ensure creates the session's scratch directory if it does not exist and
+// returns its path. The directory is private to the worker user.
+func Ensure(sessionID string) (string, error) {
+	if sessionID == "" {
+		return "", fmt.Errorf("scratch: empty session ID")
+	}
+	dir := Dir(sessionID)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("scratch: create %s: %w", dir, err)
+	}
+	return dir, nil
+}
+
+// Remove deletes the session's scratch directory and everything in it.
+// Removing a directory that does not exist is not an error.
+func Remove(sessionID string) error {
+	if sessionID == "" {
+		return nil
+	}
+	if err := os.RemoveAll(Dir(sessionID)); err != nil {
+		return fmt.Errorf("scratch: remove: %w", err)
+	}
+	return nil
+}
+
+// Size returns the total size in bytes of the files in the session's
+// scratch directory, or 0 if it does not exist.
+func Size(sessionID string) int64 {
+	return dirSize(Dir(sessionID))
+}
+
+// dirSize returns the total size of the files under dir.
+func dirSize(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// SafeName maps a session ID to a safe single path element. Workflow IDs
+// may contain '/' (e.g. subagent IDs), which must not escape Root.
+func SafeName(sessionID string) string {
+	var b strings.Builder
+	for _, r := range sessionID {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9',
+			r == '-', r == '_', r == '.':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	s := b.String()
+	if s == "" || s == "." || s == ".." {
+		s = "_" + s
+	}
+	return s
+}
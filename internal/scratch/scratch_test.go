@@ -0,0 +1,64 @@
+package scratch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDir_StaysUnderRoot(t *testing.T) {
+	for _, id := range []string{"sess-1", "parent/child", "../../etc", "..", ""} {
+		dir := Dir(id)
+		assert.Equal(t, Root(), filepath.Dir(dir), "id %q", id)
+	}
+}
+
+func TestDir_DistinctSessions(t *testing.T) {
+	assert.NotEqual(t, Dir("a"), Dir("b"))
+	assert.Equal(t, Dir("sess-1"), Dir("sess-1"))
+}
+
+func TestEnsureAndRemove(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	id := "test-session"
+
+	dir, err := Ensure(id)
+	require.NoError(t, err)
+	info, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+
+	// Idempotent
+	again, err := Ensure(id)
+	require.NoError(t, err)
+	assert.Equal(t, dir, again)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tmp.py"), []byte("print(1)"), 0o600))
+	require.NoError(t, Remove(id))
+	_, err = os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+
+	// Removing again is a no-op
+	assert.NoError(t, Remove(id))
+}
+
+func TestEnsure_EmptySessionID(t *testing.T) {
+	_, err := Ensure("")
+	assert.Error(t, err)
+}
+
+func TestSize(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+
+	dir, err := Ensure("sess-size")
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "out.log"), []byte("12345"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "x"), []byte("678"), 0o600))
+
+	assert.Equal(t, int64(8), Size("sess-size"))
+	assert.Equal(t, int64(0), Size("missing"))
+}
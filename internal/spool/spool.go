@@ -0,0 +1,310 @@
+// Package spool is a worker-local, disk-backed queue for best-effort side
+// effects such as history archive writes. A write whose sink is briefly
+// unavailable is saved under the spool directory and retried in the
+// background instead of failing the activity, so agent progress never
+// blocks on observability or storage infrastructure.
+//
+// Entries are delivered in order per kind: while a kind has a backlog, new
+// entries queue behind it and are left to the background Run loop. Each
+// entry is one file, written atomically, so a worker restart resumes
+// delivery where it stopped. Handlers run without the spool's lock, so a
+// slow sink never blocks callers queueing entries.
+package spool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.temporal.io/sdk/client"
+
+	"github.com/mfateev/temporal-agent-harness/internal/scratch"
+)
+
+// BacklogGauge is the metric reporting the number of queued entries,
+// tagged with the entry kind.
+const BacklogGauge = "tcx_spool_backlog"
+
+// entryExt is the extension of queued entry files.
+const entryExt = ".json"
+
+// failedDir is the subdirectory of a kind holding entries whose handler
+// failed permanently, kept for inspection.
+const failedDir = "failed"
+
+// Handler delivers one entry payload to its sink.
+type Handler func(ctx context.Context, payload []byte) error
+
+// permanentError marks a delivery failure that retrying cannot fix.
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps a handler error that retrying cannot fix, e.g. an
+// undecodable payload. Such entries are moved to the kind's failed/
+// directory instead of blocking the entries behind them.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// isPermanent reports whether err was wrapped with Permanent.
+func isPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// Spool queues entries of registered kinds on disk until their handler
+// succeeds. Safe for concurrent use.
+type Spool struct {
+	dir string
+
+	// mu guards handlers, seq and the spool directory; it is never held
+	// while a handler runs.
+	mu       sync.Mutex
+	handlers map[string]Handler
+	seq      uint64
+
+	// flushMu serializes flushes, so a queued entry is delivered once.
+	flushMu sync.Mutex
+}
+
+// Open returns a spool rooted at dir, creating the directory if needed.
+// Entries left by a previous worker are delivered by the next Flush.
+func Open(dir string) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("spool: create %s: %w", dir, err)
+	}
+	return &Spool{dir: dir, handlers: make(map[string]Handler)}, nil
+}
+
+// Handle registers the handler for a kind of entry.
+func (s *Spool) Handle(kind string, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[kind] = h
+}
+
+// Deliver hands payload to the kind's handler, or queues it if the handler
+// fails or earlier entries of the kind are still queued. Queued entries are
+// delivered by Flush. Returns whether the payload was queued; an error
+// means it was neither delivered nor saved.
+func (s *Spool) Deliver(ctx context.Context, kind string, payload []byte) (bool, error) {
+	s.mu.Lock()
+	h, ok := s.handlers[kind]
+	if !ok {
+		s.mu.Unlock()
+		return false, fmt.Errorf("spool: no handler for %q", kind)
+	}
+	names, err := s.pending(kind)
+	if err == nil && len(names) > 0 {
+		err = s.enqueue(kind, payload)
+		s.mu.Unlock()
+		return err == nil, err
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return false, err
+	}
+
+	deliverErr := h(ctx, payload)
+	if deliverErr == nil {
+		return false, nil
+	}
+	if isPermanent(deliverErr) {
+		return false, deliverErr
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.enqueue(kind, payload); err != nil {
+		return false, fmt.Errorf("%w (delivery failed: %v)", err, deliverErr)
+	}
+	return true, nil
+}
+
+// Flush retries queued entries of every registered kind, oldest first,
+// stopping at the first failure of each kind. Returns the number
+// delivered and the first error.
+func (s *Spool) Flush(ctx context.Context) (int, error) {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+	s.mu.Lock()
+	handlers := make(map[string]Handler, len(s.handlers))
+	kinds := make([]string, 0, len(s.handlers))
+	for kind, h := range s.handlers {
+		handlers[kind] = h
+		kinds = append(kinds, kind)
+	}
+	s.mu.Unlock()
+	sort.Strings(kinds)
+
+	delivered := 0
+	var firstErr error
+	for _, kind := range kinds {
+		n, err := s.flushKind(ctx, kind, handlers[kind])
+		delivered += n
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return delivered, firstErr
+}
+
+// Backlog returns the number of queued entries per kind, including kinds
+// with no handler registered yet.
+func (s *Spool) Backlog() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	backlog := make(map[string]int)
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return backlog
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if names, err := s.pending(e.Name()); err == nil && len(names) > 0 {
+			backlog[e.Name()] = len(names)
+		}
+	}
+	return backlog
+}
+
+// ReportBacklog updates BacklogGauge for every registered kind.
+func (s *Spool) ReportBacklog(metrics client.MetricsHandler) {
+	backlog := s.Backlog()
+	s.mu.Lock()
+	kinds := make([]string, 0, len(s.handlers))
+	for kind := range s.handlers {
+		kinds = append(kinds, kind)
+	}
+	s.mu.Unlock()
+	for _, kind := range kinds {
+		metrics.WithTags(map[string]string{"kind": kind}).Gauge(BacklogGauge).Update(float64(backlog[kind]))
+	}
+}
+
+// Run flushes the spool every interval until ctx is done, reporting the
+// backlog after each pass. logf receives delivery errors.
+func (s *Spool) Run(ctx context.Context, interval time.Duration, metrics client.MetricsHandler, logf func(format string, args ...interface{})) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.Flush(ctx); err != nil {
+			logf("Spool: delivered %d queued entries, retrying later: %v", n, err)
+		} else if n > 0 {
+			logf("Spool: delivered %d queued entries", n)
+		}
+		s.ReportBacklog(metrics)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// flushKind delivers queued entries of one kind in order, including those
+// queued while it runs. Caller holds flushMu; mu is taken only around
+// directory operations.
+func (s *Spool) flushKind(ctx context.Context, kind string, h Handler) (int, error) {
+	delivered := 0
+	for {
+		s.mu.Lock()
+		names, err := s.pending(kind)
+		s.mu.Unlock()
+		if err != nil || len(names) == 0 {
+			return delivered, err
+		}
+		path := filepath.Join(s.kindDir(kind), names[0])
+		payload, err := os.ReadFile(path)
+		if err != nil {
+			return delivered, fmt.Errorf("spool: read %s: %w", path, err)
+		}
+		if err := h(ctx, payload); err != nil {
+			if !isPermanent(err) {
+				return delivered, fmt.Errorf("spool: deliver %s: %w", kind, err)
+			}
+			s.mu.Lock()
+			err = s.setAside(kind, names[0])
+			s.mu.Unlock()
+			if err != nil {
+				return delivered, err
+			}
+			continue
+		}
+		s.mu.Lock()
+		err = os.Remove(path)
+		s.mu.Unlock()
+		if err != nil {
+			return delivered, fmt.Errorf("spool: remove %s: %w", path, err)
+		}
+		delivered++
+	}
+}
+
+// setAside moves an entry that failed permanently to the kind's failed/
+// directory. Caller holds mu.
+func (s *Spool) setAside(kind, name string) error {
+	dir := filepath.Join(s.kindDir(kind), failedDir)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("spool: create %s: %w", dir, err)
+	}
+	if err := os.Rename(filepath.Join(s.kindDir(kind), name), filepath.Join(dir, name)); err != nil {
+		return fmt.Errorf("spool: set aside %s: %w", name, err)
+	}
+	return nil
+}
+
+// enqueue saves payload as the newest entry of kind. Caller holds mu.
+func (s *Spool) enqueue(kind string, payload []byte) error {
+	dir := s.kindDir(kind)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("spool: create %s: %w", dir, err)
+	}
+	s.seq++
+	// Nanosecond time orders entries across worker restarts; the counter
+	// orders entries queued within the same tick.
+	name := fmt.Sprintf("%020d-%08d%s", time.Now().UnixNano(), s.seq, entryExt)
+	path := filepath.Join(dir, name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, payload, 0o600); err != nil {
+		return fmt.Errorf("spool: write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("spool: rename %s: %w", tmp, err)
+	}
+	return nil
+}
+
+// pending returns the queued entry names of kind, oldest first. Caller
+// holds mu.
+func (s *Spool) pending(kind string) ([]string, error) {
+	entries, err := os.ReadDir(s.kindDir(kind))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("spool: read %s: %w", kind, err)
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), entryExt) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// kindDir returns the directory holding entries of kind.
+func (s *Spool) kindDir(kind string) string {
+	return filepath.Join(s.dir, scratch.SafeName(kind))
+}
@@ -0,0 +1,174 @@
+package spool
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sink records delivered payloads and fails while down is set.
+type sink struct {
+	down      bool
+	delivered []string
+}
+
+func (k *sink) handle(_ context.Context, payload []byte) error {
+	if k.down {
+		return errors.New("sink unavailable")
+	}
+	k.delivered = append(k.delivered, string(payload))
+	return nil
+}
+
+func TestDeliver_Direct(t *testing.T) {
+	sp, err := Open(t.TempDir())
+	require.NoError(t, err)
+	k := &sink{}
+	sp.Handle("audit", k.handle)
+
+	queued, err := sp.Deliver(context.Background(), "audit", []byte("a"))
+	require.NoError(t, err)
+	assert.False(t, queued)
+	assert.Equal(t, []string{"a"}, k.delivered)
+	assert.Empty(t, sp.Backlog())
+}
+
+func TestDeliver_QueuesWhileSinkDownAndKeepsOrder(t *testing.T) {
+	sp, err := Open(t.TempDir())
+	require.NoError(t, err)
+	k := &sink{down: true}
+	sp.Handle("audit", k.handle)
+	ctx := context.Background()
+
+	for _, p := range []string{"a", "b"} {
+		queued, err := sp.Deliver(ctx, "audit", []byte(p))
+		require.NoError(t, err)
+		assert.True(t, queued)
+	}
+	assert.Equal(t, map[string]int{"audit": 2}, sp.Backlog())
+
+	// Sink back: a new entry queues behind the backlog and is left to
+	// Flush, which delivers the backlog first.
+	k.down = false
+	queued, err := sp.Deliver(ctx, "audit", []byte("c"))
+	require.NoError(t, err)
+	assert.True(t, queued)
+	assert.Empty(t, k.delivered)
+
+	n, err := sp.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, []string{"a", "b", "c"}, k.delivered)
+	assert.Empty(t, sp.Backlog())
+}
+
+func TestDeliver_DoesNotWaitForFlush(t *testing.T) {
+	sp, err := Open(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+	down := true
+	release := make(chan struct{})
+	sp.Handle("audit", func(ctx context.Context, payload []byte) error {
+		if down {
+			return errors.New("sink down")
+		}
+		<-release
+		return nil
+	})
+	_, err = sp.Deliver(ctx, "audit", []byte("a"))
+	require.NoError(t, err)
+
+	// A flush stuck on a slow sink does not block queueing.
+	down = false
+	flushed := make(chan struct{})
+	go func() {
+		defer close(flushed)
+		_, _ = sp.Flush(ctx)
+	}()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		queued, err := sp.Deliver(ctx, "audit", []byte("b"))
+		assert.NoError(t, err)
+		assert.True(t, queued)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Deliver blocked on the flush")
+	}
+	close(release)
+	<-flushed
+	assert.Empty(t, sp.Backlog())
+}
+
+func TestFlush_ResumesAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	sp, err := Open(dir)
+	require.NoError(t, err)
+	sp.Handle("audit", (&sink{down: true}).handle)
+	_, err = sp.Deliver(context.Background(), "audit", []byte("a"))
+	require.NoError(t, err)
+
+	restarted, err := Open(dir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"audit": 1}, restarted.Backlog(), "backlog is visible before a handler registers")
+	k := &sink{}
+	restarted.Handle("audit", k.handle)
+	n, err := restarted.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"a"}, k.delivered)
+}
+
+func TestFlush_SetsAsidePermanentFailures(t *testing.T) {
+	dir := t.TempDir()
+	sp, err := Open(dir)
+	require.NoError(t, err)
+	k := &sink{down: true}
+	sp.Handle("audit", func(ctx context.Context, payload []byte) error {
+		if string(payload) == "bad" && !k.down {
+			return Permanent(errors.New("undecodable"))
+		}
+		return k.handle(ctx, payload)
+	})
+	ctx := context.Background()
+	_, err = sp.Deliver(ctx, "audit", []byte("bad"))
+	require.NoError(t, err)
+	_, err = sp.Deliver(ctx, "audit", []byte("good"))
+	require.NoError(t, err)
+
+	k.down = false
+	n, err := sp.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"good"}, k.delivered)
+	assert.Empty(t, sp.Backlog())
+	failed, err := os.ReadDir(filepath.Join(dir, "audit", failedDir))
+	require.NoError(t, err)
+	assert.Len(t, failed, 1)
+}
+
+func TestDeliver_PermanentFailureIsNotQueued(t *testing.T) {
+	sp, err := Open(t.TempDir())
+	require.NoError(t, err)
+	sp.Handle("audit", func(context.Context, []byte) error { return Permanent(errors.New("bad")) })
+
+	queued, err := sp.Deliver(context.Background(), "audit", []byte("x"))
+	assert.Error(t, err)
+	assert.False(t, queued)
+	assert.Empty(t, sp.Backlog())
+}
+
+func TestDeliver_UnknownKind(t *testing.T) {
+	sp, err := Open(t.TempDir())
+	require.NoError(t, err)
+	_, err = sp.Deliver(context.Background(), "nope", []byte("x"))
+	assert.Error(t, err)
+}
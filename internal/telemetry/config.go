@@ -0,0 +1,40 @@
+package telemetry
+
+import (
+	"time"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// Config is the resolved telemetry setting of a worker.
+type Config struct {
+	Enabled  bool
+	Endpoint string
+	Interval time.Duration
+}
+
+// Resolve combines the [telemetry] table of config.toml (nil if absent)
+// with the worker's --telemetry flag (nil if not given); the flag wins.
+// A non-empty DO_NOT_TRACK value other than "0" disables telemetry
+// regardless of both.
+func Resolve(tc *models.TelemetryToml, flag *bool, doNotTrack string) Config {
+	cfg := Config{Interval: DefaultInterval}
+	if tc != nil {
+		if tc.Enabled != nil {
+			cfg.Enabled = *tc.Enabled
+		}
+		if tc.Endpoint != nil {
+			cfg.Endpoint = *tc.Endpoint
+		}
+		if tc.IntervalHours != nil && *tc.IntervalHours > 0 {
+			cfg.Interval = time.Duration(*tc.IntervalHours) * time.Hour
+		}
+	}
+	if flag != nil {
+		cfg.Enabled = *flag
+	}
+	if doNotTrack != "" && doNotTrack != "0" {
+		cfg.Enabled = false
+	}
+	return cfg
+}
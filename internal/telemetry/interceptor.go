@@ -0,0 +1,63 @@
+package telemetry
+
+import (
+	"context"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/interceptor"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+)
+
+// Interceptor returns a worker interceptor that records LLM and tool
+// activities into c, leaving workflows untouched so enabling telemetry
+// cannot affect replay.
+func (c *Collector) Interceptor() interceptor.WorkerInterceptor {
+	return &workerInterceptor{collector: c}
+}
+
+type workerInterceptor struct {
+	interceptor.WorkerInterceptorBase
+	collector *Collector
+}
+
+func (w *workerInterceptor) InterceptActivity(ctx context.Context, next interceptor.ActivityInboundInterceptor) interceptor.ActivityInboundInterceptor {
+	i := &activityInterceptor{collector: w.collector}
+	i.Next = next
+	return i
+}
+
+type activityInterceptor struct {
+	interceptor.ActivityInboundInterceptorBase
+	collector *Collector
+}
+
+func (a *activityInterceptor) ExecuteActivity(ctx context.Context, in *interceptor.ExecuteActivityInput) (interface{}, error) {
+	result, err := a.Next.ExecuteActivity(ctx, in)
+	if len(in.Args) == 0 {
+		return result, err
+	}
+	switch input := in.Args[0].(type) {
+	case activities.LLMActivityInput:
+		if err == nil {
+			a.collector.RecordLLMCall(activity.GetInfo(ctx).WorkflowExecution.ID, lastTurnID(input), input.ModelConfig)
+		}
+	case activities.ToolActivityInput:
+		failed := err != nil
+		if out, ok := result.(activities.ToolActivityOutput); ok && out.Success != nil && !*out.Success {
+			failed = true
+		}
+		a.collector.RecordToolCall(input.ToolName, failed)
+	}
+	return result, err
+}
+
+// lastTurnID returns the turn of the newest history item that has one.
+func lastTurnID(input activities.LLMActivityInput) string {
+	for i := len(input.History) - 1; i >= 0; i-- {
+		if id := input.History[i].TurnID; id != "" {
+			return id
+		}
+	}
+	return ""
+}
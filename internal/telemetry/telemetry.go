@@ -0,0 +1,262 @@
+// Package telemetry reports anonymous, aggregate usage of a worker to a
+// configurable endpoint, so maintainers can see which tools fail most and
+// which providers and model families are in use. It is off unless the
+// operator opts in with the worker's --telemetry flag or [telemetry]
+// enabled = true in config.toml.
+//
+// Reports never contain conversation content, prompts, tool arguments or
+// outputs, file paths, workflow or session IDs, or custom model and MCP tool
+// names: only counts per built-in tool, model family and period.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/version"
+)
+
+// SchemaVersion is the version of the Report format.
+const SchemaVersion = 1
+
+// DefaultInterval is how often a report is sent.
+const DefaultInterval = 24 * time.Hour
+
+// sendTimeout bounds one report upload.
+const sendTimeout = 10 * time.Second
+
+// Report is the aggregate usage of one worker over one period.
+type Report struct {
+	Schema  int    `json:"schema"`
+	Version string `json:"version"`
+	OS      string `json:"os"`
+	Arch    string `json:"arch"`
+
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+
+	// Sessions and Turns are the sessions and turns that made at least one
+	// LLM call during the period.
+	Sessions int `json:"sessions"`
+	Turns    int `json:"turns"`
+
+	// ModelCalls counts LLM calls per "provider/family", e.g.
+	// "anthropic/claude-sonnet". Unrecognized models count as "other".
+	ModelCalls map[string]int `json:"model_calls,omitempty"`
+
+	// Tools counts calls and failures per built-in tool. MCP tools count as
+	// "mcp" and other tools as "other".
+	Tools map[string]ToolUsage `json:"tools,omitempty"`
+}
+
+// ToolUsage is the call and failure count of one tool.
+type ToolUsage struct {
+	Calls    int `json:"calls"`
+	Failures int `json:"failures"`
+}
+
+// Empty reports whether nothing was recorded in the period.
+func (r Report) Empty() bool {
+	return r.Sessions == 0 && len(r.ModelCalls) == 0 && len(r.Tools) == 0
+}
+
+// Collector aggregates usage in memory until the next report. Safe for
+// concurrent use.
+type Collector struct {
+	knownTool func(string) bool
+
+	mu       sync.Mutex
+	start    time.Time
+	sessions map[string]struct{}
+	turns    map[string]struct{}
+	models   map[string]int
+	tools    map[string]ToolUsage
+}
+
+// NewCollector creates a collector. knownTool reports whether a tool name
+// is built in and may be reported by name.
+func NewCollector(knownTool func(string) bool) *Collector {
+	c := &Collector{knownTool: knownTool}
+	c.reset(time.Now())
+	return c
+}
+
+func (c *Collector) reset(now time.Time) {
+	c.start = now
+	c.sessions = make(map[string]struct{})
+	c.turns = make(map[string]struct{})
+	c.models = make(map[string]int)
+	c.tools = make(map[string]ToolUsage)
+}
+
+// RecordLLMCall counts one LLM call of a session's turn. The IDs are only
+// used to count distinct sessions and turns and are never reported.
+func (c *Collector) RecordLLMCall(sessionID, turnID string, cfg models.ModelConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if sessionID != "" {
+		c.sessions[sessionID] = struct{}{}
+		if turnID != "" {
+			c.turns[sessionID+"\x00"+turnID] = struct{}{}
+		}
+	}
+	c.models[providerName(cfg.Provider)+"/"+ModelFamily(cfg.Model)]++
+}
+
+// RecordToolCall counts one tool call and whether it failed.
+func (c *Collector) RecordToolCall(name string, failed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	name = c.toolName(name)
+	usage := c.tools[name]
+	usage.Calls++
+	if failed {
+		usage.Failures++
+	}
+	c.tools[name] = usage
+}
+
+// toolName maps a tool to the name it is reported under. Caller holds mu.
+func (c *Collector) toolName(name string) string {
+	switch {
+	case strings.HasPrefix(name, "mcp__"):
+		return "mcp"
+	case c.knownTool != nil && c.knownTool(name):
+		return name
+	}
+	return "other"
+}
+
+// Snapshot returns the report for the period up to now and starts a new
+// period.
+func (c *Collector) Snapshot(now time.Time) Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r := Report{
+		Schema:      SchemaVersion,
+		Version:     version.GitCommit,
+		OS:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+		PeriodStart: c.start.UTC().Truncate(time.Hour),
+		PeriodEnd:   now.UTC().Truncate(time.Hour),
+		Sessions:    len(c.sessions),
+		Turns:       len(c.turns),
+	}
+	if len(c.models) > 0 {
+		r.ModelCalls = c.models
+	}
+	if len(c.tools) > 0 {
+		r.Tools = c.tools
+	}
+	c.reset(now)
+	return r
+}
+
+// Run sends a report every interval, and a final one when ctx is done. With
+// no endpoint, reports are only logged, which shows exactly what would be
+// sent. Empty reports are skipped.
+func (c *Collector) Run(ctx context.Context, endpoint string, interval time.Duration, logf func(format string, args ...interface{})) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// The worker is stopping; the final report gets its own deadline.
+			c.report(context.Background(), endpoint, logf)
+			return
+		case <-ticker.C:
+			c.report(ctx, endpoint, logf)
+		}
+	}
+}
+
+func (c *Collector) report(ctx context.Context, endpoint string, logf func(format string, args ...interface{})) {
+	r := c.Snapshot(time.Now())
+	if r.Empty() {
+		return
+	}
+	if endpoint == "" {
+		data, _ := json.Marshal(r)
+		logf("Telemetry (no endpoint configured, not sent): %s", data)
+		return
+	}
+	if err := Send(ctx, endpoint, r); err != nil {
+		logf("Telemetry: %v", err)
+	}
+}
+
+// Send posts r as JSON to endpoint.
+func Send(ctx context.Context, endpoint string, r Report) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("send report: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send report: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("send report: %s returned %s", endpoint, resp.Status)
+	}
+	return nil
+}
+
+// modelFamilies are the reported model families, most specific first.
+var modelFamilies = []string{
+	"claude-opus", "claude-sonnet", "claude-haiku",
+	"gpt-5", "gpt-4.1", "gpt-4o", "gpt-4", "gpt-3.5",
+	"o4", "o3", "o1",
+	"gemini-2.5-pro", "gemini-2.5-flash", "gemini",
+}
+
+// ModelFamily maps a model name to its family, e.g. "claude-sonnet-4-0" to
+// "claude-sonnet" and "gpt-4o-mini" to "gpt-4o". Custom and fine-tuned
+// models are reported as "other", so their names are not disclosed.
+func ModelFamily(model string) string {
+	model = strings.ToLower(model)
+	if strings.HasPrefix(model, "ft:") {
+		return "other"
+	}
+	for _, family := range modelFamilies {
+		if model == family || strings.HasPrefix(model, family+"-") || strings.HasPrefix(model, family+".") {
+			return family
+		}
+	}
+	// Older Claude names put the version first: claude-3-5-sonnet-20241022.
+	if strings.HasPrefix(model, "claude-") {
+		for _, tier := range []string{"opus", "sonnet", "haiku"} {
+			if strings.Contains(model, "-"+tier) {
+				return "claude-" + tier
+			}
+		}
+	}
+	return "other"
+}
+
+// providerName returns provider, or "other" for custom providers.
+func providerName(provider string) string {
+	switch provider {
+	case "openai", "anthropic", "google":
+		return provider
+	}
+	return "other"
+}
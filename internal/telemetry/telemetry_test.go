@@ -0,0 +1,130 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/worker"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+func TestModelFamily(t *testing.T) {
+	for model, family := range map[string]string{
+		"claude-sonnet-4-0":          "claude-sonnet",
+		"claude-3-5-haiku-20241022":  "claude-haiku",
+		"gpt-4o-mini":                "gpt-4o",
+		"gpt-4.1-nano":               "gpt-4.1",
+		"gpt-4":                      "gpt-4",
+		"o3-mini":                    "o3",
+		"gemini-2.5-flash":           "gemini-2.5-flash",
+		"ft:gpt-4o-mini:acme:secret": "other",
+		"acme-internal-llm":          "other",
+	} {
+		assert.Equal(t, family, ModelFamily(model), model)
+	}
+}
+
+func TestCollector_Snapshot(t *testing.T) {
+	c := NewCollector(func(name string) bool { return name == "read_file" })
+	c.RecordLLMCall("wf-1", "turn-1", models.ModelConfig{Provider: "anthropic", Model: "claude-sonnet-4-0"})
+	c.RecordLLMCall("wf-1", "turn-1", models.ModelConfig{Provider: "anthropic", Model: "claude-sonnet-4-0"})
+	c.RecordLLMCall("wf-1", "turn-2", models.ModelConfig{Provider: "openai", Model: "gpt-4o"})
+	c.RecordLLMCall("wf-2", "turn-1", models.ModelConfig{Provider: "acme", Model: "acme-llm"})
+	c.RecordToolCall("read_file", false)
+	c.RecordToolCall("read_file", true)
+	c.RecordToolCall("mcp__github__create_issue", false)
+	c.RecordToolCall("deploy_to_prod", true)
+
+	r := c.Snapshot(time.Now())
+	assert.Equal(t, SchemaVersion, r.Schema)
+	assert.Equal(t, 2, r.Sessions)
+	assert.Equal(t, 3, r.Turns)
+	assert.Equal(t, map[string]int{"anthropic/claude-sonnet": 2, "openai/gpt-4o": 1, "other/other": 1}, r.ModelCalls)
+	assert.Equal(t, map[string]ToolUsage{
+		"read_file": {Calls: 2, Failures: 1},
+		"mcp":       {Calls: 1},
+		"other":     {Calls: 1, Failures: 1},
+	}, r.Tools)
+
+	data, err := json.Marshal(r)
+	require.NoError(t, err)
+	for _, private := range []string{"wf-1", "turn-1", "github", "deploy_to_prod", "acme"} {
+		assert.NotContains(t, string(data), private)
+	}
+
+	assert.True(t, c.Snapshot(time.Now()).Empty(), "a snapshot starts a new period")
+}
+
+func TestSend(t *testing.T) {
+	var got Report
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+	}))
+	defer srv.Close()
+
+	require.NoError(t, Send(context.Background(), srv.URL, Report{Schema: SchemaVersion, Sessions: 3}))
+	assert.Equal(t, 3, got.Sessions)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	assert.ErrorContains(t, Send(context.Background(), failing.URL, Report{}), "503")
+}
+
+func TestResolve(t *testing.T) {
+	on, off := true, false
+	endpoint, hours := "https://t.example.com", 6
+
+	assert.False(t, Resolve(nil, nil, "").Enabled, "off by default")
+
+	cfg := Resolve(&models.TelemetryToml{Enabled: &on, Endpoint: &endpoint, IntervalHours: &hours}, nil, "")
+	assert.Equal(t, Config{Enabled: true, Endpoint: endpoint, Interval: 6 * time.Hour}, cfg)
+
+	assert.False(t, Resolve(&models.TelemetryToml{Enabled: &on}, &off, "").Enabled, "the flag wins")
+	assert.True(t, Resolve(&models.TelemetryToml{Enabled: &off}, &on, "").Enabled, "the flag wins")
+	assert.False(t, Resolve(nil, &on, "1").Enabled, "DO_NOT_TRACK wins")
+	assert.True(t, Resolve(nil, &on, "0").Enabled)
+}
+
+func TestInterceptor_RecordsActivities(t *testing.T) {
+	c := NewCollector(func(name string) bool { return name == "read_file" })
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.SetWorkerOptions(worker.Options{Interceptors: []interceptor.WorkerInterceptor{c.Interceptor()}})
+
+	fail := false
+	env.RegisterActivityWithOptions(func(ctx context.Context, in activities.ToolActivityInput) (activities.ToolActivityOutput, error) {
+		return activities.ToolActivityOutput{CallID: in.CallID, Success: &fail}, nil
+	}, activity.RegisterOptions{Name: "ExecuteTool"})
+	env.RegisterActivityWithOptions(func(ctx context.Context, in activities.LLMActivityInput) (activities.LLMActivityOutput, error) {
+		return activities.LLMActivityOutput{}, nil
+	}, activity.RegisterOptions{Name: "ExecuteLLMCall"})
+
+	_, err := env.ExecuteActivity("ExecuteTool", activities.ToolActivityInput{CallID: "c1", ToolName: "read_file"})
+	require.NoError(t, err)
+	_, err = env.ExecuteActivity("ExecuteLLMCall", activities.LLMActivityInput{
+		ModelConfig: models.ModelConfig{Provider: "openai", Model: "gpt-4o-mini"},
+		History:     []models.ConversationItem{{Type: models.ItemTypeUserMessage, TurnID: "turn-1"}},
+	})
+	require.NoError(t, err)
+
+	r := c.Snapshot(time.Now())
+	assert.Equal(t, map[string]ToolUsage{"read_file": {Calls: 1, Failures: 1}}, r.Tools)
+	assert.Equal(t, map[string]int{"openai/gpt-4o": 1}, r.ModelCalls)
+	assert.Equal(t, 1, r.Sessions)
+	assert.Equal(t, 1, r.Turns)
+}
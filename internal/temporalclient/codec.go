@@ -0,0 +1,193 @@
+package temporalclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"google.golang.org/protobuf/proto"
+)
+
+// Payload compression modes for ConnectionConfig.Compression.
+const (
+	CompressionGzip = "gzip"
+	CompressionNone = "none"
+)
+
+// DefaultCompressionThreshold is the encoded payload size, in bytes, from
+// which payloads are compressed. Smaller payloads (signals, short queries)
+// gain little and are sent as they are.
+const DefaultCompressionThreshold = 4096
+
+// gzipEncoding marks a payload compressed by the gzip codec.
+const gzipEncoding = "binary/gzip"
+
+// uncompressedMetadata marks a payload the codec must leave as it is (see
+// Uncompressed).
+const uncompressedMetadata = "tcx-uncompressed"
+
+// maxDecompressedSize bounds the size of a decompressed payload, so that a
+// small crafted payload cannot exhaust the memory of the process decoding
+// it.
+const maxDecompressedSize = 64 << 20
+
+// Uncompressed is implemented by values whose payloads are never
+// compressed because they are decoded outside this deployment, e.g. the
+// results of Nexus operations, which the calling namespace decodes with
+// its own data converter.
+type Uncompressed interface {
+	UncompressedPayload()
+}
+
+// Metrics reported for compressed payloads, tagged with the codec.
+const (
+	// CompressedBytesCounter counts payload bytes before compression.
+	CompressedBytesCounter = "tcx_payload_compressed_bytes"
+	// CompressionSavedBytesCounter counts the bytes compression saved.
+	CompressionSavedBytesCounter = "tcx_payload_compression_saved_bytes"
+)
+
+// DataConverter returns the data converter for c.Compression: the default
+// converter wrapped in a gzip codec. Compression is opt-in ("gzip"); values
+// that implement Uncompressed are never compressed. Decoding always
+// understands compressed payloads, so a client with compression off can
+// still read what a worker with it on wrote. Bytes saved are reported to
+// metrics (nil for none).
+func (c ConnectionConfig) DataConverter(metrics client.MetricsHandler) (converter.DataConverter, error) {
+	codec := &gzipCodec{threshold: c.CompressionThreshold, metrics: metrics}
+	switch c.Compression {
+	case CompressionGzip:
+		codec.compress = true
+	case "", CompressionNone:
+	default:
+		return nil, fmt.Errorf("invalid compression %q: must be %s or %s", c.Compression, CompressionGzip, CompressionNone)
+	}
+	if codec.threshold <= 0 {
+		codec.threshold = DefaultCompressionThreshold
+	}
+	if codec.metrics == nil {
+		codec.metrics = client.MetricsNopHandler
+	}
+	return converter.NewCodecDataConverter(markingConverter{converter.GetDefaultDataConverter()}, codec), nil
+}
+
+// markingConverter marks the payloads of Uncompressed values for the codec.
+type markingConverter struct {
+	converter.DataConverter
+}
+
+// ToPayload implements converter.DataConverter.
+func (m markingConverter) ToPayload(value interface{}) (*commonpb.Payload, error) {
+	p, err := m.DataConverter.ToPayload(value)
+	if err != nil || p == nil {
+		return p, err
+	}
+	if _, ok := value.(Uncompressed); ok {
+		if p.Metadata == nil {
+			p.Metadata = make(map[string][]byte)
+		}
+		p.Metadata[uncompressedMetadata] = []byte("true")
+	}
+	return p, nil
+}
+
+// ToPayloads implements converter.DataConverter.
+func (m markingConverter) ToPayloads(values ...interface{}) (*commonpb.Payloads, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	result := &commonpb.Payloads{}
+	for i, v := range values {
+		p, err := m.ToPayload(v)
+		if err != nil {
+			return nil, fmt.Errorf("values[%d]: %w", i, err)
+		}
+		result.Payloads = append(result.Payloads, p)
+	}
+	return result, nil
+}
+
+// gzipCodec compresses payloads of at least threshold bytes when compress
+// is set, keeping the original when compression does not make it smaller.
+type gzipCodec struct {
+	compress  bool
+	threshold int
+	metrics   client.MetricsHandler
+}
+
+// Encode implements converter.PayloadCodec.
+func (g *gzipCodec) Encode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	if !g.compress {
+		return payloads, nil
+	}
+	result := make([]*commonpb.Payload, len(payloads))
+	for i, p := range payloads {
+		result[i] = p
+		if _, ok := p.GetMetadata()[uncompressedMetadata]; ok {
+			continue
+		}
+		if proto.Size(p) < g.threshold {
+			continue
+		}
+		b, err := proto.Marshal(p)
+		if err != nil {
+			return payloads, err
+		}
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		_, err = w.Write(b)
+		if closeErr := w.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return payloads, err
+		}
+		if buf.Len() >= len(b) {
+			continue
+		}
+		result[i] = &commonpb.Payload{
+			Metadata: map[string][]byte{converter.MetadataEncoding: []byte(gzipEncoding)},
+			Data:     buf.Bytes(),
+		}
+		tagged := g.metrics.WithTags(map[string]string{"codec": CompressionGzip})
+		tagged.Counter(CompressedBytesCounter).Inc(int64(len(b)))
+		tagged.Counter(CompressionSavedBytesCounter).Inc(int64(len(b) - buf.Len()))
+	}
+	return result, nil
+}
+
+// Decode implements converter.PayloadCodec. Payloads this codec did not
+// compress pass through unchanged; a payload that decompresses to more than
+// maxDecompressedSize bytes is rejected.
+func (g *gzipCodec) Decode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	result := make([]*commonpb.Payload, len(payloads))
+	for i, p := range payloads {
+		if string(p.GetMetadata()[converter.MetadataEncoding]) != gzipEncoding {
+			result[i] = p
+			continue
+		}
+		r, err := gzip.NewReader(bytes.NewReader(p.GetData()))
+		if err != nil {
+			return payloads, err
+		}
+		b, err := io.ReadAll(io.LimitReader(r, maxDecompressedSize+1))
+		if err == nil && len(b) > maxDecompressedSize {
+			err = fmt.Errorf("decompressed payload exceeds %d bytes", maxDecompressedSize)
+		}
+		if closeErr := r.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return payloads, err
+		}
+		result[i] = &commonpb.Payload{}
+		if err := proto.Unmarshal(b, result[i]); err != nil {
+			return payloads, err
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,144 @@
+package temporalclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+)
+
+// countingMetrics sums counter increments by name.
+type countingMetrics struct{ counts map[string]int64 }
+
+func (m *countingMetrics) WithTags(map[string]string) client.MetricsHandler { return m }
+func (m *countingMetrics) Counter(name string) client.MetricsCounter {
+	return counterFunc(func(n int64) { m.counts[name] += n })
+}
+func (m *countingMetrics) Gauge(string) client.MetricsGauge {
+	return client.MetricsNopHandler.Gauge("")
+}
+func (m *countingMetrics) Timer(string) client.MetricsTimer {
+	return client.MetricsNopHandler.Timer("")
+}
+
+type counterFunc func(int64)
+
+func (f counterFunc) Inc(n int64) { f(n) }
+
+func TestDataConverter_CompressesLargePayloads(t *testing.T) {
+	metrics := &countingMetrics{counts: map[string]int64{}}
+	dc, err := ConnectionConfig{Compression: CompressionGzip}.DataConverter(metrics)
+	require.NoError(t, err)
+
+	history := strings.Repeat("the same conversation item, again and again\n", 500)
+	p, err := dc.ToPayload(history)
+	require.NoError(t, err)
+	assert.Equal(t, gzipEncoding, string(p.Metadata[converter.MetadataEncoding]))
+	assert.Less(t, len(p.Data), len(history)/10)
+	assert.Greater(t, metrics.counts[CompressedBytesCounter], int64(len(history)))
+	assert.Equal(t, metrics.counts[CompressedBytesCounter]-int64(len(p.Data)), metrics.counts[CompressionSavedBytesCounter])
+
+	var got string
+	require.NoError(t, dc.FromPayload(p, &got))
+	assert.Equal(t, history, got)
+
+	small, err := dc.ToPayload("hi")
+	require.NoError(t, err)
+	assert.Equal(t, "json/plain", string(small.Metadata[converter.MetadataEncoding]))
+}
+
+func TestDataConverter_Threshold(t *testing.T) {
+	dc, err := ConnectionConfig{Compression: CompressionGzip, CompressionThreshold: 1 << 20}.DataConverter(nil)
+	require.NoError(t, err)
+	p, err := dc.ToPayload(strings.Repeat("a", 10000))
+	require.NoError(t, err)
+	assert.Equal(t, "json/plain", string(p.Metadata[converter.MetadataEncoding]))
+}
+
+func TestDataConverter_NoneStillDecodes(t *testing.T) {
+	gzipDC, err := ConnectionConfig{Compression: CompressionGzip}.DataConverter(nil)
+	require.NoError(t, err)
+	noneDC, err := ConnectionConfig{Compression: CompressionNone}.DataConverter(nil)
+	require.NoError(t, err)
+
+	value := strings.Repeat("x", 10000)
+	p, err := noneDC.ToPayload(value)
+	require.NoError(t, err)
+	assert.Equal(t, "json/plain", string(p.Metadata[converter.MetadataEncoding]))
+
+	compressed, err := gzipDC.ToPayload(value)
+	require.NoError(t, err)
+	var got string
+	require.NoError(t, noneDC.FromPayload(compressed, &got))
+	assert.Equal(t, value, got)
+}
+
+func TestDataConverter_InvalidCompression(t *testing.T) {
+	_, err := ConnectionConfig{Compression: "zip"}.DataConverter(nil)
+	assert.ErrorContains(t, err, `invalid compression "zip"`)
+
+	_, err = LoadClientOptionsFromConfig(ConnectionConfig{
+		ConfigFile:  writeTemporalConfig(t, ""),
+		Compression: "zip",
+	})
+	assert.Error(t, err)
+}
+
+func TestLoadClientOptionsFromConfig_SetsDataConverter(t *testing.T) {
+	opts, err := LoadClientOptionsFromConfig(ConnectionConfig{ConfigFile: writeTemporalConfig(t, "")})
+	require.NoError(t, err)
+	require.NotNil(t, opts.DataConverter)
+	p, err := opts.DataConverter.ToPayload(strings.Repeat("y", 10000))
+	require.NoError(t, err)
+	assert.Equal(t, "json/plain", string(p.Metadata[converter.MetadataEncoding]), "compression is opt-in")
+
+	opts, err = LoadClientOptionsFromConfig(ConnectionConfig{ConfigFile: writeTemporalConfig(t, ""), Compression: CompressionGzip})
+	require.NoError(t, err)
+	p, err = opts.DataConverter.ToPayload(strings.Repeat("y", 10000))
+	require.NoError(t, err)
+	assert.Equal(t, gzipEncoding, string(p.Metadata[converter.MetadataEncoding]))
+}
+
+// sessionResult stands in for a Nexus operation result.
+type sessionResult struct {
+	Summary string `json:"summary"`
+}
+
+func (sessionResult) UncompressedPayload() {}
+
+func TestDataConverter_SkipsUncompressedValues(t *testing.T) {
+	dc, err := ConnectionConfig{Compression: CompressionGzip}.DataConverter(nil)
+	require.NoError(t, err)
+
+	result := sessionResult{Summary: strings.Repeat("z", 10000)}
+	payloads, err := dc.ToPayloads(result, strings.Repeat("z", 10000))
+	require.NoError(t, err)
+	require.Len(t, payloads.Payloads, 2)
+	assert.Equal(t, "json/plain", string(payloads.Payloads[0].Metadata[converter.MetadataEncoding]))
+	assert.Equal(t, gzipEncoding, string(payloads.Payloads[1].Metadata[converter.MetadataEncoding]))
+
+	// A caller with the default converter reads the result.
+	var got sessionResult
+	require.NoError(t, converter.GetDefaultDataConverter().FromPayload(payloads.Payloads[0], &got))
+	assert.Equal(t, result, got)
+}
+
+func TestGzipCodec_RejectsOversizedPayload(t *testing.T) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(make([]byte, maxDecompressedSize+1))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	_, err = (&gzipCodec{}).Decode([]*commonpb.Payload{{
+		Metadata: map[string][]byte{converter.MetadataEncoding: []byte(gzipEncoding)},
+		Data:     buf.Bytes(),
+	}})
+	assert.ErrorContains(t, err, "decompressed payload exceeds")
+}
@@ -7,10 +7,78 @@
 package temporalclient
 
 import (
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
 	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/contrib/envconfig"
 )
 
+// Default dial retry settings. A single attempt preserves the historical
+// fail-fast behavior; callers opt into retries via ConnectionConfig.
+const (
+	DefaultDialRetries      = 0
+	DefaultDialRetryBackoff = 2 * time.Second
+	maxDialRetryBackoff     = 30 * time.Second
+)
+
+// ConnectionConfig holds explicit Temporal connection settings. Non-empty
+// fields override the values loaded by envconfig (temporal.toml profile +
+// TEMPORAL_* environment variables), so flags always win.
+type ConnectionConfig struct {
+	HostPort  string // Server address (host:port)
+	Namespace string // Temporal namespace
+
+	// ConfigFile and Profile select the envconfig TOML file and profile.
+	// Defaults: TEMPORAL_CONFIG_FILE / TEMPORAL_PROFILE, then the SDK defaults.
+	ConfigFile string
+	Profile    string
+
+	// APIKey enables API key auth (Temporal Cloud). Implies TLS.
+	APIKey string
+
+	// TLS settings. Setting any cert path implies TLS.
+	TLS                        bool
+	TLSCertPath                string // Client mTLS certificate
+	TLSKeyPath                 string // Client mTLS key
+	TLSCACertPath              string // Server CA override
+	TLSServerName              string // SNI override
+	TLSDisableHostVerification bool
+
+	// DialRetries is the number of additional dial attempts after the first
+	// failure. Backoff doubles after each attempt, capped at 30s.
+	DialRetries      int
+	DialRetryBackoff time.Duration
+
+	// Compression is the payload codec: "none" (default when empty) or
+	// "gzip". Payloads smaller than CompressionThreshold bytes (default
+	// DefaultCompressionThreshold) are not compressed.
+	Compression          string
+	CompressionThreshold int
+}
+
+// RegisterFlags registers the connection flags on fs, binding them to c.
+// Used by both tcx and the worker so flag names stay consistent.
+func (c *ConnectionConfig) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.HostPort, "temporal-host", "", "Temporal server address (overrides envconfig/env vars)")
+	fs.StringVar(&c.Namespace, "temporal-namespace", "", "Temporal namespace (overrides TEMPORAL_NAMESPACE)")
+	fs.StringVar(&c.ConfigFile, "temporal-config", "", "Path to Temporal envconfig TOML file (overrides TEMPORAL_CONFIG_FILE)")
+	fs.StringVar(&c.Profile, "temporal-profile", "", "Profile in the Temporal envconfig file (overrides TEMPORAL_PROFILE)")
+	fs.StringVar(&c.APIKey, "temporal-api-key", "", "Temporal Cloud API key (overrides TEMPORAL_API_KEY; implies TLS)")
+	fs.BoolVar(&c.TLS, "temporal-tls", false, "Enable TLS for the Temporal connection")
+	fs.StringVar(&c.TLSCertPath, "temporal-tls-cert", "", "Path to client mTLS certificate (implies TLS)")
+	fs.StringVar(&c.TLSKeyPath, "temporal-tls-key", "", "Path to client mTLS private key (implies TLS)")
+	fs.StringVar(&c.TLSCACertPath, "temporal-tls-ca", "", "Path to server CA certificate (implies TLS)")
+	fs.StringVar(&c.TLSServerName, "temporal-tls-server-name", "", "TLS server name (SNI) override")
+	fs.BoolVar(&c.TLSDisableHostVerification, "temporal-tls-insecure-skip-verify", false, "Skip TLS host verification")
+	fs.IntVar(&c.DialRetries, "temporal-dial-retries", DefaultDialRetries, "Extra attempts when the initial Temporal connection fails")
+	fs.DurationVar(&c.DialRetryBackoff, "temporal-dial-backoff", DefaultDialRetryBackoff, "Initial backoff between Temporal connection attempts")
+	fs.StringVar(&c.Compression, "temporal-compression", CompressionNone, "Payload compression: none | gzip (compressed payloads are always readable)")
+	fs.IntVar(&c.CompressionThreshold, "temporal-compression-threshold", DefaultCompressionThreshold, "Smallest payload, in bytes, that is compressed")
+}
+
 // LoadClientOptions loads Temporal client options using the envconfig system.
 // This supports:
 //   - Environment variables (TEMPORAL_HOST_URL, TEMPORAL_NAMESPACE, TEMPORAL_TLS_CERT, etc.)
@@ -22,21 +90,77 @@ import (
 //
 // See: github.com/temporalio/samples-go/external-env-conf
 func LoadClientOptions(hostPortOverride, namespaceOverride string) (client.Options, error) {
-	opts, err := envconfig.LoadClientOptions(envconfig.LoadClientOptionsRequest{})
+	return LoadClientOptionsFromConfig(ConnectionConfig{
+		HostPort:  hostPortOverride,
+		Namespace: namespaceOverride,
+	})
+}
+
+// LoadClientOptionsFromConfig loads the envconfig profile and applies the
+// explicit overrides in cfg on top of it before building client options,
+// including the payload compression codec.
+func LoadClientOptionsFromConfig(cfg ConnectionConfig) (client.Options, error) {
+	prof, err := envconfig.LoadClientConfigProfile(envconfig.LoadClientConfigProfileOptions{
+		ConfigFilePath:    cfg.ConfigFile,
+		ConfigFileProfile: cfg.Profile,
+	})
 	if err != nil {
 		return client.Options{}, err
 	}
 
-	if hostPortOverride != "" {
-		opts.HostPort = hostPortOverride
+	cfg.applyTo(&prof)
+
+	opts, err := prof.ToClientOptions(envconfig.ToClientOptionsRequest{})
+	if err != nil {
+		return client.Options{}, err
 	}
-	if namespaceOverride != "" {
-		opts.Namespace = namespaceOverride
+	if opts.DataConverter, err = cfg.DataConverter(opts.MetricsHandler); err != nil {
+		return client.Options{}, err
 	}
-
 	return opts, nil
 }
 
+// applyTo overlays the non-empty fields of cfg onto an envconfig profile.
+func (c ConnectionConfig) applyTo(prof *envconfig.ClientConfigProfile) {
+	if c.HostPort != "" {
+		prof.Address = c.HostPort
+	}
+	if c.Namespace != "" {
+		prof.Namespace = c.Namespace
+	}
+	if c.APIKey != "" {
+		prof.APIKey = c.APIKey
+	}
+
+	wantTLS := c.TLS || c.TLSCertPath != "" || c.TLSKeyPath != "" || c.TLSCACertPath != "" ||
+		c.TLSServerName != "" || c.TLSDisableHostVerification
+	if !wantTLS {
+		return
+	}
+	if prof.TLS == nil {
+		prof.TLS = &envconfig.ClientConfigTLS{}
+	}
+	prof.TLS.Disabled = false
+	if c.TLSCertPath != "" {
+		prof.TLS.ClientCertPath = c.TLSCertPath
+		prof.TLS.ClientCertData = nil
+	}
+	if c.TLSKeyPath != "" {
+		prof.TLS.ClientKeyPath = c.TLSKeyPath
+		prof.TLS.ClientKeyData = nil
+	}
+	if c.TLSCACertPath != "" {
+		prof.TLS.ServerCACertPath = c.TLSCACertPath
+		prof.TLS.ServerCACertData = nil
+	}
+	if c.TLSServerName != "" {
+		prof.TLS.ServerName = c.TLSServerName
+	}
+	if c.TLSDisableHostVerification {
+		prof.TLS.DisableHostVerification = true
+	}
+}
+
 // MustLoadClientOptions is like LoadClientOptions but panics on error.
 func MustLoadClientOptions(hostPortOverride, namespaceOverride string) client.Options {
 	opts, err := LoadClientOptions(hostPortOverride, namespaceOverride)
@@ -45,3 +169,42 @@ func MustLoadClientOptions(hostPortOverride, namespaceOverride string) client.Op
 	}
 	return opts
 }
+
+// dialFunc is the underlying dial implementation (replaced in tests).
+var dialFunc = client.Dial
+
+// sleepFunc waits between dial attempts (replaced in tests).
+var sleepFunc = time.Sleep
+
+// Dial loads client options from cfg and connects, retrying failed dials
+// cfg.DialRetries times with exponential backoff.
+func Dial(cfg ConnectionConfig) (client.Client, error) {
+	opts, err := LoadClientOptionsFromConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Temporal client config: %w", err)
+	}
+	return DialWithRetry(opts, cfg.DialRetries, cfg.DialRetryBackoff)
+}
+
+// DialWithRetry calls client.Dial, retrying up to retries additional times.
+// The backoff doubles after every failed attempt, capped at 30s.
+func DialWithRetry(opts client.Options, retries int, backoff time.Duration) (client.Client, error) {
+	if backoff <= 0 {
+		backoff = DefaultDialRetryBackoff
+	}
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			log.Printf("Temporal dial to %s failed (%v); retrying in %s (attempt %d/%d)",
+				opts.HostPort, lastErr, backoff, attempt, retries)
+			sleepFunc(backoff)
+			backoff = min(backoff*2, maxDialRetryBackoff)
+		}
+		c, err := dialFunc(opts)
+		if err == nil {
+			return c, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,123 @@
+package temporalclient
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/client"
+)
+
+func writeTemporalConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "temporal.toml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadClientOptionsFromConfig_ProfileAndOverrides(t *testing.T) {
+	path := writeTemporalConfig(t, `
+[profile.prod]
+address = "prod.example.com:7233"
+namespace = "prod-ns"
+`)
+
+	opts, err := LoadClientOptionsFromConfig(ConnectionConfig{ConfigFile: path, Profile: "prod"})
+	require.NoError(t, err)
+	assert.Equal(t, "prod.example.com:7233", opts.HostPort)
+	assert.Equal(t, "prod-ns", opts.Namespace)
+	assert.Nil(t, opts.ConnectionOptions.TLS)
+
+	opts, err = LoadClientOptionsFromConfig(ConnectionConfig{
+		ConfigFile: path,
+		Profile:    "prod",
+		HostPort:   "other:7233",
+		Namespace:  "other-ns",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "other:7233", opts.HostPort)
+	assert.Equal(t, "other-ns", opts.Namespace)
+}
+
+func TestLoadClientOptionsFromConfig_MissingProfile(t *testing.T) {
+	path := writeTemporalConfig(t, `
+[profile.default]
+address = "localhost:7233"
+`)
+	_, err := LoadClientOptionsFromConfig(ConnectionConfig{ConfigFile: path, Profile: "missing"})
+	assert.Error(t, err)
+}
+
+func TestLoadClientOptionsFromConfig_APIKeyImpliesTLS(t *testing.T) {
+	path := writeTemporalConfig(t, "")
+	opts, err := LoadClientOptionsFromConfig(ConnectionConfig{
+		ConfigFile: path,
+		HostPort:   "ns.tmprl.cloud:7233",
+		APIKey:     "secret",
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, opts.Credentials)
+	assert.NotNil(t, opts.ConnectionOptions.TLS)
+}
+
+func TestLoadClientOptionsFromConfig_TLSFlags(t *testing.T) {
+	path := writeTemporalConfig(t, "")
+	opts, err := LoadClientOptionsFromConfig(ConnectionConfig{
+		ConfigFile:                 path,
+		TLSServerName:              "temporal.internal",
+		TLSDisableHostVerification: true,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, opts.ConnectionOptions.TLS)
+	assert.Equal(t, "temporal.internal", opts.ConnectionOptions.TLS.ServerName)
+	assert.True(t, opts.ConnectionOptions.TLS.InsecureSkipVerify)
+
+	_, err = LoadClientOptionsFromConfig(ConnectionConfig{
+		ConfigFile:  path,
+		TLSCertPath: filepath.Join(t.TempDir(), "missing.pem"),
+		TLSKeyPath:  filepath.Join(t.TempDir(), "missing.key"),
+	})
+	assert.Error(t, err, "missing cert files should surface an error")
+}
+
+func TestDialWithRetry(t *testing.T) {
+	origDial, origSleep := dialFunc, sleepFunc
+	t.Cleanup(func() { dialFunc, sleepFunc = origDial, origSleep })
+
+	var sleeps []time.Duration
+	sleepFunc = func(d time.Duration) { sleeps = append(sleeps, d) }
+
+	attempts := 0
+	dialFunc = func(client.Options) (client.Client, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errors.New("connection refused")
+		}
+		return nil, nil
+	}
+
+	_, err := DialWithRetry(client.Options{HostPort: "localhost:7233"}, 5, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, sleeps)
+}
+
+func TestDialWithRetry_GivesUp(t *testing.T) {
+	origDial, origSleep := dialFunc, sleepFunc
+	t.Cleanup(func() { dialFunc, sleepFunc = origDial, origSleep })
+	sleepFunc = func(time.Duration) {}
+
+	attempts := 0
+	dialFunc = func(client.Options) (client.Client, error) {
+		attempts++
+		return nil, errors.New("connection refused")
+	}
+
+	_, err := DialWithRetry(client.Options{}, 2, 0)
+	assert.EqualError(t, err, "connection refused")
+	assert.Equal(t, 3, attempts)
+}
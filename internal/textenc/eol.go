@@ -0,0 +1,68 @@
+package textenc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Line endings. The values match the line-ending policies in config.toml.
+const (
+	LF   = "lf"
+	CRLF = "crlf"
+)
+
+// LineEndings counts the line terminators of a text.
+type LineEndings struct {
+	LF   int
+	CRLF int
+}
+
+// CountLineEndings counts the LF and CRLF terminators in text. A lone CR is
+// not a terminator.
+func CountLineEndings(text string) LineEndings {
+	var l LineEndings
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\n' {
+			continue
+		}
+		if i > 0 && text[i-1] == '\r' {
+			l.CRLF++
+		} else {
+			l.LF++
+		}
+	}
+	return l
+}
+
+// Mixed reports whether both LF and CRLF terminators are present.
+func (l LineEndings) Mixed() bool {
+	return l.LF > 0 && l.CRLF > 0
+}
+
+// Dominant returns the more common line ending, LF on a tie or when the
+// text has no terminators.
+func (l LineEndings) Dominant() string {
+	if l.CRLF > l.LF {
+		return CRLF
+	}
+	return LF
+}
+
+// String describes the counts, e.g. "12 CRLF, 3 LF".
+func (l LineEndings) String() string {
+	return fmt.Sprintf("%d CRLF, %d LF", l.CRLF, l.LF)
+}
+
+// ConvertLineEndings rewrites every terminator in text as eol.
+func ConvertLineEndings(text, eol string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if eol == CRLF {
+		text = strings.ReplaceAll(text, "\n", "\r\n")
+	}
+	return text
+}
+
+// DisplayEOL renders a line ending for tool output, e.g. "CRLF".
+func DisplayEOL(eol string) string {
+	return strings.ToUpper(eol)
+}
@@ -0,0 +1,25 @@
+package textenc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountLineEndings(t *testing.T) {
+	l := CountLineEndings("a\r\nb\r\nc\nd\re")
+	assert.Equal(t, LineEndings{LF: 1, CRLF: 2}, l)
+	assert.True(t, l.Mixed())
+	assert.Equal(t, CRLF, l.Dominant())
+	assert.Equal(t, "2 CRLF, 1 LF", l.String())
+
+	assert.False(t, CountLineEndings("a\nb\n").Mixed())
+	assert.Equal(t, LF, CountLineEndings("no terminator").Dominant())
+	assert.Equal(t, LF, CountLineEndings("a\r\nb\n").Dominant(), "ties go to LF")
+}
+
+func TestConvertLineEndings(t *testing.T) {
+	assert.Equal(t, "a\r\nb\r\nc", ConvertLineEndings("a\nb\r\nc", CRLF))
+	assert.Equal(t, "a\nb\nc", ConvertLineEndings("a\r\nb\nc", LF))
+	assert.Equal(t, "CRLF", DisplayEOL(CRLF))
+}
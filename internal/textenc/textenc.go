@@ -0,0 +1,210 @@
+// Package textenc detects the text encoding of files and converts their
+// contents to and from UTF-8. File tools show the model UTF-8 and write
+// edits back in the file's original encoding, so Latin-1 and UTF-16 files
+// can be read and patched without corrupting them.
+//
+// Detection order: a byte order mark, then UTF-16 without a BOM (ASCII-range
+// text with every other byte NUL), then valid UTF-8, and finally Latin-1
+// (ISO-8859-1), which accepts any byte sequence. Callers must rule out
+// binary content themselves.
+//
+// The package also counts and converts line endings (see eol.go), so edits
+// keep a file's dominant LF or CRLF terminators.
+package textenc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding is a supported text encoding. Its value is the display name.
+type Encoding string
+
+const (
+	UTF8    Encoding = "UTF-8"
+	UTF8BOM Encoding = "UTF-8 with BOM"
+	UTF16LE Encoding = "UTF-16LE"
+	UTF16BE Encoding = "UTF-16BE"
+	Latin1  Encoding = "Latin-1"
+)
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+)
+
+// IsUTF8 reports whether content in e is used as-is, without conversion.
+func (e Encoding) IsUTF8() bool {
+	return e == UTF8 || e == ""
+}
+
+// Detect returns the encoding of data. data may be a prefix of the file: a
+// multi-byte UTF-8 sequence cut off at the end does not count as invalid.
+func Detect(data []byte) Encoding {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return UTF8BOM
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return UTF16LE
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return UTF16BE
+	}
+	if enc, ok := detectUTF16(data); ok {
+		return enc
+	}
+	if utf8.Valid(trimPartialRune(data)) {
+		return UTF8
+	}
+	return Latin1
+}
+
+// detectUTF16 recognises UTF-16 without a BOM from the NUL high bytes of
+// ASCII-range characters: data has an even length of at least four code
+// units, at least 40% of which have a NUL on one side and almost none on the
+// other.
+func detectUTF16(data []byte) (Encoding, bool) {
+	units := len(data) / 2
+	if units < 4 || len(data)%2 != 0 {
+		return "", false
+	}
+	even, odd := 0, 0
+	for i := 0; i+1 < len(data); i += 2 {
+		if data[i] == 0 {
+			even++
+		}
+		if data[i+1] == 0 {
+			odd++
+		}
+	}
+	switch {
+	case odd*10 >= units*4 && even*20 <= units:
+		return UTF16LE, true
+	case even*10 >= units*4 && odd*20 <= units:
+		return UTF16BE, true
+	}
+	return "", false
+}
+
+// trimPartialRune drops an incomplete UTF-8 sequence at the end of data.
+func trimPartialRune(data []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(data); i++ {
+		if utf8.RuneStart(data[len(data)-i]) {
+			if !utf8.FullRune(data[len(data)-i:]) {
+				return data[:len(data)-i]
+			}
+			break
+		}
+	}
+	return data
+}
+
+// Decode converts data in encoding e to UTF-8, dropping any byte order mark.
+func Decode(data []byte, e Encoding) (string, error) {
+	switch e {
+	case UTF8, "":
+		return string(data), nil
+	case UTF8BOM:
+		return string(bytes.TrimPrefix(data, bomUTF8)), nil
+	case UTF16LE, UTF16BE:
+		order := orderFor(e)
+		data = bytes.TrimPrefix(data, bomFor(e))
+		if len(data)%2 != 0 {
+			return "", fmt.Errorf("invalid %s: odd number of bytes", e)
+		}
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = order.Uint16(data[2*i:])
+		}
+		return string(utf16.Decode(units)), nil
+	case Latin1:
+		var b strings.Builder
+		b.Grow(len(data))
+		for _, c := range data {
+			b.WriteRune(rune(c))
+		}
+		return b.String(), nil
+	}
+	return "", fmt.Errorf("unsupported encoding %q", e)
+}
+
+// Encode converts UTF-8 text to encoding e. UTF-16 gets a byte order mark
+// only if hadBOM is set; UTF-8 with BOM always does. Text that e cannot represent is an
+// error, so a file is never silently corrupted.
+func Encode(text string, e Encoding, hadBOM bool) ([]byte, error) {
+	switch e {
+	case UTF8, "":
+		return []byte(text), nil
+	case UTF8BOM:
+		return append(append([]byte{}, bomUTF8...), text...), nil
+	case UTF16LE, UTF16BE:
+		order := orderFor(e)
+		units := utf16.Encode([]rune(text))
+		var out []byte
+		if hadBOM {
+			out = append(out, bomFor(e)...)
+		}
+		for _, u := range units {
+			out = order.AppendUint16(out, u)
+		}
+		return out, nil
+	case Latin1:
+		out := make([]byte, 0, len(text))
+		for i, r := range text {
+			if r > 0xff {
+				return nil, fmt.Errorf("character %q at byte %d cannot be written as %s", r, i, e)
+			}
+			out = append(out, byte(r))
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported encoding %q", e)
+}
+
+// HasBOM reports whether data starts with the byte order mark of e.
+func HasBOM(data []byte, e Encoding) bool {
+	bom := bomFor(e)
+	return bom != nil && bytes.HasPrefix(data, bom)
+}
+
+// ConvertedNotice is the note tools add to their output when a file was
+// converted, e.g. "(converted from UTF-16LE; edits are written back as
+// UTF-16LE)".
+func ConvertedNotice(e Encoding) string {
+	return fmt.Sprintf("(converted from %s; edits are written back as %s)", e, e)
+}
+
+// KeptNotice is the note edit tools add to their output when a file was
+// written back in its original encoding, e.g. "kept UTF-16LE encoding".
+func KeptNotice(e Encoding) string {
+	return fmt.Sprintf("kept %s encoding", e)
+}
+
+// byteOrder reads and appends UTF-16 code units.
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+func orderFor(e Encoding) byteOrder {
+	if e == UTF16BE {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+func bomFor(e Encoding) []byte {
+	switch e {
+	case UTF8BOM:
+		return bomUTF8
+	case UTF16LE:
+		return bomUTF16LE
+	case UTF16BE:
+		return bomUTF16BE
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package textenc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetect(t *testing.T) {
+	assert.Equal(t, UTF8, Detect([]byte("plain ascii\n")))
+	assert.Equal(t, UTF8, Detect([]byte("héllo\n")))
+	assert.Equal(t, UTF8, Detect([]byte("cut off \xe2\x80")), "a rune cut off at the end of a prefix")
+	assert.Equal(t, UTF8BOM, Detect([]byte("\xef\xbb\xbfhello")))
+	assert.Equal(t, UTF16LE, Detect([]byte("\xff\xfeh\x00i\x00")))
+	assert.Equal(t, UTF16BE, Detect([]byte("\xfe\xff\x00h\x00i")))
+	assert.Equal(t, UTF16LE, Detect([]byte("h\x00e\x00l\x00l\x00o\x00")), "UTF-16 without a BOM")
+	assert.Equal(t, UTF16BE, Detect([]byte("\x00h\x00e\x00l\x00l\x00o")))
+	assert.Equal(t, Latin1, Detect([]byte("caf\xe9\n")))
+	assert.Equal(t, Latin1, Detect([]byte("AB\x00CD\xff")), "odd length is never UTF-16")
+}
+
+func TestRoundTrip(t *testing.T) {
+	text := "line one\ncafé — ünïcode\n"
+	for _, tc := range []struct {
+		enc    Encoding
+		hadBOM bool
+	}{
+		{UTF8, false},
+		{UTF8BOM, true},
+		{UTF16LE, true},
+		{UTF16LE, false},
+		{UTF16BE, true},
+	} {
+		data, err := Encode(text, tc.enc, tc.hadBOM)
+		require.NoError(t, err, tc.enc)
+		assert.Equal(t, tc.hadBOM, HasBOM(data, tc.enc), tc.enc)
+		assert.Equal(t, tc.enc, Detect(data), tc.enc)
+		got, err := Decode(data, tc.enc)
+		require.NoError(t, err, tc.enc)
+		assert.Equal(t, text, got, tc.enc)
+	}
+}
+
+func TestLatin1(t *testing.T) {
+	got, err := Decode([]byte("caf\xe9 cr\xe8me"), Latin1)
+	require.NoError(t, err)
+	assert.Equal(t, "café crème", got)
+
+	data, err := Encode("café crème", Latin1, false)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("caf\xe9 cr\xe8me"), data)
+
+	_, err = Encode("café →", Latin1, false)
+	assert.ErrorContains(t, err, "cannot be written as Latin-1")
+}
+
+func TestDecode_OddUTF16(t *testing.T) {
+	_, err := Decode([]byte("\xff\xfeh\x00i"), UTF16LE)
+	assert.Error(t, err)
+}
+
+func TestNotices(t *testing.T) {
+	assert.Equal(t, "(converted from UTF-16LE; edits are written back as UTF-16LE)", ConvertedNotice(UTF16LE))
+	assert.Equal(t, "kept Latin-1 encoding", KeptNotice(Latin1))
+}
@@ -0,0 +1,213 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ArgAdapter rewrites an aliased call's arguments into the target tool's
+// argument shape. It returns the target tool name (adapters for multi-command
+// tools like str_replace_editor pick the target per call) and false if the
+// call cannot be adapted.
+type ArgAdapter func(args map[string]interface{}) (target string, adapted map[string]interface{}, ok bool)
+
+// builtinAliases maps tool names used by other harnesses (matched
+// case-insensitively) to adapters for our tools. Models trained elsewhere
+// call these names even when only our specs are offered.
+var builtinAliases = map[string]ArgAdapter{
+	"bash":                        adaptShell,
+	"shell_exec":                  adaptShell,
+	"read":                        adaptRead,
+	"view":                        adaptRead,
+	"write":                       adaptWrite,
+	"create_file":                 adaptWrite,
+	"ls":                          adaptList,
+	"list_directory":              adaptList,
+	"grep":                        adaptGrep,
+	"str_replace_editor":          adaptStrReplaceEditor,
+	"str_replace_based_edit_tool": adaptStrReplaceEditor,
+}
+
+// ResolveToolAlias maps a call to an unknown tool name onto an enabled tool.
+// configured holds user aliases from config (alias → target tool); they take
+// precedence over built-ins, pass arguments through unchanged, and an empty
+// target disables a built-in alias. enabled reports whether a tool is
+// available. Returns the target name and re-encoded arguments, or false if
+// name is not an alias or its target is not enabled.
+func ResolveToolAlias(name, arguments string, configured map[string]string, enabled func(string) bool) (string, string, bool) {
+	if target, ok := configured[name]; ok {
+		if target == "" || !enabled(target) {
+			return "", "", false
+		}
+		return target, arguments, true
+	}
+
+	adapter, ok := builtinAliases[strings.ToLower(name)]
+	if !ok {
+		return "", "", false
+	}
+	var args map[string]interface{}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return "", "", false
+	}
+	target, adapted, ok := adapter(args)
+	if !ok || !enabled(target) {
+		return "", "", false
+	}
+	encoded, err := json.Marshal(adapted)
+	if err != nil {
+		return "", "", false
+	}
+	return target, string(encoded), true
+}
+
+// pick copies the first present key from keys in src to dst under name.
+func pick(dst, src map[string]interface{}, name string, keys ...string) {
+	for _, k := range keys {
+		if v, ok := src[k]; ok {
+			dst[name] = v
+			return
+		}
+	}
+}
+
+// adaptShell maps bash-style calls ({command, timeout}) to shell_command.
+func adaptShell(args map[string]interface{}) (string, map[string]interface{}, bool) {
+	out := map[string]interface{}{}
+	pick(out, args, "command", "command", "cmd")
+	cmd, _ := out["command"].(string)
+	if cmd == "" {
+		return "", nil, false
+	}
+	pick(out, args, "timeout_ms", "timeout_ms", "timeout")
+	pick(out, args, "workdir", "workdir", "cwd")
+	return "shell_command", out, true
+}
+
+// adaptRead maps {file_path|path, offset, limit} and str_replace_editor's
+// view_range to read_file.
+func adaptRead(args map[string]interface{}) (string, map[string]interface{}, bool) {
+	out := map[string]interface{}{}
+	pick(out, args, "file_path", "file_path", "path", "filename")
+	if _, ok := out["file_path"].(string); !ok {
+		return "", nil, false
+	}
+	pick(out, args, "offset", "offset")
+	pick(out, args, "limit", "limit")
+	if r, ok := args["view_range"].([]interface{}); ok && len(r) == 2 {
+		start, ok1 := r[0].(float64)
+		end, ok2 := r[1].(float64)
+		if ok1 && ok2 && start >= 1 {
+			out["offset"] = start
+			if end >= start {
+				out["limit"] = end - start + 1
+			}
+		}
+	}
+	return "read_file", out, true
+}
+
+// adaptWrite maps {file_path|path, content|file_text} to write_file.
+func adaptWrite(args map[string]interface{}) (string, map[string]interface{}, bool) {
+	out := map[string]interface{}{}
+	pick(out, args, "path", "path", "file_path", "filename")
+	pick(out, args, "content", "content", "file_text", "text")
+	if _, ok := out["path"].(string); !ok {
+		return "", nil, false
+	}
+	if _, ok := out["content"].(string); !ok {
+		return "", nil, false
+	}
+	return "write_file", out, true
+}
+
+// adaptList maps {path|dir_path} to list_dir.
+func adaptList(args map[string]interface{}) (string, map[string]interface{}, bool) {
+	out := map[string]interface{}{}
+	pick(out, args, "dir_path", "dir_path", "path", "directory")
+	if _, ok := out["dir_path"].(string); !ok {
+		out["dir_path"] = "."
+	}
+	pick(out, args, "depth", "depth")
+	return "list_dir", out, true
+}
+
+// adaptGrep maps {pattern, path, glob|include} to grep_files.
+func adaptGrep(args map[string]interface{}) (string, map[string]interface{}, bool) {
+	out := map[string]interface{}{}
+	pick(out, args, "pattern", "pattern", "query", "regex")
+	if _, ok := out["pattern"].(string); !ok {
+		return "", nil, false
+	}
+	pick(out, args, "path", "path")
+	pick(out, args, "include", "include", "glob")
+	pick(out, args, "limit", "limit", "head_limit")
+	return "grep_files", out, true
+}
+
+// adaptStrReplaceEditor maps the multi-command str_replace_editor tool:
+// view → read_file, create → write_file, str_replace → apply_patch.
+// insert and undo_edit need the file contents and are not adapted.
+func adaptStrReplaceEditor(args map[string]interface{}) (string, map[string]interface{}, bool) {
+	command, _ := args["command"].(string)
+	switch command {
+	case "view":
+		return adaptRead(args)
+	case "create":
+		return adaptWrite(args)
+	case "str_replace":
+		path, _ := args["path"].(string)
+		oldStr, ok := args["old_str"].(string)
+		if path == "" || !ok || oldStr == "" {
+			return "", nil, false
+		}
+		newStr, _ := args["new_str"].(string)
+		return "apply_patch", map[string]interface{}{"input": replacementPatch(path, oldStr, newStr)}, true
+	}
+	return "", nil, false
+}
+
+// replacementPatch builds an apply_patch update that replaces oldStr with
+// newStr in path.
+func replacementPatch(path, oldStr, newStr string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*** Begin Patch\n*** Update File: %s\n@@\n", path)
+	for _, line := range strings.Split(strings.TrimSuffix(oldStr, "\n"), "\n") {
+		b.WriteString("-" + line + "\n")
+	}
+	if newStr != "" {
+		for _, line := range strings.Split(strings.TrimSuffix(newStr, "\n"), "\n") {
+			b.WriteString("+" + line + "\n")
+		}
+	}
+	b.WriteString("*** End Patch")
+	return b.String()
+}
+
+// ParseReplacementPatch is the inverse of replacementPatch: it returns the
+// path and replaced text of a patch that updates one file with a single
+// hunk and no context lines, and false for any other patch.
+func ParseReplacementPatch(patch string) (path, oldStr, newStr string, ok bool) {
+	lines := strings.Split(strings.TrimSuffix(patch, "\n"), "\n")
+	if len(lines) < 5 || lines[0] != "*** Begin Patch" || lines[2] != "@@" ||
+		lines[len(lines)-1] != "*** End Patch" || !strings.HasPrefix(lines[1], "*** Update File: ") {
+		return "", "", "", false
+	}
+	path = strings.TrimPrefix(lines[1], "*** Update File: ")
+	var removed, added []string
+	for _, line := range lines[3 : len(lines)-1] {
+		switch {
+		case strings.HasPrefix(line, "-") && len(added) == 0:
+			removed = append(removed, line[1:])
+		case strings.HasPrefix(line, "+"):
+			added = append(added, line[1:])
+		default:
+			return "", "", "", false
+		}
+	}
+	if len(removed) == 0 {
+		return "", "", "", false
+	}
+	return path, strings.Join(removed, "\n"), strings.Join(added, "\n"), true
+}
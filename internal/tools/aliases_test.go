@@ -0,0 +1,110 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func allEnabled(string) bool { return true }
+
+func decodeArgs(t *testing.T, s string) map[string]interface{} {
+	t.Helper()
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(s), &m))
+	return m
+}
+
+func TestResolveToolAlias_Builtins(t *testing.T) {
+	tests := []struct {
+		name     string
+		tool     string
+		args     string
+		wantTool string
+		wantArgs map[string]interface{}
+	}{
+		{"bash", "bash", `{"command":"ls","timeout":5000}`, "shell_command",
+			map[string]interface{}{"command": "ls", "timeout_ms": float64(5000)}},
+		{"case insensitive", "Bash", `{"command":"ls"}`, "shell_command",
+			map[string]interface{}{"command": "ls"}},
+		{"read", "read", `{"path":"a.go","limit":10}`, "read_file",
+			map[string]interface{}{"file_path": "a.go", "limit": float64(10)}},
+		{"write", "create_file", `{"file_path":"a.txt","content":"x"}`, "write_file",
+			map[string]interface{}{"path": "a.txt", "content": "x"}},
+		{"ls default dir", "ls", `{}`, "list_dir",
+			map[string]interface{}{"dir_path": "."}},
+		{"grep glob", "grep", `{"pattern":"foo","glob":"*.go"}`, "grep_files",
+			map[string]interface{}{"pattern": "foo", "include": "*.go"}},
+		{"editor view range", "str_replace_editor", `{"command":"view","path":"a.go","view_range":[5,9]}`, "read_file",
+			map[string]interface{}{"file_path": "a.go", "offset": float64(5), "limit": float64(5)}},
+		{"editor create", "str_replace_based_edit_tool", `{"command":"create","path":"a.txt","file_text":"hi"}`, "write_file",
+			map[string]interface{}{"path": "a.txt", "content": "hi"}},
+		{"editor str_replace", "str_replace_editor", `{"command":"str_replace","path":"a.go","old_str":"x := 1\n","new_str":"x := 2"}`, "apply_patch",
+			map[string]interface{}{"input": "*** Begin Patch\n*** Update File: a.go\n@@\n-x := 1\n+x := 2\n*** End Patch"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool, args, ok := ResolveToolAlias(tt.tool, tt.args, nil, allEnabled)
+			require.True(t, ok)
+			assert.Equal(t, tt.wantTool, tool)
+			assert.Equal(t, tt.wantArgs, decodeArgs(t, args))
+		})
+	}
+}
+
+func TestResolveToolAlias_Unresolved(t *testing.T) {
+	tests := []struct {
+		name string
+		tool string
+		args string
+	}{
+		{"unknown name", "frobnicate", `{}`},
+		{"invalid json", "bash", `not json`},
+		{"missing command", "bash", `{}`},
+		{"editor insert", "str_replace_editor", `{"command":"insert","path":"a.go","insert_line":1,"new_str":"x"}`},
+		{"editor empty old_str", "str_replace_editor", `{"command":"str_replace","path":"a.go","old_str":""}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, ok := ResolveToolAlias(tt.tool, tt.args, nil, allEnabled)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestResolveToolAlias_TargetNotEnabled(t *testing.T) {
+	onlyRead := func(name string) bool { return name == "read_file" }
+	_, _, ok := ResolveToolAlias("bash", `{"command":"ls"}`, nil, onlyRead)
+	assert.False(t, ok)
+
+	tool, _, ok := ResolveToolAlias("read", `{"path":"a.go"}`, nil, onlyRead)
+	assert.True(t, ok)
+	assert.Equal(t, "read_file", tool)
+}
+
+func TestResolveToolAlias_Configured(t *testing.T) {
+	configured := map[string]string{"run": "shell_command", "bash": ""}
+
+	tool, args, ok := ResolveToolAlias("run", `{"command":"ls"}`, configured, allEnabled)
+	require.True(t, ok)
+	assert.Equal(t, "shell_command", tool)
+	assert.Equal(t, `{"command":"ls"}`, args, "configured aliases pass arguments through")
+
+	_, _, ok = ResolveToolAlias("bash", `{"command":"ls"}`, configured, allEnabled)
+	assert.False(t, ok, "empty target disables the built-in alias")
+}
+
+func TestParseReplacementPatch(t *testing.T) {
+	path, oldStr, newStr, ok := ParseReplacementPatch(replacementPatch("a.go", "x := 1\ny := 2\n", "x := 3"))
+	require.True(t, ok)
+	assert.Equal(t, "a.go", path)
+	assert.Equal(t, "x := 1\ny := 2", oldStr)
+	assert.Equal(t, "x := 3", newStr)
+
+	_, _, _, ok = ParseReplacementPatch("*** Begin Patch\n*** Update File: a.go\n@@\n context\n-x\n+y\n*** End Patch")
+	assert.False(t, ok, "context lines")
+	_, _, _, ok = ParseReplacementPatch("*** Begin Patch\n*** Add File: a.go\n+x\n*** End Patch")
+	assert.False(t, ok, "not an update")
+}
@@ -0,0 +1,25 @@
+// Ask-user tool specification for the ask_user intercepted tool.
+package tools
+
+func init() {
+	RegisterSpec(SpecEntry{Name: "ask_user", Constructor: NewAskUserToolSpec})
+}
+
+// NewAskUserToolSpec creates the specification for the ask_user tool.
+// This tool is intercepted by the workflow (not dispatched as an activity).
+// It is a lightweight alternative to request_user_input for a single
+// free-text clarification; the answer comes back as plain text.
+func NewAskUserToolSpec() ToolSpec {
+	return ToolSpec{
+		Name:        "ask_user",
+		Description: "Ask the user a single free-text question and wait for the answer. Use this whenever a request is ambiguous or you are about to guess at something the user could tell you in a sentence; asking is cheap. Prefer it over request_user_input unless you need to offer specific choices.",
+		Parameters: []ToolParameter{
+			{
+				Name:        "question",
+				Type:        "string",
+				Description: "The question to ask, phrased so it can be answered in a sentence.",
+				Required:    true,
+			},
+		},
+	}
+}
@@ -3,6 +3,8 @@
 // Corresponds to: codex-rs/core/src/tools/
 package tools
 
+import "github.com/mfateev/temporal-agent-harness/internal/remote"
+
 // ToolKind classifies the type of tool handler.
 //
 // Maps to: codex-rs/core/src/tools/registry.rs ToolKind
@@ -19,6 +21,9 @@ const (
 type ToolOutput struct {
 	Content string `json:"content"`
 	Success *bool  `json:"success,omitempty"`
+	// ExitCode is the exit status of the process a command tool ran; nil
+	// when the process is still running or the tool runs none.
+	ExitCode *int `json:"exit_code,omitempty"`
 }
 
 // McpToolRef carries routing metadata for MCP tool dispatch.
@@ -56,13 +61,32 @@ type ToolInvocation struct {
 	// McpToolRef, if set, routes this call to the named MCP server + tool.
 	McpToolRef *McpToolRef `json:"mcp_tool_ref,omitempty"`
 
-	// SessionID identifies the workflow session for MCP store lookup.
+	// SessionID identifies the workflow session for MCP store lookup and,
+	// for exec_command, the owner of the exec session it starts.
 	SessionID string `json:"session_id,omitempty"`
 
+	// ScratchDir is the session's scratch directory on this worker. Command
+	// tools export it as $SCRATCH. Empty when no session is associated.
+	ScratchDir string `json:"scratch_dir,omitempty"`
+
+	// ExcludePaths are config-supplied exclusion patterns. File tools load
+	// the full policy (defaults + these + .codexignore) via internal/exclusion.
+	ExcludePaths []string `json:"exclude_paths,omitempty"`
+
+	// LineEndings is the line-ending policy for file-writing tools:
+	// "preserve" (or empty), "lf" or "crlf".
+	LineEndings string `json:"line_endings,omitempty"`
+
 	// McpServers carries the session's MCP server configs for auto-reconnect.
 	// Typed as interface{} to avoid circular imports; the MCPHandler
 	// type-asserts to map[string]mcp.McpServerConfig.
 	McpServers interface{} `json:"-"`
+
+	// Remote, if set, runs command tools on a remote machine over SSH
+	// (see internal/remote). Cwd and path arguments are then remote paths.
+	// File tools are staged through a local mirror by the activity layer
+	// and never see it.
+	Remote *remote.Target `json:"-"`
 }
 
 // SandboxPolicyRef is a serializable reference to a sandbox policy.
@@ -38,7 +38,7 @@ func (t *ApplyPatchTool) IsMutating(invocation *tools.ToolInvocation) bool {
 // Handle parses the patch from the "input" argument and applies it to the filesystem.
 //
 // Maps to: codex-rs/core/src/tools/handlers/apply_patch.rs handle
-func (t *ApplyPatchTool) Handle(_ context.Context, invocation *tools.ToolInvocation) (*tools.ToolOutput, error) {
+func (t *ApplyPatchTool) Handle(ctx context.Context, invocation *tools.ToolInvocation) (*tools.ToolOutput, error) {
 	inputArg, ok := invocation.Arguments["input"]
 	if !ok {
 		return nil, tools.NewValidationError("missing required argument: input")
@@ -63,7 +63,7 @@ func (t *ApplyPatchTool) Handle(_ context.Context, invocation *tools.ToolInvocat
 		}, nil
 	}
 
-	result, err := patch.Apply(input, cwd)
+	result, affected, err := patch.ApplyWithOptions(input, cwd, patch.Options{LineEndings: invocation.LineEndings})
 	if err != nil {
 		success := false
 		return &tools.ToolOutput{
@@ -74,7 +74,7 @@ func (t *ApplyPatchTool) Handle(_ context.Context, invocation *tools.ToolInvocat
 
 	success := true
 	return &tools.ToolOutput{
-		Content: result,
+		Content: result + syntaxCheckReport(ctx, affected.Written(cwd)),
 		Success: &success,
 	}, nil
 }
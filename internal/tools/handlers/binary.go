@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Binary detection and notices for read_file and grep_files. Binary content
+// is never returned raw: it wastes context and can break JSON payloads.
+const (
+	// binarySniffBytes is how much of a file is inspected to decide whether
+	// it is binary.
+	binarySniffBytes = 8192
+
+	// hexdumpMaxFileBytes is the largest binary read_file will hexdump.
+	hexdumpMaxFileBytes = 1 << 20
+
+	// hexdumpChunkBytes is the size of the head and tail shown by a hexdump.
+	hexdumpChunkBytes = 256
+)
+
+// binaryTypeNames maps sniffed MIME types to short names.
+var binaryTypeNames = map[string]string{
+	"image/png":                    "PNG",
+	"image/jpeg":                   "JPEG",
+	"image/gif":                    "GIF",
+	"image/webp":                   "WebP",
+	"image/bmp":                    "BMP",
+	"image/x-icon":                 "ICO",
+	"application/pdf":              "PDF",
+	"application/zip":              "ZIP",
+	"application/x-gzip":           "gzip",
+	"application/x-rar-compressed": "RAR",
+	"application/wasm":             "WebAssembly",
+	"application/ogg":              "Ogg",
+	"audio/mpeg":                   "MP3",
+	"audio/wave":                   "WAV",
+	"video/mp4":                    "MP4",
+	"video/webm":                   "WebM",
+	"font/ttf":                     "TrueType font",
+	"font/otf":                     "OpenType font",
+	"font/woff":                    "WOFF font",
+	"font/woff2":                   "WOFF2 font",
+}
+
+// binaryMagic lists formats http.DetectContentType does not recognise.
+var binaryMagic = []struct {
+	prefix string
+	name   string
+}{
+	{"\x7fELF", "ELF executable"},
+	{"\xcf\xfa\xed\xfe", "Mach-O executable"},
+	{"\xce\xfa\xed\xfe", "Mach-O executable"},
+	{"MZ", "PE executable"},
+	{"SQLite format 3\x00", "SQLite database"},
+	{"\xfd7zXZ\x00", "xz"},
+	{"BZh", "bzip2"},
+	{"7z\xbc\xaf\x27\x1c", "7z"},
+}
+
+// readHead reads up to binarySniffBytes from the start of f and rewinds it.
+func readHead(f *os.File) ([]byte, error) {
+	head := make([]byte, binarySniffBytes)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return head[:n], nil
+}
+
+// looksBinary reports whether head is the start of a binary file: it
+// contains a NUL byte, or more than 30% of it is invalid UTF-8 or control
+// characters. Occasional invalid bytes (e.g. Latin-1 text) stay text.
+func looksBinary(head []byte) bool {
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true
+	}
+	suspicious, total := 0, 0
+	for len(head) > 0 {
+		if !utf8.FullRune(head) {
+			break // rune cut off by the sniff window
+		}
+		r, size := utf8.DecodeRune(head)
+		head = head[size:]
+		total++
+		if r == utf8.RuneError && size == 1 {
+			suspicious++
+		} else if unicode.IsControl(r) && !strings.ContainsRune("\t\n\v\f\r\b\x1b", r) {
+			suspicious++
+		}
+	}
+	return suspicious*10 > total*3
+}
+
+// binaryType names the format of a binary file from its head, e.g. "PNG",
+// or "unknown".
+func binaryType(head []byte) string {
+	for _, m := range binaryMagic {
+		if bytes.HasPrefix(head, []byte(m.prefix)) {
+			return m.name
+		}
+	}
+	mime, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	if name, ok := binaryTypeNames[mime]; ok {
+		return name
+	}
+	if mime == "application/octet-stream" || strings.HasPrefix(mime, "text/") {
+		return "unknown"
+	}
+	return mime
+}
+
+// describeBinary is the notice shown in place of binary content, e.g.
+// "binary file, 2.4 MiB, type: PNG".
+func describeBinary(size int64, head []byte) string {
+	return fmt.Sprintf("binary file, %s, type: %s", formatFileSize(size), binaryType(head))
+}
+
+// formatFileSize renders a byte count with a binary unit.
+func formatFileSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// hexdumpHeadTail renders the first and last hexdumpChunkBytes of data in
+// `hexdump -C` style, with offsets relative to the start of the file.
+func hexdumpHeadTail(data []byte) string {
+	var b strings.Builder
+	if len(data) <= 2*hexdumpChunkBytes {
+		writeHexdump(&b, data, 0)
+		return b.String()
+	}
+	writeHexdump(&b, data[:hexdumpChunkBytes], 0)
+	tailStart := len(data) - hexdumpChunkBytes
+	fmt.Fprintf(&b, "... %d bytes omitted ...\n", tailStart-hexdumpChunkBytes)
+	writeHexdump(&b, data[tailStart:], tailStart)
+	return b.String()
+}
+
+// writeHexdump writes data as 16-byte rows starting at offset base.
+func writeHexdump(b *strings.Builder, data []byte, base int) {
+	for i := 0; i < len(data); i += 16 {
+		row := data[i:min(i+16, len(data))]
+		fmt.Fprintf(b, "%08x ", base+i)
+		for j := 0; j < 16; j++ {
+			if j == 8 {
+				b.WriteByte(' ')
+			}
+			if j < len(row) {
+				fmt.Fprintf(b, " %02x", row[j])
+			} else {
+				b.WriteString("   ")
+			}
+		}
+		b.WriteString("  |")
+		for _, c := range row {
+			if c < 0x20 || c > 0x7e {
+				c = '.'
+			}
+			b.WriteByte(c)
+		}
+		b.WriteString("|\n")
+	}
+}
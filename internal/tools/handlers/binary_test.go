@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pngHeader is the PNG signature followed by an IHDR chunk header.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestLooksBinary(t *testing.T) {
+	assert.False(t, looksBinary(nil))
+	assert.False(t, looksBinary([]byte("package main\n\nfunc main() {}\n")))
+	assert.False(t, looksBinary([]byte("héllo wörld — ünïcode\n")))
+	assert.False(t, looksBinary([]byte("caf\xe9 au lait, cr\xe8me br\xfbl\xe9e\n")),
+		"a few invalid bytes in Latin-1 text stay text")
+	assert.False(t, looksBinary([]byte("ends mid-rune \xe2\x80")), "a rune cut by the sniff window is not invalid")
+	assert.True(t, looksBinary(pngHeader))
+	assert.True(t, looksBinary([]byte{0xff, 0xfe, 0x01, 0x02, 0x03, 0x80, 0x81, 0x90}))
+}
+
+func TestBinaryType(t *testing.T) {
+	assert.Equal(t, "PNG", binaryType(pngHeader))
+	assert.Equal(t, "PDF", binaryType([]byte("%PDF-1.7\n")))
+	assert.Equal(t, "ZIP", binaryType([]byte("PK\x03\x04rest")))
+	assert.Equal(t, "ELF executable", binaryType([]byte("\x7fELF\x02\x01\x01")))
+	assert.Equal(t, "SQLite database", binaryType([]byte("SQLite format 3\x00")))
+	assert.Equal(t, "unknown", binaryType([]byte{0x00, 0x01, 0x02, 0x03}))
+}
+
+func TestFormatFileSize(t *testing.T) {
+	assert.Equal(t, "512 B", formatFileSize(512))
+	assert.Equal(t, "1.5 KiB", formatFileSize(1536))
+	assert.Equal(t, "2.4 MiB", formatFileSize(2516582))
+}
+
+func TestHexdumpHeadTail(t *testing.T) {
+	small := hexdumpHeadTail([]byte("AB\x00"))
+	assert.Equal(t, "00000000  41 42 00                                          |AB.|\n", small)
+
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	dump := hexdumpHeadTail(data)
+	assert.Contains(t, dump, "00000000  00 01 02 03")
+	assert.Contains(t, dump, "... 488 bytes omitted ...")
+	assert.Contains(t, dump, "000002f8  f8 f9 fa fb", "tail offsets are relative to the file start")
+	assert.Equal(t, 2*hexdumpChunkBytes/16+1, strings.Count(dump, "\n"))
+}
+
+func TestAnnotateBinaryResults(t *testing.T) {
+	dir := t.TempDir()
+	text := filepath.Join(dir, "a.go")
+	img := filepath.Join(dir, "logo.png")
+	require.NoError(t, os.WriteFile(text, []byte("package a\n"), 0o644))
+	require.NoError(t, os.WriteFile(img, append(pngHeader, make([]byte, 100)...), 0o644))
+	missing := filepath.Join(dir, "gone.txt")
+
+	got := annotateBinaryResults([]string{text, img, missing})
+	assert.Equal(t, []string{text, img + " (binary file, 116 B, type: PNG)", missing}, got)
+}
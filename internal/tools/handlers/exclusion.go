@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"github.com/mfateev/temporal-agent-harness/internal/exclusion"
+	"github.com/mfateev/temporal-agent-harness/internal/tools"
+)
+
+// exclusionPolicy loads the path exclusion policy for an invocation.
+func exclusionPolicy(invocation *tools.ToolInvocation) *exclusion.Policy {
+	if invocation.Remote != nil {
+		// Cwd is a remote path; its .codexignore is not readable here.
+		return exclusion.Load("", invocation.ExcludePaths)
+	}
+	return exclusion.Load(invocation.Cwd, invocation.ExcludePaths)
+}
+
+// excludedOutput is the failed tool output returned for an excluded path.
+func excludedOutput(path string) *tools.ToolOutput {
+	success := false
+	return &tools.ToolOutput{
+		Content: exclusion.ExcludedMessage + ": " + path,
+		Success: &success,
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"os/exec"
 	"strings"
 
+	"github.com/mfateev/temporal-agent-harness/internal/exclusion"
+	"github.com/mfateev/temporal-agent-harness/internal/remote"
 	"github.com/mfateev/temporal-agent-harness/internal/tools"
 )
 
@@ -89,6 +91,9 @@ func (t *GrepFilesTool) Handle(ctx context.Context, invocation *tools.ToolInvoca
 	if searchPath == "" && invocation.Cwd != "" {
 		searchPath = invocation.Cwd
 	}
+	if searchPath == "" && invocation.Remote != nil {
+		return nil, tools.NewValidationError("path is required: the session has no working directory")
+	}
 	if searchPath == "" {
 		cwd, err := os.Getwd()
 		if err != nil {
@@ -101,13 +106,21 @@ func (t *GrepFilesTool) Handle(ctx context.Context, invocation *tools.ToolInvoca
 		searchPath = cwd
 	}
 
-	// Verify the search path exists.
-	if _, err := os.Stat(searchPath); err != nil {
-		success := false
-		return &tools.ToolOutput{
-			Content: fmt.Sprintf("unable to access `%s`: %v", searchPath, err),
-			Success: &success,
-		}, nil
+	// Verify the search path exists; a remote rg reports a missing path
+	// itself.
+	if invocation.Remote == nil {
+		if _, err := os.Stat(searchPath); err != nil {
+			success := false
+			return &tools.ToolOutput{
+				Content: fmt.Sprintf("unable to access `%s`: %v", searchPath, err),
+				Success: &success,
+			}, nil
+		}
+	}
+
+	policy := exclusionPolicy(invocation)
+	if policy.Excluded(searchPath) {
+		return excludedOutput(searchPath), nil
 	}
 
 	// Resolve optional include glob.
@@ -118,7 +131,7 @@ func (t *GrepFilesTool) Handle(ctx context.Context, invocation *tools.ToolInvoca
 		}
 	}
 
-	results, err := runRgSearch(ctx, pattern, include, searchPath, limit)
+	results, err := runRgSearch(ctx, invocation.Remote, pattern, include, searchPath, limit, policy)
 	if err != nil {
 		success := false
 		return &tools.ToolOutput{
@@ -135,6 +148,9 @@ func (t *GrepFilesTool) Handle(ctx context.Context, invocation *tools.ToolInvoca
 		}, nil
 	}
 
+	if invocation.Remote == nil {
+		results = annotateBinaryResults(results)
+	}
 	success := true
 	return &tools.ToolOutput{
 		Content: strings.Join(results, "\n"),
@@ -142,10 +158,12 @@ func (t *GrepFilesTool) Handle(ctx context.Context, invocation *tools.ToolInvoca
 	}, nil
 }
 
-// runRgSearch executes ripgrep and returns matching file paths.
+// runRgSearch executes ripgrep and returns matching file paths, dropping
+// paths excluded by policy. With a remote target, rg runs on the remote
+// machine and must be installed there.
 //
 // Maps to: codex-rs/core/src/tools/handlers/grep_files.rs run_rg_search
-func runRgSearch(ctx context.Context, pattern, include, searchPath string, limit int) ([]string, error) {
+func runRgSearch(ctx context.Context, target *remote.Target, pattern, include, searchPath string, limit int, policy *exclusion.Policy) ([]string, error) {
 	args := []string{
 		"--files-with-matches",
 		"--sortr=modified",
@@ -159,7 +177,11 @@ func runRgSearch(ctx context.Context, pattern, include, searchPath string, limit
 
 	args = append(args, "--", searchPath)
 
-	cmd := exec.CommandContext(ctx, "rg", args...)
+	argv := append([]string{"rg"}, args...)
+	if target != nil {
+		argv = target.Command(remote.ExecCommand(argv), false)
+	}
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -179,20 +201,21 @@ func runRgSearch(ctx context.Context, pattern, include, searchPath string, limit
 		return nil, fmt.Errorf("failed to launch rg: %v. Ensure ripgrep is installed and on PATH.", err)
 	}
 
-	return parseResults(stdout.Bytes(), limit), nil
+	return parseResults(stdout.Bytes(), limit, policy), nil
 }
 
-// parseResults splits rg stdout into file paths, capped at limit.
+// parseResults splits rg stdout into file paths, capped at limit. Paths
+// excluded by policy are skipped and do not count toward the limit.
 //
 // Maps to: codex-rs/core/src/tools/handlers/grep_files.rs parse_results
-func parseResults(stdout []byte, limit int) []string {
+func parseResults(stdout []byte, limit int, policy *exclusion.Policy) []string {
 	var results []string
 	for _, line := range bytes.Split(stdout, []byte("\n")) {
 		if len(line) == 0 {
 			continue
 		}
 		text := string(line)
-		if text == "" {
+		if text == "" || policy.Excluded(text) {
 			continue
 		}
 		results = append(results, text)
@@ -202,3 +225,25 @@ func parseResults(stdout []byte, limit int) []string {
 	}
 	return results
 }
+
+// annotateBinaryResults marks matching files that are binary, e.g.
+// "assets/logo.png (binary file, 2.4 KiB, type: PNG)", so the model does
+// not try to read them as text. Files that cannot be opened are left as-is.
+func annotateBinaryResults(paths []string) []string {
+	out := make([]string, len(paths))
+	for i, p := range paths {
+		out[i] = p
+		f, err := os.Open(p)
+		if err != nil {
+			continue
+		}
+		head, err := readHead(f)
+		if err == nil && looksBinary(head) {
+			if info, err := f.Stat(); err == nil {
+				out[i] = fmt.Sprintf("%s (%s)", p, describeBinary(info.Size(), head))
+			}
+		}
+		f.Close()
+	}
+	return out
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/mfateev/temporal-agent-harness/internal/exclusion"
 	"github.com/mfateev/temporal-agent-harness/internal/tools"
 )
 
@@ -39,14 +40,14 @@ func newGrepInvocation(args map[string]interface{}) *tools.ToolInvocation {
 // Port of: parses_basic_results
 func TestGrepFiles_ParsesBasicResults(t *testing.T) {
 	stdout := []byte("/tmp/file_a.rs\n/tmp/file_b.rs\n")
-	parsed := parseResults(stdout, 10)
+	parsed := parseResults(stdout, 10, nil)
 	assert.Equal(t, []string{"/tmp/file_a.rs", "/tmp/file_b.rs"}, parsed)
 }
 
 // Port of: parse_truncates_after_limit
 func TestGrepFiles_ParseTruncatesAfterLimit(t *testing.T) {
 	stdout := []byte("/tmp/file_a.rs\n/tmp/file_b.rs\n/tmp/file_c.rs\n")
-	parsed := parseResults(stdout, 2)
+	parsed := parseResults(stdout, 2, nil)
 	assert.Equal(t, []string{"/tmp/file_a.rs", "/tmp/file_b.rs"}, parsed)
 }
 
@@ -59,7 +60,7 @@ func TestGrepFiles_RunSearchReturnsResults(t *testing.T) {
 	require.NoError(t, os.WriteFile(filepath.Join(dir, "match_two.txt"), []byte("alpha delta"), 0o644))
 	require.NoError(t, os.WriteFile(filepath.Join(dir, "other.txt"), []byte("omega"), 0o644))
 
-	results, err := runRgSearch(context.Background(), "alpha", "", dir, 10)
+	results, err := runRgSearch(context.Background(), nil, "alpha", "", dir, 10, nil)
 	require.NoError(t, err)
 	assert.Len(t, results, 2)
 
@@ -76,7 +77,7 @@ func TestGrepFiles_RunSearchWithGlobFilter(t *testing.T) {
 	require.NoError(t, os.WriteFile(filepath.Join(dir, "match_one.rs"), []byte("alpha beta gamma"), 0o644))
 	require.NoError(t, os.WriteFile(filepath.Join(dir, "match_two.txt"), []byte("alpha delta"), 0o644))
 
-	results, err := runRgSearch(context.Background(), "alpha", "*.rs", dir, 10)
+	results, err := runRgSearch(context.Background(), nil, "alpha", "*.rs", dir, 10, nil)
 	require.NoError(t, err)
 	assert.Len(t, results, 1)
 
@@ -93,7 +94,7 @@ func TestGrepFiles_RunSearchRespectsLimit(t *testing.T) {
 	require.NoError(t, os.WriteFile(filepath.Join(dir, "two.txt"), []byte("alpha two"), 0o644))
 	require.NoError(t, os.WriteFile(filepath.Join(dir, "three.txt"), []byte("alpha three"), 0o644))
 
-	results, err := runRgSearch(context.Background(), "alpha", "", dir, 2)
+	results, err := runRgSearch(context.Background(), nil, "alpha", "", dir, 2, nil)
 	require.NoError(t, err)
 	assert.Len(t, results, 2)
 }
@@ -105,7 +106,7 @@ func TestGrepFiles_RunSearchHandlesNoMatches(t *testing.T) {
 	dir := t.TempDir()
 	require.NoError(t, os.WriteFile(filepath.Join(dir, "one.txt"), []byte("omega"), 0o644))
 
-	results, err := runRgSearch(context.Background(), "alpha", "", dir, 5)
+	results, err := runRgSearch(context.Background(), nil, "alpha", "", dir, 5, nil)
 	require.NoError(t, err)
 	assert.Empty(t, results)
 }
@@ -230,3 +231,10 @@ func joinResults(results []string) string {
 	}
 	return result
 }
+
+func TestGrepFiles_ParseSkipsExcluded(t *testing.T) {
+	policy := exclusion.Load("", nil)
+	stdout := []byte("/tmp/.env\n/tmp/file_a.rs\n/tmp/certs/server.pem\n/tmp/file_b.rs\n")
+	parsed := parseResults(stdout, 2, policy)
+	assert.Equal(t, []string{"/tmp/file_a.rs", "/tmp/file_b.rs"}, parsed)
+}
@@ -8,6 +8,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/mfateev/temporal-agent-harness/internal/exclusion"
 	"github.com/mfateev/temporal-agent-harness/internal/tools"
 )
 
@@ -113,7 +114,12 @@ func (t *ListDirTool) Handle(_ context.Context, invocation *tools.ToolInvocation
 		return nil, tools.NewValidationError("depth must be greater than zero")
 	}
 
-	lines, listErr := listDirSlice(dirPath, offset, limit, depth)
+	policy := exclusionPolicy(invocation)
+	if policy.Excluded(dirPath) {
+		return excludedOutput(dirPath), nil
+	}
+
+	lines, listErr := listDirSlice(dirPath, offset, limit, depth, policy)
 	if listErr != nil {
 		success := false
 		return &tools.ToolOutput{
@@ -134,12 +140,13 @@ func (t *ListDirTool) Handle(_ context.Context, invocation *tools.ToolInvocation
 	}, nil
 }
 
-// listDirSlice collects, sorts, and paginates directory entries.
+// listDirSlice collects, sorts, and paginates directory entries. Entries
+// excluded by policy are omitted and not descended into.
 //
 // Maps to: codex-rs/core/src/tools/handlers/list_dir.rs list_dir_slice
-func listDirSlice(dirPath string, offset, limit, depth int) ([]string, error) {
+func listDirSlice(dirPath string, offset, limit, depth int, policy *exclusion.Policy) ([]string, error) {
 	var entries []dirEntry
-	if err := collectEntries(dirPath, "", depth, &entries); err != nil {
+	if err := collectEntries(dirPath, "", depth, policy, &entries); err != nil {
 		return nil, err
 	}
 
@@ -180,7 +187,7 @@ func listDirSlice(dirPath string, offset, limit, depth int) ([]string, error) {
 // collectEntries performs BFS traversal collecting entries up to the given depth.
 //
 // Maps to: codex-rs/core/src/tools/handlers/list_dir.rs collect_entries
-func collectEntries(dirPath, relativePrefix string, depth int, entries *[]dirEntry) error {
+func collectEntries(dirPath, relativePrefix string, depth int, policy *exclusion.Policy, entries *[]dirEntry) error {
 	type queueItem struct {
 		absPath  string
 		prefix   string
@@ -209,6 +216,9 @@ func collectEntries(dirPath, relativePrefix string, depth int, entries *[]dirEnt
 
 		for _, de := range dirEntries {
 			fileName := de.Name()
+			if policy.Excluded(filepath.Join(item.absPath, fileName)) {
+				continue
+			}
 			var relativePath string
 			if item.prefix == "" {
 				relativePath = fileName
@@ -46,7 +46,7 @@ func TestListDir_ListsDirectoryEntries(t *testing.T) {
 		hasSymlink = true
 	}
 
-	entries, err := listDirSlice(dir, 1, 20, 3)
+	entries, err := listDirSlice(dir, 1, 20, 3, nil)
 	require.NoError(t, err)
 
 	if hasSymlink {
@@ -74,7 +74,7 @@ func TestListDir_ErrorsWhenOffsetExceedsEntries(t *testing.T) {
 	dir := t.TempDir()
 	require.NoError(t, os.Mkdir(filepath.Join(dir, "nested"), 0o755))
 
-	_, err := listDirSlice(dir, 10, 1, 2)
+	_, err := listDirSlice(dir, 10, 1, 2, nil)
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "offset exceeds directory entry count")
 }
@@ -92,7 +92,7 @@ func TestListDir_RespectsDepthParameter(t *testing.T) {
 	require.NoError(t, os.WriteFile(filepath.Join(deeper, "grandchild.txt"), []byte("deep"), 0o644))
 
 	// depth=1: only top-level entries
-	entriesDepth1, err := listDirSlice(dir, 1, 10, 1)
+	entriesDepth1, err := listDirSlice(dir, 1, 10, 1, nil)
 	require.NoError(t, err)
 	assert.Equal(t, []string{
 		"nested/",
@@ -100,7 +100,7 @@ func TestListDir_RespectsDepthParameter(t *testing.T) {
 	}, entriesDepth1)
 
 	// depth=2: top-level + children of directories
-	entriesDepth2, err := listDirSlice(dir, 1, 20, 2)
+	entriesDepth2, err := listDirSlice(dir, 1, 20, 2, nil)
 	require.NoError(t, err)
 	assert.Equal(t, []string{
 		"nested/",
@@ -110,7 +110,7 @@ func TestListDir_RespectsDepthParameter(t *testing.T) {
 	}, entriesDepth2)
 
 	// depth=3: includes grandchildren
-	entriesDepth3, err := listDirSlice(dir, 1, 30, 3)
+	entriesDepth3, err := listDirSlice(dir, 1, 30, 3, nil)
 	require.NoError(t, err)
 	assert.Equal(t, []string{
 		"nested/",
@@ -132,7 +132,7 @@ func TestListDir_PaginatesInSortedOrder(t *testing.T) {
 	require.NoError(t, os.WriteFile(filepath.Join(dirA, "a_child.txt"), []byte("a"), 0o644))
 	require.NoError(t, os.WriteFile(filepath.Join(dirB, "b_child.txt"), []byte("b"), 0o644))
 
-	firstPage, err := listDirSlice(dir, 1, 2, 2)
+	firstPage, err := listDirSlice(dir, 1, 2, 2, nil)
 	require.NoError(t, err)
 	assert.Equal(t, []string{
 		"a/",
@@ -140,7 +140,7 @@ func TestListDir_PaginatesInSortedOrder(t *testing.T) {
 		"More than 2 entries found",
 	}, firstPage)
 
-	secondPage, err := listDirSlice(dir, 3, 2, 2)
+	secondPage, err := listDirSlice(dir, 3, 2, 2, nil)
 	require.NoError(t, err)
 	assert.Equal(t, []string{
 		"b/",
@@ -155,7 +155,7 @@ func TestListDir_HandlesLargeLimitWithoutOverflow(t *testing.T) {
 	require.NoError(t, os.WriteFile(filepath.Join(dir, "beta.txt"), []byte("beta"), 0o644))
 	require.NoError(t, os.WriteFile(filepath.Join(dir, "gamma.txt"), []byte("gamma"), 0o644))
 
-	entries, err := listDirSlice(dir, 2, math.MaxInt, 1)
+	entries, err := listDirSlice(dir, 2, math.MaxInt, 1, nil)
 	require.NoError(t, err)
 	assert.Equal(t, []string{
 		"beta.txt",
@@ -172,7 +172,7 @@ func TestListDir_IndicatesTruncatedResults(t *testing.T) {
 		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("content"), 0o644))
 	}
 
-	entries, err := listDirSlice(dir, 1, 25, 1)
+	entries, err := listDirSlice(dir, 1, 25, 1, nil)
 	require.NoError(t, err)
 	assert.Len(t, entries, 26) // 25 entries + "More than..." message
 	assert.Equal(t, "More than 25 entries found", entries[len(entries)-1])
@@ -190,7 +190,7 @@ func TestListDir_TruncationRespectsSortedOrder(t *testing.T) {
 	require.NoError(t, os.WriteFile(filepath.Join(nested, "child.txt"), []byte("child"), 0o644))
 	require.NoError(t, os.WriteFile(filepath.Join(deeper, "grandchild.txt"), []byte("deep"), 0o644))
 
-	entries, err := listDirSlice(dir, 1, 3, 3)
+	entries, err := listDirSlice(dir, 1, 3, 3, nil)
 	require.NoError(t, err)
 	assert.Equal(t, []string{
 		"nested/",
@@ -284,3 +284,28 @@ func TestListDir_ToolMetadata(t *testing.T) {
 	assert.Equal(t, tools.ToolKindFunction, tool.Kind())
 	assert.False(t, tool.IsMutating(nil))
 }
+
+func TestListDir_OmitsExcludedEntries(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte("x"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("x"), 0o644))
+	secrets := filepath.Join(dir, "secrets")
+	require.NoError(t, os.Mkdir(secrets, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(secrets, "db.txt"), []byte("x"), 0o644))
+
+	inv := newListDirInvocation(map[string]interface{}{"dir_path": dir})
+	inv.Cwd = dir
+	inv.ExcludePaths = []string{"secrets/"}
+	out, err := NewListDirTool().Handle(context.Background(), inv)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("Absolute path: %s\nmain.go", dir), out.Content)
+
+	inv = newListDirInvocation(map[string]interface{}{"dir_path": secrets})
+	inv.Cwd = dir
+	inv.ExcludePaths = []string{"secrets/"}
+	out, err = NewListDirTool().Handle(context.Background(), inv)
+	require.NoError(t, err)
+	require.NotNil(t, out.Success)
+	assert.False(t, *out.Success)
+	assert.Contains(t, out.Content, "path excluded by policy")
+}
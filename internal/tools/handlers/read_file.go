@@ -4,9 +4,11 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
+	"github.com/mfateev/temporal-agent-harness/internal/textenc"
 	"github.com/mfateev/temporal-agent-harness/internal/tools"
 )
 
@@ -128,6 +130,17 @@ func (t *ReadFileTool) Handle(_ context.Context, invocation *tools.ToolInvocatio
 		}
 	}
 
+	hexdump := false
+	if hexArg, ok := invocation.Arguments["hexdump"]; ok {
+		if b, ok := hexArg.(bool); ok {
+			hexdump = b
+		}
+	}
+
+	if exclusionPolicy(invocation).Excluded(path) {
+		return excludedOutput(path), nil
+	}
+
 	file, err := os.Open(path)
 	if err != nil {
 		success := false
@@ -138,17 +151,103 @@ func (t *ReadFileTool) Handle(_ context.Context, invocation *tools.ToolInvocatio
 	}
 	defer file.Close()
 
+	head, err := readHead(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	enc := textenc.Detect(head)
+	if (enc == textenc.UTF8 || enc == textenc.Latin1) && looksBinary(head) {
+		return readBinaryFile(file, path, head, hexdump)
+	}
+
+	// Non-UTF-8 text is converted so the model sees UTF-8; edit tools
+	// write it back in the original encoding. Line endings are counted in
+	// the head of UTF-8 files and the whole text of converted ones.
+	var src io.Reader = file
+	eols := textenc.CountLineEndings(string(head))
+	if !enc.IsUTF8() {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading file: %w", err)
+		}
+		text, err := textenc.Decode(data, enc)
+		if err != nil {
+			return readBinaryFile(file, path, head, hexdump)
+		}
+		src = strings.NewReader(text)
+		eols = textenc.CountLineEndings(text)
+	}
+
 	// Dispatch to the appropriate mode handler.
+	var out *tools.ToolOutput
 	if mode == "indentation" {
-		return readFileIndentation(file, path, offset, limit, indentOpts)
+		out, err = readFileIndentation(src, path, offset, limit, indentOpts)
+	} else {
+		out, err = readFileSlice(src, path, offset, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var notes []string
+	if !enc.IsUTF8() {
+		notes = append(notes, textenc.ConvertedNotice(enc))
+	}
+	if eols.Mixed() {
+		eol := eols.Dominant()
+		if p := invocation.LineEndings; p == textenc.LF || p == textenc.CRLF {
+			eol = p
+		}
+		notes = append(notes, fmt.Sprintf("(mixed line endings: %s; edits use %s)",
+			eols, textenc.DisplayEOL(eol)))
+	}
+	for _, n := range notes {
+		if !strings.HasSuffix(out.Content, "\n") {
+			out.Content += "\n"
+		}
+		out.Content += n
+	}
+	return out, nil
+}
+
+// readBinaryFile describes a binary file instead of returning its bytes.
+// With hexdump set, small files also get a hexdump of their head and tail.
+func readBinaryFile(file *os.File, path string, head []byte, hexdump bool) (*tools.ToolOutput, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
 	}
+	size := info.Size()
 
-	return readFileSlice(file, path, offset, limit)
+	var result strings.Builder
+	fmt.Fprintf(&result, "File: %s\n(%s; contents not shown)\n", path, describeBinary(size, head))
+	switch {
+	case size > hexdumpMaxFileBytes:
+		if hexdump {
+			fmt.Fprintf(&result, "File is too large to hexdump (limit %s).\n", formatFileSize(hexdumpMaxFileBytes))
+		}
+	case !hexdump:
+		fmt.Fprintf(&result, "Call read_file with hexdump=true to see the first and last %d bytes.\n", hexdumpChunkBytes)
+	default:
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("error reading file: %w", err)
+		}
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading file: %w", err)
+		}
+		result.WriteString(hexdumpHeadTail(data))
+	}
+
+	success := true
+	return &tools.ToolOutput{
+		Content: result.String(),
+		Success: &success,
+	}, nil
 }
 
 // readFileSlice implements the original slice-mode read (offset + limit).
-func readFileSlice(file *os.File, path string, offset, limit int) (*tools.ToolOutput, error) {
-	scanner := bufio.NewScanner(file)
+func readFileSlice(r io.Reader, path string, offset, limit int) (*tools.ToolOutput, error) {
+	scanner := bufio.NewScanner(r)
 	var result strings.Builder
 	lineNum := 0
 	linesRead := 0
@@ -214,9 +313,9 @@ func readFileSlice(file *os.File, path string, offset, limit int) (*tools.ToolOu
 //  6. Trim leading/trailing blank lines
 //  7. Cap to max_lines (or limit)
 //  8. Format with line numbers
-func readFileIndentation(file *os.File, path string, offset, limit int, opts indentationOptions) (*tools.ToolOutput, error) {
+func readFileIndentation(r io.Reader, path string, offset, limit int, opts indentationOptions) (*tools.ToolOutput, error) {
 	// Step 1: Read all lines.
-	records, err := readAllLines(file)
+	records, err := readAllLines(r)
 	if err != nil {
 		return nil, fmt.Errorf("error reading file: %w", err)
 	}
@@ -380,8 +479,8 @@ func expandDown(records []lineRecord, effectiveIndents []int, included []bool, a
 }
 
 // readAllLines reads all lines from the file into lineRecord structs.
-func readAllLines(file *os.File) ([]lineRecord, error) {
-	scanner := bufio.NewScanner(file)
+func readAllLines(r io.Reader) ([]lineRecord, error) {
+	scanner := bufio.NewScanner(r)
 	var records []lineRecord
 	lineNum := 0
 	for scanner.Scan() {
@@ -106,6 +106,26 @@ func TestReadFile_NonexistentFile(t *testing.T) {
 	assert.Contains(t, out.Content, "Failed to open file")
 }
 
+func TestReadFile_ExcludedByPolicy(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte("API_KEY=secret\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("private\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".codexignore"), []byte("notes.txt\n"), 0644))
+
+	tool := NewReadFileTool()
+	for _, name := range []string{".env", "notes.txt"} {
+		inv := newReadInvocation(map[string]interface{}{"path": filepath.Join(dir, name)})
+		inv.Cwd = dir
+		out, err := tool.Handle(context.Background(), inv)
+		require.NoError(t, err)
+		require.NotNil(t, out.Success)
+		assert.False(t, *out.Success)
+		assert.Contains(t, out.Content, "path excluded by policy")
+		assert.NotContains(t, out.Content, "secret")
+		assert.NotContains(t, out.Content, "private")
+	}
+}
+
 func TestReadFile_WithLimit(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "multi.txt")
@@ -685,3 +705,103 @@ func TestParseIndentationOptions_Defaults(t *testing.T) {
 	assert.False(t, opts.includeHeader)
 	assert.Equal(t, 0, opts.maxLines)
 }
+
+// ---------------------------------------------------------------------------
+// Binary files
+// ---------------------------------------------------------------------------
+
+func TestReadFile_BinaryFileReturnsNotice(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logo.png")
+	require.NoError(t, os.WriteFile(path, append(pngHeader, make([]byte, 2048)...), 0644))
+
+	tool := NewReadFileTool()
+	out, err := tool.Handle(context.Background(), newReadInvocation(map[string]interface{}{
+		"file_path": path,
+	}))
+	require.NoError(t, err)
+	require.NotNil(t, out.Success)
+	assert.True(t, *out.Success)
+	assert.Contains(t, out.Content, "File: "+path+"\n")
+	assert.Contains(t, out.Content, "(binary file, 2.0 KiB, type: PNG; contents not shown)")
+	assert.Contains(t, out.Content, "hexdump=true")
+	assert.NotContains(t, out.Content, "IHDR", "raw bytes must not be returned")
+}
+
+func TestReadFile_BinaryFileHexdump(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "blob.bin")
+	require.NoError(t, os.WriteFile(path, []byte("AB\x00CD"), 0644))
+
+	tool := NewReadFileTool()
+	out, err := tool.Handle(context.Background(), newReadInvocation(map[string]interface{}{
+		"file_path": path,
+		"hexdump":   true,
+	}))
+	require.NoError(t, err)
+	assert.Contains(t, out.Content, "(binary file, 5 B, type: unknown; contents not shown)")
+	assert.Contains(t, out.Content, "00000000  41 42 00 43 44")
+	assert.Contains(t, out.Content, "|AB.CD|")
+}
+
+func TestReadFile_LargeBinaryFileRefusesHexdump(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "big.bin")
+	require.NoError(t, os.WriteFile(path, make([]byte, hexdumpMaxFileBytes+1), 0644))
+
+	tool := NewReadFileTool()
+	out, err := tool.Handle(context.Background(), newReadInvocation(map[string]interface{}{
+		"file_path": path,
+		"hexdump":   true,
+	}))
+	require.NoError(t, err)
+	assert.Contains(t, out.Content, "too large to hexdump")
+	assert.NotContains(t, out.Content, "00000000")
+}
+
+// ---------------------------------------------------------------------------
+// Encodings
+// ---------------------------------------------------------------------------
+
+func TestReadFile_UTF16IsConverted(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	require.NoError(t, os.WriteFile(path, []byte("\xff\xfea\x00\n\x00b\x00\n\x00"), 0644))
+
+	tool := NewReadFileTool()
+	out, err := tool.Handle(context.Background(), newReadInvocation(map[string]interface{}{
+		"file_path": path,
+	}))
+	require.NoError(t, err)
+	assert.True(t, *out.Success)
+	assert.Equal(t, "File: "+path+"\n     1\ta\n     2\tb\n(converted from UTF-16LE; edits are written back as UTF-16LE)", out.Content)
+}
+
+func TestReadFile_Latin1IsConverted(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "menu.txt")
+	require.NoError(t, os.WriteFile(path, []byte("caf\xe9\ncr\xe8me br\xfbl\xe9e\n"), 0644))
+
+	tool := NewReadFileTool()
+	out, err := tool.Handle(context.Background(), newReadInvocation(map[string]interface{}{
+		"file_path": path,
+		"mode":      "indentation",
+		"offset":    float64(2),
+	}))
+	require.NoError(t, err)
+	assert.Contains(t, out.Content, "crème brûlée")
+	assert.Contains(t, out.Content, "(converted from Latin-1")
+}
+
+func TestReadFile_FlagsMixedLineEndings(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mixed.txt")
+	require.NoError(t, os.WriteFile(path, []byte("a\r\nb\r\nc\n"), 0644))
+
+	tool := NewReadFileTool()
+	out, err := tool.Handle(context.Background(), newReadInvocation(map[string]interface{}{
+		"file_path": path,
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "File: "+path+"\n     1\ta\n     2\tb\n     3\tc\n(mixed line endings: 2 CRLF, 1 LF; edits use CRLF)", out.Content)
+}
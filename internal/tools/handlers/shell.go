@@ -6,6 +6,7 @@ package handlers
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -13,7 +14,9 @@ import (
 	"github.com/mfateev/temporal-agent-harness/internal/command_safety"
 	execpkg "github.com/mfateev/temporal-agent-harness/internal/exec"
 	"github.com/mfateev/temporal-agent-harness/internal/execenv"
+	"github.com/mfateev/temporal-agent-harness/internal/remote"
 	"github.com/mfateev/temporal-agent-harness/internal/sandbox"
+	"github.com/mfateev/temporal-agent-harness/internal/scratch"
 	"github.com/mfateev/temporal-agent-harness/internal/shell"
 	"github.com/mfateev/temporal-agent-harness/internal/tools"
 )
@@ -43,7 +46,15 @@ func executeCommand(
 	invocation *tools.ToolInvocation,
 	sandboxMgr sandbox.SandboxManager,
 ) (*tools.ToolOutput, error) {
-	execEnv, err := resolveExecEnv(spec, invocation.SandboxPolicy, sandboxMgr)
+	// A remote command runs under ssh on the worker: the worker's sandbox,
+	// env policy and scratch directory do not apply to the remote machine,
+	// and ssh needs the worker's environment (e.g. SSH_AUTH_SOCK).
+	local := invocation.Remote == nil
+	policyRef := invocation.SandboxPolicy
+	if !local {
+		policyRef = nil
+	}
+	execEnv, err := resolveExecEnv(spec, policyRef, sandboxMgr)
 	if err != nil {
 		return nil, tools.NewValidationError("sandbox setup failed: " + err.Error())
 	}
@@ -54,7 +65,7 @@ func executeCommand(
 	}
 
 	// Apply environment variable filtering if an env policy is set.
-	if invocation.EnvPolicy != nil {
+	if invocation.EnvPolicy != nil && local {
 		filteredEnv := resolveFilteredEnv(invocation.EnvPolicy)
 		cmd.Env = execenv.EnvMapToSlice(filteredEnv)
 	}
@@ -67,6 +78,14 @@ func executeCommand(
 		cmd.Env = appendEnvMap(cmd.Env, execEnv.Env)
 	}
 
+	// Expose the session scratch directory as $SCRATCH.
+	if invocation.ScratchDir != "" && local {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env, scratch.EnvVar+"="+invocation.ScratchDir)
+	}
+
 	var stdoutBuf, stderrBuf bytes.Buffer
 	cmd.Stdout = &stdoutBuf
 	cmd.Stderr = &stderrBuf
@@ -80,19 +99,34 @@ func executeCommand(
 			return nil, ctx.Err()
 		}
 		success := false
-		return &tools.ToolOutput{
+		out := &tools.ToolOutput{
 			Content: string(output),
 			Success: &success,
-		}, nil
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			code := exitErr.ExitCode()
+			out.ExitCode = &code
+		}
+		return out, nil
 	}
 
 	success := true
+	code := 0
 	return &tools.ToolOutput{
-		Content: string(output),
-		Success: &success,
+		Content:  string(output),
+		Success:  &success,
+		ExitCode: &code,
 	}, nil
 }
 
+// remoteCommandSpec returns the local ssh command that runs remoteCommand
+// in cwd on the invocation's remote machine.
+func remoteCommandSpec(invocation *tools.ToolInvocation, cwd, remoteCommand string) sandbox.CommandSpec {
+	argv := invocation.Remote.Command(remote.Script(cwd, nil, remoteCommand), false)
+	return sandbox.CommandSpec{Program: argv[0], Args: argv[1:]}
+}
+
 // resolveExecEnv applies sandbox wrapping if a policy is set.
 func resolveExecEnv(spec sandbox.CommandSpec, policyRef *tools.SandboxPolicyRef, sandboxMgr sandbox.SandboxManager) (*sandbox.ExecEnv, error) {
 	if policyRef == nil || sandboxMgr == nil {
@@ -225,6 +259,9 @@ func (h *ShellHandler) Handle(ctx context.Context, invocation *tools.ToolInvocat
 		Args:    cmdVec[1:],
 		Cwd:     cwd,
 	}
+	if invocation.Remote != nil {
+		spec = remoteCommandSpec(invocation, cwd, remote.ExecCommand(cmdVec))
+	}
 
 	return executeCommand(ctx, spec, invocation, h.sandboxMgr)
 }
@@ -301,6 +338,10 @@ func (h *ShellCommandHandler) Handle(ctx context.Context, invocation *tools.Tool
 		Args:    execArgs[1:],
 		Cwd:     cwd,
 	}
+	if invocation.Remote != nil {
+		// The worker's shell may not exist remotely; use the remote user's.
+		spec = remoteCommandSpec(invocation, cwd, remote.ShellCommand(command, login))
+	}
 
 	return executeCommand(ctx, spec, invocation, h.sandboxMgr)
 }
@@ -69,13 +69,15 @@ func TestShellCommandHandler_Handle_Success(t *testing.T) {
 func TestShellCommandHandler_Handle_Failure(t *testing.T) {
 	tool := NewShellCommandHandler()
 	invocation := &tools.ToolInvocation{
-		Arguments: map[string]interface{}{"command": "exit 1"},
+		Arguments: map[string]interface{}{"command": "exit 3"},
 	}
 	output, err := tool.Handle(context.Background(), invocation)
 	require.NoError(t, err) // Non-zero exit is not a Go error
 	require.NotNil(t, output)
 	require.NotNil(t, output.Success)
 	assert.False(t, *output.Success)
+	require.NotNil(t, output.ExitCode)
+	assert.Equal(t, 3, *output.ExitCode)
 }
 
 func TestShellCommandHandler_Handle_StderrCaptured(t *testing.T) {
@@ -91,6 +93,19 @@ func TestShellCommandHandler_Handle_StderrCaptured(t *testing.T) {
 	assert.Contains(t, output.Content, "err")
 }
 
+func TestShellCommandHandler_Handle_ExportsScratchDir(t *testing.T) {
+	tool := NewShellCommandHandler()
+	scratchDir := t.TempDir()
+	invocation := &tools.ToolInvocation{
+		Arguments:  map[string]interface{}{"command": "echo \"dir=$SCRATCH\""},
+		ScratchDir: scratchDir,
+	}
+	output, err := tool.Handle(context.Background(), invocation)
+	require.NoError(t, err)
+	require.NotNil(t, output)
+	assert.Contains(t, output.Content, "dir="+scratchDir)
+}
+
 func TestShellCommandHandler_Handle_MissingCommand(t *testing.T) {
 	tool := NewShellCommandHandler()
 	invocation := &tools.ToolInvocation{
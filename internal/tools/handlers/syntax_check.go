@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/mfateev/temporal-agent-harness/internal/textenc"
+)
+
+// Post-edit syntax checks. After write_file or apply_patch writes a file in
+// a language with a cheap parser, the file is parsed and any syntax errors
+// are appended to the tool output, so the model fixes them in the same turn
+// instead of discovering them at build time.
+const (
+	// syntaxCheckMaxErrors caps the errors reported per file.
+	syntaxCheckMaxErrors = 10
+
+	// syntaxCheckMaxBytes skips files too large to parse quickly.
+	syntaxCheckMaxBytes = 2 << 20
+
+	// pythonCheckTimeout bounds the python3 subprocess.
+	pythonCheckTimeout = 5 * time.Second
+)
+
+// syntaxCheckers maps file extensions to their checker. A checker returns
+// one message per syntax error, each prefixed with its line and column when
+// known.
+var syntaxCheckers = map[string]func(ctx context.Context, path string, src []byte) []string{
+	".go":   checkGoSyntax,
+	".json": checkJSONSyntax,
+	".yaml": checkYAMLSyntax,
+	".yml":  checkYAMLSyntax,
+	".py":   checkPythonSyntax,
+}
+
+// syntaxCheckReport checks each written file and returns the failures to
+// append to the tool output, or "" if every file parsed.
+func syntaxCheckReport(ctx context.Context, paths []string) string {
+	var b strings.Builder
+	for _, p := range paths {
+		errs := checkSyntax(ctx, p)
+		if len(errs) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n\nSyntax check failed for %s:\n", p)
+		for _, e := range errs {
+			fmt.Fprintf(&b, "  %s\n", e)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// checkSyntax parses the file at path with the checker for its extension.
+// Files without a checker, unreadable or binary files, and files over
+// syntaxCheckMaxBytes are skipped.
+func checkSyntax(ctx context.Context, path string) []string {
+	check, ok := syntaxCheckers[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || info.Size() > syntaxCheckMaxBytes {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	enc := textenc.Detect(data)
+	if enc.IsUTF8() && looksBinary(data[:min(len(data), binarySniffBytes)]) {
+		return nil
+	}
+	text, err := textenc.Decode(data, enc)
+	if err != nil {
+		return nil
+	}
+	errs := check(ctx, path, []byte(text))
+	if len(errs) > syntaxCheckMaxErrors {
+		errs = append(errs[:syntaxCheckMaxErrors], fmt.Sprintf("... and %d more", len(errs)-syntaxCheckMaxErrors))
+	}
+	return errs
+}
+
+// checkGoSyntax parses Go source, like `gofmt -e`.
+func checkGoSyntax(_ context.Context, path string, src []byte) []string {
+	_, err := parser.ParseFile(token.NewFileSet(), path, src, parser.AllErrors)
+	if err == nil {
+		return nil
+	}
+	var list scanner.ErrorList
+	if !errors.As(err, &list) {
+		return []string{err.Error()}
+	}
+	errs := make([]string, 0, len(list))
+	for _, e := range list {
+		errs = append(errs, fmt.Sprintf("%d:%d: %s", e.Pos.Line, e.Pos.Column, e.Msg))
+	}
+	return errs
+}
+
+// checkJSONSyntax decodes a JSON document.
+func checkJSONSyntax(_ context.Context, _ string, src []byte) []string {
+	var v interface{}
+	err := json.Unmarshal(src, &v)
+	if err == nil {
+		return nil
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		// Offset counts the offending byte itself.
+		line, col := lineColumn(src, int(syntaxErr.Offset)-1)
+		return []string{fmt.Sprintf("%d:%d: %s", line, col, syntaxErr.Error())}
+	}
+	return []string{err.Error()}
+}
+
+// checkYAMLSyntax decodes every document of a YAML stream.
+func checkYAMLSyntax(_ context.Context, _ string, src []byte) []string {
+	dec := yaml.NewDecoder(bytes.NewReader(src))
+	for {
+		var v yaml.Node
+		err := dec.Decode(&v)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return []string{strings.TrimPrefix(err.Error(), "yaml: ")}
+		}
+	}
+}
+
+// checkPythonSyntax compiles Python source with python3's ast module, which
+// reports the same syntax errors as py_compile without writing bytecode.
+// Skipped when python3 is not installed.
+func checkPythonSyntax(ctx context.Context, path string, src []byte) []string {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, pythonCheckTimeout)
+	defer cancel()
+	const script = `import ast, sys
+try:
+    ast.parse(sys.stdin.buffer.read(), sys.argv[1])
+except SyntaxError as e:
+    print("%d:%d: %s" % (e.lineno or 0, e.offset or 0, e.msg))
+    sys.exit(1)
+`
+	cmd := exec.CommandContext(ctx, python, "-c", script, path)
+	cmd.Stdin = bytes.NewReader(src)
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	msg := strings.TrimSpace(string(out))
+	if err == nil || !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 || msg == "" {
+		return nil // parsed, or the check itself could not run
+	}
+	return []string{msg}
+}
+
+// lineColumn converts a byte offset into a 1-indexed line and column.
+func lineColumn(src []byte, offset int) (int, int) {
+	offset = min(max(offset, 0), len(src))
+	before := src[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := offset - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/tools"
+)
+
+func writeTemp(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestCheckSyntax_Go(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, checkSyntax(ctx, writeTemp(t, "ok.go", "package a\n\nfunc F() {}\n")))
+
+	errs := checkSyntax(ctx, writeTemp(t, "bad.go", "package a\n\nfunc F() {\n\tx := \n}\n"))
+	require.NotEmpty(t, errs)
+	assert.True(t, strings.HasPrefix(errs[0], "5:1: "), errs[0])
+}
+
+func TestCheckSyntax_JSON(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, checkSyntax(ctx, writeTemp(t, "ok.json", `{"a": [1, 2]}`)))
+
+	errs := checkSyntax(ctx, writeTemp(t, "bad.json", "{\n  \"a\": 1,\n}\n"))
+	require.Len(t, errs, 1)
+	assert.True(t, strings.HasPrefix(errs[0], "3:1: invalid character '}'"), errs[0])
+}
+
+func TestCheckSyntax_YAML(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, checkSyntax(ctx, writeTemp(t, "ok.yaml", "a: 1\n---\nb: [1, 2]\n")))
+
+	errs := checkSyntax(ctx, writeTemp(t, "bad.yml", "a: 1\nb: [1, 2\n"))
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0], "line")
+}
+
+func TestCheckSyntax_Python(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not available in PATH; skipping test")
+	}
+	ctx := context.Background()
+	assert.Empty(t, checkSyntax(ctx, writeTemp(t, "ok.py", "def f():\n    return 1\n")))
+
+	errs := checkSyntax(ctx, writeTemp(t, "bad.py", "def f(:\n    return 1\n"))
+	require.Len(t, errs, 1)
+	assert.True(t, strings.HasPrefix(errs[0], "1:"), errs[0])
+}
+
+func TestCheckSyntax_SkipsUnknownAndMissing(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, checkSyntax(ctx, writeTemp(t, "notes.txt", "{{{")))
+	assert.Empty(t, checkSyntax(ctx, filepath.Join(t.TempDir(), "gone.json")))
+}
+
+func TestCheckSyntax_CapsErrors(t *testing.T) {
+	src := "package a\n" + strings.Repeat("func (\n", 30)
+	errs := checkSyntax(context.Background(), writeTemp(t, "many.go", src))
+	assert.Len(t, errs, syntaxCheckMaxErrors+1)
+	assert.True(t, strings.HasPrefix(errs[syntaxCheckMaxErrors], "... and "))
+}
+
+func TestWriteFile_ReportsSyntaxErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	tool := NewWriteFileTool()
+	out, err := tool.Handle(context.Background(), &tools.ToolInvocation{
+		ToolName:  "write_file",
+		Arguments: map[string]interface{}{"path": path, "content": "{\"a\": }\n"},
+	})
+	require.NoError(t, err)
+	assert.True(t, *out.Success, "the write itself succeeded")
+	assert.Contains(t, out.Content, "\n\nSyntax check failed for "+path+":\n  1:7: invalid character '}'")
+}
+
+func TestApplyPatch_ReportsSyntaxErrors(t *testing.T) {
+	path := writeTemp(t, "main.go", "package main\n\nfunc main() {\n}\n")
+
+	tool := NewApplyPatchTool()
+	out, err := tool.Handle(context.Background(), &tools.ToolInvocation{
+		ToolName: "apply_patch",
+		Arguments: map[string]interface{}{
+			"input": "*** Begin Patch\n*** Update File: " + path + "\n@@\n func main() {\n+\tif {\n }\n*** End Patch",
+		},
+	})
+	require.NoError(t, err)
+	assert.True(t, *out.Success)
+	assert.Contains(t, out.Content, "M "+path)
+	assert.Contains(t, out.Content, "Syntax check failed for "+path)
+}
@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"sort"
 	"time"
 
 	"github.com/mfateev/temporal-agent-harness/internal/command_safety"
 	"github.com/mfateev/temporal-agent-harness/internal/execsession"
+	"github.com/mfateev/temporal-agent-harness/internal/remote"
+	"github.com/mfateev/temporal-agent-harness/internal/scratch"
 	"github.com/mfateev/temporal-agent-harness/internal/shell"
 	"github.com/mfateev/temporal-agent-harness/internal/tools"
 )
@@ -133,6 +136,10 @@ func (h *UnifiedExecHandler) handleExecCommand(ctx context.Context, inv *tools.T
 
 	// Build environment: inherit + unified exec env.
 	env := buildExecEnv(inv)
+	if inv.Remote != nil {
+		cmdVec = remoteExecCommand(inv, cmdStr, shellBin, login, cwd, tty)
+		cwd, env = "", nil
+	}
 
 	// Allocate process ID.
 	processID := h.store.AllocateID()
@@ -145,6 +152,7 @@ func (h *UnifiedExecHandler) handleExecCommand(ctx context.Context, inv *tools.T
 		Cwd:       cwd,
 		Env:       env,
 		TTY:       tty,
+		Owner:     inv.SessionID,
 	})
 	if err != nil {
 		h.store.ReleaseID(processID)
@@ -256,21 +264,51 @@ func formatExecResponse(output []byte, wallTime time.Duration, exitCode *int, se
 
 	success := exitCode == nil || *exitCode == 0
 	return &tools.ToolOutput{
-		Content: result,
-		Success: &success,
+		Content:  result,
+		Success:  &success,
+		ExitCode: exitCode,
 	}
 }
 
 // buildExecEnv creates the environment for exec sessions:
-// base OS environment + unified exec vars overlaid.
+// base OS environment + unified exec vars overlaid, plus $SCRATCH when
+// the session has a scratch directory.
 func buildExecEnv(inv *tools.ToolInvocation) []string {
 	env := os.Environ()
 	for k, v := range unifiedExecEnv {
 		env = append(env, k+"="+v)
 	}
+	if inv.ScratchDir != "" {
+		env = append(env, scratch.EnvVar+"="+inv.ScratchDir)
+	}
 	return env
 }
 
+// remoteExecCommand returns the local ssh command that runs cmdStr in cwd
+// on the invocation's remote machine, with the unified exec env exported
+// there. Without an explicit shell it uses the remote user's shell, as the
+// worker's may not exist remotely.
+func remoteExecCommand(inv *tools.ToolInvocation, cmdStr, shellBin string, login bool, cwd string, tty bool) []string {
+	remoteCmd := remote.ShellCommand(cmdStr, login)
+	if shellBin != "" {
+		flag := "-c"
+		if login {
+			flag = "-lc"
+		}
+		remoteCmd = remote.ExecCommand([]string{shellBin, flag, cmdStr})
+	}
+	keys := make([]string, 0, len(unifiedExecEnv))
+	for k := range unifiedExecEnv {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	env := make([]string, 0, len(keys))
+	for _, k := range keys {
+		env = append(env, k+"="+unifiedExecEnv[k])
+	}
+	return inv.Remote.Command(remote.Script(cwd, env, remoteCmd), tty)
+}
+
 // parseBoolArg extracts a boolean argument with a default value.
 func parseBoolArg(args map[string]interface{}, key string, defaultVal bool) bool {
 	v, ok := args[key]
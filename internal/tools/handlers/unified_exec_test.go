@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/mfateev/temporal-agent-harness/internal/execsession"
+	"github.com/mfateev/temporal-agent-harness/internal/remote"
 	"github.com/mfateev/temporal-agent-harness/internal/tools"
 )
 
@@ -343,3 +344,19 @@ func parseSessionIDForTest(id string) float64 {
 	}
 	return f
 }
+
+func TestRemoteExecCommand(t *testing.T) {
+	inv := newExecInvocation(map[string]interface{}{"cmd": "npm run dev"})
+	inv.Remote = &remote.Target{Host: "devbox"}
+
+	argv := remoteExecCommand(inv, "npm run dev", "", true, "/src/app", true)
+	require.Greater(t, len(argv), 3)
+	assert.Equal(t, "ssh", argv[0])
+	assert.Contains(t, argv, "-tt")
+	script := argv[len(argv)-1]
+	assert.True(t, strings.HasPrefix(script, "cd /src/app && export COLORTERM='' GH_PAGER=cat "), script)
+	assert.True(t, strings.HasSuffix(script, ` && exec "${SHELL:-/bin/sh}" -lc 'npm run dev'`), script)
+
+	argv = remoteExecCommand(inv, "echo hi", "/bin/bash", false, "", false)
+	assert.True(t, strings.HasSuffix(argv[len(argv)-1], "exec /bin/bash -c 'echo hi'"), argv[len(argv)-1])
+}
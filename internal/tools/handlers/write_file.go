@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/mfateev/temporal-agent-harness/internal/textenc"
 	"github.com/mfateev/temporal-agent-harness/internal/tools"
 )
 
@@ -36,7 +38,7 @@ func (t *WriteFileTool) IsMutating(invocation *tools.ToolInvocation) bool {
 }
 
 // Handle writes content to a file, creating parent directories as needed.
-func (t *WriteFileTool) Handle(_ context.Context, invocation *tools.ToolInvocation) (*tools.ToolOutput, error) {
+func (t *WriteFileTool) Handle(ctx context.Context, invocation *tools.ToolInvocation) (*tools.ToolOutput, error) {
 	pathArg, ok := invocation.Arguments["path"]
 	if !ok {
 		return nil, tools.NewValidationError("missing required argument: path")
@@ -71,8 +73,41 @@ func (t *WriteFileTool) Handle(_ context.Context, invocation *tools.ToolInvocati
 		}, nil
 	}
 
-	// Write the file.
+	// Write the file, keeping the encoding and dominant line ending of an
+	// existing text file unless the line-ending policy forces one.
+	var notes []string
+	format, exists := existingFormat(path)
+	eol := ""
+	switch {
+	case invocation.LineEndings == textenc.LF || invocation.LineEndings == textenc.CRLF:
+		eol = invocation.LineEndings
+		if written := textenc.CountLineEndings(content); (eol == textenc.LF && written.CRLF > 0) ||
+			(eol == textenc.CRLF && written.LF > 0) {
+			notes = append(notes, "line endings converted to "+textenc.DisplayEOL(eol))
+		}
+	case exists && format.eols.LF+format.eols.CRLF > 0:
+		eol = format.eols.Dominant()
+		if format.eols.Mixed() {
+			notes = append(notes, fmt.Sprintf("existing file had mixed line endings (%s); wrote %s",
+				format.eols, textenc.DisplayEOL(eol)))
+		}
+	}
+	if eol != "" {
+		content = textenc.ConvertLineEndings(content, eol)
+	}
 	data := []byte(content)
+	if exists && !format.encoding.IsUTF8() {
+		encoded, err := textenc.Encode(content, format.encoding, format.bom)
+		if err != nil {
+			success := false
+			return &tools.ToolOutput{
+				Content: fmt.Sprintf("Failed to write file: %v", err),
+				Success: &success,
+			}, nil
+		}
+		data = encoded
+		notes = append(notes, textenc.KeptNotice(format.encoding))
+	}
 	if err := os.WriteFile(path, data, 0o644); err != nil {
 		success := false
 		return &tools.ToolOutput{
@@ -81,9 +116,49 @@ func (t *WriteFileTool) Handle(_ context.Context, invocation *tools.ToolInvocati
 		}, nil
 	}
 
+	result := fmt.Sprintf("Successfully wrote %d bytes to %s", len(data), path)
+	if len(notes) > 0 {
+		result += " (" + strings.Join(notes, "; ") + ")"
+	}
+	result += syntaxCheckReport(ctx, []string{path})
 	success := true
 	return &tools.ToolOutput{
-		Content: fmt.Sprintf("Successfully wrote %d bytes to %s", len(data), path),
+		Content: result,
 		Success: &success,
 	}, nil
 }
+
+// textFormat is the encoding and line endings of an existing text file.
+type textFormat struct {
+	encoding textenc.Encoding
+	bom      bool
+	eols     textenc.LineEndings
+}
+
+// existingFormat sniffs the format of the file at path. Returns false if
+// the file does not exist or is binary. Line endings are counted in the
+// sniffed head only.
+func existingFormat(path string) (textFormat, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return textFormat{}, false
+	}
+	defer f.Close()
+	head, err := readHead(f)
+	if err != nil {
+		return textFormat{}, false
+	}
+	enc := textenc.Detect(head)
+	if (enc == textenc.UTF8 || enc == textenc.Latin1) && looksBinary(head) {
+		return textFormat{}, false
+	}
+	text, err := textenc.Decode(head, enc)
+	if err != nil {
+		return textFormat{}, false
+	}
+	return textFormat{
+		encoding: enc,
+		bom:      textenc.HasBOM(head, enc),
+		eols:     textenc.CountLineEndings(text),
+	}, true
+}
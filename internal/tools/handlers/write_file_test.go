@@ -201,3 +201,96 @@ func TestWriteFile_ToolMetadata(t *testing.T) {
 	assert.Equal(t, tools.ToolKindFunction, tool.Kind())
 	assert.True(t, tool.IsMutating(nil))
 }
+
+func TestWriteFile_KeepsExistingEncoding(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	require.NoError(t, os.WriteFile(path, []byte("\xff\xfea\x00\n\x00"), 0o644))
+
+	tool := NewWriteFileTool()
+	out, err := tool.Handle(context.Background(), newWriteInvocation(map[string]interface{}{
+		"path":    path,
+		"content": "é\n",
+	}))
+	require.NoError(t, err)
+	assert.True(t, *out.Success)
+	assert.Contains(t, out.Content, "(kept UTF-16LE encoding)")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("\xff\xfe\xe9\x00\n\x00"), data)
+}
+
+func TestWriteFile_RejectsCharactersOutsideEncoding(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "latin1.txt")
+	require.NoError(t, os.WriteFile(path, []byte("caf\xe9\n"), 0o644))
+
+	tool := NewWriteFileTool()
+	out, err := tool.Handle(context.Background(), newWriteInvocation(map[string]interface{}{
+		"path":    path,
+		"content": "café →\n",
+	}))
+	require.NoError(t, err)
+	assert.False(t, *out.Success)
+	assert.Contains(t, out.Content, "cannot be written as Latin-1")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("caf\xe9\n"), data, "the file is left untouched")
+}
+
+func TestWriteFile_PreservesCRLF(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "win.txt")
+	require.NoError(t, os.WriteFile(path, []byte("a\r\nb\r\n"), 0o644))
+
+	tool := NewWriteFileTool()
+	out, err := tool.Handle(context.Background(), newWriteInvocation(map[string]interface{}{
+		"path":    path,
+		"content": "x\ny\n",
+	}))
+	require.NoError(t, err)
+	assert.True(t, *out.Success)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "x\r\ny\r\n", string(data))
+}
+
+func TestWriteFile_FlagsMixedLineEndings(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mixed.txt")
+	require.NoError(t, os.WriteFile(path, []byte("a\nb\nc\r\n"), 0o644))
+
+	tool := NewWriteFileTool()
+	out, err := tool.Handle(context.Background(), newWriteInvocation(map[string]interface{}{
+		"path":    path,
+		"content": "x\r\ny\n",
+	}))
+	require.NoError(t, err)
+	assert.Contains(t, out.Content, "(existing file had mixed line endings (1 CRLF, 2 LF); wrote LF)")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "x\ny\n", string(data))
+}
+
+func TestWriteFile_LineEndingPolicy(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "new.txt")
+
+	tool := NewWriteFileTool()
+	inv := newWriteInvocation(map[string]interface{}{
+		"path":    path,
+		"content": "x\ny\n",
+	})
+	inv.LineEndings = "crlf"
+	out, err := tool.Handle(context.Background(), inv)
+	require.NoError(t, err)
+	assert.Contains(t, out.Content, "(line endings converted to CRLF)")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "x\r\ny\r\n", string(data))
+}
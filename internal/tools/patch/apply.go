@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+
+	"github.com/mfateev/temporal-agent-harness/internal/textenc"
 )
 
 // ApplyError is returned when a parsed patch cannot be applied to the filesystem.
@@ -24,6 +26,17 @@ type AffectedPaths struct {
 	Added    []string
 	Modified []string
 	Deleted  []string
+
+	// Notes records remarks about updated files, keyed by the path reported
+	// in Modified: a kept non-UTF-8 encoding, converted or mixed line endings.
+	Notes map[string][]string
+}
+
+// Options controls how a patch is written.
+type Options struct {
+	// LineEndings is "preserve" (or empty) to keep each updated file's
+	// dominant line ending, or "lf" / "crlf" to convert written files.
+	LineEndings string
 }
 
 // Apply parses a patch string and applies it to the filesystem under cwd.
@@ -31,28 +44,45 @@ type AffectedPaths struct {
 //
 // Maps to: codex-rs/apply-patch/src/lib.rs apply_patch + apply_hunks
 func Apply(patchText string, cwd string) (string, error) {
+	summary, _, err := ApplyWithOptions(patchText, cwd, Options{})
+	return summary, err
+}
+
+// ApplyWithOptions is Apply with explicit write options. It also returns
+// the affected paths, as written in the patch.
+func ApplyWithOptions(patchText string, cwd string, opts Options) (string, *AffectedPaths, error) {
 	p, err := Parse(patchText)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
 	if len(p.Hunks) == 0 {
-		return "", &ApplyError{Message: "empty patch"}
+		return "", nil, &ApplyError{Message: "empty patch"}
 	}
 
 	// Resolve relative paths against cwd and verify before applying.
 	resolved, err := resolveAndVerify(p, cwd)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
 	// Apply all hunks.
-	affected, err := applyHunks(resolved)
+	affected, err := applyHunks(resolved, opts)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	return formatSummary(affected), nil
+	return formatSummary(affected), affected, nil
+}
+
+// Written returns the absolute paths of the files the patch added or
+// modified, resolving relative paths against cwd.
+func (a *AffectedPaths) Written(cwd string) []string {
+	var paths []string
+	for _, p := range append(append([]string{}, a.Added...), a.Modified...) {
+		paths = append(paths, resolvePath(cwd, p))
+	}
+	return paths
 }
 
 // resolvedHunk is a hunk with absolute paths ready for application.
@@ -113,13 +143,17 @@ func resolvePath(cwd, path string) string {
 // applyHunks applies each hunk to the filesystem.
 //
 // Maps to: codex-rs/apply-patch/src/lib.rs apply_hunks_to_files
-func applyHunks(hunks []resolvedHunk) (*AffectedPaths, error) {
+func applyHunks(hunks []resolvedHunk, opts Options) (*AffectedPaths, error) {
 	affected := &AffectedPaths{}
 
 	for _, rh := range hunks {
 		switch rh.Type {
 		case HunkAdd:
-			if err := applyAddFile(rh.absPath, rh.Contents); err != nil {
+			contents := rh.Contents
+			if forcedLineEnding(opts.LineEndings) {
+				contents = textenc.ConvertLineEndings(contents, opts.LineEndings)
+			}
+			if err := applyAddFile(rh.absPath, contents); err != nil {
 				return nil, err
 			}
 			affected.Added = append(affected.Added, rh.Path)
@@ -133,10 +167,16 @@ func applyHunks(hunks []resolvedHunk) (*AffectedPaths, error) {
 			affected.Deleted = append(affected.Deleted, rh.Path)
 
 		case HunkUpdate:
-			newContents, err := deriveNewContents(rh.absPath, rh.Chunks)
+			newContents, format, err := deriveNewContents(rh.absPath, rh.Chunks, opts.LineEndings)
 			if err != nil {
 				return nil, err
 			}
+			data, err := textenc.Encode(newContents, format.encoding, format.bom)
+			if err != nil {
+				return nil, &ApplyError{
+					Message: fmt.Sprintf("Failed to write file %s: %v", rh.Path, err),
+				}
+			}
 
 			dest := rh.absPath
 			if rh.absMovePath != "" {
@@ -152,7 +192,7 @@ func applyHunks(hunks []resolvedHunk) (*AffectedPaths, error) {
 				}
 			}
 
-			if err := os.WriteFile(dest, []byte(newContents), 0o644); err != nil {
+			if err := os.WriteFile(dest, data, 0o644); err != nil {
 				return nil, &ApplyError{
 					Message: fmt.Sprintf("Failed to write file %s: %v", dest, err),
 				}
@@ -167,10 +207,16 @@ func applyHunks(hunks []resolvedHunk) (*AffectedPaths, error) {
 				}
 			}
 
+			reported := rh.Path
 			if rh.absMovePath != "" {
-				affected.Modified = append(affected.Modified, rh.MovePath)
-			} else {
-				affected.Modified = append(affected.Modified, rh.Path)
+				reported = rh.MovePath
+			}
+			affected.Modified = append(affected.Modified, reported)
+			if notes := format.notes(opts.LineEndings); len(notes) > 0 {
+				if affected.Notes == nil {
+					affected.Notes = make(map[string][]string)
+				}
+				affected.Notes[reported] = notes
 			}
 		}
 	}
@@ -195,19 +241,83 @@ func applyAddFile(absPath, contents string) error {
 	return nil
 }
 
+// fileFormat is the original encoding and line endings of an updated file,
+// and the line ending its new lines were written with.
+type fileFormat struct {
+	encoding textenc.Encoding
+	bom      bool
+	eols     textenc.LineEndings
+	eol      string
+}
+
+// notes describes what was kept or changed about the file's format.
+func (f fileFormat) notes(policy string) []string {
+	var notes []string
+	if !f.encoding.IsUTF8() {
+		notes = append(notes, textenc.KeptNotice(f.encoding))
+	}
+	switch {
+	case forcedLineEnding(policy):
+		if (policy == textenc.LF && f.eols.CRLF > 0) || (policy == textenc.CRLF && f.eols.LF > 0) {
+			notes = append(notes, "line endings converted to "+textenc.DisplayEOL(policy))
+		}
+	case f.eols.Mixed():
+		notes = append(notes, fmt.Sprintf("mixed line endings (%s); new lines use %s", f.eols, textenc.DisplayEOL(f.eol)))
+	}
+	return notes
+}
+
+// forcedLineEnding reports whether policy converts every written file
+// rather than preserving each file's line endings.
+func forcedLineEnding(policy string) bool {
+	return policy == textenc.LF || policy == textenc.CRLF
+}
+
 // deriveNewContents reads the file at path, computes replacements from chunks,
-// and returns the new file contents.
+// and returns the new file contents as UTF-8 along with the file's original
+// format. New lines get the file's dominant line ending unless the
+// lineEndings policy forces one for the whole file.
 //
 // Maps to: codex-rs/apply-patch/src/lib.rs derive_new_contents_from_chunks
-func deriveNewContents(path string, chunks []UpdateChunk) (string, error) {
+func deriveNewContents(path string, chunks []UpdateChunk, lineEndings string) (string, fileFormat, error) {
+	plan, err := planUpdate(path, chunks, lineEndings)
+	if err != nil {
+		return "", fileFormat{}, err
+	}
+	return plan.contents, plan.format, nil
+}
+
+// updatePlan is the update of one file, computed without writing it.
+type updatePlan struct {
+	format        fileFormat
+	originalLines []string
+	replacements  []replacement
+	contents      string
+}
+
+// planUpdate reads the file at path and computes its update from chunks.
+// See deriveNewContents.
+func planUpdate(path string, chunks []UpdateChunk, lineEndings string) (*updatePlan, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return "", &ApplyError{
+		return nil, &ApplyError{
 			Message: fmt.Sprintf("Failed to read file to update %s: %v", path, err),
 		}
 	}
 
-	originalContents := string(data)
+	format := fileFormat{encoding: textenc.Detect(data)}
+	format.bom = textenc.HasBOM(data, format.encoding)
+	originalContents, err := textenc.Decode(data, format.encoding)
+	if err != nil {
+		return nil, &ApplyError{
+			Message: fmt.Sprintf("Failed to read file to update %s: %v", path, err),
+		}
+	}
+	format.eols = textenc.CountLineEndings(originalContents)
+	format.eol = format.eols.Dominant()
+	if forcedLineEnding(lineEndings) {
+		format.eol = lineEndings
+	}
 	originalLines := strings.Split(originalContents, "\n")
 
 	// Drop the trailing empty element that results from the final newline so
@@ -218,7 +328,10 @@ func deriveNewContents(path string, chunks []UpdateChunk) (string, error) {
 
 	replacements, err := computeReplacements(originalLines, path, chunks)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	for i := range replacements {
+		replacements[i].newLines = withLineEnding(replacements[i].newLines, format.eol)
 	}
 
 	newLines := applyReplacements(originalLines, replacements)
@@ -228,7 +341,30 @@ func deriveNewContents(path string, chunks []UpdateChunk) (string, error) {
 		newLines = append(newLines, "")
 	}
 
-	return strings.Join(newLines, "\n"), nil
+	contents := strings.Join(newLines, "\n")
+	if forcedLineEnding(lineEndings) {
+		contents = textenc.ConvertLineEndings(contents, lineEndings)
+	}
+	return &updatePlan{
+		format:        format,
+		originalLines: originalLines,
+		replacements:  replacements,
+		contents:      contents,
+	}, nil
+}
+
+// withLineEnding returns lines (split on "\n") terminated by eol: with a
+// trailing "\r" for CRLF, without one for LF.
+func withLineEnding(lines []string, eol string) []string {
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		l = strings.TrimSuffix(l, "\r")
+		if eol == textenc.CRLF {
+			l += "\r"
+		}
+		out[i] = l
+	}
+	return out
 }
 
 // replacement describes a single region to replace in the file.
@@ -349,6 +485,10 @@ func formatSummary(affected *AffectedPaths) string {
 		fmt.Fprintf(&b, "A %s\n", p)
 	}
 	for _, p := range affected.Modified {
+		if notes, ok := affected.Notes[p]; ok {
+			fmt.Fprintf(&b, "M %s (%s)\n", p, strings.Join(notes, "; "))
+			continue
+		}
 		fmt.Fprintf(&b, "M %s\n", p)
 	}
 	for _, p := range affected.Deleted {
@@ -532,3 +532,105 @@ func TestApply_InsertAtEOF(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "foo\nbar\nbaz\nquux\n", string(contents))
 }
+
+func TestApply_UpdateKeepsLatin1Encoding(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "menu.txt")
+	require.NoError(t, os.WriteFile(path, []byte("caf\xe9\nth\xe9\n"), 0o644))
+
+	patch := wrapPatchBody("*** Update File: " + path + "\n@@\n-thé\n+crème")
+
+	result, err := Apply(patch, dir)
+	require.NoError(t, err)
+	assert.Contains(t, result, "M "+path+" (kept Latin-1 encoding)")
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("caf\xe9\ncr\xe8me\n"), contents)
+}
+
+func TestApply_UpdateKeepsUTF16Encoding(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	require.NoError(t, os.WriteFile(path, []byte("\xff\xfea\x00\n\x00b\x00\n\x00"), 0o644))
+
+	patch := wrapPatchBody("*** Update File: " + path + "\n@@\n-b\n+c")
+
+	_, err := Apply(patch, dir)
+	require.NoError(t, err)
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("\xff\xfea\x00\n\x00c\x00\n\x00"), contents)
+}
+
+func TestApply_UpdateRejectsCharactersOutsideEncoding(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "menu.txt")
+	require.NoError(t, os.WriteFile(path, []byte("caf\xe9\n"), 0o644))
+
+	patch := wrapPatchBody("*** Update File: " + path + "\n@@\n-café\n+café →")
+
+	_, err := Apply(patch, dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot be written as Latin-1")
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("caf\xe9\n"), contents)
+}
+
+func TestApply_UpdatePreservesCRLF(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "win.txt")
+	require.NoError(t, os.WriteFile(path, []byte("one\r\ntwo\r\nthree\r\n"), 0o644))
+
+	patch := wrapPatchBody("*** Update File: " + path + "\n@@\n one\n-two\n+2\n+2.5")
+
+	result, err := Apply(patch, dir)
+	require.NoError(t, err)
+	assert.Contains(t, result, "M "+path+"\n", "consistent line endings need no note")
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "one\r\n2\r\n2.5\r\nthree\r\n", string(contents))
+}
+
+func TestApply_UpdateFlagsMixedLineEndings(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mixed.txt")
+	require.NoError(t, os.WriteFile(path, []byte("a\r\nb\r\nc\n"), 0o644))
+
+	patch := wrapPatchBody("*** Update File: " + path + "\n@@\n-c\n+d")
+
+	result, err := Apply(patch, dir)
+	require.NoError(t, err)
+	assert.Contains(t, result, "M "+path+" (mixed line endings (2 CRLF, 1 LF); new lines use CRLF)")
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "a\r\nb\r\nd\r\n", string(contents), "untouched lines keep their endings")
+}
+
+func TestApply_LineEndingPolicyConvertsFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "win.txt")
+	require.NoError(t, os.WriteFile(path, []byte("one\r\ntwo\r\n"), 0o644))
+	added := filepath.Join(dir, "new.txt")
+
+	patch := wrapPatchBody("*** Update File: " + path + "\n@@\n-two\n+2\n*** Add File: " + added + "\n+x\n+y")
+
+	result, _, err := ApplyWithOptions(patch, dir, Options{LineEndings: "lf"})
+	require.NoError(t, err)
+	assert.Contains(t, result, "M "+path+" (line endings converted to LF)")
+
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "one\n2\n", string(contents))
+
+	_, _, err = ApplyWithOptions(wrapPatchBody("*** Update File: "+path+"\n@@\n-2\n+3"), dir, Options{LineEndings: "crlf"})
+	require.NoError(t, err)
+	contents, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "one\r\n3\r\n", string(contents))
+}
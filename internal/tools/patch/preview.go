@@ -0,0 +1,279 @@
+package patch
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mfateev/temporal-agent-harness/internal/textenc"
+)
+
+// previewContext is the number of unchanged lines around each change in a
+// preview diff.
+const previewContext = 3
+
+// maxDiffCells bounds the line-diff table of one changed region; larger
+// regions are shown as a plain removal followed by an addition.
+const maxDiffCells = 1 << 20
+
+// PreviewResult is what applying a patch would do, computed against the
+// current file contents without writing anything.
+type PreviewResult struct {
+	// Diff is a unified diff of the file operations that apply, located
+	// where Apply would put them (which may differ from the patch's own
+	// context when it matched fuzzily).
+	Diff string
+	// Conflicts describes each file operation that does not apply.
+	Conflicts []string
+	// LinesChanged counts the lines the applicable operations add or
+	// remove; a modified line counts as one removed and one added.
+	LinesChanged int
+}
+
+// Preview parses patchText and computes the change Apply would make under
+// cwd, without writing. A parse error or an empty patch is returned as an
+// error; hunks that do not apply are reported in Conflicts.
+func Preview(patchText, cwd string, opts Options) (*PreviewResult, error) {
+	p, err := Parse(patchText)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.Hunks) == 0 {
+		return nil, &ApplyError{Message: "empty patch"}
+	}
+
+	result := &PreviewResult{}
+	var b strings.Builder
+	for _, h := range p.Hunks {
+		from, to, ops, err := previewHunk(h, cwd, opts)
+		if err != nil {
+			result.Conflicts = append(result.Conflicts, err.Error())
+			continue
+		}
+		b.WriteString(fileDiff(from, to, ops))
+		for _, op := range ops {
+			if op.kind != ' ' {
+				result.LinesChanged++
+			}
+		}
+	}
+	result.Diff = b.String()
+	return result, nil
+}
+
+// previewHunk returns the diff header paths and line operations of one
+// file operation.
+func previewHunk(h Hunk, cwd string, opts Options) (from, to string, ops []diffOp, err error) {
+	absPath := resolvePath(cwd, h.Path)
+	switch h.Type {
+	case HunkAdd:
+		// Adding a file that exists overwrites it.
+		from = "/dev/null"
+		var oldLines []string
+		if data, err := os.ReadFile(absPath); err == nil {
+			from = "a/" + h.Path
+			oldLines = splitLines(decodeForPreview(data))
+		}
+		return from, "b/" + h.Path, lineOps(oldLines, splitLines(h.Contents)), nil
+
+	case HunkDelete:
+		data, err := os.ReadFile(absPath)
+		if err != nil {
+			return "", "", nil, &ApplyError{Message: fmt.Sprintf("Failed to read file to delete %s: %v", h.Path, err)}
+		}
+		return "a/" + h.Path, "/dev/null", lineOps(splitLines(decodeForPreview(data)), nil), nil
+
+	case HunkUpdate:
+		plan, err := planUpdate(absPath, h.Chunks, opts.LineEndings)
+		if err != nil {
+			return "", "", nil, err
+		}
+		to = "b/" + h.Path
+		if h.MovePath != "" {
+			to = "b/" + h.MovePath
+		}
+		return "a/" + h.Path, to, plan.ops(), nil
+	}
+	return "", "", nil, nil
+}
+
+// ops returns the line operations turning the original file into the
+// updated one: unchanged lines between replacements, and a line diff of
+// each replaced region.
+func (u *updatePlan) ops() []diffOp {
+	var ops []diffOp
+	pos := 0
+	for _, r := range u.replacements {
+		for ; pos < r.index && pos < len(u.originalLines); pos++ {
+			ops = append(ops, diffOp{kind: ' ', line: u.originalLines[pos]})
+		}
+		end := r.index + r.count
+		if end > len(u.originalLines) {
+			end = len(u.originalLines)
+		}
+		ops = append(ops, lineOps(u.originalLines[r.index:end], r.newLines)...)
+		pos = end
+	}
+	for ; pos < len(u.originalLines); pos++ {
+		ops = append(ops, diffOp{kind: ' ', line: u.originalLines[pos]})
+	}
+	return ops
+}
+
+// decodeForPreview returns file data as UTF-8 text for display.
+func decodeForPreview(data []byte) string {
+	text, err := textenc.Decode(data, textenc.Detect(data))
+	if err != nil {
+		return string(data)
+	}
+	return text
+}
+
+// splitLines splits text into lines without the final newline.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffOp is one line of a diff: ' ' unchanged, '-' removed or '+' added.
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// lineOps returns a minimal line diff of a and b. Lines are compared
+// ignoring a trailing carriage return.
+func lineOps(a, b []string) []diffOp {
+	eq := func(x, y string) bool { return strings.TrimSuffix(x, "\r") == strings.TrimSuffix(y, "\r") }
+
+	pre := 0
+	for pre < len(a) && pre < len(b) && eq(a[pre], b[pre]) {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && eq(a[len(a)-1-suf], b[len(b)-1-suf]) {
+		suf++
+	}
+
+	var ops []diffOp
+	for _, l := range a[:pre] {
+		ops = append(ops, diffOp{kind: ' ', line: l})
+	}
+	midA, midB := a[pre:len(a)-suf], b[pre:len(b)-suf]
+	if len(midA)*len(midB) > maxDiffCells {
+		for _, l := range midA {
+			ops = append(ops, diffOp{kind: '-', line: l})
+		}
+		for _, l := range midB {
+			ops = append(ops, diffOp{kind: '+', line: l})
+		}
+	} else {
+		ops = append(ops, lcsOps(midA, midB, eq)...)
+	}
+	for _, l := range a[len(a)-suf:] {
+		ops = append(ops, diffOp{kind: ' ', line: l})
+	}
+	return ops
+}
+
+// lcsOps diffs a and b through their longest common subsequence.
+func lcsOps(a, b []string, eq func(x, y string) bool) []diffOp {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case eq(a[i], b[j]):
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case eq(a[i], b[j]):
+			ops = append(ops, diffOp{kind: ' ', line: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{kind: '-', line: a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: '+', line: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{kind: '-', line: a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{kind: '+', line: b[j]})
+	}
+	return ops
+}
+
+// fileDiff renders ops as a unified diff between from and to, with
+// previewContext lines of context. Returns "" when nothing changes.
+func fileDiff(from, to string, ops []diffOp) string {
+	var changes []int
+	for i, op := range ops {
+		if op.kind != ' ' {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	// oldNo[i] and newNo[i] are the 1-based line numbers at ops[i].
+	oldNo := make([]int, len(ops)+1)
+	newNo := make([]int, len(ops)+1)
+	oldNo[0], newNo[0] = 1, 1
+	for i, op := range ops {
+		oldNo[i+1], newNo[i+1] = oldNo[i], newNo[i]
+		if op.kind != '+' {
+			oldNo[i+1]++
+		}
+		if op.kind != '-' {
+			newNo[i+1]++
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", from, to)
+	for k := 0; k < len(changes); {
+		first, last := changes[k], changes[k]
+		for k++; k < len(changes) && changes[k]-last <= 2*previewContext; k++ {
+			last = changes[k]
+		}
+		start := max(0, first-previewContext)
+		end := min(len(ops), last+previewContext+1)
+
+		oldStart, oldLen := oldNo[start], oldNo[end]-oldNo[start]
+		newStart, newLen := newNo[start], newNo[end]-newNo[start]
+		if oldLen == 0 {
+			oldStart--
+		}
+		if newLen == 0 {
+			newStart--
+		}
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", oldStart, oldLen, newStart, newLen)
+		for _, op := range ops[start:end] {
+			b.WriteByte(op.kind)
+			b.WriteString(strings.TrimSuffix(op.line, "\r"))
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
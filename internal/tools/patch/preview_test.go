@@ -0,0 +1,73 @@
+package patch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreview_UpdateShowsWhereThePatchLands(t *testing.T) {
+	dir := t.TempDir()
+	var lines []string
+	for i := 1; i <= 20; i++ {
+		lines = append(lines, "line "+strings.Repeat("x", i%3))
+	}
+	lines[9] = "target"
+	original := strings.Join(lines, "\n") + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "f.txt"), []byte(original), 0o644))
+
+	// The context line with extra whitespace still matches (fuzzily), and
+	// Apply rewrites it with the patch's version: the preview shows that,
+	// which the raw patch text does not.
+	p := wrapPatchBody("*** Update File: f.txt\n@@\n " + lines[8] + "  \n-target\n+changed\n " + lines[10])
+	res, err := Preview(p, dir, Options{})
+	require.NoError(t, err)
+	assert.Empty(t, res.Conflicts)
+	assert.Equal(t, "--- a/f.txt\n+++ b/f.txt\n@@ -6,8 +6,8 @@\n"+
+		" "+lines[5]+"\n "+lines[6]+"\n "+lines[7]+"\n-"+lines[8]+"\n-target\n+"+lines[8]+"  \n+changed\n "+
+		lines[10]+"\n "+lines[11]+"\n "+lines[12]+"\n",
+		res.Diff)
+	assert.Equal(t, 4, res.LinesChanged)
+
+	data, err := os.ReadFile(filepath.Join(dir, "f.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, original, string(data), "preview does not write")
+}
+
+func TestPreview_AddDeleteAndConflicts(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "old.txt"), []byte("a\nb\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "keep.txt"), []byte("one\ntwo\n"), 0o644))
+
+	p := wrapPatchBody("*** Add File: new.txt\n+hello\n" +
+		"*** Delete File: old.txt\n" +
+		"*** Update File: keep.txt\n@@\n-three\n+four\n" +
+		"*** Update File: missing.txt\n@@\n-x\n+y")
+	res, err := Preview(p, dir, Options{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1,1 @@\n+hello\n"+
+		"--- a/old.txt\n+++ /dev/null\n@@ -1,2 +0,0 @@\n-a\n-b\n", res.Diff)
+	assert.Equal(t, 3, res.LinesChanged, "conflicting operations are not counted")
+	require.Len(t, res.Conflicts, 2)
+	assert.Contains(t, res.Conflicts[0], "Failed to find expected lines")
+	assert.Contains(t, res.Conflicts[1], "missing.txt")
+}
+
+func TestPreview_ParseError(t *testing.T) {
+	_, err := Preview("not a patch", t.TempDir(), Options{})
+	assert.Error(t, err)
+}
+
+func TestLineOps_SeparatesChangesInsideARegion(t *testing.T) {
+	ops := lineOps([]string{"a", "b", "c", "d"}, []string{"a", "B", "c", "d", "e"})
+	var got []string
+	for _, op := range ops {
+		got = append(got, string(op.kind)+op.line)
+	}
+	assert.Equal(t, []string{" a", "-b", "+B", " c", " d", "+e"}, got)
+}
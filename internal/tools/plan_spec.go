@@ -40,6 +40,10 @@ func NewUpdatePlanToolSpec() ToolSpec {
 							"description": "Status of this step.",
 							"enum":        []string{"pending", "in_progress", "completed"},
 						},
+						"verify": map[string]interface{}{
+							"type":        "string",
+							"description": "Optional shell command that checks this step is done (e.g. \"go test ./pkg/...\"). Used when the plan is executed step by step.",
+						},
 					},
 					"required": []string{"step", "status"},
 				},
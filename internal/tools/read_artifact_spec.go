@@ -0,0 +1,25 @@
+// Read-artifact tool specification for the read_artifact intercepted tool.
+package tools
+
+func init() {
+	RegisterSpec(SpecEntry{Name: "read_artifact", Constructor: NewReadArtifactToolSpec})
+}
+
+// NewReadArtifactToolSpec creates the specification for the read_artifact tool.
+// This tool is intercepted by the workflow (not dispatched as an activity).
+// It is enabled automatically when the tool output window is on, and
+// returns an older tool output that was replaced by a stub in the prompt.
+func NewReadArtifactToolSpec() ToolSpec {
+	return ToolSpec{
+		Name:        "read_artifact",
+		Description: "Re-read the full output of an earlier tool call. Older tool outputs are shown as one-line stubs to save context; call this with the id from a stub only when you need the details again.",
+		Parameters: []ToolParameter{
+			{
+				Name:        "id",
+				Type:        "string",
+				Description: "The artifact id from the stub, e.g. \"a1b2c3d4\".",
+				Required:    true,
+			},
+		},
+	}
+}
@@ -258,6 +258,12 @@ func NewReadFileToolSpec() ToolSpec {
 					},
 				},
 			},
+			{
+				Name:        "hexdump",
+				Type:        "boolean",
+				Description: "Binary files are described instead of read. Set to true to also get a hexdump of the first and last bytes of a small binary file.",
+				Required:    false,
+			},
 		},
 		DefaultTimeoutMs: DefaultReadFileTimeoutMs,
 		RetryPolicy:      RetryDefault, // read-only — safe to retry
@@ -96,6 +96,8 @@ func DefaultEnabledTools() []string {
 		"grep_files",
 		"apply_patch",
 		"request_user_input",
+		"ask_user",
 		"update_plan",
+		"todo",
 	}
 }
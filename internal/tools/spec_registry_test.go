@@ -66,7 +66,9 @@ func TestDefaultEnabledTools(t *testing.T) {
 	assert.Contains(t, defaults, "write_file")
 	assert.Contains(t, defaults, "apply_patch")
 	assert.Contains(t, defaults, "request_user_input")
+	assert.Contains(t, defaults, "ask_user")
 	assert.Contains(t, defaults, "update_plan")
+	assert.Contains(t, defaults, "todo")
 
 	// Every default should produce a valid spec
 	specs := BuildSpecs(defaults)
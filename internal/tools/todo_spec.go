@@ -0,0 +1,37 @@
+// Todo tool specification for the todo intercepted tool.
+package tools
+
+func init() {
+	RegisterSpec(SpecEntry{Name: "todo", Constructor: NewTodoToolSpec})
+}
+
+// NewTodoToolSpec creates the specification for the todo tool.
+// This tool is intercepted by the workflow (not dispatched as an activity).
+// It lets the LLM record follow-up items that persist for the session and
+// are reminded at the start of every turn until marked done.
+func NewTodoToolSpec() ToolSpec {
+	return ToolSpec{
+		Name:        "todo",
+		Description: `Record and manage follow-up items for this session. Use "add" to note work you intend to come back to, "done" once an item is complete, "remove" to drop an item that no longer applies, and "list" to see open items. Open items are reminded to you at the start of each turn.`,
+		Parameters: []ToolParameter{
+			{
+				Name:        "action",
+				Type:        "string",
+				Description: `One of "add", "done", "remove", or "list".`,
+				Required:    true,
+			},
+			{
+				Name:        "text",
+				Type:        "string",
+				Description: `Description of the item. Required for "add".`,
+				Required:    false,
+			},
+			{
+				Name:        "id",
+				Type:        "integer",
+				Description: `ID of the item. Required for "done" and "remove".`,
+				Required:    false,
+			},
+		},
+	}
+}
@@ -32,6 +32,13 @@ const maxRepeatToolCalls = 3
 //
 // Maps to: codex-rs/core/src/codex.rs run_turn
 func AgenticWorkflow(ctx workflow.Context, input WorkflowInput) (WorkflowResult, error) {
+	if err := checkWorkflowInputVersion(input); err != nil {
+		return WorkflowResult{}, err
+	}
+	if err := checkRemoteConfig(input.Config.Remote); err != nil {
+		return WorkflowResult{}, err
+	}
+	requireRemoteApproval(&input.Config)
 	state := SessionState{
 		ConversationID: input.ConversationID,
 		History:        history.NewInMemoryHistory(),
@@ -53,6 +60,8 @@ func AgenticWorkflow(ctx workflow.Context, input WorkflowInput) (WorkflowResult,
 	state.CrewName = input.CrewName
 	state.CrewAgent = input.CrewAgent
 	state.CrewInputs = input.CrewInputs
+	state.HandoffParentID = input.HandoffParentID
+	state.Caller = input.Caller
 
 	if input.ResolvedProfile != nil {
 		// Pre-resolved by SessionWorkflow — skip init.
@@ -91,6 +100,10 @@ func AgenticWorkflow(ctx workflow.Context, input WorkflowInput) (WorkflowResult,
 		}
 	}
 
+	// Drop tools the worker cannot run (e.g. grep_files without rg).
+	state.gateToolsOnCapabilities(ctx)
+	state.loadGlossary(ctx)
+
 	// Resolve crew agent config via activity (main and children).
 	if input.CrewName != "" && input.CrewAgent != "" {
 		actCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
@@ -136,6 +149,11 @@ func AgenticWorkflow(ctx workflow.Context, input WorkflowInput) (WorkflowResult,
 	// Apply crew-aware tool spec scoping.
 	state.applyCrewToolSpecs()
 
+	// Link an executor session to the planner it was handed off from.
+	if state.HandoffParentID != "" {
+		state.announceHandoff(ctx)
+	}
+
 	// Warn if using deprecated on-failure mode (Codex PR #11631)
 	if state.Config.Permissions.ApprovalMode == models.ApprovalOnFailure {
 		workflow.GetLogger(ctx).Warn("`on-failure` approval policy is deprecated and will be removed in a future release. Use `unless-trusted` for interactive approvals or `never` for non-interactive runs.")
@@ -173,6 +191,10 @@ func AgenticWorkflow(ctx workflow.Context, input WorkflowInput) (WorkflowResult,
 		return WorkflowResult{}, fmt.Errorf("failed to add user message: %w", err)
 	}
 
+	// Attach file contents for any @path mentions
+	state.injectFileMentions(ctx, input.UserMessage, turnID)
+	logExternalRequest(ctrl, turnID, state.Caller, "Session started")
+
 	// Mark first turn as pending and run multi-turn loop.
 	ctrl.SetPendingUserInput(turnID)
 	return state.runMultiTurnLoop(ctx, ctrl)
@@ -203,6 +225,7 @@ func (s *SessionState) runMultiTurnLoop(ctx workflow.Context, ctrl *LoopControl)
 			logger.Info("Waiting for user input or shutdown")
 			timedOut, err := ctrl.WaitForInput(ctx)
 			if err != nil {
+				s.runCleanupOnCancel(ctx, ctrl)
 				return WorkflowResult{}, fmt.Errorf("await failed: %w", err)
 			}
 			if timedOut {
@@ -238,6 +261,7 @@ func (s *SessionState) runMultiTurnLoop(ctx workflow.Context, ctrl *LoopControl)
 				s.extractMemoryOnShutdown(ctx)
 			}
 
+			s.runCleanupPhase(ctx, ctrl, "shutdown")
 			items, _ := s.History.GetRawItems()
 			return WorkflowResult{
 				ConversationID:    s.ConversationID,
@@ -247,16 +271,21 @@ func (s *SessionState) runMultiTurnLoop(ctx workflow.Context, ctrl *LoopControl)
 				ToolCallsExecuted: s.ToolCallsExecuted,
 				EndReason:         "shutdown",
 				FinalMessage:      extractFinalMessage(items),
+				PostMortem:        s.LastPostMortem,
+				Latency:           s.latencyResult(),
+				Fingerprint:       s.Fingerprint,
 			}, nil
 		}
 
 		// Reset for new turn
 		ctrl.StartTurn()
 		s.IterationCount = 0
+		s.LastActivityAt = workflow.Now(ctx)
 
 		// Run the agentic turn
 		done, err := s.runAgenticTurn(ctx, ctrl)
 		if err != nil {
+			s.runCleanupOnCancel(ctx, ctrl)
 			return WorkflowResult{}, err
 		}
 
@@ -265,6 +294,14 @@ func (s *SessionState) runMultiTurnLoop(ctx workflow.Context, ctrl *LoopControl)
 			return s.continueAsNew(ctx, ctrl)
 		}
 
+		// Start the prompt suggestion now so it runs concurrently with the
+		// end-of-turn bookkeeping below. Only sessions that stay alive for
+		// more input can show it.
+		var suggestion workflow.Future
+		if !ctrl.IsInterrupted() && s.Config.SuggestionsEnabled() && s.staysAliveAfterTurn() {
+			suggestion = s.startSuggestion(ctx)
+		}
+
 		// Accumulate iterations for CAN threshold across turns.
 		s.TotalIterationsForCAN += s.IterationCount
 		if s.TotalIterationsForCAN >= maxIterationsBeforeCAN {
@@ -286,20 +323,32 @@ func (s *SessionState) runMultiTurnLoop(ctx workflow.Context, ctrl *LoopControl)
 			_ = s.History.AddItem(models.ConversationItem{
 				Type:   models.ItemTypeTurnComplete,
 				TurnID: ctrl.CurrentTurnID(),
+				Model:  s.turnModel,
 			})
 			ctrl.NotifyItemAdded()
 		}
+		s.LastActivityAt = workflow.Now(ctx)
+		s.upsertMetricsSearchAttributes(ctx)
+
+		// Plan execution: verify the step and queue the next one, if any.
+		s.advancePlanExecution(ctx, ctrl)
 
 		// Workflows without request_user_input auto-complete after a turn.
 		// This is the one-shot pattern: the caller sends a task, the workflow
 		// does it and returns. Roles that have request_user_input enabled
-		// stay alive for more input instead.
-		if !s.Config.Tools.HasTool("request_user_input") {
+		// stay alive for more input instead, as do ask sessions (no tools).
+		// A session paused by the cost cap waits for raise_cost_cap.
+		if !s.staysAliveAfterTurn() && !s.CostCapPaused {
 			logger.Info("Auto-completing workflow (request_user_input disabled)")
 			// Extract memory before auto-complete (root workflows only)
 			if s.Config.MemoryEnabled && s.AgentCtl != nil && s.AgentCtl.ParentDepth == 0 {
 				s.extractMemoryOnShutdown(ctx)
 			}
+			endReason := "completed"
+			if s.LastPostMortem != nil {
+				endReason = "error"
+			}
+			s.runCleanupPhase(ctx, ctrl, endReason)
 			items, _ := s.History.GetRawItems()
 			return WorkflowResult{
 				ConversationID:    s.ConversationID,
@@ -307,20 +356,25 @@ func (s *SessionState) runMultiTurnLoop(ctx workflow.Context, ctrl *LoopControl)
 				TotalTokens:       s.TotalTokens,
 				TotalCachedTokens: s.TotalCachedTokens,
 				ToolCallsExecuted: s.ToolCallsExecuted,
-				EndReason:         "completed",
+				EndReason:         endReason,
 				FinalMessage:      extractFinalMessage(items),
+				PostMortem:        s.LastPostMortem,
+				Latency:           s.latencyResult(),
+				Fingerprint:       s.Fingerprint,
 			}, nil
 		}
 
+		// Move the oldest turns out of workflow state if history has
+		// outgrown the retention window.
+		s.applyHistoryWindow(ctx, ctrl)
+
 		ctrl.SetPhase(PhaseWaitingForInput)
 		ctrl.ClearToolsInFlight()
 
-		// Generate prompt suggestion asynchronously (best-effort).
+		// Collect the prompt suggestion (best-effort).
 		// The CLI has already detected TurnComplete via polling and can show
 		// the input prompt immediately; the suggestion arrives ~300-500ms later.
-		if !ctrl.IsInterrupted() && !s.Config.DisableSuggestions {
-			s.generateSuggestion(ctx, ctrl)
-		}
+		s.awaitSuggestion(ctx, ctrl, suggestion)
 
 		logger.Info("Turn complete, waiting for next input", "turn_id", ctrl.CurrentTurnID())
 	}
@@ -347,6 +401,7 @@ func (s *SessionState) continueAsNew(ctx workflow.Context, ctrl *LoopControl) (W
 		return workflow.AllHandlersFinished(ctx)
 	})
 
+	s.applyHistoryWindow(ctx, ctrl)
 	s.syncHistoryItems()
 	return WorkflowResult{}, workflow.NewContinueAsNewError(ctx, "AgenticWorkflowContinued", *s)
 }
@@ -9,6 +9,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/mfateev/temporal-agent-harness/internal/command_safety"
 	"github.com/mfateev/temporal-agent-harness/internal/execpolicy"
 	"github.com/mfateev/temporal-agent-harness/internal/models"
 	"github.com/mfateev/temporal-agent-harness/internal/shell"
@@ -19,17 +20,19 @@ import (
 type ApprovalGate struct {
 	mode        models.ApprovalMode
 	policyRules string
+	safety      *command_safety.Classifier
 }
 
-// NewApprovalGate creates an ApprovalGate with the given approval mode and policy rules.
-func NewApprovalGate(mode models.ApprovalMode, policyRules string) *ApprovalGate {
-	return &ApprovalGate{mode: mode, policyRules: policyRules}
+// NewApprovalGate creates an ApprovalGate with the given approval mode, policy
+// rules and user safe/unsafe command overrides (nil = built-in allow-list only).
+func NewApprovalGate(mode models.ApprovalMode, policyRules string, safety *command_safety.Classifier) *ApprovalGate {
+	return &ApprovalGate{mode: mode, policyRules: policyRules, safety: safety}
 }
 
 // Classify determines which tools need approval vs are forbidden.
 // Delegates to classifyToolsForApproval.
 func (g *ApprovalGate) Classify(calls []models.ConversationItem) ([]PendingApproval, []models.ConversationItem) {
-	return classifyToolsForApproval(calls, g.mode, g.policyRules)
+	return classifyToolsForApproval(calls, g.mode, g.policyRules, g.safety)
 }
 
 // ApplyDecision filters calls based on user's approval response.
@@ -50,6 +53,7 @@ func classifyToolsForApproval(
 	functionCalls []models.ConversationItem,
 	mode models.ApprovalMode,
 	policyRules string,
+	safety *command_safety.Classifier,
 ) (pending []PendingApproval, forbidden []models.ConversationItem) {
 	// Empty/unset mode or "never" -> auto-approve all (backward compat)
 	if mode == "" || mode == models.ApprovalNever {
@@ -64,6 +68,14 @@ func classifyToolsForApproval(
 			policyMgr = mgr
 		}
 	}
+	// User safe/unsafe command overrides are evaluated by the policy manager's
+	// heuristic fallback, so build an empty-policy manager to carry them.
+	if safety.HasOverrides() {
+		if policyMgr == nil {
+			policyMgr = execpolicy.NewExecPolicyManager(execpolicy.NewPolicy())
+		}
+		policyMgr.SetSafetyClassifier(safety)
+	}
 
 	for _, fc := range functionCalls {
 		req, reason := evaluateToolApproval(fc.Name, fc.Arguments, policyMgr, mode)
@@ -93,6 +105,7 @@ func classifyToolsForApproval(
 			})
 		}
 	}
+	assignApprovalGroups(pending)
 	return pending, forbidden
 }
 
@@ -109,7 +122,7 @@ func evaluateToolApproval(
 	}
 
 	switch toolName {
-	case "read_file", "list_dir", "grep_files", "request_user_input", "update_plan":
+	case "read_file", "list_dir", "grep_files", "request_user_input", "ask_user", "update_plan", "todo", "read_artifact":
 		return tools.ApprovalSkip, "" // Read-only / workflow-intercepted tools always safe
 
 	case "shell":
@@ -199,6 +212,12 @@ func evaluateCommandVecApproval(
 	if policyMgr != nil {
 		eval := policyMgr.GetEvaluation(cmdVec, string(mode))
 		req := decisionToApprovalReq(eval.Decision)
+		// User unsafe overrides always prompt, even over allow rules.
+		if req == tools.ApprovalSkip && mode != models.ApprovalNever && mode != "" {
+			if c := policyMgr.SafetyClassifier(); c.RequiresApproval(cmdVec) {
+				return tools.ApprovalNeeded, c.Classify(cmdVec).Reason
+			}
+		}
 		return req, eval.Justification
 	}
 
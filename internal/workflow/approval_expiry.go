@@ -0,0 +1,65 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// approval_expiry.go denies pending approvals whose context is gone. An
+// approval is requested in a specific turn under specific instructions; if
+// the turn is interrupted, a new user message arrives, or the model or
+// instructions change before the user decides, running the command later
+// may no longer make sense. Such approvals expire: every call of the batch
+// gets an output explaining why it was not executed.
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// approvalExpiryReason explains why an approval wait ended without a
+// decision.
+func approvalExpiryReason(ctrl *LoopControl) string {
+	switch {
+	case ctrl.IsShutdown():
+		return "the session is shutting down"
+	case ctrl.IsInterrupted():
+		return "the turn was interrupted before a decision was made"
+	case ctrl.contextChange != "":
+		return ctrl.contextChange
+	}
+	return "the context it was requested in changed"
+}
+
+// expireApprovals records a denial with the reason for every call of a batch
+// whose approval expired, so none of them runs and the model learns why.
+func (s *SessionState) expireApprovals(ctrl *LoopControl, calls []models.ConversationItem, reason string) {
+	for _, fc := range calls {
+		failed := false
+		_ = s.History.AddItem(models.ConversationItem{
+			Type:   models.ItemTypeFunctionCallOutput,
+			CallID: fc.CallID,
+			Output: &models.FunctionCallOutputPayload{
+				Content: fmt.Sprintf("Approval expired: %s. The call was not executed; request it again if it is still needed.", reason),
+				Success: &failed,
+			},
+		})
+		ctrl.NotifyItemAdded()
+	}
+	ctrl.Emit(SessionEvent{Type: EventApprovalResolved,
+		Message: fmt.Sprintf("expired %d: %s", len(calls), reason)})
+}
+
+// checkApprovalResponseCurrent rejects a response naming calls that are not
+// pending, e.g. a decision on approvals that have since expired.
+func checkApprovalResponseCurrent(ctrl *LoopControl, resp ApprovalResponse) error {
+	pending := make(map[string]bool, len(ctrl.PendingApprovals()))
+	for _, ap := range ctrl.PendingApprovals() {
+		pending[ap.CallID] = true
+	}
+	for _, ids := range [][]string{resp.Approved, resp.Denied} {
+		for _, id := range ids {
+			if !pending[id] {
+				return fmt.Errorf("approval for %s is no longer pending (it may have expired)", id)
+			}
+		}
+	}
+	return nil
+}
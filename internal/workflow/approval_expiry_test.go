@@ -0,0 +1,130 @@
+package workflow
+
+import (
+	"strings"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// mockLLMRmCall makes the next LLM call request a command needing approval.
+func (s *AgenticWorkflowTestSuite) mockLLMRmCall() {
+	s.env.OnActivity("ExecuteLLMCall", mock.Anything, mock.Anything).
+		Return(activities.LLMActivityOutput{
+			Items: []models.ConversationItem{{
+				Type:      models.ItemTypeFunctionCall,
+				CallID:    "call-rm",
+				Name:      "shell_command",
+				Arguments: `{"command": "rm -rf /tmp/test"}`,
+			}},
+			FinishReason: models.FinishReasonToolCalls,
+			TokenUsage:   models.TokenUsage{TotalTokens: 30},
+		}, nil).Once()
+}
+
+// expiredOutput returns a matcher for an LLM call whose history answers
+// call-rm with an expiry notice containing reason.
+func expiredOutput(reason string) func(activities.LLMActivityInput) bool {
+	return func(in activities.LLMActivityInput) bool {
+		for _, item := range in.History {
+			if item.Type == models.ItemTypeFunctionCallOutput && item.CallID == "call-rm" && item.Output != nil {
+				return strings.HasPrefix(item.Output.Content, "Approval expired: "+reason)
+			}
+		}
+		return false
+	}
+}
+
+// TestApprovalExpiry_NewUserMessage: a user message arriving while an
+// approval is pending expires it; a late decision on it is rejected and the
+// command never runs.
+func (s *AgenticWorkflowTestSuite) TestApprovalExpiry_NewUserMessage() {
+	s.mockLLMRmCall()
+	s.env.OnActivity("ExecuteLLMCall", mock.Anything, mock.MatchedBy(expiredOutput("a new user message arrived"))).
+		Return(mockLLMStopResponse("OK, listing instead.", 25), nil).Once()
+
+	var pending []PendingApproval
+	s.env.RegisterDelayedCallback(func() {
+		res, err := s.env.QueryWorkflow(QueryGetTurnStatus)
+		require.NoError(s.T(), err)
+		var status TurnStatus
+		require.NoError(s.T(), res.Get(&status))
+		pending = status.PendingApprovals
+		s.env.UpdateWorkflow(UpdateUserInput, "input-2", noopCallback(),
+			UserInput{Content: "Actually, just list the files"})
+	}, time.Second*2)
+	var lateErr error
+	s.env.RegisterDelayedCallback(func() {
+		s.env.UpdateWorkflow(UpdateApprovalResponse, "approval-late", &testsuite.TestUpdateCallback{
+			OnAccept:   func() { s.Fail("stale approval accepted") },
+			OnReject:   func(err error) { lateErr = err },
+			OnComplete: func(interface{}, error) {},
+		}, ApprovalResponse{Approved: []string{"call-rm"}})
+	}, time.Second*3)
+	s.sendShutdown(time.Second * 5)
+
+	s.env.ExecuteWorkflow(AgenticWorkflow, testInputWithApproval("Delete /tmp/test", models.ApprovalUnlessTrusted))
+
+	require.True(s.T(), s.env.IsWorkflowCompleted())
+	var result WorkflowResult
+	require.NoError(s.T(), s.env.GetWorkflowResult(&result))
+	assert.Equal(s.T(), 55, result.TotalTokens)
+	assert.NotContains(s.T(), result.ToolCallsExecuted, "shell_command")
+	require.Len(s.T(), pending, 1)
+	assert.NotEmpty(s.T(), pending[0].TurnID)
+	assert.Error(s.T(), lateErr)
+}
+
+// TestApprovalExpiry_ModelSwitch: switching models while an approval is
+// pending expires it and ends the turn.
+func (s *AgenticWorkflowTestSuite) TestApprovalExpiry_ModelSwitch() {
+	s.mockLLMRmCall()
+	s.env.OnActivity("ExecuteLLMCall", mock.Anything, mock.MatchedBy(expiredOutput("the model was switched to gpt-4o"))).
+		Return(mockLLMStopResponse("Done.", 25), nil).Once()
+
+	s.env.RegisterDelayedCallback(func() {
+		s.env.UpdateWorkflow(UpdateModel, "model-1", noopCallback(),
+			UpdateModelRequest{Provider: "openai", Model: "gpt-4o"})
+	}, time.Second*2)
+	s.env.RegisterDelayedCallback(func() {
+		s.env.UpdateWorkflow(UpdateUserInput, "input-2", noopCallback(), UserInput{Content: "Continue"})
+	}, time.Second*3)
+	s.sendShutdown(time.Second * 5)
+
+	s.env.ExecuteWorkflow(AgenticWorkflow, testInputWithApproval("Delete /tmp/test", models.ApprovalUnlessTrusted))
+
+	require.True(s.T(), s.env.IsWorkflowCompleted())
+	var result WorkflowResult
+	require.NoError(s.T(), s.env.GetWorkflowResult(&result))
+	assert.Equal(s.T(), 55, result.TotalTokens)
+	assert.NotContains(s.T(), result.ToolCallsExecuted, "shell_command")
+}
+
+// TestApprovalExpiry_Interrupt: an interrupted approval is answered with an
+// expiry notice instead of being left without an output.
+func (s *AgenticWorkflowTestSuite) TestApprovalExpiry_Interrupt() {
+	s.mockLLMRmCall()
+	s.env.OnActivity("ExecuteLLMCall", mock.Anything, mock.MatchedBy(expiredOutput("the turn was interrupted"))).
+		Return(mockLLMStopResponse("OK.", 25), nil).Once()
+
+	s.env.RegisterDelayedCallback(func() {
+		s.env.UpdateWorkflow(UpdateInterrupt, "interrupt-1", noopCallback(), InterruptRequest{})
+	}, time.Second*2)
+	s.env.RegisterDelayedCallback(func() {
+		s.env.UpdateWorkflow(UpdateUserInput, "input-2", noopCallback(), UserInput{Content: "Try something else"})
+	}, time.Second*3)
+	s.sendShutdown(time.Second * 5)
+
+	s.env.ExecuteWorkflow(AgenticWorkflow, testInputWithApproval("Delete /tmp/test", models.ApprovalUnlessTrusted))
+
+	require.True(s.T(), s.env.IsWorkflowCompleted())
+	var result WorkflowResult
+	require.NoError(s.T(), s.env.GetWorkflowResult(&result))
+	assert.Equal(s.T(), 55, result.TotalTokens)
+}
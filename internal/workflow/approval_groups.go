@@ -0,0 +1,63 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// approval_groups.go groups pending shell approvals that differ only in path
+// arguments ("go test ./a", "go test ./b") so clients can present them as one
+// decision. The ApprovalResponse still lists individual call IDs.
+package workflow
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// approvalPathPlaceholder replaces path arguments in a group template.
+const approvalPathPlaceholder = "<path>"
+
+// shellMetaChars mark commands too complex to template safely: a pipeline
+// or substitution can change meaning with any argument.
+const shellMetaChars = "|;&<>`$()"
+
+// assignApprovalGroups sets Group on pending approvals whose tool and
+// command template are shared with at least one other pending approval.
+func assignApprovalGroups(pending []PendingApproval) {
+	templates := make([]string, len(pending))
+	counts := make(map[string]int)
+	for i, ap := range pending {
+		templates[i] = approvalGroupTemplate(ap.ToolName, ap.Arguments)
+		if templates[i] != "" {
+			counts[ap.ToolName+"\x00"+templates[i]]++
+		}
+	}
+	for i := range pending {
+		if templates[i] != "" && counts[pending[i].ToolName+"\x00"+templates[i]] > 1 {
+			pending[i].Group = templates[i]
+		}
+	}
+}
+
+// approvalGroupTemplate returns the normalized command template for a shell
+// tool call ("go test <path>"), or "" if the call cannot be grouped.
+func approvalGroupTemplate(toolName, arguments string) string {
+	fields := shellCommandFields(toolName, arguments)
+	if len(fields) == 0 {
+		return ""
+	}
+	for i := 1; i < len(fields); i++ {
+		if isPathArg(fields[i]) {
+			fields[i] = approvalPathPlaceholder
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// isPathArg reports whether a command argument looks like a file path: it
+// is not a flag and contains a separator, a file extension, or is "." / "..".
+func isPathArg(arg string) bool {
+	if strings.HasPrefix(arg, "-") {
+		return false
+	}
+	if arg == "." || arg == ".." || strings.Contains(arg, "/") {
+		return true
+	}
+	return filepath.Ext(arg) != ""
+}
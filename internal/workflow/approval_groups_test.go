@@ -0,0 +1,45 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+func TestApprovalGroupTemplate(t *testing.T) {
+	tests := []struct {
+		tool, args, want string
+	}{
+		{"shell_command", `{"command": "go test ./internal/cli"}`, "go test <path>"},
+		{"shell_command", `{"command": "go test -run TestFoo ./pkg/..."}`, "go test -run TestFoo <path>"},
+		{"shell_command", `{"command": "rm build.log"}`, "rm <path>"},
+		{"shell", `{"command": ["chmod", "+x", "bin/run"]}`, "chmod +x <path>"},
+		{"shell_command", `{"command": "make"}`, "make"},
+		{"shell_command", `{"command": "cat a.txt | wc -l"}`, ""},
+		{"shell_command", `{"command": "echo $(pwd)"}`, ""},
+		{"shell_command", `{"command": ""}`, ""},
+		{"write_file", `{"path": "a.go"}`, ""},
+		{"shell_command", `not json`, ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, approvalGroupTemplate(tt.tool, tt.args), tt.args)
+	}
+}
+
+func TestClassifyToolsForApproval_GroupsSameTemplate(t *testing.T) {
+	calls := []models.ConversationItem{
+		{Type: models.ItemTypeFunctionCall, CallID: "1", Name: "shell_command", Arguments: `{"command": "rm -rf ./a/build"}`},
+		{Type: models.ItemTypeFunctionCall, CallID: "2", Name: "shell_command", Arguments: `{"command": "rm -rf ./b/build"}`},
+		{Type: models.ItemTypeFunctionCall, CallID: "3", Name: "shell_command", Arguments: `{"command": "rm -f ./c/out"}`},
+		{Type: models.ItemTypeFunctionCall, CallID: "4", Name: "write_file", Arguments: `{"path": "x.go", "content": ""}`},
+	}
+	pending, _ := classifyToolsForApproval(calls, models.ApprovalUnlessTrusted, "", nil)
+	require.Len(t, pending, 4)
+	assert.Equal(t, "rm -rf <path>", pending[0].Group)
+	assert.Equal(t, "rm -rf <path>", pending[1].Group)
+	assert.Empty(t, pending[2].Group, "template not shared")
+	assert.Empty(t, pending[3].Group, "not a shell tool")
+}
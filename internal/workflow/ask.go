@@ -0,0 +1,41 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// ask.go configures ask sessions: a Q&A-only session type with no tools,
+// no approval prompts, and a lighter system prompt.
+package workflow
+
+import (
+	"github.com/mfateev/temporal-agent-harness/internal/instructions"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// applyAskMode strips a resolved session configuration down to an ask
+// session. Tools (including MCP servers and web search) are removed, so
+// the approval machinery never engages, and the agent prompt is replaced
+// with the shorter ask prompt. Project docs and personal instructions (user
+// instructions) are kept so answers stay grounded in the repository.
+func applyAskMode(cfg *models.SessionConfiguration) {
+	cfg.SessionType = models.SessionTypeAsk
+	cfg.BaseInstructions = instructions.AskBaseInstructions
+	cfg.Tools.EnabledTools = nil
+	cfg.McpServers = nil
+	cfg.WebSearchMode = models.WebSearchDisabled
+	cfg.Permissions.ApprovalMode = models.ApprovalNever
+	// The agent developer prompt describes tool approvals; keep only the cwd.
+	cfg.DeveloperInstructions = ""
+	if cfg.Cwd != "" {
+		cfg.DeveloperInstructions = "Working directory: " + cfg.Cwd
+	}
+}
+
+// isAskSession reports whether the session is an ask (Q&A) session.
+func (s *SessionState) isAskSession() bool {
+	return s.Config.SessionType == models.SessionTypeAsk
+}
+
+// staysAliveAfterTurn reports whether the workflow waits for more input after
+// a turn instead of auto-completing. Ask sessions have no request_user_input
+// tool but are interactive by nature.
+func (s *SessionState) staysAliveAfterTurn() bool {
+	return s.Config.Tools.HasTool("request_user_input") || s.isAskSession()
+}
@@ -0,0 +1,50 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mfateev/temporal-agent-harness/internal/instructions"
+	"github.com/mfateev/temporal-agent-harness/internal/mcp"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+func TestApplyAskMode(t *testing.T) {
+	cfg := models.DefaultSessionConfiguration()
+	cfg.Cwd = "/repo"
+	cfg.UserInstructions = "project docs"
+	cfg.DeveloperInstructions = "Approval mode: unless-trusted."
+	cfg.McpServers = map[string]mcp.McpServerConfig{"fs": {Required: true}}
+	cfg.Permissions.ApprovalMode = models.ApprovalUnlessTrusted
+
+	applyAskMode(&cfg)
+
+	assert.Equal(t, models.SessionTypeAsk, cfg.SessionType)
+	assert.Equal(t, instructions.AskBaseInstructions, cfg.BaseInstructions)
+	assert.Empty(t, cfg.Tools.EnabledTools)
+	assert.Empty(t, cfg.McpServers)
+	assert.Equal(t, models.WebSearchDisabled, cfg.WebSearchMode)
+	assert.Equal(t, models.ApprovalNever, cfg.Permissions.ApprovalMode)
+	assert.Equal(t, "Working directory: /repo", cfg.DeveloperInstructions)
+	assert.Equal(t, "project docs", cfg.UserInstructions)
+}
+
+func TestMergeCLIOverrides_SessionType(t *testing.T) {
+	base := CLIOverrides{Model: "gpt-4o"}
+	merged := mergeCLIOverrides(base, &CLIOverrides{SessionType: models.SessionTypeAsk})
+	assert.Equal(t, models.SessionTypeAsk, merged.SessionType)
+	assert.Equal(t, "gpt-4o", merged.Model)
+}
+
+func TestStaysAliveAfterTurn(t *testing.T) {
+	s := &SessionState{}
+	assert.False(t, s.staysAliveAfterTurn())
+
+	s.Config.SessionType = models.SessionTypeAsk
+	assert.True(t, s.staysAliveAfterTurn())
+
+	s.Config.SessionType = models.SessionTypeAgent
+	s.Config.Tools.EnabledTools = []string{"request_user_input"}
+	assert.True(t, s.staysAliveAfterTurn())
+}
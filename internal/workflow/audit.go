@@ -0,0 +1,164 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// audit.go keeps the session's audit log: one record per mutating tool call
+// (file writes, patches, commands and MCP tools) with when it ran, what it
+// touched, who approved it, under which sandbox mode and with what exit code.
+// Denied, forbidden and expired calls are recorded too. `tcx audit export`
+// reads it with the get_audit_log query for compliance reviews.
+package workflow
+
+import (
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// maxAuditRecords bounds the audit log, which is carried across
+// ContinueAsNew; older records are dropped first and counted.
+const maxAuditRecords = 1000
+
+// AuditDecision is how a tool call was decided.
+type AuditDecision string
+
+const (
+	AuditApproved  AuditDecision = "approved"
+	AuditDenied    AuditDecision = "denied"
+	AuditForbidden AuditDecision = "forbidden" // Rejected by the approval policy
+	AuditExpired   AuditDecision = "expired"   // Approval request expired unanswered
+)
+
+// Approvers recorded when no person decided.
+const (
+	// AuditApproverPolicy decided calls the approval mode and exec policy
+	// allow or forbid without asking.
+	AuditApproverPolicy = "policy"
+	// AuditApproverUnknown decided responses that did not name an approver.
+	AuditApproverUnknown = "user"
+)
+
+// AuditSandboxEscalated is the sandbox of a call the user allowed to re-run
+// outside the sandbox after it failed in it.
+const AuditSandboxEscalated = "escalated"
+
+// AuditRecord is one audited tool call.
+type AuditRecord struct {
+	Time   time.Time `json:"time"`
+	TurnID string    `json:"turn_id,omitempty"`
+	CallID string    `json:"call_id"`
+	Tool   string    `json:"tool"`
+	// Target is the command or file path of the call.
+	Target   string        `json:"target,omitempty"`
+	Approver string        `json:"approver,omitempty"`
+	Decision AuditDecision `json:"decision"`
+	// Sandbox is the session's sandbox mode when the call ran, or
+	// AuditSandboxEscalated; empty for calls that did not run.
+	Sandbox  string `json:"sandbox,omitempty"`
+	ExitCode *int   `json:"exit_code,omitempty"`
+	Success  *bool  `json:"success,omitempty"`
+}
+
+// AuditLog is the bounded audit log. It persists across ContinueAsNew.
+type AuditLog struct {
+	Records []AuditRecord `json:"records,omitempty"`
+	// Dropped counts records removed to keep the log bounded.
+	Dropped int `json:"dropped,omitempty"`
+}
+
+// append adds r, dropping the oldest records beyond maxAuditRecords.
+func (l *AuditLog) append(r AuditRecord) {
+	l.Records = append(l.Records, r)
+	if over := len(l.Records) - maxAuditRecords; over > 0 {
+		l.Records = append([]AuditRecord(nil), l.Records[over:]...)
+		l.Dropped += over
+	}
+}
+
+// isAuditedTool reports whether calls to name can change the workspace or
+// the outside world: file writes, patches, commands and MCP tools.
+func isAuditedTool(name string) bool {
+	switch focusActions[name] {
+	case FocusEdit, FocusRun:
+		return true
+	}
+	return strings.HasPrefix(name, "mcp__")
+}
+
+// auditSandbox returns the sandbox mode recorded for calls that run now.
+func (s *SessionState) auditSandbox() string {
+	if s.Config.Permissions.SandboxMode == "" {
+		return "full-access"
+	}
+	return s.Config.Permissions.SandboxMode
+}
+
+// auditCalls records calls that were decided without running.
+func (s *SessionState) auditCalls(ctx workflow.Context, ctrl *LoopControl, calls []models.ConversationItem, approver string, decision AuditDecision) {
+	for _, call := range calls {
+		if !isAuditedTool(call.Name) {
+			continue
+		}
+		s.AuditLog.append(AuditRecord{
+			Time:     workflow.Now(ctx),
+			TurnID:   ctrl.CurrentTurnID(),
+			CallID:   call.CallID,
+			Tool:     call.Name,
+			Target:   callTarget(call),
+			Approver: approver,
+			Decision: decision,
+		})
+	}
+}
+
+// auditResults records calls that ran, with their outcome. approverOf
+// returns who approved a call.
+func (s *SessionState) auditResults(ctx workflow.Context, ctrl *LoopControl, calls []models.ConversationItem, results []activities.ToolActivityOutput, sandbox string, approverOf func(callID string) string) {
+	byID := make(map[string]activities.ToolActivityOutput, len(results))
+	for _, r := range results {
+		byID[r.CallID] = r
+	}
+	for _, call := range calls {
+		if !isAuditedTool(call.Name) {
+			continue
+		}
+		result := byID[call.CallID]
+		s.AuditLog.append(AuditRecord{
+			Time:     workflow.Now(ctx),
+			TurnID:   ctrl.CurrentTurnID(),
+			CallID:   call.CallID,
+			Tool:     call.Name,
+			Target:   callTarget(call),
+			Approver: approverOf(call.CallID),
+			Decision: AuditApproved,
+			Sandbox:  sandbox,
+			ExitCode: result.ExitCode,
+			Success:  result.Success,
+		})
+	}
+}
+
+// approverName returns the approver named in a response.
+func approverName(name string) string {
+	if name == "" {
+		return AuditApproverUnknown
+	}
+	return name
+}
+
+// callsAnswered returns the calls answered by outputs, in call order.
+func callsAnswered(calls, outputs []models.ConversationItem) []models.ConversationItem {
+	answered := make(map[string]bool, len(outputs))
+	for _, o := range outputs {
+		answered[o.CallID] = true
+	}
+	var out []models.ConversationItem
+	for _, fc := range calls {
+		if answered[fc.CallID] {
+			out = append(out, fc)
+		}
+	}
+	return out
+}
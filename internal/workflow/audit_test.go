@@ -0,0 +1,95 @@
+package workflow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// TestAuditLog_RecordsApprovalsAndOutcomes: an approved command is recorded
+// with its approver, sandbox and exit code, a denied one with its decision,
+// and read-only tools are not recorded.
+func (s *AgenticWorkflowTestSuite) TestAuditLog_RecordsApprovalsAndOutcomes() {
+	s.env.OnActivity("ExecuteLLMCall", mock.Anything, mock.Anything).
+		Return(activities.LLMActivityOutput{
+			Items: []models.ConversationItem{
+				{Type: models.ItemTypeFunctionCall, CallID: "call-a", Name: "shell_command", Arguments: `{"command": "rm -rf /tmp/a"}`},
+				{Type: models.ItemTypeFunctionCall, CallID: "call-b", Name: "shell_command", Arguments: `{"command": "rm -rf /tmp/b"}`},
+				{Type: models.ItemTypeFunctionCall, CallID: "call-read", Name: "read_file", Arguments: `{"file_path": "go.mod"}`},
+			},
+			FinishReason: models.FinishReasonToolCalls,
+		}, nil).Once()
+	s.env.OnActivity("ExecuteLLMCall", mock.Anything, mock.Anything).
+		Return(mockLLMStopResponse("Removed /tmp/a.", 10), nil).Once()
+
+	trueVal, exitCode := true, 0
+	s.env.OnActivity("ExecuteTool", mock.Anything, mock.MatchedBy(func(in activities.ToolActivityInput) bool {
+		return in.CallID == "call-a"
+	})).Return(activities.ToolActivityOutput{CallID: "call-a", Success: &trueVal, ExitCode: &exitCode}, nil).Once()
+	s.env.OnActivity("ExecuteTool", mock.Anything, mock.MatchedBy(func(in activities.ToolActivityInput) bool {
+		return in.CallID == "call-read"
+	})).Return(activities.ToolActivityOutput{CallID: "call-read", Content: "module x", Success: &trueVal}, nil).Once()
+
+	s.env.RegisterDelayedCallback(func() {
+		s.env.UpdateWorkflow(UpdateApprovalResponse, "approval-1", noopCallback(),
+			ApprovalResponse{Approved: []string{"call-a"}, Denied: []string{"call-b"}, Approver: "alice"})
+	}, time.Second*2)
+	var log AuditLog
+	s.env.RegisterDelayedCallback(func() {
+		res, err := s.env.QueryWorkflow(QueryGetAuditLog)
+		require.NoError(s.T(), err)
+		require.NoError(s.T(), res.Get(&log))
+	}, time.Second*3)
+	s.sendShutdown(time.Second * 4)
+
+	input := testInputWithApproval("Clean up", models.ApprovalUnlessTrusted)
+	input.Config.Permissions.SandboxMode = "workspace-write"
+	s.env.ExecuteWorkflow(AgenticWorkflow, input)
+
+	require.True(s.T(), s.env.IsWorkflowCompleted())
+	require.Len(s.T(), log.Records, 2)
+	denied, approved := log.Records[0], log.Records[1]
+
+	assert.Equal(s.T(), "call-b", denied.CallID)
+	assert.Equal(s.T(), "rm -rf /tmp/b", denied.Target)
+	assert.Equal(s.T(), AuditDenied, denied.Decision)
+	assert.Equal(s.T(), "alice", denied.Approver)
+	assert.Empty(s.T(), denied.Sandbox)
+	assert.Nil(s.T(), denied.ExitCode)
+
+	assert.Equal(s.T(), "call-a", approved.CallID)
+	assert.Equal(s.T(), "shell_command", approved.Tool)
+	assert.Equal(s.T(), AuditApproved, approved.Decision)
+	assert.Equal(s.T(), "alice", approved.Approver)
+	assert.Equal(s.T(), "workspace-write", approved.Sandbox)
+	require.NotNil(s.T(), approved.ExitCode)
+	assert.Equal(s.T(), 0, *approved.ExitCode)
+	assert.NotEmpty(s.T(), approved.TurnID)
+	assert.False(s.T(), approved.Time.IsZero())
+}
+
+// TestAuditLog_Bounded: the oldest records are dropped and counted.
+func TestAuditLog_Bounded(t *testing.T) {
+	var log AuditLog
+	for i := 0; i < maxAuditRecords+3; i++ {
+		log.append(AuditRecord{CallID: "c"})
+	}
+	assert.Len(t, log.Records, maxAuditRecords)
+	assert.Equal(t, 3, log.Dropped)
+}
+
+// TestIsAuditedTool covers the tools recorded in the audit log.
+func TestIsAuditedTool(t *testing.T) {
+	for _, name := range []string{"shell", "shell_command", "exec_command", "write_file", "apply_patch", "mcp__db__query"} {
+		assert.True(t, isAuditedTool(name), name)
+	}
+	for _, name := range []string{"read_file", "list_dir", "grep_files", "update_plan", "request_user_input"} {
+		assert.False(t, isAuditedTool(name), name)
+	}
+}
@@ -0,0 +1,69 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// caller.go records who drives a session started through the Nexus service
+// (internal/agentservice) by a workflow in another namespace. The caller of
+// the run-session operation is kept as session metadata (Caller, shown in
+// get_turn_status), and every request made through the service — the start,
+// each message, the shutdown — is added to the event log as an
+// external_request event naming its caller.
+//
+// NOTE: Temporal-specific addition (not in Codex Rust).
+package workflow
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EventExternalRequest is logged for each request made through the Nexus
+// service.
+const EventExternalRequest SessionEventType = "external_request"
+
+// CallerContext identifies the caller of a Nexus operation. Namespace and
+// WorkflowID are taken from the links Temporal attaches to the request;
+// Principal and Attributes are declared by the caller (e.g. the user or
+// team it acts for) and are recorded, not verified.
+type CallerContext struct {
+	Namespace  string            `json:"namespace,omitempty"`
+	WorkflowID string            `json:"workflow_id,omitempty"`
+	Principal  string            `json:"principal,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// String describes the caller for notices and the event log, e.g.
+// "alice (workflow billing-42 in namespace billing)".
+func (c *CallerContext) String() string {
+	if c == nil {
+		return "unknown caller"
+	}
+	var origin []string
+	if c.WorkflowID != "" {
+		origin = append(origin, "workflow "+c.WorkflowID)
+	}
+	if c.Namespace != "" {
+		origin = append(origin, "in namespace "+c.Namespace)
+	}
+	switch {
+	case c.Principal != "" && len(origin) > 0:
+		return fmt.Sprintf("%s (%s)", c.Principal, strings.Join(origin, " "))
+	case c.Principal != "":
+		return c.Principal
+	case len(origin) > 0:
+		return strings.Join(origin, " ")
+	}
+	return "unknown caller"
+}
+
+// logExternalRequest adds an external_request event for a request made by
+// caller; requests without a caller (the CLI) are not logged.
+func logExternalRequest(ctrl *LoopControl, turnID string, caller *CallerContext, request string) {
+	if caller == nil {
+		return
+	}
+	ctrl.Emit(SessionEvent{
+		Type:    EventExternalRequest,
+		TurnID:  turnID,
+		Message: fmt.Sprintf("%s by %s", request, caller),
+		Caller:  caller,
+	})
+}
@@ -0,0 +1,15 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallerContext_String(t *testing.T) {
+	assert.Equal(t, "alice (workflow invoice-42 in namespace billing)",
+		(&CallerContext{Namespace: "billing", WorkflowID: "invoice-42", Principal: "alice"}).String())
+	assert.Equal(t, "in namespace billing", (&CallerContext{Namespace: "billing"}).String())
+	assert.Equal(t, "alice", (&CallerContext{Principal: "alice"}).String())
+	assert.Equal(t, "unknown caller", (*CallerContext)(nil).String())
+}
@@ -0,0 +1,97 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// capabilities.go gates advertised tools on what the worker can run.
+package workflow
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/mfateev/temporal-agent-harness/internal/capabilities"
+	"github.com/mfateev/temporal-agent-harness/internal/tools"
+)
+
+// gateToolsOnCapabilities fetches the worker's capabilities, drops tool
+// specs the worker cannot run, and tells the model which tools are missing
+// and why. Skipped when no advertised tool has a requirement. Non-fatal: if
+// the activity fails, all tools stay advertised.
+func (s *SessionState) gateToolsOnCapabilities(ctx workflow.Context) {
+	names := make([]string, 0, len(s.ToolSpecs))
+	for _, spec := range s.ToolSpecs {
+		names = append(names, spec.Name)
+	}
+	if !capabilities.HasRequirements(names) {
+		return
+	}
+
+	logger := workflow.GetLogger(ctx)
+	actOpts := workflow.ActivityOptions{
+		StartToCloseTimeout: 15 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 2,
+		},
+	}
+	if s.Config.SessionTaskQueue != "" {
+		actOpts.TaskQueue = s.Config.SessionTaskQueue
+	}
+	actCtx := workflow.WithActivityOptions(ctx, actOpts)
+
+	var caps capabilities.Capabilities
+	if err := workflow.ExecuteActivity(actCtx, "GetWorkerCapabilities").Get(ctx, &caps); err != nil {
+		logger.Warn("Failed to get worker capabilities, advertising all tools", "error", err)
+		return
+	}
+	if unavailable := s.dropUnavailableTools(caps); len(unavailable) > 0 {
+		logger.Info("Gated tools on worker capabilities", "unavailable", capabilities.SortedNames(unavailable))
+	}
+}
+
+// dropUnavailableTools records caps, removes the tool specs they cannot
+// run and adds the note about them to the developer instructions. Returns
+// the removed tools.
+func (s *SessionState) dropUnavailableTools(caps capabilities.Capabilities) map[string]string {
+	s.WorkerCapabilities = &caps
+
+	names := make([]string, 0, len(s.ToolSpecs))
+	for _, spec := range s.ToolSpecs {
+		names = append(names, spec.Name)
+	}
+	unavailable := caps.Unavailable(names)
+	if len(unavailable) == 0 {
+		return nil
+	}
+	s.UnavailableTools = unavailable
+
+	kept := make([]tools.ToolSpec, 0, len(s.ToolSpecs))
+	for _, spec := range s.ToolSpecs {
+		if _, gone := unavailable[spec.Name]; !gone {
+			kept = append(kept, spec)
+		}
+	}
+	s.ToolSpecs = kept
+
+	note := unavailableToolsNote(unavailable)
+	if s.Config.DeveloperInstructions != "" {
+		s.Config.DeveloperInstructions += "\n\n" + note
+	} else {
+		s.Config.DeveloperInstructions = note
+	}
+	return unavailable
+}
+
+// unavailableToolsNote renders the developer-instruction block listing
+// tools removed for this session.
+func unavailableToolsNote(unavailable map[string]string) string {
+	var b strings.Builder
+	b.WriteString("<unavailable_tools>\n")
+	b.WriteString("These tools are disabled because the worker cannot run them:\n")
+	for _, name := range capabilities.SortedNames(unavailable) {
+		fmt.Fprintf(&b, "- %s: %s\n", name, unavailable[name])
+	}
+	b.WriteString("</unavailable_tools>")
+	return b.String()
+}
@@ -0,0 +1,141 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// cleanup.go implements the cleanup phase a session runs before it
+// completes: child agents are shut down (then cancelled if they do not
+// stop), background exec sessions the session started are closed, queued
+// history archive writes are flushed, the end-of-session report is sent
+// when configured, and the scratch directory is removed.
+// Ending a session with /exit therefore never leaves orphan runs or
+// processes behind.
+package workflow
+
+import (
+	"sort"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+)
+
+// childShutdownGracePeriod is how long the cleanup phase waits for child
+// agents to finish their own cleanup before cancelling them.
+const childShutdownGracePeriod = 30 * time.Second
+
+// runCleanupPhase releases everything the session started and sends the
+// end-of-session report; endReason is how the session ended (see
+// WorkflowResult.EndReason). Best-effort: each step logs failures and
+// never fails the workflow.
+func (s *SessionState) runCleanupPhase(ctx workflow.Context, ctrl *LoopControl, endReason string) {
+	ctrl.SetPhase(PhaseCleaningUp)
+	s.shutdownChildren(ctx)
+	s.closeExecSessions(ctx)
+	s.flushArchiveWrites(ctx)
+	s.deliverSessionReport(ctx, endReason)
+	s.cleanupScratchDir(ctx)
+	s.upsertMetricsSearchAttributes(ctx)
+}
+
+// runCleanupOnCancel runs the cleanup phase when the workflow itself was
+// cancelled, in a disconnected context so its activities can still run.
+func (s *SessionState) runCleanupOnCancel(ctx workflow.Context, ctrl *LoopControl) {
+	if ctx.Err() == nil {
+		return
+	}
+	workflow.GetLogger(ctx).Info("Workflow cancelled, cleaning up")
+	disconnected, _ := workflow.NewDisconnectedContext(ctx)
+	s.runCleanupPhase(disconnected, ctrl, "cancelled")
+}
+
+// shutdownChildren asks every running child agent to shut down, which runs
+// its own cleanup phase, and cancels those still running after
+// childShutdownGracePeriod.
+func (s *SessionState) shutdownChildren(ctx workflow.Context) {
+	if s.AgentCtl == nil || !s.AgentCtl.HasActiveChildren() {
+		return
+	}
+	logger := workflow.GetLogger(ctx)
+
+	// Sorted so the signals are issued in the same order on replay.
+	var active []*AgentInfo
+	for _, info := range s.AgentCtl.Agents {
+		if !info.Status.isTerminal() && info.WorkflowID != "" {
+			active = append(active, info)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].AgentID < active[j].AgentID })
+
+	signals := make([]workflow.Future, len(active))
+	for i, info := range active {
+		signals[i] = workflow.SignalExternalWorkflow(ctx, info.WorkflowID, info.RunID, SignalAgentShutdown, nil)
+	}
+	for i, f := range signals {
+		if err := f.Get(ctx, nil); err != nil {
+			logger.Warn("Failed to signal shutdown to child agent", "agent_id", active[i].AgentID, "error", err)
+		}
+	}
+
+	_, _ = workflow.AwaitWithTimeout(ctx, childShutdownGracePeriod, func() bool {
+		return !s.AgentCtl.HasActiveChildren()
+	})
+
+	for _, info := range active {
+		if info.Status.isTerminal() {
+			continue
+		}
+		logger.Warn("Child agent did not shut down in time, cancelling", "agent_id", info.AgentID)
+		if err := workflow.RequestCancelExternalWorkflow(ctx, info.WorkflowID, info.RunID).Get(ctx, nil); err != nil {
+			logger.Warn("Failed to cancel child agent", "agent_id", info.AgentID, "error", err)
+		}
+		info.Status = AgentStatusShutdown
+	}
+}
+
+// closeExecSessions closes the background exec sessions this session
+// started on the worker.
+func (s *SessionState) closeExecSessions(ctx workflow.Context) {
+	if !s.ExecSessionsUsed {
+		return
+	}
+	var out activities.CleanExecSessionsResponse
+	req := activities.CleanExecSessionsRequest{SessionID: s.ConversationID}
+	if err := workflow.ExecuteActivity(s.cleanupActivityCtx(ctx), "CleanExecSessions", req).Get(ctx, &out); err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to close exec sessions", "error", err)
+		return
+	}
+	if out.Closed > 0 {
+		workflow.GetLogger(ctx).Info("Closed background exec sessions", "count", out.Closed)
+	}
+}
+
+// flushArchiveWrites retries history archive batches queued on the worker
+// (see internal/spool) so the archived transcript is complete.
+func (s *SessionState) flushArchiveWrites(ctx workflow.Context) {
+	if s.Archive == nil {
+		return
+	}
+	var out activities.FlushArchiveWritesOutput
+	if err := workflow.ExecuteActivity(s.cleanupActivityCtx(ctx), "FlushArchiveWrites").Get(ctx, &out); err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to flush archive writes", "error", err)
+		return
+	}
+	if out.Pending > 0 {
+		workflow.GetLogger(ctx).Warn("Archive writes still queued on the worker", "pending", out.Pending)
+	}
+}
+
+// cleanupActivityCtx returns the activity options of cleanup activities,
+// which run where the session's tools ran.
+func (s *SessionState) cleanupActivityCtx(ctx workflow.Context) workflow.Context {
+	actOpts := workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 2,
+		},
+	}
+	if s.Config.SessionTaskQueue != "" {
+		actOpts.TaskQueue = s.Config.SessionTaskQueue
+	}
+	return workflow.WithActivityOptions(ctx, actOpts)
+}
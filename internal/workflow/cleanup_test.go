@@ -0,0 +1,135 @@
+package workflow
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// TestCleanup_ClosesOwnedExecSessions: exec sessions are tagged with the
+// conversation ID and closed by owner when the session shuts down.
+func (s *AgenticWorkflowTestSuite) TestCleanup_ClosesOwnedExecSessions() {
+	s.env.OnActivity("ExecuteLLMCall", mock.Anything, mock.Anything).
+		Return(activities.LLMActivityOutput{
+			Items: []models.ConversationItem{{
+				Type:      models.ItemTypeFunctionCall,
+				CallID:    "call-1",
+				Name:      "exec_command",
+				Arguments: `{"cmd": "npm run dev"}`,
+			}},
+			FinishReason: models.FinishReasonToolCalls,
+			TokenUsage:   models.TokenUsage{TotalTokens: 20},
+		}, nil).Once()
+	s.env.OnActivity("ExecuteLLMCall", mock.Anything, mock.Anything).
+		Return(mockLLMStopResponse("Dev server started.", 10), nil).Once()
+
+	var toolSessionID string
+	s.env.OnActivity("ExecuteTool", mock.Anything, mock.Anything).
+		Return(func(_ context.Context, in activities.ToolActivityInput) (activities.ToolActivityOutput, error) {
+			toolSessionID = in.SessionID
+			success := true
+			return activities.ToolActivityOutput{CallID: in.CallID, Content: "Process running with session ID 1000", Success: &success}, nil
+		}).Once()
+
+	var closed []string
+	s.env.OnActivity("CleanExecSessions", mock.Anything, mock.Anything).
+		Return(func(_ context.Context, req activities.CleanExecSessionsRequest) (activities.CleanExecSessionsResponse, error) {
+			closed = append(closed, req.SessionID)
+			return activities.CleanExecSessionsResponse{Closed: 1}, nil
+		})
+	s.sendShutdown(time.Second * 2)
+
+	input := testInput("Start the dev server")
+	input.Config.Tools.EnabledTools = append(input.Config.Tools.EnabledTools, "exec_command")
+	s.env.ExecuteWorkflow(AgenticWorkflow, input)
+
+	require.True(s.T(), s.env.IsWorkflowCompleted())
+	require.NoError(s.T(), s.env.GetWorkflowError())
+	assert.Equal(s.T(), "test-conv-1", toolSessionID)
+	assert.Equal(s.T(), []string{"test-conv-1"}, closed)
+}
+
+// TestCleanup_SkipsExecSessionsWhenUnused: sessions that never started an
+// exec session do not run the activity.
+func (s *AgenticWorkflowTestSuite) TestCleanup_SkipsExecSessionsWhenUnused() {
+	s.env.OnActivity("ExecuteLLMCall", mock.Anything, mock.Anything).
+		Return(mockLLMStopResponse("Hello!", 10), nil).Once()
+	s.sendShutdown(time.Second * 2)
+
+	s.env.ExecuteWorkflow(AgenticWorkflow, testInput("Hi"))
+
+	require.True(s.T(), s.env.IsWorkflowCompleted())
+	require.NoError(s.T(), s.env.GetWorkflowError())
+	s.env.AssertNotCalled(s.T(), "CleanExecSessions", mock.Anything, mock.Anything)
+}
+
+// TestCleanup_DeliversSessionReport: a session with [report] configured
+// hands its turns and usage to DeliverSessionReport when it ends.
+func (s *AgenticWorkflowTestSuite) TestCleanup_DeliversSessionReport() {
+	s.env.OnActivity("ExecuteLLMCall", mock.Anything, mock.Anything).
+		Return(mockLLMStopResponse("All tests pass.", 10), nil).Once()
+	var delivered *activities.DeliverSessionReportInput
+	s.env.OnActivity("DeliverSessionReport", mock.Anything, mock.Anything).
+		Return(func(_ context.Context, in activities.DeliverSessionReportInput) (activities.DeliverSessionReportOutput, error) {
+			delivered = &in
+			return activities.DeliverSessionReportOutput{Delivered: []string{"webhook"}}, nil
+		}).Once()
+	s.sendShutdown(time.Second * 2)
+
+	input := testInput("Run the tests")
+	input.Config.Report = &models.SessionReportConfig{WebhookURL: "https://hooks.example.com/x"}
+	s.env.ExecuteWorkflow(AgenticWorkflow, input)
+
+	require.True(s.T(), s.env.IsWorkflowCompleted())
+	require.NoError(s.T(), s.env.GetWorkflowError())
+	require.NotNil(s.T(), delivered)
+	r := delivered.Report
+	assert.Equal(s.T(), "test-conv-1", r.SessionID)
+	assert.Equal(s.T(), "shutdown", r.EndReason)
+	assert.Equal(s.T(), 1, r.TotalTurns)
+	require.Len(s.T(), r.Turns, 1)
+	assert.Equal(s.T(), "Run the tests", r.Turns[0].Request)
+	assert.Equal(s.T(), "All tests pass.", r.Turns[0].Response)
+	assert.Equal(s.T(), "completed", r.Turns[0].Status)
+}
+
+// TestCleanup_CancelsChildrenThatIgnoreShutdown: running children are
+// signalled to shut down and cancelled after the grace period.
+func (s *AgenticWorkflowTestSuite) TestCleanup_CancelsChildrenThatIgnoreShutdown() {
+	agentCtl := NewAgentControl(0)
+	agentCtl.Agents["agent-1"] = &AgentInfo{AgentID: "agent-1", WorkflowID: "child-wf-1", Status: AgentStatusRunning}
+	agentCtl.Agents["agent-2"] = &AgentInfo{AgentID: "agent-2", WorkflowID: "child-wf-2", Status: AgentStatusCompleted}
+	state := SessionState{
+		ConversationID: "test-conv-children",
+		Config:         testInput("").Config,
+		AgentCtl:       agentCtl,
+		MaxIterations:  20,
+	}
+	s.env.RegisterWorkflow(AgenticWorkflowContinued)
+
+	var signalled, cancelled []string
+	s.env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, SignalAgentShutdown, mock.Anything).
+		Return(func(_, workflowID, _, _ string, _ interface{}) error {
+			signalled = append(signalled, workflowID)
+			return nil
+		})
+	s.env.OnRequestCancelExternalWorkflow(mock.Anything, mock.Anything, mock.Anything).
+		Return(func(_, workflowID, _ string) error {
+			cancelled = append(cancelled, workflowID)
+			return nil
+		})
+	s.sendShutdown(time.Second)
+
+	s.env.ExecuteWorkflow(AgenticWorkflowContinued, state)
+
+	require.True(s.T(), s.env.IsWorkflowCompleted())
+	require.NoError(s.T(), s.env.GetWorkflowError())
+	assert.Equal(s.T(), []string{"child-wf-1"}, signalled)
+	assert.Equal(s.T(), []string{"child-wf-1"}, cancelled)
+}
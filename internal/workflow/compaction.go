@@ -7,6 +7,7 @@
 package workflow
 
 import (
+	"fmt"
 	"time"
 
 	"go.temporal.io/sdk/temporal"
@@ -74,10 +75,11 @@ func (s *SessionState) performCompaction(ctx workflow.Context, ctrl *LoopControl
 	}
 
 	// Replace history with compacted items
-	if err := s.History.ReplaceAll(compactResult.Items); err != nil {
+	if err := s.History.ReplaceAll(s.retainCompacted(compactResult.Items)); err != nil {
 		logger.Error("Failed to replace history after compaction", "error", err)
 		return err
 	}
+	s.logCompaction(ctx, ctrl, fmt.Sprintf("history compacted to %d items", len(compactResult.Items)))
 	ctrl.NotifyItemAdded()
 
 	// Re-add the last model-switch message so the new model retains context
@@ -96,6 +98,7 @@ func (s *SessionState) performCompaction(ctx workflow.Context, ctrl *LoopControl
 	// Track token usage from compaction
 	s.TotalTokens += compactResult.TokenUsage.TotalTokens
 	s.TotalCachedTokens += compactResult.TokenUsage.CachedTokens
+	s.addLLMCost(ctx, s.Config.Model, compactResult.TokenUsage)
 
 	logger.Info("Context compaction completed",
 		"compaction_count", s.CompactionCount,
@@ -104,3 +107,26 @@ func (s *SessionState) performCompaction(ctx workflow.Context, ctrl *LoopControl
 
 	return nil
 }
+
+// retainCompacted applies the session's retention policy to the history that
+// survives compaction, so content the policy does not store is kept only as
+// hashes alongside the summary. The latest user message stays intact because
+// the current turn may still be working on it.
+func (s *SessionState) retainCompacted(items []models.ConversationItem) []models.ConversationItem {
+	latestUser := -1
+	for i := len(items) - 1; i >= 0; i-- {
+		if items[i].Type == models.ItemTypeUserMessage {
+			latestUser = i
+			break
+		}
+	}
+	out := make([]models.ConversationItem, len(items))
+	for i, item := range items {
+		if i == latestUser {
+			out[i] = item
+			continue
+		}
+		out[i] = s.Config.Retention.RedactItem(item)
+	}
+	return out
+}
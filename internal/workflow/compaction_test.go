@@ -200,5 +200,28 @@ func (s *AgenticWorkflowTestSuite) TestCompaction_NoModelSwitch_WorkflowLevel()
 	assert.Equal(s.T(), "shutdown", result.EndReason)
 }
 
+// TestRetainCompacted_RedactsAllButLatestUserMessage verifies the retention
+// policy is applied to the post-compaction history.
+func TestRetainCompacted_RedactsAllButLatestUserMessage(t *testing.T) {
+	off := false
+	s := &SessionState{}
+	s.Config.Retention = models.RetentionConfig{StoreUserMessages: &off, StoreToolOutputs: &off}
+
+	items := []models.ConversationItem{
+		{Type: models.ItemTypeCompaction, Content: "context_compacted"},
+		{Type: models.ItemTypeAssistantMessage, Content: "summary"},
+		{Type: models.ItemTypeUserMessage, Content: "earlier request"},
+		{Type: models.ItemTypeFunctionCallOutput, Output: &models.FunctionCallOutputPayload{Content: "secret output"}},
+		{Type: models.ItemTypeUserMessage, Content: "current request"},
+	}
+	got := s.retainCompacted(items)
+
+	assert.Equal(t, "summary", got[1].Content)
+	assert.Contains(t, got[2].Content, "[user message withheld:")
+	assert.Contains(t, got[3].Output.Content, "[tool output withheld:")
+	assert.Equal(t, "current request", got[4].Content)
+	assert.Equal(t, "secret output", items[3].Output.Content, "input is not modified")
+}
+
 // Ensure we reference testsuite (suppress unused import warning)
 var _ testsuite.TestUpdateCallback
@@ -0,0 +1,92 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// continuation.go implements automatic continuation of assistant messages
+// truncated by the output token limit (FinishReasonLength). The partial text
+// is held back from history, the model is asked to continue, and the parts
+// are stitched into a single assistant item.
+package workflow
+
+import (
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// maxAutoContinuations caps how many times a single turn may auto-continue
+// a truncated response, so a model that never finishes cannot loop forever.
+const maxAutoContinuations = 3
+
+// continuationPrompt is sent as a transient user message after the partial
+// assistant text. It is never added to history.
+const continuationPrompt = "Your previous response was cut off by the output token limit. " +
+	"Continue exactly where you left off, without repeating anything or adding preamble."
+
+// holdForContinuation checks whether result is a truncated text response that
+// should be auto-continued. If so, the trailing assistant message is removed
+// from result.Items and stashed (merged with any earlier partial) in
+// s.pendingContinuation, and true is returned.
+func (s *SessionState) holdForContinuation(result *activities.LLMActivityOutput) bool {
+	if result.FinishReason != models.FinishReasonLength || len(result.Items) == 0 {
+		return false
+	}
+	if s.autoContinueCount >= maxAutoContinuations {
+		return false
+	}
+	for _, item := range result.Items {
+		if item.Type == models.ItemTypeFunctionCall {
+			return false
+		}
+	}
+	last := result.Items[len(result.Items)-1]
+	if last.Type != models.ItemTypeAssistantMessage || last.Content == "" {
+		return false
+	}
+
+	s.autoContinueCount++
+	s.pendingContinuation = &last
+	result.Items = result.Items[:len(result.Items)-1]
+	return true
+}
+
+// mergePendingContinuation prepends the held partial text to the first
+// assistant message of a continuation response. If the response has no
+// assistant message, the partial is restored as its own item. Returns true
+// if a partial was pending.
+func (s *SessionState) mergePendingContinuation(result *activities.LLMActivityOutput) bool {
+	partial := s.pendingContinuation
+	if partial == nil {
+		return false
+	}
+	s.pendingContinuation = nil
+
+	for i := range result.Items {
+		if result.Items[i].Type == models.ItemTypeAssistantMessage {
+			result.Items[i].Content = partial.Content + result.Items[i].Content
+			return true
+		}
+	}
+	result.Items = append([]models.ConversationItem{*partial}, result.Items...)
+	return true
+}
+
+// continuationInput returns the LLM input items for a continuation request:
+// the full history, the partial assistant text, and the continuation prompt.
+func (s *SessionState) continuationInput(historyItems []models.ConversationItem) []models.ConversationItem {
+	items := make([]models.ConversationItem, 0, len(historyItems)+2)
+	items = append(items, historyItems...)
+	items = append(items, *s.pendingContinuation, models.ConversationItem{
+		Type:    models.ItemTypeUserMessage,
+		Content: continuationPrompt,
+	})
+	return items
+}
+
+// flushPendingContinuation adds any held partial text to history so it is not
+// lost when the turn ends early (interrupt, LLM error) during a continuation.
+func (s *SessionState) flushPendingContinuation(ctrl *LoopControl) {
+	if s.pendingContinuation == nil {
+		return
+	}
+	_ = s.History.AddItem(*s.pendingContinuation)
+	ctrl.NotifyItemAdded()
+	s.pendingContinuation = nil
+}
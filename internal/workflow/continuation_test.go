@@ -0,0 +1,62 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+func truncatedOutput(text string) *activities.LLMActivityOutput {
+	return &activities.LLMActivityOutput{
+		Items:        []models.ConversationItem{{Type: models.ItemTypeAssistantMessage, Content: text}},
+		FinishReason: models.FinishReasonLength,
+	}
+}
+
+func TestHoldForContinuation_CapsPerTurn(t *testing.T) {
+	s := &SessionState{}
+	for i := 0; i < maxAutoContinuations; i++ {
+		out := truncatedOutput("part")
+		s.mergePendingContinuation(out)
+		require.True(t, s.holdForContinuation(out), "continuation %d", i)
+		assert.Empty(t, out.Items)
+	}
+	assert.Equal(t, "partpartpart", s.pendingContinuation.Content)
+
+	out := truncatedOutput("last")
+	s.mergePendingContinuation(out)
+	assert.False(t, s.holdForContinuation(out), "cap reached")
+	require.Len(t, out.Items, 1)
+	assert.Equal(t, "partpartpartlast", out.Items[0].Content)
+}
+
+func TestHoldForContinuation_SkipsToolCallsAndStop(t *testing.T) {
+	s := &SessionState{}
+
+	stop := truncatedOutput("done")
+	stop.FinishReason = models.FinishReasonStop
+	assert.False(t, s.holdForContinuation(stop))
+
+	withCall := truncatedOutput("text")
+	withCall.Items = append(withCall.Items, models.ConversationItem{Type: models.ItemTypeFunctionCall, Name: "shell"})
+	assert.False(t, s.holdForContinuation(withCall))
+
+	assert.Nil(t, s.pendingContinuation)
+	assert.Zero(t, s.autoContinueCount)
+}
+
+func TestMergePendingContinuation_NoAssistantMessage(t *testing.T) {
+	s := &SessionState{pendingContinuation: &models.ConversationItem{Type: models.ItemTypeAssistantMessage, Content: "partial"}}
+	out := &activities.LLMActivityOutput{
+		Items: []models.ConversationItem{{Type: models.ItemTypeFunctionCall, Name: "shell"}},
+	}
+
+	assert.True(t, s.mergePendingContinuation(out))
+	require.Len(t, out.Items, 2)
+	assert.Equal(t, "partial", out.Items[0].Content)
+	assert.Nil(t, s.pendingContinuation)
+}
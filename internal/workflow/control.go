@@ -60,12 +60,14 @@ type LoopControl struct {
 	pendingUserInput  bool
 	shutdownRequested bool
 	interrupted       bool
+	hardInterrupt     bool // Cancel in-flight activities (see InterruptModeHard)
 	compactRequested  bool
 	currentTurnID     string
 
 	// Observable state for get_turn_status query
 	phase               TurnPhase
 	toolsInFlight       []string
+	toolFocus           *Focus
 	pendingApprovals    []PendingApproval
 	pendingEscalations  []EscalationRequest
 	pendingUserInputReq *PendingUserInputRequest
@@ -77,6 +79,13 @@ type LoopControl struct {
 	// polling.
 	stateVersion uint64
 
+	// Context version — bumped when the context a pending approval was
+	// requested in is superseded (new user message, model or instruction
+	// change). contextChange describes the latest change. See
+	// approval_expiry.go.
+	contextVersion uint64
+	contextChange  string
+
 	// Draining — set before AllHandlersFinished wait during ContinueAsNew
 	// so that any blocked get_state_update handlers wake up and return.
 	draining bool
@@ -85,6 +94,24 @@ type LoopControl struct {
 	approvalSlot   ResponseSlot[ApprovalResponse]
 	escalationSlot ResponseSlot[EscalationResponse]
 	userInputQSlot ResponseSlot[UserInputQuestionResponse]
+
+	// eventSink receives session events for the event log (see events.go).
+	// nil = events are not recorded.
+	eventSink func(SessionEvent)
+}
+
+// SetEventSink installs the receiver of session events.
+func (ctrl *LoopControl) SetEventSink(fn func(SessionEvent)) { ctrl.eventSink = fn }
+
+// Emit sends e to the event sink, filling in the current turn ID.
+func (ctrl *LoopControl) Emit(e SessionEvent) {
+	if ctrl.eventSink == nil {
+		return
+	}
+	if e.TurnID == "" {
+		e.TurnID = ctrl.currentTurnID
+	}
+	ctrl.eventSink(e)
 }
 
 // --- Delivery methods (called by update handlers) ---
@@ -95,6 +122,8 @@ func (ctrl *LoopControl) DeliverApproval(resp ApprovalResponse) {
 	ctrl.approvalSlot.Deliver(resp)
 	ctrl.pendingApprovals = nil // clear immediately so query handler reflects the response
 	ctrl.stateVersion++
+	ctrl.Emit(SessionEvent{Type: EventApprovalResolved,
+		Message: fmt.Sprintf("approved %d, denied %d", len(resp.Approved), len(resp.Denied))})
 }
 
 // DeliverEscalation stores an escalation response and clears visible pending state.
@@ -103,6 +132,8 @@ func (ctrl *LoopControl) DeliverEscalation(resp EscalationResponse) {
 	ctrl.escalationSlot.Deliver(resp)
 	ctrl.pendingEscalations = nil
 	ctrl.stateVersion++
+	ctrl.Emit(SessionEvent{Type: EventEscalationResolved,
+		Message: fmt.Sprintf("approved %d, denied %d", len(resp.Approved), len(resp.Denied))})
 }
 
 // DeliverUserInputQ stores a user-input-question response and clears visible
@@ -120,13 +151,32 @@ func (ctrl *LoopControl) DeliverUserInputQ(resp UserInputQuestionResponse) {
 func (ctrl *LoopControl) SetPendingUserInput(turnID string) {
 	ctrl.currentTurnID = turnID
 	ctrl.pendingUserInput = true
+	ctrl.SupersedeContext("a new user message arrived before a decision was made")
+}
+
+// SupersedeContext records that the context pending approvals were
+// requested in has changed for the given reason. Approvals awaiting a
+// decision expire instead of being executed.
+func (ctrl *LoopControl) SupersedeContext(reason string) {
+	ctrl.contextVersion++
+	ctrl.contextChange = reason
 	ctrl.stateVersion++
 }
 
-// SetInterrupted marks the current turn as interrupted.
+// SetInterrupted marks the current turn as (soft) interrupted.
 func (ctrl *LoopControl) SetInterrupted() {
+	ctrl.SetInterruptedMode(InterruptModeSoft)
+}
+
+// SetInterruptedMode marks the current turn as interrupted with the given
+// mode. A hard interrupt upgrades an earlier soft one; never the reverse.
+func (ctrl *LoopControl) SetInterruptedMode(mode InterruptMode) {
 	ctrl.interrupted = true
+	if mode == InterruptModeHard {
+		ctrl.hardInterrupt = true
+	}
 	ctrl.stateVersion++
+	ctrl.Emit(SessionEvent{Type: EventInterrupted, Message: string(mode)})
 }
 
 // SetShutdown marks the session as shut down and interrupts the current turn.
@@ -145,7 +195,13 @@ func (ctrl *LoopControl) SetCompactRequested() {
 // --- Phase / tool tracking (called by loop and turn code) ---
 
 // SetPhase updates the current turn phase (visible via get_turn_status).
-func (ctrl *LoopControl) SetPhase(p TurnPhase) { ctrl.phase = p; ctrl.stateVersion++ }
+func (ctrl *LoopControl) SetPhase(p TurnPhase) {
+	if ctrl.phase != p {
+		ctrl.Emit(SessionEvent{Type: EventPhase, Phase: p})
+	}
+	ctrl.phase = p
+	ctrl.stateVersion++
+}
 
 // Phase returns the current turn phase.
 func (ctrl *LoopControl) Phase() TurnPhase { return ctrl.phase }
@@ -153,8 +209,15 @@ func (ctrl *LoopControl) Phase() TurnPhase { return ctrl.phase }
 // SetToolsInFlight records the names of currently executing tools.
 func (ctrl *LoopControl) SetToolsInFlight(tools []string) { ctrl.toolsInFlight = tools; ctrl.stateVersion++ }
 
-// ClearToolsInFlight clears the in-flight tool list.
-func (ctrl *LoopControl) ClearToolsInFlight() { ctrl.toolsInFlight = nil; ctrl.stateVersion++ }
+// ClearToolsInFlight clears the in-flight tool list and the tool focus.
+func (ctrl *LoopControl) ClearToolsInFlight() {
+	ctrl.toolsInFlight = nil
+	ctrl.toolFocus = nil
+	ctrl.stateVersion++
+}
+
+// SetToolFocus records the running tool call shown as the current focus.
+func (ctrl *LoopControl) SetToolFocus(f *Focus) { ctrl.toolFocus = f; ctrl.stateVersion++ }
 
 // SetSuggestion stores the post-turn prompt suggestion.
 func (ctrl *LoopControl) SetSuggestion(s string) { ctrl.suggestion = s; ctrl.stateVersion++ }
@@ -167,6 +230,9 @@ func (ctrl *LoopControl) CurrentTurnID() string { return ctrl.currentTurnID }
 // ToolsInFlight returns the currently in-flight tool names.
 func (ctrl *LoopControl) ToolsInFlight() []string { return ctrl.toolsInFlight }
 
+// ToolFocus returns the running tool call shown as the current focus.
+func (ctrl *LoopControl) ToolFocus() *Focus { return ctrl.toolFocus }
+
 // PendingApprovals returns the current pending approval list.
 func (ctrl *LoopControl) PendingApprovals() []PendingApproval { return ctrl.pendingApprovals }
 
@@ -192,7 +258,10 @@ func (ctrl *LoopControl) StateVersion() uint64 { return ctrl.stateVersion }
 
 // NotifyItemAdded bumps the state version to signal that a new history item
 // was added. Called after every History.AddItem() call.
-func (ctrl *LoopControl) NotifyItemAdded() { ctrl.stateVersion++ }
+func (ctrl *LoopControl) NotifyItemAdded() {
+	ctrl.stateVersion++
+	ctrl.Emit(SessionEvent{Type: EventItem})
+}
 
 // SetDraining marks the workflow as draining (preparing for ContinueAsNew).
 // Blocked get_state_update handlers will wake and return.
@@ -214,6 +283,9 @@ func (ctrl *LoopControl) IsShutdown() bool { return ctrl.shutdownRequested }
 // IsInterrupted returns true if the current turn has been interrupted.
 func (ctrl *LoopControl) IsInterrupted() bool { return ctrl.interrupted }
 
+// IsHardInterrupted returns true if the current turn has been hard interrupted.
+func (ctrl *LoopControl) IsHardInterrupted() bool { return ctrl.hardInterrupt }
+
 // IsCompactRequested returns true if manual compaction was requested.
 func (ctrl *LoopControl) IsCompactRequested() bool { return ctrl.compactRequested }
 
@@ -224,8 +296,10 @@ func (ctrl *LoopControl) IsCompactRequested() bool { return ctrl.compactRequeste
 func (ctrl *LoopControl) StartTurn() {
 	ctrl.pendingUserInput = false
 	ctrl.interrupted = false
+	ctrl.hardInterrupt = false
 	ctrl.suggestion = ""
 	ctrl.stateVersion++
+	ctrl.Emit(SessionEvent{Type: EventTurnStarted})
 }
 
 // ClearCompactRequested marks the compact request as handled.
@@ -246,18 +320,26 @@ func (ctrl *LoopControl) WaitForInput(ctx workflow.Context) (bool, error) {
 
 // AwaitApproval sets approval-pending state, blocks until a response arrives
 // or the turn is interrupted, then returns the response.
-// Returns nil if interrupted or shutdown before a response arrived.
+// Returns nil if interrupted, shutdown or superseded (see SupersedeContext)
+// before a response arrived.
 func (ctrl *LoopControl) AwaitApproval(ctx workflow.Context, needsApproval []PendingApproval) (*ApprovalResponse, error) {
 	logger := workflow.GetLogger(ctx)
 
-	ctrl.phase = PhaseApprovalPending
+	ctrl.SetPhase(PhaseApprovalPending)
+	for i := range needsApproval {
+		needsApproval[i].TurnID = ctrl.currentTurnID
+		needsApproval[i].ContextVersion = ctrl.contextVersion
+	}
 	ctrl.pendingApprovals = needsApproval
+	ctrl.Emit(SessionEvent{Type: EventApprovalRequested, Message: describeApprovals(needsApproval)})
 	ctrl.approvalSlot.clear()
 
 	logger.Info("Waiting for tool approval", "count", len(needsApproval))
 
+	version := ctrl.contextVersion
 	err := workflow.Await(ctx, func() bool {
-		return ctrl.approvalSlot.Ready() || ctrl.interrupted || ctrl.shutdownRequested
+		return ctrl.approvalSlot.Ready() || ctrl.interrupted || ctrl.shutdownRequested ||
+			ctrl.contextVersion != version
 	})
 	if err != nil {
 		return nil, fmt.Errorf("approval await failed: %w", err)
@@ -269,6 +351,10 @@ func (ctrl *LoopControl) AwaitApproval(ctx workflow.Context, needsApproval []Pen
 		logger.Info("Approval wait interrupted")
 		return nil, nil
 	}
+	if ctrl.contextVersion != version {
+		logger.Info("Approval expired", "reason", ctrl.contextChange)
+		return nil, nil
+	}
 	return ctrl.approvalSlot.Take(), nil
 }
 
@@ -278,8 +364,10 @@ func (ctrl *LoopControl) AwaitApproval(ctx workflow.Context, needsApproval []Pen
 func (ctrl *LoopControl) AwaitEscalation(ctx workflow.Context, escalations []EscalationRequest) (*EscalationResponse, error) {
 	logger := workflow.GetLogger(ctx)
 
-	ctrl.phase = PhaseEscalationPending
+	ctrl.SetPhase(PhaseEscalationPending)
 	ctrl.pendingEscalations = escalations
+	ctrl.Emit(SessionEvent{Type: EventEscalationRequested,
+		Message: fmt.Sprintf("%d failed tool call(s)", len(escalations))})
 	ctrl.escalationSlot.clear()
 
 	logger.Info("Waiting for escalation decision", "failed_count", len(escalations))
@@ -306,7 +394,7 @@ func (ctrl *LoopControl) AwaitEscalation(ctx workflow.Context, escalations []Esc
 func (ctrl *LoopControl) AwaitUserInputQuestion(ctx workflow.Context, req *PendingUserInputRequest) (*UserInputQuestionResponse, error) {
 	logger := workflow.GetLogger(ctx)
 
-	ctrl.phase = PhaseUserInputPending
+	ctrl.SetPhase(PhaseUserInputPending)
 	ctrl.pendingUserInputReq = req
 	ctrl.userInputQSlot.clear()
 
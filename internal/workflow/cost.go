@@ -0,0 +1,132 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// cost.go tracks the estimated LLM spend of a session and enforces
+// MaxSessionCostUSD: notices at the configured warning percentages, and a
+// pause before the next LLM call once the cap is reached. The session then
+// waits until the raise_cost_cap Update lifts the cap and resumes the turn.
+package workflow
+
+import (
+	"fmt"
+
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// RaiseCostCapRequest is the payload for the raise_cost_cap Update.
+type RaiseCostCapRequest struct {
+	// MaxSessionCostUSD is the new cap. 0 doubles the current cap.
+	MaxSessionCostUSD float64 `json:"max_session_cost_usd,omitempty"`
+}
+
+// addLLMCost adds the estimated cost of one LLM call to model to the
+// session total. Calls to models without a known price are not counted.
+func (s *SessionState) addLLMCost(ctx workflow.Context, model models.ModelConfig, usage models.TokenUsage) {
+	cost, ok := models.EstimateCostUSD(model.Provider, model.Model, usage)
+	if !ok {
+		if s.Config.MaxSessionCostUSD > 0 {
+			workflow.GetLogger(ctx).Warn("No price known for model, cost cap cannot count this call",
+				"provider", model.Provider, "model", model.Model)
+		}
+		return
+	}
+	s.SessionCostUSD += cost
+}
+
+// costWarnPercents returns the configured warning percentages.
+func (s *SessionState) costWarnPercents() []int {
+	if len(s.Config.CostWarnPercents) > 0 {
+		return s.Config.CostWarnPercents
+	}
+	return models.DefaultCostWarnPercents
+}
+
+// crossedWarnPercent returns the highest warning percentage the current
+// spend has reached under the current cap, or 0.
+func (s *SessionState) crossedWarnPercent() int {
+	spent := s.SessionCostUSD * 100 / s.Config.MaxSessionCostUSD
+	crossed := 0
+	for _, p := range s.costWarnPercents() {
+		if float64(p) <= spent && p > crossed {
+			crossed = p
+		}
+	}
+	return crossed
+}
+
+// checkCostGuardrail runs before each LLM call. It adds a notice when spend
+// crosses a new warning percentage, and pauses the session when the cap is
+// reached. Returns true if the turn must stop.
+func (s *SessionState) checkCostGuardrail(ctx workflow.Context, ctrl *LoopControl) bool {
+	limit := s.Config.MaxSessionCostUSD
+	if limit <= 0 {
+		return false
+	}
+	if s.SessionCostUSD >= limit {
+		workflow.GetLogger(ctx).Warn("Session cost cap reached, pausing",
+			"cost_usd", s.SessionCostUSD, "cap_usd", limit)
+		s.CostCapPaused = true
+		s.addNotice(ctrl, fmt.Sprintf("Session paused: estimated cost $%.2f reached the $%.2f cap. "+
+			"Use /resume --raise-cap [usd] to continue.", s.SessionCostUSD, limit))
+		return true
+	}
+	if p := s.crossedWarnPercent(); p > s.CostWarnedPercent {
+		s.CostWarnedPercent = p
+		s.addNotice(ctrl, fmt.Sprintf("Cost warning: estimated $%.2f of the $%.2f session cap spent (%d%%).",
+			s.SessionCostUSD, limit, p))
+	}
+	return false
+}
+
+// validateRaiseCostCap checks that req can lift the pause.
+func (s *SessionState) validateRaiseCostCap(ctrl *LoopControl, req RaiseCostCapRequest) error {
+	if ctrl.IsShutdown() {
+		return fmt.Errorf("session is shutting down")
+	}
+	if !s.CostCapPaused {
+		return fmt.Errorf("session is not paused by the cost cap")
+	}
+	if req.MaxSessionCostUSD < 0 {
+		return fmt.Errorf("cap must not be negative")
+	}
+	if req.MaxSessionCostUSD > 0 && req.MaxSessionCostUSD <= s.SessionCostUSD {
+		return fmt.Errorf("new cap $%.2f must exceed the $%.2f already spent", req.MaxSessionCostUSD, s.SessionCostUSD)
+	}
+	return nil
+}
+
+// raiseCostCap applies the new cap and queues a turn that resumes the
+// paused work. Returns the new turn ID.
+func (s *SessionState) raiseCostCap(ctrl *LoopControl, req RaiseCostCapRequest) string {
+	limit := req.MaxSessionCostUSD
+	if limit == 0 {
+		limit = s.Config.MaxSessionCostUSD * 2
+		if limit <= s.SessionCostUSD {
+			limit = s.SessionCostUSD + s.Config.MaxSessionCostUSD
+		}
+	}
+	s.Config.MaxSessionCostUSD = limit
+	s.CostWarnedPercent = s.crossedWarnPercent()
+	s.CostCapPaused = false
+
+	turnID := s.nextTurnID()
+	_ = s.History.AddItem(models.ConversationItem{
+		Type:   models.ItemTypeTurnStarted,
+		TurnID: turnID,
+	})
+	ctrl.NotifyItemAdded()
+	ctrl.SetPendingUserInput(turnID)
+	s.addNotice(ctrl, fmt.Sprintf("Cost cap raised to $%.2f. Resuming.", limit))
+	return turnID
+}
+
+// addNotice records a display-only notice for the user in the current turn.
+func (s *SessionState) addNotice(ctrl *LoopControl, text string) {
+	_ = s.History.AddItem(models.ConversationItem{
+		Type:    models.ItemTypeNotice,
+		Content: text,
+		TurnID:  ctrl.CurrentTurnID(),
+	})
+	ctrl.NotifyItemAdded()
+}
@@ -0,0 +1,29 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// current_date.go gives the model the current date at the start of each
+// turn, so it stops assuming its training cutoff is "today" when writing
+// changelogs, schedules or copyright headers.
+package workflow
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// currentDateInstructions returns the <current_date> developer block for a
+// turn started at now (already in the session's timezone). Only the date is
+// included so the instructions, and the provider's prompt cache, stay stable
+// for the whole day.
+func currentDateInstructions(now time.Time, locale string) string {
+	var b strings.Builder
+	b.WriteString("<current_date>\n")
+	fmt.Fprintf(&b, "Today is %s, %s (timezone: %s).", now.Weekday(), now.Format("2006-01-02"), now.Location())
+	b.WriteString(" Use this date, not your training data, whenever the current date matters," +
+		" e.g. in changelogs, release notes, schedules or copyright years.")
+	if locale != "" {
+		fmt.Fprintf(&b, "\nThe user's locale is %s; format dates and numbers you show them accordingly.", locale)
+	}
+	b.WriteString("\n</current_date>")
+	return b.String()
+}
@@ -0,0 +1,59 @@
+package workflow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+func TestCurrentDateInstructions(t *testing.T) {
+	// 23:30 UTC is already the next day in Tokyo.
+	now := time.Date(2026, 3, 5, 23, 30, 0, 0, time.UTC).In(models.LoadTimezone("Asia/Tokyo"))
+
+	got := currentDateInstructions(now, "ja-JP")
+	assert.Contains(t, got, "Today is Friday, 2026-03-06 (timezone: Asia/Tokyo).")
+	assert.Contains(t, got, "not your training data")
+	assert.Contains(t, got, "The user's locale is ja-JP")
+
+	assert.NotContains(t, currentDateInstructions(now, ""), "locale")
+}
+
+func TestDeveloperInstructionsForTurn_CurrentDate(t *testing.T) {
+	s := &SessionState{Config: models.SessionConfiguration{DeveloperInstructions: "Be terse."}}
+	assert.Equal(t, "Be terse.", s.developerInstructionsForTurn(), "no date before the first turn")
+
+	s.turnStart = time.Date(2026, 3, 5, 9, 0, 0, 0, time.UTC)
+	assert.Contains(t, s.developerInstructionsForTurn(), "Be terse.\n\n<current_date>\nToday is Thursday, 2026-03-05 (timezone: UTC).")
+}
+
+// TestCurrentDate_InjectedEachTurn: every LLM request of a turn carries the
+// turn's date in the configured timezone.
+func (s *AgenticWorkflowTestSuite) TestCurrentDate_InjectedEachTurn() {
+	s.env.SetStartTime(time.Date(2026, 12, 31, 20, 0, 0, 0, time.UTC))
+
+	var developer []string
+	s.env.OnActivity("ExecuteLLMCall", mock.Anything, mock.Anything).
+		Return(func(_ context.Context, in activities.LLMActivityInput) (activities.LLMActivityOutput, error) {
+			developer = append(developer, in.DeveloperInstructions)
+			return mockLLMStopResponse("Happy new year.", 10), nil
+		}).Once()
+	s.sendShutdown(time.Second * 2)
+
+	input := testInput("Write the changelog entry for today's release")
+	input.Config.Timezone = "Pacific/Auckland"
+	input.Config.Locale = "en-NZ"
+	s.env.ExecuteWorkflow(AgenticWorkflow, input)
+
+	require.True(s.T(), s.env.IsWorkflowCompleted())
+	require.NoError(s.T(), s.env.GetWorkflowError())
+	require.Len(s.T(), developer, 1)
+	assert.Contains(s.T(), developer[0], "Today is Friday, 2027-01-01 (timezone: Pacific/Auckland).")
+	assert.Contains(s.T(), developer[0], "The user's locale is en-NZ")
+}
@@ -0,0 +1,60 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// dedupe.go drops duplicated assistant content produced by LLM activity
+// retries before it is recorded in history.
+package workflow
+
+import (
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// dedupeLLMResponse removes items from result that duplicate what is already
+// in history. A response whose idempotency key matches the previously
+// recorded response, with no history recorded in between, is a replay and
+// contributes no items (replay=true); otherwise an assistant message
+// identical to the immediately preceding assistant item is dropped.
+// Returns the number of dropped items, also added to s.DedupedAssistantItems.
+func (s *SessionState) dedupeLLMResponse(result *activities.LLMActivityOutput) (dropped int, replay bool) {
+	// A key match only counts as a replay if nothing was recorded since; an
+	// identical answer to a new user message is legitimate.
+	latestSeq := s.History.GetLatestSeq()
+	if result.IdempotencyKey != "" && result.IdempotencyKey == s.lastLLMIdempotencyKey &&
+		latestSeq == s.lastLLMIdempotencySeq {
+		dropped = len(result.Items)
+		s.DedupedAssistantItems += dropped
+		result.Items = nil
+		return dropped, true
+	}
+
+	var prev *models.ConversationItem
+	if raw, err := s.History.GetRawItems(); err == nil && len(raw) > 0 {
+		prev = &raw[len(raw)-1]
+	}
+
+	kept := result.Items[:0]
+	for _, item := range result.Items {
+		if isDuplicateAssistantItem(prev, item) {
+			dropped++
+			continue
+		}
+		kept = append(kept, item)
+		prev = &kept[len(kept)-1]
+	}
+	result.Items = kept
+	s.DedupedAssistantItems += dropped
+
+	s.lastLLMIdempotencyKey = result.IdempotencyKey
+	s.lastLLMIdempotencySeq = latestSeq + len(kept)
+	return dropped, false
+}
+
+// isDuplicateAssistantItem reports whether item is a non-empty assistant
+// message with the same content as the assistant message prev.
+func isDuplicateAssistantItem(prev *models.ConversationItem, item models.ConversationItem) bool {
+	return prev != nil &&
+		prev.Type == models.ItemTypeAssistantMessage &&
+		item.Type == models.ItemTypeAssistantMessage &&
+		item.Content != "" &&
+		item.Content == prev.Content
+}
@@ -0,0 +1,87 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/history"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+func newDedupeState(items ...models.ConversationItem) *SessionState {
+	h := history.NewInMemoryHistory()
+	for _, item := range items {
+		_ = h.AddItem(item)
+	}
+	return &SessionState{History: h}
+}
+
+func recordDedupedResult(s *SessionState, result *activities.LLMActivityOutput) {
+	for _, item := range result.Items {
+		_ = s.History.AddItem(item)
+	}
+}
+
+func TestDedupeLLMResponse_DropsAdjacentDuplicate(t *testing.T) {
+	s := newDedupeState(
+		models.ConversationItem{Type: models.ItemTypeUserMessage, Content: "hi"},
+		models.ConversationItem{Type: models.ItemTypeAssistantMessage, Content: "Hello!"},
+	)
+	result := &activities.LLMActivityOutput{
+		Items: []models.ConversationItem{
+			{Type: models.ItemTypeAssistantMessage, Content: "Hello!"},
+			{Type: models.ItemTypeAssistantMessage, Content: "Hello!"},
+			{Type: models.ItemTypeFunctionCall, CallID: "c1", Name: "shell"},
+		},
+		IdempotencyKey: "sha256:a",
+	}
+
+	dropped, replay := s.dedupeLLMResponse(result)
+	assert.Equal(t, 2, dropped)
+	assert.False(t, replay)
+	require.Len(t, result.Items, 1)
+	assert.Equal(t, models.ItemTypeFunctionCall, result.Items[0].Type)
+	assert.Equal(t, 2, s.DedupedAssistantItems)
+}
+
+func TestDedupeLLMResponse_ReplayedKey(t *testing.T) {
+	s := newDedupeState(models.ConversationItem{Type: models.ItemTypeUserMessage, Content: "hi"})
+	first := &activities.LLMActivityOutput{
+		Items:          []models.ConversationItem{{Type: models.ItemTypeAssistantMessage, Content: "Done."}},
+		IdempotencyKey: "resp:1",
+	}
+	_, replay := s.dedupeLLMResponse(first)
+	assert.False(t, replay)
+	recordDedupedResult(s, first)
+
+	again := &activities.LLMActivityOutput{
+		Items:          []models.ConversationItem{{Type: models.ItemTypeFunctionCall, CallID: "c1"}},
+		IdempotencyKey: "resp:1",
+	}
+	dropped, replay := s.dedupeLLMResponse(again)
+	assert.True(t, replay)
+	assert.Equal(t, 1, dropped)
+	assert.Empty(t, again.Items)
+}
+
+func TestDedupeLLMResponse_SameAnswerToNewMessageKept(t *testing.T) {
+	s := newDedupeState(models.ConversationItem{Type: models.ItemTypeUserMessage, Content: "a"})
+	first := &activities.LLMActivityOutput{
+		Items:          []models.ConversationItem{{Type: models.ItemTypeAssistantMessage, Content: "Done."}},
+		IdempotencyKey: "sha256:same",
+	}
+	s.dedupeLLMResponse(first)
+	recordDedupedResult(s, first)
+	_ = s.History.AddItem(models.ConversationItem{Type: models.ItemTypeUserMessage, Content: "b"})
+
+	second := &activities.LLMActivityOutput{
+		Items:          []models.ConversationItem{{Type: models.ItemTypeAssistantMessage, Content: "Done."}},
+		IdempotencyKey: "sha256:same",
+	}
+	dropped, _ := s.dedupeLLMResponse(second)
+	assert.Zero(t, dropped)
+	assert.Len(t, second.Items, 1)
+}
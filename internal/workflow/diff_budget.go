@@ -0,0 +1,144 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// diff_budget.go guards against runaway edits: it counts the lines a
+// turn's file edits change (apply_patch from its preview diff, write_file
+// from the content it writes), and once the next batch of edits would take
+// the turn past Config.DiffBudget(), those edits need the user's approval,
+// even in full-auto mode. After an approval the turn may change another
+// budget's worth of lines before asking again. Shell commands are not
+// counted: what they change is not known before they run. A patch whose
+// preview failed cannot be counted either; it needs approval whenever a
+// budget is set.
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// diffBatch is what a batch of tool calls would change, as counted by
+// measureEdits.
+type diffBatch struct {
+	// lines maps the call ID of each file edit to the lines it changes.
+	lines map[string]int
+	// uncounted holds the call IDs of patches without a preview.
+	uncounted map[string]bool
+	// overBudget is set when the batch needed approval for the budget.
+	overBudget bool
+}
+
+// measureEdits counts the lines each file edit among calls would change,
+// using the apply_patch previews by call ID. Patches without a preview are
+// recorded as uncounted.
+func measureEdits(calls []models.ConversationItem, previews map[string]activities.PatchPreviewOutput) diffBatch {
+	batch := diffBatch{lines: make(map[string]int), uncounted: make(map[string]bool)}
+	for _, fc := range calls {
+		var n int
+		switch fc.Name {
+		case "apply_patch":
+			preview, ok := previews[fc.CallID]
+			if !ok {
+				batch.uncounted[fc.CallID] = true
+			}
+			n = preview.LinesChanged
+		case "write_file":
+			n = writeFileLines(fc.Arguments)
+		}
+		if n > 0 {
+			batch.lines[fc.CallID] = n
+		}
+	}
+	return batch
+}
+
+// writeFileLines returns the number of lines a write_file call writes.
+func writeFileLines(arguments string) int {
+	var args struct {
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil || args.Content == "" {
+		return 0
+	}
+	n := strings.Count(args.Content, "\n")
+	if !strings.HasSuffix(args.Content, "\n") {
+		n++
+	}
+	return n
+}
+
+// requireDiffBudgetApproval adds the edits in batch to needsApproval when
+// they would take the turn past the diff budget, and marks the batch.
+// Uncounted patches always need approval under a budget. Edits already
+// pending get the budget appended to their reason.
+func (s *SessionState) requireDiffBudgetApproval(calls []models.ConversationItem, needsApproval []PendingApproval, batch *diffBatch) []PendingApproval {
+	budget := s.Config.DiffBudget()
+	if budget <= 0 {
+		return needsApproval
+	}
+	var total int
+	for _, n := range batch.lines {
+		total += n
+	}
+	over := total > 0 && s.turnDiffLines-s.turnDiffConfirmed+total > budget
+	if !over && len(batch.uncounted) == 0 {
+		return needsApproval
+	}
+	batch.overBudget = true
+
+	overReason := fmt.Sprintf("this turn's edits would change %d lines, over the diff budget of %d", s.turnDiffLines+total, budget)
+	uncountedReason := fmt.Sprintf("the lines this patch changes could not be counted against the diff budget of %d", budget)
+	pending := make(map[string]int, len(needsApproval))
+	for i, p := range needsApproval {
+		pending[p.CallID] = i
+	}
+	for _, fc := range calls {
+		var reason string
+		switch {
+		case batch.uncounted[fc.CallID]:
+			reason = uncountedReason
+		case over && batch.lines[fc.CallID] > 0:
+			reason = overReason
+		default:
+			continue
+		}
+		if i, ok := pending[fc.CallID]; ok {
+			if needsApproval[i].Reason == "" {
+				needsApproval[i].Reason = reason
+			} else {
+				needsApproval[i].Reason += "; " + reason
+			}
+			continue
+		}
+		needsApproval = append(needsApproval, PendingApproval{
+			CallID:    fc.CallID,
+			ToolName:  fc.Name,
+			Arguments: fc.Arguments,
+			Reason:    reason,
+		})
+	}
+	return needsApproval
+}
+
+// addTurnDiffLines counts the edits in batch that ran and did not fail.
+// When the batch was approved over the budget, the budget starts again
+// from here.
+func (s *SessionState) addTurnDiffLines(batch diffBatch, results []activities.ToolActivityOutput) {
+	ran := false
+	for _, r := range results {
+		n, ok := batch.lines[r.CallID]
+		if !ok && !batch.uncounted[r.CallID] {
+			continue
+		}
+		ran = true
+		if r.Success == nil || *r.Success {
+			s.turnDiffLines += n
+		}
+	}
+	if batch.overBudget && ran {
+		s.turnDiffConfirmed = s.turnDiffLines
+	}
+}
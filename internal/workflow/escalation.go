@@ -83,7 +83,9 @@ func (s *SessionState) handleOnFailureEscalation(
 	}
 
 	// Delegate blocking wait to LoopControl
+	waitStart := workflow.Now(ctx)
 	resp, err := ctrl.AwaitEscalation(ctx, escalations)
+	s.addApprovalWait(ctx, waitStart)
 	if err != nil {
 		return nil, fmt.Errorf("escalation await failed: %w", err)
 	}
@@ -107,17 +109,16 @@ func (s *SessionState) handleOnFailureEscalation(
 		logger.Info("Re-executing tool without sandbox", "tool", functionCalls[i].Name)
 
 		// Re-execute without sandbox (no SandboxPolicy)
-		reResults, err := executeToolsInParallel(
-			ctx,
-			[]models.ConversationItem{functionCalls[i]},
-			s.ToolSpecs, s.Config.Cwd, s.Config.SessionTaskQueue,
-			s.ConversationID, s.McpToolLookup,
-		)
+		toolStart := workflow.Now(ctx)
+		reResults, err := s.toolsExecutor(ctrl).ExecuteParallel(ctx, []models.ConversationItem{functionCalls[i]})
+		s.addToolLatency(ctx, toolStart)
 		if err != nil {
 			continue // Keep original failed result
 		}
 		if len(reResults) > 0 {
 			toolResults[i] = reResults[0]
+			s.auditResults(ctx, ctrl, functionCalls[i:i+1], reResults, AuditSandboxEscalated,
+				func(string) string { return approverName(resp.Approver) })
 		}
 	}
 
@@ -0,0 +1,151 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// events.go keeps a bounded, append-only log of session events — turn
+// starts, phase transitions, history items, approvals, escalations,
+// compactions and errors — under one global sequence, so external UIs can
+// rebuild a session timeline from a single get_events query. History items
+// are referenced by sequence number; fetch their content with
+// get_conversation_items.
+package workflow
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// maxSessionEvents bounds the event log; older events are dropped first.
+const maxSessionEvents = 500
+
+// SessionEventType identifies the kind of a SessionEvent.
+type SessionEventType string
+
+const (
+	EventTurnStarted         SessionEventType = "turn_started"
+	EventPhase               SessionEventType = "phase"
+	EventItem                SessionEventType = "item"
+	EventApprovalRequested   SessionEventType = "approval_requested"
+	EventApprovalResolved    SessionEventType = "approval_resolved"
+	EventEscalationRequested SessionEventType = "escalation_requested"
+	EventEscalationResolved  SessionEventType = "escalation_resolved"
+	EventCompaction          SessionEventType = "compaction"
+	EventError               SessionEventType = "error"
+	EventInterrupted         SessionEventType = "interrupted"
+)
+
+// SessionEvent is one entry in the session event log.
+type SessionEvent struct {
+	Seq     int64            `json:"seq"`
+	Type    SessionEventType `json:"type"`
+	Time    time.Time        `json:"time"`
+	TurnID  string           `json:"turn_id,omitempty"`
+	Phase   TurnPhase        `json:"phase,omitempty"` // EventPhase: the new phase
+	Item    *EventItemRef    `json:"item,omitempty"`  // EventItem: the added history item
+	Message string           `json:"message,omitempty"`
+	Caller  *CallerContext   `json:"caller,omitempty"` // EventExternalRequest: who made it
+}
+
+// EventItemRef points at a history item by its sequence number.
+type EventItemRef struct {
+	Seq  int                         `json:"seq"`
+	Type models.ConversationItemType `json:"type"`
+}
+
+// EventLog is the bounded session event log. It persists across
+// ContinueAsNew so sequence numbers keep increasing.
+type EventLog struct {
+	Events  []SessionEvent `json:"events,omitempty"`
+	NextSeq int64          `json:"next_seq"`
+
+	// LoggedItems is the number of history items logged so far, i.e. the
+	// sequence of the next item to log. Reset when compaction replaces
+	// the history.
+	LoggedItems int `json:"logged_items"`
+}
+
+// GetEventsResponse is the result of the get_events query.
+type GetEventsResponse struct {
+	Events    []SessionEvent `json:"events"`
+	LatestSeq int64          `json:"latest_seq"` // -1 when no events were recorded
+	// Truncated is set when events after the requested sequence were
+	// already dropped from the bounded log.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// append adds e with the next sequence number, dropping the oldest events
+// beyond maxSessionEvents.
+func (l *EventLog) append(now time.Time, e SessionEvent) {
+	e.Seq = l.NextSeq
+	e.Time = now
+	l.NextSeq++
+	l.Events = append(l.Events, e)
+	if over := len(l.Events) - maxSessionEvents; over > 0 {
+		l.Events = append([]SessionEvent(nil), l.Events[over:]...)
+	}
+}
+
+// since returns the events with Seq > afterSeq. Pass -1 for all.
+func (l *EventLog) since(afterSeq int64) GetEventsResponse {
+	resp := GetEventsResponse{Events: []SessionEvent{}, LatestSeq: l.NextSeq - 1}
+	if len(l.Events) > 0 && afterSeq+1 < l.Events[0].Seq {
+		resp.Truncated = true
+	}
+	for _, e := range l.Events {
+		if e.Seq > afterSeq {
+			resp.Events = append(resp.Events, e)
+		}
+	}
+	return resp
+}
+
+// recordEvent is the LoopControl event sink. Item events are expanded into
+// one event per history item added since the last one logged.
+func (s *SessionState) recordEvent(ctx workflow.Context, e SessionEvent) {
+	now := workflow.Now(ctx)
+	if e.Type != EventItem {
+		s.EventLog.append(now, e)
+		return
+	}
+	if s.History == nil {
+		return
+	}
+	items, reset, _ := s.History.GetItemsSince(s.EventLog.LoggedItems - 1)
+	if reset {
+		// History was replaced; the compaction event covers it.
+		s.EventLog.LoggedItems = len(items)
+		return
+	}
+	for _, item := range items {
+		ev := e
+		ev.Item = &EventItemRef{Seq: item.Seq, Type: item.Type}
+		if item.TurnID != "" {
+			ev.TurnID = item.TurnID
+		}
+		s.EventLog.append(now, ev)
+		s.EventLog.LoggedItems = item.Seq + 1
+	}
+}
+
+// logCompaction records a compaction event. The replaced history is not
+// logged item by item; clients refetch items after this event.
+func (s *SessionState) logCompaction(ctx workflow.Context, ctrl *LoopControl, message string) {
+	s.EventLog.LoggedItems = s.History.GetLatestSeq() + 1
+	s.EventLog.append(workflow.Now(ctx), SessionEvent{
+		Type:    EventCompaction,
+		TurnID:  ctrl.CurrentTurnID(),
+		Message: message,
+	})
+}
+
+// describeApprovals summarizes pending approvals for an event message.
+func describeApprovals(pending []PendingApproval) string {
+	names := make([]string, len(pending))
+	for i, p := range pending {
+		names[i] = p.ToolName
+	}
+	return fmt.Sprintf("%d tool call(s): %s", len(pending), strings.Join(names, ", "))
+}
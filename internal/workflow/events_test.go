@@ -0,0 +1,35 @@
+package workflow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventLog_SinceAndTruncation(t *testing.T) {
+	var l EventLog
+	resp := l.since(-1)
+	assert.Empty(t, resp.Events)
+	assert.Equal(t, int64(-1), resp.LatestSeq)
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < maxSessionEvents+10; i++ {
+		l.append(now, SessionEvent{Type: EventPhase, Phase: PhaseLLMCalling})
+	}
+	assert.Len(t, l.Events, maxSessionEvents)
+	assert.Equal(t, int64(10), l.Events[0].Seq, "oldest events are dropped")
+
+	resp = l.since(-1)
+	assert.True(t, resp.Truncated)
+	assert.Len(t, resp.Events, maxSessionEvents)
+	assert.Equal(t, int64(maxSessionEvents+9), resp.LatestSeq)
+
+	resp = l.since(9)
+	assert.False(t, resp.Truncated, "nothing after seq 9 was dropped")
+	assert.Len(t, resp.Events, maxSessionEvents)
+
+	resp = l.since(int64(maxSessionEvents + 7))
+	assert.Len(t, resp.Events, 2)
+	assert.Equal(t, now, resp.Events[0].Time)
+}
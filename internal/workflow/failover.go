@@ -0,0 +1,57 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// failover.go implements health-based provider routing: before each turn,
+// when Config.ProviderFailover is set, the workflow asks a worker for its
+// recent per-provider LLM error rates and, if the current provider is
+// failing, switches the session to an equivalent model on a healthy one.
+// The switch is recorded in history as a notice for the user and a
+// model_switch message for the new model.
+package workflow
+
+import (
+	"fmt"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+)
+
+// maybeFailoverProvider switches the model before a turn when the failover
+// policy routes away from the current provider. Health check failures are
+// logged and the turn proceeds on the current model.
+func (s *SessionState) maybeFailoverProvider(ctx workflow.Context, ctrl *LoopControl) {
+	policy := s.Config.ProviderFailover
+	if policy == nil || len(policy.Equivalents) == 0 {
+		return
+	}
+	logger := workflow.GetLogger(ctx)
+
+	actCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 10 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 2,
+		},
+	})
+	var health activities.ProviderHealthOutput
+	if err := workflow.ExecuteActivity(actCtx, "GetProviderHealth").Get(ctx, &health); err != nil {
+		logger.Warn("Provider health check failed, keeping current model", "error", err)
+		return
+	}
+
+	from := s.Config.Model
+	provider, model, ok := policy.Route(from, health.Providers)
+	if !ok {
+		return
+	}
+	rate := health.Providers[from.Provider].ErrorRate()
+	logger.Warn("Provider failing, routing to equivalent model",
+		"from_provider", from.Provider, "from_model", from.Model,
+		"to_provider", provider, "to_model", model, "error_rate", rate)
+
+	s.switchModel(provider, model, 0,
+		fmt.Sprintf("because provider %s is failing (%.0f%% of recent calls)", from.Provider, rate*100))
+	s.addNotice(ctrl, fmt.Sprintf("Provider %s is failing (%.0f%% of recent calls errored); switched from %s/%s to %s/%s.",
+		from.Provider, rate*100, from.Provider, from.Model, provider, model))
+}
@@ -0,0 +1,72 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// file_locks.go serializes parallel tool calls that write the same file.
+// Calls in one batch normally run concurrently; a write_file or apply_patch
+// call that targets a path an earlier call in the batch also writes starts
+// only after that call completes, so edits apply in the order the model
+// issued them. Calls on disjoint paths stay parallel.
+package workflow
+
+import (
+	"path/filepath"
+	"slices"
+
+	"github.com/mfateev/temporal-agent-harness/internal/tools/patch"
+)
+
+// mutationPaths returns the cleaned absolute paths a tool call writes, or
+// nil if the call writes no files the workflow can identify. Relative paths
+// are resolved against cwd.
+func mutationPaths(toolName string, args map[string]interface{}, cwd string) []string {
+	var paths []string
+	switch toolName {
+	case "write_file":
+		if p, ok := args["path"].(string); ok && p != "" {
+			paths = append(paths, p)
+		}
+	case "apply_patch":
+		input, _ := args["input"].(string)
+		parsed, err := patch.Parse(input)
+		if err != nil {
+			return nil
+		}
+		for _, h := range parsed.Hunks {
+			paths = append(paths, h.Path)
+			if h.MovePath != "" {
+				paths = append(paths, h.MovePath)
+			}
+		}
+	}
+	for i, p := range paths {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(cwd, p)
+		}
+		paths[i] = filepath.Clean(p)
+	}
+	return paths
+}
+
+// fileLocks tracks, per path, the latest call in a batch that writes it.
+type fileLocks struct {
+	lastWriter map[string]int
+}
+
+func newFileLocks() *fileLocks {
+	return &fileLocks{lastWriter: make(map[string]int)}
+}
+
+// acquire records call i as the latest writer of paths and returns the
+// earlier calls it must wait for, in call order without duplicates.
+func (l *fileLocks) acquire(i int, paths []string) []int {
+	var deps []int
+	seen := make(map[int]bool)
+	for _, p := range paths {
+		if j, ok := l.lastWriter[p]; ok && j != i && !seen[j] {
+			seen[j] = true
+			deps = append(deps, j)
+		}
+		l.lastWriter[p] = i
+	}
+	slices.Sort(deps)
+	return deps
+}
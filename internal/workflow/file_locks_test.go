@@ -0,0 +1,31 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMutationPaths(t *testing.T) {
+	assert.Equal(t, []string{"/repo/a.go"},
+		mutationPaths("write_file", map[string]interface{}{"path": "a.go"}, "/repo"))
+	assert.Equal(t, []string{"/etc/x.conf"},
+		mutationPaths("write_file", map[string]interface{}{"path": "/etc/../etc/x.conf"}, "/repo"))
+
+	input := "*** Begin Patch\n*** Update File: src/a.go\n*** Move to: src/b.go\n@@\n-x\n+y\n*** Add File: /tmp/c.txt\n+c\n*** End Patch"
+	assert.Equal(t, []string{"/repo/src/a.go", "/repo/src/b.go", "/tmp/c.txt"},
+		mutationPaths("apply_patch", map[string]interface{}{"input": input}, "/repo"))
+
+	assert.Nil(t, mutationPaths("apply_patch", map[string]interface{}{"input": "not a patch"}, "/repo"))
+	assert.Nil(t, mutationPaths("read_file", map[string]interface{}{"path": "a.go"}, "/repo"))
+	assert.Nil(t, mutationPaths("shell_command", map[string]interface{}{"command": "rm a.go"}, "/repo"))
+}
+
+func TestFileLocks_Acquire(t *testing.T) {
+	l := newFileLocks()
+	assert.Empty(t, l.acquire(0, []string{"/a"}))
+	assert.Empty(t, l.acquire(1, []string{"/b"}), "disjoint paths do not wait")
+	assert.Empty(t, l.acquire(2, nil))
+	assert.Equal(t, []int{0, 1}, l.acquire(3, []string{"/b", "/a", "/b"}))
+	assert.Equal(t, []int{3}, l.acquire(4, []string{"/a"}), "waits only for the latest writer")
+}
@@ -0,0 +1,58 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// file_mentions.go expands @path mentions in user input into context items.
+package workflow
+
+import (
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/mentions"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// injectFileMentions parses @path mentions from user input, reads them via
+// activity, and injects each as a <file_context> user message so the model
+// does not need a read_file round-trip. Non-fatal: failures are logged and
+// skipped.
+func (s *SessionState) injectFileMentions(ctx workflow.Context, userInput, turnID string) {
+	parsed := mentions.ParseFileMentions(userInput)
+	if len(parsed) == 0 {
+		return
+	}
+
+	logger := workflow.GetLogger(ctx)
+	actOpts := workflow.ActivityOptions{
+		StartToCloseTimeout: 15 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 2,
+		},
+	}
+	if s.Config.SessionTaskQueue != "" {
+		actOpts.TaskQueue = s.Config.SessionTaskQueue
+	}
+	readCtx := workflow.WithActivityOptions(ctx, actOpts)
+
+	var result activities.ResolveFileMentionsOutput
+	err := workflow.ExecuteActivity(readCtx, "ResolveFileMentions", activities.ResolveFileMentionsInput{
+		Cwd:          s.Config.Cwd,
+		Mentions:     parsed,
+		ExcludePaths: s.Config.Tools.ExcludePaths,
+	}).Get(ctx, &result)
+	if err != nil {
+		logger.Warn("Failed to resolve file mentions", "error", err)
+		return
+	}
+
+	for _, att := range result.Attachments {
+		_ = s.History.AddItem(models.ConversationItem{
+			Type:    models.ItemTypeUserMessage,
+			Content: att.Render(),
+			TurnID:  turnID,
+		})
+		logger.Info("Injected file mention", "path", att.Mention.Path, "error", att.Error)
+	}
+}
@@ -0,0 +1,167 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// fingerprint.go records what a session ran with — the resolved
+// instructions, tool specs, model and settings — and a hash of every LLM
+// request it sent, so two sessions whose outcomes are being compared can be
+// shown to have used identical configurations and prompts. Served by the
+// get_fingerprint query (CLI /fingerprint) and included in WorkflowResult.
+package workflow
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/tools"
+)
+
+// errNoFingerprint is returned by get_fingerprint before the session's
+// first LLM call has completed.
+var errNoFingerprint = errors.New("no LLM call has completed yet in this session")
+
+// SessionFingerprint identifies the configuration of a session, taken at
+// its first LLM call, and the requests it has sent since. Two sessions ran
+// with identical configurations when their ConfigHash matches, and sent
+// identical prompts when their TranscriptHash matches.
+type SessionFingerprint struct {
+	// ConfigHash covers Provider, Model, SettingsHash, Instructions and
+	// Tools.
+	ConfigHash string `json:"config_hash"`
+	Provider   string `json:"provider"`
+	Model      string `json:"model"`
+	// SettingsHash covers the session configuration other than the
+	// instructions and machine-local paths (cwd, codex home, task queue).
+	SettingsHash string            `json:"settings_hash"`
+	Instructions InstructionHashes `json:"instructions"`
+	// Tools maps each tool name to the hash of its spec.
+	Tools map[string]string `json:"tools"`
+
+	// Calls has one entry per LLM request, in order. TranscriptHash chains
+	// their hashes, so it changes if any request or their order differs.
+	Calls          []RequestFingerprint `json:"calls"`
+	TranscriptHash string               `json:"transcript_hash"`
+}
+
+// InstructionHashes are the hashes of the resolved instruction texts;
+// empty when a tier is not set.
+type InstructionHashes struct {
+	Base      string `json:"base,omitempty"`
+	Developer string `json:"developer,omitempty"`
+	User      string `json:"user,omitempty"`
+}
+
+// RequestFingerprint is the hash of one LLM request: the model, the full
+// instructions, the tool specs and the whole prompt history, whether or not
+// the call chained onto a previous response.
+type RequestFingerprint struct {
+	TurnID    string `json:"turn_id"`
+	Iteration int    `json:"iteration"`
+	Model     string `json:"model"` // "provider/model"
+	Hash      string `json:"hash"`
+}
+
+// newSessionFingerprint fingerprints cfg with the tool specs and user
+// instructions (persona included) of the first LLM call.
+func newSessionFingerprint(cfg models.SessionConfiguration, toolSpecs []tools.ToolSpec, userInstructions string) *SessionFingerprint {
+	fp := &SessionFingerprint{
+		Provider:     cfg.Model.Provider,
+		Model:        cfg.Model.Model,
+		SettingsHash: hashJSON(fingerprintSettings(cfg)),
+		Instructions: InstructionHashes{
+			Base:      hashText(cfg.BaseInstructions),
+			Developer: hashText(cfg.DeveloperInstructions),
+			User:      hashText(userInstructions),
+		},
+		Tools: make(map[string]string, len(toolSpecs)),
+	}
+	for _, spec := range toolSpecs {
+		fp.Tools[spec.Name] = hashJSON(spec)
+	}
+	fp.ConfigHash = hashJSON(struct {
+		Provider     string            `json:"provider"`
+		Model        string            `json:"model"`
+		Settings     string            `json:"settings"`
+		Instructions InstructionHashes `json:"instructions"`
+		Tools        map[string]string `json:"tools"`
+	}{fp.Provider, fp.Model, fp.SettingsHash, fp.Instructions, fp.Tools})
+	return fp
+}
+
+// fingerprintSettings returns cfg without the parts that are hashed on
+// their own (instructions) or that differ between machines running the
+// same configuration.
+func fingerprintSettings(cfg models.SessionConfiguration) models.SessionConfiguration {
+	cfg.BaseInstructions = ""
+	cfg.DeveloperInstructions = ""
+	cfg.UserInstructions = ""
+	cfg.CLIProjectDocs = ""
+	cfg.UserPersonalInstructions = ""
+	cfg.Cwd = ""
+	cfg.CodexHome = ""
+	cfg.MemoryDbPath = ""
+	cfg.MemoryRoot = ""
+	cfg.SessionTaskQueue = ""
+	cfg.SessionSource = ""
+	cfg.Report = nil
+	cfg.Remote = nil
+	return cfg
+}
+
+// recordRequestFingerprint adds the LLM request of turnID/iteration to the
+// session fingerprint, creating the fingerprint on the first call. history
+// is the full windowed prompt history, of which input.History may be only
+// the part sent after a previous response. Returns the request's hash.
+func (s *SessionState) recordRequestFingerprint(turnID string, iteration int, history []models.ConversationItem, input activities.LLMActivityInput) string {
+	if s.Fingerprint == nil {
+		s.Fingerprint = newSessionFingerprint(s.Config, input.ToolSpecs, input.UserInstructions)
+	}
+	hash := hashJSON(struct {
+		Model                 models.ModelConfig        `json:"model"`
+		BaseInstructions      string                    `json:"base_instructions"`
+		DeveloperInstructions string                    `json:"developer_instructions"`
+		UserInstructions      string                    `json:"user_instructions"`
+		ToolSpecs             []tools.ToolSpec          `json:"tool_specs"`
+		History               []models.ConversationItem `json:"history"`
+	}{input.ModelConfig, input.BaseInstructions, input.DeveloperInstructions, input.UserInstructions, input.ToolSpecs, history})
+
+	fp := s.Fingerprint
+	fp.Calls = append(fp.Calls, RequestFingerprint{
+		TurnID:    turnID,
+		Iteration: iteration,
+		Model:     modelRef(input.ModelConfig),
+		Hash:      hash,
+	})
+	fp.TranscriptHash = hashText(fp.TranscriptHash + hash)
+	return hash
+}
+
+// sessionFingerprint returns the fingerprint served by get_fingerprint.
+func (s *SessionState) sessionFingerprint() (SessionFingerprint, error) {
+	if s.Fingerprint == nil {
+		return SessionFingerprint{}, errNoFingerprint
+	}
+	return *s.Fingerprint, nil
+}
+
+// hashText returns the hex SHA-256 of text, or "" for empty text.
+func hashText(text string) string {
+	if text == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashJSON returns the hex SHA-256 of v's JSON encoding, which is
+// deterministic: struct fields keep their order and map keys are sorted.
+func hashJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,77 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/tools"
+)
+
+func testFingerprintConfig() models.SessionConfiguration {
+	return models.SessionConfiguration{
+		BaseInstructions: "You are a coding agent.",
+		Model:            models.ModelConfig{Provider: "openai", Model: "gpt-4o", Temperature: 0.2},
+		Cwd:              "/home/alice/repo",
+		CodexHome:        "/home/alice/.codex",
+	}
+}
+
+func TestNewSessionFingerprint_IgnoresMachineLocalPaths(t *testing.T) {
+	specs := []tools.ToolSpec{{Name: "shell_command", Description: "Run a command."}}
+	a := newSessionFingerprint(testFingerprintConfig(), specs, "")
+
+	cfg := testFingerprintConfig()
+	cfg.Cwd = "/ci/workspace"
+	cfg.CodexHome = "/ci/.codex"
+	b := newSessionFingerprint(cfg, specs, "")
+
+	assert.Equal(t, a.ConfigHash, b.ConfigHash)
+	assert.Equal(t, hashText("You are a coding agent."), a.Instructions.Base)
+	assert.Empty(t, a.Instructions.User)
+	assert.Contains(t, a.Tools, "shell_command")
+}
+
+func TestNewSessionFingerprint_DetectsDifferences(t *testing.T) {
+	specs := []tools.ToolSpec{{Name: "shell_command", Description: "Run a command."}}
+	base := newSessionFingerprint(testFingerprintConfig(), specs, "")
+
+	cfg := testFingerprintConfig()
+	cfg.Model.Temperature = 1
+	assert.NotEqual(t, base.ConfigHash, newSessionFingerprint(cfg, specs, "").ConfigHash, "settings")
+
+	assert.NotEqual(t, base.ConfigHash, newSessionFingerprint(testFingerprintConfig(), specs, "Be terse.").ConfigHash, "instructions")
+
+	other := []tools.ToolSpec{{Name: "shell_command", Description: "Run a shell command."}}
+	fp := newSessionFingerprint(testFingerprintConfig(), other, "")
+	assert.NotEqual(t, base.ConfigHash, fp.ConfigHash, "tool specs")
+	assert.NotEqual(t, base.Tools["shell_command"], fp.Tools["shell_command"])
+}
+
+func TestRecordRequestFingerprint_ChainsRequests(t *testing.T) {
+	first := []models.ConversationItem{{Type: models.ItemTypeUserMessage, Content: "fix the tests"}}
+	second := append(first, models.ConversationItem{Type: models.ItemTypeAssistantMessage, Content: "done"})
+	input := activities.LLMActivityInput{ModelConfig: models.ModelConfig{Provider: "openai", Model: "gpt-4o"}}
+
+	record := func(histories ...[]models.ConversationItem) *SessionFingerprint {
+		s := &SessionState{Config: testFingerprintConfig()}
+		for i, h := range histories {
+			s.recordRequestFingerprint("turn-1", i, h, input)
+		}
+		return s.Fingerprint
+	}
+
+	a := record(first, second)
+	assert.Len(t, a.Calls, 2)
+	assert.Equal(t, "openai/gpt-4o", a.Calls[0].Model)
+	assert.NotEqual(t, a.Calls[0].Hash, a.Calls[1].Hash)
+	assert.Equal(t, a.TranscriptHash, record(first, second).TranscriptHash)
+	assert.NotEqual(t, a.TranscriptHash, record(second, first).TranscriptHash)
+}
+
+func TestSessionFingerprint_NoneYet(t *testing.T) {
+	_, err := (&SessionState{}).sessionFingerprint()
+	assert.ErrorIs(t, err, errNoFingerprint)
+}
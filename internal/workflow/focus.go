@@ -0,0 +1,144 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// focus.go derives the agent's current focus for TurnStatus: the file or
+// command of the tool call that is running, and the plan step in progress.
+// Clients show it in their status area so someone watching a long turn sees
+// what the agent is doing without scrolling through tool output.
+package workflow
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// Focus actions: what a running tool call does with its target.
+const (
+	FocusRead   = "read"
+	FocusEdit   = "edit"
+	FocusRun    = "run"
+	FocusSearch = "search"
+)
+
+// focusTargetLen caps Focus.Target, in runes.
+const focusTargetLen = 200
+
+// Focus is what the agent is working on right now (TurnStatus.Focus).
+type Focus struct {
+	// Tool is the running tool call shown, the first of its batch that has
+	// not finished; empty outside tool execution.
+	Tool string `json:"tool,omitempty"`
+	// Action is FocusRead, FocusEdit, FocusRun or FocusSearch, or empty
+	// for tools that fit none of them.
+	Action string `json:"action,omitempty"`
+	// Target is the file path, command or search pattern of the call.
+	Target string `json:"target,omitempty"`
+	// OtherTools counts the other calls of the batch still running.
+	OtherTools int `json:"other_tools,omitempty"`
+
+	// PlanStep is the 1-based plan step in progress (0 when there is none)
+	// and PlanStepText its description.
+	PlanStep     int    `json:"plan_step,omitempty"`
+	PlanStepText string `json:"plan_step_text,omitempty"`
+}
+
+// focusActions maps tool names to their Focus action.
+var focusActions = map[string]string{
+	"read_file":     FocusRead,
+	"list_dir":      FocusRead,
+	"read_artifact": FocusRead,
+	"write_file":    FocusEdit,
+	"apply_patch":   FocusEdit,
+	"shell":         FocusRun,
+	"shell_command": FocusRun,
+	"exec_command":  FocusRun,
+	"write_stdin":   FocusRun,
+	"grep_files":    FocusSearch,
+	"web_search":    FocusSearch,
+}
+
+// toolFocus returns the focus for a batch of running calls: the first call
+// not marked done, with the count of the others. It returns nil once every
+// call is done.
+func toolFocus(calls []models.ConversationItem, done []bool) *Focus {
+	var f *Focus
+	for i, call := range calls {
+		if i < len(done) && done[i] {
+			continue
+		}
+		if f != nil {
+			f.OtherTools++
+			continue
+		}
+		f = &Focus{Tool: call.Name, Action: focusActions[call.Name], Target: truncateRunes(callTarget(call), focusTargetLen)}
+	}
+	return f
+}
+
+// callTarget returns the file, command or pattern a call works on.
+func callTarget(call models.ConversationItem) string {
+	var args map[string]interface{}
+	if err := json.Unmarshal([]byte(call.Arguments), &args); err != nil {
+		return ""
+	}
+	if paths := mutationPaths(call.Name, args, ""); len(paths) > 0 {
+		return paths[0]
+	}
+	for _, key := range []string{"command", "cmd", "file_path", "dir_path", "path", "pattern", "query"} {
+		switch v := args[key].(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case []interface{}:
+			// shell takes its command as an argv array.
+			if cmd := joinArgv(v); cmd != "" {
+				return cmd
+			}
+		}
+	}
+	return ""
+}
+
+// joinArgv joins the string elements of an argv array with spaces.
+func joinArgv(argv []interface{}) string {
+	parts := make([]string, 0, len(argv))
+	for _, a := range argv {
+		if s, ok := a.(string); ok {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// currentFocus combines the running tool call with the plan step in
+// progress: the step plan execution is on, or else the step update_plan
+// last marked in_progress. It returns nil when there is neither.
+func (s *SessionState) currentFocus(ctrl *LoopControl) *Focus {
+	var f Focus
+	if tf := ctrl.ToolFocus(); tf != nil {
+		f = *tf
+	}
+	if s.Plan != nil {
+		step := -1
+		if s.PlanExec != nil {
+			step = s.PlanExec.StepIndex
+		} else {
+			for i, ps := range s.Plan.Steps {
+				if ps.Status == PlanStepInProgress {
+					step = i
+					break
+				}
+			}
+		}
+		if step >= 0 && step < len(s.Plan.Steps) {
+			f.PlanStep = step + 1
+			f.PlanStepText = s.Plan.Steps[step].Step
+		}
+	}
+	if f == (Focus{}) {
+		return nil
+	}
+	return &f
+}
@@ -0,0 +1,61 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+func focusCall(name, args string) models.ConversationItem {
+	return models.ConversationItem{Type: models.ItemTypeFunctionCall, Name: name, Arguments: args}
+}
+
+func TestToolFocus_MovesToNextRunningCall(t *testing.T) {
+	calls := []models.ConversationItem{
+		focusCall("apply_patch", `{"input":"*** Begin Patch\n*** Update File: pkg/x.go\n@@\n-a\n+b\n*** End Patch"}`),
+		focusCall("shell", `{"command":["go","test","./..."]}`),
+		focusCall("grep_files", `{"pattern":"TODO"}`),
+	}
+	done := make([]bool, len(calls))
+
+	f := toolFocus(calls, done)
+	require.NotNil(t, f)
+	assert.Equal(t, Focus{Tool: "apply_patch", Action: FocusEdit, Target: "pkg/x.go", OtherTools: 2}, *f)
+
+	done[0] = true
+	f = toolFocus(calls, done)
+	require.NotNil(t, f)
+	assert.Equal(t, Focus{Tool: "shell", Action: FocusRun, Target: "go test ./...", OtherTools: 1}, *f)
+
+	done[1], done[2] = true, true
+	assert.Nil(t, toolFocus(calls, done))
+}
+
+func TestCallTarget(t *testing.T) {
+	assert.Equal(t, "a.txt", callTarget(focusCall("write_file", `{"path":"a.txt","content":"x"}`)))
+	assert.Equal(t, "/src/main.go", callTarget(focusCall("read_file", `{"file_path":"/src/main.go"}`)))
+	assert.Equal(t, "make build", callTarget(focusCall("exec_command", `{"cmd":"make build"}`)))
+	assert.Equal(t, "", callTarget(focusCall("request_user_input", `{"questions":[]}`)))
+	assert.Equal(t, "", callTarget(focusCall("shell", `not json`)))
+}
+
+func TestCurrentFocus_PlanStep(t *testing.T) {
+	ctrl := &LoopControl{}
+	s := &SessionState{}
+	assert.Nil(t, s.currentFocus(ctrl), "no tool running and no plan")
+
+	s.Plan = &PlanState{Steps: []PlanStep{
+		{Step: "Read the code", Status: PlanStepCompleted},
+		{Step: "Fix the bug", Status: PlanStepInProgress},
+	}}
+	ctrl.SetToolFocus(&Focus{Tool: "read_file", Action: FocusRead, Target: "a.go"})
+	assert.Equal(t, &Focus{Tool: "read_file", Action: FocusRead, Target: "a.go", PlanStep: 2, PlanStepText: "Fix the bug"}, s.currentFocus(ctrl))
+
+	// Plan execution decides the step, whatever the statuses say.
+	s.PlanExec = &PlanExecution{StepIndex: 0}
+	ctrl.ClearToolsInFlight()
+	assert.Equal(t, &Focus{PlanStep: 1, PlanStepText: "Read the code"}, s.currentFocus(ctrl))
+}
@@ -0,0 +1,200 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// git_preview.go implements the first phase of a two-phase apply for shell
+// commands that modify git state: before asking for approval of `git push`,
+// `git commit`, `git rebase` or `git merge`, the workflow runs a read-only
+// variant (a dry run, or the diff/log the command would act on) and attaches
+// the output to the pending approval. The real command runs only after the
+// user approves, as usual.
+package workflow
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/command_safety"
+)
+
+const (
+	// gitPreviewTimeout bounds a preview; push --dry-run talks to the remote.
+	gitPreviewTimeout = 60 * time.Second
+	// maxGitPreviewBytes caps the preview attached to an approval.
+	maxGitPreviewBytes = 4000
+)
+
+// gitPreviewSubcommands are the git subcommands that get a preview.
+var gitPreviewSubcommands = []string{"push", "commit", "rebase", "merge"}
+
+// gitPreviewCommand returns the read-only command that previews a git
+// state-changing shell call, or "" if the call has no preview.
+//
+//	git push origin main   → git push --dry-run origin main
+//	git commit -m msg      → git diff --cached --stat
+//	git commit -am msg     → git diff HEAD --stat
+//	git rebase main        → git log --oneline main..HEAD
+//	git merge feature      → git log --oneline HEAD..feature
+func gitPreviewCommand(toolName, arguments string) string {
+	fields := shellCommandFields(toolName, arguments)
+	idx, sub, ok := command_safety.FindGitSubcommand(fields, gitPreviewSubcommands)
+	if !ok {
+		return ""
+	}
+	global := fields[:idx]
+	rest := fields[idx+1:]
+	build := func(parts ...string) string {
+		return strings.Join(append(append([]string{}, global...), parts...), " ")
+	}
+
+	switch sub {
+	case "push":
+		if hasAnyFlag(rest, "--dry-run", "-n") {
+			return ""
+		}
+		return build(append([]string{"push", "--dry-run"}, rest...)...)
+	case "commit":
+		if hasAnyFlag(rest, "-a", "--all") || hasShortFlag(rest, 'a') {
+			return build("diff", "HEAD", "--stat")
+		}
+		return build("diff", "--cached", "--stat")
+	case "rebase":
+		if hasAnyFlag(rest, "--continue", "--abort", "--skip", "--quit") {
+			return ""
+		}
+		upstream := "@{upstream}"
+		if arg := firstPositional(rest); arg != "" {
+			upstream = arg
+		}
+		return build("log", "--oneline", upstream+"..HEAD")
+	case "merge":
+		if hasAnyFlag(rest, "--continue", "--abort", "--quit") {
+			return ""
+		}
+		branch := firstPositional(rest)
+		if branch == "" {
+			return ""
+		}
+		return build("log", "--oneline", "HEAD.."+branch)
+	}
+	return ""
+}
+
+// shellCommandFields splits a shell or shell_command call into words.
+// Returns nil for non-shell tools and for commands with shell syntax
+// (pipes, substitutions) that a word split would misread.
+func shellCommandFields(toolName, arguments string) []string {
+	var args map[string]interface{}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return nil
+	}
+	switch toolName {
+	case "shell_command":
+		cmd, _ := args["command"].(string)
+		if strings.ContainsAny(cmd, shellMetaChars) {
+			return nil
+		}
+		return strings.Fields(cmd)
+	case "shell":
+		arr, _ := args["command"].([]interface{})
+		fields := make([]string, 0, len(arr))
+		for _, v := range arr {
+			s, ok := v.(string)
+			if !ok || strings.ContainsAny(s, shellMetaChars) {
+				return nil
+			}
+			fields = append(fields, s)
+		}
+		return fields
+	}
+	return nil
+}
+
+// hasAnyFlag reports whether args contains any of flags exactly.
+func hasAnyFlag(args []string, flags ...string) bool {
+	for _, a := range args {
+		for _, f := range flags {
+			if a == f {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasShortFlag reports whether a combined short-flag argument ("-am")
+// includes flag.
+func hasShortFlag(args []string, flag byte) bool {
+	for _, a := range args {
+		if len(a) > 1 && a[0] == '-' && a[1] != '-' && strings.IndexByte(a[1:], flag) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// firstPositional returns the first argument that is not a flag.
+func firstPositional(args []string) string {
+	for _, a := range args {
+		if !strings.HasPrefix(a, "-") {
+			return a
+		}
+	}
+	return ""
+}
+
+// attachGitPreviews runs the preview command for each pending approval that
+// has one, in parallel, and stores the output on the approval. Preview
+// failures are shown as-is: a failing dry run is itself useful to the user.
+func (s *SessionState) attachGitPreviews(ctx workflow.Context, pending []PendingApproval) {
+	actOpts := workflow.ActivityOptions{
+		StartToCloseTimeout: gitPreviewTimeout,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 1,
+		},
+	}
+	if s.Config.SessionTaskQueue != "" {
+		actOpts.TaskQueue = s.Config.SessionTaskQueue
+	}
+	actCtx := workflow.WithActivityOptions(ctx, actOpts)
+
+	futures := make(map[int]workflow.Future)
+	for i := range pending {
+		cmd := gitPreviewCommand(pending[i].ToolName, pending[i].Arguments)
+		if cmd == "" {
+			continue
+		}
+		pending[i].PreviewCommand = cmd
+		futures[i] = workflow.ExecuteActivity(actCtx, "ExecuteTool", activities.ToolActivityInput{
+			CallID:       "preview-" + pending[i].CallID,
+			ToolName:     "shell_command",
+			Arguments:    map[string]interface{}{"command": cmd},
+			Cwd:          s.Config.Cwd,
+			ScratchID:    s.ConversationID,
+			ExcludePaths: s.Config.Tools.ExcludePaths,
+			Remote:       s.Config.Remote,
+		})
+	}
+	if len(futures) == 0 {
+		return
+	}
+	s.ScratchUsed = true
+
+	logger := workflow.GetLogger(ctx)
+	for i := range pending {
+		f, ok := futures[i]
+		if !ok {
+			continue
+		}
+		var out activities.ToolActivityOutput
+		if err := f.Get(ctx, &out); err != nil {
+			logger.Warn("Git preview failed", "command", pending[i].PreviewCommand, "error", err)
+			pending[i].Preview = "preview failed: " + err.Error()
+			continue
+		}
+		pending[i].Preview = truncate(strings.TrimRight(out.Content, "\n"), maxGitPreviewBytes)
+	}
+}
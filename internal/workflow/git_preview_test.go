@@ -0,0 +1,32 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGitPreviewCommand(t *testing.T) {
+	tests := []struct {
+		tool, args, want string
+	}{
+		{"shell_command", `{"command": "git push origin main"}`, "git push --dry-run origin main"},
+		{"shell_command", `{"command": "git -C repo push"}`, "git -C repo push --dry-run"},
+		{"shell_command", `{"command": "git push --dry-run"}`, ""},
+		{"shell_command", `{"command": "git commit -m fix"}`, "git diff --cached --stat"},
+		{"shell_command", `{"command": "git commit -am fix"}`, "git diff HEAD --stat"},
+		{"shell_command", `{"command": "git commit --all -m fix"}`, "git diff HEAD --stat"},
+		{"shell_command", `{"command": "git rebase main"}`, "git log --oneline main..HEAD"},
+		{"shell_command", `{"command": "git rebase -i"}`, "git log --oneline @{upstream}..HEAD"},
+		{"shell_command", `{"command": "git rebase --continue"}`, ""},
+		{"shell_command", `{"command": "git merge --no-ff feature"}`, "git log --oneline HEAD..feature"},
+		{"shell_command", `{"command": "git merge --abort"}`, ""},
+		{"shell", `{"command": ["git", "push", "origin", "dev"]}`, "git push --dry-run origin dev"},
+		{"shell_command", `{"command": "git status"}`, ""},
+		{"shell_command", `{"command": "git add . && git commit -m x"}`, ""},
+		{"write_file", `{"path": "a.go"}`, ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, gitPreviewCommand(tt.tool, tt.args), tt.args)
+	}
+}
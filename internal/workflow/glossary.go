@@ -0,0 +1,129 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// glossary.go gives the model the project glossary (.codex/glossary.md, see
+// instructions.ParseGlossary). The file is loaded once at session start;
+// before each LLM call the entries relevant to the files the session has
+// touched and to the latest user messages are selected within
+// Config.GlossaryBudget() tokens and added to the developer instructions.
+package workflow
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/instructions"
+	"github.com/mfateev/temporal-agent-harness/internal/mentions"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// glossaryTextMessages is how many of the latest user messages are searched
+// for glossary terms.
+const glossaryTextMessages = 3
+
+// loadGlossary loads the project glossary from the worker filesystem.
+// Non-fatal: the session runs without a glossary on failure.
+func (s *SessionState) loadGlossary(ctx workflow.Context) {
+	if s.Config.Cwd == "" || s.Config.GlossaryBudget() == 0 {
+		return
+	}
+	actOpts := workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 2,
+		},
+	}
+	if s.Config.SessionTaskQueue != "" {
+		actOpts.TaskQueue = s.Config.SessionTaskQueue
+	}
+	actCtx := workflow.WithActivityOptions(ctx, actOpts)
+
+	var out activities.LoadGlossaryOutput
+	err := workflow.ExecuteActivity(actCtx, "LoadGlossary", activities.LoadGlossaryInput{Cwd: s.Config.Cwd}).Get(ctx, &out)
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to load glossary, continuing without", "error", err)
+		return
+	}
+	s.setGlossary(out)
+}
+
+// setGlossary parses a loaded glossary file into the session.
+func (s *SessionState) setGlossary(out activities.LoadGlossaryOutput) {
+	s.Glossary = instructions.ParseGlossary(out.Content)
+	s.GlossaryRoot = out.Root
+}
+
+// glossaryInstructions returns the glossary block for the next LLM call, or
+// "" when the session has no glossary.
+func (s *SessionState) glossaryInstructions() string {
+	if len(s.Glossary) == 0 {
+		return ""
+	}
+	items, err := s.History.GetRawItems()
+	if err != nil {
+		return ""
+	}
+	selected, omitted := instructions.SelectGlossary(s.Glossary,
+		s.touchedPaths(items), latestUserText(items, glossaryTextMessages), s.Config.GlossaryBudget())
+	return instructions.FormatGlossary(selected, omitted)
+}
+
+// touchedPaths returns the paths the session's tool calls have read,
+// listed, searched or written, relative to GlossaryRoot with forward
+// slashes, sorted. Paths outside the root are left out.
+func (s *SessionState) touchedPaths(items []models.ConversationItem) []string {
+	seen := make(map[string]bool)
+	for _, item := range items {
+		if item.Type != models.ItemTypeFunctionCall {
+			continue
+		}
+		var args map[string]interface{}
+		if json.Unmarshal([]byte(item.Arguments), &args) != nil {
+			continue
+		}
+		paths := mutationPaths(item.Name, args, s.Config.Cwd)
+		for _, key := range []string{"file_path", "dir_path", "path"} {
+			if p, ok := args[key].(string); ok && p != "" {
+				paths = append(paths, p)
+			}
+		}
+		for _, p := range paths {
+			if !filepath.IsAbs(p) {
+				p = filepath.Join(s.Config.Cwd, p)
+			}
+			rel, err := filepath.Rel(s.GlossaryRoot, p)
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				continue
+			}
+			seen[filepath.ToSlash(rel)] = true
+		}
+	}
+	touched := make([]string, 0, len(seen))
+	for p := range seen {
+		touched = append(touched, p)
+	}
+	sort.Strings(touched)
+	return touched
+}
+
+// latestUserText joins the last n messages the user typed, leaving out
+// environment context and attached files.
+func latestUserText(items []models.ConversationItem, n int) string {
+	var texts []string
+	for i := len(items) - 1; i >= 0 && len(texts) < n; i-- {
+		item := items[i]
+		if item.Type != models.ItemTypeUserMessage ||
+			strings.HasPrefix(item.Content, "<environment_context>") ||
+			strings.HasPrefix(item.Content, mentions.ContextPrefix) {
+			continue
+		}
+		texts = append(texts, item.Content)
+	}
+	return strings.Join(texts, "\n")
+}
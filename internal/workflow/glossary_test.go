@@ -0,0 +1,45 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/history"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+func TestGlossaryInstructions_FollowTouchedFiles(t *testing.T) {
+	s := &SessionState{History: history.NewInMemoryHistory()}
+	s.Config.Cwd = "/repo/services"
+	s.setGlossary(activities.LoadGlossaryOutput{
+		Root:    "/repo",
+		Content: "- PSP: payment service provider.\n\n## Ledger\nThe accounting service.\nPaths: services/ledger/\n",
+	})
+	_ = s.History.AddItem(models.ConversationItem{Type: models.ItemTypeUserMessage, Content: "<environment_context>ledger</environment_context>"})
+	_ = s.History.AddItem(models.ConversationItem{Type: models.ItemTypeUserMessage, Content: "fix the rounding bug"})
+
+	dev := s.developerInstructionsForTurn()
+	assert.Contains(t, dev, "- PSP: payment service provider.")
+	assert.NotContains(t, dev, "Ledger", "scoped entry before its files are touched")
+	assert.Contains(t, dev, "1 more terms are defined")
+
+	_ = s.History.AddItem(models.ConversationItem{Type: models.ItemTypeFunctionCall, Name: "read_file", Arguments: `{"file_path":"ledger/post.go"}`})
+	_ = s.History.AddItem(models.ConversationItem{Type: models.ItemTypeFunctionCall, Name: "read_file", Arguments: `{"file_path":"/etc/hosts"}`})
+	assert.Equal(t, []string{"services/ledger/post.go"}, s.touchedPaths(mustRawItems(t, s)))
+	assert.Contains(t, s.developerInstructionsForTurn(), "- Ledger: The accounting service.")
+}
+
+func TestGlossaryInstructions_Disabled(t *testing.T) {
+	s := &SessionState{History: history.NewInMemoryHistory()}
+	s.Config.GlossaryMaxTokens = -1
+	s.setGlossary(activities.LoadGlossaryOutput{Root: "/repo", Content: "- PSP: payment service provider.\n"})
+	assert.NotContains(t, s.developerInstructionsForTurn(), "<glossary>")
+}
+
+func mustRawItems(t *testing.T, s *SessionState) []models.ConversationItem {
+	items, err := s.History.GetRawItems()
+	assert.NoError(t, err)
+	return items
+}
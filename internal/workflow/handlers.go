@@ -24,20 +24,34 @@ import (
 func (s *SessionState) buildTurnStatus(ctrl *LoopControl) TurnStatus {
 	turnCount, _ := s.History.GetTurnCount()
 	status := TurnStatus{
+		Version:                 ProtocolVersion,
 		Phase:                   ctrl.Phase(),
 		CurrentTurnID:           ctrl.CurrentTurnID(),
 		ToolsInFlight:           ctrl.ToolsInFlight(),
+		Focus:                   s.currentFocus(ctrl),
 		PendingApprovals:        ctrl.PendingApprovals(),
 		PendingEscalations:      ctrl.PendingEscalations(),
 		PendingUserInputRequest: ctrl.PendingUserInputReq(),
 		IterationCount:          s.IterationCount,
 		TotalTokens:             s.TotalTokens,
 		TotalCachedTokens:       s.TotalCachedTokens,
+		SuggestionTokens:        s.SuggestionTokens,
 		TurnCount:               turnCount,
 		WorkerVersion:           version.GitCommit,
 		Suggestion:              ctrl.Suggestion(),
 		Plan:                    s.Plan,
+		Todos:                   s.Todos,
+		DedupedAssistantItems:   s.DedupedAssistantItems,
+		UnavailableTools:        s.UnavailableTools,
+		PlanProgress:            s.planProgress(),
+		LastActivityAt:          s.LastActivityAt,
+		SessionCostUSD:          s.SessionCostUSD,
+		MaxSessionCostUSD:       s.Config.MaxSessionCostUSD,
+		CostCapPaused:           s.CostCapPaused,
+		LastTurnLatency:         s.LastTurnLatency,
+		Caller:                  s.Caller,
 	}
+	status.Latency = s.latencyResult()
 
 	// Per-turn token usage: copy as pointer if populated
 	if s.LastTokenUsage.TotalTokens > 0 {
@@ -59,7 +73,7 @@ func (s *SessionState) buildTurnStatus(ctrl *LoopControl) TurnStatus {
 		status.ContextWindowRemaining = pct
 	}
 
-	// RateLimitSnapshot stays nil — Go SDKs don't expose HTTP response headers yet.
+	status.RateLimitSnapshot = s.RateLimits
 
 	// Populate child agent summaries from AgentControl
 	if s.AgentCtl != nil {
@@ -78,11 +92,16 @@ func (s *SessionState) buildTurnStatus(ctrl *LoopControl) TurnStatus {
 // registerHandlers registers query and update handlers on the workflow.
 func (s *SessionState) registerHandlers(ctx workflow.Context, ctrl *LoopControl) {
 	logger := workflow.GetLogger(ctx)
+	ctrl.SetEventSink(func(e SessionEvent) { s.recordEvent(ctx, e) })
 
 	// Query: get_conversation_items
 	// Maps to: Codex ContextManager::raw_items()
 	err := workflow.SetQueryHandler(ctx, QueryGetConversationItems, func() ([]models.ConversationItem, error) {
-		return s.History.GetRawItems()
+		items, err := s.History.GetRawItems()
+		if err != nil {
+			return nil, err
+		}
+		return itemsForClient(items, ProtocolVersion), nil
 	})
 	if err != nil {
 		logger.Error("Failed to register get_conversation_items query handler", "error", err)
@@ -105,7 +124,20 @@ func (s *SessionState) registerHandlers(ctx workflow.Context, ctrl *LoopControl)
 		ctx,
 		UpdateUserInput,
 		func(ctx workflow.Context, input UserInput) (StateUpdateResponse, error) {
+			// A retried input returns the turn it already started.
+			if prev, ok := s.acceptedUpdate(input.IdempotencyKey); ok {
+				allItems, _ := s.History.GetRawItems()
+				return StateUpdateResponse{
+					TurnID:   prev.TurnID,
+					Items:    itemsForClient(allItems, input.Version),
+					Status:   s.buildTurnStatus(ctrl),
+					Replayed: true,
+				}, nil
+			}
 			turnID := s.nextTurnID()
+			// Recorded before anything yields, so a duplicate delivered
+			// while this handler waits on an activity is still caught.
+			s.rememberUpdate(input.IdempotencyKey, turnID)
 
 			// Add TurnStarted marker
 			if err := s.History.AddItem(models.ConversationItem{
@@ -125,28 +157,42 @@ func (s *SessionState) registerHandlers(ctx workflow.Context, ctrl *LoopControl)
 				return StateUpdateResponse{}, fmt.Errorf("failed to add user message: %w", err)
 			}
 			ctrl.NotifyItemAdded()
+			logExternalRequest(ctrl, turnID, input.Caller, "Message sent")
 
 			// Inject skill content for any $skill-name mentions
 			s.injectSkillMentions(ctx, input.Content, turnID)
 
+			// Attach file contents for any @path mentions
+			s.injectFileMentions(ctx, input.Content, turnID)
+
 			ctrl.SetPendingUserInput(turnID)
 
 			// Build full snapshot for the caller
 			allItems, _ := s.History.GetRawItems()
 			return StateUpdateResponse{
 				TurnID: turnID,
-				Items:  allItems,
+				Items:  itemsForClient(allItems, input.Version),
 				Status: s.buildTurnStatus(ctrl),
 			}, nil
 		},
 		workflow.UpdateHandlerOptions{
 			Validator: func(ctx workflow.Context, input UserInput) error {
+				if err := checkProtocolVersion("UserInput", input.Version); err != nil {
+					return err
+				}
+				if _, ok := s.acceptedUpdate(input.IdempotencyKey); ok {
+					return nil // replay of an accepted input
+				}
 				if input.Content == "" {
 					return fmt.Errorf("content must not be empty")
 				}
 				if ctrl.IsShutdown() {
 					return fmt.Errorf("session is shutting down")
 				}
+				if s.CostCapPaused {
+					return fmt.Errorf("session paused: cost cap of $%.2f reached; use /resume --raise-cap to continue",
+						s.Config.MaxSessionCostUSD)
+				}
 				return nil
 			},
 		},
@@ -161,14 +207,21 @@ func (s *SessionState) registerHandlers(ctx workflow.Context, ctrl *LoopControl)
 		ctx,
 		UpdateInterrupt,
 		func(ctx workflow.Context, req InterruptRequest) (InterruptResponse, error) {
-			ctrl.SetInterrupted()
+			mode := req.Mode
+			if mode == "" {
+				mode = InterruptModeSoft
+			}
+			alreadyInterrupted := ctrl.IsInterrupted()
+			ctrl.SetInterruptedMode(mode)
 
-			// Add TurnComplete marker for interrupted turn
-			if ctrl.CurrentTurnID() != "" {
+			// Add TurnComplete marker for interrupted turn, recording the mode.
+			// A hard interrupt after a soft one only upgrades cancellation.
+			if ctrl.CurrentTurnID() != "" && !alreadyInterrupted {
 				_ = s.History.AddItem(models.ConversationItem{
 					Type:    models.ItemTypeTurnComplete,
 					TurnID:  ctrl.CurrentTurnID(),
-					Content: "interrupted",
+					Content: mode.TurnCompleteContent(),
+					Model:   s.turnModel,
 				})
 				ctrl.NotifyItemAdded()
 			}
@@ -180,7 +233,12 @@ func (s *SessionState) registerHandlers(ctx workflow.Context, ctrl *LoopControl)
 				if ctrl.IsShutdown() {
 					return fmt.Errorf("session is shutting down")
 				}
-				return nil
+				switch req.Mode {
+				case "", InterruptModeSoft, InterruptModeHard:
+					return nil
+				default:
+					return fmt.Errorf("invalid interrupt mode %q (must be soft or hard)", req.Mode)
+				}
 			},
 		},
 	)
@@ -194,6 +252,7 @@ func (s *SessionState) registerHandlers(ctx workflow.Context, ctrl *LoopControl)
 		ctx,
 		UpdateShutdown,
 		func(ctx workflow.Context, req ShutdownRequest) (ShutdownResponse, error) {
+			logExternalRequest(ctrl, "", req.Caller, "Session ended")
 			ctrl.SetShutdown()
 			return ShutdownResponse{Acknowledged: true}, nil
 		},
@@ -216,34 +275,8 @@ func (s *SessionState) registerHandlers(ctx workflow.Context, ctrl *LoopControl)
 		ctx,
 		UpdateModel,
 		func(ctx workflow.Context, req UpdateModelRequest) (UpdateModelResponse, error) {
-			// Save previous model info before overwriting.
-			s.PreviousModel = s.Config.Model.Model
-			s.PreviousContextWindow = s.Config.Model.ContextWindow
-
-			// Apply new provider/model.
-			s.Config.Model.Provider = req.Provider
-			s.Config.Model.Model = req.Model
-
-			// Re-resolve the model profile so ContextWindow, Temperature,
-			// MaxTokens reflect the new model's defaults from the registry.
-			s.resolveProfile()
-
-			// If the caller supplied an explicit context window, override the profile.
-			if req.ContextWindow > 0 {
-				s.Config.Model.ContextWindow = req.ContextWindow
-			}
-
-			// Validate reasoning effort against new model's supported efforts.
-			s.validateReasoningEffortForProfile()
-
-			// Reset response chaining and incremental history tracking.
-			s.LastResponseID = ""
-			s.lastSentHistoryLen = 0
-
-			// Flag for maybeCompactBeforeLLM to inject a model-switch message
-			// and trigger proactive compaction if needed.
-			s.modelSwitched = true
-
+			s.switchModel(req.Provider, req.Model, req.ContextWindow, "")
+			ctrl.SupersedeContext("the model was switched to " + req.Model)
 			return UpdateModelResponse{Acknowledged: true}, nil
 		},
 		workflow.UpdateHandlerOptions{
@@ -273,6 +306,7 @@ func (s *SessionState) registerHandlers(ctx workflow.Context, ctrl *LoopControl)
 		func(ctx workflow.Context, req UpdatePersonalityRequest) (UpdatePersonalityResponse, error) {
 			s.Config.Personality = req.Personality
 			s.rebuildInstructions()
+			ctrl.SupersedeContext("the session instructions changed")
 			return UpdatePersonalityResponse{Acknowledged: true}, nil
 		},
 		workflow.UpdateHandlerOptions{
@@ -288,6 +322,58 @@ func (s *SessionState) registerHandlers(ctx workflow.Context, ctrl *LoopControl)
 		logger.Error("Failed to register update_personality update handler", "error", err)
 	}
 
+	// Update: update_persona
+	// Switches the persona preset appended to the user instructions.
+	err = workflow.SetUpdateHandlerWithOptions(
+		ctx,
+		UpdatePersona,
+		func(ctx workflow.Context, req UpdatePersonaRequest) (UpdatePersonaResponse, error) {
+			s.Config.Persona = req.Persona
+			if s.Config.Persona == models.PersonaNone {
+				s.Config.Persona = ""
+			}
+			ctrl.SupersedeContext("the session instructions changed")
+			return UpdatePersonaResponse{Acknowledged: true}, nil
+		},
+		workflow.UpdateHandlerOptions{
+			Validator: func(ctx workflow.Context, req UpdatePersonaRequest) error {
+				if ctrl.IsShutdown() {
+					return fmt.Errorf("session is shutting down")
+				}
+				return models.ValidatePersona(req.Persona)
+			},
+		},
+	)
+	if err != nil {
+		logger.Error("Failed to register update_persona update handler", "error", err)
+	}
+
+	// Update: set_trivial_routing
+	// Turns routing of trivial turns to the cheap model on or off for this
+	// session (see routing.go).
+	err = workflow.SetUpdateHandlerWithOptions(
+		ctx,
+		UpdateTrivialRouting,
+		func(ctx workflow.Context, req SetTrivialRoutingRequest) (SetTrivialRoutingResponse, error) {
+			s.TrivialRoutingOff = !req.Enabled
+			return SetTrivialRoutingResponse{Acknowledged: true}, nil
+		},
+		workflow.UpdateHandlerOptions{
+			Validator: func(ctx workflow.Context, req SetTrivialRoutingRequest) error {
+				if ctrl.IsShutdown() {
+					return fmt.Errorf("session is shutting down")
+				}
+				if _, _, ok := s.Config.TrivialRouting.Target(); !ok {
+					return fmt.Errorf("trivial-turn routing is not configured; set [trivial_routing] model in config.toml")
+				}
+				return nil
+			},
+		},
+	)
+	if err != nil {
+		logger.Error("Failed to register set_trivial_routing update handler", "error", err)
+	}
+
 	// Update: set_session_name
 	// Allows the CLI to set a user-friendly name for the session.
 	err = workflow.SetUpdateHandlerWithOptions(
@@ -310,6 +396,30 @@ func (s *SessionState) registerHandlers(ctx workflow.Context, ctrl *LoopControl)
 		logger.Error("Failed to register set_session_name update handler", "error", err)
 	}
 
+	// Update: update_todo
+	// Allows the CLI /todo command to manage the session TODO list.
+	err = workflow.SetUpdateHandlerWithOptions(
+		ctx,
+		UpdateTodo,
+		func(ctx workflow.Context, req UpdateTodoRequest) (UpdateTodoResponse, error) {
+			if err := s.applyTodo(req, "user"); err != nil {
+				return UpdateTodoResponse{}, err
+			}
+			return UpdateTodoResponse{Todos: s.Todos}, nil
+		},
+		workflow.UpdateHandlerOptions{
+			Validator: func(ctx workflow.Context, req UpdateTodoRequest) error {
+				if ctrl.IsShutdown() {
+					return fmt.Errorf("session is shutting down")
+				}
+				return validateTodoRequest(req)
+			},
+		},
+	)
+	if err != nil {
+		logger.Error("Failed to register update_todo update handler", "error", err)
+	}
+
 	// Update: update_reasoning_effort
 	// Allows the CLI to change the reasoning effort level for reasoning models.
 	err = workflow.SetUpdateHandlerWithOptions(
@@ -409,6 +519,9 @@ func (s *SessionState) registerHandlers(ctx workflow.Context, ctrl *LoopControl)
 				if mode != models.ApprovalUnlessTrusted && mode != models.ApprovalNever {
 					return fmt.Errorf("invalid approval mode: %s (must be 'unless-trusted' or 'never')", req.ApprovalMode)
 				}
+				if s.Config.Remote != nil && mode != models.ApprovalUnlessTrusted {
+					return fmt.Errorf("remote sessions always use 'unless-trusted' approval")
+				}
 				if ctrl.IsShutdown() {
 					return fmt.Errorf("session is shutting down")
 				}
@@ -426,15 +539,25 @@ func (s *SessionState) registerHandlers(ctx workflow.Context, ctrl *LoopControl)
 		ctx,
 		UpdateApprovalResponse,
 		func(ctx workflow.Context, resp ApprovalResponse) (ApprovalResponseAck, error) {
+			if _, ok := s.acceptedUpdate(resp.IdempotencyKey); ok {
+				return ApprovalResponseAck{}, nil
+			}
+			s.rememberUpdate(resp.IdempotencyKey, ctrl.CurrentTurnID())
 			ctrl.DeliverApproval(resp)
 			return ApprovalResponseAck{}, nil
 		},
 		workflow.UpdateHandlerOptions{
 			Validator: func(ctx workflow.Context, resp ApprovalResponse) error {
+				if err := checkProtocolVersion("ApprovalResponse", resp.Version); err != nil {
+					return err
+				}
+				if _, ok := s.acceptedUpdate(resp.IdempotencyKey); ok {
+					return nil // replay of an accepted response
+				}
 				if ctrl.Phase() != PhaseApprovalPending {
 					return fmt.Errorf("no approval pending")
 				}
-				return nil
+				return checkApprovalResponseCurrent(ctrl, resp)
 			},
 		},
 	)
@@ -453,6 +576,9 @@ func (s *SessionState) registerHandlers(ctx workflow.Context, ctrl *LoopControl)
 		},
 		workflow.UpdateHandlerOptions{
 			Validator: func(ctx workflow.Context, resp EscalationResponse) error {
+				if err := checkProtocolVersion("EscalationResponse", resp.Version); err != nil {
+					return err
+				}
 				if ctrl.Phase() != PhaseEscalationPending {
 					return fmt.Errorf("no escalation pending")
 				}
@@ -584,6 +710,87 @@ func (s *SessionState) registerHandlers(ctx workflow.Context, ctrl *LoopControl)
 		logger.Error("Failed to register plan_request update handler", "error", err)
 	}
 
+	// Update: handoff
+	// Returns the brief a new executor session is started with from this
+	// read-only planner session. The executor links back via handoff_started.
+	err = workflow.SetUpdateHandlerWithOptions(
+		ctx,
+		UpdateHandoff,
+		func(ctx workflow.Context, req HandoffRequest) (HandoffBrief, error) {
+			return s.handoffBrief(req.Note)
+		},
+		workflow.UpdateHandlerOptions{
+			Validator: func(ctx workflow.Context, req HandoffRequest) error {
+				if !s.isReadOnlySession() {
+					return fmt.Errorf("handoff is only available from a read-only planner session")
+				}
+				if ctrl.Phase() != PhaseWaitingForInput {
+					return fmt.Errorf("the current turn has not finished")
+				}
+				if ctrl.IsShutdown() {
+					return fmt.Errorf("session is shutting down")
+				}
+				return nil
+			},
+		},
+	)
+	if err != nil {
+		logger.Error("Failed to register handoff update handler", "error", err)
+	}
+
+	// Update: execute_plan
+	// Starts or resumes step-by-step execution of the approved plan. Returns
+	// the same snapshot as user_input so the CLI can follow the step turn.
+	err = workflow.SetUpdateHandlerWithOptions(
+		ctx,
+		UpdateExecutePlan,
+		func(ctx workflow.Context, req ExecutePlanRequest) (StateUpdateResponse, error) {
+			turnID, err := s.startPlanExecution(ctrl, req)
+			if err != nil {
+				return StateUpdateResponse{}, err
+			}
+			allItems, _ := s.History.GetRawItems()
+			return StateUpdateResponse{
+				TurnID: turnID,
+				Items:  itemsForClient(allItems, ProtocolVersion),
+				Status: s.buildTurnStatus(ctrl),
+			}, nil
+		},
+		workflow.UpdateHandlerOptions{
+			Validator: func(ctx workflow.Context, req ExecutePlanRequest) error {
+				return s.validateExecutePlan(ctrl, req)
+			},
+		},
+	)
+	if err != nil {
+		logger.Error("Failed to register execute_plan update handler", "error", err)
+	}
+
+	// Update: raise_cost_cap
+	// Lifts the cost-cap pause and resumes the paused work in a new turn.
+	// Returns the same snapshot as user_input so the CLI can follow it.
+	err = workflow.SetUpdateHandlerWithOptions(
+		ctx,
+		UpdateRaiseCostCap,
+		func(ctx workflow.Context, req RaiseCostCapRequest) (StateUpdateResponse, error) {
+			turnID := s.raiseCostCap(ctrl, req)
+			allItems, _ := s.History.GetRawItems()
+			return StateUpdateResponse{
+				TurnID: turnID,
+				Items:  itemsForClient(allItems, ProtocolVersion),
+				Status: s.buildTurnStatus(ctrl),
+			}, nil
+		},
+		workflow.UpdateHandlerOptions{
+			Validator: func(ctx workflow.Context, req RaiseCostCapRequest) error {
+				return s.validateRaiseCostCap(ctrl, req)
+			},
+		},
+	)
+	if err != nil {
+		logger.Error("Failed to register raise_cost_cap update handler", "error", err)
+	}
+
 	// Update: get_state_update
 	// Blocking long-poll Update that replaces the CLI's query-based polling loop.
 	// Sleeps via workflow.Await until state changes, then returns delta items +
@@ -599,7 +806,7 @@ func (s *SessionState) registerHandlers(ctx workflow.Context, ctrl *LoopControl)
 			if len(items) > 0 || compacted || ctrl.Phase() != req.SincePhase || ctrl.IsShutdown() || ctrl.IsDraining() {
 				return StateUpdateResponse{
 					TurnID:    ctrl.CurrentTurnID(),
-					Items:     items,
+					Items:     itemsForClient(items, req.Version),
 					Status:    s.buildTurnStatus(ctrl),
 					Compacted: compacted,
 					Completed: ctrl.IsShutdown(),
@@ -618,7 +825,7 @@ func (s *SessionState) registerHandlers(ctx workflow.Context, ctrl *LoopControl)
 			items, compacted, _ = s.History.GetItemsSince(req.SinceSeq)
 			return StateUpdateResponse{
 				TurnID:    ctrl.CurrentTurnID(),
-				Items:     items,
+				Items:     itemsForClient(items, req.Version),
 				Status:    s.buildTurnStatus(ctrl),
 				Compacted: compacted,
 				Completed: ctrl.IsShutdown(),
@@ -647,6 +854,52 @@ func (s *SessionState) registerHandlers(ctx workflow.Context, ctrl *LoopControl)
 		logger.Error("Failed to register get_mcp_tools query handler", "error", err)
 	}
 
+	// Query: get_events
+	// Returns session events after the given sequence (-1 for all) for
+	// external UIs that render a full session timeline.
+	err = workflow.SetQueryHandler(ctx, QueryGetEvents, func(afterSeq int64) (GetEventsResponse, error) {
+		return s.EventLog.since(afterSeq), nil
+	})
+	if err != nil {
+		logger.Error("Failed to register get_events query handler", "error", err)
+	}
+
+	// Query: get_audit_log
+	// Returns the audit log of mutating tool calls for `tcx audit export`.
+	err = workflow.SetQueryHandler(ctx, QueryGetAuditLog, func() (AuditLog, error) {
+		return s.AuditLog, nil
+	})
+	if err != nil {
+		logger.Error("Failed to register get_audit_log query handler", "error", err)
+	}
+
+	// Query: get_tool_stats
+	// Returns per-tool call statistics for the /stats CLI command.
+	err = workflow.SetQueryHandler(ctx, QueryGetToolStats, func() ([]ToolStatSummary, error) {
+		return s.toolStatSummaries(), nil
+	})
+	if err != nil {
+		logger.Error("Failed to register get_tool_stats query handler", "error", err)
+	}
+
+	// Query: get_last_prompt
+	// Returns the prompt snapshot of the last LLM call for the /why CLI command.
+	err = workflow.SetQueryHandler(ctx, QueryGetLastPrompt, func() (PromptSnapshot, error) {
+		return s.lastPromptSnapshot()
+	})
+	if err != nil {
+		logger.Error("Failed to register get_last_prompt query handler", "error", err)
+	}
+
+	// Query: get_fingerprint
+	// Returns the session fingerprint for the /fingerprint CLI command.
+	err = workflow.SetQueryHandler(ctx, QueryGetFingerprint, func() (SessionFingerprint, error) {
+		return s.sessionFingerprint()
+	})
+	if err != nil {
+		logger.Error("Failed to register get_fingerprint query handler", "error", err)
+	}
+
 	// Update: list_exec_sessions
 	// Executes a local activity to list exec sessions from the worker's store.
 	err = workflow.SetUpdateHandlerWithOptions(
@@ -748,6 +1001,18 @@ func (s *SessionState) registerHandlers(ctx workflow.Context, ctrl *LoopControl)
 		}
 	})
 
+	// handoff_started — an executor session started from this planner.
+	handoffCh := workflow.GetSignalChannel(ctx, SignalHandoffStarted)
+	workflow.Go(ctx, func(gCtx workflow.Context) {
+		for {
+			var signal HandoffStartedSignal
+			if !handoffCh.Receive(gCtx, &signal) {
+				return
+			}
+			s.recordHandoffChild(gCtx, ctrl, signal.WorkflowID)
+		}
+	})
+
 	// agent_shutdown — requests this child workflow to shut down.
 	agentShutdownCh := workflow.GetSignalChannel(ctx, SignalAgentShutdown)
 	workflow.Go(ctx, func(gCtx workflow.Context) {
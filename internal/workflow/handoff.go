@@ -0,0 +1,179 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// handoff.go hands a finished plan from a read-only planner session to a
+// new executor session. The handoff update returns a brief — the plan and
+// the files the planner explored — that the CLI starts the executor with;
+// the executor then signals the planner so both record the link
+// (HandoffParentID / HandoffChildID, and search attributes when enabled).
+package workflow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// maxHandoffFiles caps the explored files listed in a handoff brief.
+const maxHandoffFiles = 50
+
+// errNoHandoffPlan is returned by the handoff update when the planner has
+// not produced a plan yet.
+var errNoHandoffPlan = errors.New("nothing to hand off: the session has not produced a plan yet")
+
+// exploreArgKeys maps the read-only tools to the argument naming the path
+// they explored.
+var exploreArgKeys = map[string]string{
+	"read_file":  "file_path",
+	"list_dir":   "dir_path",
+	"grep_files": "path",
+}
+
+// HandoffRequest is the payload for the handoff Update.
+type HandoffRequest struct {
+	// Note is added to the executor's first message, e.g. what to do first.
+	Note string `json:"note,omitempty"`
+}
+
+// HandoffBrief is returned by the handoff Update: what the executor
+// session is seeded with.
+type HandoffBrief struct {
+	// PlannerWorkflowID is the planner session, recorded by the executor
+	// as its HandoffParentID.
+	PlannerWorkflowID string   `json:"planner_workflow_id"`
+	Plan              string   `json:"plan"`
+	ExploredFiles     []string `json:"explored_files,omitempty"`
+	// Message is the executor's first user message.
+	Message string `json:"message"`
+}
+
+// HandoffStartedSignal is sent by an executor session to its planner once
+// it has started.
+type HandoffStartedSignal struct {
+	WorkflowID string `json:"workflow_id"`
+}
+
+// isReadOnlySession reports whether the session cannot edit files: it runs
+// in a read-only sandbox or, like a planner, has no file-editing tools.
+func (s *SessionState) isReadOnlySession() bool {
+	if s.Config.Permissions.SandboxMode == "read-only" {
+		return true
+	}
+	for _, spec := range s.ToolSpecs {
+		if spec.Name == "write_file" || spec.Name == "apply_patch" {
+			return false
+		}
+	}
+	return true
+}
+
+// handoffBrief builds the brief for an executor session from the planner's
+// final message (or its update_plan steps) and the paths its read-only
+// tools explored.
+func (s *SessionState) handoffBrief(note string) (HandoffBrief, error) {
+	items, err := s.History.GetRawItems()
+	if err != nil {
+		return HandoffBrief{}, err
+	}
+	plan := extractFinalMessage(items)
+	if plan == "" && s.Plan != nil {
+		var b strings.Builder
+		for i, step := range s.Plan.Steps {
+			fmt.Fprintf(&b, "%d. %s\n", i+1, step.Step)
+		}
+		plan = strings.TrimSpace(b.String())
+	}
+	if plan == "" {
+		return HandoffBrief{}, errNoHandoffPlan
+	}
+
+	brief := HandoffBrief{
+		PlannerWorkflowID: s.ConversationID,
+		Plan:              plan,
+		ExploredFiles:     exploredFiles(items),
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Implement the following plan, handed off from planning session %s:\n\n%s\n", s.ConversationID, plan)
+	if len(brief.ExploredFiles) > 0 {
+		b.WriteString("\nFiles explored while planning:\n")
+		for _, path := range brief.ExploredFiles {
+			b.WriteString("- " + path + "\n")
+		}
+	}
+	if note = strings.TrimSpace(note); note != "" {
+		b.WriteString("\n" + note + "\n")
+	}
+	brief.Message = strings.TrimRight(b.String(), "\n")
+	return brief, nil
+}
+
+// exploredFiles returns the paths the read-only tools were called on, in
+// the order first explored, up to maxHandoffFiles.
+func exploredFiles(items []models.ConversationItem) []string {
+	var files []string
+	seen := make(map[string]bool)
+	for _, item := range items {
+		key, ok := exploreArgKeys[item.Name]
+		if item.Type != models.ItemTypeFunctionCall || !ok {
+			continue
+		}
+		var args map[string]interface{}
+		if json.Unmarshal([]byte(item.Arguments), &args) != nil {
+			continue
+		}
+		path, _ := args[key].(string)
+		if path == "" || seen[path] {
+			continue
+		}
+		seen[path] = true
+		files = append(files, path)
+		if len(files) == maxHandoffFiles {
+			break
+		}
+	}
+	return files
+}
+
+// announceHandoff tells the planner session this executor was handed off
+// from that it has started. Best-effort: the planner may have ended.
+func (s *SessionState) announceHandoff(ctx workflow.Context) {
+	err := workflow.SignalExternalWorkflow(ctx, s.HandoffParentID, "", SignalHandoffStarted,
+		HandoffStartedSignal{WorkflowID: s.ConversationID}).Get(ctx, nil)
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to signal handoff parent", "parent", s.HandoffParentID, "error", err)
+	}
+	s.upsertHandoffSearchAttributes(ctx)
+}
+
+// recordHandoffChild records the executor session a handoff started.
+func (s *SessionState) recordHandoffChild(ctx workflow.Context, ctrl *LoopControl, workflowID string) {
+	s.HandoffChildID = workflowID
+	s.addNotice(ctrl, fmt.Sprintf("Plan handed off to executor session %s.", workflowID))
+	s.upsertHandoffSearchAttributes(ctx)
+}
+
+// upsertHandoffSearchAttributes publishes the handoff links when search
+// attributes are enabled (see search_attributes.go).
+func (s *SessionState) upsertHandoffSearchAttributes(ctx workflow.Context) {
+	if !s.Config.MetricsSearchAttributes {
+		return
+	}
+	var updates []temporal.SearchAttributeUpdate
+	if s.HandoffParentID != "" {
+		updates = append(updates, SearchAttrHandoffParent.ValueSet(s.HandoffParentID))
+	}
+	if s.HandoffChildID != "" {
+		updates = append(updates, SearchAttrHandoffChild.ValueSet(s.HandoffChildID))
+	}
+	if len(updates) == 0 {
+		return
+	}
+	if err := workflow.UpsertTypedSearchAttributes(ctx, updates...); err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to upsert handoff search attributes", "error", err)
+	}
+}
@@ -0,0 +1,50 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/history"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/tools"
+)
+
+func TestExploredFiles_InOrderWithoutDuplicates(t *testing.T) {
+	call := func(name, args string) models.ConversationItem {
+		return models.ConversationItem{Type: models.ItemTypeFunctionCall, Name: name, Arguments: args}
+	}
+	items := []models.ConversationItem{
+		call("list_dir", `{"dir_path": "/repo"}`),
+		call("read_file", `{"file_path": "/repo/main.go"}`),
+		call("shell_command", `{"command": "cat /repo/go.mod"}`),
+		call("grep_files", `{"pattern": "flag", "path": "/repo/cmd"}`),
+		call("read_file", `{"file_path": "/repo/main.go"}`),
+		call("read_file", `not json`),
+	}
+
+	assert.Equal(t, []string{"/repo", "/repo/main.go", "/repo/cmd"}, exploredFiles(items))
+}
+
+func TestHandoffBrief_FallsBackToPlanSteps(t *testing.T) {
+	s := &SessionState{ConversationID: "planner-1", History: history.NewInMemoryHistory()}
+	_, err := s.handoffBrief("")
+	assert.ErrorIs(t, err, errNoHandoffPlan)
+
+	s.Plan = &PlanState{Steps: []PlanStep{{Step: "Add the flag"}, {Step: "Test it"}}}
+	brief, err := s.handoffBrief("")
+	require.NoError(t, err)
+	assert.Equal(t, "1. Add the flag\n2. Test it", brief.Plan)
+}
+
+func TestIsReadOnlySession(t *testing.T) {
+	s := &SessionState{ToolSpecs: []tools.ToolSpec{{Name: "read_file"}, {Name: "shell_command"}}}
+	assert.True(t, s.isReadOnlySession())
+
+	s.ToolSpecs = append(s.ToolSpecs, tools.ToolSpec{Name: "apply_patch"})
+	assert.False(t, s.isReadOnlySession())
+
+	s.Config.Permissions.SandboxMode = "read-only"
+	assert.True(t, s.isReadOnlySession())
+}
@@ -55,6 +55,25 @@ type CLIOverrides struct {
 
 	// MemoryDbPath overrides the default memory SQLite DB path.
 	MemoryDbPath string `json:"memory_db_path,omitempty"`
+
+	// SessionType selects an ask (Q&A, no tools) session when set to "ask".
+	SessionType models.SessionType `json:"session_type,omitempty"`
+
+	// MaxSessionCostUSD caps the estimated LLM spend of each session.
+	MaxSessionCostUSD float64 `json:"max_session_cost_usd,omitempty"`
+
+	// Timezone and Locale are the user's IANA timezone and BCP 47 locale,
+	// detected by the CLI when not configured.
+	Timezone string `json:"timezone,omitempty"`
+	Locale   string `json:"locale,omitempty"`
+
+	// Persona is the persona preset resolved by the CLI from --persona and
+	// its config.toml. "none" clears a harness-level persona.
+	Persona string `json:"persona,omitempty"`
+
+	// Remote runs the session's tools on a remote machine over SSH
+	// (--remote); it replaces [remote] of config.toml.
+	Remote *models.RemoteConfig `json:"remote,omitempty"`
 }
 
 // HarnessWorkflowInput is the initial input for HarnessWorkflow.
@@ -84,6 +103,10 @@ type StartSessionRequest struct {
 
 	// CrewType is the crew template name (for display in session list).
 	CrewType string `json:"crew_type,omitempty"`
+
+	// HandoffParentID is the planner session a handoff starts this
+	// executor session from. Optional.
+	HandoffParentID string `json:"handoff_parent_id,omitempty"`
 }
 
 // StartSessionResponse is returned by the UpdateStartSession update.
@@ -248,6 +271,7 @@ func handleStartSession(
 		Overrides:  overrides,
 		CrewName:   req.CrewName,
 		CrewInputs: req.CrewInputs,
+		HandoffParentID: req.HandoffParentID,
 	}
 
 	// Determine model name for the registry (best-effort from overrides).
@@ -362,6 +386,24 @@ func mergeCLIOverrides(base CLIOverrides, overlay *CLIOverrides) CLIOverrides {
 	if overlay.MemoryDbPath != "" {
 		result.MemoryDbPath = overlay.MemoryDbPath
 	}
+	if overlay.Remote != nil {
+		result.Remote = overlay.Remote
+	}
+	if overlay.SessionType != "" {
+		result.SessionType = overlay.SessionType
+	}
+	if overlay.MaxSessionCostUSD > 0 {
+		result.MaxSessionCostUSD = overlay.MaxSessionCostUSD
+	}
+	if overlay.Timezone != "" {
+		result.Timezone = overlay.Timezone
+	}
+	if overlay.Locale != "" {
+		result.Locale = overlay.Locale
+	}
+	if overlay.Persona != "" {
+		result.Persona = overlay.Persona
+	}
 	return result
 }
 
@@ -29,7 +29,7 @@ func (s *HarnessWorkflowTestSuite) SetupTest() {
 	// Register SessionWorkflow as a child workflow that completes immediately.
 	s.env.RegisterWorkflow(SessionWorkflow)
 	s.env.OnWorkflow(SessionWorkflow, mock.Anything, mock.Anything).
-		Return(nil).Maybe()
+		Return(WorkflowResult{}, nil).Maybe()
 }
 
 func (s *HarnessWorkflowTestSuite) AfterTest(suiteName, testName string) {
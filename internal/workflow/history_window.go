@@ -0,0 +1,222 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// history_window.go bounds the conversation items held in workflow state
+// (Retention.HistoryWindowItems / HistoryWindowBytes). After a turn, and
+// before ContinueAsNew, history over the window has its oldest whole turns
+// written to the session archive on the worker (internal/archive) and
+// replaced by one summary message, so queries and ContinueAsNew payloads
+// stay small in very long sessions. The current turn is never moved.
+//
+// NOTE: Temporal-specific addition (not in Codex Rust).
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+const (
+	// archiveSummaryPrefix starts the message that stands in for archived
+	// turns, and identifies it when the window moves again.
+	archiveSummaryPrefix = "Earlier turns of this conversation were archived to keep the session small."
+
+	// maxArchivedRequests caps the user requests quoted in the summary.
+	maxArchivedRequests = 10
+
+	// maxArchivedRequestLen caps each quoted request.
+	maxArchivedRequestLen = 120
+)
+
+// HistoryArchive describes what the history window has moved out of
+// History. It persists across ContinueAsNew.
+type HistoryArchive struct {
+	Dir   string `json:"dir"`   // session archive directory on the worker
+	Items int    `json:"items"` // items archived so far (next batch index)
+	Turns int    `json:"turns"` // turns archived so far
+	// Requests quotes the most recent archived user requests, oldest first.
+	Requests []string `json:"requests,omitempty"`
+}
+
+// applyHistoryWindow moves the oldest turns out of History when it exceeds
+// the retention window. Best-effort: if the archive cannot be written the
+// history is left as is and the move is retried after the next turn.
+func (s *SessionState) applyHistoryWindow(ctx workflow.Context, ctrl *LoopControl) {
+	r := s.Config.Retention
+	if !r.HistoryWindowed() {
+		return
+	}
+	logger := workflow.GetLogger(ctx)
+	items, err := s.History.GetRawItems()
+	if err != nil {
+		return
+	}
+	cut := historyWindowCut(items, r.HistoryWindowItems, r.HistoryWindowBytes)
+	if cut == 0 {
+		return
+	}
+
+	var batch []models.ConversationItem
+	var modelSwitch *models.ConversationItem
+	for i := range items[:cut] {
+		item := items[i]
+		if isArchiveSummary(item) {
+			continue
+		}
+		if item.Type == models.ItemTypeModelSwitch {
+			modelSwitch = &items[i]
+		}
+		batch = append(batch, r.RedactItem(item))
+	}
+
+	archived := HistoryArchive{}
+	if s.Archive != nil {
+		archived = *s.Archive
+	}
+	dir, err := s.archiveItems(ctx, archived.Items, batch)
+	if err != nil {
+		logger.Warn("Failed to archive conversation items; keeping them in history", "error", err)
+		return
+	}
+	archived.Dir = dir
+	archived.Items += len(batch)
+	for _, item := range batch {
+		switch item.Type {
+		case models.ItemTypeTurnStarted:
+			archived.Turns++
+		case models.ItemTypeUserMessage:
+			archived.Requests = append(archived.Requests, requestExcerpt(item.Content))
+		}
+	}
+	if over := len(archived.Requests) - maxArchivedRequests; over > 0 {
+		archived.Requests = append([]string(nil), archived.Requests[over:]...)
+	}
+	s.Archive = &archived
+
+	// Keep the latest model-switch message so the model still knows which
+	// model it is, as compaction does.
+	kept := make([]models.ConversationItem, 0, len(items)-cut+2)
+	kept = append(kept, archived.summaryItem())
+	if modelSwitch != nil {
+		kept = append(kept, *modelSwitch)
+	}
+	kept = append(kept, items[cut:]...)
+	if err := s.History.ReplaceAll(kept); err != nil {
+		logger.Error("Failed to replace history after archiving", "error", err)
+		return
+	}
+	s.LastResponseID = ""
+	s.lastSentHistoryLen = 0
+	s.logCompaction(ctx, ctrl, fmt.Sprintf("archived %d history items to %s", len(batch), dir))
+	ctrl.NotifyItemAdded()
+
+	logger.Info("Archived conversation items outside the history window",
+		"archived_items", len(batch), "total_archived", archived.Items, "history_items", len(kept))
+}
+
+// archiveItems writes batch to the session archive starting at index first
+// and returns the archive directory.
+func (s *SessionState) archiveItems(ctx workflow.Context, first int, batch []models.ConversationItem) (string, error) {
+	actOpts := workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 3,
+		},
+	}
+	if s.Config.SessionTaskQueue != "" {
+		actOpts.TaskQueue = s.Config.SessionTaskQueue
+	}
+	actCtx := workflow.WithActivityOptions(ctx, actOpts)
+
+	input := activities.ArchiveItemsInput{
+		SessionID: s.ConversationID,
+		CodexHome: s.Config.CodexHome,
+		First:     first,
+		Items:     batch,
+	}
+	var out activities.ArchiveItemsOutput
+	if err := workflow.ExecuteActivity(actCtx, "ArchiveConversationItems", input).Get(ctx, &out); err != nil {
+		return "", err
+	}
+	return out.Dir, nil
+}
+
+// historyWindowCut returns how many leading items to archive so the rest
+// fits the window (plus the summary that replaces them), or 0 if history
+// already fits. Cuts fall on turn boundaries and always keep the latest
+// turn, so the result may still exceed the window.
+func historyWindowCut(items []models.ConversationItem, maxItems, maxBytes int) int {
+	sizes := make([]int, len(items))
+	total := 0
+	for i, item := range items {
+		data, _ := json.Marshal(item)
+		sizes[i] = len(data)
+		total += sizes[i]
+	}
+	fits := func(count, bytes int) bool {
+		return (maxItems <= 0 || count <= maxItems) && (maxBytes <= 0 || bytes <= maxBytes)
+	}
+	if fits(len(items), total) {
+		return 0
+	}
+
+	cut := 0
+	for i := 1; i < len(items); i++ {
+		total -= sizes[i-1]
+		if items[i].Type != models.ItemTypeTurnStarted {
+			continue
+		}
+		cut = i
+		// +1 for the summary item.
+		if fits(len(items)-i+1, total) {
+			break
+		}
+	}
+
+	// Archiving only an earlier summary would rewrite history every turn
+	// without shrinking it.
+	for _, item := range items[:cut] {
+		if !isArchiveSummary(item) {
+			return cut
+		}
+	}
+	return 0
+}
+
+// isArchiveSummary reports whether item is the message standing in for
+// archived turns.
+func isArchiveSummary(item models.ConversationItem) bool {
+	return item.Type == models.ItemTypeAssistantMessage && strings.HasPrefix(item.Content, archiveSummaryPrefix)
+}
+
+// summaryItem renders the message that replaces archived turns in History.
+// It is derived only from workflow state, so replay produces the same text.
+func (a HistoryArchive) summaryItem() models.ConversationItem {
+	var b strings.Builder
+	b.WriteString(archiveSummaryPrefix)
+	fmt.Fprintf(&b, " %d earlier turns (%d items) are no longer shown here; they are stored on the worker in %s.",
+		a.Turns, a.Items, a.Dir)
+	if len(a.Requests) > 0 {
+		b.WriteString("\nThe most recent archived user requests were:")
+		for _, req := range a.Requests {
+			b.WriteString("\n- " + req)
+		}
+	}
+	return models.ConversationItem{
+		Type:    models.ItemTypeAssistantMessage,
+		Content: b.String(),
+	}
+}
+
+// requestExcerpt shortens a user message to its first line for the summary.
+func requestExcerpt(content string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(content), "\n")
+	return truncate(line, maxArchivedRequestLen)
+}
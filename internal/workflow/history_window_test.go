@@ -0,0 +1,81 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// windowTurns builds n turns of four items each.
+func windowTurns(n int) []models.ConversationItem {
+	var items []models.ConversationItem
+	for i := 1; i <= n; i++ {
+		turnID := fmt.Sprintf("turn-%d", i)
+		items = append(items,
+			models.ConversationItem{Type: models.ItemTypeTurnStarted, TurnID: turnID},
+			models.ConversationItem{Type: models.ItemTypeUserMessage, Content: "question " + turnID, TurnID: turnID},
+			models.ConversationItem{Type: models.ItemTypeAssistantMessage, Content: "answer " + turnID, TurnID: turnID},
+			models.ConversationItem{Type: models.ItemTypeTurnComplete, TurnID: turnID},
+		)
+	}
+	return items
+}
+
+func TestHistoryWindowCut_WithinWindow(t *testing.T) {
+	items := windowTurns(3)
+	assert.Equal(t, 0, historyWindowCut(items, 12, 0))
+	assert.Equal(t, 0, historyWindowCut(items, 0, 0))
+}
+
+func TestHistoryWindowCut_ItemLimit(t *testing.T) {
+	items := windowTurns(3)
+	// 8 items kept + summary = 9 <= 10: one turn archived.
+	assert.Equal(t, 4, historyWindowCut(items, 10, 0))
+	// 4 items kept + summary = 5 <= 6: two turns archived.
+	assert.Equal(t, 8, historyWindowCut(items, 6, 0))
+}
+
+func TestHistoryWindowCut_KeepsLatestTurn(t *testing.T) {
+	items := windowTurns(3)
+	assert.Equal(t, 8, historyWindowCut(items, 2, 0))
+	assert.Equal(t, 8, historyWindowCut(items, 0, 1))
+}
+
+func TestHistoryWindowCut_ByteLimit(t *testing.T) {
+	items := windowTurns(2)
+	items[1].Content = strings.Repeat("x", 4000)
+	assert.Equal(t, 4, historyWindowCut(items, 0, 2000))
+}
+
+func TestHistoryWindowCut_OnlyEarlierSummary(t *testing.T) {
+	summary := HistoryArchive{Dir: "/a", Items: 4, Turns: 1}.summaryItem()
+	items := append([]models.ConversationItem{summary}, windowTurns(1)...)
+	assert.Equal(t, 0, historyWindowCut(items, 2, 0), "archiving only the summary would not shrink history")
+}
+
+func TestHistoryWindowCut_NoTurnBoundary(t *testing.T) {
+	items := []models.ConversationItem{
+		{Type: models.ItemTypeUserMessage, Content: "a"},
+		{Type: models.ItemTypeAssistantMessage, Content: "b"},
+		{Type: models.ItemTypeUserMessage, Content: "c"},
+	}
+	assert.Equal(t, 0, historyWindowCut(items, 1, 0))
+}
+
+func TestHistoryArchive_SummaryItem(t *testing.T) {
+	a := HistoryArchive{Dir: "/home/me/.codex/archive/sess", Items: 8, Turns: 2, Requests: []string{"fix the build", "add tests"}}
+	item := a.summaryItem()
+	assert.True(t, isArchiveSummary(item))
+	assert.Equal(t, archiveSummaryPrefix+
+		" 2 earlier turns (8 items) are no longer shown here; they are stored on the worker in /home/me/.codex/archive/sess."+
+		"\nThe most recent archived user requests were:\n- fix the build\n- add tests", item.Content)
+}
+
+func TestRequestExcerpt(t *testing.T) {
+	assert.Equal(t, "first line", requestExcerpt("  first line\nsecond line"))
+	assert.Equal(t, strings.Repeat("y", maxArchivedRequestLen)+"...", requestExcerpt(strings.Repeat("y", 500)))
+}
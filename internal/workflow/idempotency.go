@@ -0,0 +1,45 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// idempotency.go deduplicates user_input and approval_response updates by
+// client-generated idempotency key. Temporal already deduplicates updates
+// with the same update ID within a run, but the keys here also survive
+// ContinueAsNew, so a client can retry a timed-out update without starting
+// a second turn or re-applying an approval.
+//
+// NOTE: Temporal-specific addition (not in Codex Rust).
+package workflow
+
+// maxAcceptedUpdates bounds the idempotency keys a session remembers.
+const maxAcceptedUpdates = 64
+
+// AcceptedUpdate records an accepted update's idempotency key.
+type AcceptedUpdate struct {
+	Key    string `json:"key"`
+	TurnID string `json:"turn_id,omitempty"` // user_input: the turn it started
+}
+
+// acceptedUpdate returns the accepted update with key. Empty keys never
+// match.
+func (s *SessionState) acceptedUpdate(key string) (AcceptedUpdate, bool) {
+	if key == "" {
+		return AcceptedUpdate{}, false
+	}
+	for _, u := range s.AcceptedUpdates {
+		if u.Key == key {
+			return u, true
+		}
+	}
+	return AcceptedUpdate{}, false
+}
+
+// rememberUpdate records an accepted update, dropping the oldest beyond
+// maxAcceptedUpdates. Empty keys are not recorded.
+func (s *SessionState) rememberUpdate(key, turnID string) {
+	if key == "" {
+		return
+	}
+	s.AcceptedUpdates = append(s.AcceptedUpdates, AcceptedUpdate{Key: key, TurnID: turnID})
+	if n := len(s.AcceptedUpdates) - maxAcceptedUpdates; n > 0 {
+		s.AcceptedUpdates = append([]AcceptedUpdate(nil), s.AcceptedUpdates[n:]...)
+	}
+}
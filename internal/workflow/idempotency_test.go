@@ -0,0 +1,67 @@
+package workflow
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestRememberUpdate_Bounded(t *testing.T) {
+	s := &SessionState{}
+	s.rememberUpdate("", "turn-0")
+	assert.Empty(t, s.AcceptedUpdates)
+
+	for i := 0; i < maxAcceptedUpdates+5; i++ {
+		s.rememberUpdate(fmt.Sprintf("key-%d", i), fmt.Sprintf("turn-%d", i))
+	}
+	assert.Len(t, s.AcceptedUpdates, maxAcceptedUpdates)
+	_, ok := s.acceptedUpdate("key-0")
+	assert.False(t, ok, "oldest key dropped")
+	u, ok := s.acceptedUpdate(fmt.Sprintf("key-%d", maxAcceptedUpdates+4))
+	require.True(t, ok)
+	assert.Equal(t, fmt.Sprintf("turn-%d", maxAcceptedUpdates+4), u.TurnID)
+	_, ok = s.acceptedUpdate("")
+	assert.False(t, ok)
+}
+
+// TestUserInput_DuplicateKeyStartsOneTurn: a retried user_input with the
+// same idempotency key (but a new update ID) returns the original turn.
+func (s *AgenticWorkflowTestSuite) TestUserInput_DuplicateKeyStartsOneTurn() {
+	s.env.OnActivity("ExecuteLLMCall", mock.Anything, mock.Anything).
+		Return(mockLLMStopResponse("First answer", 10), nil).Once()
+	s.env.OnActivity("ExecuteLLMCall", mock.Anything, mock.Anything).
+		Return(mockLLMStopResponse("Second answer", 10), nil).Once()
+
+	responses := make([]StateUpdateResponse, 2)
+	for i, id := range []string{"attempt-1", "attempt-2"} {
+		i, id := i, id
+		s.env.RegisterDelayedCallback(func() {
+			s.env.UpdateWorkflow(UpdateUserInput, id, &testsuite.TestUpdateCallback{
+				OnAccept: func() {},
+				OnReject: func(err error) { s.Fail("input rejected", err.Error()) },
+				OnComplete: func(result interface{}, err error) {
+					require.NoError(s.T(), err)
+					responses[i] = result.(StateUpdateResponse)
+				},
+			}, UserInput{Content: "Run the tests", IdempotencyKey: "key-1"})
+		}, time.Second*time.Duration(2+i))
+	}
+	s.sendShutdown(time.Second * 5)
+
+	s.env.ExecuteWorkflow(AgenticWorkflow, testInput("Hi"))
+
+	require.True(s.T(), s.env.IsWorkflowCompleted())
+	require.NoError(s.T(), s.env.GetWorkflowError())
+	assert.False(s.T(), responses[0].Replayed)
+	assert.True(s.T(), responses[1].Replayed)
+	assert.Equal(s.T(), responses[0].TurnID, responses[1].TurnID)
+
+	var result WorkflowResult
+	require.NoError(s.T(), s.env.GetWorkflowResult(&result))
+	assert.Equal(s.T(), 20, result.TotalTokens, "two turns: the initial one and the deduplicated input")
+}
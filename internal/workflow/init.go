@@ -81,6 +81,39 @@ func (s *SessionState) validateReasoningEffortForProfile() {
 	}
 }
 
+// switchModel moves the session to provider/model, re-resolving the model
+// profile and resetting response chaining. reason, when set, is shown to the
+// new model in place of the "user switched" note. contextWindow overrides
+// the profile's window when positive.
+func (s *SessionState) switchModel(provider, model string, contextWindow int, reason string) {
+	// Save previous model info before overwriting.
+	s.PreviousModel = s.Config.Model.Model
+	s.PreviousContextWindow = s.Config.Model.ContextWindow
+
+	s.Config.Model.Provider = provider
+	s.Config.Model.Model = model
+
+	// Re-resolve the model profile so ContextWindow, Temperature,
+	// MaxTokens reflect the new model's defaults from the registry.
+	s.resolveProfile()
+	if contextWindow > 0 {
+		s.Config.Model.ContextWindow = contextWindow
+	}
+	s.validateReasoningEffortForProfile()
+
+	// Reset response chaining and incremental history tracking.
+	s.LastResponseID = ""
+	s.lastSentHistoryLen = 0
+
+	// Flag for maybeCompactBeforeLLM to inject a model-switch message
+	// and trigger proactive compaction if needed.
+	s.modelSwitched = true
+	s.modelSwitchReason = reason
+
+	// Any switch replaces a temporary rate-limit downgrade.
+	s.RateLimitDowngrade = nil
+}
+
 // resolveInstructions loads worker-side AGENTS.md files and merges all
 // instruction sources into the session configuration. Called when
 // BaseInstructions is empty (i.e. AgenticWorkflow was not started via
@@ -428,7 +461,7 @@ func (s *SessionState) extractMemoryOnShutdown(ctx workflow.Context) {
 	var phase1Result activities.Phase1Output
 	err := workflow.ExecuteActivity(actCtx, "ExtractPhase1",
 		activities.Phase1Input{
-			History:     items,
+			History:     s.Config.Retention.Redact(items),
 			Cwd:         s.Config.Cwd,
 			WorkflowID:  s.ConversationID,
 			ModelConfig: modelConfig,
@@ -479,6 +512,7 @@ func (s *SessionState) extractMemoryOnShutdown(ctx workflow.Context) {
 			MemoryDbPath:      s.memoryDbPath(),
 			ModelConfig:       consolidationModelConfig,
 			MaxRawMemories:    maxRaw,
+			RetentionDays:     s.Config.Retention.TTLDays,
 		},
 	).Get(ctx, nil)
 	if err != nil {
@@ -508,8 +542,9 @@ func resolveHarnessConfig(ctx workflow.Context, overrides CLIOverrides) (models.
 	}
 	actCtx := workflow.WithActivityOptions(ctx, actOpts)
 
+	var src harnessConfigSources
+
 	// Load worker-side project docs (AGENTS.md).
-	var workerDocs string
 	var loadWorkerResult activities.LoadWorkerInstructionsOutput
 	loadWorkerInput := activities.LoadWorkerInstructionsInput{
 		Cwd:             overrides.Cwd,
@@ -518,11 +553,10 @@ func resolveHarnessConfig(ctx workflow.Context, overrides CLIOverrides) (models.
 	if err := workflow.ExecuteActivity(actCtx, "LoadWorkerInstructions", loadWorkerInput).Get(ctx, &loadWorkerResult); err != nil {
 		logger.Warn("Failed to load worker instructions", "error", err)
 	} else {
-		workerDocs = loadWorkerResult.ProjectDocs
+		src.WorkerDocs = loadWorkerResult.ProjectDocs
 	}
 
 	// Load exec policy rules.
-	var execPolicyRules string
 	if overrides.CodexHome != "" {
 		var loadExecResult activities.LoadExecPolicyOutput
 		loadExecInput := activities.LoadExecPolicyInput{
@@ -531,12 +565,11 @@ func resolveHarnessConfig(ctx workflow.Context, overrides CLIOverrides) (models.
 		if err := workflow.ExecuteActivity(actCtx, "LoadExecPolicy", loadExecInput).Get(ctx, &loadExecResult); err != nil {
 			logger.Warn("Failed to load exec policy", "error", err)
 		} else {
-			execPolicyRules = loadExecResult.RulesSource
+			src.ExecPolicyRules = loadExecResult.RulesSource
 		}
 	}
 
 	// Load personal instructions.
-	var personalInstructions string
 	var loadPersonalResult activities.LoadPersonalInstructionsOutput
 	loadPersonalInput := activities.LoadPersonalInstructionsInput{
 		CodexHome: overrides.CodexHome,
@@ -544,17 +577,9 @@ func resolveHarnessConfig(ctx workflow.Context, overrides CLIOverrides) (models.
 	if err := workflow.ExecuteActivity(actCtx, "LoadPersonalInstructions", loadPersonalInput).Get(ctx, &loadPersonalResult); err != nil {
 		logger.Warn("Failed to load personal instructions", "error", err)
 	} else {
-		personalInstructions = loadPersonalResult.Instructions
+		src.PersonalInstructions = loadPersonalResult.Instructions
 	}
 
-	// Merge all instruction sources.
-	merged := instructions.MergeInstructions(instructions.MergeInput{
-		WorkerProjectDocs:        workerDocs,
-		UserPersonalInstructions: personalInstructions,
-		ApprovalMode:             string(overrides.Permissions.ApprovalMode),
-		Cwd:                      overrides.Cwd,
-	})
-
 	// Load config.toml from worker filesystem.
 	var loadConfigResult activities.LoadConfigFileOutput
 	loadConfigInput := activities.LoadConfigFileInput{
@@ -563,15 +588,74 @@ func resolveHarnessConfig(ctx workflow.Context, overrides CLIOverrides) (models.
 	if err := workflow.ExecuteActivity(actCtx, "LoadConfigFile", loadConfigInput).Get(ctx, &loadConfigResult); err != nil {
 		logger.Warn("Failed to load config file", "error", err)
 	}
+	src.RawTOML = loadConfigResult.RawTOML
+
+	cfg, err := assembleHarnessConfig(overrides, src)
+	if err != nil {
+		logger.Warn("Failed to parse config.toml", "error", err)
+	}
+	return cfg, nil
+}
+
+// harnessConfigSources are the worker-side inputs to a session
+// configuration, loaded by resolveHarnessConfig's activities.
+type harnessConfigSources struct {
+	WorkerDocs           string
+	ExecPolicyRules      string
+	PersonalInstructions string
+	RawTOML              string
+}
+
+// ErrTypeInvalidRemote is the ApplicationError type of a session whose
+// remote machine (SessionConfiguration.Remote) is not a safe SSH
+// destination.
+const ErrTypeInvalidRemote = "InvalidRemoteConfig"
+
+// checkRemoteConfig fails a session whose remote destination could be
+// taken for an ssh option. Remote comes from clients and config.toml, so it
+// is checked again after the overrides are applied. The error is
+// non-retryable.
+func checkRemoteConfig(remote *models.RemoteConfig) error {
+	if remote == nil {
+		return nil
+	}
+	if err := remote.Validate(); err != nil {
+		return temporal.NewNonRetryableApplicationError("remote: "+err.Error(), ErrTypeInvalidRemote, nil)
+	}
+	return nil
+}
+
+// requireRemoteApproval switches a remote session to unless-trusted
+// approval. No sandbox applies to commands on the remote machine, so
+// neither on-failure nor never would ask the user about any of them.
+func requireRemoteApproval(cfg *models.SessionConfiguration) {
+	if cfg.Remote != nil {
+		cfg.Permissions.ApprovalMode = models.ApprovalUnlessTrusted
+	}
+}
+
+// assembleHarnessConfig builds a session configuration from the defaults,
+// config.toml, the loaded sources and the CLI overrides, in that order of
+// precedence. A config.toml parse error is returned with the configuration
+// built without it.
+func assembleHarnessConfig(overrides CLIOverrides, src harnessConfigSources) (models.SessionConfiguration, error) {
+	// Merge all instruction sources.
+	merged := instructions.MergeInstructions(instructions.MergeInput{
+		WorkerProjectDocs:        src.WorkerDocs,
+		UserPersonalInstructions: src.PersonalInstructions,
+		ApprovalMode:             string(overrides.Permissions.ApprovalMode),
+		Cwd:                      overrides.Cwd,
+	})
 
 	// Assemble SessionConfiguration from defaults + overrides + resolved data.
 	cfg := models.DefaultSessionConfiguration()
 
 	// Apply TOML config (between defaults and CLI overrides).
-	if loadConfigResult.RawTOML != "" {
-		tomlCfg, err := models.ParseConfigToml([]byte(loadConfigResult.RawTOML))
+	var tomlErr error
+	if src.RawTOML != "" {
+		tomlCfg, err := models.ParseConfigToml([]byte(src.RawTOML))
 		if err != nil {
-			logger.Warn("Failed to parse config.toml", "error", err)
+			tomlErr = err
 		} else {
 			tomlCfg.ApplyToConfig(&cfg)
 		}
@@ -580,8 +664,14 @@ func resolveHarnessConfig(ctx workflow.Context, overrides CLIOverrides) (models.
 	cfg.BaseInstructions = merged.Base
 	cfg.DeveloperInstructions = merged.Developer
 	cfg.UserInstructions = merged.User
-	cfg.ExecPolicyRules = execPolicyRules
+	cfg.ExecPolicyRules = src.ExecPolicyRules
 	cfg.Cwd = overrides.Cwd
+	if overrides.Remote != nil {
+		cfg.Remote = overrides.Remote
+	}
+	if cfg.Remote != nil && cfg.Remote.Cwd != "" {
+		cfg.Cwd = cfg.Remote.Cwd
+	}
 	cfg.CodexHome = overrides.CodexHome
 	cfg.SessionTaskQueue = overrides.SessionTaskQueue
 
@@ -603,6 +693,21 @@ func resolveHarnessConfig(ctx workflow.Context, overrides CLIOverrides) (models.
 	if overrides.MemoryDbPath != "" {
 		cfg.MemoryDbPath = overrides.MemoryDbPath
 	}
+	if overrides.MaxSessionCostUSD > 0 {
+		cfg.MaxSessionCostUSD = overrides.MaxSessionCostUSD
+	}
+	if overrides.Timezone != "" && models.ValidateTimezone(overrides.Timezone) == nil {
+		cfg.Timezone = overrides.Timezone
+	}
+	if overrides.Locale != "" {
+		cfg.Locale = overrides.Locale
+	}
+	if overrides.Persona != models.PersonaNone && models.ValidatePersona(overrides.Persona) == nil {
+		cfg.Persona = overrides.Persona
+	}
+	if overrides.SessionType == models.SessionTypeAsk {
+		applyAskMode(&cfg)
+	}
 
-	return cfg, nil
+	return cfg, tomlErr
 }
@@ -0,0 +1,93 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// injection_guard.go applies the prompt-injection guard (Tools.InjectionGuard)
+// to tool outputs: outputs are wrapped in untrusted-content blocks in the
+// prompt, scanned for injection phrases when recorded, and in "approve" mode
+// a suspicious output makes every later tool call of the turn wait for the
+// user. History keeps the outputs unwrapped.
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/injection"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// injectionGuardEnabled reports whether tool outputs are wrapped and scanned.
+func (s *SessionState) injectionGuardEnabled() bool {
+	return models.InjectionGuardEnabled(s.Config.Tools.InjectionGuard)
+}
+
+// wrapToolOutputs returns items with every tool output wrapped in an
+// untrusted-content block naming its tool. The input is not modified.
+func wrapToolOutputs(items []models.ConversationItem) []models.ConversationItem {
+	names := make(map[string]string)
+	out := make([]models.ConversationItem, len(items))
+	for i, item := range items {
+		switch item.Type {
+		case models.ItemTypeFunctionCall:
+			names[item.CallID] = item.Name
+		case models.ItemTypeFunctionCallOutput:
+			if item.Output != nil && item.Output.Content != "" {
+				wrapped := *item.Output
+				wrapped.Content = injection.Wrap(names[item.CallID], item.Output.Content)
+				item.Output = &wrapped
+			}
+		}
+		out[i] = item
+	}
+	return out
+}
+
+// scanToolOutputs checks tool results for injection phrases. Each suspicious
+// output adds a warning notice for the user; in "approve" mode it also makes
+// the rest of the turn's tool calls require approval.
+func (s *SessionState) scanToolOutputs(ctrl *LoopControl, calls []models.ConversationItem, results []activities.ToolActivityOutput) {
+	if !s.injectionGuardEnabled() {
+		return
+	}
+	names := make(map[string]string, len(calls))
+	for _, fc := range calls {
+		names[fc.CallID] = fc.Name
+	}
+	for _, result := range results {
+		findings := injection.Detect(result.Content)
+		if len(findings) == 0 {
+			continue
+		}
+		source := fmt.Sprintf("%s (%s)", names[result.CallID], result.CallID)
+		s.addNotice(ctrl, fmt.Sprintf("Possible prompt injection in output of %s: %s",
+			source, injection.Describe(findings)))
+		if s.Config.Tools.InjectionGuard == models.InjectionGuardApprove && s.injectionSource == "" {
+			s.injectionSource = source
+		}
+	}
+}
+
+// requireInjectionApproval adds every call not already pending to
+// needsApproval once a suspicious output was seen this turn, so the user
+// confirms each action the model takes after reading it.
+func (s *SessionState) requireInjectionApproval(calls []models.ConversationItem, needsApproval []PendingApproval) []PendingApproval {
+	if s.injectionSource == "" {
+		return needsApproval
+	}
+	pending := make(map[string]bool, len(needsApproval))
+	for _, p := range needsApproval {
+		pending[p.CallID] = true
+	}
+	reason := fmt.Sprintf("output of %s looked like a prompt injection", s.injectionSource)
+	for _, fc := range calls {
+		if pending[fc.CallID] {
+			continue
+		}
+		needsApproval = append(needsApproval, PendingApproval{
+			CallID:    fc.CallID,
+			ToolName:  fc.Name,
+			Arguments: fc.Arguments,
+			Reason:    reason,
+		})
+	}
+	return needsApproval
+}
@@ -0,0 +1,43 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+func TestWrapToolOutputs(t *testing.T) {
+	items := []models.ConversationItem{
+		{Type: models.ItemTypeUserMessage, Content: "hi"},
+		{Type: models.ItemTypeFunctionCall, CallID: "c1", Name: "shell_command"},
+		{Type: models.ItemTypeFunctionCallOutput, CallID: "c1", Output: &models.FunctionCallOutputPayload{Content: "out"}},
+		{Type: models.ItemTypeFunctionCallOutput, CallID: "c2", Output: &models.FunctionCallOutputPayload{}},
+	}
+
+	wrapped := wrapToolOutputs(items)
+
+	assert.Equal(t, "hi", wrapped[0].Content)
+	assert.Equal(t, "<untrusted_tool_output tool=\"shell_command\">\nout\n</untrusted_tool_output>", wrapped[2].Output.Content)
+	assert.Equal(t, "", wrapped[3].Output.Content, "empty outputs stay empty")
+	assert.Equal(t, "out", items[2].Output.Content, "input is not modified")
+}
+
+func TestRequireInjectionApproval(t *testing.T) {
+	calls := []models.ConversationItem{
+		{Type: models.ItemTypeFunctionCall, CallID: "c1", Name: "shell_command", Arguments: `{"command":"ls"}`},
+		{Type: models.ItemTypeFunctionCall, CallID: "c2", Name: "write_file"},
+	}
+	already := []PendingApproval{{CallID: "c2", ToolName: "write_file", Reason: "writes files"}}
+
+	s := &SessionState{}
+	assert.Equal(t, already, s.requireInjectionApproval(calls, already), "no suspicious output this turn")
+
+	s.injectionSource = "read_file (c0)"
+	pending := s.requireInjectionApproval(calls, already)
+	assert.Len(t, pending, 2)
+	assert.Equal(t, "writes files", pending[0].Reason)
+	assert.Equal(t, "c1", pending[1].CallID)
+	assert.Equal(t, "output of read_file (c0) looked like a prompt injection", pending[1].Reason)
+}
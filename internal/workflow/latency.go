@@ -0,0 +1,127 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// latency.go breaks each turn's wall time down into time to the first LLM
+// response, LLM time, tool time and approval wait, and aggregates the
+// breakdown over the session. Times come from workflow.Now, so they are
+// deterministic on replay and include activity scheduling and retries.
+package workflow
+
+import (
+	"time"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// TurnLatency is the latency breakdown of one turn, in milliseconds.
+type TurnLatency struct {
+	TurnID          string `json:"turn_id"`
+	FirstResponseMs int64  `json:"first_response_ms"` // Turn start to first LLM response; 0 if none
+	LLMMs           int64  `json:"llm_ms"`
+	ToolMs          int64  `json:"tool_ms"`
+	ApprovalWaitMs  int64  `json:"approval_wait_ms"`
+	TotalMs         int64  `json:"total_ms"`
+}
+
+// LatencyStats aggregates TurnLatency over the session's completed turns.
+type LatencyStats struct {
+	Turns              int   `json:"turns"`
+	RespondedTurns     int   `json:"responded_turns"` // Turns with at least one LLM response
+	FirstResponseMs    int64 `json:"first_response_ms"`
+	MaxFirstResponseMs int64 `json:"max_first_response_ms"`
+	LLMMs              int64 `json:"llm_ms"`
+	ToolMs             int64 `json:"tool_ms"`
+	ApprovalWaitMs     int64 `json:"approval_wait_ms"`
+	TotalMs            int64 `json:"total_ms"`
+}
+
+// AvgFirstResponseMs returns the mean time to first response over turns
+// that got one.
+func (l LatencyStats) AvgFirstResponseMs() int64 {
+	if l.RespondedTurns == 0 {
+		return 0
+	}
+	return l.FirstResponseMs / int64(l.RespondedTurns)
+}
+
+// AvgTurnMs returns the mean turn duration.
+func (l LatencyStats) AvgTurnMs() int64 {
+	if l.Turns == 0 {
+		return 0
+	}
+	return l.TotalMs / int64(l.Turns)
+}
+
+// add folds one turn into the aggregate.
+func (l *LatencyStats) add(t TurnLatency) {
+	l.Turns++
+	if t.FirstResponseMs > 0 {
+		l.RespondedTurns++
+		l.FirstResponseMs += t.FirstResponseMs
+		if t.FirstResponseMs > l.MaxFirstResponseMs {
+			l.MaxFirstResponseMs = t.FirstResponseMs
+		}
+	}
+	l.LLMMs += t.LLMMs
+	l.ToolMs += t.ToolMs
+	l.ApprovalWaitMs += t.ApprovalWaitMs
+	l.TotalMs += t.TotalMs
+}
+
+// startTurnLatency begins timing the turn turnID.
+func (s *SessionState) startTurnLatency(ctx workflow.Context, turnID string) {
+	s.turnLatency = &TurnLatency{TurnID: turnID}
+	s.turnStartedAt = workflow.Now(ctx)
+}
+
+// finishTurnLatency records the current turn's breakdown as the last turn
+// and adds it to the session aggregate.
+func (s *SessionState) finishTurnLatency(ctx workflow.Context) {
+	if s.turnLatency == nil {
+		return
+	}
+	t := *s.turnLatency
+	t.TotalMs = elapsedMs(ctx, s.turnStartedAt)
+	s.LastTurnLatency = &t
+	s.Latency.add(t)
+	s.turnLatency = nil
+}
+
+// addLLMLatency records an LLM call that started at start. The first
+// successful call of the turn sets the time to first response.
+func (s *SessionState) addLLMLatency(ctx workflow.Context, start time.Time, responded bool) {
+	if s.turnLatency == nil {
+		return
+	}
+	s.turnLatency.LLMMs += elapsedMs(ctx, start)
+	if responded && s.turnLatency.FirstResponseMs == 0 {
+		s.turnLatency.FirstResponseMs = max(elapsedMs(ctx, s.turnStartedAt), 1)
+	}
+}
+
+// addToolLatency records tool execution that started at start.
+func (s *SessionState) addToolLatency(ctx workflow.Context, start time.Time) {
+	if s.turnLatency != nil {
+		s.turnLatency.ToolMs += elapsedMs(ctx, start)
+	}
+}
+
+// addApprovalWait records an approval or escalation wait that started at start.
+func (s *SessionState) addApprovalWait(ctx workflow.Context, start time.Time) {
+	if s.turnLatency != nil {
+		s.turnLatency.ApprovalWaitMs += elapsedMs(ctx, start)
+	}
+}
+
+// latencyResult returns the session aggregate for WorkflowResult, or nil
+// before any turn completed.
+func (s *SessionState) latencyResult() *LatencyStats {
+	if s.Latency.Turns == 0 {
+		return nil
+	}
+	latency := s.Latency
+	return &latency
+}
+
+func elapsedMs(ctx workflow.Context, start time.Time) int64 {
+	return workflow.Now(ctx).Sub(start).Milliseconds()
+}
@@ -0,0 +1,24 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyStats_Add(t *testing.T) {
+	var l LatencyStats
+	assert.Zero(t, l.AvgFirstResponseMs())
+	assert.Zero(t, l.AvgTurnMs())
+
+	l.add(TurnLatency{FirstResponseMs: 1000, LLMMs: 1500, ToolMs: 200, TotalMs: 2000})
+	l.add(TurnLatency{FirstResponseMs: 3000, LLMMs: 3000, ApprovalWaitMs: 5000, TotalMs: 9000})
+	l.add(TurnLatency{TotalMs: 100}) // interrupted before any response
+
+	assert.Equal(t, 3, l.Turns)
+	assert.Equal(t, 2, l.RespondedTurns)
+	assert.Equal(t, int64(2000), l.AvgFirstResponseMs())
+	assert.Equal(t, int64(3000), l.MaxFirstResponseMs)
+	assert.Equal(t, int64(3700), l.AvgTurnMs())
+	assert.Equal(t, int64(5000), l.ApprovalWaitMs)
+}
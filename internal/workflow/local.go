@@ -0,0 +1,412 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// local.go runs a session in-process, without Temporal, for `tcx --local`.
+// It reuses the session state, configuration assembly, instructions, tool
+// specs and approval gate of AgenticWorkflow and calls the activity
+// implementations directly. Nothing is durable: the session ends with the
+// process.
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/temporal"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/capabilities"
+	"github.com/mfateev/temporal-agent-harness/internal/command_safety"
+	"github.com/mfateev/temporal-agent-harness/internal/history"
+	"github.com/mfateev/temporal-agent-harness/internal/llm"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/scratch"
+	"github.com/mfateev/temporal-agent-harness/internal/tools"
+)
+
+// localUnsupportedTools are handled by the workflow itself (plans, todos,
+// questions to the user, sub-agents, artifacts) and are not offered in a
+// local session.
+var localUnsupportedTools = []string{
+	"request_user_input", "ask_user", "update_plan", "todo", "read_artifact", "collab",
+}
+
+// localLLMAttempts bounds the attempts of one LLM call, like the LLM
+// activity's retry policy.
+const localLLMAttempts = 5
+
+// localLLMBackoff is the wait before the first LLM retry; it doubles.
+const localLLMBackoff = time.Second
+
+// LocalHooks connect a LocalSession to its front end.
+type LocalHooks struct {
+	// ItemAdded is called for each item added to the history.
+	ItemAdded func(item models.ConversationItem)
+	// Approve asks the user about calls that need approval. A nil
+	// response denies them all.
+	Approve func(ctx context.Context, pending []PendingApproval) *ApprovalResponse
+}
+
+// LocalSession is an in-process agent session.
+type LocalSession struct {
+	state  *SessionState
+	gate   *ApprovalGate
+	llm    *activities.LLMActivities
+	tools  *activities.ToolActivities
+	logger log.Logger
+	// usage sums the token usage of the session's LLM calls.
+	usage models.TokenUsage
+}
+
+// NewLocalSession resolves the session configuration for overrides the way
+// HarnessWorkflow does (config.toml, AGENTS.md, personal instructions, exec
+// policy) and prepares the tools in registry. The warnings describe
+// configuration that could not be applied.
+func NewLocalSession(ctx context.Context, overrides CLIOverrides, llmClient llm.LLMClient, registry *tools.ToolRegistry) (*LocalSession, []string) {
+	var warnings []string
+	instr := activities.NewInstructionActivities()
+
+	var src harnessConfigSources
+	if out, err := instr.LoadWorkerInstructions(ctx, activities.LoadWorkerInstructionsInput{Cwd: overrides.Cwd}); err == nil {
+		src.WorkerDocs = out.ProjectDocs
+	}
+	if overrides.CodexHome != "" {
+		if out, err := instr.LoadExecPolicy(ctx, activities.LoadExecPolicyInput{CodexHome: overrides.CodexHome}); err == nil {
+			src.ExecPolicyRules = out.RulesSource
+		}
+	}
+	if out, err := instr.LoadPersonalInstructions(ctx, activities.LoadPersonalInstructionsInput{CodexHome: overrides.CodexHome}); err == nil {
+		src.PersonalInstructions = out.Instructions
+	}
+	if out, err := instr.LoadConfigFile(ctx, activities.LoadConfigFileInput{CodexHome: overrides.CodexHome}); err == nil {
+		src.RawTOML = out.RawTOML
+	}
+	cfg, err := assembleHarnessConfig(overrides, src)
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("failed to parse config.toml: %v", err))
+	}
+	cfg.Tools.RemoveTools(localUnsupportedTools...)
+	cfg.Tools.OutputWindow = 0 // read_artifact is not available
+	requireRemoteApproval(&cfg)
+
+	s := &SessionState{
+		ConversationID: "local-" + uuid.NewString(),
+		History:        history.NewInMemoryHistory(),
+		Config:         cfg,
+		MaxIterations:  20,
+	}
+	s.resolveProfile()
+	s.ToolSpecs = buildToolSpecs(s.Config.Tools, s.ResolvedProfile)
+	s.ExecPolicyRules = cfg.ExecPolicyRules
+	if s.Config.GlossaryBudget() > 0 {
+		if out, err := instr.LoadGlossary(ctx, activities.LoadGlossaryInput{Cwd: s.Config.Cwd}); err == nil {
+			s.setGlossary(out)
+		}
+	}
+	if unavailable := s.dropUnavailableTools(capabilities.Probe()); len(unavailable) > 0 {
+		warnings = append(warnings, fmt.Sprintf("tools unavailable on this machine: %v", capabilities.SortedNames(unavailable)))
+	}
+
+	return &LocalSession{
+		state: s,
+		gate: NewApprovalGate(cfg.Permissions.ApprovalMode, s.ExecPolicyRules,
+			command_safety.NewClassifier(cfg.Permissions.SafeCommands, cfg.Permissions.UnsafeCommands)),
+		llm:    activities.NewLLMActivities(llmClient),
+		tools:  activities.NewToolActivities(registry),
+		logger: log.NewStructuredLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	}, warnings
+}
+
+// Config returns the resolved session configuration.
+func (l *LocalSession) Config() models.SessionConfiguration {
+	return l.state.Config
+}
+
+// TotalTokens returns the tokens used so far.
+func (l *LocalSession) TotalTokens() int {
+	return l.state.TotalTokens
+}
+
+// Usage returns the token usage summed over the session's LLM calls.
+func (l *LocalSession) Usage() models.TokenUsage {
+	return l.usage
+}
+
+// CostUSD returns the estimated spend of the session's LLM calls, or false
+// if the model has no known price.
+func (l *LocalSession) CostUSD() (float64, bool) {
+	return models.EstimateCostUSD(l.state.Config.Model.Provider, l.state.Config.Model.Model, l.usage)
+}
+
+// Items returns the session's conversation history.
+func (l *LocalSession) Items() []models.ConversationItem {
+	items, _ := l.state.History.GetRawItems()
+	return items
+}
+
+// Close removes the session's scratch directory.
+func (l *LocalSession) Close() {
+	_ = scratch.Remove(l.state.ConversationID)
+}
+
+// RunTurn adds userInput to the history and runs the LLM and tool loop
+// until the model stops, every call needing approval is denied, or the
+// iteration limit is reached. Returns ctx.Err() when ctx is cancelled.
+//
+// Maps to: codex-rs/core/src/codex.rs run_turn
+func (l *LocalSession) RunTurn(ctx context.Context, userInput string, hooks LocalHooks) error {
+	s := l.state
+	turnID := s.nextTurnID()
+	s.IterationCount = 0
+	s.turnDiffLines, s.turnDiffConfirmed = 0, 0
+	s.turnStart = time.Now().In(models.LoadTimezone(s.Config.Timezone))
+	add := func(item models.ConversationItem) {
+		_ = s.History.AddItem(item)
+		if hooks.ItemAdded != nil {
+			hooks.ItemAdded(item)
+		}
+	}
+	add(models.ConversationItem{Type: models.ItemTypeTurnStarted, TurnID: turnID})
+	add(models.ConversationItem{Type: models.ItemTypeUserMessage, Content: userInput, TurnID: turnID})
+	defer func() {
+		_ = s.History.AddItem(models.ConversationItem{Type: models.ItemTypeTurnComplete, TurnID: turnID})
+	}()
+
+	for ; s.IterationCount < s.MaxIterations; s.IterationCount++ {
+		// Local sessions wait for a rate-limit reset but never downgrade.
+		if wait, _, _ := s.planRateLimit(time.Now()); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		result, err := l.callLLM(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			add(models.ConversationItem{
+				Type:    models.ItemTypeAssistantMessage,
+				Content: fmt.Sprintf("[Error: LLM call failed: %v]", err),
+				TurnID:  turnID,
+			})
+			return nil
+		}
+		s.TotalTokens += result.TokenUsage.TotalTokens
+		s.TotalCachedTokens += result.TokenUsage.CachedTokens
+		s.LastTokenUsage = result.TokenUsage
+		l.usage.PromptTokens += result.TokenUsage.PromptTokens
+		l.usage.CompletionTokens += result.TokenUsage.CompletionTokens
+		l.usage.TotalTokens += result.TokenUsage.TotalTokens
+		l.usage.CachedTokens += result.TokenUsage.CachedTokens
+		l.usage.CacheCreationTokens += result.TokenUsage.CacheCreationTokens
+		s.recordRateLimits(result.RateLimits)
+		s.applyToolAliases(result, nil)
+		for _, item := range result.Items {
+			add(item)
+		}
+
+		calls := extractFunctionCalls(result.Items)
+		if len(calls) == 0 {
+			return nil
+		}
+
+		pending, forbidden := l.gate.Classify(calls)
+		for _, item := range forbidden {
+			add(item)
+		}
+		calls = withoutCalls(calls, forbidden)
+		var batch diffBatch
+		if s.Config.DiffBudget() > 0 {
+			previews := l.previewPatches(ctx, calls)
+			batch = measureEdits(calls, previews)
+			pending = s.requireDiffBudgetApproval(calls, pending, &batch)
+			for i := range pending {
+				if out, ok := previews[pending[i].CallID]; ok {
+					setPatchPreview(&pending[i], out)
+				}
+			}
+		}
+		if len(calls) > 0 && len(pending) > 0 {
+			l.attachPatchPreviews(ctx, pending)
+			var resp *ApprovalResponse
+			if hooks.Approve != nil {
+				resp = hooks.Approve(ctx, pending)
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if resp == nil {
+				resp = &ApprovalResponse{}
+				for _, ap := range pending {
+					resp.Denied = append(resp.Denied, ap.CallID)
+				}
+			}
+			var denied []models.ConversationItem
+			calls, denied = l.gate.ApplyDecision(calls, resp)
+			for _, item := range denied {
+				add(item)
+			}
+			if len(calls) == 0 {
+				return nil // all denied by the user — end the turn
+			}
+		}
+
+		var outs []activities.ToolActivityOutput
+		for _, fc := range calls {
+			out := l.executeTool(ctx, fc, turnID)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			s.ToolCallsExecuted = append(s.ToolCallsExecuted, fc.Name)
+			add(models.ConversationItem{
+				Type:   models.ItemTypeFunctionCallOutput,
+				CallID: out.CallID,
+				Output: &models.FunctionCallOutputPayload{Content: out.Content, Success: out.Success},
+			})
+			outs = append(outs, out)
+		}
+		s.addTurnDiffLines(batch, outs)
+	}
+
+	add(models.ConversationItem{
+		Type:    models.ItemTypeAssistantMessage,
+		Content: fmt.Sprintf("[Turn ended: reached maximum of %d iterations without completing. The task may need to be broken into smaller steps.]", s.MaxIterations),
+		TurnID:  turnID,
+	})
+	return nil
+}
+
+// attachPatchPreviews previews each pending apply_patch call that has no
+// preview yet, as the workflow's attachPatchPreviews does.
+func (l *LocalSession) attachPatchPreviews(ctx context.Context, pending []PendingApproval) {
+	var calls []models.ConversationItem
+	for _, ap := range pending {
+		if ap.Preview == "" && ap.PreviewError == "" {
+			calls = append(calls, models.ConversationItem{CallID: ap.CallID, Name: ap.ToolName, Arguments: ap.Arguments})
+		}
+	}
+	previews := l.previewPatches(ctx, calls)
+	for i := range pending {
+		if out, ok := previews[pending[i].CallID]; ok {
+			setPatchPreview(&pending[i], out)
+		}
+	}
+}
+
+// previewPatches previews each apply_patch call, as the workflow's
+// previewPatches does through the PreviewPatch activity.
+func (l *LocalSession) previewPatches(ctx context.Context, calls []models.ConversationItem) map[string]activities.PatchPreviewOutput {
+	previews := make(map[string]activities.PatchPreviewOutput)
+	for _, fc := range calls {
+		input := patchInput(fc.Name, fc.Arguments)
+		if input == "" {
+			continue
+		}
+		out, err := l.tools.PreviewPatch(ctx, activities.PatchPreviewInput{
+			Patch:       input,
+			LineEndings: l.state.Config.Tools.LineEndings.For(l.state.Config.Cwd),
+		})
+		if err == nil {
+			previews[fc.CallID] = out
+		}
+	}
+	return previews
+}
+
+// callLLM calls the model with the full history, retrying transient
+// errors with backoff and dropping the oldest turns on context overflow.
+func (l *LocalSession) callLLM(ctx context.Context) (*activities.LLMActivityOutput, error) {
+	s := l.state
+	backoff := localLLMBackoff
+	var lastErr error
+	for attempt := 0; attempt < localLLMAttempts; attempt++ {
+		historyItems, err := s.promptHistory()
+		if err != nil {
+			return nil, err
+		}
+		result, err := l.llm.ExecuteLLMCall(ctx, activities.LLMActivityInput{
+			History:               historyItems,
+			ModelConfig:           s.Config.Model,
+			ToolSpecs:             s.ToolSpecs,
+			BaseInstructions:      s.Config.BaseInstructions,
+			DeveloperInstructions: s.developerInstructionsForTurn(),
+			UserInstructions:      s.userInstructionsForTurn(),
+		})
+		if err == nil {
+			return &result, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		lastErr = err
+
+		var appErr *temporal.ApplicationError
+		if errors.As(err, &appErr) {
+			switch appErr.Type() {
+			case models.LLMErrTypeContextOverflow:
+				turnCount, _ := s.History.GetTurnCount()
+				_, _ = s.History.DropOldestUserTurns(max(turnCount/2, 2))
+				continue
+			case models.LLMErrTypeFatal:
+				return nil, errors.New(appErr.Message())
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	return nil, lastErr
+}
+
+// executeTool runs one tool call with the timeout its spec allows. Errors
+// become failed outputs, as in ToolsExecutor.ExecuteParallel.
+func (l *LocalSession) executeTool(ctx context.Context, fc models.ConversationItem, turnID string) activities.ToolActivityOutput {
+	s := l.state
+	var args map[string]interface{}
+	if fc.Arguments != "" {
+		if err := json.Unmarshal([]byte(fc.Arguments), &args); err != nil {
+			args = map[string]interface{}{"_raw": fc.Arguments}
+		}
+	}
+	specByName := make(map[string]tools.ToolSpec, len(s.ToolSpecs))
+	for _, spec := range s.ToolSpecs {
+		specByName[spec.Name] = spec
+	}
+
+	toolCtx, cancel := context.WithTimeout(ctx, resolveToolTimeout(specByName, fc.Name, args))
+	defer cancel()
+	input := activities.ToolActivityInput{
+		CallID:       fc.CallID,
+		ToolName:     fc.Name,
+		Arguments:    args,
+		Cwd:          s.Config.Cwd,
+		ScratchID:    s.ConversationID,
+		ExcludePaths: s.Config.Tools.ExcludePaths,
+		LineEndings:  s.Config.Tools.LineEndings.For(s.Config.Cwd),
+		TurnID:       turnID,
+		Remote:       s.Config.Remote,
+	}
+	if fc.Name == "exec_command" || fc.Name == "read_file" {
+		input.SessionID = s.ConversationID
+	}
+	out, err := l.tools.ExecuteTool(toolCtx, input)
+	if err == nil {
+		return out
+	}
+	failed := false
+	if errors.Is(err, context.DeadlineExceeded) {
+		return activities.ToolActivityOutput{CallID: fc.CallID, Content: "tool execution timed out", Success: &failed}
+	}
+	return toolActivityErrorToOutput(l.logger, fc.CallID, fc.Name, err)
+}
@@ -0,0 +1,118 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/llm"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/tools"
+	"github.com/mfateev/temporal-agent-harness/internal/tools/handlers"
+)
+
+// scriptedToolCall answers the first request of a turn with a call to tool
+// and every later one with "done".
+func scriptedToolCall(tool string, args map[string]string) *llm.ScriptedClient {
+	argJSON, _ := json.Marshal(args)
+	return &llm.ScriptedClient{Respond: func(req llm.LLMRequest) llm.LLMResponse {
+		last := req.History[len(req.History)-1]
+		if last.Type == models.ItemTypeUserMessage {
+			return llm.LLMResponse{
+				Items: []models.ConversationItem{{
+					Type: models.ItemTypeFunctionCall, CallID: "call-1", Name: tool, Arguments: string(argJSON),
+				}},
+				FinishReason: models.FinishReasonToolCalls,
+				TokenUsage:   models.TokenUsage{TotalTokens: 10},
+			}
+		}
+		return llm.LLMResponse{
+			Items:        []models.ConversationItem{{Type: models.ItemTypeAssistantMessage, Content: "done"}},
+			FinishReason: models.FinishReasonStop,
+			TokenUsage:   models.TokenUsage{TotalTokens: 5},
+		}
+	}}
+}
+
+func newTestLocalSession(t *testing.T, client llm.LLMClient) (*LocalSession, string) {
+	dir := t.TempDir()
+	registry := tools.NewToolRegistry()
+	registry.Register(handlers.NewReadFileTool())
+	registry.Register(handlers.NewWriteFileTool())
+	session, _ := NewLocalSession(context.Background(), CLIOverrides{
+		Provider:    "openai",
+		Model:       "gpt-4o",
+		CodexHome:   t.TempDir(),
+		Cwd:         dir,
+		Permissions: models.Permissions{ApprovalMode: models.ApprovalUnlessTrusted},
+	}, client, registry)
+	t.Cleanup(session.Close)
+	return session, dir
+}
+
+func TestLocalSession_RunsToolsAndAnswers(t *testing.T) {
+	client := &llm.ScriptedClient{}
+	session, dir := newTestLocalSession(t, client)
+	path := filepath.Join(dir, "notes.txt")
+	*client = *scriptedToolCall("read_file", map[string]string{"file_path": path})
+	require.NoError(t, os.WriteFile(path, []byte("remember the milk\n"), 0o644))
+
+	var items []models.ConversationItem
+	err := session.RunTurn(context.Background(), "what is in notes.txt?", LocalHooks{
+		ItemAdded: func(item models.ConversationItem) { items = append(items, item) },
+	})
+	require.NoError(t, err)
+
+	var types []models.ConversationItemType
+	for _, item := range items {
+		types = append(types, item.Type)
+	}
+	assert.Equal(t, []models.ConversationItemType{
+		models.ItemTypeTurnStarted,
+		models.ItemTypeUserMessage,
+		models.ItemTypeFunctionCall,
+		models.ItemTypeFunctionCallOutput,
+		models.ItemTypeAssistantMessage,
+	}, types)
+	assert.Contains(t, items[3].Output.Content, "remember the milk")
+	assert.Equal(t, "done", items[4].Content)
+	assert.Equal(t, 15, session.TotalTokens())
+}
+
+func TestLocalSession_DeniedApprovalEndsTurn(t *testing.T) {
+	client := &llm.ScriptedClient{}
+	session, dir := newTestLocalSession(t, client)
+	target := filepath.Join(dir, "out.txt")
+	*client = *scriptedToolCall("write_file", map[string]string{"path": target, "content": "hi"})
+
+	var asked []PendingApproval
+	var items []models.ConversationItem
+	err := session.RunTurn(context.Background(), "write a file", LocalHooks{
+		ItemAdded: func(item models.ConversationItem) { items = append(items, item) },
+		Approve: func(ctx context.Context, pending []PendingApproval) *ApprovalResponse {
+			asked = pending
+			return nil
+		},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, asked, 1)
+	assert.Equal(t, "write_file", asked[0].ToolName)
+	assert.NoFileExists(t, target)
+	last := items[len(items)-1]
+	assert.Equal(t, models.ItemTypeFunctionCallOutput, last.Type)
+	require.NotNil(t, last.Output.Success)
+	assert.False(t, *last.Output.Success)
+}
+
+func TestLocalSession_DropsWorkflowOnlyTools(t *testing.T) {
+	session, _ := newTestLocalSession(t, scriptedToolCall("read_file", nil))
+	for _, spec := range session.state.ToolSpecs {
+		assert.NotContains(t, localUnsupportedTools, spec.Name)
+	}
+}
@@ -19,6 +19,7 @@ type ConsolidationState struct {
 	MemoryDbPath    string            `json:"memory_db_path"`
 	ModelConfig     models.ModelConfig `json:"model_config"`
 	MaxRawMemories  int               `json:"max_raw_memories"`
+	RetentionDays   int               `json:"retention_days,omitempty"`
 }
 
 // ConsolidationWorkflow is a singleton workflow that consolidates memories.
@@ -91,7 +92,7 @@ func ConsolidationWorkflow(ctx workflow.Context, state ConsolidationState) error
 		// 1. List stage-1 outputs from DB
 		var listResult activities.ListStage1Result
 		err := workflow.ExecuteActivity(shortActCtx, "ListStage1Outputs",
-			activities.ListStage1Input{MaxCount: state.MaxRawMemories},
+			activities.ListStage1Input{MaxCount: state.MaxRawMemories, RetentionDays: state.RetentionDays},
 		).Get(ctx, &listResult)
 		if err != nil {
 			logger.Warn("Failed to list stage1 outputs", "error", err)
@@ -0,0 +1,176 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// output_window.go implements the rolling tool output window: only the most
+// recent Tools.OutputWindow tool outputs are sent to the LLM verbatim, and
+// older large outputs are replaced in the prompt by a one-line stub. History
+// keeps every output in full, so the read_artifact tool can return any of
+// them on request without another tool run.
+package workflow
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// minStubbedOutputBytes is the smallest output worth replacing; a stub is
+// around 120 bytes, so shorter outputs stay verbatim.
+const minStubbedOutputBytes = 512
+
+// artifactID returns the short id the model uses to re-read an output.
+// It is derived from the call ID, so it is stable across continue-as-new.
+func artifactID(callID string) string {
+	sum := sha256.Sum256([]byte(callID))
+	return hex.EncodeToString(sum[:4])
+}
+
+// rollToolOutputs returns items with all but the most recent window tool
+// outputs replaced by stubs, and the number of outputs rolled out of the
+// window. Outputs roll out in batches of window/2 so the prompt prefix, and
+// with it the provider's prompt cache, changes only every few calls. The
+// input is not modified.
+func rollToolOutputs(items []models.ConversationItem, window int) ([]models.ConversationItem, int) {
+	if window <= 0 {
+		return items, 0
+	}
+	var outputs []int
+	for i, item := range items {
+		if item.Type == models.ItemTypeFunctionCallOutput {
+			outputs = append(outputs, i)
+		}
+	}
+	step := window / 2
+	if step < 1 {
+		step = 1
+	}
+	rolled := (len(outputs) - window) / step * step
+	if rolled <= 0 {
+		return items, 0
+	}
+
+	calls := make(map[string]models.ConversationItem)
+	turns := make(map[string]string)
+	turnID := ""
+	for _, item := range items {
+		switch item.Type {
+		case models.ItemTypeTurnStarted:
+			turnID = item.TurnID
+		case models.ItemTypeFunctionCall:
+			calls[item.CallID] = item
+			turns[item.CallID] = turnID
+		}
+	}
+
+	out := make([]models.ConversationItem, len(items))
+	copy(out, items)
+	for _, i := range outputs[:rolled] {
+		item := out[i]
+		if item.Output == nil || len(item.Output.Content) < minStubbedOutputBytes {
+			continue
+		}
+		stubbed := *item.Output
+		stubbed.Content = outputStub(calls[item.CallID], turns[item.CallID], item.CallID, len(item.Output.Content))
+		item.Output = &stubbed
+		out[i] = item
+	}
+	return out, rolled
+}
+
+// outputStub is the prompt text for an output outside the window, e.g.
+// "[output of `shell_command: go test ./...` from turn 3 (5120 bytes) —
+// call read_artifact("a1b2c3d4") to re-read]".
+func outputStub(call models.ConversationItem, turnID, callID string, size int) string {
+	what := "a tool call"
+	if call.Name != "" {
+		what = "`" + describeToolCall(call) + "`"
+	}
+	from := "an earlier turn"
+	if turnID != "" {
+		from = strings.Replace(turnID, "-", " ", 1)
+	}
+	return fmt.Sprintf("[output of %s from %s (%d bytes) — call read_artifact(%q) to re-read]",
+		what, from, size, artifactID(callID))
+}
+
+// promptHistory returns the history to send to the LLM with the output
+// window applied. When more outputs have rolled into stubs, outputs the
+// provider already holds verbatim in its response chain would linger, so
+// the chain is dropped and the full windowed history is sent again.
+func (s *SessionState) promptHistory() ([]models.ConversationItem, error) {
+	items, err := s.History.GetForPrompt()
+	if err != nil {
+		return nil, err
+	}
+	items, rolled := rollToolOutputs(items, s.Config.Tools.OutputWindow)
+	if rolled != s.rolledToolOutputs {
+		s.rolledToolOutputs = rolled
+		s.LastResponseID = ""
+		s.lastSentHistoryLen = 0
+	}
+	if s.injectionGuardEnabled() {
+		items = wrapToolOutputs(items)
+	}
+	return items, nil
+}
+
+// handleReadArtifact handles the read_artifact intercepted tool call by
+// returning the full output of the earlier call with the given artifact id.
+func (s *SessionState) handleReadArtifact(ctx workflow.Context, fc models.ConversationItem) models.ConversationItem {
+	var args struct {
+		ID string `json:"id"`
+	}
+	content, ok := "", false
+	err := json.Unmarshal([]byte(fc.Arguments), &args)
+	if err == nil {
+		content, ok = s.findArtifact(strings.TrimSpace(args.ID))
+		if !ok {
+			err = fmt.Errorf("no tool output with id %q", args.ID)
+		}
+	}
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("Invalid read_artifact args", "error", err)
+		falseVal := false
+		return models.ConversationItem{
+			Type:   models.ItemTypeFunctionCallOutput,
+			CallID: fc.CallID,
+			Output: &models.FunctionCallOutputPayload{
+				Content: fmt.Sprintf("Invalid read_artifact arguments: %v", err),
+				Success: &falseVal,
+			},
+		}
+	}
+
+	trueVal := true
+	return models.ConversationItem{
+		Type:   models.ItemTypeFunctionCallOutput,
+		CallID: fc.CallID,
+		Output: &models.FunctionCallOutputPayload{
+			Content: content,
+			Success: &trueVal,
+		},
+	}
+}
+
+// findArtifact returns the output content of the tool call whose artifact
+// id is id. Outputs dropped by compaction are no longer available.
+func (s *SessionState) findArtifact(id string) (string, bool) {
+	if id == "" {
+		return "", false
+	}
+	items, err := s.History.GetRawItems()
+	if err != nil {
+		return "", false
+	}
+	for _, item := range items {
+		if item.Type == models.ItemTypeFunctionCallOutput && item.Output != nil && artifactID(item.CallID) == id {
+			return item.Output.Content, true
+		}
+	}
+	return "", false
+}
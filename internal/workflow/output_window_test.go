@@ -0,0 +1,120 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/history"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// toolRounds builds n turns, each with one shell call and a large output.
+func toolRounds(n int) []models.ConversationItem {
+	var items []models.ConversationItem
+	for i := 1; i <= n; i++ {
+		callID := fmt.Sprintf("call-%d", i)
+		items = append(items,
+			models.ConversationItem{Type: models.ItemTypeTurnStarted, TurnID: fmt.Sprintf("turn-%d", i)},
+			models.ConversationItem{Type: models.ItemTypeFunctionCall, CallID: callID, Name: "shell_command",
+				Arguments: `{"command":"go test ./..."}`},
+			models.ConversationItem{Type: models.ItemTypeFunctionCallOutput, CallID: callID,
+				Output: &models.FunctionCallOutputPayload{Content: strings.Repeat("x", 1000)}},
+		)
+	}
+	return items
+}
+
+func TestRollToolOutputs(t *testing.T) {
+	items := toolRounds(5)
+
+	out, rolled := rollToolOutputs(items, 0)
+	assert.Equal(t, 0, rolled)
+	assert.Equal(t, items, out)
+
+	out, rolled = rollToolOutputs(items, 5)
+	assert.Equal(t, 0, rolled, "all outputs fit in the window")
+	assert.Equal(t, items, out)
+
+	out, rolled = rollToolOutputs(items, 2)
+	assert.Equal(t, 3, rolled)
+	assert.Equal(t, fmt.Sprintf("[output of `shell_command: go test ./...` from turn 1 (1000 bytes) — call read_artifact(%q) to re-read]",
+		artifactID("call-1")), out[2].Output.Content)
+	assert.Contains(t, out[8].Output.Content, "from turn 3")
+	assert.Len(t, out[11].Output.Content, 1000, "outputs in the window stay verbatim")
+	assert.Len(t, items[2].Output.Content, 1000, "input is not modified")
+}
+
+func TestRollToolOutputs_RollsInBatches(t *testing.T) {
+	_, rolled := rollToolOutputs(toolRounds(5), 4)
+	assert.Equal(t, 0, rolled, "one output over the window waits for a full batch")
+	_, rolled = rollToolOutputs(toolRounds(6), 4)
+	assert.Equal(t, 2, rolled)
+	_, rolled = rollToolOutputs(toolRounds(7), 4)
+	assert.Equal(t, 2, rolled)
+}
+
+func TestRollToolOutputs_KeepsSmallOutputs(t *testing.T) {
+	items := toolRounds(3)
+	items[2].Output = &models.FunctionCallOutputPayload{Content: "ok"}
+
+	out, rolled := rollToolOutputs(items, 1)
+	assert.Equal(t, 2, rolled)
+	assert.Equal(t, "ok", out[2].Output.Content)
+	assert.Contains(t, out[5].Output.Content, "read_artifact")
+}
+
+func TestPromptHistory_ResetsResponseChainWhenWindowRolls(t *testing.T) {
+	s := &SessionState{History: history.NewInMemoryHistory()}
+	s.Config.Tools.OutputWindow = 2
+	for _, item := range toolRounds(2) {
+		require.NoError(t, s.History.AddItem(item))
+	}
+	s.LastResponseID = "resp-1"
+	s.lastSentHistoryLen = 6
+
+	_, err := s.promptHistory()
+	require.NoError(t, err)
+	assert.Equal(t, "resp-1", s.LastResponseID)
+
+	for _, item := range toolRounds(3)[6:] {
+		require.NoError(t, s.History.AddItem(item))
+	}
+	_, err = s.promptHistory()
+	require.NoError(t, err)
+	assert.Equal(t, 1, s.rolledToolOutputs)
+	assert.Empty(t, s.LastResponseID)
+	assert.Zero(t, s.lastSentHistoryLen)
+}
+
+func TestFindArtifact(t *testing.T) {
+	s := &SessionState{History: history.NewInMemoryHistory()}
+	for _, item := range toolRounds(2) {
+		require.NoError(t, s.History.AddItem(item))
+	}
+
+	content, ok := s.findArtifact(artifactID("call-2"))
+	assert.True(t, ok)
+	assert.Len(t, content, 1000)
+
+	_, ok = s.findArtifact("deadbeef")
+	assert.False(t, ok)
+	_, ok = s.findArtifact("")
+	assert.False(t, ok)
+}
+
+func TestBuildToolSpecs_AddsReadArtifactWithOutputWindow(t *testing.T) {
+	hasReadArtifact := func(cfg models.ToolsConfig) bool {
+		for _, spec := range buildToolSpecs(cfg, models.ResolvedProfile{}) {
+			if spec.Name == "read_artifact" {
+				return true
+			}
+		}
+		return false
+	}
+	assert.False(t, hasReadArtifact(models.ToolsConfig{EnabledTools: []string{"shell_command"}}))
+	assert.True(t, hasReadArtifact(models.ToolsConfig{EnabledTools: []string{"shell_command"}, OutputWindow: 10}))
+}
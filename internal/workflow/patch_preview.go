@@ -0,0 +1,112 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// patch_preview.go attaches to each pending apply_patch approval the diff
+// the patch would actually make: the worker applies it in memory against
+// the current files, so the user approves the real change (including where
+// fuzzy context matching put it) rather than the patch text, and learns
+// before approving when it does not apply cleanly.
+package workflow
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+const (
+	// patchPreviewTimeout bounds a preview; it only reads local files.
+	patchPreviewTimeout = 30 * time.Second
+	// maxPatchPreviewBytes caps the diff attached to an approval.
+	maxPatchPreviewBytes = 16000
+)
+
+// patchConflictPrefix starts PreviewError for a patch that does not apply.
+const patchConflictPrefix = "patch does not apply cleanly: "
+
+// attachPatchPreviews stores on each pending apply_patch approval that has
+// no preview yet the diff and any conflict. A failed preview leaves the
+// approval as it was.
+func (s *SessionState) attachPatchPreviews(ctx workflow.Context, pending []PendingApproval) {
+	var calls []models.ConversationItem
+	for _, ap := range pending {
+		if ap.Preview == "" && ap.PreviewError == "" {
+			calls = append(calls, models.ConversationItem{CallID: ap.CallID, Name: ap.ToolName, Arguments: ap.Arguments})
+		}
+	}
+	previews := s.previewPatches(ctx, calls)
+	for i := range pending {
+		if out, ok := previews[pending[i].CallID]; ok {
+			setPatchPreview(&pending[i], out)
+		}
+	}
+}
+
+// previewPatches runs PreviewPatch for each apply_patch call, in parallel,
+// and returns the results by call ID. Failed previews are left out.
+func (s *SessionState) previewPatches(ctx workflow.Context, calls []models.ConversationItem) map[string]activities.PatchPreviewOutput {
+	actOpts := workflow.ActivityOptions{
+		StartToCloseTimeout: patchPreviewTimeout,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 1,
+		},
+	}
+	if s.Config.SessionTaskQueue != "" {
+		actOpts.TaskQueue = s.Config.SessionTaskQueue
+	}
+	actCtx := workflow.WithActivityOptions(ctx, actOpts)
+
+	futures := make(map[int]workflow.Future)
+	for i, fc := range calls {
+		input := patchInput(fc.Name, fc.Arguments)
+		if input == "" {
+			continue
+		}
+		futures[i] = workflow.ExecuteActivity(actCtx, "PreviewPatch", activities.PatchPreviewInput{
+			Patch:       input,
+			LineEndings: s.Config.Tools.LineEndings.For(s.Config.Cwd),
+		})
+	}
+
+	logger := workflow.GetLogger(ctx)
+	previews := make(map[string]activities.PatchPreviewOutput, len(futures))
+	for i, fc := range calls {
+		f, ok := futures[i]
+		if !ok {
+			continue
+		}
+		var out activities.PatchPreviewOutput
+		if err := f.Get(ctx, &out); err != nil {
+			logger.Warn("Patch preview failed", "call_id", fc.CallID, "error", err)
+			continue
+		}
+		previews[fc.CallID] = out
+	}
+	return previews
+}
+
+// setPatchPreview stores a PreviewPatch result on an approval.
+func setPatchPreview(ap *PendingApproval, out activities.PatchPreviewOutput) {
+	ap.Preview = truncate(strings.TrimRight(out.Diff, "\n"), maxPatchPreviewBytes)
+	if len(out.Conflicts) > 0 {
+		ap.PreviewError = patchConflictPrefix + strings.Join(out.Conflicts, "; ")
+	}
+}
+
+// patchInput returns the patch text of an apply_patch call, or "".
+func patchInput(toolName, arguments string) string {
+	if toolName != "apply_patch" {
+		return ""
+	}
+	var args map[string]interface{}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return ""
+	}
+	input, _ := args["input"].(string)
+	return input
+}
@@ -0,0 +1,67 @@
+package workflow
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/instructions"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+func TestUserInstructionsForTurn_Persona(t *testing.T) {
+	mentor, _ := instructions.PersonaInstructions(instructions.PersonaMentor)
+
+	s := &SessionState{Config: models.SessionConfiguration{UserInstructions: "project docs"}}
+	assert.Equal(t, "project docs", s.userInstructionsForTurn())
+
+	s.Config.Persona = instructions.PersonaMentor
+	assert.Equal(t, "project docs\n\n"+mentor, s.userInstructionsForTurn())
+
+	s.Config.UserInstructions = ""
+	assert.Equal(t, mentor, s.userInstructionsForTurn())
+}
+
+// TestUpdatePersona_SwitchesMidSession: the persona applies from the next
+// LLM call after update_persona, and "none" turns it off.
+func (s *AgenticWorkflowTestSuite) TestUpdatePersona_SwitchesMidSession() {
+	hasPersona := func(name string) func(activities.LLMActivityInput) bool {
+		return func(in activities.LLMActivityInput) bool {
+			return strings.Contains(in.UserInstructions, "(persona: "+name+")")
+		}
+	}
+	s.env.OnActivity("ExecuteLLMCall", mock.Anything, mock.MatchedBy(hasPersona("concise"))).
+		Return(mockLLMStopResponse("Done.", 10), nil).Once()
+	s.env.OnActivity("ExecuteLLMCall", mock.Anything, mock.MatchedBy(func(in activities.LLMActivityInput) bool {
+		return !strings.Contains(in.UserInstructions, "(persona:")
+	})).Return(mockLLMStopResponse("Here is a longer answer.", 10), nil).Once()
+
+	s.env.RegisterDelayedCallback(func() {
+		s.env.UpdateWorkflow(UpdatePersona, "persona-bogus", &testsuite.TestUpdateCallback{
+			OnAccept:   func() { s.Fail("unknown persona accepted") },
+			OnReject:   func(err error) { assert.ErrorContains(s.T(), err, "unknown persona") },
+			OnComplete: func(interface{}, error) {},
+		}, UpdatePersonaRequest{Persona: "pirate"})
+		s.env.UpdateWorkflow(UpdatePersona, "persona-off", noopCallback(), UpdatePersonaRequest{Persona: models.PersonaNone})
+	}, time.Second*2)
+	s.env.RegisterDelayedCallback(func() {
+		s.env.UpdateWorkflow(UpdateUserInput, "input-2", noopCallback(), UserInput{Content: "Explain"})
+	}, time.Second*3)
+	s.sendShutdown(time.Second * 5)
+
+	input := testInput("Hi")
+	input.Config.Persona = instructions.PersonaConcise
+	s.env.ExecuteWorkflow(AgenticWorkflow, input)
+
+	require.True(s.T(), s.env.IsWorkflowCompleted())
+	require.NoError(s.T(), s.env.GetWorkflowError())
+	var result WorkflowResult
+	require.NoError(s.T(), s.env.GetWorkflowResult(&result))
+	assert.Equal(s.T(), 20, result.TotalTokens)
+}
@@ -63,6 +63,7 @@ func parseUpdatePlanArgs(argsJSON string) (*PlanState, error) {
 		Plan        []struct {
 			Step   string `json:"step"`
 			Status string `json:"status"`
+			Verify string `json:"verify,omitempty"`
 		} `json:"plan"`
 	}
 	if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
@@ -95,6 +96,7 @@ func parseUpdatePlanArgs(argsJSON string) (*PlanState, error) {
 		steps[i] = PlanStep{
 			Step:   s.Step,
 			Status: status,
+			Verify: s.Verify,
 		}
 	}
 
@@ -0,0 +1,327 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// plan_exec.go drives execution of an approved plan one step per turn:
+// the execute_plan Update starts the first open step, and after each step
+// turn the workflow verifies the outcome, marks the step completed, and
+// either starts the next step or pauses for the user.
+package workflow
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/tools"
+)
+
+// ExecutePlanRequest is the payload for the execute_plan Update.
+// Plan replaces the session plan when set; otherwise the current plan (from
+// update_plan or a planner) is executed. Sending it again while execution
+// is paused resumes from the current step.
+type ExecutePlanRequest struct {
+	Plan *PlanState `json:"plan,omitempty"`
+}
+
+// PlanExecution tracks an in-progress plan execution.
+// Persists across ContinueAsNew in SessionState.PlanExec.
+type PlanExecution struct {
+	// StepIndex is the 0-based index of the step being executed.
+	StepIndex int `json:"step_index"`
+	// StepTurnID is the turn running the current step. Turns started by
+	// ordinary user input do not advance the plan.
+	StepTurnID string `json:"step_turn_id,omitempty"`
+	// Paused is set when execution waits for the user: between steps in
+	// unless-trusted mode, after a failed step, or after an interrupt.
+	Paused bool `json:"paused,omitempty"`
+	// LastFailure explains why the current step did not complete.
+	LastFailure string `json:"last_failure,omitempty"`
+}
+
+// PlanProgress summarizes plan execution for TurnStatus.
+type PlanProgress struct {
+	CurrentStep int    `json:"current_step"` // 1-based
+	Completed   int    `json:"completed"`
+	Total       int    `json:"total"`
+	Paused      bool   `json:"paused,omitempty"`
+	LastFailure string `json:"last_failure,omitempty"`
+}
+
+// planVerificationTool runs a step's verification command.
+const planVerificationTool = "shell_command"
+
+// planProgress returns the TurnStatus view of plan execution, or nil when
+// no plan is executing.
+func (s *SessionState) planProgress() *PlanProgress {
+	if s.PlanExec == nil || s.Plan == nil {
+		return nil
+	}
+	p := &PlanProgress{
+		CurrentStep: s.PlanExec.StepIndex + 1,
+		Total:       len(s.Plan.Steps),
+		Paused:      s.PlanExec.Paused,
+		LastFailure: s.PlanExec.LastFailure,
+	}
+	for _, step := range s.Plan.Steps {
+		if step.Status == PlanStepCompleted {
+			p.Completed++
+		}
+	}
+	return p
+}
+
+// validateExecutePlan checks that req can start or resume execution.
+func (s *SessionState) validateExecutePlan(ctrl *LoopControl, req ExecutePlanRequest) error {
+	if ctrl.IsShutdown() {
+		return fmt.Errorf("session is shutting down")
+	}
+	if !s.staysAliveAfterTurn() {
+		return fmt.Errorf("plan execution requires an interactive session")
+	}
+	if ctrl.HasPendingWork() || ctrl.Phase() != PhaseWaitingForInput {
+		return fmt.Errorf("a turn is already in progress")
+	}
+	if s.CostCapPaused {
+		return fmt.Errorf("session paused by the cost cap")
+	}
+	plan := req.Plan
+	if plan == nil {
+		plan = s.Plan
+	}
+	if plan == nil || len(plan.Steps) == 0 {
+		return fmt.Errorf("no plan to execute")
+	}
+	if nextOpenStep(plan) < 0 {
+		return fmt.Errorf("all plan steps are already completed")
+	}
+	return nil
+}
+
+// startPlanExecution begins (or resumes) execution at the first open step
+// and queues its turn. Resuming keeps the last failure so the retry prompt
+// can mention it. Returns the new turn ID.
+func (s *SessionState) startPlanExecution(ctrl *LoopControl, req ExecutePlanRequest) (string, error) {
+	if req.Plan != nil {
+		s.Plan = req.Plan
+		s.PlanExec = nil
+	}
+	if s.PlanExec == nil {
+		s.PlanExec = &PlanExecution{}
+	}
+	return s.startPlanStep(ctrl, nextOpenStep(s.Plan))
+}
+
+// startPlanStep marks step idx in_progress and queues a turn asking the
+// model to carry it out.
+func (s *SessionState) startPlanStep(ctrl *LoopControl, idx int) (string, error) {
+	for i := range s.Plan.Steps {
+		if s.Plan.Steps[i].Status == PlanStepInProgress {
+			s.Plan.Steps[i].Status = PlanStepPending
+		}
+	}
+	s.Plan.Steps[idx].Status = PlanStepInProgress
+	s.PlanExec.StepIndex = idx
+	s.PlanExec.Paused = false
+
+	turnID := s.nextTurnID()
+	if err := s.History.AddItem(models.ConversationItem{
+		Type:   models.ItemTypeTurnStarted,
+		TurnID: turnID,
+	}); err != nil {
+		return "", fmt.Errorf("failed to add turn started: %w", err)
+	}
+	ctrl.NotifyItemAdded()
+
+	if err := s.History.AddItem(models.ConversationItem{
+		Type:    models.ItemTypeUserMessage,
+		Content: s.planStepPrompt(idx),
+		TurnID:  turnID,
+	}); err != nil {
+		return "", fmt.Errorf("failed to add plan step message: %w", err)
+	}
+	ctrl.NotifyItemAdded()
+
+	s.PlanExec.StepTurnID = turnID
+	ctrl.SetPendingUserInput(turnID)
+	return turnID, nil
+}
+
+// planStepPrompt is the user message that drives one step.
+func (s *SessionState) planStepPrompt(idx int) string {
+	step := s.Plan.Steps[idx]
+	var b strings.Builder
+	fmt.Fprintf(&b, "Execute step %d of %d of the approved plan: %s\n\n", idx+1, len(s.Plan.Steps), step.Step)
+	b.WriteString("Work only on this step. When it is done, stop and briefly summarize what you did; the next step is started separately.")
+	if step.Verify != "" {
+		fmt.Fprintf(&b, "\n\nAfter you finish, `%s` will be run to verify the step.", step.Verify)
+	}
+	if s.PlanExec.LastFailure != "" {
+		fmt.Fprintf(&b, "\n\nThe previous attempt did not complete: %s", s.PlanExec.LastFailure)
+	}
+	return b.String()
+}
+
+// advancePlanExecution runs after every turn. If the turn executed the
+// current plan step, it checks the outcome and either completes the step
+// and moves on, or pauses execution for the user.
+func (s *SessionState) advancePlanExecution(ctx workflow.Context, ctrl *LoopControl) {
+	exec := s.PlanExec
+	if exec == nil || s.Plan == nil || exec.StepTurnID != ctrl.CurrentTurnID() {
+		return
+	}
+	logger := workflow.GetLogger(ctx)
+	exec.StepTurnID = ""
+
+	if ctrl.IsInterrupted() {
+		s.pausePlan("step was interrupted")
+		return
+	}
+	if s.CostCapPaused {
+		s.pausePlan("session cost cap reached")
+		return
+	}
+	if failure := s.planStepFailure(ctx, ctrl); failure != "" {
+		logger.Info("Plan step did not complete", "step", exec.StepIndex+1, "reason", failure)
+		s.pausePlan(failure)
+		return
+	}
+
+	s.Plan.Steps[exec.StepIndex].Status = PlanStepCompleted
+	exec.LastFailure = ""
+	logger.Info("Plan step completed", "step", exec.StepIndex+1, "total", len(s.Plan.Steps))
+
+	next := nextOpenStep(s.Plan)
+	if next < 0 {
+		logger.Info("Plan execution finished")
+		s.PlanExec = nil
+		return
+	}
+	exec.StepIndex = next
+	if s.Config.Permissions.ApprovalMode == models.ApprovalUnlessTrusted {
+		// Wait for the user to confirm (execute_plan) before the next step.
+		exec.Paused = true
+		return
+	}
+	if _, err := s.startPlanStep(ctrl, next); err != nil {
+		logger.Warn("Failed to start next plan step", "error", err)
+		s.pausePlan(err.Error())
+	}
+}
+
+// pausePlan stops automatic advancement until the user resumes.
+func (s *SessionState) pausePlan(reason string) {
+	s.PlanExec.Paused = true
+	s.PlanExec.LastFailure = reason
+}
+
+// planStepFailure returns why the just-finished step turn failed, or "" if
+// it succeeded. A step with a verification command is judged by that
+// command; otherwise by whether the turn's last tool call succeeded.
+func (s *SessionState) planStepFailure(ctx workflow.Context, ctrl *LoopControl) string {
+	step := s.Plan.Steps[s.PlanExec.StepIndex]
+	if step.Verify != "" {
+		return s.runPlanVerification(ctx, ctrl, step.Verify)
+	}
+
+	all, _ := s.History.GetRawItems()
+	items := turnItems(all, ctrl.CurrentTurnID())
+	for i := len(items) - 1; i >= 0; i-- {
+		item := items[i]
+		if item.Type != models.ItemTypeFunctionCallOutput || item.Output == nil {
+			continue
+		}
+		if item.Output.Success != nil && !*item.Output.Success {
+			return "last tool call failed"
+		}
+		return ""
+	}
+	return ""
+}
+
+// runPlanVerification executes a step's verification command on the tool
+// worker and records the result in history for the model. Returns "" on
+// success.
+func (s *SessionState) runPlanVerification(ctx workflow.Context, ctrl *LoopControl, command string) string {
+	actOpts := workflow.ActivityOptions{
+		StartToCloseTimeout: time.Duration(tools.DefaultToolTimeoutMs) * time.Millisecond,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 2,
+		},
+	}
+	if s.Config.SessionTaskQueue != "" {
+		actOpts.TaskQueue = s.Config.SessionTaskQueue
+	}
+	actCtx := workflow.WithActivityOptions(ctx, actOpts)
+
+	s.ScratchUsed = true
+	var out activities.ToolActivityOutput
+	err := workflow.ExecuteActivity(actCtx, "ExecuteTool", activities.ToolActivityInput{
+		CallID:       fmt.Sprintf("plan-verify-%d", s.PlanExec.StepIndex+1),
+		ToolName:     planVerificationTool,
+		Arguments:    map[string]interface{}{"command": command},
+		Cwd:          s.Config.Cwd,
+		ScratchID:    s.ConversationID,
+		ExcludePaths: s.Config.Tools.ExcludePaths,
+		Remote:       s.Config.Remote,
+	}).Get(ctx, &out)
+
+	passed := err == nil && out.Success != nil && *out.Success
+	content := out.Content
+	if err != nil {
+		content = err.Error()
+	}
+	_ = s.History.AddItem(models.ConversationItem{
+		Type: models.ItemTypeUserMessage,
+		Content: fmt.Sprintf("<plan_verification step=\"%d\" command=%q passed=\"%t\">\n%s\n</plan_verification>",
+			s.PlanExec.StepIndex+1, command, passed, content),
+		TurnID: ctrl.CurrentTurnID(),
+	})
+	ctrl.NotifyItemAdded()
+
+	if passed {
+		return ""
+	}
+	return fmt.Sprintf("verification `%s` failed", command)
+}
+
+// planVerificationItem matches the history item runPlanVerification records.
+var planVerificationItem = regexp.MustCompile(`(?s)^<plan_verification step="(\d+)" command=("(?:[^"\\]|\\.)*") passed="(true|false)">\n(.*)\n</plan_verification>$`)
+
+// PlanVerificationResult is a plan step verification recorded in history.
+type PlanVerificationResult struct {
+	Step    int
+	Command string
+	Passed  bool
+	Output  string
+}
+
+// ParsePlanVerification parses the history item runPlanVerification
+// records, for clients that report verification results.
+func ParsePlanVerification(content string) (PlanVerificationResult, bool) {
+	m := planVerificationItem.FindStringSubmatch(content)
+	if m == nil {
+		return PlanVerificationResult{}, false
+	}
+	step, _ := strconv.Atoi(m[1])
+	command, err := strconv.Unquote(m[2])
+	if err != nil {
+		return PlanVerificationResult{}, false
+	}
+	return PlanVerificationResult{Step: step, Command: command, Passed: m[3] == "true", Output: m[4]}, true
+}
+
+// nextOpenStep returns the index of the first step not yet completed, or -1.
+func nextOpenStep(plan *PlanState) int {
+	for i, step := range plan.Steps {
+		if step.Status != PlanStepCompleted {
+			return i
+		}
+	}
+	return -1
+}
@@ -100,3 +100,50 @@ func TestParseUpdatePlanArgs_AllCompleted(t *testing.T) {
 		assert.Equal(t, PlanStepCompleted, step.Status)
 	}
 }
+
+func TestParseUpdatePlanArgs_Verify(t *testing.T) {
+	args := `{"plan": [
+		{"step": "Add parser", "status": "pending", "verify": "go test ./parser/..."},
+		{"step": "Wire it up", "status": "pending"}
+	]}`
+	state, err := parseUpdatePlanArgs(args)
+	require.NoError(t, err)
+	assert.Equal(t, "go test ./parser/...", state.Steps[0].Verify)
+	assert.Empty(t, state.Steps[1].Verify)
+}
+
+// ---------------------------------------------------------------------------
+// Unit tests for plan execution helpers
+// ---------------------------------------------------------------------------
+
+func TestNextOpenStep(t *testing.T) {
+	plan := &PlanState{Steps: []PlanStep{
+		{Step: "a", Status: PlanStepCompleted},
+		{Step: "b", Status: PlanStepPending},
+		{Step: "c", Status: PlanStepPending},
+	}}
+	assert.Equal(t, 1, nextOpenStep(plan))
+
+	plan.Steps[1].Status = PlanStepCompleted
+	plan.Steps[2].Status = PlanStepCompleted
+	assert.Equal(t, -1, nextOpenStep(plan))
+}
+
+func TestPlanProgress(t *testing.T) {
+	s := &SessionState{}
+	assert.Nil(t, s.planProgress())
+
+	s.Plan = &PlanState{Steps: []PlanStep{
+		{Step: "a", Status: PlanStepCompleted},
+		{Step: "b", Status: PlanStepInProgress},
+		{Step: "c", Status: PlanStepPending},
+	}}
+	s.PlanExec = &PlanExecution{StepIndex: 1, Paused: true, LastFailure: "verification `make` failed"}
+	p := s.planProgress()
+	require.NotNil(t, p)
+	assert.Equal(t, 2, p.CurrentStep)
+	assert.Equal(t, 1, p.Completed)
+	assert.Equal(t, 3, p.Total)
+	assert.True(t, p.Paused)
+	assert.Equal(t, "verification `make` failed", p.LastFailure)
+}
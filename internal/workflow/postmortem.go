@@ -0,0 +1,102 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// postmortem.go builds the structured post-mortem recorded when a turn ends
+// in error: what the user asked for, the last tool call that succeeded, the
+// error class, and a suggested next action.
+package workflow
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+const (
+	// postMortemAttemptedLen caps the user request quoted in a post-mortem.
+	postMortemAttemptedLen = 200
+	// postMortemStepLen caps the tool argument shown as the last step.
+	postMortemStepLen = 120
+)
+
+// stepArgKeys are the tool arguments that best describe a call, in order.
+var stepArgKeys = []string{"command", "cmd", "path", "file_path", "pattern", "query"}
+
+// recordTurnFailure builds a post-mortem for the current turn, adds it to
+// history, and keeps it as LastPostMortem for the WorkflowResult.
+func (s *SessionState) recordTurnFailure(ctrl *LoopControl, class models.TurnErrorClass, errMsg string) {
+	items, _ := s.History.GetRawItems()
+	pm := buildPostMortem(items, ctrl.CurrentTurnID(), class, errMsg)
+	s.LastPostMortem = pm
+	s.FailedTurns++
+	_ = s.History.AddItem(models.ConversationItem{
+		Type:       models.ItemTypeTurnFailure,
+		TurnID:     ctrl.CurrentTurnID(),
+		PostMortem: pm,
+	})
+	ctrl.NotifyItemAdded()
+	ctrl.Emit(SessionEvent{Type: EventError, Message: string(class) + ": " + errMsg})
+}
+
+// buildPostMortem summarizes the items of turnID.
+func buildPostMortem(items []models.ConversationItem, turnID string, class models.TurnErrorClass, errMsg string) *models.PostMortem {
+	pm := &models.PostMortem{
+		TurnID:          turnID,
+		ErrorClass:      class,
+		Error:           errMsg,
+		SuggestedAction: suggestedAction(class),
+	}
+
+	calls := make(map[string]models.ConversationItem)
+	for _, item := range turnItems(items, turnID) {
+		switch item.Type {
+		case models.ItemTypeUserMessage:
+			// The first user message is the request; later ones are
+			// injected context (environment, file mentions, verification).
+			if pm.Attempted == "" && !strings.HasPrefix(item.Content, "<") {
+				pm.Attempted = truncate(strings.TrimSpace(item.Content), postMortemAttemptedLen)
+			}
+		case models.ItemTypeFunctionCall:
+			calls[item.CallID] = item
+		case models.ItemTypeFunctionCallOutput:
+			pm.ToolCalls++
+			if item.Output != nil && item.Output.Success != nil && !*item.Output.Success {
+				pm.FailedToolCalls++
+				continue
+			}
+			if call, ok := calls[item.CallID]; ok {
+				pm.LastSuccessfulStep = describeToolCall(call)
+			}
+		}
+	}
+	return pm
+}
+
+// describeToolCall renders a call as "name: <main argument>".
+func describeToolCall(call models.ConversationItem) string {
+	var args map[string]interface{}
+	if err := json.Unmarshal([]byte(call.Arguments), &args); err == nil {
+		for _, key := range stepArgKeys {
+			if v, ok := args[key].(string); ok && v != "" {
+				return call.Name + ": " + truncate(v, postMortemStepLen)
+			}
+		}
+	}
+	return call.Name
+}
+
+// suggestedAction returns the next step to offer the user for class.
+func suggestedAction(class models.TurnErrorClass) string {
+	switch class {
+	case models.TurnErrorLLMFatal:
+		return "Check the provider credentials, model name and request size, then resend the message."
+	case models.TurnErrorLLMFailure:
+		return "The model provider did not respond; retry the message, or switch models with /model."
+	case models.TurnErrorRepeatedToolCalls:
+		return "The model was stuck repeating the same tool calls; rephrase the request or give a more specific next step."
+	case models.TurnErrorIterationLimit:
+		return "The task needs more steps than one turn allows; reply \"continue\" or break it into smaller requests."
+	default:
+		return "Review the error and resend the message."
+	}
+}
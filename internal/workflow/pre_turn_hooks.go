@@ -0,0 +1,69 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// pre_turn_hooks.go runs embedder-registered pre-turn hooks (internal/hooks)
+// before each LLM call via the RunPreTurnHooks activity, so their output is
+// recorded in workflow history and replays deterministically.
+//
+// NOTE: Temporal-specific addition (not in Codex Rust).
+package workflow
+
+import (
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/hooks"
+)
+
+// runPreTurnHooks runs the configured pre-turn hooks and applies their
+// output: developer-instruction text is stashed for the upcoming LLM call,
+// and synthetic items are added to history before the turn's first call
+// only, so the tool loop does not repeat them. Hook failures are logged and
+// never fail the turn.
+func (s *SessionState) runPreTurnHooks(ctx workflow.Context, ctrl *LoopControl) {
+	s.hookInstructions = ""
+	if len(s.Config.PreTurnHooks) == 0 {
+		return
+	}
+	logger := workflow.GetLogger(ctx)
+
+	actCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 3,
+		},
+	})
+	input := activities.PreTurnHooksInput{
+		Hooks: s.Config.PreTurnHooks,
+		Turn: hooks.PreTurnInput{
+			ConversationID: s.ConversationID,
+			TurnID:         ctrl.CurrentTurnID(),
+			Cwd:            s.Config.Cwd,
+			Iteration:      s.IterationCount,
+		},
+	}
+
+	var out activities.PreTurnHooksOutput
+	if err := workflow.ExecuteActivity(actCtx, "RunPreTurnHooks", input).Get(ctx, &out); err != nil {
+		logger.Warn("Pre-turn hooks failed, continuing without", "error", err)
+		return
+	}
+	for _, e := range out.Errors {
+		logger.Warn("Pre-turn hook skipped", "error", e)
+	}
+
+	s.hookInstructions = out.DeveloperInstructions
+	if s.hookItemsTurnID == ctrl.CurrentTurnID() {
+		return
+	}
+	s.hookItemsTurnID = ctrl.CurrentTurnID()
+	for _, item := range out.Items {
+		if item.TurnID == "" {
+			item.TurnID = ctrl.CurrentTurnID()
+		}
+		_ = s.History.AddItem(item)
+		ctrl.NotifyItemAdded()
+	}
+}
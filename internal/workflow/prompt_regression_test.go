@@ -0,0 +1,197 @@
+package workflow
+
+// Prompt-regression scenarios for the default instructions in
+// internal/instructions. Each scenario runs a scripted task against the mock
+// provider with the instructions a real session gets, and checks two things:
+// the prompt the model receives still asks for the behavior (canary phrases,
+// with the tools they rely on offered), and the workflow carries the
+// behavior out when the model follows it. Run them after editing instruction text:
+//
+//	go test ./internal/workflow -run 'TestAgenticWorkflowSuite/TestPromptRegression'
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/instructions"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/tools"
+)
+
+// promptScenario is one scripted prompt-regression scenario.
+type promptScenario struct {
+	task         string
+	approvalMode models.ApprovalMode
+	// canaries are regexps the base + developer instructions must match.
+	canaries []string
+	// tools are the tools the canaries tell the model to use; they must be
+	// offered in the same request.
+	tools []string
+	// script is the model's responses, in order.
+	script []activities.LLMActivityOutput
+}
+
+// scriptedCall is a model response making one tool call.
+func scriptedCall(callID, name, args string) activities.LLMActivityOutput {
+	return activities.LLMActivityOutput{
+		Items:        []models.ConversationItem{{Type: models.ItemTypeFunctionCall, CallID: callID, Name: name, Arguments: args}},
+		FinishReason: models.FinishReasonToolCalls,
+	}
+}
+
+// scenarioInput is the workflow input of a scenario: the default instructions
+// and tool set a session gets, rather than testInput's placeholders.
+func scenarioInput(sc promptScenario) WorkflowInput {
+	input := testInput(sc.task)
+	merged := instructions.MergeInstructions(instructions.MergeInput{
+		ApprovalMode: string(sc.approvalMode),
+		Cwd:          "/repo",
+	})
+	input.Config.BaseInstructions = merged.Base
+	input.Config.DeveloperInstructions = merged.Developer
+	input.Config.Cwd = "/repo"
+	input.Config.Permissions.ApprovalMode = sc.approvalMode
+	input.Config.Tools.EnabledTools = tools.DefaultEnabledTools()
+	return input
+}
+
+// runPromptScenario runs sc until shutdownAt and checks the canaries against
+// the first prompt. It returns the tool calls the workflow executed.
+func (s *AgenticWorkflowTestSuite) runPromptScenario(sc promptScenario, shutdownAt time.Duration) []string {
+	var prompts []activities.LLMActivityInput
+	for _, out := range sc.script {
+		out := out
+		out.TokenUsage = models.TokenUsage{TotalTokens: 10}
+		s.env.OnActivity("ExecuteLLMCall", mock.Anything, mock.Anything).
+			Return(func(_ context.Context, in activities.LLMActivityInput) (activities.LLMActivityOutput, error) {
+				prompts = append(prompts, in)
+				return out, nil
+			}).Once()
+	}
+	var executed []string
+	trueVal := true
+	s.env.OnActivity("ExecuteTool", mock.Anything, mock.Anything).
+		Return(func(_ context.Context, in activities.ToolActivityInput) (activities.ToolActivityOutput, error) {
+			executed = append(executed, in.ToolName)
+			return activities.ToolActivityOutput{CallID: in.CallID, Content: "ok", Success: &trueVal}, nil
+		}).Maybe()
+
+	s.sendShutdown(shutdownAt)
+	s.env.ExecuteWorkflow(AgenticWorkflow, scenarioInput(sc))
+
+	require.True(s.T(), s.env.IsWorkflowCompleted())
+	require.NoError(s.T(), s.env.GetWorkflowError())
+	require.NotEmpty(s.T(), prompts)
+
+	prompt := prompts[0].BaseInstructions + "\n" + prompts[0].DeveloperInstructions
+	for _, canary := range sc.canaries {
+		assert.Regexp(s.T(), regexp.MustCompile(canary), prompt, "instructions lost guidance %q", canary)
+	}
+	offered := make(map[string]bool)
+	for _, spec := range prompts[0].ToolSpecs {
+		offered[spec.Name] = true
+	}
+	for _, name := range sc.tools {
+		assert.True(s.T(), offered[name], "instructions rely on %s but it is not offered", name)
+	}
+	s.env.AssertExpectations(s.T())
+	return executed
+}
+
+// TestPromptRegression_AsksBeforeDestructiveOps: the model is told not to
+// run destructive commands unasked, and when it tries, the user is asked;
+// a denial ends the turn without running the command.
+func (s *AgenticWorkflowTestSuite) TestPromptRegression_AsksBeforeDestructiveOps() {
+	sc := promptScenario{
+		task:         "The build directory is stale, clean it up",
+		approvalMode: models.ApprovalUnlessTrusted,
+		canaries: []string{
+			`NEVER run destructive commands like rm -rf`,
+			`When an action cannot be undone, ask the user first`,
+			`Mutating operations require user approval`,
+		},
+		tools: []string{"shell_command"},
+		script: []activities.LLMActivityOutput{
+			scriptedCall("call-rm", "shell_command", `{"command": "rm -rf build"}`),
+		},
+	}
+
+	s.env.RegisterDelayedCallback(func() {
+		status := s.queryTurnStatus()
+		assert.Equal(s.T(), PhaseApprovalPending, status.Phase)
+		if assert.Len(s.T(), status.PendingApprovals, 1) {
+			assert.Equal(s.T(), "call-rm", status.PendingApprovals[0].CallID)
+		}
+		s.env.UpdateWorkflow(UpdateApprovalResponse, "approval-1", noopCallback(),
+			ApprovalResponse{Denied: []string{"call-rm"}})
+	}, time.Second*2)
+
+	executed := s.runPromptScenario(sc, time.Second*4)
+	assert.Empty(s.T(), executed, "denied destructive command must not run")
+}
+
+// TestPromptRegression_ReadsBeforeEditing: the model is told to read a file
+// before patching it, and both tools run in that order.
+func (s *AgenticWorkflowTestSuite) TestPromptRegression_ReadsBeforeEditing() {
+	sc := promptScenario{
+		task:         "Rename the Greet function in greet.go to Hello",
+		approvalMode: models.ApprovalNever,
+		canaries: []string{
+			`Read a file with read_file before editing it`,
+			`Use apply_patch to edit files`,
+		},
+		tools: []string{"read_file", "apply_patch"},
+		script: []activities.LLMActivityOutput{
+			scriptedCall("call-read", "read_file", `{"file_path": "/repo/greet.go"}`),
+			scriptedCall("call-patch", "apply_patch",
+				`{"input": "*** Begin Patch\n*** Update File: greet.go\n@@\n-func Greet() {}\n+func Hello() {}\n*** End Patch"}`),
+			mockLLMStopResponse("Renamed Greet to Hello in greet.go.", 10),
+		},
+	}
+
+	executed := s.runPromptScenario(sc, time.Second*3)
+	assert.Equal(s.T(), []string{"read_file", "apply_patch"}, executed)
+}
+
+// TestPromptRegression_PlansMultiStepTasks: the model is told to keep a plan
+// with update_plan for multi-step work, and the plan reaches the user.
+func (s *AgenticWorkflowTestSuite) TestPromptRegression_PlansMultiStepTasks() {
+	sc := promptScenario{
+		task:         "Add a --verbose flag, document it in the README, and add a test for it",
+		approvalMode: models.ApprovalNever,
+		canaries: []string{
+			`access to an update_plan tool which tracks steps`,
+			`do more than one thing in a single prompt`,
+			`Do not use plans for simple or single-step queries`,
+		},
+		tools: []string{"update_plan"},
+		script: []activities.LLMActivityOutput{
+			scriptedCall("call-plan", "update_plan", `{"plan": [`+
+				`{"step": "Add --verbose flag", "status": "in_progress"}, `+
+				`{"step": "Document it in README", "status": "pending"}, `+
+				`{"step": "Add a test", "status": "pending"}]}`),
+			mockLLMStopResponse("Planned three steps; starting with the flag.", 10),
+		},
+	}
+
+	s.env.RegisterDelayedCallback(func() {
+		status := s.queryTurnStatus()
+		if assert.NotNil(s.T(), status.Plan) {
+			var steps []string
+			for _, step := range status.Plan.Steps {
+				steps = append(steps, step.Step)
+			}
+			assert.Equal(s.T(), "Add --verbose flag; Document it in README; Add a test", strings.Join(steps, "; "))
+		}
+	}, time.Second*2)
+
+	executed := s.runPromptScenario(sc, time.Second*3)
+	assert.Empty(s.T(), executed, "update_plan is handled by the workflow, not a tool activity")
+}
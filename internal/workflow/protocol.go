@@ -0,0 +1,99 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// protocol.go versions the payloads external clients exchange with the
+// workflow: WorkflowInput, UserInput, ApprovalResponse, EscalationResponse,
+// TurnStatus and ConversationItem. Each top-level payload carries a version
+// field; the workflow rejects inputs from a newer protocol than it speaks
+// with an error that names both versions, instead of silently dropping
+// fields it does not know. JSON Schemas for the payloads are published under
+// schemas/v<N>/ (see ContractSchemas).
+//
+// Outgoing items are stamped with the version they were encoded for, and
+// items of types newer than the client's version become notices. Clients
+// negotiate the version they send from TurnStatus.version (see
+// NegotiateProtocol) and decode items tolerantly, so mixed-version
+// deployments degrade instead of failing.
+//
+// NOTE: Temporal-specific addition (not in Codex Rust).
+package workflow
+
+import (
+	"fmt"
+
+	"go.temporal.io/sdk/temporal"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// ProtocolVersion is the version of the client payload contract. Bump it
+// when a payload changes incompatibly (a field is removed, renamed or changes
+// meaning), and publish a new schemas/v<N>/ directory.
+const ProtocolVersion = 1
+
+// MinProtocolVersion is the oldest client protocol the workflow accepts.
+const MinProtocolVersion = 1
+
+// ErrTypeUnsupportedProtocol is the ApplicationError type of a workflow
+// started with an unsupported WorkflowInput version.
+const ErrTypeUnsupportedProtocol = "UnsupportedProtocolVersion"
+
+// checkProtocolVersion validates the version of an incoming payload. Zero
+// means the client predates versioning and is treated as version 1.
+func checkProtocolVersion(payload string, v int) error {
+	switch {
+	case v == 0:
+		return nil
+	case v > ProtocolVersion:
+		return fmt.Errorf("%s: unsupported protocol version %d: this worker speaks versions %d-%d; upgrade the worker",
+			payload, v, MinProtocolVersion, ProtocolVersion)
+	case v < MinProtocolVersion:
+		return fmt.Errorf("%s: unsupported protocol version %d: this worker speaks versions %d-%d; upgrade the client",
+			payload, v, MinProtocolVersion, ProtocolVersion)
+	}
+	return nil
+}
+
+// checkWorkflowInputVersion fails a workflow run started with an
+// unsupported WorkflowInput version. The error is non-retryable: retrying
+// the same input cannot succeed.
+func checkWorkflowInputVersion(input WorkflowInput) error {
+	if err := checkProtocolVersion("WorkflowInput", input.Version); err != nil {
+		return temporal.NewNonRetryableApplicationError(err.Error(), ErrTypeUnsupportedProtocol, nil)
+	}
+	return nil
+}
+
+// NegotiateProtocol returns the protocol version a client should use with
+// a worker that reports workerVersion in TurnStatus.version: the newer
+// version both sides speak. Zero means a worker that predates versioning
+// (version 1). It fails when the worker is too old for this client.
+func NegotiateProtocol(workerVersion int) (int, error) {
+	if workerVersion == 0 {
+		workerVersion = 1
+	}
+	if workerVersion < MinProtocolVersion {
+		return 0, fmt.Errorf("the worker speaks protocol version %d; this client speaks versions %d-%d; upgrade the worker",
+			workerVersion, MinProtocolVersion, ProtocolVersion)
+	}
+	return min(workerVersion, ProtocolVersion), nil
+}
+
+// itemsForClient returns a copy of items for a client speaking version v
+// (zero means 1): each item is stamped with the version it is encoded for,
+// and items of types introduced after v are replaced by notices.
+func itemsForClient(items []models.ConversationItem, v int) []models.ConversationItem {
+	if v <= 0 {
+		v = 1
+	}
+	v = min(v, ProtocolVersion)
+	out := make([]models.ConversationItem, len(items))
+	for i, item := range items {
+		if since := item.Type.Since(); since > v {
+			item.SchemaVersion = since
+			item = models.UnsupportedItemNotice(item)
+		}
+		item.SchemaVersion = v
+		out[i] = item
+	}
+	return out
+}
@@ -0,0 +1,122 @@
+package workflow
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+func TestCheckProtocolVersion(t *testing.T) {
+	assert.NoError(t, checkProtocolVersion("UserInput", 0), "unversioned clients are accepted")
+	assert.NoError(t, checkProtocolVersion("UserInput", ProtocolVersion))
+
+	err := checkProtocolVersion("UserInput", ProtocolVersion+1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "UserInput: unsupported protocol version 2")
+	assert.Contains(t, err.Error(), "upgrade the worker")
+
+	err = checkProtocolVersion("ApprovalResponse", -1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "upgrade the client")
+}
+
+func TestCheckWorkflowInputVersion_NonRetryable(t *testing.T) {
+	err := checkWorkflowInputVersion(WorkflowInput{Version: ProtocolVersion + 1})
+	var appErr *temporal.ApplicationError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, ErrTypeUnsupportedProtocol, appErr.Type())
+	assert.True(t, appErr.NonRetryable())
+}
+
+func TestNegotiateProtocol(t *testing.T) {
+	v, err := NegotiateProtocol(0)
+	require.NoError(t, err, "workers that predate versioning speak version 1")
+	assert.Equal(t, 1, v)
+
+	v, err = NegotiateProtocol(ProtocolVersion + 1)
+	require.NoError(t, err)
+	assert.Equal(t, ProtocolVersion, v, "a newer worker is spoken to in our version")
+
+	_, err = NegotiateProtocol(-1)
+	assert.ErrorContains(t, err, "upgrade the worker")
+}
+
+func TestItemsForClient_StampsVersion(t *testing.T) {
+	stored := []models.ConversationItem{{Type: models.ItemTypeUserMessage, Seq: 0, Content: "hi"}}
+
+	out := itemsForClient(stored, 0)
+
+	require.Len(t, out, 1)
+	assert.Equal(t, 1, out[0].SchemaVersion)
+	assert.Equal(t, "hi", out[0].Content)
+	assert.Zero(t, stored[0].SchemaVersion, "stored items are not modified")
+}
+
+func TestStateUpdateResponse_DecodesNewerItems(t *testing.T) {
+	data := []byte(`{"turn_id":"t1","items":[
+		{"type":"assistant_message","seq":1,"content":"ok","schema_version":2},
+		{"type":"image_generation","seq":2,"turn_id":"t1","schema_version":2},
+		{"type":"assistant_message","seq":3,"content":{"parts":["x"]},"schema_version":2}
+	],"status":{"phase":"waiting_for_input","version":2}}`)
+
+	var resp StateUpdateResponse
+	require.NoError(t, json.Unmarshal(data, &resp))
+
+	require.Len(t, resp.Items, 3)
+	assert.Equal(t, "ok", resp.Items[0].Content)
+	assert.Equal(t, models.ItemTypeNotice, resp.Items[1].Type)
+	assert.Equal(t, 2, resp.Items[1].Seq)
+	assert.Equal(t, "[image_generation item not shown: it needs a newer client (protocol version 2)]", resp.Items[1].Content)
+	assert.Equal(t, models.ItemTypeNotice, resp.Items[2].Type, "undecodable fields degrade to a notice")
+	assert.Equal(t, 3, resp.Items[2].Seq)
+	assert.Equal(t, 2, resp.Status.Version)
+}
+
+// TestContractSchemas_MatchPublished fails when a contract type changed
+// without regenerating the published schemas:
+//
+//	go run ./cmd/tcx schema --out schemas/v1
+func TestContractSchemas_MatchPublished(t *testing.T) {
+	schemas, err := ContractSchemas()
+	require.NoError(t, err)
+
+	dir := filepath.Join("..", "..", "schemas", "v1")
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	assert.Len(t, files, len(schemas), "one published file per schema")
+
+	for name, s := range schemas {
+		want, err := MarshalContractSchema(s)
+		require.NoError(t, err)
+		got, err := os.ReadFile(filepath.Join(dir, name+".json"))
+		require.NoError(t, err, name)
+		assert.Equal(t, string(want), string(got), "%s.json is out of date", name)
+	}
+}
+
+func TestContractSchemas_VersionedPayloads(t *testing.T) {
+	schemas, err := ContractSchemas()
+	require.NoError(t, err)
+
+	for _, name := range []string{"WorkflowInput", "UserInput", "ApprovalResponse", "EscalationResponse", "TurnStatus"} {
+		s := schemas[name]
+		require.NotNil(t, s, name)
+		assert.Contains(t, s.Properties, "version", name)
+		assert.Nil(t, s.AdditionalProperties, "%s accepts additional properties", name)
+	}
+	assert.Contains(t, schemas["TurnStatus"].Required, "version")
+	assert.Contains(t, schemas["ConversationItem"].Properties["type"].Enum, "function_call_output")
+
+	data, err := MarshalContractSchema(schemas["UserInput"])
+	require.NoError(t, err)
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &doc))
+	assert.Equal(t, "https://github.com/mfateev/temporal-agent-harness/schemas/v1/UserInput.json", doc["$id"])
+}
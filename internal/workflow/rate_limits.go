@@ -0,0 +1,98 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// rate_limits.go acts on the rate-limit headroom the provider reports with
+// each LLM response. Before the next call, if it would exceed what is left
+// (no requests, or fewer tokens than the prompt), the session waits for the
+// window to reset, or, when that is longer than Config.RateLimits allows,
+// moves to the configured downgrade model until the reset, instead of
+// running into a 429 mid-turn.
+package workflow
+
+import (
+	"fmt"
+	"time"
+
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// RateLimitDowngrade records a move to the downgrade model: the model to
+// return to and when its rate-limit window resets.
+type RateLimitDowngrade struct {
+	Provider string    `json:"provider"`
+	Model    string    `json:"model"`
+	Until    time.Time `json:"until"`
+}
+
+// recordRateLimits keeps the headroom reported with an LLM response for
+// the current model. Responses without rate-limit headers keep the last.
+func (s *SessionState) recordRateLimits(rl *models.RateLimitSnapshot) {
+	if rl == nil {
+		return
+	}
+	s.RateLimits = rl
+	s.RateLimitModel = modelRef(s.callModel())
+}
+
+// planRateLimit decides what the next LLM call needs at now: a wait for the
+// window to reset at until, or a downgrade until then. Both are zero when
+// the headroom is enough, unknown, or for another model, and when the wait
+// is too long with no downgrade model to move to; the call then goes ahead.
+func (s *SessionState) planRateLimit(now time.Time) (wait time.Duration, until time.Time, downgrade bool) {
+	if s.RateLimits == nil || s.RateLimitModel != modelRef(s.Config.Model) {
+		return 0, time.Time{}, false
+	}
+	need, _ := s.History.EstimateTokenCount()
+	until, ok := s.RateLimits.ExhaustedUntil(need)
+	if !ok || !until.After(now) {
+		return 0, time.Time{}, false
+	}
+	if wait := until.Sub(now); wait <= s.Config.RateLimits.MaxWait() {
+		return wait, until, false
+	}
+	provider, model, ok := s.Config.RateLimits.Downgrade()
+	if !ok || (provider == s.Config.Model.Provider && model == s.Config.Model.Model) {
+		return 0, time.Time{}, false
+	}
+	return 0, until, true
+}
+
+// awaitRateLimitHeadroom runs before each LLM call: it returns from a
+// downgrade whose window has reset, then waits or downgrades as
+// planRateLimit decides. An interrupt ends the wait early.
+func (s *SessionState) awaitRateLimitHeadroom(ctx workflow.Context, ctrl *LoopControl) {
+	logger := workflow.GetLogger(ctx)
+	now := workflow.Now(ctx)
+
+	if d := s.RateLimitDowngrade; d != nil && !now.Before(d.Until) {
+		from := modelRef(s.Config.Model)
+		s.switchModel(d.Provider, d.Model, 0,
+			fmt.Sprintf("back, because the rate limit of %s/%s has reset", d.Provider, d.Model))
+		s.addNotice(ctrl, fmt.Sprintf("Rate limit of %s/%s has reset; switched back from %s.", d.Provider, d.Model, from))
+	}
+
+	wait, until, downgrade := s.planRateLimit(now)
+	switch {
+	case downgrade:
+		from := s.Config.Model
+		provider, model, _ := s.Config.RateLimits.Downgrade()
+		logger.Warn("Rate limit exhausted, downgrading model",
+			"from", modelRef(from), "to", s.Config.RateLimits.DowngradeModel, "until", until)
+		s.switchModel(provider, model, 0,
+			fmt.Sprintf("because the rate limit of %s is exhausted until %s", modelRef(from), until.Format(time.RFC3339)))
+		s.RateLimitDowngrade = &RateLimitDowngrade{Provider: from.Provider, Model: from.Model, Until: until}
+		s.addNotice(ctrl, fmt.Sprintf("Rate limit of %s exhausted for %s; switched to %s until it resets.",
+			modelRef(from), until.Sub(now).Round(time.Second), s.Config.RateLimits.DowngradeModel))
+	case wait > 0:
+		logger.Info("Rate limit nearly exhausted, waiting for reset", "model", s.RateLimitModel, "wait", wait)
+		s.addNotice(ctrl, fmt.Sprintf("Rate limit of %s nearly exhausted; waiting %s for it to reset.",
+			s.RateLimitModel, wait.Round(time.Second)))
+		_, _ = workflow.AwaitWithTimeout(ctx, wait, ctrl.IsInterrupted)
+	}
+}
+
+// modelRef returns "provider/model".
+func modelRef(m models.ModelConfig) string {
+	return m.Provider + "/" + m.Model
+}
@@ -0,0 +1,137 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// report.go sends the end-of-session report when [report] in config.toml
+// configures a channel: in the cleanup phase of a top-level session, the
+// turns still in history, usage, cost and plan verification results are
+// handed to the DeliverSessionReport activity, which adds the working tree
+// diffstat and delivers it (see internal/report).
+package workflow
+
+import (
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/report"
+)
+
+// reportResponseLimit caps each turn's response in the report, in runes.
+const reportResponseLimit = 2000
+
+// reportDeliveryTimeout bounds the DeliverSessionReport activity: a slow
+// webhook or SMTP server must not hold the session open.
+const reportDeliveryTimeout = 2 * time.Minute
+
+// deliverSessionReport sends the end-of-session report of a top-level
+// session. Best-effort: failures are logged and never fail the workflow.
+func (s *SessionState) deliverSessionReport(ctx workflow.Context, endReason string) {
+	if !s.Config.Report.Enabled() || (s.AgentCtl != nil && s.AgentCtl.ParentDepth > 0) {
+		return
+	}
+	items, _ := s.History.GetRawItems()
+	input := activities.DeliverSessionReportInput{
+		Config: *s.Config.Report,
+		Report: s.buildReport(items, endReason, workflow.Now(ctx)),
+	}
+
+	actOpts := workflow.ActivityOptions{
+		StartToCloseTimeout: reportDeliveryTimeout,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 2,
+		},
+	}
+	if s.Config.SessionTaskQueue != "" {
+		actOpts.TaskQueue = s.Config.SessionTaskQueue
+	}
+	logger := workflow.GetLogger(ctx)
+	var out activities.DeliverSessionReportOutput
+	if err := workflow.ExecuteActivity(workflow.WithActivityOptions(ctx, actOpts), "DeliverSessionReport", input).Get(ctx, &out); err != nil {
+		logger.Warn("Failed to deliver session report", "error", err)
+		return
+	}
+	for _, failure := range out.Failed {
+		logger.Warn("Session report channel failed", "error", failure)
+	}
+	if len(out.Delivered) > 0 {
+		logger.Info("Delivered session report", "to", strings.Join(out.Delivered, "; "))
+	}
+}
+
+// buildReport collects the report data from session state and history.
+func (s *SessionState) buildReport(items []models.ConversationItem, endReason string, now time.Time) report.Report {
+	m := s.currentMetrics()
+	r := report.Report{
+		SessionID:    s.ConversationID,
+		Model:        m.Model,
+		Cwd:          s.Config.Cwd,
+		EndedAt:      now,
+		EndReason:    endReason,
+		TotalTurns:   s.TurnCounter,
+		Turns:        reportTurns(items),
+		TotalTokens:  int(m.TotalTokens),
+		CostUSD:      m.CostUSD,
+		ToolCalls:    len(s.ToolCallsExecuted),
+		ToolFailures: int(m.ToolFailures),
+	}
+	if s.LastPostMortem != nil {
+		r.Error = s.LastPostMortem.Error
+	}
+	for _, item := range items {
+		if item.Type != models.ItemTypeUserMessage {
+			continue
+		}
+		if v, ok := ParsePlanVerification(item.Content); ok {
+			r.Verifications = append(r.Verifications, report.Verification{Step: v.Step, Command: v.Command, Passed: v.Passed})
+		}
+	}
+	return r
+}
+
+// reportTurns summarizes the turns in items: the request that started
+// each, its last assistant message, its tool call count and how it ended.
+// Turn membership is positional, from one TurnStarted marker to the next.
+func reportTurns(items []models.ConversationItem) []report.Turn {
+	var turns []report.Turn
+	for _, item := range items {
+		if item.Type == models.ItemTypeTurnStarted {
+			turns = append(turns, report.Turn{TurnID: item.TurnID, Status: report.TurnCompleted})
+			continue
+		}
+		if len(turns) == 0 {
+			continue
+		}
+		t := &turns[len(turns)-1]
+		switch item.Type {
+		case models.ItemTypeUserMessage:
+			if t.Request == "" {
+				t.Request = item.Content
+			}
+		case models.ItemTypeAssistantMessage:
+			if item.Content != "" {
+				t.Response = truncateRunes(item.Content, reportResponseLimit)
+			}
+		case models.ItemTypeFunctionCall:
+			t.ToolCalls++
+		case models.ItemTypeTurnFailure:
+			t.Status = report.TurnFailed
+		case models.ItemTypeTurnComplete:
+			if strings.HasPrefix(item.Content, "interrupted:") {
+				t.Status = report.TurnInterrupted
+			}
+		}
+	}
+	return turns
+}
+
+// truncateRunes shortens s to at most limit runes, marking the cut.
+func truncateRunes(s string, limit int) string {
+	r := []rune(s)
+	if len(r) <= limit {
+		return s
+	}
+	return string(r[:limit]) + "…"
+}
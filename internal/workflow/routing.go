@@ -0,0 +1,200 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// routing.go sends obviously trivial turns to a cheaper model when
+// Config.TrivialRouting is set: a short question, with no files or skills
+// attached and no words asking for work that needs tools. The session's own
+// model takes the turn back when the cheap model requests a tool, and
+// answers the next turn when the user objects to a routed answer. Each
+// turn's TurnComplete item records the model that answered it. /route off
+// (set_trivial_routing) opts the session out.
+package workflow
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// trivialQuestionWords start a message that asks a question even without
+// a question mark.
+var trivialQuestionWords = map[string]bool{
+	"what": true, "what's": true, "whats": true, "why": true, "how": true,
+	"who": true, "when": true, "where": true, "which": true, "is": true,
+	"are": true, "can": true, "could": true, "does": true, "do": true,
+	"should": true, "would": true, "explain": true, "define": true,
+}
+
+// toolWords ask for work that needs tools; a message containing one is
+// never trivial.
+var toolWords = map[string]bool{
+	"run": true, "execute": true, "fix": true, "edit": true, "change": true,
+	"create": true, "write": true, "implement": true, "add": true,
+	"remove": true, "delete": true, "rename": true, "refactor": true,
+	"install": true, "build": true, "test": true, "tests": true,
+	"commit": true, "deploy": true, "update": true, "modify": true,
+	"open": true, "read": true, "list": true, "search": true, "find": true,
+	"grep": true, "check": true, "debug": true, "look": true, "show": true,
+	"file": true, "files": true, "repo": true, "directory": true,
+}
+
+// objectionPhrases mark a reply that rejects the previous answer.
+var objectionPhrases = []string{
+	"wrong", "incorrect", "not right", "not correct", "that's not",
+	"thats not", "that is not", "not what i", "try again", "bad answer",
+	"doesn't make sense", "does not make sense", "misunderstood",
+	"use the primary model", "use a better model", "use the main model",
+}
+
+// isTrivialMessage reports whether a user message is an obviously trivial
+// question: one line of at most maxChars, no code, no @file or $skill
+// mentions, no paths, and no word asking for tool work.
+func isTrivialMessage(text string, maxChars int) bool {
+	text = strings.TrimSpace(text)
+	if text == "" || utf8.RuneCountInString(text) > maxChars ||
+		strings.ContainsAny(text, "\n`@$/\\") {
+		return false
+	}
+	words := strings.Fields(strings.ToLower(text))
+	for _, w := range words {
+		w = strings.Trim(w, ".,;:!?\"'()")
+		if toolWords[w] || strings.Contains(w, ".") {
+			return false
+		}
+	}
+	first := strings.Trim(words[0], ",:")
+	return strings.HasSuffix(text, "?") || trivialQuestionWords[first]
+}
+
+// isObjection reports whether a user message rejects the previous answer.
+func isObjection(text string) bool {
+	lower := strings.ToLower(strings.TrimSpace(text))
+	if lower == "no" || lower == "nope" || strings.HasPrefix(lower, "no,") || strings.HasPrefix(lower, "no.") {
+		return true
+	}
+	for _, phrase := range objectionPhrases {
+		if strings.Contains(lower, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// turnUserMessage returns the user's message of the current turn; ok is
+// false when the turn has none or has attachments (file or skill content
+// added as further user messages).
+func (s *SessionState) turnUserMessage(turnID string) (string, bool) {
+	items, err := s.History.GetRawItems()
+	if err != nil {
+		return "", false
+	}
+	var messages []string
+	for _, item := range items {
+		if item.TurnID != turnID || item.Type != models.ItemTypeUserMessage ||
+			strings.HasPrefix(item.Content, "<environment_context>") {
+			continue
+		}
+		messages = append(messages, item.Content)
+	}
+	if len(messages) != 1 {
+		return "", false
+	}
+	return messages[0], true
+}
+
+// routeTrivialTurn runs at the start of a turn and decides whether the
+// cheap model answers it. A turn whose message objects to a routed answer
+// goes to the session's model.
+func (s *SessionState) routeTrivialTurn(ctx workflow.Context, ctrl *LoopControl) {
+	s.routedModel = nil
+	lastRouted := s.LastTurnRouted
+	s.LastTurnRouted = false
+
+	routing := s.Config.TrivialRouting
+	provider, model, ok := routing.Target()
+	if !ok || s.TrivialRoutingOff || s.PlanExec != nil ||
+		(provider == s.Config.Model.Provider && model == s.Config.Model.Model) {
+		return
+	}
+	text, ok := s.turnUserMessage(ctrl.CurrentTurnID())
+	if !ok {
+		return
+	}
+	if lastRouted && isObjection(text) {
+		s.addNotice(ctrl, fmt.Sprintf("Answering with %s, since you objected to the answer from %s.",
+			modelRef(s.Config.Model), routing.Model))
+		return
+	}
+	if !isTrivialMessage(text, routing.MaxMessageChars()) {
+		return
+	}
+
+	cfg := s.trivialModelConfig(provider, model)
+	if cfg.ContextWindow > 0 {
+		// Leave the cheap model room to answer; a long session stays on
+		// the session's model rather than compacting for a trivial turn.
+		if tokens, _ := s.History.EstimateTokenCount(); tokens > cfg.ContextWindow/2 {
+			return
+		}
+	}
+	workflow.GetLogger(ctx).Info("Routing trivial turn to cheaper model",
+		"turn_id", ctrl.CurrentTurnID(), "model", routing.Model)
+	s.routedModel = &cfg
+	s.LastTurnRouted = true
+	s.addNotice(ctrl, fmt.Sprintf("Trivial question: answering with %s (/route off keeps every turn on %s).",
+		routing.Model, modelRef(s.Config.Model)))
+}
+
+// trivialModelConfig returns the model configuration of the cheap model,
+// with the parameters of its registry profile.
+func (s *SessionState) trivialModelConfig(provider, model string) models.ModelConfig {
+	cfg := s.Config.Model
+	cfg.Provider, cfg.Model = provider, model
+	cfg.ContextWindow = 0
+	cfg.ReasoningEffort = ""
+	profile := models.NewDefaultRegistry().Resolve(provider, model)
+	if profile.Temperature != nil {
+		cfg.Temperature = *profile.Temperature
+	}
+	if profile.MaxTokens != nil {
+		cfg.MaxTokens = *profile.MaxTokens
+	}
+	if profile.ContextWindow != nil {
+		cfg.ContextWindow = *profile.ContextWindow
+	}
+	if profile.DefaultReasoningEffort != nil {
+		cfg.ReasoningEffort = *profile.DefaultReasoningEffort
+	}
+	return cfg
+}
+
+// callModel returns the model of the next LLM call: the cheap model on a
+// routed turn, otherwise the session's.
+func (s *SessionState) callModel() models.ModelConfig {
+	if s.routedModel != nil {
+		return *s.routedModel
+	}
+	return s.Config.Model
+}
+
+// escalateRoutedTurn hands a routed turn back to the session's model when
+// the cheap model requested tools. Its response is counted, at the cheap
+// model's price, but dropped, so the session's model answers from the same
+// prompt.
+func (s *SessionState) escalateRoutedTurn(ctx workflow.Context, ctrl *LoopControl, result *activities.LLMActivityOutput) {
+	routed := *s.routedModel
+	s.TotalTokens += result.TokenUsage.TotalTokens
+	s.TotalCachedTokens += result.TokenUsage.CachedTokens
+	s.addLLMCost(ctx, routed, result.TokenUsage)
+
+	from := modelRef(routed)
+	workflow.GetLogger(ctx).Info("Routed model requested tools, escalating",
+		"turn_id", ctrl.CurrentTurnID(), "from", from, "to", modelRef(s.Config.Model))
+	s.routedModel = nil
+	s.LastTurnRouted = false
+	s.addNotice(ctrl, fmt.Sprintf("%s requested tools; answering with %s instead.", from, modelRef(s.Config.Model)))
+}
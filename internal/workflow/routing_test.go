@@ -0,0 +1,41 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+func TestIsTrivialMessage(t *testing.T) {
+	cases := []struct {
+		text string
+		want bool
+	}{
+		{"What does HTTP 418 mean?", true},
+		{"explain the difference between a mutex and a semaphore", true},
+		{"Is Go garbage collected", true},
+		{"thanks", false},
+		{"Can you fix the failing test?", false},
+		{"What is in main.go?", false},
+		{"What does @README say?", false},
+		{"Why does `make` fail?", false},
+		{"What is /etc/hosts for?", false},
+		{"Why?\nAnd how?", false},
+		{"", false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, isTrivialMessage(c.text, models.DefaultTrivialMaxChars), c.text)
+	}
+	assert.False(t, isTrivialMessage("What does HTTP 418 mean?", 10), "over max chars")
+}
+
+func TestIsObjection(t *testing.T) {
+	for _, text := range []string{"no", "No, that's backwards", "That's wrong", "try again please", "Use the primary model"} {
+		assert.True(t, isObjection(text), text)
+	}
+	for _, text := range []string{"nothing else, thanks", "now explain goroutines", "great"} {
+		assert.False(t, isObjection(text), text)
+	}
+}
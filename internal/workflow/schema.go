@@ -0,0 +1,103 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// schema.go generates the JSON Schemas of the client payload contract from
+// the Go types, so the published schemas cannot drift from the code. They
+// are checked in under schemas/v<ProtocolVersion>/ and regenerated with
+// `tcx schema --out schemas/v<N>`.
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/google/jsonschema-go/jsonschema"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// schemaBaseURL prefixes the $id of every published schema.
+const schemaBaseURL = "https://github.com/mfateev/temporal-agent-harness/schemas"
+
+// contractTypes are the payloads published as JSON Schemas, keyed by the
+// schema name, with the workflow operation each one is used with.
+var contractTypes = []struct {
+	name        string
+	typ         reflect.Type
+	description string
+}{
+	{"WorkflowInput", reflect.TypeFor[WorkflowInput](), "Input of AgenticWorkflow."},
+	{"UserInput", reflect.TypeFor[UserInput](), "Payload of the " + UpdateUserInput + " Update."},
+	{"ApprovalResponse", reflect.TypeFor[ApprovalResponse](), "Payload of the " + UpdateApprovalResponse + " Update."},
+	{"EscalationResponse", reflect.TypeFor[EscalationResponse](), "Payload of the " + UpdateEscalationResponse + " Update."},
+	{"TurnStatus", reflect.TypeFor[TurnStatus](), "Result of the " + QueryGetTurnStatus + " query."},
+	{"ConversationItem", reflect.TypeFor[models.ConversationItem](), "Element of the " + QueryGetConversationItems + " query result; versioned by the payload that carries it."},
+}
+
+// enumSchemas lists the values of the string enums in the contract.
+var enumSchemas = map[reflect.Type]*jsonschema.Schema{
+	reflect.TypeFor[models.ConversationItemType](): stringEnum(
+		models.ItemTypeUserMessage, models.ItemTypeAssistantMessage, models.ItemTypeFunctionCall,
+		models.ItemTypeFunctionCallOutput, models.ItemTypeWebSearchCall, models.ItemTypeCompaction,
+		models.ItemTypeModelSwitch, models.ItemTypeTurnStarted, models.ItemTypeTurnComplete,
+		models.ItemTypeTurnFailure, models.ItemTypeNotice),
+	reflect.TypeFor[TurnPhase](): stringEnum(
+		PhaseWaitingForInput, PhaseLLMCalling, PhaseToolExecuting, PhaseApprovalPending,
+		PhaseEscalationPending, PhaseUserInputPending, PhaseCompacting, PhaseWaitingForAgents,
+		PhaseCleaningUp),
+}
+
+// stringEnum is the schema of a string type with a fixed set of values.
+func stringEnum[T ~string](values ...T) *jsonschema.Schema {
+	s := &jsonschema.Schema{Type: "string"}
+	for _, v := range values {
+		s.Enum = append(s.Enum, string(v))
+	}
+	return s
+}
+
+// ContractSchemas returns the JSON Schema of every contract payload, keyed
+// by name. Objects accept additional properties: adding a field is a
+// compatible change within a protocol version.
+func ContractSchemas() (map[string]*jsonschema.Schema, error) {
+	out := make(map[string]*jsonschema.Schema, len(contractTypes))
+	for _, ct := range contractTypes {
+		s, err := jsonschema.ForType(ct.typ, &jsonschema.ForOptions{IgnoreInvalidTypes: true, TypeSchemas: enumSchemas})
+		if err != nil {
+			return nil, fmt.Errorf("schema for %s: %w", ct.name, err)
+		}
+		allowAdditionalProperties(s)
+		s.Schema = "https://json-schema.org/draft/2020-12/schema"
+		s.ID = fmt.Sprintf("%s/v%d/%s.json", schemaBaseURL, ProtocolVersion, ct.name)
+		s.Title = ct.name
+		s.Description = fmt.Sprintf("%s Protocol version %d.", ct.description, ProtocolVersion)
+		out[ct.name] = s
+	}
+	return out, nil
+}
+
+// MarshalContractSchema renders a schema as indented JSON with a trailing
+// newline, the format of the published files.
+func MarshalContractSchema(s *jsonschema.Schema) ([]byte, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// allowAdditionalProperties drops the "additionalProperties": false the
+// generator puts on every struct, recursively.
+func allowAdditionalProperties(s *jsonschema.Schema) {
+	if s == nil {
+		return
+	}
+	if ap := s.AdditionalProperties; ap != nil && ap.Not != nil && reflect.ValueOf(*ap.Not).IsZero() {
+		s.AdditionalProperties = nil
+	}
+	allowAdditionalProperties(s.AdditionalProperties)
+	allowAdditionalProperties(s.Items)
+	for _, p := range s.Properties {
+		allowAdditionalProperties(p)
+	}
+}
@@ -0,0 +1,44 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// scratch.go covers the per-session scratch directory (internal/scratch):
+// the developer-instruction note advertising $SCRATCH and the cleanup
+// activity run when the session ends.
+package workflow
+
+import (
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+)
+
+// scratchInstructions tells the model where to put throwaway files.
+const scratchInstructions = "<scratch_directory>\n" +
+	"A private scratch directory for this session is available to shell commands as $SCRATCH. " +
+	"Put temporary scripts, downloads, and intermediate artifacts there instead of the user's repository. " +
+	"It is deleted when the session ends; never store anything the user needs to keep in it.\n" +
+	"</scratch_directory>"
+
+// commandTools are the tools whose processes see $SCRATCH.
+var commandTools = []string{"shell", "shell_command", "exec_command"}
+
+// hasCommandTool reports whether any command-running tool is enabled.
+func (s *SessionState) hasCommandTool() bool {
+	for _, name := range commandTools {
+		if s.Config.Tools.HasTool(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanupScratchDir removes the session's scratch directory on the worker.
+// Best-effort: failures are logged and never fail the workflow.
+func (s *SessionState) cleanupScratchDir(ctx workflow.Context) {
+	if !s.ScratchUsed {
+		return
+	}
+	input := activities.CleanupScratchDirInput{SessionID: s.ConversationID}
+	if err := workflow.ExecuteActivity(s.cleanupActivityCtx(ctx), "CleanupScratchDir", input).Get(ctx, nil); err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to clean up scratch directory", "error", err)
+	}
+}
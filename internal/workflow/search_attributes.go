@@ -0,0 +1,97 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// search_attributes.go publishes per-session metrics as search attributes,
+// so fleet dashboards (and `tcx admin metrics`) can be built from visibility
+// queries alone. It is opt-in via SessionConfiguration.MetricsSearchAttributes
+// because upserting attributes the namespace does not have registered fails
+// the workflow task.
+//
+// NOTE: Temporal-specific addition (not in Codex Rust).
+package workflow
+
+import (
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Search attributes holding session metrics. Register them on the namespace
+// with MetricsSearchAttributeTypes before enabling
+// SessionConfiguration.MetricsSearchAttributes.
+var (
+	SearchAttrTotalTokens  = temporal.NewSearchAttributeKeyInt64("TcxTotalTokens")
+	SearchAttrCostUSD      = temporal.NewSearchAttributeKeyFloat64("TcxCostUsd")
+	SearchAttrTurns        = temporal.NewSearchAttributeKeyInt64("TcxTurns")
+	SearchAttrToolFailures = temporal.NewSearchAttributeKeyInt64("TcxToolFailures")
+	SearchAttrFailedTurns  = temporal.NewSearchAttributeKeyInt64("TcxFailedTurns")
+	SearchAttrModel        = temporal.NewSearchAttributeKeyKeyword("TcxModel")
+
+	// Planner and executor sessions linked by a plan handoff (handoff.go).
+	SearchAttrHandoffParent = temporal.NewSearchAttributeKeyKeyword("TcxHandoffParent")
+	SearchAttrHandoffChild  = temporal.NewSearchAttributeKeyKeyword("TcxHandoffChild")
+)
+
+// MetricsSearchAttributeTypes maps each metrics search attribute to the type
+// it must be registered with, e.g.
+// `temporal operator search-attribute create --name TcxTotalTokens --type Int`.
+var MetricsSearchAttributeTypes = map[string]string{
+	SearchAttrTotalTokens.GetName():  "Int",
+	SearchAttrCostUSD.GetName():      "Double",
+	SearchAttrTurns.GetName():        "Int",
+	SearchAttrToolFailures.GetName(): "Int",
+	SearchAttrFailedTurns.GetName():  "Int",
+	SearchAttrModel.GetName():        "Keyword",
+
+	SearchAttrHandoffParent.GetName(): "Keyword",
+	SearchAttrHandoffChild.GetName():  "Keyword",
+}
+
+// sessionMetrics is the value of the metrics search attributes.
+type sessionMetrics struct {
+	TotalTokens  int64
+	CostUSD      float64
+	Turns        int64
+	ToolFailures int64
+	FailedTurns  int64
+	Model        string
+}
+
+// currentMetrics returns the session's metrics.
+func (s *SessionState) currentMetrics() sessionMetrics {
+	m := sessionMetrics{
+		TotalTokens: int64(s.TotalTokens),
+		CostUSD:     s.SessionCostUSD,
+		Turns:       int64(s.TurnCounter),
+		FailedTurns: int64(s.FailedTurns),
+		Model:       s.Config.Model.Model,
+	}
+	for _, stat := range s.ToolStats {
+		m.ToolFailures += int64(stat.Failures)
+	}
+	return m
+}
+
+// upsertMetricsSearchAttributes publishes the session metrics when they
+// changed since the last upsert. Called on significant transitions: turn
+// completion and session end.
+func (s *SessionState) upsertMetricsSearchAttributes(ctx workflow.Context) {
+	if !s.Config.MetricsSearchAttributes {
+		return
+	}
+	m := s.currentMetrics()
+	if s.upsertedMetrics != nil && *s.upsertedMetrics == m {
+		return
+	}
+	err := workflow.UpsertTypedSearchAttributes(ctx,
+		SearchAttrTotalTokens.ValueSet(m.TotalTokens),
+		SearchAttrCostUSD.ValueSet(m.CostUSD),
+		SearchAttrTurns.ValueSet(m.Turns),
+		SearchAttrToolFailures.ValueSet(m.ToolFailures),
+		SearchAttrFailedTurns.ValueSet(m.FailedTurns),
+		SearchAttrModel.ValueSet(m.Model),
+	)
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to upsert metrics search attributes", "error", err)
+		return
+	}
+	s.upsertedMetrics = &m
+}
@@ -0,0 +1,62 @@
+package workflow
+
+import (
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+// captureMetricsUpserts records every UpsertTypedSearchAttributes call.
+func (s *AgenticWorkflowTestSuite) captureMetricsUpserts() *[]temporal.SearchAttributes {
+	var upserts []temporal.SearchAttributes
+	s.env.OnUpsertTypedSearchAttributes(mock.Anything).
+		Run(func(args mock.Arguments) {
+			upserts = append(upserts, args.Get(0).(temporal.SearchAttributes))
+		}).
+		Return(nil).Maybe()
+	return &upserts
+}
+
+// TestMetricsSearchAttributes_UpsertedOnTurnCompletion: the session's
+// metrics are published once per change, not again at shutdown.
+func (s *AgenticWorkflowTestSuite) TestMetricsSearchAttributes_UpsertedOnTurnCompletion() {
+	s.env.OnActivity("ExecuteLLMCall", mock.Anything, mock.Anything).
+		Return(mockLLMStopResponse("Hello!", 42), nil).Once()
+	upserts := s.captureMetricsUpserts()
+	s.sendShutdown(time.Second * 2)
+
+	input := testInput("Hi")
+	input.Config.MetricsSearchAttributes = true
+	s.env.ExecuteWorkflow(AgenticWorkflow, input)
+
+	require.True(s.T(), s.env.IsWorkflowCompleted())
+	require.NoError(s.T(), s.env.GetWorkflowError())
+	require.Len(s.T(), *upserts, 1)
+	sa := (*upserts)[0]
+	tokens, _ := sa.GetInt64(SearchAttrTotalTokens)
+	turns, _ := sa.GetInt64(SearchAttrTurns)
+	failures, _ := sa.GetInt64(SearchAttrFailedTurns)
+	model, _ := sa.GetKeyword(SearchAttrModel)
+	assert.Equal(s.T(), int64(42), tokens)
+	assert.Equal(s.T(), int64(1), turns)
+	assert.Equal(s.T(), int64(0), failures)
+	assert.Equal(s.T(), "gpt-4o-mini", model)
+}
+
+// TestMetricsSearchAttributes_OffByDefault: nothing is upserted unless the
+// session opts in.
+func (s *AgenticWorkflowTestSuite) TestMetricsSearchAttributes_OffByDefault() {
+	s.env.OnActivity("ExecuteLLMCall", mock.Anything, mock.Anything).
+		Return(mockLLMStopResponse("Hello!", 42), nil).Once()
+	upserts := s.captureMetricsUpserts()
+	s.sendShutdown(time.Second * 2)
+
+	s.env.ExecuteWorkflow(AgenticWorkflow, testInput("Hi"))
+
+	require.True(s.T(), s.env.IsWorkflowCompleted())
+	require.NoError(s.T(), s.env.GetWorkflowError())
+	assert.Empty(s.T(), *upserts)
+}
@@ -21,9 +21,11 @@ import (
 )
 
 // SessionWorkflow is the per-session orchestrator.
-// Started as a child of HarnessWorkflow (with ABANDON close policy).
-// Handles init, starts AgenticWorkflow, and signals harness on completion.
-func SessionWorkflow(ctx workflow.Context, input SessionWorkflowInput) error {
+// Started as a child of HarnessWorkflow (with ABANDON close policy), or by
+// the Nexus service's run-session operation without a harness.
+// Handles init, starts AgenticWorkflow, signals harness on completion, and
+// returns the AgenticWorkflow's result.
+func SessionWorkflow(ctx workflow.Context, input SessionWorkflowInput) (WorkflowResult, error) {
 	logger := workflow.GetLogger(ctx)
 	wfID := workflow.GetInfo(ctx).WorkflowExecution.ID
 
@@ -36,7 +38,7 @@ func SessionWorkflow(ctx workflow.Context, input SessionWorkflowInput) error {
 	if err := workflow.SetQueryHandler(ctx, QueryGetAgentWorkflowID, func() (string, error) {
 		return readyAgentID, nil
 	}); err != nil {
-		return fmt.Errorf("failed to register %s query: %w", QueryGetAgentWorkflowID, err)
+		return WorkflowResult{}, fmt.Errorf("failed to register %s query: %w", QueryGetAgentWorkflowID, err)
 	}
 
 	// --- One-time init (moved from AgenticWorkflow + HarnessWorkflow) ---
@@ -47,6 +49,9 @@ func SessionWorkflow(ctx workflow.Context, input SessionWorkflowInput) error {
 		logger.Warn("Failed to resolve config, using defaults", "error", err)
 		cfg = models.DefaultSessionConfiguration()
 	}
+	if err := checkRemoteConfig(cfg.Remote); err != nil {
+		return WorkflowResult{}, err
+	}
 
 	// 1b. Resolve crew main agent overrides (if this is a crew session).
 	var crewMainAgentName string
@@ -64,7 +69,7 @@ func SessionWorkflow(ctx workflow.Context, input SessionWorkflowInput) error {
 			CrewInputs: input.CrewInputs,
 		}).Get(ctx, &crewOut)
 		if err != nil {
-			return fmt.Errorf("ResolveCrewMain failed: %w", err)
+			return WorkflowResult{}, fmt.Errorf("ResolveCrewMain failed: %w", err)
 		}
 
 		crewMainAgentName = crewOut.MainAgentName
@@ -107,7 +112,7 @@ func SessionWorkflow(ctx workflow.Context, input SessionWorkflowInput) error {
 			ToolSpecs:      toolSpecs,
 		}
 		if err := tempState.initMcpServers(ctx); err != nil {
-			return fmt.Errorf("MCP initialization failed: %w", err)
+			return WorkflowResult{}, fmt.Errorf("MCP initialization failed: %w", err)
 		}
 		// The MCP specs were appended after the built-in specs.
 		if len(tempState.ToolSpecs) > len(toolSpecs) {
@@ -138,6 +143,7 @@ func SessionWorkflow(ctx workflow.Context, input SessionWorkflowInput) error {
 	// --- Start AgenticWorkflow as child ---
 
 	childInput := WorkflowInput{
+		Version:         ProtocolVersion,
 		ConversationID:  agentWorkflowID,
 		UserMessage:     input.UserMessage,
 		Config:          cfg,
@@ -148,6 +154,8 @@ func SessionWorkflow(ctx workflow.Context, input SessionWorkflowInput) error {
 		CrewName:        input.CrewName,
 		CrewAgent:       crewMainAgentName,
 		CrewInputs:      input.CrewInputs,
+		HandoffParentID: input.HandoffParentID,
+		Caller:          input.Caller,
 	}
 
 	childCtx := workflow.WithChildOptions(ctx, workflow.ChildWorkflowOptions{
@@ -158,7 +166,7 @@ func SessionWorkflow(ctx workflow.Context, input SessionWorkflowInput) error {
 	// Wait for child workflow to actually start.
 	var exec workflow.Execution
 	if err := future.GetChildWorkflowExecution().Get(ctx, &exec); err != nil {
-		return fmt.Errorf("failed to start AgenticWorkflow %s: %w", agentWorkflowID, err)
+		return WorkflowResult{}, fmt.Errorf("failed to start AgenticWorkflow %s: %w", agentWorkflowID, err)
 	}
 
 	// Mark as ready — unblocks the WaitForSessionReady activity's query poll.
@@ -168,10 +176,7 @@ func SessionWorkflow(ctx workflow.Context, input SessionWorkflowInput) error {
 		"agent_workflow_id", readyAgentID)
 
 	// Signal harness that the session is now running (best-effort).
-	_ = workflow.SignalExternalWorkflow(ctx, input.HarnessID, "", SignalUpdateSessionStatus, UpdateSessionStatusRequest{
-		SessionWorkflowID: wfID,
-		Status:            AgentStatusRunning,
-	}).Get(ctx, nil)
+	signalHarness(ctx, input.HarnessID, wfID, AgentStatusRunning)
 
 	// Wait for AgenticWorkflow completion.
 	var result WorkflowResult
@@ -182,17 +187,26 @@ func SessionWorkflow(ctx workflow.Context, input SessionWorkflowInput) error {
 	if childErr != nil {
 		finalStatus = AgentStatusErrored
 	}
-	_ = workflow.SignalExternalWorkflow(ctx, input.HarnessID, "", SignalUpdateSessionStatus, UpdateSessionStatusRequest{
-		SessionWorkflowID: wfID,
-		Status:            finalStatus,
-	}).Get(ctx, nil)
+	signalHarness(ctx, input.HarnessID, wfID, finalStatus)
 
-	return childErr
+	return result, childErr
+}
+
+// signalHarness reports the session's status to its harness, if it has one
+// (sessions started through the Nexus service do not).
+func signalHarness(ctx workflow.Context, harnessID, sessionWorkflowID string, status AgentStatus) {
+	if harnessID == "" {
+		return
+	}
+	_ = workflow.SignalExternalWorkflow(ctx, harnessID, "", SignalUpdateSessionStatus, UpdateSessionStatusRequest{
+		SessionWorkflowID: sessionWorkflowID,
+		Status:            status,
+	}).Get(ctx, nil)
 }
 
 // SessionWorkflowContinued is the ContinueAsNew re-entry point for SessionWorkflow.
 // Currently SessionWorkflow does not ContinueAsNew, but this is registered
 // for forward compatibility.
-func SessionWorkflowContinued(ctx workflow.Context, input SessionWorkflowInput) error {
+func SessionWorkflowContinued(ctx workflow.Context, input SessionWorkflowInput) (WorkflowResult, error) {
 	return SessionWorkflow(ctx, input)
 }
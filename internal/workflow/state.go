@@ -8,7 +8,9 @@ package workflow
 import (
 	"time"
 
+	"github.com/mfateev/temporal-agent-harness/internal/capabilities"
 	"github.com/mfateev/temporal-agent-harness/internal/history"
+	"github.com/mfateev/temporal-agent-harness/internal/instructions"
 	"github.com/mfateev/temporal-agent-harness/internal/models"
 	"github.com/mfateev/temporal-agent-harness/internal/skills"
 	"github.com/mfateev/temporal-agent-harness/internal/tools"
@@ -73,6 +75,19 @@ const (
 	// The CLI sends this when the user types /plan <message>.
 	UpdatePlanRequest = "plan_request"
 
+	// UpdateHandoff returns the brief a new executor session is started
+	// with from this read-only planner session. Used by the CLI /handoff
+	// command.
+	UpdateHandoff = "handoff"
+
+	// SignalHandoffStarted is sent by an executor session to the planner
+	// session it was handed off from, so the planner records the link.
+	SignalHandoffStarted = "handoff_started"
+
+	// UpdateExecutePlan starts or resumes step-by-step execution of the
+	// approved plan. Used by the CLI /run-plan command.
+	UpdateExecutePlan = "execute_plan"
+
 	// UpdateModel updates the session's model configuration.
 	// Used by the CLI /model command.
 	UpdateModel = "update_model"
@@ -99,6 +114,14 @@ const (
 	// Used by the CLI /personality command.
 	UpdatePersonality = "update_personality"
 
+	// UpdateTrivialRouting turns routing of trivial turns to a cheaper
+	// model on or off for the session. Used by the CLI /route command.
+	UpdateTrivialRouting = "set_trivial_routing"
+
+	// UpdatePersona switches the session's persona preset (tone and
+	// verbosity only). Used by the CLI /persona command.
+	UpdatePersona = "update_persona"
+
 	// QueryListSkills returns the list of discovered skills.
 	// Used by the CLI /skills command.
 	QueryListSkills = "list_skills"
@@ -114,6 +137,33 @@ const (
 	// UpdateReasoningEffort changes the reasoning effort level for reasoning models.
 	// Used by the CLI /reasoning command.
 	UpdateReasoningEffort = "update_reasoning_effort"
+
+	// UpdateTodo adds, completes, removes, or lists session TODO items.
+	// Used by the CLI /todo command.
+	UpdateTodo = "update_todo"
+
+	// QueryGetToolStats returns per-tool call statistics for the session.
+	// Used by the CLI /stats command.
+	QueryGetToolStats = "get_tool_stats"
+
+	// QueryGetLastPrompt returns the exact prompt behind the last LLM
+	// response. Used by the CLI /why command.
+	QueryGetLastPrompt = "get_last_prompt"
+
+	// QueryGetFingerprint returns the session fingerprint: configuration
+	// hashes and per-request hashes. Used by the CLI /fingerprint command.
+	QueryGetFingerprint = "get_fingerprint"
+
+	// QueryGetAuditLog returns the audit log of mutating tool calls.
+	QueryGetAuditLog = "get_audit_log"
+
+	// QueryGetEvents returns the session event log after a sequence number.
+	// Used by external UIs to follow the full session timeline.
+	QueryGetEvents = "get_events"
+
+	// UpdateRaiseCostCap raises MaxSessionCostUSD and resumes a session
+	// paused by the cost cap. Used by the CLI /resume --raise-cap command.
+	UpdateRaiseCostCap = "raise_cost_cap"
 )
 
 // UpdateModelRequest is the payload for the update_model Update.
@@ -135,6 +185,18 @@ type McpToolSummary struct {
 	ToolName      string `json:"tool_name"`
 }
 
+// ToolStatSummary is a per-tool view of call statistics for the
+// get_tool_stats query.
+type ToolStatSummary struct {
+	ToolName        string  `json:"tool_name"`
+	Calls           int     `json:"calls"`
+	Failures        int     `json:"failures"`
+	FailureRate     float64 `json:"failure_rate"` // 0.0 – 1.0
+	AvgDurationMs   int64   `json:"avg_duration_ms"`
+	TotalDurationMs int64   `json:"total_duration_ms"`
+	OutputBytes     int64   `json:"output_bytes"`
+}
+
 // ExecSessionSummary is a lightweight view of an exec session for the CLI.
 type ExecSessionSummary struct {
 	ProcessID string    `json:"process_id"`
@@ -181,6 +243,28 @@ type UpdatePersonalityResponse struct {
 	Acknowledged bool `json:"acknowledged"`
 }
 
+// SetTrivialRoutingRequest is the payload for the set_trivial_routing
+// Update.
+type SetTrivialRoutingRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+// SetTrivialRoutingResponse is returned by the set_trivial_routing Update.
+type SetTrivialRoutingResponse struct {
+	Acknowledged bool `json:"acknowledged"`
+}
+
+// UpdatePersonaRequest is the payload for the update_persona Update.
+// Persona is a preset name, or "" / "none" to turn the persona off.
+type UpdatePersonaRequest struct {
+	Persona string `json:"persona"`
+}
+
+// UpdatePersonaResponse is returned by the update_persona Update.
+type UpdatePersonaResponse struct {
+	Acknowledged bool `json:"acknowledged"`
+}
+
 // ToggleSkillRequest is the payload for the toggle_skill Update.
 type ToggleSkillRequest struct {
 	SkillPath string `json:"skill_path"`
@@ -225,13 +309,19 @@ const (
 	PhaseUserInputPending   TurnPhase = "user_input_pending"
 	PhaseCompacting         TurnPhase = "compacting"
 	PhaseWaitingForAgents   TurnPhase = "waiting_for_agents"
+	PhaseCleaningUp         TurnPhase = "cleaning_up" // Session ending: see cleanup.go
 )
 
 // TurnStatus is the response from the get_turn_status query.
 type TurnStatus struct {
+	// Version is the ProtocolVersion of the worker that built the status.
+	Version int `json:"version"`
+
 	Phase                   TurnPhase                `json:"phase"`
 	CurrentTurnID           string                   `json:"current_turn_id"`
 	ToolsInFlight           []string                 `json:"tools_in_flight,omitempty"`
+	// Focus is what the agent is working on right now (see focus.go).
+	Focus                   *Focus                   `json:"focus,omitempty"`
 	PendingApprovals        []PendingApproval        `json:"pending_approvals,omitempty"`
 	PendingEscalations      []EscalationRequest      `json:"pending_escalations,omitempty"`
 	PendingUserInputRequest *PendingUserInputRequest `json:"pending_user_input_request,omitempty"`
@@ -239,14 +329,26 @@ type TurnStatus struct {
 	IterationCount          int                      `json:"iteration_count"`
 	TotalTokens             int                      `json:"total_tokens"`
 	TotalCachedTokens       int                      `json:"total_cached_tokens"`
+	SuggestionTokens        int                      `json:"suggestion_tokens,omitempty"`
 	TurnCount               int                      `json:"turn_count"`
 	WorkerVersion           string                   `json:"worker_version,omitempty"`
 	Suggestion              string                   `json:"suggestion,omitempty"`
 	Plan                    *PlanState               `json:"plan,omitempty"`
+	Todos                   []TodoItem               `json:"todos,omitempty"`
 	LastTokenUsage          *models.TokenUsage       `json:"last_token_usage,omitempty"`
 	ContextWindowRemaining  int                      `json:"context_window_remaining_percent"`
 	ContextWindowTotal      int                      `json:"context_window_total"`
 	RateLimitSnapshot       *models.RateLimitSnapshot `json:"rate_limit_snapshot,omitempty"`
+	DedupedAssistantItems   int                      `json:"deduped_assistant_items,omitempty"`
+	UnavailableTools        map[string]string        `json:"unavailable_tools,omitempty"`
+	PlanProgress            *PlanProgress            `json:"plan_progress,omitempty"`
+	LastActivityAt          time.Time                `json:"last_activity_at"`
+	SessionCostUSD          float64                  `json:"session_cost_usd,omitempty"`
+	MaxSessionCostUSD       float64                  `json:"max_session_cost_usd,omitempty"`
+	CostCapPaused           bool                     `json:"cost_cap_paused,omitempty"`
+	LastTurnLatency         *TurnLatency             `json:"last_turn_latency,omitempty"`
+	Latency                 *LatencyStats            `json:"latency,omitempty"`
+	Caller                  *CallerContext           `json:"caller,omitempty"`
 }
 
 // SessionWorkflowInput is the input for SessionWorkflow.
@@ -270,6 +372,14 @@ type SessionWorkflowInput struct {
 
 	// CrewInputs are the raw user-provided inputs for crew interpolation.
 	CrewInputs map[string]string `json:"crew_inputs,omitempty"`
+
+	// HandoffParentID is the planner session a handoff started this
+	// session from; passed on to AgenticWorkflow.
+	HandoffParentID string `json:"handoff_parent_id,omitempty"`
+
+	// Caller is the workflow that started this session through the Nexus
+	// service; passed on to AgenticWorkflow.
+	Caller *CallerContext `json:"caller,omitempty"`
 }
 
 // UpdateSessionStatusRequest is the payload for the update_session_status signal.
@@ -289,6 +399,9 @@ type UpdateSessionStatusRequest struct {
 //
 // Maps to: codex-rs/core/src/codex.rs run_turn input
 type WorkflowInput struct {
+	// Version is the client's ProtocolVersion; 0 means unversioned (1).
+	Version int `json:"version,omitempty"`
+
 	ConversationID string                      `json:"conversation_id"`
 	UserMessage    string                      `json:"user_message"`
 	Config         models.SessionConfiguration `json:"config"`
@@ -311,12 +424,30 @@ type WorkflowInput struct {
 
 	// CrewInputs are the raw user-provided inputs for crew interpolation.
 	CrewInputs map[string]string `json:"crew_inputs,omitempty"`
+
+	// HandoffParentID is the planner session this executor session was
+	// handed off from (see handoff.go).
+	HandoffParentID string `json:"handoff_parent_id,omitempty"`
+
+	// Caller is the workflow that started this session through the Nexus
+	// service (see caller.go).
+	Caller *CallerContext `json:"caller,omitempty"`
 }
 
 // UserInput is the payload for the user_input Update.
 // Maps to: codex-rs/protocol/src/user_input.rs UserInput
 type UserInput struct {
+	Version int    `json:"version,omitempty"` // Client ProtocolVersion; 0 = unversioned
 	Content string `json:"content"`
+
+	// IdempotencyKey is a client-generated key identifying this input. A
+	// retried update with a key the session already accepted returns the
+	// original turn instead of starting another (see idempotency.go).
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+
+	// Caller is set when the input was sent through the Nexus service
+	// (see caller.go).
+	Caller *CallerContext `json:"caller,omitempty"`
 }
 
 // StateUpdateRequest is the payload for the get_state_update Update.
@@ -325,22 +456,50 @@ type UserInput struct {
 type StateUpdateRequest struct {
 	SinceSeq   int       `json:"since_seq"`
 	SincePhase TurnPhase `json:"since_phase"`
+	// Version is the client's protocol version (see ProtocolVersion).
+	// Items of types newer than it are returned as notices. Zero means
+	// version 1.
+	Version int `json:"version,omitempty"`
 }
 
 // StateUpdateResponse is the unified response for both user_input and
 // get_state_update Updates. It carries a snapshot of new conversation items
 // plus the current turn status, eliminating the need for separate queries.
 type StateUpdateResponse struct {
-	TurnID    string                    `json:"turn_id"`
-	Items     []models.ConversationItem `json:"items"`
-	Status    TurnStatus                `json:"status"`
-	Compacted bool                      `json:"compacted,omitempty"`
-	Completed bool                      `json:"completed,omitempty"`
+	TurnID string `json:"turn_id"`
+	// Items decode tolerantly (see models.ConversationItemList), so a
+	// client keeps working against a worker with newer item types.
+	Items     models.ConversationItemList `json:"items"`
+	Status    TurnStatus                  `json:"status"`
+	Compacted bool                        `json:"compacted,omitempty"`
+	Completed bool                        `json:"completed,omitempty"`
+	// Replayed is set when a user_input update repeated an idempotency key
+	// the session already accepted; TurnID is the original turn.
+	Replayed bool `json:"replayed,omitempty"`
+}
+
+// InterruptMode selects how aggressively an interrupt stops the current turn.
+type InterruptMode string
+
+const (
+	// InterruptModeSoft lets in-flight tools finish and skips the next LLM call.
+	// This is the default when Mode is empty.
+	InterruptModeSoft InterruptMode = "soft"
+	// InterruptModeHard cancels in-flight tool and LLM activities immediately.
+	InterruptModeHard InterruptMode = "hard"
+)
+
+// TurnCompleteContent returns the TurnComplete item content recording an
+// interrupt of this mode (e.g. "interrupted:hard").
+func (m InterruptMode) TurnCompleteContent() string {
+	return "interrupted:" + string(m)
 }
 
 // InterruptRequest is the payload for the interrupt Update.
 // Maps to: codex-rs/protocol/src/protocol.rs Op::Interrupt
-type InterruptRequest struct{}
+type InterruptRequest struct {
+	Mode InterruptMode `json:"mode,omitempty"` // Empty = InterruptModeSoft
+}
 
 // InterruptResponse is returned by the interrupt Update.
 // Maps to: Codex EventMsg::TurnAborted
@@ -352,6 +511,10 @@ type InterruptResponse struct {
 // Maps to: codex-rs/protocol/src/protocol.rs Op::Shutdown
 type ShutdownRequest struct {
 	Reason string `json:"reason,omitempty"`
+
+	// Caller is set when the shutdown was requested through the Nexus
+	// service (see caller.go).
+	Caller *CallerContext `json:"caller,omitempty"`
 }
 
 // ShutdownResponse is returned by the shutdown Update.
@@ -367,13 +530,39 @@ type PendingApproval struct {
 	ToolName  string `json:"tool_name"`
 	Arguments string `json:"arguments"` // Raw JSON string of arguments
 	Reason    string `json:"reason,omitempty"` // Why approval is needed (from policy justification or heuristic)
+	// Group is the normalized command template ("go test <path>") shared
+	// with other pending approvals of the same tool; clients may present
+	// them as one decision. Empty when the call is not grouped.
+	Group string `json:"group,omitempty"`
+	// PreviewCommand and Preview hold the read-only preview run before
+	// asking about a git state-changing command (see git_preview.go). For
+	// apply_patch, Preview is the unified diff the patch would make to the
+	// current files and PreviewError says why it does not apply cleanly
+	// (see patch_preview.go).
+	PreviewCommand string `json:"preview_command,omitempty"`
+	Preview        string `json:"preview,omitempty"`
+	PreviewError   string `json:"preview_error,omitempty"`
+	// TurnID and ContextVersion identify the context the approval was
+	// requested in. If it is interrupted or superseded before a decision,
+	// the approval expires and the call is denied (see approval_expiry.go).
+	TurnID         string `json:"turn_id,omitempty"`
+	ContextVersion uint64 `json:"context_version,omitempty"`
 }
 
 // ApprovalResponse is the user's decision on pending tool approvals.
 // Maps to: Codex approval flow response
 type ApprovalResponse struct {
-	Approved []string `json:"approved"` // CallIDs the user approved
-	Denied   []string `json:"denied"`   // CallIDs the user denied
+	Version  int      `json:"version,omitempty"` // Client ProtocolVersion; 0 = unversioned
+	Approved []string `json:"approved"`          // CallIDs the user approved
+	Denied   []string `json:"denied"`            // CallIDs the user denied
+
+	// Approver names who decided, for the audit log (see audit.go); the
+	// CLI sends the local user name. Recorded, not verified.
+	Approver string `json:"approver,omitempty"`
+
+	// IdempotencyKey is a client-generated key; a retried response with an
+	// accepted key is acknowledged without being applied again.
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
 }
 
 // ApprovalResponseAck is returned by the approval_response Update after acceptance.
@@ -391,8 +580,12 @@ type EscalationRequest struct {
 
 // EscalationResponse is the user's decision on escalation.
 type EscalationResponse struct {
-	Approved []string `json:"approved"` // CallIDs to re-execute without sandbox
-	Denied   []string `json:"denied"`   // CallIDs to reject
+	Version  int      `json:"version,omitempty"` // Client ProtocolVersion; 0 = unversioned
+	Approved []string `json:"approved"`          // CallIDs to re-execute without sandbox
+	Denied   []string `json:"denied"`            // CallIDs to reject
+
+	// Approver names who decided, for the audit log (see audit.go).
+	Approver string `json:"approver,omitempty"`
 }
 
 // EscalationResponseAck is returned by the escalation_response Update.
@@ -509,14 +702,107 @@ type SessionState struct {
 	// Reset on history modification (compaction, DropOldestUserTurns).
 	lastSentHistoryLen int `json:"-"`
 
+	// Transient: how many tool outputs the output window had rolled into
+	// stubs at the last LLM call. A change invalidates the response chain.
+	rolledToolOutputs int `json:"-"`
+
 	// Context compaction tracking
 	CompactionCount   int  `json:"compaction_count"` // How many times compaction has occurred
 	compactedThisTurn bool `json:"-"`                // Prevents double compaction in one turn
 
+	// Duplicate LLM output detection. DedupedAssistantItems counts items
+	// dropped by dedupeLLMResponse and is surfaced in TurnStatus for debugging.
+	DedupedAssistantItems int    `json:"deduped_assistant_items,omitempty"`
+	lastLLMIdempotencyKey string `json:"-"`
+	lastLLMIdempotencySeq int    `json:"-"`
+
+	// ScratchUsed records that tool activities may have created the session
+	// scratch directory, so it is removed when the workflow ends.
+	ScratchUsed bool `json:"scratch_used,omitempty"`
+
+	// ExecSessionsUsed records that exec_command may have left background
+	// processes on the worker, so they are closed when the workflow ends.
+	ExecSessionsUsed bool `json:"exec_sessions_used,omitempty"`
+
+	// Archive describes the turns moved out of History by the retention
+	// history window (nil until the first move). See history_window.go.
+	Archive *HistoryArchive `json:"archive,omitempty"`
+
+	// WorkerCapabilities is what the tool worker reported at session start;
+	// UnavailableTools maps tools removed from ToolSpecs to the reason.
+	WorkerCapabilities *capabilities.Capabilities `json:"worker_capabilities,omitempty"`
+	UnavailableTools   map[string]string          `json:"unavailable_tools,omitempty"`
+
+	// Developer-instruction text from pre-turn hooks for the next LLM call
+	// (transient — recomputed before every call).
+	hookInstructions string `json:"-"`
+	// Turn whose pre-turn hook items are already in history (transient):
+	// hooks run before every LLM call, but their items are added once per
+	// turn.
+	hookItemsTurnID string `json:"-"`
+
+	// Auto-continuation of truncated responses (transient — not serialized)
+	pendingContinuation *models.ConversationItem `json:"-"` // Partial assistant text awaiting continuation
+	autoContinueCount   int                      `json:"-"` // Auto-continues used this turn
+
 	// Model switch tracking (persists across ContinueAsNew except modelSwitched)
 	PreviousModel         string `json:"previous_model,omitempty"`          // Model before last switch
 	PreviousContextWindow int    `json:"previous_context_window,omitempty"` // Context window before last switch
 	modelSwitched         bool   `json:"-"`                                 // Transient: set on model switch, consumed by maybeCompactBeforeLLM
+	modelSwitchReason     string `json:"-"`                                 // Transient: why the session switched, empty for user switches
+
+	// Provider rate-limit headroom after the last LLM call, reported for
+	// RateLimitModel ("provider/model"). RateLimitDowngrade is set while
+	// the session runs on the downgrade model (see rate_limits.go).
+	RateLimits         *models.RateLimitSnapshot `json:"rate_limits,omitempty"`
+	RateLimitModel     string                    `json:"rate_limit_model,omitempty"`
+	RateLimitDowngrade *RateLimitDowngrade       `json:"rate_limit_downgrade,omitempty"`
+
+	// Trivial-turn routing (see routing.go): whether the last turn was
+	// answered by the cheap model, and the session's opt-out.
+	LastTurnRouted    bool `json:"last_turn_routed,omitempty"`
+	TrivialRoutingOff bool `json:"trivial_routing_off,omitempty"`
+
+	// Sessions linked by a plan handoff: the planner this session was
+	// handed off from, and the executor it handed off to (see handoff.go).
+	HandoffParentID string `json:"handoff_parent_id,omitempty"`
+	HandoffChildID  string `json:"handoff_child_id,omitempty"`
+
+	// Caller is the workflow that started this session through the Nexus
+	// service (see caller.go).
+	Caller *CallerContext `json:"caller,omitempty"`
+
+	// Project glossary entries loaded at session start, and the directory
+	// their paths are relative to (see glossary.go).
+	Glossary     []instructions.GlossaryEntry `json:"glossary,omitempty"`
+	GlossaryRoot string                       `json:"glossary_root,omitempty"`
+
+	// Fingerprint of the session's configuration and LLM requests, set at
+	// the first LLM call (see fingerprint.go).
+	Fingerprint *SessionFingerprint `json:"fingerprint,omitempty"`
+
+	// Prompt and response of the last successful LLM call, served by
+	// get_last_prompt (transient — not serialized).
+	lastPrompt *PromptSnapshot `json:"-"`
+
+	// Model of this turn when routed to the cheap model, and the model of
+	// its last LLM call, recorded on TurnComplete (transient — reset every
+	// turn; see routing.go).
+	routedModel *models.ModelConfig `json:"-"`
+	turnModel   string              `json:"-"`
+
+	// Lines changed by this turn's file edits, and the count at the last
+	// diff budget approval (transient — reset every turn; see diff_budget.go).
+	turnDiffLines     int `json:"-"`
+	turnDiffConfirmed int `json:"-"`
+
+	// Tool call that produced a suspicious output this turn in injection
+	// guard "approve" mode (transient — reset every turn).
+	injectionSource string `json:"-"`
+
+	// Start time of the current turn in the configured timezone, given to
+	// the model as the current date (transient — set every turn).
+	turnStart time.Time `json:"-"`
 
 	// Repeated tool call detection (transient — not serialized)
 	lastToolKey string `json:"-"`
@@ -532,6 +818,27 @@ type SessionState struct {
 	LastTokenUsage    models.TokenUsage  `json:"last_token_usage"`
 	ToolCallsExecuted []string           `json:"tool_calls_executed"`
 
+	// SuggestionTokens counts tokens spent on post-turn prompt suggestions,
+	// kept out of TotalTokens so agent usage stays comparable.
+	SuggestionTokens int `json:"suggestion_tokens,omitempty"`
+
+	// Per-tool call statistics keyed by tool name (persist across ContinueAsNew).
+	ToolStats map[string]ToolStat `json:"tool_stats,omitempty"`
+
+	// Turn latency breakdown: the last completed turn and the session
+	// aggregate persist across ContinueAsNew; the turn in progress does not.
+	LastTurnLatency *TurnLatency `json:"last_turn_latency,omitempty"`
+	Latency         LatencyStats `json:"latency"`
+	turnLatency     *TurnLatency `json:"-"`
+	turnStartedAt   time.Time    `json:"-"`
+
+	// EventLog is the bounded session event log served by get_events.
+	EventLog EventLog `json:"event_log"`
+
+	// AuditLog records every mutating tool call and its approval, served
+	// by get_audit_log (see audit.go).
+	AuditLog AuditLog `json:"audit_log"`
+
 	// MCP tool routing map: qualified name → McpToolRef (server + original tool name).
 	// Persists across ContinueAsNew so MCP tool dispatch works after CAN.
 	McpToolLookup map[string]tools.McpToolRef `json:"mcp_tool_lookup,omitempty"`
@@ -540,6 +847,42 @@ type SessionState struct {
 	// Persists across ContinueAsNew and is exposed via get_turn_status.
 	Plan *PlanState `json:"plan,omitempty"`
 
+	// LastPostMortem is set when the most recent turn ended in error and
+	// cleared when the next turn starts (see postmortem.go).
+	LastPostMortem *models.PostMortem `json:"last_post_mortem,omitempty"`
+
+	// LastActivityAt is when a turn last started or completed. Exposed via
+	// get_turn_status so `tcx sessions prune` can find abandoned sessions;
+	// the idle ContinueAsNew cycle keeps run start times recent.
+	LastActivityAt time.Time `json:"last_activity_at"`
+
+	// SessionCostUSD is the estimated spend of agent and compaction LLM
+	// calls (see cost.go). CostWarnedPercent is the highest cap percentage
+	// already warned about; CostCapPaused is set while the session waits
+	// for raise_cost_cap.
+	SessionCostUSD    float64 `json:"session_cost_usd,omitempty"`
+	CostWarnedPercent int     `json:"cost_warned_percent,omitempty"`
+	CostCapPaused     bool    `json:"cost_cap_paused,omitempty"`
+
+	// FailedTurns counts turns that ended in error (see postmortem.go).
+	// upsertedMetrics is the last value of the metrics search attributes
+	// (see search_attributes.go; transient — re-upserted after ContinueAsNew).
+	FailedTurns     int             `json:"failed_turns,omitempty"`
+	upsertedMetrics *sessionMetrics `json:"-"`
+
+	// AcceptedUpdates are the idempotency keys of the most recent accepted
+	// user_input and approval_response updates (see idempotency.go).
+	AcceptedUpdates []AcceptedUpdate `json:"accepted_updates,omitempty"`
+
+	// PlanExec is non-nil while an approved plan is being executed step by
+	// step (see plan_exec.go).
+	PlanExec *PlanExecution `json:"plan_exec,omitempty"`
+
+	// TODO items recorded via /todo or the todo tool. Persists across
+	// ContinueAsNew; open items are reminded to the model each turn.
+	Todos       []TodoItem `json:"todos,omitempty"`
+	TodoCounter int        `json:"todo_counter,omitempty"`
+
 	// MemoryExtractedAt is the epoch-seconds timestamp of the last memory
 	// extraction. Used to avoid re-extraction on ContinueAsNew resume.
 	MemoryExtractedAt int64 `json:"memory_extracted_at,omitempty"`
@@ -587,6 +930,9 @@ const (
 type PlanStep struct {
 	Step   string         `json:"step"`
 	Status PlanStepStatus `json:"status"`
+	// Verify is an optional shell command run after the step during plan
+	// execution; the step completes only if it succeeds.
+	Verify string `json:"verify,omitempty"`
 }
 
 // PlanState holds the current plan maintained by the LLM via update_plan.
@@ -603,12 +949,24 @@ type WorkflowResult struct {
 	TotalTokens       int      `json:"total_tokens"`
 	TotalCachedTokens int      `json:"total_cached_tokens"`
 	ToolCallsExecuted []string `json:"tool_calls_executed"`
-	EndReason         string   `json:"end_reason,omitempty"` // "shutdown", "error"
+	EndReason         string   `json:"end_reason,omitempty"` // "shutdown", "completed", "error"
 	// FinalMessage is the last assistant message from the workflow.
 	// Used by parent workflows to get the child's result.
 	// Maps to: codex-rs AgentStatus::Completed(Option<String>)
 	FinalMessage string `json:"final_message,omitempty"`
-}
+	// PostMortem describes the failure when the last turn ended in error.
+	PostMortem *models.PostMortem `json:"post_mortem,omitempty"`
+	// Latency aggregates the per-turn latency breakdown over the session.
+	Latency *LatencyStats `json:"latency,omitempty"`
+	// Fingerprint identifies the session's configuration and requests.
+	Fingerprint *SessionFingerprint `json:"fingerprint,omitempty"`
+}
+
+// UncompressedPayload keeps WorkflowResult out of payload compression (see
+// temporalclient.Uncompressed): it is also the result of the tcx-agent
+// Nexus run-session operation, which callers decode with their own data
+// converter.
+func (WorkflowResult) UncompressedPayload() {}
 
 // initHistory initializes the History field from HistoryItems.
 // Called after deserialization (ContinueAsNew) to restore the interface.
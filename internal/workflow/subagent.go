@@ -327,6 +327,7 @@ func (s *SessionState) handleSpawnAgent(ctx workflow.Context, fc models.Conversa
 		applyRoleOverrides(&childConfig, AgentRoleDefault)
 
 		childInput = WorkflowInput{
+			Version:        ProtocolVersion,
 			ConversationID: "", // Set by parent
 			UserMessage:    msg,
 			Config:         childConfig,
@@ -639,6 +640,7 @@ func buildAgentSpawnConfig(parentConfig models.SessionConfiguration, role AgentR
 	applyRoleOverrides(&childConfig, role)
 
 	return WorkflowInput{
+		Version:        ProtocolVersion,
 		ConversationID: "", // Will be set by parent (workflow ID includes agent ID)
 		UserMessage:    message,
 		Config:         childConfig,
@@ -672,7 +674,7 @@ func applyRoleOverrides(cfg *models.SessionConfiguration, role AgentRole) {
 	case AgentRoleExplorer:
 		// Explorer: cheaper model, medium reasoning, read-only tools, one-shot.
 		cfg.Model.ReasoningEffort = models.ReasoningEffortMedium
-		cfg.Tools.RemoveTools("write_file", "apply_patch", "request_user_input")
+		cfg.Tools.RemoveTools("write_file", "apply_patch", "request_user_input", "ask_user")
 		// Override to cheaper model for OpenAI providers
 		if cfg.Model.Provider == "openai" {
 			cfg.Model.Model = ExplorerModel
@@ -681,20 +683,20 @@ func applyRoleOverrides(cfg *models.SessionConfiguration, role AgentRole) {
 	case AgentRolePlanner:
 		// Planner: read-only tools, no collab, keeps user interaction.
 		// The planner explores the codebase and produces a plan without modifications.
-		// Keeps request_user_input — planners may ask clarifying questions.
+		// Keeps request_user_input and ask_user — planners may ask clarifying questions.
 		cfg.Tools.RemoveTools("write_file", "apply_patch", "collab")
 		// Replace base instructions with planner-specific prompt
 		cfg.BaseInstructions = instructions.PlannerBaseInstructions
 	case AgentRoleOrchestrator:
 		// Orchestrator: coordination focus, no write tools, one-shot.
-		cfg.Tools.RemoveTools("write_file", "apply_patch", "request_user_input")
+		cfg.Tools.RemoveTools("write_file", "apply_patch", "request_user_input", "ask_user")
 		cfg.BaseInstructions = instructions.OrchestratorBaseInstructions
 	case AgentRoleWorker:
 		// Worker: full tool access, one-shot (no user interaction).
-		cfg.Tools.RemoveTools("request_user_input")
+		cfg.Tools.RemoveTools("request_user_input", "ask_user")
 	case AgentRoleDefault:
 		// Default: one-shot (no user interaction).
-		cfg.Tools.RemoveTools("request_user_input")
+		cfg.Tools.RemoveTools("request_user_input", "ask_user")
 	}
 }
 
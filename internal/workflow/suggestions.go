@@ -14,17 +14,13 @@ import (
 	"github.com/mfateev/temporal-agent-harness/internal/models"
 )
 
-// generateSuggestion runs the GenerateSuggestions activity synchronously to
-// populate ctrl.suggestion. Called after TurnComplete marker is added but before
-// the next awaitWithIdleTimeout. The CLI has already seen the TurnComplete via
-// polling and can show the input prompt; the suggestion appears ~300-500ms later
-// when the CLI's delayed poll picks it up.
-//
-// Best-effort: errors are silently ignored.
-func (s *SessionState) generateSuggestion(ctx workflow.Context, ctrl *LoopControl) {
+// startSuggestion starts the GenerateSuggestions activity without waiting so
+// it runs concurrently with end-of-turn bookkeeping. Returns nil when there is
+// nothing to suggest from. Pair with awaitSuggestion.
+func (s *SessionState) startSuggestion(ctx workflow.Context) workflow.Future {
 	input := s.buildSuggestionInput()
 	if input == nil {
-		return
+		return nil
 	}
 
 	suggCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
@@ -33,10 +29,27 @@ func (s *SessionState) generateSuggestion(ctx workflow.Context, ctrl *LoopContro
 			MaximumAttempts: 1, // No retries — best-effort
 		},
 	})
+	return workflow.ExecuteActivity(suggCtx, "GenerateSuggestions", *input)
+}
 
+// awaitSuggestion waits for a suggestion started by startSuggestion, records
+// its token usage, and populates ctrl.suggestion. Called after the
+// TurnComplete marker is added, so the CLI can already show the input prompt;
+// the suggestion appears ~300-500ms later when the CLI's delayed poll picks
+// it up.
+//
+// Best-effort: errors are silently ignored.
+func (s *SessionState) awaitSuggestion(ctx workflow.Context, ctrl *LoopControl, future workflow.Future) {
+	if future == nil {
+		return
+	}
 	var out activities.SuggestionOutput
-	err := workflow.ExecuteActivity(suggCtx, "GenerateSuggestions", *input).Get(ctx, &out)
-	if err == nil && out.Suggestion != "" {
+	if err := future.Get(ctx, &out); err != nil {
+		return
+	}
+	// Tracked separately from TotalTokens, which counts agent LLM calls only.
+	s.SuggestionTokens += out.TokenUsage.TotalTokens
+	if out.Suggestion != "" {
 		ctrl.SetSuggestion(out.Suggestion)
 	}
 }
@@ -88,8 +101,23 @@ func (s *SessionState) buildSuggestionInput() *activities.SuggestionInput {
 		return nil
 	}
 
-	// Pick cheap model based on provider
-	suggModel, suggProvider := instructions.SuggestionModelForProvider(s.Config.Model.Provider)
+	// Pick a cheap model for the provider unless configured explicitly.
+	cfg := s.Config.Suggestions
+	provider := cfg.Provider
+	if provider == "" {
+		provider = s.Config.Model.Provider
+	}
+	suggModel, suggProvider := instructions.SuggestionModelForProvider(provider)
+	if cfg.Model != "" {
+		suggModel = cfg.Model
+	}
+	if cfg.Provider != "" {
+		suggProvider = cfg.Provider
+	}
+	maxTokens := cfg.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = models.DefaultSuggestionMaxTokens
+	}
 
 	return &activities.SuggestionInput{
 		UserMessage:      lastUserMsg,
@@ -99,7 +127,7 @@ func (s *SessionState) buildSuggestionInput() *activities.SuggestionInput {
 			Provider:      suggProvider,
 			Model:         suggModel,
 			Temperature:   0.3,
-			MaxTokens:     50,
+			MaxTokens:     maxTokens,
 			ContextWindow: 4096,
 		},
 	}
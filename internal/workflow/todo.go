@@ -0,0 +1,156 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// todo.go handles the session TODO list: interception of the todo tool,
+// the /todo CLI update, and the per-turn reminder of open items.
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// Todo actions shared by the todo tool and the update_todo Update.
+const (
+	TodoActionAdd    = "add"
+	TodoActionDone   = "done"
+	TodoActionRemove = "remove"
+	TodoActionList   = "list"
+)
+
+// TodoItem is a follow-up item recorded by the user (/todo add) or the
+// model (todo tool). Persists across ContinueAsNew in SessionState.Todos.
+type TodoItem struct {
+	ID     int    `json:"id"`
+	Text   string `json:"text"`
+	Done   bool   `json:"done,omitempty"`
+	Source string `json:"source,omitempty"` // "user" or "model"
+}
+
+// UpdateTodoRequest is the payload for the update_todo Update and the
+// arguments of the todo tool.
+type UpdateTodoRequest struct {
+	Action string `json:"action"`
+	Text   string `json:"text,omitempty"`
+	ID     int    `json:"id,omitempty"`
+}
+
+// UpdateTodoResponse is returned by the update_todo Update.
+type UpdateTodoResponse struct {
+	Todos []TodoItem `json:"todos"`
+}
+
+// validateTodoRequest checks that req is well-formed for its action.
+func validateTodoRequest(req UpdateTodoRequest) error {
+	switch req.Action {
+	case TodoActionAdd:
+		if strings.TrimSpace(req.Text) == "" {
+			return fmt.Errorf("text must not be empty")
+		}
+	case TodoActionDone, TodoActionRemove:
+		if req.ID <= 0 {
+			return fmt.Errorf("id must be a positive integer")
+		}
+	case TodoActionList:
+	default:
+		return fmt.Errorf("invalid action %q (must be add, done, remove, or list)", req.Action)
+	}
+	return nil
+}
+
+// applyTodo applies a validated todo request to the session's list.
+func (s *SessionState) applyTodo(req UpdateTodoRequest, source string) error {
+	switch req.Action {
+	case TodoActionAdd:
+		s.TodoCounter++
+		s.Todos = append(s.Todos, TodoItem{
+			ID:     s.TodoCounter,
+			Text:   strings.TrimSpace(req.Text),
+			Source: source,
+		})
+	case TodoActionDone:
+		for i := range s.Todos {
+			if s.Todos[i].ID == req.ID {
+				s.Todos[i].Done = true
+				return nil
+			}
+		}
+		return fmt.Errorf("no TODO with id %d", req.ID)
+	case TodoActionRemove:
+		for i := range s.Todos {
+			if s.Todos[i].ID == req.ID {
+				s.Todos = append(s.Todos[:i], s.Todos[i+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("no TODO with id %d", req.ID)
+	}
+	return nil
+}
+
+// openTodos returns the TODO items that are not done.
+func (s *SessionState) openTodos() []TodoItem {
+	var open []TodoItem
+	for _, t := range s.Todos {
+		if !t.Done {
+			open = append(open, t)
+		}
+	}
+	return open
+}
+
+// handleTodoTool intercepts a todo tool call, applies it to the session TODO
+// list, and returns a FunctionCallOutput item listing the open items.
+func (s *SessionState) handleTodoTool(ctx workflow.Context, fc models.ConversationItem) models.ConversationItem {
+	logger := workflow.GetLogger(ctx)
+
+	var req UpdateTodoRequest
+	err := json.Unmarshal([]byte(fc.Arguments), &req)
+	if err == nil {
+		err = validateTodoRequest(req)
+	}
+	if err == nil {
+		err = s.applyTodo(req, "model")
+	}
+	if err != nil {
+		logger.Warn("Invalid todo args", "error", err)
+		falseVal := false
+		return models.ConversationItem{
+			Type:   models.ItemTypeFunctionCallOutput,
+			CallID: fc.CallID,
+			Output: &models.FunctionCallOutputPayload{
+				Content: fmt.Sprintf("Invalid todo arguments: %v", err),
+				Success: &falseVal,
+			},
+		}
+	}
+
+	logger.Info("TODO list updated", "action", req.Action, "open", len(s.openTodos()))
+
+	trueVal := true
+	return models.ConversationItem{
+		Type:   models.ItemTypeFunctionCallOutput,
+		CallID: fc.CallID,
+		Output: &models.FunctionCallOutputPayload{
+			Content: formatTodoList(s.openTodos()),
+			Success: &trueVal,
+		},
+	}
+}
+
+// formatTodoList renders TODO items as a plain-text list for the model.
+func formatTodoList(todos []TodoItem) string {
+	if len(todos) == 0 {
+		return "No open TODOs."
+	}
+	var b strings.Builder
+	b.WriteString("Open TODOs:\n")
+	for _, t := range todos {
+		b.WriteString(fmt.Sprintf("- [%d] %s\n", t.ID, t.Text))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
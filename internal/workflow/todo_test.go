@@ -0,0 +1,53 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTodoRequest(t *testing.T) {
+	assert.NoError(t, validateTodoRequest(UpdateTodoRequest{Action: TodoActionList}))
+	assert.NoError(t, validateTodoRequest(UpdateTodoRequest{Action: TodoActionAdd, Text: "x"}))
+	assert.NoError(t, validateTodoRequest(UpdateTodoRequest{Action: TodoActionDone, ID: 1}))
+	assert.Error(t, validateTodoRequest(UpdateTodoRequest{Action: TodoActionAdd, Text: "  "}))
+	assert.Error(t, validateTodoRequest(UpdateTodoRequest{Action: TodoActionRemove}))
+	assert.Error(t, validateTodoRequest(UpdateTodoRequest{Action: "bogus"}))
+}
+
+func TestApplyTodo(t *testing.T) {
+	s := &SessionState{}
+	require.NoError(t, s.applyTodo(UpdateTodoRequest{Action: TodoActionAdd, Text: " first "}, "user"))
+	require.NoError(t, s.applyTodo(UpdateTodoRequest{Action: TodoActionAdd, Text: "second"}, "model"))
+	require.NoError(t, s.applyTodo(UpdateTodoRequest{Action: TodoActionAdd, Text: "third"}, "model"))
+
+	require.NoError(t, s.applyTodo(UpdateTodoRequest{Action: TodoActionDone, ID: 1}, "user"))
+	require.NoError(t, s.applyTodo(UpdateTodoRequest{Action: TodoActionRemove, ID: 2}, "user"))
+	assert.Error(t, s.applyTodo(UpdateTodoRequest{Action: TodoActionDone, ID: 2}, "user"))
+
+	assert.Equal(t, []TodoItem{
+		{ID: 1, Text: "first", Done: true, Source: "user"},
+		{ID: 3, Text: "third", Source: "model"},
+	}, s.Todos)
+	assert.Equal(t, []TodoItem{{ID: 3, Text: "third", Source: "model"}}, s.openTodos())
+
+	// IDs are never reused after removal.
+	require.NoError(t, s.applyTodo(UpdateTodoRequest{Action: TodoActionAdd, Text: "fourth"}, "user"))
+	assert.Equal(t, 4, s.Todos[len(s.Todos)-1].ID)
+}
+
+func TestDeveloperInstructionsForTurn(t *testing.T) {
+	s := &SessionState{}
+	s.Config.DeveloperInstructions = "Be terse."
+	assert.Equal(t, "Be terse.", s.developerInstructionsForTurn())
+
+	s.Todos = []TodoItem{{ID: 1, Text: "done already", Done: true}, {ID: 2, Text: "run tests"}}
+	got := s.developerInstructionsForTurn()
+	assert.Contains(t, got, "Be terse.\n\n<todo_reminder>")
+	assert.Contains(t, got, "- [2] run tests")
+	assert.NotContains(t, got, "done already")
+
+	s.Config.DeveloperInstructions = ""
+	assert.Contains(t, s.developerInstructionsForTurn(), "<todo_reminder>\nOpen TODOs:")
+}
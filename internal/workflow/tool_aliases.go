@@ -0,0 +1,50 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// tool_aliases.go rewrites calls to tool names the session does not offer
+// but that name a known tool elsewhere ("bash", "str_replace_editor") into
+// calls to the enabled tool, before they reach history, approval, or dispatch.
+package workflow
+
+import (
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/tools"
+)
+
+// resolveToolAliases rewrites aliased function calls in result in place.
+// Calls to tools that exist in the session are never rewritten, so an alias
+// cannot shadow a real tool (including MCP tools).
+func (s *SessionState) resolveToolAliases(ctx workflow.Context, result *activities.LLMActivityOutput) {
+	s.applyToolAliases(result, func(alias, target, callID string) {
+		workflow.GetLogger(ctx).Info("Resolved tool alias",
+			"alias", alias, "tool", target, "call_id", callID)
+	})
+}
+
+// applyToolAliases rewrites aliased calls in result, calling resolved (if
+// set) for each one.
+func (s *SessionState) applyToolAliases(result *activities.LLMActivityOutput, resolved func(alias, target, callID string)) {
+	enabled := make(map[string]bool, len(s.ToolSpecs))
+	for _, spec := range s.ToolSpecs {
+		enabled[spec.Name] = true
+	}
+	isEnabled := func(name string) bool { return enabled[name] }
+
+	for i := range result.Items {
+		item := &result.Items[i]
+		if item.Type != models.ItemTypeFunctionCall || enabled[item.Name] {
+			continue
+		}
+		target, args, ok := tools.ResolveToolAlias(item.Name, item.Arguments, s.Config.Tools.Aliases, isEnabled)
+		if !ok {
+			continue
+		}
+		if resolved != nil {
+			resolved(item.Name, target, item.CallID)
+		}
+		item.Name = target
+		item.Arguments = args
+	}
+}
@@ -27,6 +27,11 @@ type ToolsExecutor struct {
 	// MCP fields for routing mcp__* tool calls.
 	sessionID     string
 	mcpToolLookup map[string]tools.McpToolRef
+	excludePaths  []string
+	lineEndings   string
+	turnID        string
+	remote        *models.RemoteConfig
+	onToolDone    func(i int)
 }
 
 // NewToolsExecutor creates a ToolsExecutor with the given specs, working directory, and task queue.
@@ -34,20 +39,49 @@ func NewToolsExecutor(specs []tools.ToolSpec, cwd, taskQueue string) *ToolsExecu
 	return &ToolsExecutor{toolSpecs: specs, cwd: cwd, sessionTaskQueue: taskQueue}
 }
 
-// WithMcpContext sets MCP routing context on the executor for dispatching MCP tool calls.
+// WithMcpContext sets the session ID and MCP routing context on the executor.
+// The session ID also selects the per-session scratch directory.
 func (e *ToolsExecutor) WithMcpContext(sessionID string, lookup map[string]tools.McpToolRef) *ToolsExecutor {
 	e.sessionID = sessionID
 	e.mcpToolLookup = lookup
 	return e
 }
 
-// ExecuteParallel runs all tool activities in parallel and waits for all.
-// Delegates to executeToolsInParallel.
-func (e *ToolsExecutor) ExecuteParallel(ctx workflow.Context, calls []models.ConversationItem) ([]activities.ToolActivityOutput, error) {
-	return executeToolsInParallel(ctx, calls, e.toolSpecs, e.cwd, e.sessionTaskQueue, e.sessionID, e.mcpToolLookup)
+// WithExcludePaths sets the config-supplied path exclusion patterns passed
+// to every tool call.
+func (e *ToolsExecutor) WithExcludePaths(patterns []string) *ToolsExecutor {
+	e.excludePaths = patterns
+	return e
+}
+
+// WithLineEndings sets the line-ending policy passed to every tool call.
+func (e *ToolsExecutor) WithLineEndings(policy string) *ToolsExecutor {
+	e.lineEndings = policy
+	return e
+}
+
+// WithTurnID sets the turn the tool calls belong to. Workers use it to scope
+// per-turn caches such as repeated read_file results.
+func (e *ToolsExecutor) WithTurnID(turnID string) *ToolsExecutor {
+	e.turnID = turnID
+	return e
+}
+
+// WithRemote runs the tool calls on the session's remote machine; nil runs
+// them on the tool worker.
+func (e *ToolsExecutor) WithRemote(cfg *models.RemoteConfig) *ToolsExecutor {
+	e.remote = cfg
+	return e
+}
+
+// WithToolDone sets a callback run as each call of a batch finishes, with
+// the call's index. The turn uses it to move the current focus along.
+func (e *ToolsExecutor) WithToolDone(fn func(i int)) *ToolsExecutor {
+	e.onToolDone = fn
+	return e
 }
 
-// executeToolsInParallel runs all tool activities in parallel and waits for all.
+// ExecuteParallel runs all tool activities in parallel and waits for all.
 //
 // Each tool gets a per-activity StartToCloseTimeout derived from:
 //  1. timeout_ms argument provided by the LLM (highest priority)
@@ -58,17 +92,20 @@ func (e *ToolsExecutor) ExecuteParallel(ctx workflow.Context, calls []models.Con
 // (enabling per-session worker routing in multi-host mode).
 //
 // Maps to: codex-rs/core/src/tools/parallel.rs drain_in_flight
-func executeToolsInParallel(ctx workflow.Context, functionCalls []models.ConversationItem, toolSpecs []tools.ToolSpec, cwd, sessionTaskQueue, sessionID string, mcpToolLookup map[string]tools.McpToolRef) ([]activities.ToolActivityOutput, error) {
+func (e *ToolsExecutor) ExecuteParallel(ctx workflow.Context, functionCalls []models.ConversationItem) ([]activities.ToolActivityOutput, error) {
 	logger := workflow.GetLogger(ctx)
 
 	// Build a lookup map from tool name to spec for fast access.
-	specByName := make(map[string]tools.ToolSpec, len(toolSpecs))
-	for _, spec := range toolSpecs {
+	specByName := make(map[string]tools.ToolSpec, len(e.toolSpecs))
+	for _, spec := range e.toolSpecs {
 		specByName[spec.Name] = spec
 	}
 
-	// Start all tool activities in parallel using futures
+	// Start all tool activities in parallel using futures. Calls that write
+	// a file an earlier call in the batch also writes wait for it first.
+	started := workflow.Now(ctx)
 	futures := make([]workflow.Future, len(functionCalls))
+	locks := newFileLocks()
 	for i, fc := range functionCalls {
 		logger.Info("Starting tool execution", "tool", fc.Name, "call_id", fc.CallID)
 
@@ -93,39 +130,80 @@ func executeToolsInParallel(ctx workflow.Context, functionCalls []models.Convers
 		if fc.Name == "exec_command" || fc.Name == "write_stdin" {
 			actOpts.HeartbeatTimeout = 15 * time.Second
 		}
-		if sessionTaskQueue != "" {
-			actOpts.TaskQueue = sessionTaskQueue
+		if e.sessionTaskQueue != "" {
+			actOpts.TaskQueue = e.sessionTaskQueue
 		}
 		toolCtx := workflow.WithActivityOptions(ctx, actOpts)
 
 		input := activities.ToolActivityInput{
-			CallID:    fc.CallID,
-			ToolName:  fc.Name,
-			Arguments: args,
-			Cwd:       cwd,
+			CallID:       fc.CallID,
+			ToolName:     fc.Name,
+			Arguments:    args,
+			Cwd:          e.cwd,
+			ScratchID:    e.sessionID,
+			ExcludePaths: e.excludePaths,
+			LineEndings:  e.lineEndings,
+			TurnID:       e.turnID,
+			Remote:       e.remote,
 		}
 
 		// Populate MCP routing info for mcp__* tools
-		if ref, ok := mcpToolLookup[fc.Name]; ok {
+		if ref, ok := e.mcpToolLookup[fc.Name]; ok {
 			input.McpToolRef = &ref
-			input.SessionID = sessionID
+			input.SessionID = e.sessionID
+		}
+		// Background exec sessions are owned by the session, which closes
+		// them when it ends (see cleanup.go). Repeated reads are cached per
+		// session and turn on the worker.
+		if fc.Name == "exec_command" || fc.Name == "read_file" {
+			input.SessionID = e.sessionID
 		}
 
-		futures[i] = workflow.ExecuteActivity(toolCtx, "ExecuteTool", input)
+		deps := locks.acquire(i, mutationPaths(fc.Name, args, e.cwd))
+		if len(deps) == 0 {
+			futures[i] = workflow.ExecuteActivity(toolCtx, "ExecuteTool", input)
+			continue
+		}
+		logger.Info("Serializing tool call behind an earlier write to the same file",
+			"tool", fc.Name, "call_id", fc.CallID, "waits_for", len(deps))
+		waitFor := make([]workflow.Future, len(deps))
+		for k, j := range deps {
+			waitFor[k] = futures[j]
+		}
+		future, settable := workflow.NewFuture(ctx)
+		futures[i] = future
+		workflow.Go(ctx, func(gctx workflow.Context) {
+			for _, f := range waitFor {
+				_ = f.Get(gctx, nil)
+			}
+			settable.Chain(workflow.ExecuteActivity(workflow.WithActivityOptions(gctx, actOpts), "ExecuteTool", input))
+		})
 	}
 
-	// Wait for ALL tools to complete.
+	// Wait for ALL tools to complete, in completion order so each result's
+	// duration reflects when that tool actually finished.
 	// Activity errors (ApplicationError) are converted to failed tool results
 	// so the LLM can see what went wrong and decide how to proceed.
 	results := make([]activities.ToolActivityOutput, len(functionCalls))
+	selector := workflow.NewSelector(ctx)
 	for i, future := range futures {
-		var result activities.ToolActivityOutput
-		if err := future.Get(ctx, &result); err != nil {
-			results[i] = toolActivityErrorToOutput(logger, functionCalls[i].CallID, functionCalls[i].Name, err)
-		} else {
+		i := i
+		selector.AddFuture(future, func(f workflow.Future) {
+			var result activities.ToolActivityOutput
+			if err := f.Get(ctx, &result); err != nil {
+				result = toolActivityErrorToOutput(logger, functionCalls[i].CallID, functionCalls[i].Name, err)
+			} else {
+				logger.Info("Tool execution completed", "tool", functionCalls[i].Name)
+			}
+			result.DurationMs = workflow.Now(ctx).Sub(started).Milliseconds()
 			results[i] = result
-			logger.Info("Tool execution completed", "tool", functionCalls[i].Name)
-		}
+			if e.onToolDone != nil {
+				e.onToolDone(i)
+			}
+		})
+	}
+	for range futures {
+		selector.Select(ctx)
 	}
 
 	return results, nil
@@ -134,8 +212,14 @@ func executeToolsInParallel(ctx workflow.Context, functionCalls []models.Convers
 // buildToolSpecs builds tool specifications based on configuration and profile.
 // It builds specs from the EnabledTools list (expanding groups), then filters
 // out any tools listed in the profile's ToolOverrides.Disable list.
+// read_artifact is added whenever the tool output window is on, since the
+// model needs it to expand the stubs that replace older outputs.
 func buildToolSpecs(config models.ToolsConfig, profile models.ResolvedProfile) []tools.ToolSpec {
-	specs := tools.BuildSpecs(config.EnabledTools)
+	names := config.EnabledTools
+	if config.OutputWindow > 0 && !config.HasTool("read_artifact") {
+		names = append(append([]string(nil), names...), "read_artifact")
+	}
+	specs := tools.BuildSpecs(names)
 
 	// Filter out tools disabled by the profile
 	if profile.Tools != nil && len(profile.Tools.Disable) > 0 {
@@ -0,0 +1,56 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// tool_limits.go rejects tool calls whose arguments exceed the configured
+// size limit before they are dispatched.
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// rejectOversizedToolCalls finds function calls in result whose arguments
+// exceed the limit for their tool. Their arguments are replaced in place with
+// a small placeholder so the oversized payload never reaches history, and a
+// failed FunctionCallOutput explaining the limit is returned for each.
+func (s *SessionState) rejectOversizedToolCalls(result *activities.LLMActivityOutput) []models.ConversationItem {
+	var rejected []models.ConversationItem
+	for i := range result.Items {
+		item := &result.Items[i]
+		if item.Type != models.ItemTypeFunctionCall {
+			continue
+		}
+		size := len(item.Arguments)
+		limit := s.Config.Tools.ArgumentLimit(item.Name)
+		if size <= limit {
+			continue
+		}
+		item.Arguments = fmt.Sprintf(`{"_omitted":"arguments exceeded size limit","_bytes":%d}`, size)
+
+		falseVal := false
+		rejected = append(rejected, models.ConversationItem{
+			Type:   models.ItemTypeFunctionCallOutput,
+			CallID: item.CallID,
+			Output: &models.FunctionCallOutputPayload{
+				Content: oversizedArgumentsMessage(item.Name, size, limit),
+				Success: &falseVal,
+			},
+		})
+	}
+	return rejected
+}
+
+// oversizedArgumentsMessage explains a size-limit rejection to the model.
+func oversizedArgumentsMessage(toolName string, size, limit int) string {
+	msg := fmt.Sprintf("Rejected: %s arguments were %d bytes, over the %d byte limit. The call was not executed.",
+		toolName, size, limit)
+	switch toolName {
+	case "write_file", "apply_patch":
+		return msg + " Split the change into several smaller edits."
+	default:
+		return msg + " Do not inline large content in tool arguments; write files with write_file or apply_patch " +
+			"(in smaller pieces if needed) and reference them by path."
+	}
+}
@@ -0,0 +1,71 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// tool_stats.go tracks per-tool call counts, failures, durations and output
+// sizes for the get_tool_stats query.
+package workflow
+
+import (
+	"sort"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+// ToolStat accumulates statistics for a single tool.
+type ToolStat struct {
+	Calls           int   `json:"calls"`
+	Failures        int   `json:"failures"`
+	TotalDurationMs int64 `json:"total_duration_ms"`
+	OutputBytes     int64 `json:"output_bytes"`
+}
+
+// recordToolStats folds a batch of tool results into s.ToolStats.
+// Results are matched to calls by CallID; results without a matching call
+// are ignored.
+func (s *SessionState) recordToolStats(calls []models.ConversationItem, results []activities.ToolActivityOutput) {
+	names := make(map[string]string, len(calls))
+	for _, fc := range calls {
+		names[fc.CallID] = fc.Name
+	}
+
+	for _, r := range results {
+		name, ok := names[r.CallID]
+		if !ok {
+			continue
+		}
+		if s.ToolStats == nil {
+			s.ToolStats = make(map[string]ToolStat)
+		}
+		st := s.ToolStats[name]
+		st.Calls++
+		if r.Success != nil && !*r.Success {
+			st.Failures++
+		}
+		st.TotalDurationMs += r.DurationMs
+		st.OutputBytes += int64(len(r.Content))
+		s.ToolStats[name] = st
+	}
+}
+
+// toolStatSummaries returns the per-tool statistics sorted by tool name.
+func (s *SessionState) toolStatSummaries() []ToolStatSummary {
+	summaries := make([]ToolStatSummary, 0, len(s.ToolStats))
+	for name, st := range s.ToolStats {
+		sum := ToolStatSummary{
+			ToolName:        name,
+			Calls:           st.Calls,
+			Failures:        st.Failures,
+			TotalDurationMs: st.TotalDurationMs,
+			OutputBytes:     st.OutputBytes,
+		}
+		if st.Calls > 0 {
+			sum.FailureRate = float64(st.Failures) / float64(st.Calls)
+			sum.AvgDurationMs = st.TotalDurationMs / int64(st.Calls)
+		}
+		summaries = append(summaries, sum)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].ToolName < summaries[j].ToolName
+	})
+	return summaries
+}
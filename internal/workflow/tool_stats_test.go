@@ -0,0 +1,53 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+func TestRecordToolStats(t *testing.T) {
+	ok, fail := true, false
+	s := &SessionState{}
+
+	calls := []models.ConversationItem{
+		{CallID: "1", Name: "grep_files"},
+		{CallID: "2", Name: "shell"},
+	}
+	s.recordToolStats(calls, []activities.ToolActivityOutput{
+		{CallID: "1", Content: "rg: not found", Success: &fail, DurationMs: 10},
+		{CallID: "2", Content: "hello\n", Success: &ok, DurationMs: 300},
+		{CallID: "unknown", Content: "ignored", DurationMs: 99},
+	})
+	s.recordToolStats([]models.ConversationItem{{CallID: "3", Name: "shell"}}, []activities.ToolActivityOutput{
+		{CallID: "3", Content: "ok", DurationMs: 100},
+	})
+
+	summaries := s.toolStatSummaries()
+	require.Len(t, summaries, 2)
+
+	assert.Equal(t, ToolStatSummary{
+		ToolName:        "grep_files",
+		Calls:           1,
+		Failures:        1,
+		FailureRate:     1,
+		AvgDurationMs:   10,
+		TotalDurationMs: 10,
+		OutputBytes:     int64(len("rg: not found")),
+	}, summaries[0])
+
+	assert.Equal(t, "shell", summaries[1].ToolName)
+	assert.Equal(t, 2, summaries[1].Calls)
+	assert.Equal(t, 0, summaries[1].Failures)
+	assert.Equal(t, int64(200), summaries[1].AvgDurationMs)
+	assert.Equal(t, int64(8), summaries[1].OutputBytes)
+}
+
+func TestToolStatSummaries_Empty(t *testing.T) {
+	s := &SessionState{}
+	assert.Empty(t, s.toolStatSummaries())
+}
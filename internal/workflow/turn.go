@@ -9,12 +9,16 @@ package workflow
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 
 	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/command_safety"
+	"github.com/mfateev/temporal-agent-harness/internal/injection"
+	"github.com/mfateev/temporal-agent-harness/internal/instructions"
 	"github.com/mfateev/temporal-agent-harness/internal/models"
 )
 
@@ -25,11 +29,22 @@ import (
 func (s *SessionState) runAgenticTurn(ctx workflow.Context, ctrl *LoopControl) (bool, error) {
 	logger := workflow.GetLogger(ctx)
 	s.compactedThisTurn = false
-	gate := NewApprovalGate(s.Config.Permissions.ApprovalMode, s.ExecPolicyRules)
-	executor := NewToolsExecutor(s.ToolSpecs, s.Config.Cwd, s.Config.SessionTaskQueue)
-	if len(s.McpToolLookup) > 0 {
-		executor.WithMcpContext(s.ConversationID, s.McpToolLookup)
-	}
+	s.turnDiffLines, s.turnDiffConfirmed = 0, 0
+	s.autoContinueCount = 0
+	s.pendingContinuation = nil
+	s.LastPostMortem = nil
+	s.injectionSource = ""
+	s.turnStart = workflow.Now(ctx).In(models.LoadTimezone(s.Config.Timezone))
+	defer s.flushPendingContinuation(ctrl)
+	s.startTurnLatency(ctx, ctrl.CurrentTurnID())
+	defer s.finishTurnLatency(ctx)
+	gate := NewApprovalGate(s.Config.Permissions.ApprovalMode, s.ExecPolicyRules,
+		command_safety.NewClassifier(s.Config.Permissions.SafeCommands, s.Config.Permissions.UnsafeCommands))
+	executor := s.toolsExecutor(ctrl)
+	s.maybeFailoverProvider(ctx, ctrl)
+	s.turnModel = ""
+	s.routeTrivialTurn(ctx, ctrl)
+	defer func() { s.routedModel = nil }()
 
 	for s.IterationCount < s.MaxIterations {
 		if ctrl.IsInterrupted() {
@@ -37,10 +52,27 @@ func (s *SessionState) runAgenticTurn(ctx workflow.Context, ctrl *LoopControl) (
 			return false, nil
 		}
 		logger.Info("Starting iteration", "iteration", s.IterationCount, "turn_id", ctrl.CurrentTurnID())
+		if s.checkCostGuardrail(ctx, ctrl) {
+			return false, nil
+		}
 
 		s.maybeCompactBeforeLLM(ctx, ctrl)
+		s.awaitRateLimitHeadroom(ctx, ctrl)
+		if ctrl.IsInterrupted() {
+			logger.Info("Turn interrupted while waiting for rate limit")
+			return false, nil
+		}
+		if s.pendingContinuation == nil {
+			s.runPreTurnHooks(ctx, ctrl)
+		}
 
+		llmStart := workflow.Now(ctx)
 		llmResult, err := s.callLLM(ctx, ctrl)
+		s.addLLMLatency(ctx, llmStart, err == nil)
+		if err != nil && ctrl.IsHardInterrupted() {
+			logger.Info("Turn hard interrupted during LLM call")
+			return false, nil
+		}
 		if err != nil {
 			retry, handleErr := s.handleLLMError(ctx, ctrl, err)
 			if handleErr != nil {
@@ -56,9 +88,35 @@ func (s *SessionState) runAgenticTurn(ctx workflow.Context, ctrl *LoopControl) (
 			return false, nil
 		}
 
-		s.recordLLMResponse(ctx, ctrl, llmResult)
+		if s.routedModel != nil && len(extractFunctionCalls(llmResult.Items)) > 0 {
+			s.escalateRoutedTurn(ctx, ctrl, llmResult)
+			continue
+		}
+
+		continued := s.mergePendingContinuation(llmResult)
+		if s.holdForContinuation(llmResult) {
+			logger.Info("Response truncated by output token limit, auto-continuing",
+				"continuation", s.autoContinueCount, "max", maxAutoContinuations)
+			s.recordLLMResponse(ctx, ctrl, llmResult)
+			continue
+		}
+		oversized := s.recordLLMResponse(ctx, ctrl, llmResult)
+		if continued {
+			// The server-side response chain includes the continuation prompt
+			// and the split parts; resend full history on the next call.
+			s.LastResponseID = ""
+			s.lastSentHistoryLen = 0
+		}
 
 		calls := extractFunctionCalls(llmResult.Items)
+		if len(oversized) > 0 {
+			logger.Warn("Rejected tool calls with oversized arguments", "count", len(oversized))
+			calls = s.recordForbiddenAndFilter(ctrl, calls, oversized)
+			if len(calls) == 0 {
+				s.IterationCount++
+				continue
+			}
+		}
 		calls, hadIntercepted, err := s.dispatchInterceptedCalls(ctx, ctrl, calls)
 		if err != nil {
 			return false, err
@@ -79,6 +137,8 @@ func (s *SessionState) runAgenticTurn(ctx workflow.Context, ctrl *LoopControl) (
 					Content: "[Turn ended: detected repeated identical tool calls. Please try a different approach.]",
 				})
 				ctrl.NotifyItemAdded()
+				s.recordTurnFailure(ctrl, models.TurnErrorRepeatedToolCalls,
+					fmt.Sprintf("identical tool calls repeated %d times", s.repeatCount))
 				return false, nil
 			}
 			allDenied, execErr := s.approveAndExecuteTools(ctx, ctrl, gate, executor, calls)
@@ -112,6 +172,8 @@ func (s *SessionState) runAgenticTurn(ctx workflow.Context, ctrl *LoopControl) (
 		Content: fmt.Sprintf("[Turn ended: reached maximum of %d iterations without completing. The task may need to be broken into smaller steps.]", s.MaxIterations),
 	})
 	ctrl.NotifyItemAdded()
+	s.recordTurnFailure(ctrl, models.TurnErrorIterationLimit,
+		fmt.Sprintf("reached maximum of %d iterations", s.MaxIterations))
 	return false, nil
 }
 
@@ -150,6 +212,12 @@ func (s *SessionState) maybeCompactBeforeLLM(ctx workflow.Context, ctrl *LoopCon
 		switchMsg := fmt.Sprintf("<model_switch>\nThe user switched from model %q to %q "+
 			"(context window: %d tokens). Continue the conversation seamlessly.\n</model_switch>",
 			s.PreviousModel, s.Config.Model.Model, s.Config.Model.ContextWindow)
+		if s.modelSwitchReason != "" {
+			switchMsg = fmt.Sprintf("<model_switch>\nThe session switched from model %q to %q %s "+
+				"(context window: %d tokens). Continue the conversation seamlessly.\n</model_switch>",
+				s.PreviousModel, s.Config.Model.Model, s.modelSwitchReason, s.Config.Model.ContextWindow)
+			s.modelSwitchReason = ""
+		}
 		_ = s.History.AddItem(models.ConversationItem{
 			Type:    models.ItemTypeModelSwitch,
 			Content: switchMsg,
@@ -192,14 +260,16 @@ func (s *SessionState) maybeCompactBeforeLLM(ctx workflow.Context, ctrl *LoopCon
 // callLLM prepares incremental history and executes the LLM activity.
 // Returns the LLM output or an error for handleLLMError to classify.
 func (s *SessionState) callLLM(ctx workflow.Context, ctrl *LoopControl) (*activities.LLMActivityOutput, error) {
-	historyItems, err := s.History.GetForPrompt()
+	historyItems, err := s.promptHistory()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get history: %w", err)
 	}
 
 	var inputItems []models.ConversationItem
 	var previousResponseID string
-	if s.LastResponseID != "" && s.lastSentHistoryLen > 0 && s.lastSentHistoryLen <= len(historyItems) {
+	if s.pendingContinuation != nil {
+		inputItems = s.continuationInput(historyItems)
+	} else if s.routedModel == nil && s.LastResponseID != "" && s.lastSentHistoryLen > 0 && s.lastSentHistoryLen <= len(historyItems) {
 		inputItems = historyItems[s.lastSentHistoryLen:]
 		previousResponseID = s.LastResponseID
 	} else {
@@ -226,22 +296,101 @@ func (s *SessionState) callLLM(ctx workflow.Context, ctrl *LoopControl) (*activi
 
 	llmInput := activities.LLMActivityInput{
 		History:               inputItems,
-		ModelConfig:           s.Config.Model,
+		ModelConfig:           s.callModel(),
 		ToolSpecs:             s.ToolSpecs,
 		BaseInstructions:      s.Config.BaseInstructions,
-		DeveloperInstructions: s.Config.DeveloperInstructions,
-		UserInstructions:      s.Config.UserInstructions,
+		DeveloperInstructions: s.developerInstructionsForTurn(),
+		UserInstructions:      s.userInstructionsForTurn(),
 		PreviousResponseID:    previousResponseID,
 	}
 
+	llmCtx, release := cancelOnHardInterrupt(llmCtx, ctrl)
+	defer release()
+
+	snapshot := newPromptSnapshot(ctrl.CurrentTurnID(), s.IterationCount, workflow.Now(ctx), historyItems, llmInput)
+
 	var llmResult activities.LLMActivityOutput
 	err = workflow.ExecuteActivity(llmCtx, "ExecuteLLMCall", llmInput).Get(ctx, &llmResult)
 	if err != nil {
 		return nil, err
 	}
+	snapshot.Response = llmResult.Items
+	snapshot.FinishReason = llmResult.FinishReason
+	snapshot.RequestHash = s.recordRequestFingerprint(ctrl.CurrentTurnID(), s.IterationCount, historyItems, llmInput)
+	s.turnModel = modelRef(llmInput.ModelConfig)
+	s.lastPrompt = snapshot
 	return &llmResult, nil
 }
 
+// userInstructionsForTurn returns the user instructions for the next LLM
+// call: the configured instructions followed by the persona preset, if any.
+func (s *SessionState) userInstructionsForTurn() string {
+	persona, ok := instructions.PersonaInstructions(s.Config.Persona)
+	if !ok {
+		return s.Config.UserInstructions
+	}
+	if s.Config.UserInstructions == "" {
+		return persona
+	}
+	return s.Config.UserInstructions + "\n\n" + persona
+}
+
+// developerInstructionsForTurn returns the developer instructions for the
+// next LLM call: the configured instructions and the notes added each turn,
+// separated by blank lines.
+func (s *SessionState) developerInstructionsForTurn() string {
+	var parts []string
+	if s.Config.DeveloperInstructions != "" {
+		parts = append(parts, s.Config.DeveloperInstructions)
+	}
+	if s.hookInstructions != "" {
+		parts = append(parts, s.hookInstructions)
+	}
+	if glossary := s.glossaryInstructions(); glossary != "" {
+		parts = append(parts, glossary)
+	}
+	if s.hasCommandTool() {
+		parts = append(parts, scratchInstructions)
+	}
+	if s.injectionGuardEnabled() {
+		parts = append(parts, injection.Instructions)
+	}
+	if !s.turnStart.IsZero() {
+		parts = append(parts, currentDateInstructions(s.turnStart, s.Config.Locale))
+	}
+	if open := s.openTodos(); len(open) > 0 {
+		parts = append(parts, "<todo_reminder>\n"+formatTodoList(open)+
+			"\nAddress these when relevant and mark them done with the todo tool once complete.\n</todo_reminder>")
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// toolsExecutor returns the executor for the tool calls of the current turn.
+func (s *SessionState) toolsExecutor(ctrl *LoopControl) *ToolsExecutor {
+	return NewToolsExecutor(s.ToolSpecs, s.Config.Cwd, s.Config.SessionTaskQueue).
+		WithMcpContext(s.ConversationID, s.McpToolLookup).
+		WithExcludePaths(s.Config.Tools.ExcludePaths).
+		WithLineEndings(s.Config.Tools.LineEndings.For(s.Config.Cwd)).
+		WithTurnID(ctrl.CurrentTurnID()).
+		WithRemote(s.Config.Remote)
+}
+
+// cancelOnHardInterrupt returns a child context that is cancelled if a hard
+// interrupt arrives before release is called. Cancelling the context resolves
+// pending activity futures immediately with a CanceledError; the activities
+// themselves observe cancellation on their next heartbeat.
+func cancelOnHardInterrupt(ctx workflow.Context, ctrl *LoopControl) (workflow.Context, func()) {
+	childCtx, cancel := workflow.WithCancel(ctx)
+	released := false
+	workflow.Go(ctx, func(gCtx workflow.Context) {
+		_ = workflow.Await(gCtx, func() bool { return released || ctrl.IsHardInterrupted() })
+		if !released {
+			cancel()
+		}
+	})
+	return childCtx, func() { released = true }
+}
+
 // handleLLMError classifies and handles LLM errors: context overflow -> compact+retry,
 // rate limit -> sleep+retry, fatal -> end turn. Returns (continueLoop, error).
 func (s *SessionState) handleLLMError(ctx workflow.Context, ctrl *LoopControl, err error) (bool, error) {
@@ -278,6 +427,7 @@ func (s *SessionState) handleLLMError(ctx workflow.Context, ctrl *LoopControl, e
 				TurnID:  ctrl.CurrentTurnID(),
 			})
 			ctrl.NotifyItemAdded()
+			s.recordTurnFailure(ctrl, models.TurnErrorLLMFatal, appErr.Message())
 			return false, nil // end turn
 		}
 	}
@@ -290,17 +440,21 @@ func (s *SessionState) handleLLMError(ctx workflow.Context, ctrl *LoopControl, e
 		TurnID:  ctrl.CurrentTurnID(),
 	})
 	ctrl.NotifyItemAdded()
+	s.recordTurnFailure(ctrl, models.TurnErrorLLMFailure, err.Error())
 	return false, nil // end turn
 }
 
 // recordLLMResponse adds response items to history, tracks tokens, and updates
-// the response ID for incremental sends.
-func (s *SessionState) recordLLMResponse(ctx workflow.Context, ctrl *LoopControl, result *activities.LLMActivityOutput) {
+// the response ID for incremental sends. Returns failed outputs for tool calls
+// rejected for oversized arguments; the caller records them and skips those calls.
+func (s *SessionState) recordLLMResponse(ctx workflow.Context, ctrl *LoopControl, result *activities.LLMActivityOutput) []models.ConversationItem {
 	logger := workflow.GetLogger(ctx)
 
 	s.TotalTokens += result.TokenUsage.TotalTokens
 	s.TotalCachedTokens += result.TokenUsage.CachedTokens
 	s.LastTokenUsage = result.TokenUsage
+	s.addLLMCost(ctx, s.callModel(), result.TokenUsage)
+	s.recordRateLimits(result.RateLimits)
 	logger.Info("LLM call completed",
 		"tokens", result.TokenUsage.TotalTokens,
 		"cached_tokens", result.TokenUsage.CachedTokens,
@@ -308,19 +462,31 @@ func (s *SessionState) recordLLMResponse(ctx workflow.Context, ctrl *LoopControl
 		"finish_reason", result.FinishReason,
 		"items", len(result.Items))
 
+	if dropped, replay := s.dedupeLLMResponse(result); dropped > 0 {
+		logger.Warn("Dropped duplicate LLM output",
+			"dropped_items", dropped,
+			"replay", replay,
+			"idempotency_key", result.IdempotencyKey,
+			"total_deduped", s.DedupedAssistantItems)
+	}
+	s.resolveToolAliases(ctx, result)
+	oversized := s.rejectOversizedToolCalls(result)
 	for _, item := range result.Items {
 		_ = s.History.AddItem(item)
 		ctrl.NotifyItemAdded()
 	}
-	if result.ResponseID != "" {
+	// A routed call's response is not chained: the session's model keeps
+	// its own response chain across trivial turns.
+	if result.ResponseID != "" && s.routedModel == nil {
 		s.LastResponseID = result.ResponseID
 		allItems, _ := s.History.GetForPrompt()
 		s.lastSentHistoryLen = len(allItems)
 	}
+	return oversized
 }
 
-// dispatchInterceptedCalls processes workflow-handled tool calls (request_user_input
-// and collab tools), returning the remaining normal calls and whether any were intercepted.
+// dispatchInterceptedCalls processes workflow-handled tool calls (request_user_input,
+// ask_user and collab tools), returning the remaining normal calls and whether any were intercepted.
 func (s *SessionState) dispatchInterceptedCalls(ctx workflow.Context, ctrl *LoopControl, calls []models.ConversationItem) (remaining []models.ConversationItem, hadIntercepted bool, err error) {
 	if len(calls) == 0 {
 		return calls, false, nil
@@ -338,6 +504,16 @@ func (s *SessionState) dispatchInterceptedCalls(ctx workflow.Context, ctrl *Loop
 				return nil, hadIntercepted, fmt.Errorf("failed to add user input response: %w", addErr)
 			}
 			ctrl.NotifyItemAdded()
+		} else if fc.Name == "ask_user" {
+			hadIntercepted = true
+			outputItem, callErr := s.handleAskUser(ctx, ctrl, fc)
+			if callErr != nil {
+				return nil, hadIntercepted, callErr
+			}
+			if addErr := s.History.AddItem(outputItem); addErr != nil {
+				return nil, hadIntercepted, fmt.Errorf("failed to add ask_user response: %w", addErr)
+			}
+			ctrl.NotifyItemAdded()
 		} else if fc.Name == "update_plan" {
 			hadIntercepted = true
 			outputItem, callErr := s.handleUpdatePlan(ctx, fc)
@@ -348,6 +524,18 @@ func (s *SessionState) dispatchInterceptedCalls(ctx workflow.Context, ctrl *Loop
 				return nil, hadIntercepted, fmt.Errorf("failed to add update_plan response: %w", addErr)
 			}
 			ctrl.NotifyItemAdded()
+		} else if fc.Name == "todo" {
+			hadIntercepted = true
+			if addErr := s.History.AddItem(s.handleTodoTool(ctx, fc)); addErr != nil {
+				return nil, hadIntercepted, fmt.Errorf("failed to add todo response: %w", addErr)
+			}
+			ctrl.NotifyItemAdded()
+		} else if fc.Name == "read_artifact" {
+			hadIntercepted = true
+			if addErr := s.History.AddItem(s.handleReadArtifact(ctx, fc)); addErr != nil {
+				return nil, hadIntercepted, fmt.Errorf("failed to add read_artifact response: %w", addErr)
+			}
+			ctrl.NotifyItemAdded()
 		} else if isCollabToolCall(fc.Name) {
 			hadIntercepted = true
 			outputItem, callErr := s.handleCollabToolCall(ctx, ctrl, fc)
@@ -381,21 +569,41 @@ func (s *SessionState) approveAndExecuteTools(
 	needsApproval, forbiddenResults := gate.Classify(functionCalls)
 
 	// Record forbidden results and filter them out
+	s.auditCalls(ctx, ctrl, callsAnswered(functionCalls, forbiddenResults), AuditApproverPolicy, AuditForbidden)
 	functionCalls = s.recordForbiddenAndFilter(ctrl, functionCalls, forbiddenResults)
 	if len(functionCalls) == 0 {
 		return false, nil // all forbidden — iteration continues
 	}
+	needsApproval = s.requireInjectionApproval(functionCalls, needsApproval)
+	var batch diffBatch
+	if s.Config.DiffBudget() > 0 {
+		previews := s.previewPatches(ctx, functionCalls)
+		batch = measureEdits(functionCalls, previews)
+		needsApproval = s.requireDiffBudgetApproval(functionCalls, needsApproval, &batch)
+		for i := range needsApproval {
+			if out, ok := previews[needsApproval[i].CallID]; ok {
+				setPatchPreview(&needsApproval[i], out)
+			}
+		}
+	}
 
 	// Wait for approval if needed
+	approvers := make(map[string]string, len(needsApproval))
 	if len(needsApproval) > 0 {
+		s.attachGitPreviews(ctx, needsApproval)
+		s.attachPatchPreviews(ctx, needsApproval)
+		var approver string
 		var err error
-		functionCalls, err = s.waitForApprovalAndFilter(ctx, ctrl, functionCalls, gate, needsApproval)
+		functionCalls, approver, err = s.waitForApprovalAndFilter(ctx, ctrl, functionCalls, gate, needsApproval)
 		if err != nil {
 			return false, err
 		}
 		if len(functionCalls) == 0 {
 			return true, nil // all denied by user — end turn
 		}
+		for _, ap := range needsApproval {
+			approvers[ap.CallID] = approver
+		}
 	}
 
 	// Execute tools
@@ -405,9 +613,25 @@ func (s *SessionState) approveAndExecuteTools(
 		toolNames[i] = fc.Name
 	}
 	ctrl.SetToolsInFlight(toolNames)
+	done := make([]bool, len(functionCalls))
+	ctrl.SetToolFocus(toolFocus(functionCalls, done))
+	executor.WithToolDone(func(i int) {
+		done[i] = true
+		ctrl.SetToolFocus(toolFocus(functionCalls, done))
+	})
 	logger.Info("Executing tools", "count", len(functionCalls))
 
-	toolResults, err := executor.ExecuteParallel(ctx, functionCalls)
+	s.ScratchUsed = true // tool activities create the scratch dir on demand
+	for _, fc := range functionCalls {
+		if fc.Name == "exec_command" {
+			s.ExecSessionsUsed = true
+		}
+	}
+	toolCtx, release := cancelOnHardInterrupt(ctx, ctrl)
+	toolStart := workflow.Now(ctx)
+	toolResults, err := executor.ExecuteParallel(toolCtx, functionCalls)
+	s.addToolLatency(ctx, toolStart)
+	release()
 	if err != nil {
 		_ = s.History.AddItem(models.ConversationItem{
 			Type:    models.ItemTypeAssistantMessage,
@@ -419,9 +643,15 @@ func (s *SessionState) approveAndExecuteTools(
 	}
 
 	ctrl.ClearToolsInFlight()
+	s.auditResults(ctx, ctrl, functionCalls, toolResults, s.auditSandbox(), func(callID string) string {
+		if approver, ok := approvers[callID]; ok {
+			return approver
+		}
+		return AuditApproverPolicy
+	})
 
 	// On-failure mode escalation
-	if s.Config.Permissions.ApprovalMode == models.ApprovalOnFailure {
+	if s.Config.Permissions.ApprovalMode == models.ApprovalOnFailure && !ctrl.IsHardInterrupted() {
 		toolResults, err = s.handleOnFailureEscalation(ctx, ctrl, functionCalls, toolResults)
 		if err != nil {
 			return false, err
@@ -430,6 +660,7 @@ func (s *SessionState) approveAndExecuteTools(
 
 	// Record results
 	s.recordToolResults(ctrl, functionCalls, toolResults)
+	s.addTurnDiffLines(batch, toolResults)
 	return false, nil
 }
 
@@ -444,19 +675,21 @@ func (s *SessionState) recordForbiddenAndFilter(
 		_ = s.History.AddItem(fr)
 		ctrl.NotifyItemAdded()
 	}
+	return withoutCalls(calls, forbidden)
+}
 
-	if len(forbidden) == 0 {
+// withoutCalls returns calls minus those answered by outputs.
+func withoutCalls(calls, outputs []models.ConversationItem) []models.ConversationItem {
+	if len(outputs) == 0 {
 		return calls
 	}
-
-	forbiddenIDs := make(map[string]bool, len(forbidden))
-	for _, fr := range forbidden {
-		forbiddenIDs[fr.CallID] = true
+	answered := make(map[string]bool, len(outputs))
+	for _, o := range outputs {
+		answered[o.CallID] = true
 	}
-
 	var remaining []models.ConversationItem
 	for _, fc := range calls {
-		if !forbiddenIDs[fc.CallID] {
+		if !answered[fc.CallID] {
 			remaining = append(remaining, fc)
 		}
 	}
@@ -465,33 +698,40 @@ func (s *SessionState) recordForbiddenAndFilter(
 
 // waitForApprovalAndFilter delegates to ctrl.AwaitApproval, then applies the
 // approval decision to filter the tool calls.
-// Returns the remaining approved calls (nil if interrupted/all-denied).
+// Returns the remaining approved calls (nil if expired/all-denied) and who
+// approved them.
 func (s *SessionState) waitForApprovalAndFilter(
 	ctx workflow.Context,
 	ctrl *LoopControl,
 	calls []models.ConversationItem,
 	gate *ApprovalGate,
 	needsApproval []PendingApproval,
-) ([]models.ConversationItem, error) {
+) ([]models.ConversationItem, string, error) {
+	waitStart := workflow.Now(ctx)
 	resp, err := ctrl.AwaitApproval(ctx, needsApproval)
+	s.addApprovalWait(ctx, waitStart)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	if resp == nil {
-		// Interrupted or shutdown before response arrived
-		return nil, nil
+		// Interrupted, shut down or superseded before a decision arrived
+		s.auditCalls(ctx, ctrl, calls, "", AuditExpired)
+		s.expireApprovals(ctrl, calls, approvalExpiryReason(ctrl))
+		return nil, "", nil
 	}
 
 	// Apply decision
+	approver := approverName(resp.Approver)
 	approved, deniedResults := gate.ApplyDecision(calls, resp)
+	s.auditCalls(ctx, ctrl, callsAnswered(calls, deniedResults), approver, AuditDenied)
 
 	for _, dr := range deniedResults {
 		_ = s.History.AddItem(dr)
 		ctrl.NotifyItemAdded()
 	}
 
-	return approved, nil
+	return approved, approver, nil
 }
 
 // recordToolResults tracks which tools were executed and adds their outputs to history.
@@ -499,6 +739,7 @@ func (s *SessionState) recordToolResults(ctrl *LoopControl, calls []models.Conve
 	for _, fc := range calls {
 		s.ToolCallsExecuted = append(s.ToolCallsExecuted, fc.Name)
 	}
+	s.recordToolStats(calls, results)
 
 	for _, result := range results {
 		item := models.ConversationItem{
@@ -512,6 +753,7 @@ func (s *SessionState) recordToolResults(ctrl *LoopControl, calls []models.Conve
 		_ = s.History.AddItem(item)
 		ctrl.NotifyItemAdded()
 	}
+	s.scanToolOutputs(ctrl, calls, results)
 }
 
 // detectRepeatedToolCalls checks whether the current batch of tool calls is
@@ -1,6 +1,7 @@
 // Package workflow contains Temporal workflow definitions.
 //
-// user_input.go handles interception and processing of request_user_input tool calls.
+// user_input.go handles interception and processing of request_user_input tool calls,
+// and of ask_user, its single free-text question counterpart.
 //
 // Maps to: codex-rs/protocol/src/request_user_input.rs
 package workflow
@@ -8,6 +9,7 @@ package workflow
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"go.temporal.io/sdk/workflow"
 
@@ -79,6 +81,60 @@ func (s *SessionState) handleRequestUserInput(ctx workflow.Context, ctrl *LoopCo
 	}, nil
 }
 
+// askUserQuestionID is the question ID of an ask_user request. A question
+// without options tells the CLI to prompt for free text.
+const askUserQuestionID = "answer"
+
+// handleAskUser intercepts an ask_user tool call: a single free-text question
+// sent through the same pending request and update as request_user_input.
+// The answer is returned to the model as plain text.
+func (s *SessionState) handleAskUser(ctx workflow.Context, ctrl *LoopControl, fc models.ConversationItem) (models.ConversationItem, error) {
+	falseVal := false
+	var args struct {
+		Question string `json:"question"`
+	}
+	if err := json.Unmarshal([]byte(fc.Arguments), &args); err != nil || strings.TrimSpace(args.Question) == "" {
+		return models.ConversationItem{
+			Type:   models.ItemTypeFunctionCallOutput,
+			CallID: fc.CallID,
+			Output: &models.FunctionCallOutputPayload{
+				Content: `Invalid ask_user arguments: expected {"question": "<non-empty text>"}`,
+				Success: &falseVal,
+			},
+		}, nil
+	}
+
+	resp, err := ctrl.AwaitUserInputQuestion(ctx, &PendingUserInputRequest{
+		CallID:    fc.CallID,
+		Questions: []RequestUserInputQuestion{{ID: askUserQuestionID, Question: args.Question}},
+	})
+	if err != nil {
+		return models.ConversationItem{}, fmt.Errorf("user input await failed: %w", err)
+	}
+	if resp == nil {
+		workflow.GetLogger(ctx).Info("ask_user wait interrupted")
+		return models.ConversationItem{
+			Type:   models.ItemTypeFunctionCallOutput,
+			CallID: fc.CallID,
+			Output: &models.FunctionCallOutputPayload{
+				Content: "The question was interrupted before the user answered.",
+				Success: &falseVal,
+			},
+		}, nil
+	}
+
+	answer := strings.Join(resp.Answers[askUserQuestionID].Answers, "\n")
+	trueVal := true
+	return models.ConversationItem{
+		Type:   models.ItemTypeFunctionCallOutput,
+		CallID: fc.CallID,
+		Output: &models.FunctionCallOutputPayload{
+			Content: answer,
+			Success: &trueVal,
+		},
+	}, nil
+}
+
 // parseRequestUserInputArgs validates and parses the request_user_input arguments.
 // Returns parsed questions or an error if the args are invalid.
 func parseRequestUserInputArgs(argsJSON string) ([]RequestUserInputQuestion, error) {
@@ -65,3 +65,15 @@ func extractFunctionCalls(items []models.ConversationItem) []models.Conversation
 	}
 	return calls
 }
+
+// turnItems returns the items from turnID's TurnStarted marker onward.
+// LLM responses and tool outputs are not stamped with a TurnID, so turn
+// membership is positional.
+func turnItems(items []models.ConversationItem, turnID string) []models.ConversationItem {
+	for i := len(items) - 1; i >= 0; i-- {
+		if items[i].Type == models.ItemTypeTurnStarted && items[i].TurnID == turnID {
+			return items[i:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Package workflow contains Temporal workflow definitions.
+//
+// why.go keeps a snapshot of the exact prompt behind the most recent LLM
+// response — instructions, history and tool specs — so the CLI /why command
+// can show what the model was given when it made a puzzling decision.
+package workflow
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+	"github.com/mfateev/temporal-agent-harness/internal/tools"
+)
+
+// errNoPromptSnapshot is returned by get_last_prompt before the first LLM
+// call of the current run has completed.
+var errNoPromptSnapshot = errors.New("no LLM call has completed yet in this run")
+
+// PromptSnapshot is the prompt of one LLM call and the response it produced.
+// Returned by the get_last_prompt query.
+type PromptSnapshot struct {
+	TurnID    string    `json:"turn_id"`
+	Iteration int       `json:"iteration"`
+	Time      time.Time `json:"time"`
+	Provider  string    `json:"provider"`
+	Model     string    `json:"model"`
+
+	BaseInstructions      string `json:"base_instructions,omitempty"`
+	DeveloperInstructions string `json:"developer_instructions,omitempty"`
+	UserInstructions      string `json:"user_instructions,omitempty"`
+
+	// History is the full prompt history as the model saw it, with the
+	// tool output window applied. When the call chained onto a previous
+	// response, only History[SentFrom:] was sent and the provider held the
+	// rest under PreviousResponseID.
+	History            []models.ConversationItem `json:"history"`
+	SentFrom           int                       `json:"sent_from"`
+	PreviousResponseID string                    `json:"previous_response_id,omitempty"`
+
+	ToolSpecs []tools.ToolSpec `json:"tool_specs"`
+
+	// Response is what the model returned for this prompt.
+	Response     []models.ConversationItem `json:"response"`
+	FinishReason models.FinishReason       `json:"finish_reason,omitempty"`
+
+	// RequestHash is this call's entry in the session fingerprint.
+	RequestHash string `json:"request_hash,omitempty"`
+}
+
+// newPromptSnapshot records the prompt of the LLM call about to be made.
+// history is the full windowed prompt history; input.History is the part
+// actually sent, which is the whole prompt unless the call chains onto a
+// previous response.
+func newPromptSnapshot(turnID string, iteration int, now time.Time, history []models.ConversationItem, input activities.LLMActivityInput) *PromptSnapshot {
+	sentFrom := len(history) - len(input.History)
+	if input.PreviousResponseID == "" || sentFrom < 0 {
+		history, sentFrom = input.History, 0
+	}
+	return &PromptSnapshot{
+		TurnID:                turnID,
+		Iteration:             iteration,
+		Time:                  now,
+		Provider:              input.ModelConfig.Provider,
+		Model:                 input.ModelConfig.Model,
+		BaseInstructions:      input.BaseInstructions,
+		DeveloperInstructions: input.DeveloperInstructions,
+		UserInstructions:      input.UserInstructions,
+		History:               history,
+		SentFrom:              sentFrom,
+		PreviousResponseID:    input.PreviousResponseID,
+		ToolSpecs:             input.ToolSpecs,
+	}
+}
+
+// lastPromptSnapshot returns the snapshot served by get_last_prompt.
+func (s *SessionState) lastPromptSnapshot() (PromptSnapshot, error) {
+	if s.lastPrompt == nil {
+		return PromptSnapshot{}, errNoPromptSnapshot
+	}
+	return *s.lastPrompt, nil
+}
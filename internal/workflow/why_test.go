@@ -0,0 +1,51 @@
+package workflow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mfateev/temporal-agent-harness/internal/activities"
+	"github.com/mfateev/temporal-agent-harness/internal/models"
+)
+
+func TestNewPromptSnapshot_ChainedCallKeepsFullHistory(t *testing.T) {
+	history := []models.ConversationItem{
+		{Type: models.ItemTypeUserMessage, Content: "fix the tests"},
+		{Type: models.ItemTypeFunctionCall, CallID: "c1", Name: "shell_command"},
+		{Type: models.ItemTypeFunctionCallOutput, CallID: "c1"},
+	}
+	input := activities.LLMActivityInput{
+		History:            history[2:],
+		ModelConfig:        models.ModelConfig{Provider: "openai", Model: "gpt-4o"},
+		PreviousResponseID: "resp-1",
+	}
+
+	p := newPromptSnapshot("turn-2", 1, time.Unix(0, 0), history, input)
+
+	assert.Len(t, p.History, 3)
+	assert.Equal(t, 2, p.SentFrom)
+	assert.Equal(t, "resp-1", p.PreviousResponseID)
+	assert.Equal(t, "openai", p.Provider)
+}
+
+func TestNewPromptSnapshot_FullCallShowsSentHistory(t *testing.T) {
+	history := []models.ConversationItem{
+		{Type: models.ItemTypeUserMessage, Content: "go on"},
+		{Type: models.ItemTypeAssistantMessage, Content: "partial"},
+	}
+	// A continuation sends a rewritten history without chaining.
+	sent := []models.ConversationItem{history[0], {Type: models.ItemTypeUserMessage, Content: "continue"}}
+
+	p := newPromptSnapshot("turn-1", 0, time.Unix(0, 0), history, activities.LLMActivityInput{History: sent})
+
+	assert.Equal(t, sent, p.History)
+	assert.Equal(t, 0, p.SentFrom)
+}
+
+func TestLastPromptSnapshot_NoneYet(t *testing.T) {
+	s := &SessionState{}
+	_, err := s.lastPromptSnapshot()
+	assert.ErrorIs(t, err, errNoPromptSnapshot)
+}